@@ -360,6 +360,8 @@ func LoadConfig() (*Config, error) {
 		"my_chat_member",
 		"chat_member",
 		"chat_join_request",
+		"message_reaction",
+		"message_reaction_count",
 	}
 
 	cfg.ValidLangCodes = typeConvertor{str: os.Getenv("ENABLED_LOCALES")}.StringArray()
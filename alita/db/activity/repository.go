@@ -0,0 +1,128 @@
+package activity
+
+import (
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/divkix/Alita_Robot/alita/db"
+	"github.com/divkix/Alita_Robot/alita/db/models"
+)
+
+// HourActivity is the activity of a chat in one hour, as collected in Redis
+// before it is flushed.
+type HourActivity struct {
+	Hour          time.Time
+	Messages      int64
+	MediaMessages int64
+	Joins         int64
+	Leaves        int64
+	Posters       map[int64]int64 // user ID -> messages
+}
+
+// Poster is a member and the messages they sent in a window.
+type Poster struct {
+	UserID   int64
+	Messages int64
+}
+
+// AddChatActivity adds an hour of activity to the chat rollups. Counts are
+// added to what is already stored for the hour and day, so the same hour can
+// be flushed more than once. Rollups of the chat older than
+// models.ChatActivityRetention are pruned on the way.
+func AddChatActivity(chatID int64, activity *HourActivity) error {
+	hour := activity.Hour.UTC().Truncate(time.Hour)
+	day := hour.Truncate(24 * time.Hour)
+	err := db.DB.Transaction(func(tx *gorm.DB) error {
+		cutoff := time.Now().UTC().Add(-models.ChatActivityRetention)
+		if err := tx.Where("chat_id = ? AND hour < ?", chatID, cutoff).Delete(&models.ChatActivityHour{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("chat_id = ? AND day < ?", chatID, cutoff.Truncate(24*time.Hour)).Delete(&models.ChatActivityUser{}).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Clauses(clause.OnConflict{
+			Columns: []clause.Column{{Name: "chat_id"}, {Name: "hour"}},
+			DoUpdates: clause.Assignments(map[string]any{
+				"messages":       gorm.Expr("chat_activity_hours.messages + excluded.messages"),
+				"media_messages": gorm.Expr("chat_activity_hours.media_messages + excluded.media_messages"),
+				"joins":          gorm.Expr("chat_activity_hours.joins + excluded.joins"),
+				"leaves":         gorm.Expr("chat_activity_hours.leaves + excluded.leaves"),
+			}),
+		}).Create(&models.ChatActivityHour{
+			ChatID:        chatID,
+			Hour:          hour,
+			Messages:      activity.Messages,
+			MediaMessages: activity.MediaMessages,
+			Joins:         activity.Joins,
+			Leaves:        activity.Leaves,
+		}).Error; err != nil {
+			return err
+		}
+
+		for userID, messages := range activity.Posters {
+			if err := tx.Clauses(clause.OnConflict{
+				Columns: []clause.Column{{Name: "chat_id"}, {Name: "day"}, {Name: "user_id"}},
+				DoUpdates: clause.Assignments(map[string]any{
+					"messages": gorm.Expr("chat_activity_users.messages + excluded.messages"),
+				}),
+			}).Create(&models.ChatActivityUser{
+				ChatID:   chatID,
+				Day:      day,
+				UserID:   userID,
+				Messages: messages,
+			}).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		log.Errorf("[Database][AddChatActivity]: %d - %v", chatID, err)
+	}
+	return err
+}
+
+// GetChatActivityHours returns the hourly rollups of a chat since the given
+// time, oldest first.
+func GetChatActivityHours(chatID int64, since time.Time) ([]models.ChatActivityHour, error) {
+	var hours []models.ChatActivityHour
+	err := db.DB.Where("chat_id = ? AND hour >= ?", chatID, since.UTC().Truncate(time.Hour)).
+		Order("hour ASC").
+		Find(&hours).Error
+	if err != nil {
+		log.Errorf("[Database][GetChatActivityHours]: %d - %v", chatID, err)
+		return nil, err
+	}
+	return hours, nil
+}
+
+// GetTopPosters returns the members who sent the most messages in a chat
+// since the given day, busiest first, along with how many members sent at
+// least one message.
+func GetTopPosters(chatID int64, since time.Time, limit int) ([]Poster, int64, error) {
+	query := db.DB.Model(&models.ChatActivityUser{}).
+		Where("chat_id = ? AND day >= ?", chatID, since.UTC().Truncate(24*time.Hour))
+
+	var active int64
+	if err := query.Session(&gorm.Session{}).Distinct("user_id").Count(&active).Error; err != nil {
+		log.Errorf("[Database][GetTopPosters]: %d - %v", chatID, err)
+		return nil, 0, err
+	}
+
+	var posters []Poster
+	err := query.Session(&gorm.Session{}).
+		Select("user_id, SUM(messages) AS messages").
+		Group("user_id").
+		Order("messages DESC, user_id ASC").
+		Limit(limit).
+		Scan(&posters).Error
+	if err != nil {
+		log.Errorf("[Database][GetTopPosters]: %d - %v", chatID, err)
+		return nil, 0, err
+	}
+	return posters, active, nil
+}
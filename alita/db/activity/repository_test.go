@@ -0,0 +1,91 @@
+package activity
+
+import (
+	"testing"
+	"time"
+
+	"github.com/divkix/Alita_Robot/alita/db"
+	"github.com/divkix/Alita_Robot/alita/db/models"
+)
+
+func skipIfNoDb(t *testing.T) {
+	if db.DB == nil {
+		t.Skip("DB not initialized")
+	}
+}
+
+func cleanupChatActivity(t *testing.T, chatID int64) {
+	t.Cleanup(func() {
+		_ = db.DB.Where("chat_id = ?", chatID).Delete(&models.ChatActivityHour{}).Error
+		_ = db.DB.Where("chat_id = ?", chatID).Delete(&models.ChatActivityUser{}).Error
+	})
+}
+
+func TestAddChatActivityAccumulatesHoursAndPosters(t *testing.T) {
+	skipIfNoDb(t)
+
+	chatID := time.Now().UnixNano()
+	cleanupChatActivity(t, chatID)
+	hour := time.Now().UTC().Truncate(time.Hour)
+
+	flushes := []*HourActivity{
+		{Hour: hour.Add(-time.Hour), Messages: 3, MediaMessages: 1, Joins: 2, Posters: map[int64]int64{1: 2, 2: 1}},
+		{Hour: hour, Messages: 2, Leaves: 1, Posters: map[int64]int64{1: 2}},
+		{Hour: hour.Add(10 * time.Minute), Messages: 4, MediaMessages: 2, Posters: map[int64]int64{2: 1, 3: 3}},
+	}
+	for i, activity := range flushes {
+		if err := AddChatActivity(chatID, activity); err != nil {
+			t.Fatalf("AddChatActivity(%d) error = %v", i, err)
+		}
+	}
+
+	hours, err := GetChatActivityHours(chatID, hour.Add(-24*time.Hour))
+	if err != nil {
+		t.Fatalf("GetChatActivityHours() error = %v", err)
+	}
+	if len(hours) != 2 {
+		t.Fatalf("GetChatActivityHours() = %d rows, want 2", len(hours))
+	}
+	if got := hours[1]; got.Messages != 6 || got.MediaMessages != 2 || got.Leaves != 1 {
+		t.Fatalf("current hour = %+v, want the two flushes of the hour added together", got)
+	}
+	if got := hours[0]; got.Messages != 3 || got.Joins != 2 {
+		t.Fatalf("previous hour = %+v, want 3 messages and 2 joins", got)
+	}
+
+	posters, active, err := GetTopPosters(chatID, hour.Add(-24*time.Hour), 2)
+	if err != nil {
+		t.Fatalf("GetTopPosters() error = %v", err)
+	}
+	if active != 3 {
+		t.Fatalf("active users = %d, want 3", active)
+	}
+	if len(posters) != 2 || posters[0] != (Poster{UserID: 1, Messages: 4}) || posters[1] != (Poster{UserID: 3, Messages: 3}) {
+		t.Fatalf("GetTopPosters() = %+v, want users 1 and 3", posters)
+	}
+}
+
+func TestAddChatActivityPrunesExpiredRollups(t *testing.T) {
+	skipIfNoDb(t)
+
+	chatID := time.Now().UnixNano()
+	cleanupChatActivity(t, chatID)
+	old := time.Now().UTC().Add(-models.ChatActivityRetention - 48*time.Hour).Truncate(time.Hour)
+	if err := db.DB.Create(&models.ChatActivityHour{ChatID: chatID, Hour: old, Messages: 5}).Error; err != nil {
+		t.Fatalf("create expired hour: %v", err)
+	}
+	if err := db.DB.Create(&models.ChatActivityUser{ChatID: chatID, Day: old.Truncate(24 * time.Hour), UserID: 1, Messages: 5}).Error; err != nil {
+		t.Fatalf("create expired poster: %v", err)
+	}
+
+	if err := AddChatActivity(chatID, &HourActivity{Hour: time.Now(), Messages: 1, Posters: map[int64]int64{1: 1}}); err != nil {
+		t.Fatalf("AddChatActivity() error = %v", err)
+	}
+
+	var hours, users int64
+	db.DB.Model(&models.ChatActivityHour{}).Where("chat_id = ?", chatID).Count(&hours)
+	db.DB.Model(&models.ChatActivityUser{}).Where("chat_id = ?", chatID).Count(&users)
+	if hours != 1 || users != 1 {
+		t.Fatalf("rollups after pruning = %d hours, %d users; want 1 and 1", hours, users)
+	}
+}
@@ -0,0 +1,106 @@
+package aliases
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+	"gorm.io/gorm/clause"
+
+	"github.com/divkix/Alita_Robot/alita/db"
+	"github.com/divkix/Alita_Robot/alita/db/cache"
+	"github.com/divkix/Alita_Robot/alita/db/models"
+)
+
+// MaxAliasesPerChat caps how many aliases a single chat may define.
+const MaxAliasesPerChat = 50
+
+// NotePrefix marks an alias target that fetches a note instead of running a command.
+const NotePrefix = "#"
+
+// nameRegex matches a bare Telegram command name.
+var nameRegex = regexp.MustCompile(`^[a-z0-9_]{1,32}$`)
+
+// aliasesCacheKey returns the cache key for a chat's alias map.
+func aliasesCacheKey(chatID int64) string {
+	return cache.CacheKey("command_aliases", chatID)
+}
+
+// NormalizeAlias lower-cases an alias name and strips a leading slash.
+// Returns ok=false when the result is not a valid command name.
+func NormalizeAlias(input string) (string, bool) {
+	name := strings.TrimPrefix(strings.ToLower(strings.TrimSpace(input)), "/")
+	return name, nameRegex.MatchString(name)
+}
+
+// NormalizeTarget lower-cases an alias target, accepting either a command
+// ("/rules" or "rules") or a note reference ("#wiki").
+// Returns ok=false when the target is not a valid command or note name.
+func NormalizeTarget(input string) (string, bool) {
+	target := strings.ToLower(strings.TrimSpace(input))
+	if note, isNote := strings.CutPrefix(target, NotePrefix); isNote {
+		return target, note != "" && !strings.ContainsAny(note, " \t\n")
+	}
+	return NormalizeAlias(target)
+}
+
+// GetAliases returns the alias->target map for a chat, read-through cache.
+// Returns an empty (non-nil) map when no aliases are configured.
+func GetAliases(chatID int64) map[string]string {
+	result, err := cache.GetFromCacheOrLoad(aliasesCacheKey(chatID), cache.CacheTTLAliases, func() (map[string]string, error) {
+		if db.DB == nil {
+			return nil, errors.New("database not initialized")
+		}
+		var rows []*models.CommandAlias
+		if err := db.GetRecords(&rows, models.CommandAlias{ChatID: chatID}); err != nil {
+			return map[string]string{}, err
+		}
+		out := make(map[string]string, len(rows))
+		for _, r := range rows {
+			out[r.Alias] = r.Target
+		}
+		return out, nil
+	})
+	if err != nil || result == nil {
+		return map[string]string{}
+	}
+	return result
+}
+
+// ResolveAlias returns the target configured for alias in a chat, if any.
+func ResolveAlias(chatID int64, alias string) (string, bool) {
+	target, ok := GetAliases(chatID)[alias]
+	return target, ok
+}
+
+// AddAlias adds or updates an alias for a chat.
+func AddAlias(chatID int64, alias, target string) error {
+	a := &models.CommandAlias{
+		ChatID: chatID,
+		Alias:  alias,
+		Target: target,
+	}
+	err := db.DB.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "chat_id"}, {Name: "alias"}},
+		DoUpdates: clause.AssignmentColumns([]string{"target", "updated_at"}),
+	}).Create(a).Error
+	if err != nil {
+		log.Errorf("[Database] AddAlias: %v - chat:%d alias:%s", err, chatID, alias)
+		return err
+	}
+	cache.DeleteCache(aliasesCacheKey(chatID))
+	return nil
+}
+
+// RemoveAlias removes a single alias for a chat.
+// Returns true if an alias was removed.
+func RemoveAlias(chatID int64, alias string) (bool, error) {
+	result := db.DB.Where("chat_id = ? AND alias = ?", chatID, alias).Delete(&models.CommandAlias{})
+	if result.Error != nil {
+		log.Errorf("[Database] RemoveAlias: %v - chat:%d alias:%s", result.Error, chatID, alias)
+		return false, result.Error
+	}
+	cache.DeleteCache(aliasesCacheKey(chatID))
+	return result.RowsAffected > 0, nil
+}
@@ -0,0 +1,86 @@
+package aliases
+
+import (
+	"testing"
+	"time"
+
+	"github.com/divkix/Alita_Robot/alita/db"
+	"github.com/divkix/Alita_Robot/alita/db/models"
+)
+
+func skipIfNoDb(t *testing.T) {
+	if db.DB == nil {
+		t.Skip("DB not initialized")
+	}
+}
+
+func TestNormalizeAlias(t *testing.T) {
+	tests := []struct {
+		input  string
+		want   string
+		wantOK bool
+	}{
+		{"regeln", "regeln", true},
+		{"/Wiki", "wiki", true},
+		{" my_alias ", "my_alias", true},
+		{"", "", false},
+		{"two words", "two words", false},
+		{"wiki!", "wiki!", false},
+	}
+	for _, tc := range tests {
+		got, ok := NormalizeAlias(tc.input)
+		if ok != tc.wantOK || (ok && got != tc.want) {
+			t.Fatalf("NormalizeAlias(%q) = %q, %v", tc.input, got, ok)
+		}
+	}
+}
+
+func TestNormalizeTarget(t *testing.T) {
+	tests := []struct {
+		input  string
+		want   string
+		wantOK bool
+	}{
+		{"rules", "rules", true},
+		{"/Rules", "rules", true},
+		{"#Wiki", "#wiki", true},
+		{"#", "", false},
+		{"rules!", "", false},
+	}
+	for _, tc := range tests {
+		got, ok := NormalizeTarget(tc.input)
+		if ok != tc.wantOK || (ok && got != tc.want) {
+			t.Fatalf("NormalizeTarget(%q) = %q, %v", tc.input, got, ok)
+		}
+	}
+}
+
+func TestAddResolveRemoveAlias(t *testing.T) {
+	skipIfNoDb(t)
+
+	chatID := time.Now().UnixNano()
+	t.Cleanup(func() {
+		db.DB.Where("chat_id = ?", chatID).Delete(&models.CommandAlias{})
+	})
+
+	if err := AddAlias(chatID, "regeln", "rules"); err != nil {
+		t.Fatalf("AddAlias() error = %v", err)
+	}
+	if err := AddAlias(chatID, "regeln", "#rules"); err != nil {
+		t.Fatalf("AddAlias() update error = %v", err)
+	}
+	if target, ok := ResolveAlias(chatID, "regeln"); !ok || target != "#rules" {
+		t.Fatalf("ResolveAlias() = %q, %v, want #rules", target, ok)
+	}
+
+	removed, err := RemoveAlias(chatID, "regeln")
+	if err != nil || !removed {
+		t.Fatalf("RemoveAlias() = %v, %v, want true", removed, err)
+	}
+	if _, ok := ResolveAlias(chatID, "regeln"); ok {
+		t.Fatal("ResolveAlias() found removed alias")
+	}
+	if removed, err = RemoveAlias(chatID, "regeln"); err != nil || removed {
+		t.Fatalf("RemoveAlias() second call = %v, %v, want false", removed, err)
+	}
+}
@@ -56,3 +56,42 @@ func GetAntifloodSettingsCached(chatID int64) (*models.AntifloodSettings, error)
 
 	return cached, nil
 }
+
+// GetTopicFloodLimits retrieves the flood limits of a chat's forum topics,
+// keyed by topic ID, with minimal column selection.
+func GetTopicFloodLimits(chatID int64) (map[int64]int, error) {
+	if db.DB == nil {
+		return nil, errors.New("database not initialized")
+	}
+
+	var rows []models.AntifloodTopicLimit
+	err := db.DB.Model(&models.AntifloodTopicLimit{}).
+		Select("thread_id, flood_limit").
+		Where("chat_id = ?", chatID).
+		Find(&rows).Error
+	if err != nil {
+		log.Errorf("[OptimizedAntifloodQueries] GetTopicFloodLimits: %v", err)
+		return nil, err
+	}
+
+	limits := make(map[int64]int, len(rows))
+	for _, row := range rows {
+		limits[row.ThreadId] = row.Limit
+	}
+	return limits, nil
+}
+
+// GetTopicFloodLimitsCached retrieves the topic flood limits of a chat with the
+// same caching as GetAntifloodSettingsCached.
+func GetTopicFloodLimitsCached(chatID int64) (map[int64]int, error) {
+	cacheKey := cache.CacheKey("antiflood_topics", chatID)
+
+	cached, err := cache.GetFromCacheOrLoad(cacheKey, cache.CacheTTLAntiflood, func() (map[int64]int, error) {
+		return GetTopicFloodLimits(chatID)
+	})
+	if err != nil {
+		return GetTopicFloodLimits(chatID)
+	}
+
+	return cached, nil
+}
@@ -8,6 +8,7 @@ import (
 	"github.com/divkix/Alita_Robot/alita/db/models"
 	log "github.com/sirupsen/logrus"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 // default mode is 'mute'
@@ -101,6 +102,57 @@ func SetFloodMsgDel(chatID int64, val bool) error {
 	return upsertChatField(chatID, updates)
 }
 
+// GetTopicFlood returns the flood settings that apply in a forum topic: the
+// chat settings, with the limit of the topic when it has its own. ok reports
+// whether it does, so callers can count messages per topic only then.
+func GetTopicFlood(chatID, threadID int64) (floodSrc *models.AntifloodSettings, ok bool) {
+	floodSrc = checkFloodSetting(chatID)
+	if threadID == 0 {
+		return floodSrc, false
+	}
+	limits, err := GetTopicFloodLimitsCached(chatID)
+	if err != nil {
+		log.Errorf("[Database][GetTopicFlood]: %v", err)
+		return floodSrc, false
+	}
+	limit, ok := limits[threadID]
+	if !ok {
+		return floodSrc, false
+	}
+	topicSrc := *floodSrc
+	topicSrc.Limit = limit
+	return &topicSrc, true
+}
+
+// SetTopicFlood sets the flood limit of a forum topic, overriding the limit of
+// the chat there. A limit of 0 turns flood control off in the topic.
+func SetTopicFlood(chatID, threadID int64, limit int) error {
+	record := models.AntifloodTopicLimit{ChatId: chatID, ThreadId: threadID, Limit: limit}
+	err := db.DB.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "chat_id"}, {Name: "thread_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"flood_limit", "updated_at"}),
+	}).Create(&record).Error
+	if err != nil {
+		log.Errorf("[Database] SetTopicFlood: %v - %d", err, chatID)
+		return err
+	}
+	cache.DeleteCache(cache.CacheKey("antiflood_topics", chatID))
+	return nil
+}
+
+// RemoveTopicFlood drops the flood limit of a forum topic, so the limit of the
+// chat applies there again.
+func RemoveTopicFlood(chatID, threadID int64) error {
+	err := db.DB.Where("chat_id = ? AND thread_id = ?", chatID, threadID).
+		Delete(&models.AntifloodTopicLimit{}).Error
+	if err != nil {
+		log.Errorf("[Database] RemoveTopicFlood: %v - %d", err, chatID)
+		return err
+	}
+	cache.DeleteCache(cache.CacheKey("antiflood_topics", chatID))
+	return nil
+}
+
 // LoadAntifloodStats returns the count of chats with antiflood enabled (limit > 0).
 func LoadAntifloodStats() (antiCount int64) {
 	var totalCount int64
@@ -321,3 +321,48 @@ func TestLoadAntifloodStats(t *testing.T) {
 		}
 	})
 }
+
+func TestTopicFloodOverridesChatLimit(t *testing.T) {
+	skipIfNoDb(t)
+
+	chatID := time.Now().UnixNano()
+	const topic = int64(7)
+
+	t.Cleanup(func() {
+		if err := db.DB.Where("chat_id = ?", chatID).Delete(&models.AntifloodTopicLimit{}).Error; err != nil {
+			t.Fatalf("cleanup failed: %v", err)
+		}
+		if err := db.DB.Where("chat_id = ?", chatID).Delete(&models.AntifloodSettings{}).Error; err != nil {
+			t.Fatalf("cleanup failed: %v", err)
+		}
+	})
+
+	if err := SetFlood(chatID, 10); err != nil {
+		t.Fatalf("SetFlood() failed: %v", err)
+	}
+	if _, ok := GetTopicFlood(chatID, topic); ok {
+		t.Fatalf("GetTopicFlood() found a topic limit before one was set")
+	}
+
+	if err := SetTopicFlood(chatID, topic, 3); err != nil {
+		t.Fatalf("SetTopicFlood() failed: %v", err)
+	}
+	if err := SetTopicFlood(chatID, topic, 4); err != nil {
+		t.Fatalf("SetTopicFlood() update failed: %v", err)
+	}
+	settings, ok := GetTopicFlood(chatID, topic)
+	if !ok || settings.Limit != 4 {
+		t.Fatalf("GetTopicFlood() = %+v, %v, want limit 4 for the topic", settings, ok)
+	}
+	if chat, _ := GetTopicFlood(chatID, 0); chat.Limit != 10 {
+		t.Fatalf("chat limit = %d, want 10 left untouched", chat.Limit)
+	}
+
+	if err := RemoveTopicFlood(chatID, topic); err != nil {
+		t.Fatalf("RemoveTopicFlood() failed: %v", err)
+	}
+	settings, ok = GetTopicFlood(chatID, topic)
+	if ok || settings.Limit != 10 {
+		t.Fatalf("GetTopicFlood() after remove = %+v, %v, want the chat limit", settings, ok)
+	}
+}
@@ -18,7 +18,7 @@ func getAntiRaidSettingsRaw(chatID int64) (*models.AntiRaidSettings, error) {
 
 	var settings models.AntiRaidSettings
 	err := db.DB.Model(&models.AntiRaidSettings{}).
-		Select("id, chat_id, raid_time, raid_action_time, auto_antiraid_threshold").
+		Select("id, chat_id, raid_time, raid_action_time, auto_antiraid_threshold, name_weight, profile_weight, message_weight, invite_weight").
 		Where("chat_id = ?", chatID).
 		First(&settings).Error
 
@@ -82,6 +82,48 @@ func SetRaidActionTime(chatID int64, seconds int) error {
 	return upsertChatField(chatID, updates)
 }
 
+// Raid detectors with a configurable weight, besides the join count.
+const (
+	DetectorNames     = "names"
+	DetectorNoProfile = "noprofile"
+	DetectorMessages  = "messages"
+	DetectorInvite    = "invite"
+
+	MaxDetectorWeight = 10
+)
+
+// detectorColumns maps the weighted raid detectors to their columns.
+var detectorColumns = map[string]string{
+	DetectorNames:     "name_weight",
+	DetectorNoProfile: "profile_weight",
+	DetectorMessages:  "message_weight",
+	DetectorInvite:    "invite_weight",
+}
+
+// Detectors returns the names of the weighted raid detectors, in the order
+// they are shown.
+func Detectors() []string {
+	return []string{DetectorNames, DetectorNoProfile, DetectorMessages, DetectorInvite}
+}
+
+// SetDetectorWeight sets the points per hit of a raid detector. 0 turns it
+// off.
+func SetDetectorWeight(chatID int64, detector string, weight int) error {
+	column, ok := detectorColumns[detector]
+	if !ok {
+		return fmt.Errorf("unknown raid detector %q", detector)
+	}
+	if weight < 0 || weight > MaxDetectorWeight {
+		return fmt.Errorf("detector weight must be between 0 and %d, got %d", MaxDetectorWeight, weight)
+	}
+
+	updates := map[string]any{
+		"chat_id": chatID,
+		column:    weight,
+	}
+	return upsertChatField(chatID, updates)
+}
+
 // SetAutoAntiRaidThreshold sets the auto-trigger join-rate threshold.
 // 0 disables auto-trigger.
 func SetAutoAntiRaidThreshold(chatID int64, threshold int) error {
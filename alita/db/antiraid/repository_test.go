@@ -336,3 +336,51 @@ func TestAntiRaidSettingsCacheInvalidation(t *testing.T) {
 		t.Fatalf("expected RaidTime=10800 after cache invalidation, got %d", fresh.RaidTime)
 	}
 }
+
+func TestSetDetectorWeight(t *testing.T) {
+	skipIfNoDb(t)
+
+	chatID := time.Now().UnixNano()
+	t.Cleanup(func() {
+		if err := db.DB.Where("chat_id = ?", chatID).Delete(&models.AntiRaidSettings{}).Error; err != nil {
+			t.Fatalf("cleanup failed: %v", err)
+		}
+	})
+
+	if err := SetDetectorWeight(chatID, DetectorNames, 3); err != nil {
+		t.Fatalf("SetDetectorWeight(names, 3) failed: %v", err)
+	}
+	if err := SetDetectorWeight(chatID, DetectorInvite, MaxDetectorWeight); err != nil {
+		t.Fatalf("SetDetectorWeight(invite, max) failed: %v", err)
+	}
+	settings := GetAntiRaidSettings(chatID)
+	if settings.NameWeight != 3 || settings.InviteWeight != MaxDetectorWeight || settings.ProfileWeight != 0 || settings.MessageWeight != 0 {
+		t.Fatalf("settings = %+v, want names 3 and invite %d", settings, MaxDetectorWeight)
+	}
+	if settings.RaidTime != 21600 || settings.RaidActionTime != 3600 {
+		t.Fatalf("settings = %+v, want default raid times kept", settings)
+	}
+	if got := settings.DetectorWeight(DetectorNames); got != 3 {
+		t.Fatalf("DetectorWeight(names) = %d, want 3", got)
+	}
+
+	if err := SetDetectorWeight(chatID, DetectorNames, 0); err != nil {
+		t.Fatalf("SetDetectorWeight(names, 0) failed: %v", err)
+	}
+	if got := GetAntiRaidSettings(chatID).NameWeight; got != 0 {
+		t.Fatalf("NameWeight = %d after setting 0, want 0", got)
+	}
+
+	for _, tc := range []struct {
+		detector string
+		weight   int
+	}{
+		{detector: "joins", weight: 1},
+		{detector: DetectorMessages, weight: -1},
+		{detector: DetectorMessages, weight: MaxDetectorWeight + 1},
+	} {
+		if err := SetDetectorWeight(chatID, tc.detector, tc.weight); err == nil {
+			t.Fatalf("SetDetectorWeight(%s, %d) = nil, want error", tc.detector, tc.weight)
+		}
+	}
+}
@@ -0,0 +1,295 @@
+package antispam
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"unicode/utf8"
+
+	log "github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/divkix/Alita_Robot/alita/db"
+	"github.com/divkix/Alita_Robot/alita/db/cache"
+	"github.com/divkix/Alita_Robot/alita/db/models"
+	"github.com/divkix/Alita_Robot/alita/utils/spamfilter"
+)
+
+const (
+	// MinThreshold and MaxThreshold bound the spam threshold, in percent.
+	MinThreshold = 50
+	MaxThreshold = 99
+
+	// MinDupeChats and MaxDupeChats bound in how many chats a message has to
+	// show up before it is treated as a duplicate.
+	MinDupeChats = 2
+	MaxDupeChats = 10
+
+	// maxTokenLength is the size of spam_tokens.token.
+	maxTokenLength = 64
+)
+
+// Actions lists the spam actions admins can choose from.
+var Actions = []string{
+	models.SpamActionDelete,
+	models.SpamActionWarn,
+	models.SpamActionMute,
+	models.SpamActionKick,
+	models.SpamActionBan,
+}
+
+// DupeActions lists the actions admins can choose from for duplicate
+// messages. flag keeps the message.
+var DupeActions = append([]string{models.SpamActionFlag}, Actions...)
+
+// defaultAntispamSettings returns the settings of a chat that never
+// configured the spam classifier: disabled, deleting at 90% and only using
+// its own model. Duplicate detection is off and mutes once a message shows up
+// in 3 chats.
+func defaultAntispamSettings(chatID int64) *models.AntispamSettings {
+	return &models.AntispamSettings{
+		ChatID:     chatID,
+		Action:     models.SpamActionDelete,
+		Threshold:  models.DefaultSpamThreshold,
+		DupeAction: models.SpamActionMute,
+		DupeChats:  models.DefaultDupeChats,
+	}
+}
+
+// getAntispamSettingsRaw retrieves the spam classifier settings of a chat,
+// or the defaults if it has none.
+func getAntispamSettingsRaw(chatID int64) (*models.AntispamSettings, error) {
+	if db.DB == nil {
+		return defaultAntispamSettings(chatID), errors.New("database not initialized")
+	}
+
+	var settings models.AntispamSettings
+	err := db.DB.Where("chat_id = ?", chatID).First(&settings).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return defaultAntispamSettings(chatID), nil
+	}
+	if err != nil {
+		log.Errorf("[Database][getAntispamSettingsRaw]: %d - %v", chatID, err)
+		return nil, err
+	}
+	return &settings, nil
+}
+
+// GetAntispamSettings returns the spam classifier settings of a chat. It is
+// called for every message, so the settings are cached.
+func GetAntispamSettings(chatID int64) *models.AntispamSettings {
+	settings, err := cache.GetFromCacheOrLoad(cache.CacheKey("antispam", chatID), cache.CacheTTLAntispam, func() (*models.AntispamSettings, error) {
+		return getAntispamSettingsRaw(chatID)
+	})
+	if err != nil || settings == nil {
+		return defaultAntispamSettings(chatID)
+	}
+	return settings
+}
+
+// upsertChatField updates the given columns of a chat's spam classifier
+// settings, creating the row first if needed, and invalidates the cache. The
+// row is created before updating so false values are not replaced by the
+// column defaults on insert.
+func upsertChatField(chatID int64, updates map[string]any) error {
+	err := db.DB.Transaction(func(tx *gorm.DB) error {
+		settings := defaultAntispamSettings(chatID)
+		if err := tx.Where("chat_id = ?", chatID).FirstOrCreate(settings).Error; err != nil {
+			return err
+		}
+		return tx.Model(&models.AntispamSettings{}).Where("chat_id = ?", chatID).Updates(updates).Error
+	})
+	if err != nil {
+		log.Errorf("[Database][antispam upsertChatField]: %d - %v", chatID, err)
+		return err
+	}
+	cache.DeleteCache(cache.CacheKey("antispam", chatID))
+	return nil
+}
+
+// SetAntispamEnabled turns the spam classifier of a chat on or off.
+func SetAntispamEnabled(chatID int64, enabled bool) error {
+	return upsertChatField(chatID, map[string]any{"enabled": enabled})
+}
+
+// SetAntispamAction sets what is done to the sender of a message flagged as
+// spam.
+func SetAntispamAction(chatID int64, action string) error {
+	if !slices.Contains(Actions, action) {
+		return fmt.Errorf("unknown spam action %q", action)
+	}
+	return upsertChatField(chatID, map[string]any{"action": action})
+}
+
+// SetAntispamThreshold sets the spam probability, in percent, from which a
+// message is acted on.
+func SetAntispamThreshold(chatID int64, threshold int) error {
+	if threshold < MinThreshold || threshold > MaxThreshold {
+		return fmt.Errorf("spam threshold must be between %d and %d, got %d", MinThreshold, MaxThreshold, threshold)
+	}
+	return upsertChatField(chatID, map[string]any{"threshold": threshold})
+}
+
+// SetAntispamUseGlobal sets whether the chat scores messages with the global
+// model on top of its own.
+func SetAntispamUseGlobal(chatID int64, useGlobal bool) error {
+	return upsertChatField(chatID, map[string]any{"use_global": useGlobal})
+}
+
+// SetDupeEnabled turns the cross-chat duplicate message detection of a chat
+// on or off.
+func SetDupeEnabled(chatID int64, enabled bool) error {
+	return upsertChatField(chatID, map[string]any{"dupe_enabled": enabled})
+}
+
+// SetDupeAction sets what is done to the sender of a duplicate message.
+func SetDupeAction(chatID int64, action string) error {
+	if !slices.Contains(DupeActions, action) {
+		return fmt.Errorf("unknown duplicate action %q", action)
+	}
+	return upsertChatField(chatID, map[string]any{"dupe_action": action})
+}
+
+// SetDupeChats sets in how many chats a message has to show up before the
+// chat treats it as a duplicate.
+func SetDupeChats(chatID int64, chats int) error {
+	if chats < MinDupeChats || chats > MaxDupeChats {
+		return fmt.Errorf("duplicate chat count must be between %d and %d, got %d", MinDupeChats, MaxDupeChats, chats)
+	}
+	return upsertChatField(chatID, map[string]any{"dupe_chats": chats})
+}
+
+// SetAntispamTrainGlobal sets whether the chat is trusted to train the global
+// model. Only the bot owner may change it.
+func SetAntispamTrainGlobal(chatID int64, trainGlobal bool) error {
+	return upsertChatField(chatID, map[string]any{"train_global": trainGlobal})
+}
+
+// TrainSpamModel adds one message, as its tokens, to the model of the chat
+// and, if the chat is trusted, to the global model, as spam or as not spam.
+func TrainSpamModel(chatID int64, tokens []string, spam bool) error {
+	var spamDocs, hamDocs int64 = 0, 1
+	if spam {
+		spamDocs, hamDocs = 1, 0
+	}
+	tokens = uniqueTokens(tokens)
+	ids := modelIDs(chatID, GetAntispamSettings(chatID).TrainGlobal)
+
+	err := db.DB.Transaction(func(tx *gorm.DB) error {
+		for _, modelID := range ids {
+			if err := tx.Clauses(clause.OnConflict{
+				Columns: []clause.Column{{Name: "chat_id"}},
+				DoUpdates: clause.Assignments(map[string]any{
+					"spam_docs":  gorm.Expr("spam_models.spam_docs + excluded.spam_docs"),
+					"ham_docs":   gorm.Expr("spam_models.ham_docs + excluded.ham_docs"),
+					"updated_at": gorm.Expr("excluded.updated_at"),
+				}),
+			}).Create(&models.SpamModel{ChatID: modelID, SpamDocs: spamDocs, HamDocs: hamDocs}).Error; err != nil {
+				return err
+			}
+			if len(tokens) == 0 {
+				continue
+			}
+			rows := make([]models.SpamToken, 0, len(tokens))
+			for _, token := range tokens {
+				rows = append(rows, models.SpamToken{ChatID: modelID, Token: token, SpamCount: spamDocs, HamCount: hamDocs})
+			}
+			if err := tx.Clauses(clause.OnConflict{
+				Columns: []clause.Column{{Name: "chat_id"}, {Name: "token"}},
+				DoUpdates: clause.Assignments(map[string]any{
+					"spam_count": gorm.Expr("spam_tokens.spam_count + excluded.spam_count"),
+					"ham_count":  gorm.Expr("spam_tokens.ham_count + excluded.ham_count"),
+				}),
+			}).Create(&rows).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		log.Errorf("[Database][TrainSpamModel]: %d - %v", chatID, err)
+	}
+	return err
+}
+
+// GetSpamCounts returns the training counts of the given tokens, summed over
+// the model of the chat and, if useGlobal is set, the global model.
+func GetSpamCounts(chatID int64, tokens []string, useGlobal bool) (*spamfilter.Counts, error) {
+	ids := modelIDs(chatID, useGlobal)
+	counts := &spamfilter.Counts{Tokens: make(map[string]spamfilter.TokenCounts, len(tokens))}
+
+	var docs struct {
+		SpamDocs int64
+		HamDocs  int64
+	}
+	if err := db.DB.Model(&models.SpamModel{}).
+		Select("COALESCE(SUM(spam_docs), 0) AS spam_docs, COALESCE(SUM(ham_docs), 0) AS ham_docs").
+		Where("chat_id IN ?", ids).
+		Scan(&docs).Error; err != nil {
+		log.Errorf("[Database][GetSpamCounts]: %d - %v", chatID, err)
+		return nil, err
+	}
+	counts.SpamDocs, counts.HamDocs = docs.SpamDocs, docs.HamDocs
+	if len(tokens) == 0 || !counts.Trained() {
+		return counts, nil
+	}
+
+	var rows []models.SpamToken
+	if err := db.DB.Select("token, spam_count, ham_count").
+		Where("chat_id IN ? AND token IN ?", ids, uniqueTokens(tokens)).
+		Find(&rows).Error; err != nil {
+		log.Errorf("[Database][GetSpamCounts]: %d - %v", chatID, err)
+		return nil, err
+	}
+	for _, row := range rows {
+		tc := counts.Tokens[row.Token]
+		tc.Spam += row.SpamCount
+		tc.Ham += row.HamCount
+		counts.Tokens[row.Token] = tc
+	}
+	return counts, nil
+}
+
+// GetSpamModels returns how many messages the model of the chat and the
+// global model were trained on. Models never trained are returned empty.
+func GetSpamModels(chatID int64) (chatModel, globalModel models.SpamModel, err error) {
+	var rows []models.SpamModel
+	if err = db.DB.Where("chat_id IN ?", modelIDs(chatID, true)).Find(&rows).Error; err != nil {
+		log.Errorf("[Database][GetSpamModels]: %d - %v", chatID, err)
+		return chatModel, globalModel, err
+	}
+	for _, row := range rows {
+		if row.ChatID == chatID {
+			chatModel = row
+		} else {
+			globalModel = row
+		}
+	}
+	return chatModel, globalModel, nil
+}
+
+// modelIDs returns the models a chat uses.
+func modelIDs(chatID int64, useGlobal bool) []int64 {
+	if useGlobal && chatID != models.SpamGlobalModel {
+		return []int64{chatID, models.SpamGlobalModel}
+	}
+	return []int64{chatID}
+}
+
+// uniqueTokens drops duplicate and oversized tokens.
+func uniqueTokens(tokens []string) []string {
+	seen := make(map[string]struct{}, len(tokens))
+	unique := make([]string, 0, len(tokens))
+	for _, token := range tokens {
+		if token == "" || utf8.RuneCountInString(token) > maxTokenLength {
+			continue
+		}
+		if _, ok := seen[token]; ok {
+			continue
+		}
+		seen[token] = struct{}{}
+		unique = append(unique, token)
+	}
+	return unique
+}
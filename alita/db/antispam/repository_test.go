@@ -0,0 +1,182 @@
+package antispam
+
+import (
+	"testing"
+	"time"
+
+	"github.com/divkix/Alita_Robot/alita/db"
+	"github.com/divkix/Alita_Robot/alita/db/models"
+)
+
+func skipIfNoDb(t *testing.T) {
+	if db.DB == nil {
+		t.Skip("DB not initialized")
+	}
+}
+
+func cleanupSpamModels(t *testing.T, chatIDs ...int64) {
+	t.Cleanup(func() {
+		for _, chatID := range chatIDs {
+			_ = db.DB.Where("chat_id = ?", chatID).Delete(&models.AntispamSettings{}).Error
+			_ = db.DB.Where("chat_id = ?", chatID).Delete(&models.SpamModel{}).Error
+			_ = db.DB.Where("chat_id = ?", chatID).Delete(&models.SpamToken{}).Error
+		}
+	})
+}
+
+func TestAntispamSettingsDefaultsAndUpdates(t *testing.T) {
+	skipIfNoDb(t)
+
+	chatID := time.Now().UnixNano()
+	cleanupSpamModels(t, chatID)
+
+	settings := GetAntispamSettings(chatID)
+	if settings.Enabled || settings.Action != models.SpamActionDelete || settings.Threshold != models.DefaultSpamThreshold || settings.UseGlobal || settings.TrainGlobal {
+		t.Fatalf("default settings = %+v, want disabled, delete at %d%% without the global model", settings, models.DefaultSpamThreshold)
+	}
+
+	if err := SetAntispamUseGlobal(chatID, true); err != nil {
+		t.Fatalf("SetAntispamUseGlobal() error = %v", err)
+	}
+	if err := SetAntispamEnabled(chatID, true); err != nil {
+		t.Fatalf("SetAntispamEnabled() error = %v", err)
+	}
+	if err := SetAntispamAction(chatID, models.SpamActionMute); err != nil {
+		t.Fatalf("SetAntispamAction() error = %v", err)
+	}
+	if err := SetAntispamThreshold(chatID, 75); err != nil {
+		t.Fatalf("SetAntispamThreshold() error = %v", err)
+	}
+
+	settings = GetAntispamSettings(chatID)
+	if !settings.Enabled || settings.Action != models.SpamActionMute || settings.Threshold != 75 || !settings.UseGlobal {
+		t.Fatalf("settings = %+v, want enabled, mute at 75%% with the global model", settings)
+	}
+
+	if err := SetAntispamAction(chatID, "explode"); err == nil {
+		t.Fatal("SetAntispamAction(explode) error = nil, want an error")
+	}
+	if err := SetAntispamThreshold(chatID, MaxThreshold+1); err == nil {
+		t.Fatal("SetAntispamThreshold(100) error = nil, want an error")
+	}
+}
+
+func TestDupeSettings(t *testing.T) {
+	skipIfNoDb(t)
+
+	chatID := time.Now().UnixNano()
+	cleanupSpamModels(t, chatID)
+
+	settings := GetAntispamSettings(chatID)
+	if settings.DupeEnabled || settings.DupeAction != models.SpamActionMute || settings.DupeChats != models.DefaultDupeChats {
+		t.Fatalf("default settings = %+v, want duplicate detection off, muting from %d chats", settings, models.DefaultDupeChats)
+	}
+
+	if err := SetDupeEnabled(chatID, true); err != nil {
+		t.Fatalf("SetDupeEnabled() error = %v", err)
+	}
+	if err := SetDupeAction(chatID, models.SpamActionFlag); err != nil {
+		t.Fatalf("SetDupeAction() error = %v", err)
+	}
+	if err := SetDupeChats(chatID, 5); err != nil {
+		t.Fatalf("SetDupeChats() error = %v", err)
+	}
+
+	settings = GetAntispamSettings(chatID)
+	if !settings.DupeEnabled || settings.DupeAction != models.SpamActionFlag || settings.DupeChats != 5 || settings.Enabled {
+		t.Fatalf("settings = %+v, want duplicate detection on, flagging from 5 chats, spam filter still off", settings)
+	}
+
+	if err := SetDupeAction(chatID, "explode"); err == nil {
+		t.Fatal("SetDupeAction(explode) error = nil, want an error")
+	}
+	if err := SetDupeChats(chatID, MinDupeChats-1); err == nil {
+		t.Fatal("SetDupeChats(1) error = nil, want an error")
+	}
+}
+
+func TestTrainSpamModelFeedsGlobalModelFromTrustedChats(t *testing.T) {
+	skipIfNoDb(t)
+
+	chatID := time.Now().UnixNano()
+	otherChatID := chatID + 1
+	cleanupSpamModels(t, chatID, otherChatID)
+	_, globalBefore, err := GetSpamModels(chatID)
+	if err != nil {
+		t.Fatalf("GetSpamModels() error = %v", err)
+	}
+	tokenPrefix := "t" + time.Now().Format("150405.000000000")
+	spamToken, hamToken := tokenPrefix+"spam", tokenPrefix+"ham"
+	t.Cleanup(func() {
+		_ = db.DB.Where("chat_id = ? AND token IN ?", models.SpamGlobalModel, []string{spamToken, hamToken}).Delete(&models.SpamToken{}).Error
+		_ = db.DB.Model(&models.SpamModel{}).Where("chat_id = ?", models.SpamGlobalModel).
+			Updates(map[string]any{"spam_docs": globalBefore.SpamDocs, "ham_docs": globalBefore.HamDocs}).Error
+	})
+
+	// Untrusted chats only train their own model.
+	if err := TrainSpamModel(otherChatID, []string{spamToken}, true); err != nil {
+		t.Fatalf("TrainSpamModel(untrusted) error = %v", err)
+	}
+	if _, globalModel, _ := GetSpamModels(otherChatID); globalModel.SpamDocs != globalBefore.SpamDocs {
+		t.Fatalf("global model = %+v after an untrusted chat trained, want it unchanged from %+v", globalModel, globalBefore)
+	}
+	if err := db.DB.Where("chat_id = ?", otherChatID).Delete(&models.SpamModel{}).Error; err != nil {
+		t.Fatalf("reset untrusted chat model: %v", err)
+	}
+	if err := db.DB.Where("chat_id = ?", otherChatID).Delete(&models.SpamToken{}).Error; err != nil {
+		t.Fatalf("reset untrusted chat tokens: %v", err)
+	}
+
+	if err := SetAntispamTrainGlobal(chatID, true); err != nil {
+		t.Fatalf("SetAntispamTrainGlobal() error = %v", err)
+	}
+	for range 5 {
+		if err := TrainSpamModel(chatID, []string{spamToken, spamToken}, true); err != nil {
+			t.Fatalf("TrainSpamModel(spam) error = %v", err)
+		}
+		if err := TrainSpamModel(chatID, []string{hamToken}, false); err != nil {
+			t.Fatalf("TrainSpamModel(ham) error = %v", err)
+		}
+	}
+
+	chatModel, globalModel, err := GetSpamModels(chatID)
+	if err != nil {
+		t.Fatalf("GetSpamModels() error = %v", err)
+	}
+	if chatModel.SpamDocs != 5 || chatModel.HamDocs != 5 {
+		t.Fatalf("chat model = %+v, want 5 spam and 5 ham", chatModel)
+	}
+	if globalModel.SpamDocs != globalBefore.SpamDocs+5 || globalModel.HamDocs != globalBefore.HamDocs+5 {
+		t.Fatalf("global model = %+v, want 5 more spam and ham than %+v", globalModel, globalBefore)
+	}
+
+	counts, err := GetSpamCounts(chatID, []string{spamToken, hamToken, "unknown"}, false)
+	if err != nil {
+		t.Fatalf("GetSpamCounts() error = %v", err)
+	}
+	if !counts.Trained() {
+		t.Fatalf("counts = %+v, want a trained model", counts)
+	}
+	if got := counts.Tokens[spamToken]; got.Spam != 5 || got.Ham != 0 {
+		t.Fatalf("spam token counts = %+v, want 5 spam (duplicates counted once per message)", got)
+	}
+	if got := counts.Tokens[hamToken]; got.Spam != 0 || got.Ham != 5 {
+		t.Fatalf("ham token counts = %+v, want 5 ham", got)
+	}
+
+	// Another chat only sees the training through the global model.
+	own, err := GetSpamCounts(otherChatID, []string{spamToken}, false)
+	if err != nil {
+		t.Fatalf("GetSpamCounts(other chat) error = %v", err)
+	}
+	if own.SpamDocs != 0 || len(own.Tokens) != 0 {
+		t.Fatalf("other chat own counts = %+v, want nothing", own)
+	}
+	global, err := GetSpamCounts(otherChatID, []string{spamToken}, true)
+	if err != nil {
+		t.Fatalf("GetSpamCounts(other chat, global) error = %v", err)
+	}
+	if got := global.Tokens[spamToken]; got.Spam != 5 {
+		t.Fatalf("other chat global counts = %+v, want the 5 spam messages", got)
+	}
+}
@@ -1,10 +1,27 @@
 package approvals
 
 import (
+	"slices"
+	"strings"
+	"time"
+
 	"github.com/divkix/Alita_Robot/alita/db"
 	"github.com/divkix/Alita_Robot/alita/db/cache"
 	"github.com/divkix/Alita_Robot/alita/db/models"
 	log "github.com/sirupsen/logrus"
+	"gorm.io/gorm/clause"
+)
+
+// Approval scopes accepted by /approve. Watchers pass their own scope to
+// chat_status.IsApprovedFor so scoped approvals only bypass matching checks.
+const (
+	ScopeLocks     = models.ApprovalScopeLocks
+	ScopeMedia     = models.ApprovalScopeMedia
+	ScopeFlood     = models.ApprovalScopeFlood
+	ScopeBlacklist = models.ApprovalScopeBlacklist
+	ScopeCaptcha   = models.ApprovalScopeCaptcha
+	ScopeAntispam  = models.ApprovalScopeAntispam
+	ScopeAntiraid  = models.ApprovalScopeAntiraid
 )
 
 // AddApprovedUser adds a user to the approved list for a chat.
@@ -27,18 +44,94 @@ func AddApprovedUser(chatID, userID, approvedBy int64, reason string) error {
 	return nil
 }
 
-// IsUserApproved checks if a user is in the approved list for a chat.
+// SetApproval creates or replaces a user's approval in a chat.
+// Empty scopes exempt the user from every protection; a nil expiresAt never expires.
+// Unlike AddApprovedUser, re-approving an existing (or expired) user overwrites it.
+func SetApproval(chatID, userID, approvedBy int64, reason string, scopes []string, expiresAt *time.Time) error {
+	approval := &models.ApprovedUsers{
+		ChatID:     chatID,
+		UserID:     userID,
+		ApprovedBy: approvedBy,
+		Reason:     reason,
+		Scopes:     models.StringArray(scopes),
+		ExpiresAt:  expiresAt,
+	}
+
+	err := db.DB.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}, {Name: "chat_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"approved_by", "reason", "scopes", "expires_at", "updated_at"}),
+	}).Create(approval).Error
+	if err != nil {
+		log.Errorf("[Database] SetApproval: %v - chat:%d user:%d", err, chatID, userID)
+		return err
+	}
+
+	cache.DeleteCache(cache.CacheKey("approvals", chatID))
+	return nil
+}
+
+// IsUserApproved checks if a user has an active approval of any scope in a chat.
 func IsUserApproved(chatID, userID int64) bool {
+	return GetApproval(chatID, userID) != nil
+}
+
+// IsUserApprovedFor checks if a user has an active approval covering the given scope.
+func IsUserApprovedFor(chatID, userID int64, scope string) bool {
+	approval := GetApproval(chatID, userID)
+	return approval != nil && approval.Covers(scope)
+}
+
+// GetApproval returns a user's active approval in a chat, or nil if the user
+// is not approved or the approval has expired.
+func GetApproval(chatID, userID int64) *models.ApprovedUsers {
 	for _, u := range GetApprovedUsers(chatID) {
 		if u.UserID == userID {
-			return true
+			return u
+		}
+	}
+	return nil
+}
+
+// AllScopes returns every valid approval scope in display order.
+func AllScopes() []string {
+	return slices.Clone(models.ApprovalScopes)
+}
+
+// ParseScopes parses a comma-separated scope list such as "locks,blacklist".
+// Returns false if the input is empty or contains an unknown scope.
+func ParseScopes(input string) ([]string, bool) {
+	var scopes []string
+	for _, part := range strings.Split(strings.ToLower(input), ",") {
+		part = strings.TrimSpace(part)
+		if !slices.Contains(models.ApprovalScopes, part) {
+			return nil, false
+		}
+		if !slices.Contains(scopes, part) {
+			scopes = append(scopes, part)
 		}
 	}
-	return false
+	return scopes, len(scopes) > 0
 }
 
-// GetApprovedUsers returns all approved users for a chat.
+// GetApprovedUsers returns all active (non-expired) approved users for a chat.
 func GetApprovedUsers(chatID int64) []*models.ApprovedUsers {
+	users := getAllApprovedUsers(chatID)
+	if users == nil {
+		return nil
+	}
+	now := time.Now()
+	active := make([]*models.ApprovedUsers, 0, len(users))
+	for _, u := range users {
+		if !u.IsExpired(now) {
+			active = append(active, u)
+		}
+	}
+	return active
+}
+
+// getAllApprovedUsers returns every stored approval for a chat, including expired
+// ones. Expiry is evaluated by callers so cached rows never outlive their expiry.
+func getAllApprovedUsers(chatID int64) []*models.ApprovedUsers {
 	cacheKey := cache.CacheKey("approvals", chatID)
 	result, err := cache.GetFromCacheOrLoad(cacheKey, cache.CacheTTLApprovals, func() ([]*models.ApprovedUsers, error) {
 		var users []*models.ApprovedUsers
@@ -4,6 +4,7 @@ package approvals
 
 import (
 	"testing"
+	"time"
 
 	"github.com/divkix/Alita_Robot/alita/db"
 )
@@ -231,3 +232,44 @@ func TestDuplicateApprovalIsError(t *testing.T) {
 		t.Fatalf("AddApprovedUser() duplicate = nil, expected error")
 	}
 }
+
+func TestSetApprovalScopesAndExpiry(t *testing.T) {
+	skipIfNoDb(t)
+
+	chatID := int64(-999999999999997)
+
+	t.Cleanup(func() {
+		_ = RemoveAllApprovedUsers(chatID)
+	})
+
+	if err := SetApproval(chatID, 22222, 99999, "", []string{ScopeLocks}, nil); err != nil {
+		t.Fatalf("SetApproval() error = %v", err)
+	}
+	if !IsUserApprovedFor(chatID, 22222, ScopeMedia) {
+		t.Fatalf("IsUserApprovedFor(media) = false, expected locks scope to cover media")
+	}
+	if IsUserApprovedFor(chatID, 22222, ScopeFlood) {
+		t.Fatalf("IsUserApprovedFor(flood) = true for locks-only approval")
+	}
+
+	expired := time.Now().Add(-time.Minute)
+	if err := SetApproval(chatID, 22222, 99999, "", nil, &expired); err != nil {
+		t.Fatalf("SetApproval() error = %v", err)
+	}
+	if IsUserApproved(chatID, 22222) {
+		t.Fatalf("IsUserApproved() = true for expired approval")
+	}
+	if users := GetApprovedUsers(chatID); len(users) != 0 {
+		t.Fatalf("GetApprovedUsers() len = %d, expected expired approval to be hidden", len(users))
+	}
+}
+
+func TestParseScopes(t *testing.T) {
+	scopes, ok := ParseScopes("Locks, flood")
+	if !ok || len(scopes) != 2 || scopes[0] != ScopeLocks || scopes[1] != ScopeFlood {
+		t.Fatalf("ParseScopes() = %v, %v", scopes, ok)
+	}
+	if _, ok := ParseScopes("locks,bogus"); ok {
+		t.Fatalf("ParseScopes() accepted unknown scope")
+	}
+}
@@ -17,6 +17,8 @@ func ExportModuleData(chatID int64, module string) (interface{}, error) {
 	switch module {
 	case BackupModuleAdmin:
 		return exportAdminData(chatID)
+	case BackupModuleAliases:
+		return exportAliasesData(chatID)
 	case BackupModuleAntiflood:
 		return exportAntifloodData(chatID)
 	case BackupModuleAntiraid:
@@ -352,9 +354,18 @@ func exportAdminData(chatID int64) (*AdminBackup, error) {
 	return result, nil
 }
 
+func exportAliasesData(chatID int64) (*AliasesBackup, error) {
+	rows, err := findChatRows[models.CommandAlias](chatID)
+	return &AliasesBackup{Aliases: rows}, err
+}
+
 func exportAntifloodData(chatID int64) (*AntifloodBackup, error) {
 	settings, err := findChatSetting[models.AntifloodSettings](chatID)
-	return &AntifloodBackup{Settings: settings}, err
+	if err != nil {
+		return nil, err
+	}
+	topicLimits, err := findChatRows[models.AntifloodTopicLimit](chatID)
+	return &AntifloodBackup{Settings: settings, TopicLimits: topicLimits}, err
 }
 
 func exportAntiraidData(chatID int64) (*AntiraidBackup, error) {
@@ -381,7 +392,11 @@ func exportBlacklistsData(chatID int64) (*BlacklistsBackup, error) {
 
 func exportCaptchaData(chatID int64) (*CaptchaBackup, error) {
 	settings, err := findChatSetting[models.CaptchaSettings](chatID)
-	return &CaptchaBackup{Settings: settings}, err
+	if err != nil {
+		return nil, err
+	}
+	questions, err := findChatRows[models.CaptchaQuestion](chatID)
+	return &CaptchaBackup{Settings: settings, Questions: questions}, err
 }
 
 func exportConnectionsData(chatID int64) (*ConnectionsBackup, error) {
@@ -398,7 +413,11 @@ func exportDisablingData(chatID int64) (*DisablingBackup, error) {
 	if err != nil {
 		return nil, err
 	}
-	return &DisablingBackup{ChatSettings: settings, Commands: commands}, nil
+	permissions, err := findChatRows[models.CommandPermission](chatID)
+	if err != nil {
+		return nil, err
+	}
+	return &DisablingBackup{ChatSettings: settings, Commands: commands, Permissions: permissions}, nil
 }
 
 func exportFiltersData(chatID int64) (*FiltersBackup, error) {
@@ -435,7 +454,11 @@ func exportPinsData(chatID int64) (*PinsBackup, error) {
 
 func exportReactionsData(chatID int64) (*ReactionsBackup, error) {
 	rows, err := findChatRows[models.Reactions](chatID)
-	return &ReactionsBackup{Reactions: rows}, err
+	if err != nil {
+		return nil, err
+	}
+	actions, err := findChatRows[models.ReactionAction](chatID)
+	return &ReactionsBackup{Reactions: rows, Moderation: actions}, err
 }
 
 func exportReportsData(chatID int64) (*ReportsBackup, error) {
@@ -467,6 +490,8 @@ func importModuleData(tx *gorm.DB, chatID int64, module string, data interface{}
 	switch module {
 	case BackupModuleAdmin:
 		return importAdmin(tx, chatID, data)
+	case BackupModuleAliases:
+		return importAliases(tx, chatID, data)
 	case BackupModuleAntiflood:
 		return importAntiflood(tx, chatID, data)
 	case BackupModuleAntiraid:
@@ -543,11 +568,29 @@ func importAdmin(tx *gorm.DB, chatID int64, payload interface{}) ([]string, erro
 	}
 	return []string{
 		cacheKey("antiflood", chatID),
+		cacheKey("antiflood_topics", chatID),
 		cacheKey("captcha_settings", chatID),
 		cacheKey("blacklist", chatID),
 	}, nil
 }
 
+func importAliases(tx *gorm.DB, chatID int64, payload interface{}) ([]string, error) {
+	var data AliasesBackup
+	if err := decodeModuleData(payload, BackupModuleAliases, &data); err != nil {
+		return nil, err
+	}
+	for i := range data.Aliases {
+		if data.Aliases[i].Alias == "" || data.Aliases[i].Target == "" {
+			return nil, fmt.Errorf("invalid empty alias")
+		}
+		data.Aliases[i].ChatID = chatID
+	}
+	if err := replaceChatRows(tx, chatID, data.Aliases); err != nil {
+		return nil, err
+	}
+	return []string{cacheKey("command_aliases", chatID)}, nil
+}
+
 func importAntiflood(tx *gorm.DB, chatID int64, payload interface{}) ([]string, error) {
 	var data AntifloodBackup
 	if err := decodeModuleData(payload, BackupModuleAntiflood, &data); err != nil {
@@ -562,7 +605,16 @@ func importAntiflood(tx *gorm.DB, chatID int64, payload interface{}) ([]string,
 	if err := replaceChatSetting(tx, chatID, data.Settings); err != nil {
 		return nil, err
 	}
-	return []string{cacheKey("antiflood", chatID)}, nil
+	for i := range data.TopicLimits {
+		if data.TopicLimits[i].ThreadId <= 0 || data.TopicLimits[i].Limit < 0 {
+			return nil, fmt.Errorf("invalid antiflood topic limit for topic %d", data.TopicLimits[i].ThreadId)
+		}
+		data.TopicLimits[i].ChatId = chatID
+	}
+	if err := replaceChatRows(tx, chatID, data.TopicLimits); err != nil {
+		return nil, err
+	}
+	return []string{cacheKey("antiflood", chatID), cacheKey("antiflood_topics", chatID)}, nil
 }
 
 func importAntiraid(tx *gorm.DB, chatID int64, payload interface{}) ([]string, error) {
@@ -572,7 +624,8 @@ func importAntiraid(tx *gorm.DB, chatID int64, payload interface{}) ([]string, e
 	}
 	if data.Settings != nil {
 		data.Settings.ChatID = chatID
-		if data.Settings.RaidTime < 0 || data.Settings.RaidActionTime < 0 || data.Settings.AutoAntiRaidThreshold < 0 {
+		if data.Settings.RaidTime < 0 || data.Settings.RaidActionTime < 0 || data.Settings.AutoAntiRaidThreshold < 0 ||
+			data.Settings.NameWeight < 0 || data.Settings.ProfileWeight < 0 || data.Settings.MessageWeight < 0 || data.Settings.InviteWeight < 0 {
 			return nil, fmt.Errorf("invalid antiraid settings")
 		}
 	}
@@ -633,9 +686,21 @@ func importCaptcha(tx *gorm.DB, chatID int64, payload interface{}) ([]string, er
 	if data.Settings != nil {
 		data.Settings.ChatID = chatID
 	}
+	if len(data.Questions) > models.MaxCaptchaQuestions {
+		return nil, fmt.Errorf("too many captcha questions: %d", len(data.Questions))
+	}
+	for i := range data.Questions {
+		if data.Questions[i].Question == "" || data.Questions[i].Answer == "" {
+			return nil, fmt.Errorf("invalid captcha question without question or answer")
+		}
+		data.Questions[i].ChatID = chatID
+	}
 	if err := replaceChatSetting(tx, chatID, data.Settings); err != nil {
 		return nil, err
 	}
+	if err := replaceChatRows(tx, chatID, data.Questions); err != nil {
+		return nil, err
+	}
 	return []string{cacheKey("captcha_settings", chatID)}, nil
 }
 
@@ -664,13 +729,22 @@ func importDisabling(tx *gorm.DB, chatID int64, payload interface{}) ([]string,
 		}
 		data.Commands[i].ChatId = chatID
 	}
+	for i := range data.Permissions {
+		if data.Permissions[i].Command == "" || data.Permissions[i].Level == "" {
+			return nil, fmt.Errorf("invalid command permission")
+		}
+		data.Permissions[i].ChatId = chatID
+	}
 	if err := replaceChatSetting(tx, chatID, data.ChatSettings); err != nil {
 		return nil, err
 	}
 	if err := replaceChatRows(tx, chatID, data.Commands); err != nil {
 		return nil, err
 	}
-	return []string{cacheKey("disabled_cmds", chatID)}, nil
+	if err := replaceChatRows(tx, chatID, data.Permissions); err != nil {
+		return nil, err
+	}
+	return []string{cacheKey("disabled_cmds", chatID), cacheKey("command_perms", chatID)}, nil
 }
 
 func importFilters(tx *gorm.DB, chatID int64, payload interface{}) ([]string, error) { //nolint:dupl // module-specific schema
@@ -721,7 +795,7 @@ func importLocks(tx *gorm.DB, chatID int64, payload interface{}) ([]string, erro
 	if err := replaceChatRows(tx, chatID, data.Locks); err != nil {
 		return nil, err
 	}
-	return []string{cacheKey("lock", chatID), cacheKey("locks_map", chatID)}, nil
+	return []string{cacheKey("lock", chatID), cacheKey("locks_map", chatID), cacheKey("topic_locks", chatID)}, nil
 }
 
 func importNotes(tx *gorm.DB, chatID int64, payload interface{}, preserveLegacyOmissions bool) ([]string, error) { //nolint:dupl // module-specific schema
@@ -747,7 +821,11 @@ func importNotes(tx *gorm.DB, chatID int64, payload interface{}, preserveLegacyO
 	if err := replaceChatRows(tx, chatID, data.Notes); err != nil {
 		return nil, err
 	}
-	return []string{cacheKey("notes_settings", chatID)}, nil
+	return []string{
+		cacheKey("notes_settings", chatID),
+		cacheKey("notes_list", chatID),
+		cacheKey("notes_all", chatID),
+	}, nil
 }
 
 func importPins(tx *gorm.DB, chatID int64, payload interface{}) ([]string, error) {
@@ -766,16 +844,42 @@ func importReactions(tx *gorm.DB, chatID int64, payload interface{}) ([]string,
 	if err := decodeModuleData(payload, BackupModuleReactions, &data); err != nil {
 		return nil, err
 	}
+	var legacy legacyReactionsBackup
+	if err := decodeModuleData(payload, BackupModuleReactions, &legacy); err != nil {
+		return nil, err
+	}
 	for i := range data.Reactions {
-		if data.Reactions[i].Keyword == "" || data.Reactions[i].Emoji == "" {
+		rule := &data.Reactions[i]
+		if len(rule.Emojis) == 0 && legacy.Reactions[i].Emoji != "" {
+			rule.Emojis = models.ReactionEmojiArray{{Emoji: legacy.Reactions[i].Emoji}}
+		}
+		if rule.Probability == 0 {
+			rule.Probability = 100
+		}
+		if rule.Keyword == "" || len(rule.Emojis) == 0 || rule.Probability < 1 || rule.Probability > 100 || rule.CooldownSeconds < 0 {
 			return nil, fmt.Errorf("invalid reaction")
 		}
-		data.Reactions[i].ChatID = chatID
+		for _, emoji := range rule.Emojis {
+			if emoji.Emoji == "" {
+				return nil, fmt.Errorf("invalid reaction")
+			}
+		}
+		rule.ChatID = chatID
+	}
+	for i := range data.Moderation {
+		action := &data.Moderation[i]
+		if action.Emoji == "" || action.Action == "" || action.DurationSeconds < 0 {
+			return nil, fmt.Errorf("invalid reaction action")
+		}
+		action.ChatID = chatID
 	}
 	if err := replaceChatRows(tx, chatID, data.Reactions); err != nil {
 		return nil, err
 	}
-	return []string{cacheKey("reactions", chatID)}, nil
+	if err := replaceChatRows(tx, chatID, data.Moderation); err != nil {
+		return nil, err
+	}
+	return []string{cacheKey("reactions", chatID), cacheKey("reaction_actions", chatID)}, nil
 }
 
 func importReports(tx *gorm.DB, chatID int64, payload interface{}) ([]string, error) {
@@ -879,6 +983,8 @@ func clearModuleData(tx *gorm.DB, chatID int64, module string) ([]string, error)
 	switch module {
 	case BackupModuleAdmin:
 		return clearAdmin(tx, chatID)
+	case BackupModuleAliases:
+		return clearAliases(tx, chatID)
 	case BackupModuleAntiflood:
 		return clearAntiflood(tx, chatID)
 	case BackupModuleAntiraid:
@@ -943,14 +1049,23 @@ func clearAdmin(tx *gorm.DB, chatID int64) ([]string, error) {
 	}
 	return []string{
 		cacheKey("antiflood", chatID),
+		cacheKey("antiflood_topics", chatID),
 		cacheKey("captcha_settings", chatID),
 		cacheKey("blacklist", chatID),
 	}, nil
 }
 
+func clearAliases(tx *gorm.DB, chatID int64) ([]string, error) {
+	return []string{cacheKey("command_aliases", chatID)}, replaceChatRows[models.CommandAlias](tx, chatID, nil)
+}
+
 func clearAntiflood(tx *gorm.DB, chatID int64) ([]string, error) {
 	settings := &models.AntifloodSettings{ChatId: chatID, Limit: 0, Action: "mute"}
-	return []string{cacheKey("antiflood", chatID)}, replaceChatSetting(tx, chatID, settings)
+	if err := replaceChatSetting(tx, chatID, settings); err != nil {
+		return nil, err
+	}
+	return []string{cacheKey("antiflood", chatID), cacheKey("antiflood_topics", chatID)},
+		replaceChatRows[models.AntifloodTopicLimit](tx, chatID, nil)
 }
 
 func clearAntiraid(tx *gorm.DB, chatID int64) ([]string, error) {
@@ -979,6 +1094,9 @@ func clearCaptcha(tx *gorm.DB, chatID int64) ([]string, error) {
 		FailureAction: "kick",
 		MaxAttempts:   3,
 	}
+	if err := replaceChatRows[models.CaptchaQuestion](tx, chatID, nil); err != nil {
+		return nil, err
+	}
 	return []string{cacheKey("captcha_settings", chatID)}, replaceChatSetting(tx, chatID, settings)
 }
 
@@ -990,7 +1108,10 @@ func clearDisabling(tx *gorm.DB, chatID int64) ([]string, error) {
 	if err := replaceChatSetting(tx, chatID, &models.DisableChatSettings{ChatId: chatID}); err != nil {
 		return nil, err
 	}
-	return []string{cacheKey("disabled_cmds", chatID)}, replaceChatRows[models.DisableSettings](tx, chatID, nil)
+	if err := replaceChatRows[models.DisableSettings](tx, chatID, nil); err != nil {
+		return nil, err
+	}
+	return []string{cacheKey("disabled_cmds", chatID), cacheKey("command_perms", chatID)}, replaceChatRows[models.CommandPermission](tx, chatID, nil)
 }
 
 func clearFilters(tx *gorm.DB, chatID int64) ([]string, error) {
@@ -1021,6 +1142,7 @@ func clearLocks(tx *gorm.DB, chatID int64) ([]string, error) {
 	return []string{
 		cacheKey("lock", chatID),
 		cacheKey("locks_map", chatID),
+		cacheKey("topic_locks", chatID),
 	}, replaceChatRows[models.LockSettings](tx, chatID, nil)
 }
 
@@ -1028,7 +1150,11 @@ func clearNotes(tx *gorm.DB, chatID int64) ([]string, error) {
 	if err := replaceChatSetting(tx, chatID, &models.NotesSettings{ChatId: chatID}); err != nil {
 		return nil, err
 	}
-	return []string{cacheKey("notes_settings", chatID)}, replaceChatRows[models.Notes](tx, chatID, nil)
+	return []string{
+		cacheKey("notes_settings", chatID),
+		cacheKey("notes_list", chatID),
+		cacheKey("notes_all", chatID),
+	}, replaceChatRows[models.Notes](tx, chatID, nil)
 }
 
 func clearPins(tx *gorm.DB, chatID int64) ([]string, error) {
@@ -1036,7 +1162,10 @@ func clearPins(tx *gorm.DB, chatID int64) ([]string, error) {
 }
 
 func clearReactions(tx *gorm.DB, chatID int64) ([]string, error) {
-	return []string{cacheKey("reactions", chatID)}, replaceChatRows[models.Reactions](tx, chatID, nil)
+	if err := replaceChatRows[models.ReactionAction](tx, chatID, nil); err != nil {
+		return nil, err
+	}
+	return []string{cacheKey("reactions", chatID), cacheKey("reaction_actions", chatID)}, replaceChatRows[models.Reactions](tx, chatID, nil)
 }
 
 func clearReports(tx *gorm.DB, chatID int64) ([]string, error) {
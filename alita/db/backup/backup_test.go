@@ -323,12 +323,18 @@ func cleanupBackupChat(t *testing.T, chatID int64) {
 	if err := db.DB.Where("chat_id = ?", chatID).Delete(&models.AntifloodSettings{}).Error; err != nil {
 		t.Errorf("cleanup failed deleting AntifloodSettings: %v", err)
 	}
+	if err := db.DB.Where("chat_id = ?", chatID).Delete(&models.AntifloodTopicLimit{}).Error; err != nil {
+		t.Errorf("cleanup failed deleting AntifloodTopicLimit: %v", err)
+	}
 	if err := db.DB.Where("chat_id = ?", chatID).Delete(&models.BlacklistSettings{}).Error; err != nil {
 		t.Errorf("cleanup failed deleting BlacklistSettings: %v", err)
 	}
 	if err := db.DB.Where("chat_id = ?", chatID).Delete(&models.CaptchaSettings{}).Error; err != nil {
 		t.Errorf("cleanup failed deleting CaptchaSettings: %v", err)
 	}
+	if err := db.DB.Where("chat_id = ?", chatID).Delete(&models.CaptchaQuestion{}).Error; err != nil {
+		t.Errorf("cleanup failed deleting CaptchaQuestion: %v", err)
+	}
 	if err := db.DB.Where("chat_id = ?", chatID).Delete(&models.ConnectionChatSettings{}).Error; err != nil {
 		t.Errorf("cleanup failed deleting ConnectionChatSettings: %v", err)
 	}
@@ -338,6 +344,9 @@ func cleanupBackupChat(t *testing.T, chatID int64) {
 	if err := db.DB.Where("chat_id = ?", chatID).Delete(&models.DisableChatSettings{}).Error; err != nil {
 		t.Errorf("cleanup failed deleting DisableChatSettings: %v", err)
 	}
+	if err := db.DB.Where("chat_id = ?", chatID).Delete(&models.CommandPermission{}).Error; err != nil {
+		t.Errorf("cleanup failed deleting CommandPermission: %v", err)
+	}
 	if err := db.DB.Where("chat_id = ?", chatID).Delete(&models.ChatFilters{}).Error; err != nil {
 		t.Errorf("cleanup failed deleting ChatFilters: %v", err)
 	}
@@ -377,6 +386,9 @@ func cleanupBackupChat(t *testing.T, chatID int64) {
 	if err := db.DB.Where("chat_id = ?", chatID).Delete(&models.Reactions{}).Error; err != nil {
 		t.Errorf("cleanup failed deleting Reactions: %v", err)
 	}
+	if err := db.DB.Where("chat_id = ?", chatID).Delete(&models.CommandAlias{}).Error; err != nil {
+		t.Errorf("cleanup failed deleting CommandAlias: %v", err)
+	}
 	if err := db.DB.Where("chat_id = ?", chatID).Delete(&models.Chat{}).Error; err != nil {
 		t.Errorf("cleanup failed deleting Chat: %v", err)
 	}
@@ -488,8 +500,8 @@ func TestExportFiltersData(t *testing.T) {
 	assert.Empty(t, backup.Filters)
 
 	// Add filters
-	require.NoError(t, filters.AddFilter(chatID, "hello", "hi there", "", nil, db.TEXT))
-	require.NoError(t, filters.AddFilter(chatID, "bye", "see ya", "", nil, db.TEXT))
+	require.NoError(t, filters.AddFilter(chatID, "hello", "hi there", "", nil, nil, db.TEXT))
+	require.NoError(t, filters.AddFilter(chatID, "bye", "see ya", "", nil, nil, db.TEXT))
 
 	backup, err = exportFiltersData(chatID)
 	require.NoError(t, err)
@@ -561,8 +573,12 @@ func TestExportImportNotesRoundTrip(t *testing.T) {
 	})
 
 	// Add notes to source chat
-	require.NoError(t, notes.AddNote(srcChat, "welcome", "Welcome!", "", nil, db.TEXT, false, false, false, true, false, false))
-	require.NoError(t, notes.AddNote(srcChat, "rules", "Follow the rules", "", nil, db.TEXT, false, false, false, true, false, false))
+	require.NoError(t, notes.AddNote(srcChat, "welcome", "faq", "Welcome!", "", nil, nil, db.TEXT, false, false, false, true, false, false))
+	album := models.MediaItemArray{
+		{FileID: "photo-1", MsgType: db.PHOTO},
+		{FileID: "photo-2", MsgType: db.PHOTO, Caption: "page two"},
+	}
+	require.NoError(t, notes.AddNote(srcChat, "rules", "", "Follow the rules", "photo-1", nil, album, db.PHOTO, false, false, false, true, false, false))
 
 	// Export
 	exported, err := exportNotesData(srcChat)
@@ -586,6 +602,14 @@ func TestExportImportNotesRoundTrip(t *testing.T) {
 	note := notes.GetNote(dstChat, "welcome")
 	require.NotNil(t, note)
 	assert.Equal(t, "Welcome!", note.NoteContent)
+	assert.Equal(t, "faq", note.Category)
+	rules := notes.GetNote(dstChat, "rules")
+	require.NotNil(t, rules)
+	assert.Equal(t, album, rules.Media)
+	assert.Equal(t, []notes.NoteIndexEntry{
+		{Name: "rules"},
+		{Name: "welcome", Category: "faq"},
+	}, notes.GetNoteIndex(dstChat, true))
 }
 
 func TestExportImportRulesRoundTrip(t *testing.T) {
@@ -827,6 +851,7 @@ func TestExportImportCaptchaRoundTrip(t *testing.T) {
 	require.NoError(t, captcha.SetCaptchaMode(srcChat, "text"))
 	require.NoError(t, captcha.SetCaptchaTimeout(srcChat, 7))
 	require.NoError(t, captcha.SetCaptchaMaxAttempts(srcChat, 5))
+	require.NoError(t, captcha.AddCaptchaQuestion(srcChat, "Which editor?", "vim", []string{"emacs", "nano"}))
 
 	exported, err := exportCaptchaData(srcChat)
 	require.NoError(t, err)
@@ -836,6 +861,8 @@ func TestExportImportCaptchaRoundTrip(t *testing.T) {
 	assert.Equal(t, "text", exported.Settings.CaptchaMode)
 	assert.Equal(t, 7, exported.Settings.Timeout)
 	assert.Equal(t, 5, exported.Settings.MaxAttempts)
+	require.Len(t, exported.Questions, 1)
+	assert.Equal(t, "vim", exported.Questions[0].Answer)
 
 	payload := map[string]interface{}{
 		"settings": map[string]interface{}{
@@ -846,6 +873,13 @@ func TestExportImportCaptchaRoundTrip(t *testing.T) {
 			"max_attempts":   float64(5),
 			"failure_action": "kick",
 		},
+		"questions": []interface{}{
+			map[string]interface{}{
+				"question":      "Which editor?",
+				"answer":        "vim",
+				"wrong_answers": []interface{}{"emacs", "nano"},
+			},
+		},
 	}
 
 	require.NoError(t, ImportModuleData(dstChat, BackupModuleCaptcha, payload))
@@ -857,6 +891,11 @@ func TestExportImportCaptchaRoundTrip(t *testing.T) {
 	assert.Equal(t, "text", settings.CaptchaMode)
 	assert.Equal(t, 7, settings.Timeout)
 	assert.Equal(t, 5, settings.MaxAttempts)
+
+	questions := captcha.GetCaptchaQuestions(dstChat)
+	require.Len(t, questions, 1)
+	assert.Equal(t, "Which editor?", questions[0].Question)
+	assert.Equal(t, []string{"emacs", "nano"}, []string(questions[0].WrongAnswers))
 }
 
 func TestExportImportAntifloodRoundTrip(t *testing.T) {
@@ -874,6 +913,7 @@ func TestExportImportAntifloodRoundTrip(t *testing.T) {
 	require.NoError(t, antiflood.SetFlood(srcChat, 3))
 	require.NoError(t, antiflood.SetFloodMode(srcChat, "mute"))
 	require.NoError(t, antiflood.SetFloodMsgDel(srcChat, true))
+	require.NoError(t, antiflood.SetTopicFlood(srcChat, 12, 8))
 
 	exported, err := exportAntifloodData(srcChat)
 	require.NoError(t, err)
@@ -882,6 +922,9 @@ func TestExportImportAntifloodRoundTrip(t *testing.T) {
 	assert.Equal(t, 3, exported.Settings.Limit)
 	assert.Equal(t, "mute", exported.Settings.Action)
 	assert.True(t, exported.Settings.DeleteAntifloodMessage)
+	require.Len(t, exported.TopicLimits, 1)
+	assert.Equal(t, int64(12), exported.TopicLimits[0].ThreadId)
+	assert.Equal(t, 8, exported.TopicLimits[0].Limit)
 
 	payload := map[string]interface{}{
 		"settings": map[string]interface{}{
@@ -890,6 +933,9 @@ func TestExportImportAntifloodRoundTrip(t *testing.T) {
 			"action":                   "mute",
 			"delete_antiflood_message": true,
 		},
+		"topic_limits": []interface{}{
+			map[string]interface{}{"thread_id": float64(12), "limit": float64(8)},
+		},
 	}
 
 	require.NoError(t, ImportModuleData(dstChat, BackupModuleAntiflood, payload))
@@ -899,6 +945,11 @@ func TestExportImportAntifloodRoundTrip(t *testing.T) {
 	assert.Equal(t, 3, settings.Limit)
 	assert.Equal(t, "mute", settings.Action)
 	assert.True(t, settings.DeleteAntifloodMessage)
+
+	topicSettings, ok := antiflood.GetTopicFlood(dstChat, 12)
+	require.True(t, ok)
+	assert.Equal(t, 8, topicSettings.Limit)
+	assert.Equal(t, "mute", topicSettings.Action)
 }
 
 func TestExportImportGreetingsRoundTrip(t *testing.T) {
@@ -985,6 +1036,36 @@ func TestExportImportPinsRoundTrip(t *testing.T) {
 	assert.True(t, settings.AntiChannelPin)
 }
 
+func TestImportReactionsReadsSingleEmojiBackups(t *testing.T) {
+	skipIfNoDb(t)
+
+	chatID := time.Now().UnixNano()
+	require.NoError(t, chats.EnsureChatInDb(chatID, "test_import_reactions"))
+	t.Cleanup(func() { cleanupBackupChat(t, chatID) })
+
+	// Backups made before reaction rules held one emoji per keyword.
+	payload := map[string]interface{}{
+		"reactions": []interface{}{
+			map[string]interface{}{"keyword": "hello", "emoji": "👋"},
+		},
+	}
+	require.NoError(t, ImportModuleData(chatID, BackupModuleReactions, payload))
+
+	rows, err := findChatRows[models.Reactions](chatID)
+	require.NoError(t, err)
+	require.Len(t, rows, 1)
+	assert.Equal(t, models.ReactionEmojiArray{{Emoji: "👋"}}, rows[0].Emojis)
+	assert.Equal(t, 100, rows[0].Probability)
+	assert.Zero(t, rows[0].CooldownSeconds)
+
+	invalid := map[string]interface{}{
+		"reactions": []interface{}{
+			map[string]interface{}{"keyword": "hello", "emojis": []interface{}{map[string]interface{}{"emoji": "👋"}}, "probability": float64(150)},
+		},
+	}
+	assert.Error(t, ImportModuleData(chatID, BackupModuleReactions, invalid))
+}
+
 func TestExportImportReportsRoundTrip(t *testing.T) {
 	skipIfNoDb(t)
 
@@ -1102,7 +1183,7 @@ func TestClearChatData_SpecificModules(t *testing.T) {
 	t.Cleanup(func() { cleanupBackupChat(t, chatID) })
 
 	// Set up data for multiple modules
-	require.NoError(t, filters.AddFilter(chatID, "hello", "hi", "", nil, db.TEXT))
+	require.NoError(t, filters.AddFilter(chatID, "hello", "hi", "", nil, nil, db.TEXT))
 	require.NoError(t, antiflood.SetFlood(chatID, 5))
 	rules.SetChatRules(chatID, "rules text")
 
@@ -1122,7 +1203,7 @@ func TestClearChatData_AllModules(t *testing.T) {
 	t.Cleanup(func() { cleanupBackupChat(t, chatID) })
 
 	// Set up data
-	require.NoError(t, filters.AddFilter(chatID, "hello", "hi", "", nil, db.TEXT))
+	require.NoError(t, filters.AddFilter(chatID, "hello", "hi", "", nil, nil, db.TEXT))
 	require.NoError(t, blacklists.AddBlacklist(chatID, "bad"))
 	require.NoError(t, antiflood.SetFlood(chatID, 5))
 	rules.SetChatRules(chatID, "rules text")
@@ -1166,7 +1247,7 @@ func TestClearModuleData_IndividualModules(t *testing.T) {
 	t.Cleanup(func() { cleanupBackupChat(t, chatID) })
 
 	// --- Filters ---
-	require.NoError(t, filters.AddFilter(chatID, "f", "r", "", nil, db.TEXT))
+	require.NoError(t, filters.AddFilter(chatID, "f", "r", "", nil, nil, db.TEXT))
 	require.NoError(t, ClearModuleData(chatID, BackupModuleFilters))
 	assert.Empty(t, filters.GetFiltersList(chatID))
 
@@ -1176,7 +1257,7 @@ func TestClearModuleData_IndividualModules(t *testing.T) {
 	assert.Empty(t, blacklists.GetBlacklistSettings(chatID))
 
 	// --- Notes ---
-	require.NoError(t, notes.AddNote(chatID, "n1", "c1", "", nil, db.TEXT, false, false, false, true, false, false))
+	require.NoError(t, notes.AddNote(chatID, "n1", "", "c1", "", nil, nil, db.TEXT, false, false, false, true, false, false))
 	require.NoError(t, ClearModuleData(chatID, BackupModuleNotes))
 	assert.Empty(t, notes.GetNotesList(chatID, true))
 
@@ -1308,7 +1389,7 @@ func TestExportChatData_Full(t *testing.T) {
 	// Populate multiple modules
 	require.NoError(t, admin.SetAnonAdminMode(chatID, true))
 	require.NoError(t, antiflood.SetFlood(chatID, 4))
-	require.NoError(t, filters.AddFilter(chatID, "hi", "hello", "", nil, db.TEXT))
+	require.NoError(t, filters.AddFilter(chatID, "hi", "hello", "", nil, nil, db.TEXT))
 	rules.SetChatRules(chatID, "Be kind")
 	require.NoError(t, captcha.SetCaptchaEnabled(chatID, true))
 
@@ -1339,7 +1420,7 @@ func TestExportChatData_EmptyModulesExportsAll(t *testing.T) {
 	t.Cleanup(func() { cleanupBackupChat(t, chatID) })
 
 	require.NoError(t, antiflood.SetFlood(chatID, 2))
-	require.NoError(t, filters.AddFilter(chatID, "a", "b", "", nil, db.TEXT))
+	require.NoError(t, filters.AddFilter(chatID, "a", "b", "", nil, nil, db.TEXT))
 
 	backup, err := ExportChatData(chatID, "Test", 1, nil)
 	require.NoError(t, err)
@@ -203,6 +203,11 @@ func TestBackupFormat_IsCompatibleVersion(t *testing.T) {
 			version: "",
 			want:    false,
 		},
+		{
+			name:    "previous version remains compatible",
+			version: previousFormatVersion,
+			want:    true,
+		},
 		{
 			name:    "legacy version remains compatible",
 			version: legacyFormatVersion,
@@ -353,6 +358,7 @@ func TestAllExportableModules(t *testing.T) {
 
 	expected := []string{
 		BackupModuleAdmin,
+		BackupModuleAliases,
 		BackupModuleAntiflood,
 		BackupModuleAntiraid,
 		BackupModuleApprovals,
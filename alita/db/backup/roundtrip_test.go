@@ -33,6 +33,7 @@ func TestAllModulesRoundTripEveryMeaningfulField(t *testing.T) {
 
 	buttons := models.ButtonArray{{Name: "docs", Url: "https://example.com", SameLine: true}}
 	require.NoError(t, db.DB.Create(&models.AdminSettings{ChatId: srcChat, AnonAdmin: true}).Error)
+	require.NoError(t, db.DB.Create(&models.CommandAlias{ChatID: srcChat, Alias: "regeln", Target: "rules"}).Error)
 	require.NoError(t, db.DB.Create(&models.AntifloodSettings{
 		ChatId:                 srcChat,
 		Limit:                  9,
@@ -47,6 +48,7 @@ func TestAllModulesRoundTripEveryMeaningfulField(t *testing.T) {
 	}).Error)
 	require.NoError(t, db.DB.Create(&models.ApprovedUsers{
 		ChatID: srcChat, UserID: 101, ApprovedBy: 202, Reason: "trusted",
+		Scopes: models.StringArray{models.ApprovalScopeLocks, models.ApprovalScopeFlood},
 	}).Error)
 	require.NoError(t, db.DB.Create(&models.BlacklistSettings{
 		ChatId: srcChat, Word: "scam", Action: "tban", Reason: "custom reason",
@@ -70,6 +72,9 @@ func TestAllModulesRoundTripEveryMeaningfulField(t *testing.T) {
 	require.NoError(t, db.DB.Model(&models.DisableSettings{}).
 		Where("chat_id = ?", srcChat).
 		Update("disabled", false).Error)
+	require.NoError(t, db.DB.Create(&models.CommandPermission{
+		ChatId: srcChat, Command: "warn", Level: models.CommandPermAdmins, RequiredRight: models.CommandRightRestrict,
+	}).Error)
 	require.NoError(t, db.DB.Create(&models.ChatFilters{
 		ChatId: srcChat, KeyWord: "hello", FilterReply: "world", MsgType: 2,
 		FileID: "filter-file", NoNotif: true, Buttons: buttons,
@@ -106,7 +111,11 @@ func TestAllModulesRoundTripEveryMeaningfulField(t *testing.T) {
 		ChatId: srcChat, MsgId: 4242, CleanLinked: true, AntiChannelPin: true,
 	}).Error)
 	require.NoError(t, db.DB.Create(&models.Reactions{
-		ChatID: srcChat, Keyword: "nice", Emoji: "🔥",
+		ChatID: srcChat, Keyword: "nice", Probability: 40, CooldownSeconds: 600,
+		Emojis: models.ReactionEmojiArray{{Emoji: "🔥"}, {Emoji: "👍", CustomEmojiID: "5368324170671202286"}},
+	}).Error)
+	require.NoError(t, db.DB.Create(&models.ReactionAction{
+		ChatID: srcChat, Emoji: "🤬", Action: "dmute", DurationSeconds: 3600,
 	}).Error)
 	require.NoError(t, db.DB.Create(&models.ReportChatSettings{
 		ChatId: srcChat, Enabled: true, Status: true, BlockedList: models.Int64Array{303, 404},
@@ -126,7 +135,10 @@ func TestAllModulesRoundTripEveryMeaningfulField(t *testing.T) {
 
 	// Existing destination data must be replaced, not merged.
 	require.NoError(t, db.DB.Create(&models.Reactions{
-		ChatID: dstChat, Keyword: "stale", Emoji: "❌",
+		ChatID: dstChat, Keyword: "stale", Probability: 100, Emojis: models.ReactionEmojiArray{{Emoji: "❌"}},
+	}).Error)
+	require.NoError(t, db.DB.Create(&models.ReactionAction{
+		ChatID: dstChat, Emoji: "💩", Action: "delete",
 	}).Error)
 	require.NoError(t, db.DB.Create(&models.ChatFilters{
 		ChatId: dstChat, KeyWord: "stale", FilterReply: "stale",
@@ -150,6 +162,12 @@ func TestAllModulesRoundTripEveryMeaningfulField(t *testing.T) {
 	require.NotNil(t, adminData.AdminSettings)
 	assert.True(t, adminData.AdminSettings.AnonAdmin)
 
+	aliasesData, err := exportAliasesData(dstChat)
+	require.NoError(t, err)
+	require.Len(t, aliasesData.Aliases, 1)
+	assert.Equal(t, "regeln", aliasesData.Aliases[0].Alias)
+	assert.Equal(t, "rules", aliasesData.Aliases[0].Target)
+
 	antifloodData, err := exportAntifloodData(dstChat)
 	require.NoError(t, err)
 	require.NotNil(t, antifloodData.Settings)
@@ -170,6 +188,7 @@ func TestAllModulesRoundTripEveryMeaningfulField(t *testing.T) {
 	assert.Equal(t, int64(101), approvalsData.ApprovedUsers[0].UserID)
 	assert.Equal(t, int64(202), approvalsData.ApprovedUsers[0].ApprovedBy)
 	assert.Equal(t, "trusted", approvalsData.ApprovedUsers[0].Reason)
+	assert.Equal(t, models.StringArray{models.ApprovalScopeLocks, models.ApprovalScopeFlood}, approvalsData.ApprovedUsers[0].Scopes)
 
 	blacklistsData, err := exportBlacklistsData(dstChat)
 	require.NoError(t, err)
@@ -199,6 +218,9 @@ func TestAllModulesRoundTripEveryMeaningfulField(t *testing.T) {
 	require.Len(t, disablingData.Commands, 1)
 	assert.Equal(t, "ban", disablingData.Commands[0].Command)
 	assert.False(t, disablingData.Commands[0].Disabled)
+	require.Len(t, disablingData.Permissions, 1)
+	assert.Equal(t, "warn", disablingData.Permissions[0].Command)
+	assert.Equal(t, "admins:can_restrict", disablingData.Permissions[0].Policy())
 
 	filtersData, err := exportFiltersData(dstChat)
 	require.NoError(t, err)
@@ -267,7 +289,13 @@ func TestAllModulesRoundTripEveryMeaningfulField(t *testing.T) {
 	require.NoError(t, err)
 	require.Len(t, reactionsData.Reactions, 1)
 	assert.Equal(t, "nice", reactionsData.Reactions[0].Keyword)
-	assert.Equal(t, "🔥", reactionsData.Reactions[0].Emoji)
+	assert.Equal(t, models.ReactionEmojiArray{{Emoji: "🔥"}, {Emoji: "👍", CustomEmojiID: "5368324170671202286"}}, reactionsData.Reactions[0].Emojis)
+	assert.Equal(t, 40, reactionsData.Reactions[0].Probability)
+	assert.Equal(t, 600, reactionsData.Reactions[0].CooldownSeconds)
+	require.Len(t, reactionsData.Moderation, 1)
+	assert.Equal(t, "🤬", reactionsData.Moderation[0].Emoji)
+	assert.Equal(t, "dmute", reactionsData.Moderation[0].Action)
+	assert.Equal(t, 3600, reactionsData.Moderation[0].DurationSeconds)
 
 	reportsData, err := exportReportsData(dstChat)
 	require.NoError(t, err)
@@ -53,10 +53,12 @@ func TestMain(m *testing.M) {
 			&models.DevSettings{},
 			&models.ChannelSettings{},
 			&models.AntifloodSettings{},
+			&models.AntifloodTopicLimit{},
 			&models.ConnectionSettings{},
 			&models.ConnectionChatSettings{},
 			&models.DisableSettings{},
 			&models.DisableChatSettings{},
+			&models.CommandPermission{},
 			&models.RulesSettings{},
 			&models.LockSettings{},
 			&models.NotesSettings{},
@@ -68,6 +70,29 @@ func TestMain(m *testing.M) {
 			&models.ApprovedUsers{},
 			&models.AntiRaidSettings{},
 			&models.Reactions{},
+			&models.CommandAlias{},
+			&models.NoteRevision{},
+			&models.FilterRevision{},
+			&models.JoinRequest{},
+			&models.JoinQuestion{},
+			&models.JoinApplication{},
+			&models.CaptchaQuestion{},
+			&models.CaptchaEvent{},
+			&models.ChatActivityHour{},
+			&models.ChatActivityUser{},
+			&models.AntispamSettings{},
+			&models.SpamModel{},
+			&models.SpamToken{},
+			&models.ForumTopicSettings{},
+			&models.ForumTopic{},
+			&models.ReactionAction{},
+			&models.StarboardSettings{},
+			&models.StarboardEntry{},
+			&models.KarmaSettings{},
+			&models.KarmaUser{},
+			&models.KarmaVote{},
+			&models.TrustSettings{},
+			&models.TrustMember{},
 		)
 		if err != nil {
 			fmt.Printf("AutoMigrate failed: %v\n", err)
@@ -12,8 +12,11 @@ import (
 
 const (
 	// BackupFormatVersion is the current backup format version.
-	BackupFormatVersion = "1.1"
-	legacyFormatVersion = "1.0"
+	BackupFormatVersion = "1.2"
+	// previousFormatVersion backups predate media albums in notes and
+	// filters and import unchanged.
+	previousFormatVersion = "1.1"
+	legacyFormatVersion   = "1.0"
 )
 
 // BackupFormat represents the structure of an exported backup file
@@ -75,7 +78,7 @@ func (b *BackupFormat) Validate() error {
 
 // IsCompatibleVersion checks if the backup version is compatible
 func (b *BackupFormat) IsCompatibleVersion() bool {
-	return b.Version == BackupFormatVersion || b.Version == legacyFormatVersion
+	return b.Version == BackupFormatVersion || b.Version == previousFormatVersion || b.Version == legacyFormatVersion
 }
 
 // ToJSON marshals the backup format to JSON bytes
@@ -100,6 +103,7 @@ func BackupFormatFromJSON(data []byte) (*BackupFormat, error) {
 // Module names for export/import
 const (
 	BackupModuleAdmin       = "admin"
+	BackupModuleAliases     = "aliases"
 	BackupModuleAntiflood   = "antiflood"
 	BackupModuleAntiraid    = "antiraid"
 	BackupModuleApprovals   = "approvals"
@@ -122,6 +126,7 @@ const (
 func AllExportableModules() []string {
 	return []string{
 		BackupModuleAdmin,
+		BackupModuleAliases,
 		BackupModuleAntiflood,
 		BackupModuleAntiraid,
 		BackupModuleApprovals,
@@ -175,7 +180,8 @@ type AdminBackup struct {
 
 // AntifloodBackup represents antiflood settings backup data
 type AntifloodBackup struct {
-	Settings *models.AntifloodSettings `json:"settings,omitempty"`
+	Settings    *models.AntifloodSettings    `json:"settings,omitempty"`
+	TopicLimits []models.AntifloodTopicLimit `json:"topic_limits,omitempty"`
 }
 
 // BlacklistsBackup represents blacklist settings and entries backup data
@@ -187,7 +193,8 @@ type BlacklistsBackup struct {
 
 // CaptchaBackup represents captcha settings backup data
 type CaptchaBackup struct {
-	Settings *models.CaptchaSettings `json:"settings,omitempty"`
+	Settings  *models.CaptchaSettings  `json:"settings,omitempty"`
+	Questions []models.CaptchaQuestion `json:"questions,omitempty"`
 }
 
 // ConnectionsBackup represents connection settings backup data
@@ -199,6 +206,7 @@ type ConnectionsBackup struct {
 type DisablingBackup struct {
 	ChatSettings *models.DisableChatSettings `json:"chat_settings,omitempty"`
 	Commands     []models.DisableSettings    `json:"commands,omitempty"`
+	Permissions  []models.CommandPermission  `json:"permissions,omitempty"`
 }
 
 // FiltersBackup represents filters backup data
@@ -253,7 +261,22 @@ type ApprovalsBackup struct {
 	ApprovedUsers []models.ApprovedUsers `json:"approved_users,omitempty"`
 }
 
-// ReactionsBackup represents keyword reaction mappings.
+// AliasesBackup represents per-chat command aliases.
+type AliasesBackup struct {
+	Aliases []models.CommandAlias `json:"aliases,omitempty"`
+}
+
+// ReactionsBackup represents keyword reaction rules and the moderation
+// actions mapped to reactions.
 type ReactionsBackup struct {
-	Reactions []models.Reactions `json:"reactions,omitempty"`
+	Reactions  []models.Reactions      `json:"reactions,omitempty"`
+	Moderation []models.ReactionAction `json:"moderation,omitempty"`
+}
+
+// legacyReactionsBackup reads the single emoji per keyword of backups made
+// before reaction rules could hold several reactions.
+type legacyReactionsBackup struct {
+	Reactions []struct {
+		Emoji string `json:"emoji"`
+	} `json:"reactions,omitempty"`
 }
@@ -20,4 +20,8 @@ const (
 	CacheTTLAntiRaid        = 30 * time.Minute
 	CacheTTLChannels        = 30 * time.Minute
 	CacheTTLReactions       = 30 * time.Minute
+	CacheTTLAliases         = 30 * time.Minute
+	CacheTTLAntispam        = 30 * time.Minute
+	CacheTTLInlineResults   = 5 * time.Minute
+	CacheTTLJoinAnswering   = 30 * time.Minute
 )
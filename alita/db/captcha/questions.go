@@ -0,0 +1,76 @@
+package captcha
+
+import (
+	"errors"
+
+	log "github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+
+	"github.com/divkix/Alita_Robot/alita/db"
+	"github.com/divkix/Alita_Robot/alita/db/models"
+)
+
+// ErrTooManyCaptchaQuestions is returned when a chat already has the most
+// custom captcha questions allowed.
+var ErrTooManyCaptchaQuestions = errors.New("TOO_MANY_CAPTCHA_QUESTIONS")
+
+// GetCaptchaQuestions returns the custom captcha questions of a chat in the
+// order they were added. Returns an empty slice if there are none or an
+// error occurs.
+func GetCaptchaQuestions(chatID int64) []*models.CaptchaQuestion {
+	var questions []*models.CaptchaQuestion
+	if err := db.DB.Where("chat_id = ?", chatID).Order("id ASC").Find(&questions).Error; err != nil {
+		log.Errorf("[Database][GetCaptchaQuestions]: %d - %v", chatID, err)
+		return []*models.CaptchaQuestion{}
+	}
+	return questions
+}
+
+// AddCaptchaQuestion adds a custom captcha question with its answer and the
+// wrong answers shown next to it. Returns ErrTooManyCaptchaQuestions once the
+// chat has models.MaxCaptchaQuestions questions.
+func AddCaptchaQuestion(chatID int64, question, answer string, wrongAnswers []string) error {
+	err := db.DB.Transaction(func(tx *gorm.DB) error {
+		var count int64
+		if err := tx.Model(&models.CaptchaQuestion{}).Where("chat_id = ?", chatID).Count(&count).Error; err != nil {
+			return err
+		}
+		if count >= models.MaxCaptchaQuestions {
+			return ErrTooManyCaptchaQuestions
+		}
+		return tx.Create(&models.CaptchaQuestion{
+			ChatID:       chatID,
+			Question:     question,
+			Answer:       answer,
+			WrongAnswers: models.StringArray(wrongAnswers),
+		}).Error
+	})
+	if err != nil && !errors.Is(err, ErrTooManyCaptchaQuestions) {
+		log.Errorf("[Database][AddCaptchaQuestion]: %d - %v", chatID, err)
+	}
+	return err
+}
+
+// RemoveCaptchaQuestion deletes the n-th custom captcha question (1-based)
+// of a chat. Returns false if the chat has no such question.
+func RemoveCaptchaQuestion(chatID int64, n int) (bool, error) {
+	questions := GetCaptchaQuestions(chatID)
+	if n < 1 || n > len(questions) {
+		return false, nil
+	}
+	result := db.DB.Where("id = ? AND chat_id = ?", questions[n-1].ID, chatID).Delete(&models.CaptchaQuestion{})
+	if result.Error != nil {
+		log.Errorf("[Database][RemoveCaptchaQuestion]: %d - %v", chatID, result.Error)
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// ClearCaptchaQuestions deletes every custom captcha question of a chat.
+func ClearCaptchaQuestions(chatID int64) error {
+	err := db.DB.Where("chat_id = ?", chatID).Delete(&models.CaptchaQuestion{}).Error
+	if err != nil {
+		log.Errorf("[Database][ClearCaptchaQuestions]: %d - %v", chatID, err)
+	}
+	return err
+}
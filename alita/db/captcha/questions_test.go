@@ -0,0 +1,103 @@
+package captcha
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/divkix/Alita_Robot/alita/db"
+	dbmodels "github.com/divkix/Alita_Robot/alita/db/models"
+)
+
+func TestCaptchaQuestionsAddListRemoveClear(t *testing.T) {
+	skipIfNoDb(t)
+
+	chatID := time.Now().UnixNano()
+	ensureCaptchaParents(t, chatID+1, chatID)
+	t.Cleanup(func() {
+		_ = db.DB.Where("chat_id = ?", chatID).Delete(&dbmodels.CaptchaQuestion{}).Error
+	})
+
+	if got := GetCaptchaQuestions(chatID); len(got) != 0 {
+		t.Fatalf("GetCaptchaQuestions(empty) = %d questions, want 0", len(got))
+	}
+	for i := 1; i <= 3; i++ {
+		if err := AddCaptchaQuestion(chatID, fmt.Sprintf("q%d", i), fmt.Sprintf("a%d", i), []string{"w1", "w2"}); err != nil {
+			t.Fatalf("AddCaptchaQuestion(%d) error = %v", i, err)
+		}
+	}
+	questions := GetCaptchaQuestions(chatID)
+	if len(questions) != 3 || questions[0].Question != "q1" || questions[2].Answer != "a3" {
+		t.Fatalf("GetCaptchaQuestions() = %+v, want q1..q3 in order", questions)
+	}
+	if len(questions[1].WrongAnswers) != 2 || questions[1].WrongAnswers[1] != "w2" {
+		t.Fatalf("WrongAnswers = %v, want [w1 w2]", questions[1].WrongAnswers)
+	}
+
+	removed, err := RemoveCaptchaQuestion(chatID, 2)
+	if err != nil || !removed {
+		t.Fatalf("RemoveCaptchaQuestion(2) = %v, %v; want true, nil", removed, err)
+	}
+	questions = GetCaptchaQuestions(chatID)
+	if len(questions) != 2 || questions[1].Question != "q3" {
+		t.Fatalf("questions after remove = %+v, want q1 and q3", questions)
+	}
+	for _, n := range []int{0, 3} {
+		if removed, err := RemoveCaptchaQuestion(chatID, n); err != nil || removed {
+			t.Fatalf("RemoveCaptchaQuestion(%d) = %v, %v; want false, nil", n, removed, err)
+		}
+	}
+
+	if err := ClearCaptchaQuestions(chatID); err != nil {
+		t.Fatalf("ClearCaptchaQuestions() error = %v", err)
+	}
+	if got := GetCaptchaQuestions(chatID); len(got) != 0 {
+		t.Fatalf("GetCaptchaQuestions(after clear) = %d questions, want 0", len(got))
+	}
+}
+
+func TestAddCaptchaQuestionEnforcesLimit(t *testing.T) {
+	skipIfNoDb(t)
+
+	chatID := time.Now().UnixNano()
+	ensureCaptchaParents(t, chatID+1, chatID)
+	t.Cleanup(func() {
+		_ = db.DB.Where("chat_id = ?", chatID).Delete(&dbmodels.CaptchaQuestion{}).Error
+	})
+
+	for i := 0; i < dbmodels.MaxCaptchaQuestions; i++ {
+		if err := AddCaptchaQuestion(chatID, fmt.Sprintf("q%d", i), "a", nil); err != nil {
+			t.Fatalf("AddCaptchaQuestion(%d) error = %v", i, err)
+		}
+	}
+	if err := AddCaptchaQuestion(chatID, "one too many", "a", nil); !errors.Is(err, ErrTooManyCaptchaQuestions) {
+		t.Fatalf("AddCaptchaQuestion(over limit) error = %v, want %v", err, ErrTooManyCaptchaQuestions)
+	}
+}
+
+func TestSetCaptchaModeAcceptsEveryMode(t *testing.T) {
+	skipIfNoDb(t)
+
+	chatID := time.Now().UnixNano()
+	ensureCaptchaParents(t, chatID+1, chatID)
+	t.Cleanup(func() {
+		_ = db.DB.Where("chat_id = ?", chatID).Delete(&dbmodels.CaptchaSettings{}).Error
+	})
+
+	for _, mode := range dbmodels.CaptchaModes {
+		if err := SetCaptchaMode(chatID, mode); err != nil {
+			t.Fatalf("SetCaptchaMode(%q) error = %v", mode, err)
+		}
+		settings, err := GetCaptchaSettings(chatID)
+		if err != nil {
+			t.Fatalf("GetCaptchaSettings() error = %v", err)
+		}
+		if settings.CaptchaMode != mode {
+			t.Fatalf("CaptchaMode = %q, want %q", settings.CaptchaMode, mode)
+		}
+	}
+	if err := SetCaptchaMode(chatID, "puzzle"); err == nil {
+		t.Fatal("SetCaptchaMode(puzzle) error = nil, want invalid mode error")
+	}
+}
@@ -3,6 +3,7 @@ package captcha
 import (
 	"errors"
 	"fmt"
+	"slices"
 	"time"
 
 	"github.com/divkix/Alita_Robot/alita/db"
@@ -72,10 +73,10 @@ func SetCaptchaEnabled(chatID int64, enabled bool) error {
 	return nil
 }
 
-// SetCaptchaMode sets the captcha mode (math or text) for a chat.
+// SetCaptchaMode sets the captcha mode (one of models.CaptchaModes) for a chat.
 // Creates settings record if it doesn't exist.
 func SetCaptchaMode(chatID int64, mode string) error {
-	if mode != "math" && mode != "text" {
+	if !slices.Contains(models.CaptchaModes, mode) {
 		return ErrInvalidCaptchaMode
 	}
 
@@ -0,0 +1,92 @@
+package captcha
+
+import (
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+
+	"github.com/divkix/Alita_Robot/alita/db"
+	"github.com/divkix/Alita_Robot/alita/db/models"
+)
+
+// CaptchaStats sums the captcha outcomes of a chat over a time window.
+type CaptchaStats struct {
+	Solved       int64
+	Failed       int64
+	Timeout      int64
+	Left         int64
+	WrongAnswers int64
+	Refreshes    int64
+	SolveSeconds int64 // total over the solved attempts
+}
+
+// Total returns how many captcha attempts finished in the window.
+func (s CaptchaStats) Total() int64 {
+	return s.Solved + s.Failed + s.Timeout + s.Left
+}
+
+// AvgSolveSeconds returns the mean time solved attempts took, or 0 if none
+// were solved.
+func (s CaptchaStats) AvgSolveSeconds() int64 {
+	if s.Solved == 0 {
+		return 0
+	}
+	return s.SolveSeconds / s.Solved
+}
+
+// RecordCaptchaEvent stores the outcome of a finished captcha attempt.
+// Outcomes of the chat older than models.CaptchaEventRetention are pruned on
+// the way.
+func RecordCaptchaEvent(event *models.CaptchaEvent) error {
+	err := db.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("chat_id = ? AND created_at < ?", event.ChatID, time.Now().UTC().Add(-models.CaptchaEventRetention)).
+			Delete(&models.CaptchaEvent{}).Error; err != nil {
+			return err
+		}
+		return tx.Create(event).Error
+	})
+	if err != nil {
+		log.Errorf("[Database][RecordCaptchaEvent]: %d - %v", event.ChatID, err)
+	}
+	return err
+}
+
+// GetCaptchaStats sums the captcha outcomes of a chat recorded since the
+// given time.
+func GetCaptchaStats(chatID int64, since time.Time) (*CaptchaStats, error) {
+	var rows []struct {
+		Outcome      string
+		Count        int64
+		WrongAnswers int64
+		Refreshes    int64
+		SolveSeconds int64
+	}
+	err := db.DB.Model(&models.CaptchaEvent{}).
+		Select("outcome, COUNT(*) AS count, COALESCE(SUM(wrong_answers), 0) AS wrong_answers, COALESCE(SUM(refreshes), 0) AS refreshes, COALESCE(SUM(solve_seconds), 0) AS solve_seconds").
+		Where("chat_id = ? AND created_at >= ?", chatID, since).
+		Group("outcome").
+		Scan(&rows).Error
+	if err != nil {
+		log.Errorf("[Database][GetCaptchaStats]: %d - %v", chatID, err)
+		return nil, err
+	}
+
+	stats := &CaptchaStats{}
+	for _, row := range rows {
+		switch row.Outcome {
+		case models.CaptchaOutcomeSolved:
+			stats.Solved = row.Count
+			stats.SolveSeconds = row.SolveSeconds
+		case models.CaptchaOutcomeFailed:
+			stats.Failed = row.Count
+		case models.CaptchaOutcomeTimeout:
+			stats.Timeout = row.Count
+		case models.CaptchaOutcomeLeft:
+			stats.Left = row.Count
+		}
+		stats.WrongAnswers += row.WrongAnswers
+		stats.Refreshes += row.Refreshes
+	}
+	return stats, nil
+}
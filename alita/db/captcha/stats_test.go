@@ -0,0 +1,93 @@
+package captcha
+
+import (
+	"testing"
+	"time"
+
+	"github.com/divkix/Alita_Robot/alita/db"
+	dbmodels "github.com/divkix/Alita_Robot/alita/db/models"
+)
+
+func TestRecordCaptchaEventAndGetCaptchaStats(t *testing.T) {
+	skipIfNoDb(t)
+
+	chatID := time.Now().UnixNano()
+	ensureCaptchaParents(t, chatID+1, chatID)
+	t.Cleanup(func() {
+		_ = db.DB.Where("chat_id = ?", chatID).Delete(&dbmodels.CaptchaEvent{}).Error
+	})
+
+	now := time.Now().UTC()
+	events := []dbmodels.CaptchaEvent{
+		{Outcome: dbmodels.CaptchaOutcomeSolved, SolveSeconds: 10, Refreshes: 1},
+		{Outcome: dbmodels.CaptchaOutcomeSolved, SolveSeconds: 20, WrongAnswers: 1},
+		{Outcome: dbmodels.CaptchaOutcomeFailed, WrongAnswers: 3},
+		{Outcome: dbmodels.CaptchaOutcomeTimeout},
+		{Outcome: dbmodels.CaptchaOutcomeLeft, CreatedAt: now.Add(-3 * 24 * time.Hour)},
+	}
+	for i := range events {
+		events[i].ChatID = chatID
+		events[i].UserID = chatID + 1
+		events[i].Mode = "math"
+		if err := RecordCaptchaEvent(&events[i]); err != nil {
+			t.Fatalf("RecordCaptchaEvent(%d) error = %v", i, err)
+		}
+	}
+
+	day, err := GetCaptchaStats(chatID, now.Add(-24*time.Hour))
+	if err != nil {
+		t.Fatalf("GetCaptchaStats(24h) error = %v", err)
+	}
+	want := CaptchaStats{Solved: 2, Failed: 1, Timeout: 1, WrongAnswers: 4, Refreshes: 1, SolveSeconds: 30}
+	if *day != want {
+		t.Fatalf("GetCaptchaStats(24h) = %+v, want %+v", *day, want)
+	}
+	if day.Total() != 4 || day.AvgSolveSeconds() != 15 {
+		t.Fatalf("Total() = %d, AvgSolveSeconds() = %d; want 4, 15", day.Total(), day.AvgSolveSeconds())
+	}
+
+	week, err := GetCaptchaStats(chatID, now.Add(-7*24*time.Hour))
+	if err != nil {
+		t.Fatalf("GetCaptchaStats(7d) error = %v", err)
+	}
+	if week.Left != 1 || week.Total() != 5 {
+		t.Fatalf("GetCaptchaStats(7d) = %+v, want the left member counted", *week)
+	}
+}
+
+func TestRecordCaptchaEventPrunesExpiredEvents(t *testing.T) {
+	skipIfNoDb(t)
+
+	chatID := time.Now().UnixNano()
+	ensureCaptchaParents(t, chatID+1, chatID)
+	t.Cleanup(func() {
+		_ = db.DB.Where("chat_id = ?", chatID).Delete(&dbmodels.CaptchaEvent{}).Error
+	})
+
+	old := &dbmodels.CaptchaEvent{
+		ChatID:    chatID,
+		UserID:    chatID + 1,
+		Mode:      "math",
+		Outcome:   dbmodels.CaptchaOutcomeTimeout,
+		CreatedAt: time.Now().UTC().Add(-dbmodels.CaptchaEventRetention - time.Hour),
+	}
+	if err := db.DB.Create(old).Error; err != nil {
+		t.Fatalf("create expired event: %v", err)
+	}
+	if err := RecordCaptchaEvent(&dbmodels.CaptchaEvent{
+		ChatID:  chatID,
+		UserID:  chatID + 1,
+		Mode:    "math",
+		Outcome: dbmodels.CaptchaOutcomeSolved,
+	}); err != nil {
+		t.Fatalf("RecordCaptchaEvent() error = %v", err)
+	}
+
+	var count int64
+	if err := db.DB.Model(&dbmodels.CaptchaEvent{}).Where("chat_id = ?", chatID).Count(&count).Error; err != nil {
+		t.Fatalf("count events: %v", err)
+	}
+	if count != 1 {
+		t.Fatalf("events after pruning = %d, want 1", count)
+	}
+}
@@ -50,6 +50,8 @@ func TestMain(m *testing.M) {
 			&models.CaptchaAttempts{},
 			&models.StoredMessages{},
 			&models.CaptchaMutedUsers{},
+			&models.CaptchaQuestion{},
+			&models.CaptchaEvent{},
 		); err != nil {
 			fmt.Printf("AutoMigrate failed: %v\n", err)
 			os.Exit(1)
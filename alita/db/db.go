@@ -21,6 +21,8 @@ type (
 	ButtonArray            = models.ButtonArray
 	StringArray            = models.StringArray
 	Int64Array             = models.Int64Array
+	MediaItem              = models.MediaItem
+	MediaItemArray         = models.MediaItemArray
 	User                   = models.User
 	Chat                   = models.Chat
 	WarnSettings           = models.WarnSettings
@@ -38,10 +40,12 @@ type (
 	DevSettings            = models.DevSettings
 	ChannelSettings        = models.ChannelSettings
 	AntifloodSettings      = models.AntifloodSettings
+	AntifloodTopicLimit    = models.AntifloodTopicLimit
 	ConnectionSettings     = models.ConnectionSettings
 	ConnectionChatSettings = models.ConnectionChatSettings
 	DisableSettings        = models.DisableSettings
 	DisableChatSettings    = models.DisableChatSettings
+	CommandPermission      = models.CommandPermission
 	RulesSettings          = models.RulesSettings
 	LockSettings           = models.LockSettings
 	NotesSettings          = models.NotesSettings
@@ -53,6 +57,29 @@ type (
 	CaptchaMutedUsers      = models.CaptchaMutedUsers
 	AntiRaidSettings       = models.AntiRaidSettings
 	Reactions              = models.Reactions
+	ReactionAction         = models.ReactionAction
+	CommandAlias           = models.CommandAlias
+	NoteRevision           = models.NoteRevision
+	FilterRevision         = models.FilterRevision
+	JoinRequest            = models.JoinRequest
+	JoinQuestion           = models.JoinQuestion
+	JoinApplication        = models.JoinApplication
+	CaptchaQuestion        = models.CaptchaQuestion
+	CaptchaEvent           = models.CaptchaEvent
+	ChatActivityHour       = models.ChatActivityHour
+	ChatActivityUser       = models.ChatActivityUser
+	AntispamSettings       = models.AntispamSettings
+	SpamModel              = models.SpamModel
+	SpamToken              = models.SpamToken
+	ForumTopicSettings     = models.ForumTopicSettings
+	ForumTopic             = models.ForumTopic
+	StarboardSettings      = models.StarboardSettings
+	StarboardEntry         = models.StarboardEntry
+	KarmaSettings          = models.KarmaSettings
+	KarmaUser              = models.KarmaUser
+	KarmaVote              = models.KarmaVote
+	TrustSettings          = models.TrustSettings
+	TrustMember            = models.TrustMember
 )
 
 // Message type constants - maintain compatibility with existing code
@@ -1,7 +1,9 @@
 package disabling
 
 import (
+	"errors"
 	"slices"
+	"strings"
 
 	log "github.com/sirupsen/logrus"
 	"gorm.io/gorm/clause"
@@ -156,3 +158,104 @@ func LoadDisableStats() (disabledCmds, disableEnabledChats int64) {
 
 	return
 }
+
+// Command permission levels, re-exported for callers outside the db layer.
+const (
+	LevelEveryone = models.CommandPermEveryone
+	LevelApproved = models.CommandPermApproved
+	LevelAdmins   = models.CommandPermAdmins
+)
+
+// ParseCommandPolicy parses a policy string such as "everyone", "approved",
+// "admins" or "admins:can_restrict" into its level and required admin right.
+// Returns ok=false when the level or right is unknown.
+func ParseCommandPolicy(input string) (level, right string, ok bool) {
+	level, right, _ = strings.Cut(strings.ToLower(strings.TrimSpace(input)), ":")
+	switch level {
+	case LevelEveryone, LevelApproved:
+		return level, "", right == ""
+	case LevelAdmins:
+		if right == "" {
+			return level, "", true
+		}
+		return level, right, slices.Contains(models.CommandRights, right)
+	}
+	return "", "", false
+}
+
+// SetCommandPermission stores the permission override for a command in a chat.
+// Setting the everyone level removes the override, since that is the default.
+// Invalidates cache to ensure consistency.
+// Returns an error if the database operation fails.
+func SetCommandPermission(chatID int64, cmd, level, right string) error {
+	if level == LevelEveryone {
+		return RemoveCommandPermission(chatID, cmd)
+	}
+
+	perm := &models.CommandPermission{
+		ChatId:        chatID,
+		Command:       cmd,
+		Level:         level,
+		RequiredRight: right,
+	}
+	err := db.DB.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "chat_id"}, {Name: "command"}},
+		DoUpdates: clause.AssignmentColumns([]string{"level", "required_right", "updated_at"}),
+	}).Create(perm).Error
+	if err != nil {
+		log.Errorf("[Database][SetCommandPermission]: %v", err)
+		return err
+	}
+
+	invalidateCommandPermissionsCache(chatID)
+	return nil
+}
+
+// RemoveCommandPermission deletes the permission override for a command,
+// restoring the command's default behaviour in the chat.
+// Returns an error if the database operation fails.
+func RemoveCommandPermission(chatID int64, cmd string) error {
+	err := db.DB.Where("chat_id = ? AND command = ?", chatID, cmd).Delete(&models.CommandPermission{}).Error
+	if err != nil {
+		log.Errorf("[Database][RemoveCommandPermission]: %v", err)
+		return err
+	}
+
+	invalidateCommandPermissionsCache(chatID)
+	return nil
+}
+
+// GetCommandPermissions retrieves all permission overrides for a chat with caching,
+// sorted by command name. Returns an empty slice on error.
+func GetCommandPermissions(chatID int64) []*models.CommandPermission {
+	cacheKey := cache.CacheKey("command_perms", chatID)
+	perms, err := cache.GetFromCacheOrLoad(cacheKey, cache.CacheTTLDisabledCmds, func() ([]*models.CommandPermission, error) {
+		if db.DB == nil {
+			return nil, errors.New("database not initialized")
+		}
+		var perms []*models.CommandPermission
+		err := db.DB.Where("chat_id = ?", chatID).Order("command ASC").Find(&perms).Error
+		return perms, err
+	})
+	if err != nil {
+		log.Errorf("[Database] GetCommandPermissions: %v - %d", err, chatID)
+		return []*models.CommandPermission{}
+	}
+	return perms
+}
+
+// GetCommandPermission returns the permission override for a command in a chat,
+// or nil when the command uses its default behaviour.
+func GetCommandPermission(chatID int64, cmd string) *models.CommandPermission {
+	for _, perm := range GetCommandPermissions(chatID) {
+		if perm.Command == cmd {
+			return perm
+		}
+	}
+	return nil
+}
+
+// invalidateCommandPermissionsCache invalidates the command permissions cache for a specific chat.
+func invalidateCommandPermissionsCache(chatID int64) {
+	cache.DeleteCache(cache.CacheKey("command_perms", chatID))
+}
@@ -272,3 +272,26 @@ func TestDisableSameCommandTwice(t *testing.T) {
 		t.Fatalf("DisableCMD() twice: command %q appears %d times, want 1; list: %v", cmd, count, cmds)
 	}
 }
+
+func TestParseCommandPolicy(t *testing.T) {
+	tests := []struct {
+		input     string
+		wantLevel string
+		wantRight string
+		wantOK    bool
+	}{
+		{"everyone", LevelEveryone, "", true},
+		{"Approved", LevelApproved, "", true},
+		{"admins", LevelAdmins, "", true},
+		{"admins:can_restrict", LevelAdmins, models.CommandRightRestrict, true},
+		{"admins:can_fly", "", "", false},
+		{"approved:can_pin", "", "", false},
+		{"mods", "", "", false},
+	}
+	for _, tc := range tests {
+		level, right, ok := ParseCommandPolicy(tc.input)
+		if ok != tc.wantOK || (ok && (level != tc.wantLevel || right != tc.wantRight)) {
+			t.Fatalf("ParseCommandPolicy(%q) = %q, %q, %v", tc.input, level, right, ok)
+		}
+	}
+}
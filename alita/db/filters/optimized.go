@@ -12,7 +12,7 @@ import (
 
 // GetChatFiltersOptimized retrieves filters with minimal column selection.
 // Optimized for high-frequency calls (34K+ calls) by selecting only essential filter fields.
-// Includes all fields needed by filtersWatcher: keyword, filter_reply, msgtype, fileid, filter_buttons, nonotif.
+// Includes all fields needed by filtersWatcher: keyword, filter_reply, msgtype, fileid, filter_buttons, media, nonotif.
 func GetChatFiltersOptimized(chatID int64) ([]*models.ChatFilters, error) {
 	if db.DB == nil {
 		return nil, errors.New("database not initialized")
@@ -20,7 +20,7 @@ func GetChatFiltersOptimized(chatID int64) ([]*models.ChatFilters, error) {
 
 	var filters []*models.ChatFilters
 	err := db.DB.Model(&models.ChatFilters{}).
-		Select("id, chat_id, keyword, filter_reply, msgtype, fileid, filter_buttons, nonotif").
+		Select("id, chat_id, thread_id, keyword, filter_reply, msgtype, fileid, filter_buttons, media, nonotif").
 		Where("chat_id = ?", chatID).
 		Find(&filters).Error
 	if err != nil {
@@ -70,18 +70,40 @@ func DoesFilterExists(chatId int64, keyword string) bool {
 	return true
 }
 
-// AddFilter creates a filter if its keyword is unused.
+// GetFilterTopic returns the forum topic the filter with the given keyword
+// answers in (0 = the whole chat), and whether such a filter exists.
+func GetFilterTopic(chatID int64, keyword string) (int64, bool) {
+	var filter models.ChatFilters
+	err := db.DB.Select("thread_id").Where("chat_id = ? AND LOWER(keyword) = LOWER(?)", chatID, keyword).Take(&filter).Error
+	if err != nil {
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			log.Errorf("[Database] GetFilterTopic: %v - %d", err, chatID)
+		}
+		return 0, false
+	}
+	return filter.ThreadId, true
+}
+
+// AddFilter creates a chat-wide filter if its keyword is unused.
 // Explicit overwrite confirmation uses UpdateFilter.
-func AddFilter(chatID int64, keyWord, replyText, fileID string, buttons []models.Button, filtType int) error {
+func AddFilter(chatID int64, keyWord, replyText, fileID string, buttons []models.Button, media []models.MediaItem, filtType int) error {
+	return AddTopicFilter(chatID, 0, keyWord, replyText, fileID, buttons, media, filtType)
+}
+
+// AddTopicFilter creates a filter that only answers in the given forum topic
+// if its keyword is unused. A threadID of 0 makes it chat-wide.
+func AddTopicFilter(chatID, threadID int64, keyWord, replyText, fileID string, buttons []models.Button, media []models.MediaItem, filtType int) error {
 	now := time.Now().UTC()
 	newFilter := map[string]any{
 		"chat_id":        chatID,
+		"thread_id":      threadID,
 		"keyword":        keyWord,
 		"filter_reply":   replyText,
 		"msgtype":        filtType,
 		"fileid":         fileID,
 		"nonotif":        false,
 		"filter_buttons": models.ButtonArray(buttons),
+		"media":          models.MediaItemArray(media),
 		"created_at":     now,
 		"updated_at":     now,
 	}
@@ -102,49 +124,83 @@ func AddFilter(chatID int64, keyWord, replyText, fileID string, buttons []models
 }
 
 // UpdateFilter replaces an existing filter without recreating one removed while
-// an overwrite confirmation was pending.
-func UpdateFilter(chatID int64, keyWord, replyText, fileID string, buttons []models.Button, filtType int) (bool, error) {
-	result := db.DB.Model(&models.ChatFilters{}).
-		Where("chat_id = ? AND keyword = ?", chatID, keyWord).
-		Updates(map[string]any{
-			"filter_reply":   replyText,
-			"msgtype":        filtType,
-			"fileid":         fileID,
-			"filter_buttons": models.ButtonArray(buttons),
-			"updated_at":     time.Now().UTC(),
-		})
-	if result.Error != nil {
-		log.Errorf("[Database][UpdateFilter]: %d - %v", chatID, result.Error)
-		return false, result.Error
+// an overwrite confirmation was pending. Keywords are unique per chat, so only a
+// filter already scoped to threadID (0 = the whole chat) is replaced; one in
+// another topic is left alone rather than moved. The previous content is kept
+// as a revision attributed to editorID.
+func UpdateFilter(chatID, editorID, threadID int64, keyWord, replyText, fileID string, buttons []models.Button, media []models.MediaItem, filtType int) (bool, error) {
+	var updated bool
+	err := db.DB.Transaction(func(tx *gorm.DB) error {
+		var sameTopic int64
+		if err := tx.Model(&models.ChatFilters{}).
+			Where("chat_id = ? AND keyword = ? AND thread_id = ?", chatID, keyWord, threadID).
+			Count(&sameTopic).Error; err != nil || sameTopic == 0 {
+			return err
+		}
+		archived, err := archiveFilters(tx, chatID, editorID, models.RevisionActionOverwrite, 0, keyWord)
+		if err != nil || archived == 0 {
+			return err
+		}
+		result := tx.Model(&models.ChatFilters{}).
+			Where("chat_id = ? AND keyword = ? AND thread_id = ?", chatID, keyWord, threadID).
+			Updates(map[string]any{
+				"filter_reply":   replyText,
+				"msgtype":        filtType,
+				"fileid":         fileID,
+				"filter_buttons": models.ButtonArray(buttons),
+				"media":          models.MediaItemArray(media),
+				"updated_at":     time.Now().UTC(),
+			})
+		updated = result.RowsAffected > 0
+		return result.Error
+	})
+	if err != nil {
+		log.Errorf("[Database][UpdateFilter]: %d - %v", chatID, err)
+		return false, err
 	}
-	if result.RowsAffected > 0 {
+	if updated {
 		invalidateFilterCaches(chatID)
 	}
-	return result.RowsAffected > 0, nil
+	return updated, nil
 }
 
-// RemoveFilter deletes a filter with the specified keyword from the chat.
+// RemoveFilter deletes a filter with the specified keyword from the chat,
+// keeping its content as a revision attributed to editorID.
 // Invalidates the filter list cache if a filter was successfully removed.
-func RemoveFilter(chatID int64, keyWord string) error {
-	// Directly attempt to delete the filter without checking existence first
-	result := db.DB.Where("chat_id = ? AND keyword = ?", chatID, keyWord).Delete(&models.ChatFilters{})
-	if result.Error != nil {
-		log.Errorf("[Database][RemoveFilter]: %d - %v", chatID, result.Error)
+func RemoveFilter(chatID, editorID int64, keyWord string) error {
+	var removed bool
+	err := db.DB.Transaction(func(tx *gorm.DB) error {
+		archived, err := archiveFilters(tx, chatID, editorID, models.RevisionActionDelete, 0, keyWord)
+		if err != nil || archived == 0 {
+			return err
+		}
+		result := tx.Where("chat_id = ? AND keyword = ?", chatID, keyWord).Delete(&models.ChatFilters{})
+		removed = result.RowsAffected > 0
 		return result.Error
+	})
+	if err != nil {
+		log.Errorf("[Database][RemoveFilter]: %d - %v", chatID, err)
+		return err
 	}
-	// result.RowsAffected will be 0 if no filter was found, which is fine
 
 	// Invalidate cache after removing filter
-	if result.RowsAffected > 0 {
+	if removed {
 		invalidateFilterCaches(chatID)
 	}
 	return nil
 }
 
 // RemoveAllFilters deletes all filters for the specified chat ID from the database.
+// Every filter is kept as a revision of the same batch, so the whole /stopall
+// can be undone with RestoreClearedFilters.
 // Invalidates the filter list cache after successful removal.
-func RemoveAllFilters(chatID int64) error {
-	err := db.DB.Where("chat_id = ?", chatID).Delete(&models.ChatFilters{}).Error
+func RemoveAllFilters(chatID, editorID int64) error {
+	err := db.DB.Transaction(func(tx *gorm.DB) error {
+		if _, err := archiveFilters(tx, chatID, editorID, models.RevisionActionClearAll, time.Now().UnixNano()); err != nil {
+			return err
+		}
+		return tx.Where("chat_id = ?", chatID).Delete(&models.ChatFilters{}).Error
+	})
 	if err != nil {
 		log.Errorf("[Database][RemoveAllFilters]: %d - %v", chatID, err)
 		return err
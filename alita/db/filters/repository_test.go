@@ -37,7 +37,7 @@ func TestAddAndGetFiltersList(t *testing.T) {
 	chatID := newFilterTestChat(t)
 
 	t.Cleanup(func() {
-		if err := RemoveAllFilters(chatID); err != nil {
+		if err := RemoveAllFilters(chatID, 0); err != nil {
 			t.Errorf("RemoveAllFilters failed: %v", err)
 		}
 	})
@@ -49,10 +49,10 @@ func TestAddAndGetFiltersList(t *testing.T) {
 	}
 
 	// Add two filters
-	if err := AddFilter(chatID, "spam", "spam reply", "", nil, 1); err != nil {
+	if err := AddFilter(chatID, "spam", "spam reply", "", nil, nil, 1); err != nil {
 		t.Fatalf("AddFilter failed: %v", err)
 	}
-	if err := AddFilter(chatID, "flood", "flood reply", "", nil, 1); err != nil {
+	if err := AddFilter(chatID, "flood", "flood reply", "", nil, nil, 1); err != nil {
 		t.Fatalf("AddFilter failed: %v", err)
 	}
 
@@ -62,7 +62,7 @@ func TestAddAndGetFiltersList(t *testing.T) {
 	}
 
 	// Adding the same keyword again must not bypass overwrite confirmation.
-	if err := AddFilter(chatID, "spam", "different reply", "", nil, 2); err != nil {
+	if err := AddFilter(chatID, "spam", "different reply", "", nil, nil, 2); err != nil {
 		t.Fatalf("AddFilter failed: %v", err)
 	}
 	list = GetFiltersList(chatID)
@@ -84,7 +84,7 @@ func TestDoesFilterExists(t *testing.T) {
 	chatID := newFilterTestChat(t)
 
 	t.Cleanup(func() {
-		if err := RemoveAllFilters(chatID); err != nil {
+		if err := RemoveAllFilters(chatID, 0); err != nil {
 			t.Errorf("RemoveAllFilters failed: %v", err)
 		}
 	})
@@ -93,7 +93,7 @@ func TestDoesFilterExists(t *testing.T) {
 		t.Fatal("expected DoesFilterExists=false for non-existent filter")
 	}
 
-	if err := AddFilter(chatID, "hello", "hello reply", "", nil, 1); err != nil {
+	if err := AddFilter(chatID, "hello", "hello reply", "", nil, nil, 1); err != nil {
 		t.Fatalf("AddFilter failed: %v", err)
 	}
 
@@ -113,19 +113,19 @@ func TestRemoveFilter(t *testing.T) {
 	chatID := newFilterTestChat(t)
 
 	t.Cleanup(func() {
-		if err := RemoveAllFilters(chatID); err != nil {
+		if err := RemoveAllFilters(chatID, 0); err != nil {
 			t.Errorf("RemoveAllFilters failed: %v", err)
 		}
 	})
 
-	if err := AddFilter(chatID, "remove_me", "reply", "", nil, 1); err != nil {
+	if err := AddFilter(chatID, "remove_me", "reply", "", nil, nil, 1); err != nil {
 		t.Fatalf("AddFilter failed: %v", err)
 	}
-	if err := AddFilter(chatID, "keep_me", "reply", "", nil, 1); err != nil {
+	if err := AddFilter(chatID, "keep_me", "reply", "", nil, nil, 1); err != nil {
 		t.Fatalf("AddFilter failed: %v", err)
 	}
 
-	if err := RemoveFilter(chatID, "remove_me"); err != nil {
+	if err := RemoveFilter(chatID, 0, "remove_me"); err != nil {
 		t.Fatalf("RemoveFilter failed: %v", err)
 	}
 
@@ -137,7 +137,7 @@ func TestRemoveFilter(t *testing.T) {
 	}
 
 	// Removing non-existent filter should not error
-	if err := RemoveFilter(chatID, "does_not_exist"); err != nil {
+	if err := RemoveFilter(chatID, 0, "does_not_exist"); err != nil {
 		t.Fatalf("RemoveFilter(nonexistent) failed: %v", err)
 	}
 }
@@ -147,17 +147,17 @@ func TestRemoveAllFilters(t *testing.T) {
 
 	chatID := newFilterTestChat(t)
 
-	if err := AddFilter(chatID, "a", "a", "", nil, 1); err != nil {
+	if err := AddFilter(chatID, "a", "a", "", nil, nil, 1); err != nil {
 		t.Fatalf("AddFilter failed: %v", err)
 	}
-	if err := AddFilter(chatID, "b", "b", "", nil, 1); err != nil {
+	if err := AddFilter(chatID, "b", "b", "", nil, nil, 1); err != nil {
 		t.Fatalf("AddFilter failed: %v", err)
 	}
-	if err := AddFilter(chatID, "c", "c", "", nil, 1); err != nil {
+	if err := AddFilter(chatID, "c", "c", "", nil, nil, 1); err != nil {
 		t.Fatalf("AddFilter failed: %v", err)
 	}
 
-	if err := RemoveAllFilters(chatID); err != nil {
+	if err := RemoveAllFilters(chatID, 0); err != nil {
 		t.Fatalf("RemoveAllFilters failed: %v", err)
 	}
 
@@ -173,7 +173,7 @@ func TestCountFilters(t *testing.T) {
 	chatID := newFilterTestChat(t)
 
 	t.Cleanup(func() {
-		if err := RemoveAllFilters(chatID); err != nil {
+		if err := RemoveAllFilters(chatID, 0); err != nil {
 			t.Errorf("RemoveAllFilters failed: %v", err)
 		}
 	})
@@ -183,7 +183,7 @@ func TestCountFilters(t *testing.T) {
 	}
 
 	for i := 0; i < 3; i++ {
-		if err := AddFilter(chatID, fmt.Sprintf("word%d", i), "reply", "", nil, 1); err != nil {
+		if err := AddFilter(chatID, fmt.Sprintf("word%d", i), "reply", "", nil, nil, 1); err != nil {
 			t.Fatalf("AddFilter failed: %v", err)
 		}
 	}
@@ -218,7 +218,7 @@ func TestLoadFilterStatsErrorBranch(t *testing.T) {
 		}
 	})
 
-	if err := AddFilter(1, "missing-table", "reply", "", nil, db.TEXT); err == nil {
+	if err := AddFilter(1, "missing-table", "reply", "", nil, nil, db.TEXT); err == nil {
 		t.Fatal("AddFilter() error = nil after filters table was dropped")
 	}
 	total, chats := LoadFilterStats()
@@ -233,7 +233,7 @@ func TestAddFilterWithButtons(t *testing.T) {
 	chatID := newFilterTestChat(t)
 
 	t.Cleanup(func() {
-		if err := RemoveAllFilters(chatID); err != nil {
+		if err := RemoveAllFilters(chatID, 0); err != nil {
 			t.Errorf("RemoveAllFilters failed: %v", err)
 		}
 	})
@@ -242,7 +242,7 @@ func TestAddFilterWithButtons(t *testing.T) {
 		{Name: "Click me", Url: "https://example.com", SameLine: false},
 	}
 
-	if err := AddFilter(chatID, "btn_filter", "Filter with button", "", buttons, 1); err != nil {
+	if err := AddFilter(chatID, "btn_filter", "Filter with button", "", buttons, nil, 1); err != nil {
 		t.Fatalf("AddFilter failed: %v", err)
 	}
 
@@ -256,7 +256,7 @@ func TestAddFilterConcurrentInsert(t *testing.T) {
 
 	chatID := newFilterTestChat(t)
 	t.Cleanup(func() {
-		_ = RemoveAllFilters(chatID)
+		_ = RemoveAllFilters(chatID, 0)
 	})
 
 	const writers = 16
@@ -266,7 +266,7 @@ func TestAddFilterConcurrentInsert(t *testing.T) {
 		wg.Add(1)
 		go func() {
 			defer wg.Done()
-			errs <- AddFilter(chatID, "shared", "concurrent", "", nil, db.TEXT)
+			errs <- AddFilter(chatID, "shared", "concurrent", "", nil, nil, db.TEXT)
 		}()
 	}
 	wg.Wait()
@@ -277,7 +277,7 @@ func TestAddFilterConcurrentInsert(t *testing.T) {
 		}
 	}
 
-	if err := AddFilter(chatID, "shared", "final", "", nil, db.TEXT); err != nil {
+	if err := AddFilter(chatID, "shared", "final", "", nil, nil, db.TEXT); err != nil {
 		t.Fatalf("final AddFilter() error = %v", err)
 	}
 	var rows []models.ChatFilters
@@ -288,3 +288,102 @@ func TestAddFilterConcurrentInsert(t *testing.T) {
 		t.Fatalf("concurrent insert left filters=%+v", rows)
 	}
 }
+
+func TestFilterRevisionsRestoreOverwriteAndStopAll(t *testing.T) {
+	skipIfNoDb(t)
+
+	chatID := newFilterTestChat(t)
+	t.Cleanup(func() {
+		if err := db.DB.Where("chat_id = ?", chatID).Delete(&models.FilterRevision{}).Error; err != nil {
+			t.Errorf("cleanup FilterRevision failed: %v", err)
+		}
+		if err := db.DB.Where("chat_id = ?", chatID).Delete(&models.ChatFilters{}).Error; err != nil {
+			t.Errorf("cleanup ChatFilters failed: %v", err)
+		}
+	})
+
+	if err := AddFilter(chatID, "hello", "hi there", "", nil, nil, db.TEXT); err != nil {
+		t.Fatalf("AddFilter() error = %v", err)
+	}
+	updated, err := UpdateFilter(chatID, 42, 0, "hello", "go away", "", nil, nil, db.TEXT)
+	if err != nil || !updated {
+		t.Fatalf("UpdateFilter() = %v, %v", updated, err)
+	}
+
+	revisions := GetFilterRevisions(chatID, "hello")
+	if len(revisions) != 1 || revisions[0].FilterReply != "hi there" || revisions[0].EditorId != 42 || revisions[0].Action != models.RevisionActionOverwrite {
+		t.Fatalf("unexpected revisions after overwrite: %+v", revisions)
+	}
+
+	restored, err := RestoreFilterRevision(chatID, 42, "hello", 1)
+	if err != nil || !restored {
+		t.Fatalf("RestoreFilterRevision() = %v, %v", restored, err)
+	}
+	filters, err := GetChatFiltersCached(chatID)
+	if err != nil || len(filters) != 1 || filters[0].FilterReply != "hi there" {
+		t.Fatalf("filters after restore = %+v, %v", filters, err)
+	}
+
+	if err := AddFilter(chatID, "bye", "see you", "", nil, nil, db.TEXT); err != nil {
+		t.Fatalf("AddFilter() error = %v", err)
+	}
+	if err := RemoveAllFilters(chatID, 42); err != nil {
+		t.Fatalf("RemoveAllFilters() error = %v", err)
+	}
+	if len(GetFiltersList(chatID)) != 0 {
+		t.Fatal("expected no filters after RemoveAllFilters")
+	}
+
+	count, err := RestoreClearedFilters(chatID, 42)
+	if err != nil || count != 2 {
+		t.Fatalf("RestoreClearedFilters() = %d, %v; want 2", count, err)
+	}
+	if got := GetFiltersList(chatID); len(got) != 2 {
+		t.Fatalf("filters after RestoreClearedFilters = %v", got)
+	}
+	// The restore consumed nothing: running it again finds every filter present.
+	if count, err := RestoreClearedFilters(chatID, 42); err != nil || count != 0 {
+		t.Fatalf("second RestoreClearedFilters() = %d, %v; want 0", count, err)
+	}
+}
+
+func TestFilterMediaAlbumRoundTrip(t *testing.T) {
+	skipIfNoDb(t)
+
+	chatID := newFilterTestChat(t)
+	t.Cleanup(func() {
+		if err := db.DB.Where("chat_id = ?", chatID).Delete(&models.FilterRevision{}).Error; err != nil {
+			t.Errorf("cleanup FilterRevision failed: %v", err)
+		}
+		if err := db.DB.Where("chat_id = ?", chatID).Delete(&models.ChatFilters{}).Error; err != nil {
+			t.Errorf("cleanup ChatFilters failed: %v", err)
+		}
+	})
+
+	album := []models.MediaItem{
+		{FileID: "video-1", MsgType: db.VIDEO, Caption: "first"},
+		{FileID: "video-2", MsgType: db.VIDEO},
+	}
+	if err := AddFilter(chatID, "clips", "watch", "video-1", nil, album, db.VIDEO); err != nil {
+		t.Fatalf("AddFilter() error = %v", err)
+	}
+	filters, err := GetChatFiltersCached(chatID)
+	if err != nil || len(filters) != 1 || len(filters[0].Media) != 2 || filters[0].Media[0] != album[0] {
+		t.Fatalf("saved filters = %+v, %v", filters, err)
+	}
+
+	if _, err := UpdateFilter(chatID, 1, 0, "clips", "text", "", nil, nil, db.TEXT); err != nil {
+		t.Fatalf("UpdateFilter() error = %v", err)
+	}
+	revisions := GetFilterRevisions(chatID, "clips")
+	if len(revisions) != 1 || len(revisions[0].Media) != 2 {
+		t.Fatalf("revision must keep the album, got %+v", revisions)
+	}
+	if restored, err := RestoreFilterRevision(chatID, 1, "clips", 1); err != nil || !restored {
+		t.Fatalf("RestoreFilterRevision() = %v, %v", restored, err)
+	}
+	filters, err = GetChatFiltersCached(chatID)
+	if err != nil || len(filters) != 1 || len(filters[0].Media) != 2 {
+		t.Fatalf("restored filters = %+v, %v", filters, err)
+	}
+}
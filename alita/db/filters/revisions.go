@@ -0,0 +1,215 @@
+package filters
+
+import (
+	"errors"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+
+	"github.com/divkix/Alita_Robot/alita/db"
+	"github.com/divkix/Alita_Robot/alita/db/models"
+)
+
+// archiveFilters snapshots filters of a chat into filter_revisions before they
+// are changed: the named filters, or every filter of the chat when no keyword
+// is given. Revisions older than the retention window are pruned on the way.
+// Returns how many filters were archived.
+func archiveFilters(tx *gorm.DB, chatID, editorID int64, action string, batch int64, keywords ...string) (int, error) {
+	query := tx.Where("chat_id = ?", chatID)
+	if len(keywords) > 0 {
+		query = query.Where("keyword IN ?", keywords)
+	}
+	var current []models.ChatFilters
+	if err := query.Find(&current).Error; err != nil {
+		return 0, err
+	}
+	if len(current) == 0 {
+		return 0, nil
+	}
+
+	now := time.Now().UTC()
+	if err := tx.Where("chat_id = ? AND created_at < ?", chatID, now.Add(-models.RevisionRetention)).
+		Delete(&models.FilterRevision{}).Error; err != nil {
+		return 0, err
+	}
+
+	var latest []struct {
+		KeyWord  string `gorm:"column:keyword"`
+		Revision int
+	}
+	if err := tx.Model(&models.FilterRevision{}).
+		Select("keyword, MAX(revision) AS revision").
+		Where("chat_id = ?", chatID).
+		Group("keyword").
+		Scan(&latest).Error; err != nil {
+		return 0, err
+	}
+	lastRevision := make(map[string]int, len(latest))
+	for _, l := range latest {
+		lastRevision[l.KeyWord] = l.Revision
+	}
+
+	revisions := make([]models.FilterRevision, 0, len(current))
+	for _, filter := range current {
+		revisions = append(revisions, models.FilterRevision{
+			ChatId:      chatID,
+			KeyWord:     filter.KeyWord,
+			Revision:    lastRevision[filter.KeyWord] + 1,
+			Action:      action,
+			EditorId:    editorID,
+			Batch:       batch,
+			ThreadId:    filter.ThreadId,
+			FilterReply: filter.FilterReply,
+			MsgType:     filter.MsgType,
+			FileID:      filter.FileID,
+			NoNotif:     filter.NoNotif,
+			Buttons:     filter.Buttons,
+			Media:       filter.Media,
+			CreatedAt:   now,
+		})
+	}
+	if err := tx.Create(&revisions).Error; err != nil {
+		return 0, err
+	}
+	return len(revisions), nil
+}
+
+// revisionCutoff returns the oldest creation time of a revision that can
+// still be restored.
+func revisionCutoff() time.Time {
+	return time.Now().UTC().Add(-models.RevisionRetention)
+}
+
+// GetFilterRevisions returns the restorable revisions of a filter, newest
+// first. Returns an empty slice if there are none or an error occurs.
+func GetFilterRevisions(chatID int64, keyWord string) []*models.FilterRevision {
+	var revisions []*models.FilterRevision
+	err := db.DB.Where("chat_id = ? AND keyword = ? AND created_at >= ?", chatID, keyWord, revisionCutoff()).
+		Order("revision DESC").
+		Find(&revisions).Error
+	if err != nil {
+		log.Errorf("[Database][GetFilterRevisions]: %d - %v", chatID, err)
+		return []*models.FilterRevision{}
+	}
+	return revisions
+}
+
+// GetFilterRevisionByID returns a restorable revision by its row ID, or nil
+// if it does not exist, belongs to another chat or has expired.
+func GetFilterRevisionByID(chatID int64, id uint) *models.FilterRevision {
+	revision := &models.FilterRevision{}
+	err := db.DB.Where("id = ? AND chat_id = ? AND created_at >= ?", id, chatID, revisionCutoff()).Take(revision).Error
+	if err != nil {
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			log.Errorf("[Database][GetFilterRevisionByID]: %d - %v", chatID, err)
+		}
+		return nil
+	}
+	return revision
+}
+
+// restoreFilter writes the content of a revision back as the current filter,
+// archiving the filter it replaces, if any.
+func restoreFilter(tx *gorm.DB, editorID int64, revision *models.FilterRevision) error {
+	if _, err := archiveFilters(tx, revision.ChatId, editorID, models.RevisionActionRestore, 0, revision.KeyWord); err != nil {
+		return err
+	}
+	now := time.Now().UTC()
+	content := map[string]any{
+		"thread_id":      revision.ThreadId,
+		"filter_reply":   revision.FilterReply,
+		"msgtype":        revision.MsgType,
+		"fileid":         revision.FileID,
+		"nonotif":        revision.NoNotif,
+		"filter_buttons": revision.Buttons,
+		"media":          revision.Media,
+		"updated_at":     now,
+	}
+	result := tx.Model(&models.ChatFilters{}).
+		Where("chat_id = ? AND keyword = ?", revision.ChatId, revision.KeyWord).
+		Updates(content)
+	if result.Error != nil || result.RowsAffected > 0 {
+		return result.Error
+	}
+	content["chat_id"] = revision.ChatId
+	content["keyword"] = revision.KeyWord
+	content["created_at"] = now
+	return tx.Model(&models.ChatFilters{}).Create(content).Error
+}
+
+// RestoreFilterRevision makes revision rev of a filter its current content
+// again. The content it replaces is kept as a new revision, so a restore can
+// itself be undone. Returns false if the revision does not exist or has
+// expired.
+func RestoreFilterRevision(chatID, editorID int64, keyWord string, rev int) (bool, error) {
+	var restored bool
+	err := db.DB.Transaction(func(tx *gorm.DB) error {
+		revision := &models.FilterRevision{}
+		err := tx.Where("chat_id = ? AND keyword = ? AND revision = ? AND created_at >= ?", chatID, keyWord, rev, revisionCutoff()).
+			Take(revision).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		restored = true
+		return restoreFilter(tx, editorID, revision)
+	})
+	if err != nil {
+		log.Errorf("[Database][RestoreFilterRevision]: %d - %v", chatID, err)
+		return false, err
+	}
+	if restored {
+		invalidateFilterCaches(chatID)
+	}
+	return restored, nil
+}
+
+// RestoreClearedFilters brings back the filters removed by the latest
+// /stopall that is still within the retention window. Filters added again
+// since then are left untouched. Returns how many filters were restored.
+func RestoreClearedFilters(chatID, editorID int64) (int, error) {
+	restored := 0
+	err := db.DB.Transaction(func(tx *gorm.DB) error {
+		var latest models.FilterRevision
+		err := tx.Where("chat_id = ? AND action = ? AND created_at >= ?", chatID, models.RevisionActionClearAll, revisionCutoff()).
+			Order("batch DESC").
+			Take(&latest).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		} else if err != nil {
+			return err
+		}
+
+		var revisions []*models.FilterRevision
+		if err := tx.Where("chat_id = ? AND action = ? AND batch = ?", chatID, models.RevisionActionClearAll, latest.Batch).
+			Find(&revisions).Error; err != nil {
+			return err
+		}
+		for _, revision := range revisions {
+			var existing int64
+			if err := tx.Model(&models.ChatFilters{}).
+				Where("chat_id = ? AND keyword = ?", chatID, revision.KeyWord).
+				Count(&existing).Error; err != nil {
+				return err
+			}
+			if existing > 0 {
+				continue
+			}
+			if err := restoreFilter(tx, editorID, revision); err != nil {
+				return err
+			}
+			restored++
+		}
+		return nil
+	})
+	if err != nil {
+		log.Errorf("[Database][RestoreClearedFilters]: %d - %v", chatID, err)
+		return 0, err
+	}
+	if restored > 0 {
+		invalidateFilterCaches(chatID)
+	}
+	return restored, nil
+}
@@ -33,7 +33,7 @@ func TestMain(m *testing.M) {
 			fmt.Printf("SQLite init failed: %v\n", err)
 			os.Exit(1)
 		}
-		if err := db.DB.AutoMigrate(&models.Chat{}, &models.ChatFilters{}); err != nil {
+		if err := db.DB.AutoMigrate(&models.Chat{}, &models.ChatFilters{}, &models.FilterRevision{}); err != nil {
 			fmt.Printf("AutoMigrate failed: %v\n", err)
 			os.Exit(1)
 		}
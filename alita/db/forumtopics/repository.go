@@ -0,0 +1,178 @@
+package forumtopics
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/divkix/Alita_Robot/alita/db"
+	"github.com/divkix/Alita_Robot/alita/db/models"
+)
+
+const (
+	// MaxAutoCloseDays is the longest idle time /topicautoclose accepts.
+	MaxAutoCloseDays = 365
+	// CloseRetryDelay is how long a topic that could not be closed is left
+	// alone before auto-closing tries it again.
+	CloseRetryDelay = 24 * time.Hour
+)
+
+// GetTopicSettings returns the forum topic settings of a chat, or the
+// defaults when none are stored.
+func GetTopicSettings(chatID int64) *models.ForumTopicSettings {
+	settings := &models.ForumTopicSettings{}
+	err := db.DB.Where("chat_id = ?", chatID).First(settings).Error
+	if err != nil {
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			log.Errorf("[Database][GetTopicSettings]: %v", err)
+		}
+		return &models.ForumTopicSettings{ChatId: chatID}
+	}
+	return settings
+}
+
+// SetAutoCloseDays sets after how many idle days the topics of a chat are
+// closed. 0 turns auto-closing off.
+func SetAutoCloseDays(chatID int64, days int) error {
+	if days < 0 || days > MaxAutoCloseDays {
+		return fmt.Errorf("auto-close days must be between 0 and %d, got %d", MaxAutoCloseDays, days)
+	}
+	err := db.DB.Where("chat_id = ?", chatID).
+		Assign(map[string]any{"chat_id": chatID, "auto_close_days": days}).
+		FirstOrCreate(&models.ForumTopicSettings{}).Error
+	if err != nil {
+		log.Errorf("[Database] SetAutoCloseDays: %v - %d", err, chatID)
+	}
+	return err
+}
+
+// upsertTopic stores a topic, updating the given columns when the topic is
+// already known.
+func upsertTopic(topic *models.ForumTopic, columns ...string) error {
+	return db.DB.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "chat_id"}, {Name: "thread_id"}},
+		DoUpdates: clause.AssignmentColumns(append(columns, "updated_at")),
+	}).Create(topic).Error
+}
+
+// TouchTopic records a message in a topic at the given time.
+func TouchTopic(chatID, threadID int64, at time.Time) error {
+	err := upsertTopic(&models.ForumTopic{ChatId: chatID, ThreadId: threadID, LastActivityAt: at}, "last_activity_at")
+	if err != nil {
+		log.Errorf("[Database] TouchTopic: %v - %d", err, chatID)
+	}
+	return err
+}
+
+// SaveTopic records a topic that was created or renamed, which also counts as
+// activity in it.
+func SaveTopic(chatID, threadID int64, name string, at time.Time) error {
+	err := upsertTopic(&models.ForumTopic{ChatId: chatID, ThreadId: threadID, Name: name, LastActivityAt: at}, "name", "last_activity_at")
+	if err != nil {
+		log.Errorf("[Database] SaveTopic: %v - %d", err, chatID)
+	}
+	return err
+}
+
+// SetTopicClosed records that a topic was closed or reopened. Reopening
+// counts as activity, so a reopened topic is not closed again straight away.
+func SetTopicClosed(chatID, threadID int64, closed bool, at time.Time) error {
+	topic := &models.ForumTopic{ChatId: chatID, ThreadId: threadID, Closed: closed, LastActivityAt: at}
+	var err error
+	if closed {
+		err = upsertTopic(topic, "closed", "close_failed_at")
+	} else {
+		err = upsertTopic(topic, "closed", "close_failed_at", "last_activity_at")
+	}
+	if err != nil {
+		log.Errorf("[Database] SetTopicClosed: %v - %d", err, chatID)
+	}
+	return err
+}
+
+// MarkCloseFailed records that closing a topic failed, so IdleTopics skips
+// it for CloseRetryDelay instead of retrying it every run.
+func MarkCloseFailed(chatID, threadID int64, at time.Time) error {
+	err := db.DB.Model(&models.ForumTopic{}).
+		Where("chat_id = ? AND thread_id = ?", chatID, threadID).
+		Update("close_failed_at", at).Error
+	if err != nil {
+		log.Errorf("[Database] MarkCloseFailed: %v - %d", err, chatID)
+	}
+	return err
+}
+
+// SeedTopics starts tracking the topics of a chat the bot knows from its
+// settings (topic locks, antiflood limits, filters and the welcome topic) but
+// has not seen a message in, as if they were active at the given time. The
+// Bot API cannot list the topics of a forum, so topics that appear nowhere
+// are only tracked once a message is sent in them. Known topics are kept as
+// they are. It returns the number of topics added.
+func SeedTopics(chatID int64, at time.Time) (int, error) {
+	var threadIDs []int64
+	err := db.DB.Raw(`
+		SELECT thread_id FROM locks WHERE chat_id = ? AND thread_id > 0
+		UNION SELECT thread_id FROM antiflood_topic_limits WHERE chat_id = ? AND thread_id > 0
+		UNION SELECT thread_id FROM filters WHERE chat_id = ? AND thread_id > 0
+		UNION SELECT welcome_topic FROM greetings WHERE chat_id = ? AND welcome_topic > 0`,
+		chatID, chatID, chatID, chatID).Scan(&threadIDs).Error
+	if err != nil {
+		log.Errorf("[Database] SeedTopics: %v - %d", err, chatID)
+		return 0, err
+	}
+	if len(threadIDs) == 0 {
+		return 0, nil
+	}
+	topics := make([]models.ForumTopic, 0, len(threadIDs))
+	for _, threadID := range threadIDs {
+		topics = append(topics, models.ForumTopic{ChatId: chatID, ThreadId: threadID, LastActivityAt: at})
+	}
+	result := db.DB.Clauses(clause.OnConflict{DoNothing: true}).Create(&topics)
+	if result.Error != nil {
+		log.Errorf("[Database] SeedTopics: %v - %d", result.Error, chatID)
+		return 0, result.Error
+	}
+	return int(result.RowsAffected), nil
+}
+
+// DeleteTopic forgets a topic.
+func DeleteTopic(chatID, threadID int64) error {
+	err := db.DB.Where("chat_id = ? AND thread_id = ?", chatID, threadID).Delete(&models.ForumTopic{}).Error
+	if err != nil {
+		log.Errorf("[Database] DeleteTopic: %v - %d", err, chatID)
+	}
+	return err
+}
+
+// IdleTopics returns up to limit open topics, across all chats with
+// auto-closing on, that have had no activity for longer than their chat
+// allows. Topics whose last close attempt failed less than CloseRetryDelay
+// ago are skipped, so they don't hold up the topics of other chats.
+func IdleTopics(now time.Time, limit int) ([]models.ForumTopic, error) {
+	var settings []models.ForumTopicSettings
+	if err := db.DB.Where("auto_close_days > 0").Find(&settings).Error; err != nil {
+		return nil, err
+	}
+	var idle []models.ForumTopic
+	for _, s := range settings {
+		if len(idle) >= limit {
+			break
+		}
+		cutoff := now.Add(-time.Duration(s.AutoCloseDays) * 24 * time.Hour)
+		var topics []models.ForumTopic
+		err := db.DB.Where("chat_id = ? AND closed = ? AND last_activity_at < ?", s.ChatId, false, cutoff).
+			Where("close_failed_at IS NULL OR close_failed_at < ?", now.Add(-CloseRetryDelay)).
+			Order("last_activity_at").
+			Limit(limit - len(idle)).
+			Find(&topics).Error
+		if err != nil {
+			return idle, err
+		}
+		idle = append(idle, topics...)
+	}
+	return idle, nil
+}
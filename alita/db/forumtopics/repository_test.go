@@ -0,0 +1,196 @@
+package forumtopics
+
+import (
+	"testing"
+	"time"
+
+	"github.com/divkix/Alita_Robot/alita/db"
+	"github.com/divkix/Alita_Robot/alita/db/models"
+)
+
+func skipIfNoDb(t *testing.T) {
+	if db.DB == nil {
+		t.Skip("DB not initialized")
+	}
+}
+
+func cleanupForumTopics(t *testing.T, chatID int64) {
+	t.Cleanup(func() {
+		_ = db.DB.Where("chat_id = ?", chatID).Delete(&models.ForumTopicSettings{}).Error
+		_ = db.DB.Where("chat_id = ?", chatID).Delete(&models.ForumTopic{}).Error
+		_ = db.DB.Where("chat_id = ?", chatID).Delete(&models.LockSettings{}).Error
+		_ = db.DB.Where("chat_id = ?", chatID).Delete(&models.ChatFilters{}).Error
+		_ = db.DB.Where("chat_id = ?", chatID).Delete(&models.GreetingSettings{}).Error
+	})
+}
+
+func TestSetAutoCloseDays(t *testing.T) {
+	skipIfNoDb(t)
+
+	chatID := time.Now().UnixNano()
+	cleanupForumTopics(t, chatID)
+
+	if got := GetTopicSettings(chatID).AutoCloseDays; got != 0 {
+		t.Fatalf("default AutoCloseDays = %d, want 0", got)
+	}
+	if err := SetAutoCloseDays(chatID, 14); err != nil {
+		t.Fatalf("SetAutoCloseDays(14) error = %v", err)
+	}
+	if got := GetTopicSettings(chatID).AutoCloseDays; got != 14 {
+		t.Fatalf("AutoCloseDays = %d, want 14", got)
+	}
+	if err := SetAutoCloseDays(chatID, MaxAutoCloseDays+1); err == nil {
+		t.Fatal("SetAutoCloseDays above the maximum succeeded")
+	}
+	if err := SetAutoCloseDays(chatID, 0); err != nil {
+		t.Fatalf("SetAutoCloseDays(0) error = %v", err)
+	}
+	if got := GetTopicSettings(chatID).AutoCloseDays; got != 0 {
+		t.Fatalf("AutoCloseDays = %d after turning it off, want 0", got)
+	}
+}
+
+func TestIdleTopicsFollowsActivity(t *testing.T) {
+	skipIfNoDb(t)
+
+	chatID := time.Now().UnixNano()
+	cleanupForumTopics(t, chatID)
+	now := time.Now()
+	old := now.Add(-10 * 24 * time.Hour)
+
+	if err := SaveTopic(chatID, 1, "Stale", old); err != nil {
+		t.Fatalf("SaveTopic(1) error = %v", err)
+	}
+	if err := SaveTopic(chatID, 2, "Busy", old); err != nil {
+		t.Fatalf("SaveTopic(2) error = %v", err)
+	}
+	if err := TouchTopic(chatID, 2, now); err != nil {
+		t.Fatalf("TouchTopic(2) error = %v", err)
+	}
+	if err := SaveTopic(chatID, 3, "Closed", old); err != nil {
+		t.Fatalf("SaveTopic(3) error = %v", err)
+	}
+	if err := SetTopicClosed(chatID, 3, true, now); err != nil {
+		t.Fatalf("SetTopicClosed(3) error = %v", err)
+	}
+
+	if idle := idleIn(t, chatID, now); len(idle) != 0 {
+		t.Fatalf("idle topics = %v with auto-close off, want none", idle)
+	}
+
+	if err := SetAutoCloseDays(chatID, 7); err != nil {
+		t.Fatalf("SetAutoCloseDays error = %v", err)
+	}
+	idle := idleIn(t, chatID, now)
+	if len(idle) != 1 || idle[0].ThreadId != 1 || idle[0].Name != "Stale" {
+		t.Fatalf("idle topics = %+v, want only topic 1", idle)
+	}
+
+	// Reopening counts as activity.
+	if err := SetTopicClosed(chatID, 1, true, now); err != nil {
+		t.Fatalf("SetTopicClosed(1, true) error = %v", err)
+	}
+	if err := SetTopicClosed(chatID, 1, false, now); err != nil {
+		t.Fatalf("SetTopicClosed(1, false) error = %v", err)
+	}
+	if idle := idleIn(t, chatID, now); len(idle) != 0 {
+		t.Fatalf("idle topics = %+v after reopening, want none", idle)
+	}
+
+	if err := DeleteTopic(chatID, 2); err != nil {
+		t.Fatalf("DeleteTopic error = %v", err)
+	}
+	var count int64
+	db.DB.Model(&models.ForumTopic{}).Where("chat_id = ?", chatID).Count(&count)
+	if count != 2 {
+		t.Fatalf("topics = %d after DeleteTopic, want 2", count)
+	}
+}
+
+func TestIdleTopicsSkipsTopicsThatFailedToClose(t *testing.T) {
+	skipIfNoDb(t)
+
+	chatID := time.Now().UnixNano()
+	cleanupForumTopics(t, chatID)
+	now := time.Now()
+	old := now.Add(-10 * 24 * time.Hour)
+
+	if err := SetAutoCloseDays(chatID, 7); err != nil {
+		t.Fatalf("SetAutoCloseDays error = %v", err)
+	}
+	for _, threadID := range []int64{1, 2} {
+		if err := SaveTopic(chatID, threadID, "Stale", old); err != nil {
+			t.Fatalf("SaveTopic(%d) error = %v", threadID, err)
+		}
+	}
+	if err := MarkCloseFailed(chatID, 1, now); err != nil {
+		t.Fatalf("MarkCloseFailed error = %v", err)
+	}
+
+	idle := idleIn(t, chatID, now)
+	if len(idle) != 1 || idle[0].ThreadId != 2 {
+		t.Fatalf("idle topics = %+v right after a failed close, want only topic 2", idle)
+	}
+	if idle := idleIn(t, chatID, now.Add(CloseRetryDelay+time.Minute)); len(idle) != 2 {
+		t.Fatalf("idle topics = %+v after the retry delay, want both", idle)
+	}
+}
+
+func TestSeedTopicsTracksKnownTopics(t *testing.T) {
+	skipIfNoDb(t)
+
+	chatID := time.Now().UnixNano()
+	cleanupForumTopics(t, chatID)
+	now := time.Now()
+	old := now.Add(-10 * 24 * time.Hour)
+
+	seed := []any{
+		&models.LockSettings{ChatId: chatID, ThreadId: 5, LockType: "photo", Locked: true},
+		&models.LockSettings{ChatId: chatID, ThreadId: 0, LockType: "video", Locked: true},
+		&models.ChatFilters{ChatId: chatID, KeyWord: "hi", ThreadId: 6},
+		&models.GreetingSettings{ChatID: chatID, WelcomeTopic: 7},
+	}
+	for _, row := range seed {
+		if err := db.DB.Create(row).Error; err != nil {
+			t.Fatalf("Create(%T) error = %v", row, err)
+		}
+	}
+	if err := SaveTopic(chatID, 6, "Known", old); err != nil {
+		t.Fatalf("SaveTopic error = %v", err)
+	}
+
+	added, err := SeedTopics(chatID, now)
+	if err != nil {
+		t.Fatalf("SeedTopics error = %v", err)
+	}
+	if added != 2 {
+		t.Fatalf("SeedTopics added %d topics, want 2", added)
+	}
+	var topics []models.ForumTopic
+	db.DB.Where("chat_id = ?", chatID).Order("thread_id").Find(&topics)
+	if len(topics) != 3 || topics[0].ThreadId != 5 || topics[1].ThreadId != 6 || topics[2].ThreadId != 7 {
+		t.Fatalf("topics = %+v, want 5, 6 and 7", topics)
+	}
+	if topics[1].Name != "Known" || topics[1].LastActivityAt.After(now.Add(-time.Hour)) {
+		t.Fatalf("known topic = %+v, want it kept as it was", topics[1])
+	}
+	if idle := idleIn(t, chatID, now); len(idle) != 0 {
+		t.Fatalf("idle topics = %+v with auto-close off, want none", idle)
+	}
+}
+
+// idleIn returns the idle topics of one chat.
+func idleIn(t *testing.T, chatID int64, now time.Time) []models.ForumTopic {
+	t.Helper()
+	all, err := IdleTopics(now, 1000)
+	if err != nil {
+		t.Fatalf("IdleTopics error = %v", err)
+	}
+	var idle []models.ForumTopic
+	for _, topic := range all {
+		if topic.ChatId == chatID {
+			idle = append(idle, topic)
+		}
+	}
+	return idle
+}
@@ -627,10 +627,35 @@ func TestTableNames(t *testing.T) {
 		{"DevSettings", DevSettings{}, "devs"},
 		{"ChannelSettings", ChannelSettings{}, "channels"},
 		{"AntifloodSettings", AntifloodSettings{}, "antiflood_settings"},
+		{"AntifloodTopicLimit", AntifloodTopicLimit{}, "antiflood_topic_limits"},
 		{"ConnectionSettings", ConnectionSettings{}, "connection"},
 		{"ConnectionChatSettings", ConnectionChatSettings{}, "connection_settings"},
 		{"DisableSettings", DisableSettings{}, "disable"},
 		{"DisableChatSettings", DisableChatSettings{}, "disable_chat_settings"},
+		{"CommandPermission", CommandPermission{}, "command_permissions"},
+		{"CommandAlias", CommandAlias{}, "command_aliases"},
+		{"NoteRevision", NoteRevision{}, "note_revisions"},
+		{"FilterRevision", FilterRevision{}, "filter_revisions"},
+		{"JoinRequest", JoinRequest{}, "join_requests"},
+		{"JoinQuestion", JoinQuestion{}, "join_questions"},
+		{"JoinApplication", JoinApplication{}, "join_applications"},
+		{"CaptchaQuestion", CaptchaQuestion{}, "captcha_questions"},
+		{"CaptchaEvent", CaptchaEvent{}, "captcha_events"},
+		{"ChatActivityHour", ChatActivityHour{}, "chat_activity_hours"},
+		{"ChatActivityUser", ChatActivityUser{}, "chat_activity_users"},
+		{"AntispamSettings", AntispamSettings{}, "antispam_settings"},
+		{"SpamModel", SpamModel{}, "spam_models"},
+		{"SpamToken", SpamToken{}, "spam_tokens"},
+		{"ForumTopicSettings", ForumTopicSettings{}, "forum_topic_settings"},
+		{"ForumTopic", ForumTopic{}, "forum_topics"},
+		{"ReactionAction", ReactionAction{}, "reaction_actions"},
+		{"StarboardSettings", StarboardSettings{}, "starboard_settings"},
+		{"StarboardEntry", StarboardEntry{}, "starboard_entries"},
+		{"KarmaSettings", KarmaSettings{}, "karma_settings"},
+		{"KarmaUser", KarmaUser{}, "karma_users"},
+		{"KarmaVote", KarmaVote{}, "karma_votes"},
+		{"TrustSettings", TrustSettings{}, "trust_settings"},
+		{"TrustMember", TrustMember{}, "trust_members"},
 		{"RulesSettings", RulesSettings{}, "rules"},
 		{"LockSettings", LockSettings{}, "locks"},
 		{"NotesSettings", NotesSettings{}, "notes_settings"},
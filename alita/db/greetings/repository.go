@@ -312,6 +312,60 @@ func SetShouldAutoApprove(chatID int64, pref bool) error {
 	return nil
 }
 
+// SetJoinReviewChat sets the chat join requests of chatID are posted to for
+// review, with a profile summary of each requester. 0 turns review off.
+// Creates default greeting settings if they don't exist.
+func SetJoinReviewChat(chatID, reviewChatID int64) error {
+	updates := map[string]any{
+		"join_review_chat": reviewChatID,
+	}
+
+	err := upsertGreetingSettings(chatID, updates)
+	if err != nil {
+		log.Errorf("[Database][SetJoinReviewChat]: %v", err)
+		return err
+	}
+
+	cache.DeleteCache(cache.CacheKey("greetings", chatID))
+	return nil
+}
+
+// SetJoinQuestionsTimeout sets how many minutes join applicants have to
+// answer the chat's questions before their request is declined.
+// Creates default greeting settings if they don't exist.
+func SetJoinQuestionsTimeout(chatID int64, minutes int) error {
+	updates := map[string]any{
+		"join_questions_timeout": minutes,
+	}
+
+	err := upsertGreetingSettings(chatID, updates)
+	if err != nil {
+		log.Errorf("[Database][SetJoinQuestionsTimeout]: %v", err)
+		return err
+	}
+
+	cache.DeleteCache(cache.CacheKey("greetings", chatID))
+	return nil
+}
+
+// SetWelcomeTopic sets the forum topic welcome and captcha messages of a chat
+// are sent to. 0 sends them where the member joined.
+// Creates default greeting settings if they don't exist.
+func SetWelcomeTopic(chatID, threadID int64) error {
+	updates := map[string]any{
+		"welcome_topic": threadID,
+	}
+
+	err := upsertGreetingSettings(chatID, updates)
+	if err != nil {
+		log.Errorf("[Database][SetWelcomeTopic]: %v", err)
+		return err
+	}
+
+	cache.DeleteCache(cache.CacheKey("greetings", chatID))
+	return nil
+}
+
 // SetCleanWelcomeSetting sets whether old welcome messages should be automatically cleaned.
 // Creates default greeting settings if they don't exist.
 func SetCleanWelcomeSetting(chatID int64, pref bool) error {
@@ -0,0 +1,178 @@
+package joinrequests
+
+import (
+	"errors"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/divkix/Alita_Robot/alita/db"
+	"github.com/divkix/Alita_Robot/alita/db/cache"
+	"github.com/divkix/Alita_Robot/alita/db/models"
+)
+
+func answeringCacheKey(userID int64) string {
+	return cache.CacheKey("join_answering", userID)
+}
+
+// IsAnswering reports whether userID may have an opened application, so
+// private messages of everyone else skip the application lookup. The flag is
+// cached and cleared whenever an application of the user is opened or
+// removed.
+func IsAnswering(userID int64) bool {
+	answering, err := cache.GetFromCacheOrLoad(answeringCacheKey(userID), cache.CacheTTLJoinAnswering, func() (bool, error) {
+		var count int64
+		err := db.DB.Model(&models.JoinApplication{}).
+			Where("user_id = ? AND started = ? AND expires_at > ?", userID, true, time.Now().UTC()).
+			Count(&count).Error
+		return count > 0, err
+	})
+	if err != nil {
+		log.Errorf("[Database][IsAnswering]: %d - %v", userID, err)
+		return true
+	}
+	return answering
+}
+
+// StartApplication stores a new application, replacing any earlier one of
+// the same user to the same chat.
+func StartApplication(application *models.JoinApplication) error {
+	err := db.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("chat_id = ? AND user_id = ?", application.ChatId, application.UserId).
+			Delete(&models.JoinApplication{}).Error; err != nil {
+			return err
+		}
+		if application.Answers == nil {
+			application.Answers = models.StringArray{}
+		}
+		return tx.Create(application).Error
+	})
+	if err != nil {
+		log.Errorf("[Database][StartApplication]: %d - %v", application.ChatId, err)
+	}
+	cache.DeleteCache(answeringCacheKey(application.UserId))
+	return err
+}
+
+// RestoreApplication puts back an application removed by DeleteApplication
+// when acting on it failed, unless the user has applied again since.
+func RestoreApplication(application *models.JoinApplication) error {
+	restored := *application
+	restored.ID = 0
+	err := db.DB.Clauses(clause.OnConflict{DoNothing: true}).Create(&restored).Error
+	if err != nil {
+		log.Errorf("[Database][RestoreApplication]: %d - %v", application.ChatId, err)
+	}
+	cache.DeleteCache(answeringCacheKey(application.UserId))
+	return err
+}
+
+// GetApplication returns the unexpired application of userID to a chat, or
+// nil.
+func GetApplication(chatID, userID int64) *models.JoinApplication {
+	application := &models.JoinApplication{}
+	err := db.DB.Where("chat_id = ? AND user_id = ? AND expires_at > ?", chatID, userID, time.Now().UTC()).
+		Take(application).Error
+	if err != nil {
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			log.Errorf("[Database][GetApplication]: %d - %v", chatID, err)
+		}
+		return nil
+	}
+	return application
+}
+
+// GetAnsweringApplication returns the unexpired, opened application userID
+// is answering, or nil. If the user applied to several chats, the one they
+// opened most recently wins.
+func GetAnsweringApplication(userID int64) *models.JoinApplication {
+	application := &models.JoinApplication{}
+	err := db.DB.Where("user_id = ? AND started = ? AND expires_at > ?", userID, true, time.Now().UTC()).
+		Order("updated_at DESC").
+		Take(application).Error
+	if err != nil {
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			log.Errorf("[Database][GetAnsweringApplication]: %d - %v", userID, err)
+		}
+		return nil
+	}
+	return application
+}
+
+// OpenApplication marks an application as opened, so the applicant's private
+// messages are taken as answers to it.
+func OpenApplication(application *models.JoinApplication) error {
+	application.Started = true
+	err := db.DB.Model(application).Updates(map[string]any{
+		"started":    true,
+		"updated_at": time.Now().UTC(),
+	}).Error
+	if err != nil {
+		log.Errorf("[Database][OpenApplication]: %d - %v", application.ChatId, err)
+	}
+	cache.DeleteCache(answeringCacheKey(application.UserId))
+	return err
+}
+
+// AddAnswer stores the applicant's answer to the current question. It
+// returns false without changing anything if the application was already
+// removed or fully answered, e.g. because two answers raced.
+func AddAnswer(application *models.JoinApplication, answer string) (bool, error) {
+	saved := false
+	err := db.DB.Transaction(func(tx *gorm.DB) error {
+		current := &models.JoinApplication{}
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", application.ID).Take(current).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		if len(current.Answers) >= len(current.Questions) {
+			return nil
+		}
+		current.Answers = append(current.Answers, answer)
+		if err := tx.Model(current).Updates(map[string]any{
+			"answers":    current.Answers,
+			"updated_at": time.Now().UTC(),
+		}).Error; err != nil {
+			return err
+		}
+		*application = *current
+		saved = true
+		return nil
+	})
+	if err != nil {
+		log.Errorf("[Database][AddAnswer]: %d - %v", application.ChatId, err)
+		return false, err
+	}
+	return saved, nil
+}
+
+// DeleteApplication removes the application of userID to a chat, if any.
+// It reports whether there was one, so that only one of several racing
+// paths (answering, timing out, an admin deciding) acts on it.
+func DeleteApplication(chatID, userID int64) bool {
+	result := db.DB.Where("chat_id = ? AND user_id = ?", chatID, userID).Delete(&models.JoinApplication{})
+	if result.Error != nil {
+		log.Errorf("[Database][DeleteApplication]: %d - %v", chatID, result.Error)
+		return false
+	}
+	if result.RowsAffected > 0 {
+		cache.DeleteCache(answeringCacheKey(userID))
+	}
+	return result.RowsAffected > 0
+}
+
+// GetExpiredApplications returns the applications whose time to answer ran
+// out.
+func GetExpiredApplications() ([]*models.JoinApplication, error) {
+	var applications []*models.JoinApplication
+	err := db.DB.Where("expires_at <= ?", time.Now().UTC()).Find(&applications).Error
+	if err != nil {
+		log.Errorf("[Database][GetExpiredApplications]: %v", err)
+		return nil, err
+	}
+	return applications, nil
+}
@@ -0,0 +1,102 @@
+package joinrequests
+
+import (
+	"errors"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+
+	"github.com/divkix/Alita_Robot/alita/db"
+	"github.com/divkix/Alita_Robot/alita/db/models"
+)
+
+// ErrTooManyQuestions is returned when a chat already has the most join
+// questions allowed.
+var ErrTooManyQuestions = errors.New("too many join questions")
+
+// GetQuestions returns the join questions of a chat in the order they are
+// asked. Returns an empty slice if there are none or an error occurs.
+func GetQuestions(chatID int64) []*models.JoinQuestion {
+	var questions []*models.JoinQuestion
+	if err := db.DB.Where("chat_id = ?", chatID).Order("position ASC").Find(&questions).Error; err != nil {
+		log.Errorf("[Database][GetQuestions]: %d - %v", chatID, err)
+		return []*models.JoinQuestion{}
+	}
+	return questions
+}
+
+// AddQuestion appends a question to the chat's join questions. answer lists
+// the accepted answers separated by ";", or is empty for questions admins
+// read themselves. Returns ErrTooManyQuestions once the chat has
+// models.MaxJoinQuestions questions.
+func AddQuestion(chatID int64, question, answer string) error {
+	err := db.DB.Transaction(func(tx *gorm.DB) error {
+		var count int64
+		if err := tx.Model(&models.JoinQuestion{}).Where("chat_id = ?", chatID).Count(&count).Error; err != nil {
+			return err
+		}
+		if count >= models.MaxJoinQuestions {
+			return ErrTooManyQuestions
+		}
+		var last struct{ Position int }
+		if err := tx.Model(&models.JoinQuestion{}).
+			Select("COALESCE(MAX(position), 0) AS position").
+			Where("chat_id = ?", chatID).
+			Scan(&last).Error; err != nil {
+			return err
+		}
+		return tx.Create(&models.JoinQuestion{
+			ChatId:   chatID,
+			Position: last.Position + 1,
+			Question: question,
+			Answer:   answer,
+		}).Error
+	})
+	if err != nil && !errors.Is(err, ErrTooManyQuestions) {
+		log.Errorf("[Database][AddQuestion]: %d - %v", chatID, err)
+	}
+	return err
+}
+
+// RemoveQuestion deletes the n-th question (1-based) of a chat and moves the
+// ones after it up. Returns false if the chat has no such question.
+func RemoveQuestion(chatID int64, n int) (bool, error) {
+	removed := false
+	err := db.DB.Transaction(func(tx *gorm.DB) error {
+		var questions []models.JoinQuestion
+		if err := tx.Where("chat_id = ?", chatID).Order("position ASC").Find(&questions).Error; err != nil {
+			return err
+		}
+		if n < 1 || n > len(questions) {
+			return nil
+		}
+		if err := tx.Delete(&models.JoinQuestion{}, questions[n-1].ID).Error; err != nil {
+			return err
+		}
+		removed = true
+		now := time.Now().UTC()
+		for i := n; i < len(questions); i++ {
+			if err := tx.Model(&models.JoinQuestion{}).Where("id = ?", questions[i].ID).
+				Updates(map[string]any{"position": i, "updated_at": now}).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		log.Errorf("[Database][RemoveQuestion]: %d - %v", chatID, err)
+		return false, err
+	}
+	return removed, nil
+}
+
+// ClearQuestions deletes every join question of a chat, which turns the
+// questionnaire off.
+func ClearQuestions(chatID int64) error {
+	err := db.DB.Where("chat_id = ?", chatID).Delete(&models.JoinQuestion{}).Error
+	if err != nil {
+		log.Errorf("[Database][ClearQuestions]: %d - %v", chatID, err)
+	}
+	return err
+}
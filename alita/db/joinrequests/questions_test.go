@@ -0,0 +1,125 @@
+package joinrequests
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/divkix/Alita_Robot/alita/db/models"
+)
+
+func TestQuestionsKeepOrderAndLimit(t *testing.T) {
+	chatID := time.Now().UnixNano()
+
+	for i, question := range []string{"one", "two", "three", "four", "five"} {
+		if err := AddQuestion(chatID, question, ""); err != nil {
+			t.Fatalf("AddQuestion(%d) error = %v", i+1, err)
+		}
+	}
+	if err := AddQuestion(chatID, "six", ""); !errors.Is(err, ErrTooManyQuestions) {
+		t.Fatalf("AddQuestion() past the limit error = %v, want ErrTooManyQuestions", err)
+	}
+
+	removed, err := RemoveQuestion(chatID, 2)
+	if err != nil || !removed {
+		t.Fatalf("RemoveQuestion(2) = %v, %v; want true, nil", removed, err)
+	}
+	if removed, _ := RemoveQuestion(chatID, 9); removed {
+		t.Fatal("RemoveQuestion(9) removed a question that does not exist")
+	}
+	if err := AddQuestion(chatID, "six", "yes;y"); err != nil {
+		t.Fatalf("AddQuestion() after removal error = %v", err)
+	}
+
+	questions := GetQuestions(chatID)
+	want := []string{"one", "three", "four", "five", "six"}
+	if len(questions) != len(want) {
+		t.Fatalf("GetQuestions() returned %d questions, want %d", len(questions), len(want))
+	}
+	for i, question := range questions {
+		if question.Question != want[i] || question.Position != i+1 {
+			t.Fatalf("question %d = %q at position %d, want %q at %d", i, question.Question, question.Position, want[i], i+1)
+		}
+	}
+	if questions[4].Answer != "yes;y" {
+		t.Fatalf("accepted answers = %q, want %q", questions[4].Answer, "yes;y")
+	}
+
+	if err := ClearQuestions(chatID); err != nil {
+		t.Fatalf("ClearQuestions() error = %v", err)
+	}
+	if got := GetQuestions(chatID); len(got) != 0 {
+		t.Fatalf("GetQuestions() after clear = %d questions, want 0", len(got))
+	}
+}
+
+func TestApplicationLifecycle(t *testing.T) {
+	chatID := time.Now().UnixNano()
+	userID := chatID + 1
+	newApplication := func(expires time.Time) *models.JoinApplication {
+		return &models.JoinApplication{
+			ChatId:     chatID,
+			UserId:     userID,
+			UserChatId: userID,
+			Questions:  models.StringArray{"q1", "q2"},
+			Expected:   models.StringArray{"", ""},
+			ExpiresAt:  expires,
+		}
+	}
+
+	if err := StartApplication(newApplication(time.Now().Add(-time.Minute).UTC())); err != nil {
+		t.Fatalf("StartApplication() error = %v", err)
+	}
+	if GetApplication(chatID, userID) != nil {
+		t.Fatal("GetApplication() returned an expired application")
+	}
+	expired, err := GetExpiredApplications()
+	if err != nil {
+		t.Fatalf("GetExpiredApplications() error = %v", err)
+	}
+	found := false
+	for _, application := range expired {
+		found = found || application.ChatId == chatID
+	}
+	if !found {
+		t.Fatal("GetExpiredApplications() did not return the expired application")
+	}
+
+	// Starting again replaces the expired application.
+	if err := StartApplication(newApplication(time.Now().Add(time.Hour).UTC())); err != nil {
+		t.Fatalf("StartApplication() error = %v", err)
+	}
+	application := GetApplication(chatID, userID)
+	if application == nil {
+		t.Fatal("GetApplication() = nil, want the new application")
+	}
+	if GetAnsweringApplication(userID) != nil {
+		t.Fatal("GetAnsweringApplication() returned an application that was not opened")
+	}
+	if err := OpenApplication(application); err != nil {
+		t.Fatalf("OpenApplication() error = %v", err)
+	}
+	answering := GetAnsweringApplication(userID)
+	if answering == nil || answering.ID != application.ID {
+		t.Fatalf("GetAnsweringApplication() = %+v, want the opened application", answering)
+	}
+
+	for _, answer := range []string{"a1", "a2"} {
+		if saved, err := AddAnswer(answering, answer); err != nil || !saved {
+			t.Fatalf("AddAnswer(%q) = %v, %v; want true, nil", answer, saved, err)
+		}
+	}
+	if saved, _ := AddAnswer(answering, "extra"); saved {
+		t.Fatal("AddAnswer() stored an answer past the last question")
+	}
+	if len(answering.Answers) != 2 || answering.Answers[1] != "a2" {
+		t.Fatalf("answers = %v, want [a1 a2]", answering.Answers)
+	}
+
+	if !DeleteApplication(chatID, userID) {
+		t.Fatal("DeleteApplication() = false, want true")
+	}
+	if DeleteApplication(chatID, userID) {
+		t.Fatal("second DeleteApplication() = true, want false")
+	}
+}
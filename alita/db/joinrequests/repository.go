@@ -0,0 +1,145 @@
+package joinrequests
+
+import (
+	"errors"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+
+	"github.com/divkix/Alita_Robot/alita/db"
+	"github.com/divkix/Alita_Robot/alita/db/models"
+)
+
+// RecordPending stores a join request whose review card was just posted.
+// A request of the same user that is still pending is updated in place, so a
+// user who asks again does not show up twice in /pendingrequests.
+func RecordPending(request *models.JoinRequest) error {
+	request.Status = models.JoinRequestPending
+	err := db.DB.Transaction(func(tx *gorm.DB) error {
+		existing := &models.JoinRequest{}
+		err := tx.Where("chat_id = ? AND user_id = ? AND status = ?", request.ChatId, request.UserId, models.JoinRequestPending).
+			Take(existing).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return tx.Create(request).Error
+		} else if err != nil {
+			return err
+		}
+		request.ID = existing.ID
+		return tx.Model(existing).Updates(map[string]any{
+			"first_name":        request.FirstName,
+			"username":          request.Username,
+			"bio":               request.Bio,
+			"invite_link":       request.InviteLink,
+			"review_chat_id":    request.ReviewChatId,
+			"review_message_id": request.ReviewMessageId,
+			"requested_at":      request.RequestedAt,
+		}).Error
+	})
+	if err != nil {
+		log.Errorf("[Database][RecordPending]: %d - %v", request.ChatId, err)
+	}
+	return err
+}
+
+// Decide records an admin's decision on the pending request of userID. A
+// decision on a request that has no pending record, such as one posted
+// before records were kept, is stored as a new record.
+func Decide(chatID, userID, deciderID int64, status string) error {
+	now := time.Now().UTC()
+	err := db.DB.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&models.JoinRequest{}).
+			Where("chat_id = ? AND user_id = ? AND status = ?", chatID, userID, models.JoinRequestPending).
+			Updates(map[string]any{
+				"status":     status,
+				"decided_by": deciderID,
+				"decided_at": now,
+				"updated_at": now,
+			})
+		if result.Error != nil || result.RowsAffected > 0 {
+			return result.Error
+		}
+		return tx.Create(&models.JoinRequest{
+			ChatId:      chatID,
+			UserId:      userID,
+			Status:      status,
+			DecidedBy:   deciderID,
+			DecidedAt:   &now,
+			RequestedAt: now,
+		}).Error
+	})
+	if err != nil {
+		log.Errorf("[Database][Decide]: %d - %v", chatID, err)
+	}
+	return err
+}
+
+// MarkJoined approves the pending request of a user who was seen joining the
+// chat, which happens when an admin approves it outside the bot.
+func MarkJoined(chatID, userID int64) {
+	now := time.Now().UTC()
+	err := db.DB.Model(&models.JoinRequest{}).
+		Where("chat_id = ? AND user_id = ? AND status = ?", chatID, userID, models.JoinRequestPending).
+		Updates(map[string]any{
+			"status":     models.JoinRequestApproved,
+			"decided_at": now,
+			"updated_at": now,
+		}).Error
+	if err != nil {
+		log.Errorf("[Database][MarkJoined]: %d - %v", chatID, err)
+	}
+}
+
+// GetPending returns up to limit pending requests of a chat, oldest first,
+// and how many are pending in total.
+func GetPending(chatID int64, limit int) ([]*models.JoinRequest, int64, error) {
+	query := db.DB.Model(&models.JoinRequest{}).Where("chat_id = ? AND status = ?", chatID, models.JoinRequestPending)
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		log.Errorf("[Database][GetPending]: %d - %v", chatID, err)
+		return nil, 0, err
+	}
+	var requests []*models.JoinRequest
+	if err := query.Order("requested_at ASC").Limit(limit).Find(&requests).Error; err != nil {
+		log.Errorf("[Database][GetPending]: %d - %v", chatID, err)
+		return nil, 0, err
+	}
+	return requests, total, nil
+}
+
+// GetPendingRequest returns the pending request of userID in a chat, or nil.
+func GetPendingRequest(chatID, userID int64) *models.JoinRequest {
+	request := &models.JoinRequest{}
+	err := db.DB.Where("chat_id = ? AND user_id = ? AND status = ?", chatID, userID, models.JoinRequestPending).
+		Take(request).Error
+	if err != nil {
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			log.Errorf("[Database][GetPendingRequest]: %d - %v", chatID, err)
+		}
+		return nil
+	}
+	return request
+}
+
+// DecisionCounts returns how many earlier requests of userID in a chat were
+// approved, declined and banned.
+func DecisionCounts(chatID, userID int64) map[string]int64 {
+	var rows []struct {
+		Status string
+		Count  int64
+	}
+	err := db.DB.Model(&models.JoinRequest{}).
+		Select("status, COUNT(*) AS count").
+		Where("chat_id = ? AND user_id = ? AND status <> ?", chatID, userID, models.JoinRequestPending).
+		Group("status").
+		Scan(&rows).Error
+	counts := make(map[string]int64, len(rows))
+	if err != nil {
+		log.Errorf("[Database][DecisionCounts]: %d - %v", chatID, err)
+		return counts
+	}
+	for _, row := range rows {
+		counts[row.Status] = row.Count
+	}
+	return counts
+}
@@ -0,0 +1,83 @@
+package joinrequests
+
+import (
+	"testing"
+	"time"
+
+	"github.com/divkix/Alita_Robot/alita/db/models"
+)
+
+func TestRecordPendingKeepsOneRowPerUser(t *testing.T) {
+	chatID := time.Now().UnixNano()
+	first := time.Now().Add(-time.Hour).UTC().Truncate(time.Second)
+
+	if err := RecordPending(&models.JoinRequest{ChatId: chatID, UserId: 1, FirstName: "Old", RequestedAt: first}); err != nil {
+		t.Fatalf("RecordPending() error = %v", err)
+	}
+	if err := RecordPending(&models.JoinRequest{ChatId: chatID, UserId: 1, FirstName: "New", Bio: "hi", RequestedAt: first.Add(time.Minute)}); err != nil {
+		t.Fatalf("RecordPending() error = %v", err)
+	}
+	if err := RecordPending(&models.JoinRequest{ChatId: chatID, UserId: 2, FirstName: "Other", RequestedAt: first.Add(-time.Minute)}); err != nil {
+		t.Fatalf("RecordPending() error = %v", err)
+	}
+
+	requests, total, err := GetPending(chatID, 10)
+	if err != nil {
+		t.Fatalf("GetPending() error = %v", err)
+	}
+	if total != 2 || len(requests) != 2 {
+		t.Fatalf("GetPending() = %d requests, total %d; want 2, 2", len(requests), total)
+	}
+	if requests[0].UserId != 2 {
+		t.Fatalf("GetPending() first user = %d, want oldest request first", requests[0].UserId)
+	}
+	if requests[1].FirstName != "New" || requests[1].Bio != "hi" {
+		t.Fatalf("repeated request was not updated in place: %+v", requests[1])
+	}
+
+	limited, total, err := GetPending(chatID, 1)
+	if err != nil || len(limited) != 1 || total != 2 {
+		t.Fatalf("GetPending(limit 1) = %d requests, total %d, err %v", len(limited), total, err)
+	}
+}
+
+func TestDecideRecordsAuditTrail(t *testing.T) {
+	chatID := time.Now().UnixNano()
+	if err := RecordPending(&models.JoinRequest{ChatId: chatID, UserId: 7, FirstName: "Ann", RequestedAt: time.Now()}); err != nil {
+		t.Fatalf("RecordPending() error = %v", err)
+	}
+
+	if err := Decide(chatID, 7, 42, models.JoinRequestDeclined); err != nil {
+		t.Fatalf("Decide() error = %v", err)
+	}
+	if GetPendingRequest(chatID, 7) != nil {
+		t.Fatal("request still pending after a decision")
+	}
+
+	// A decision without a pending record, e.g. on a card posted before
+	// records were kept, is still stored.
+	if err := Decide(chatID, 7, 43, models.JoinRequestBanned); err != nil {
+		t.Fatalf("Decide() error = %v", err)
+	}
+
+	counts := DecisionCounts(chatID, 7)
+	if counts[models.JoinRequestDeclined] != 1 || counts[models.JoinRequestBanned] != 1 || counts[models.JoinRequestApproved] != 0 {
+		t.Fatalf("DecisionCounts() = %v", counts)
+	}
+}
+
+func TestMarkJoinedApprovesPendingRequest(t *testing.T) {
+	chatID := time.Now().UnixNano()
+	if err := RecordPending(&models.JoinRequest{ChatId: chatID, UserId: 9, RequestedAt: time.Now()}); err != nil {
+		t.Fatalf("RecordPending() error = %v", err)
+	}
+
+	MarkJoined(chatID, 9)
+
+	if GetPendingRequest(chatID, 9) != nil {
+		t.Fatal("request still pending after the user joined")
+	}
+	if counts := DecisionCounts(chatID, 9); counts[models.JoinRequestApproved] != 1 {
+		t.Fatalf("DecisionCounts() = %v, want one approval", counts)
+	}
+}
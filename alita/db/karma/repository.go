@@ -0,0 +1,267 @@
+package karma
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/divkix/Alita_Robot/alita/db"
+	"github.com/divkix/Alita_Robot/alita/db/cache"
+	"github.com/divkix/Alita_Robot/alita/db/models"
+)
+
+const (
+	// DefaultCooldownSeconds is how long a member waits before giving karma
+	// to the same person again, unless /karmaconfig cooldown says otherwise.
+	DefaultCooldownSeconds = 60 * 60
+	MaxCooldownSeconds     = 7 * 24 * 60 * 60
+	MaxTriggers            = 20
+	MaxTriggerLength       = 64
+)
+
+// unapprovableScopes are the approval scopes karma never grants. They guard
+// against bots and raids, and a few accounts are enough to gather karma.
+var unapprovableScopes = []string{models.ApprovalScopeCaptcha, models.ApprovalScopeAntiraid}
+
+// ApproveScopes returns the approval scopes karma may grant, in display
+// order.
+func ApproveScopes() []string {
+	return slices.DeleteFunc(slices.Clone(models.ApprovalScopes), func(scope string) bool {
+		return slices.Contains(unapprovableScopes, scope)
+	})
+}
+
+// ApproveScopesAllowed reports whether karma may approve members for the
+// given scopes: at least one, and none that karma never grants.
+func ApproveScopesAllowed(scopes []string) bool {
+	return len(scopes) > 0 && !slices.ContainsFunc(scopes, func(scope string) bool {
+		return slices.Contains(unapprovableScopes, scope)
+	})
+}
+
+// defaultSettings returns the settings of a chat that never configured
+// karma.
+func defaultSettings(chatID int64) *models.KarmaSettings {
+	return &models.KarmaSettings{ChatId: chatID, Reactions: true, CooldownSeconds: DefaultCooldownSeconds}
+}
+
+// settingsCacheKey returns the cache key for a chat's karma settings.
+func settingsCacheKey(chatID int64) string {
+	return cache.CacheKey("karma_settings", chatID)
+}
+
+// GetKarmaSettings returns the karma settings of a chat, read-through cache,
+// or the defaults, with karma off, when none are stored.
+func GetKarmaSettings(chatID int64) *models.KarmaSettings {
+	result, err := cache.GetFromCacheOrLoad(settingsCacheKey(chatID), cache.CacheTTLChatSettings, func() (*models.KarmaSettings, error) {
+		settings := &models.KarmaSettings{}
+		err := db.DB.Where("chat_id = ?", chatID).First(settings).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return defaultSettings(chatID), nil
+		}
+		if err != nil {
+			log.Errorf("[Database] GetKarmaSettings: %v - chat:%d", err, chatID)
+			return nil, err
+		}
+		return settings, nil
+	})
+	if err != nil || result == nil {
+		return defaultSettings(chatID)
+	}
+	return result
+}
+
+// updateSettings stores the given columns of a chat's karma settings,
+// creating them with the defaults first when needed. The columns are written
+// separately, as creating a row with reactions off would store the column
+// default instead.
+func updateSettings(chatID int64, columns map[string]any) error {
+	settings := &models.KarmaSettings{}
+	err := db.DB.Where("chat_id = ?", chatID).
+		Attrs(defaultSettings(chatID)).
+		FirstOrCreate(settings).Error
+	if err == nil {
+		err = db.DB.Model(settings).Updates(columns).Error
+	}
+	if err != nil {
+		log.Errorf("[Database] updateSettings(karma): %v - chat:%d", err, chatID)
+		return err
+	}
+	cache.DeleteCache(settingsCacheKey(chatID))
+	return nil
+}
+
+// SetKarmaEnabled turns karma on or off in a chat.
+func SetKarmaEnabled(chatID int64, enabled bool) error {
+	return updateSettings(chatID, map[string]any{"enabled": enabled})
+}
+
+// SetKarmaReactions sets whether 👍 reactions give karma.
+func SetKarmaReactions(chatID int64, reactions bool) error {
+	return updateSettings(chatID, map[string]any{"reactions": reactions})
+}
+
+// SetKarmaCooldown sets how long a member waits before giving karma to the
+// same person again.
+func SetKarmaCooldown(chatID int64, seconds int) error {
+	if seconds < 0 || seconds > MaxCooldownSeconds {
+		return fmt.Errorf("karma cooldown must be between 0 and %d seconds, got %d", MaxCooldownSeconds, seconds)
+	}
+	return updateSettings(chatID, map[string]any{"cooldown_seconds": seconds})
+}
+
+// SetKarmaTriggers replaces the custom triggers of a chat.
+func SetKarmaTriggers(chatID int64, triggers []string) error {
+	if len(triggers) > MaxTriggers {
+		return fmt.Errorf("a chat can have at most %d karma triggers, got %d", MaxTriggers, len(triggers))
+	}
+	return updateSettings(chatID, map[string]any{"triggers": models.StringArray(triggers)})
+}
+
+// SetKarmaApproval sets the karma at which members are approved with the
+// given scopes, which must pass ApproveScopesAllowed. 0 turns approving off.
+func SetKarmaApproval(chatID int64, approveAt int, scopes []string) error {
+	if approveAt < 0 {
+		return fmt.Errorf("karma approval threshold must not be negative, got %d", approveAt)
+	}
+	if approveAt > 0 && !ApproveScopesAllowed(scopes) {
+		return fmt.Errorf("karma cannot approve members for scopes %v", scopes)
+	}
+	return updateSettings(chatID, map[string]any{"approve_at": approveAt, "approve_scopes": models.StringArray(scopes)})
+}
+
+// addKarma changes the karma of a member by delta.
+func addKarma(tx *gorm.DB, chatID, userID int64, delta int) error {
+	return tx.Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "chat_id"}, {Name: "user_id"}},
+		DoUpdates: clause.Assignments(map[string]any{
+			"karma":      gorm.Expr("karma_users.karma + ?", delta),
+			"updated_at": time.Now(),
+		}),
+	}).Create(&models.KarmaUser{ChatId: chatID, UserId: userID, Karma: delta}).Error
+}
+
+// AddKarma changes the karma of a member by delta and returns the new
+// value.
+func AddKarma(chatID, userID int64, delta int) (int, error) {
+	if err := addKarma(db.DB, chatID, userID, delta); err != nil {
+		log.Errorf("[Database] AddKarma: %v - chat:%d user:%d", err, chatID, userID)
+		return 0, err
+	}
+	return GetKarma(chatID, userID), nil
+}
+
+// HasVote reports whether a member already gave karma for a message.
+func HasVote(chatID, messageID, giverID int64) bool {
+	var count int64
+	err := db.DB.Model(&models.KarmaVote{}).
+		Where("chat_id = ? AND message_id = ? AND giver_id = ?", chatID, messageID, giverID).
+		Count(&count).Error
+	if err != nil {
+		log.Errorf("[Database] HasVote: %v - chat:%d message:%d giver:%d", err, chatID, messageID, giverID)
+	}
+	return count > 0
+}
+
+// GiveVote gives the receiver a point of karma from the giver for a message
+// and returns the receiver's new karma. A member gives each message karma at
+// most once; given is false when they already did.
+func GiveVote(chatID, messageID, giverID, receiverID int64) (points int, given bool, err error) {
+	err = db.DB.Transaction(func(tx *gorm.DB) error {
+		result := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&models.KarmaVote{
+			ChatId:     chatID,
+			MessageId:  messageID,
+			GiverId:    giverID,
+			ReceiverId: receiverID,
+		})
+		if result.Error != nil || result.RowsAffected == 0 {
+			return result.Error
+		}
+		given = true
+		return addKarma(tx, chatID, receiverID, 1)
+	})
+	if err != nil {
+		log.Errorf("[Database] GiveVote: %v - chat:%d message:%d giver:%d", err, chatID, messageID, giverID)
+		return 0, false, err
+	}
+	if !given {
+		return 0, false, nil
+	}
+	return GetKarma(chatID, receiverID), true, nil
+}
+
+// TakeVote takes back the karma a member gave for a message. It returns who
+// had received it and their new karma; taken is false when there was none.
+func TakeVote(chatID, messageID, giverID int64) (receiverID int64, points int, taken bool, err error) {
+	err = db.DB.Transaction(func(tx *gorm.DB) error {
+		vote := &models.KarmaVote{}
+		err := tx.Where("chat_id = ? AND message_id = ? AND giver_id = ?", chatID, messageID, giverID).First(vote).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		result := tx.Delete(vote)
+		if result.Error != nil || result.RowsAffected == 0 {
+			return result.Error
+		}
+		receiverID, taken = vote.ReceiverId, true
+		return addKarma(tx, chatID, receiverID, -1)
+	})
+	if err != nil {
+		log.Errorf("[Database] TakeVote: %v - chat:%d message:%d giver:%d", err, chatID, messageID, giverID)
+		return 0, 0, false, err
+	}
+	if !taken {
+		return 0, 0, false, nil
+	}
+	return receiverID, GetKarma(chatID, receiverID), true, nil
+}
+
+// GetKarma returns the karma of a member, 0 when they have none.
+func GetKarma(chatID, userID int64) int {
+	row := &models.KarmaUser{}
+	err := db.DB.Where("chat_id = ? AND user_id = ?", chatID, userID).First(row).Error
+	if err != nil {
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			log.Errorf("[Database] GetKarma: %v - chat:%d user:%d", err, chatID, userID)
+		}
+		return 0
+	}
+	return row.Karma
+}
+
+// TopKarma returns up to limit members of a chat with the most karma,
+// leaving out those with none.
+func TopKarma(chatID int64, limit int) ([]models.KarmaUser, error) {
+	var rows []models.KarmaUser
+	err := db.DB.Where("chat_id = ? AND karma > 0", chatID).
+		Order("karma DESC").
+		Order("updated_at").
+		Limit(limit).
+		Find(&rows).Error
+	if err != nil {
+		log.Errorf("[Database] TopKarma: %v - chat:%d", err, chatID)
+	}
+	return rows, err
+}
+
+// ResetKarma removes all karma in a chat, and the votes it came from.
+func ResetKarma(chatID int64) error {
+	err := db.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("chat_id = ?", chatID).Delete(&models.KarmaVote{}).Error; err != nil {
+			return err
+		}
+		return tx.Where("chat_id = ?", chatID).Delete(&models.KarmaUser{}).Error
+	})
+	if err != nil {
+		log.Errorf("[Database] ResetKarma: %v - chat:%d", err, chatID)
+	}
+	return err
+}
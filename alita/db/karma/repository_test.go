@@ -0,0 +1,152 @@
+package karma
+
+import (
+	"testing"
+	"time"
+
+	"github.com/divkix/Alita_Robot/alita/db"
+	"github.com/divkix/Alita_Robot/alita/db/cache"
+	"github.com/divkix/Alita_Robot/alita/db/models"
+)
+
+func skipIfNoDb(t *testing.T) {
+	if db.DB == nil {
+		t.Skip("DB not initialized")
+	}
+}
+
+func cleanupKarma(t *testing.T, chatID int64) {
+	t.Cleanup(func() {
+		_ = db.DB.Where("chat_id = ?", chatID).Delete(&models.KarmaSettings{}).Error
+		_ = db.DB.Where("chat_id = ?", chatID).Delete(&models.KarmaUser{}).Error
+		_ = db.DB.Where("chat_id = ?", chatID).Delete(&models.KarmaVote{}).Error
+		cache.DeleteCache(settingsCacheKey(chatID))
+	})
+}
+
+func TestKarmaSettings(t *testing.T) {
+	skipIfNoDb(t)
+
+	chatID := time.Now().UnixNano()
+	cleanupKarma(t, chatID)
+
+	settings := GetKarmaSettings(chatID)
+	if settings.Enabled || !settings.Reactions || settings.CooldownSeconds != DefaultCooldownSeconds || settings.ApproveAt != 0 {
+		t.Fatalf("default settings = %+v, want off, with reactions and the default cooldown", settings)
+	}
+
+	// Turning reactions off first must not lose the false to column defaults.
+	if err := SetKarmaReactions(chatID, false); err != nil {
+		t.Fatalf("SetKarmaReactions(false) error = %v", err)
+	}
+	if err := SetKarmaEnabled(chatID, true); err != nil {
+		t.Fatalf("SetKarmaEnabled error = %v", err)
+	}
+	if err := SetKarmaCooldown(chatID, 300); err != nil {
+		t.Fatalf("SetKarmaCooldown error = %v", err)
+	}
+	if err := SetKarmaTriggers(chatID, []string{"gracias", "danke"}); err != nil {
+		t.Fatalf("SetKarmaTriggers error = %v", err)
+	}
+	if err := SetKarmaApproval(chatID, 10, []string{"locks", "media"}); err != nil {
+		t.Fatalf("SetKarmaApproval error = %v", err)
+	}
+	settings = GetKarmaSettings(chatID)
+	if !settings.Enabled || settings.Reactions || settings.CooldownSeconds != 300 ||
+		len(settings.Triggers) != 2 || settings.ApproveAt != 10 || len(settings.ApproveScopes) != 2 {
+		t.Fatalf("settings = %+v, want them as set", settings)
+	}
+
+	if err := SetKarmaCooldown(chatID, MaxCooldownSeconds+1); err == nil {
+		t.Fatal("SetKarmaCooldown above the maximum succeeded")
+	}
+	if err := SetKarmaTriggers(chatID, make([]string, MaxTriggers+1)); err == nil {
+		t.Fatal("SetKarmaTriggers above the maximum succeeded")
+	}
+	for _, scopes := range [][]string{nil, {"locks", "captcha"}, {"antiraid"}} {
+		if err := SetKarmaApproval(chatID, 5, scopes); err == nil {
+			t.Fatalf("SetKarmaApproval(%v) succeeded, want karma kept from those scopes", scopes)
+		}
+	}
+	if err := SetKarmaApproval(chatID, 0, nil); err != nil {
+		t.Fatalf("SetKarmaApproval(off) error = %v", err)
+	}
+}
+
+func TestKarmaCounts(t *testing.T) {
+	skipIfNoDb(t)
+
+	chatID := time.Now().UnixNano()
+	cleanupKarma(t, chatID)
+
+	if got := GetKarma(chatID, 1); got != 0 {
+		t.Fatalf("GetKarma() on a fresh chat = %d, want 0", got)
+	}
+	for range 3 {
+		if _, err := AddKarma(chatID, 1, 1); err != nil {
+			t.Fatalf("AddKarma(1) error = %v", err)
+		}
+	}
+	if got, err := AddKarma(chatID, 2, 1); err != nil || got != 1 {
+		t.Fatalf("AddKarma(2) = %d, %v, want 1", got, err)
+	}
+	if got, _ := AddKarma(chatID, 3, 1); got != 1 {
+		t.Fatalf("AddKarma(3) = %d, want 1", got)
+	}
+	if got, _ := AddKarma(chatID, 3, -1); got != 0 {
+		t.Fatalf("AddKarma(3, -1) = %d, want 0", got)
+	}
+
+	top, err := TopKarma(chatID, 10)
+	if err != nil || len(top) != 2 || top[0].UserId != 1 || top[0].Karma != 3 || top[1].UserId != 2 {
+		t.Fatalf("TopKarma() = %+v, %v, want user 1 with 3, then user 2", top, err)
+	}
+	if top, _ := TopKarma(chatID, 1); len(top) != 1 {
+		t.Fatalf("TopKarma(limit 1) = %+v, want one row", top)
+	}
+
+	if err := ResetKarma(chatID); err != nil {
+		t.Fatalf("ResetKarma() error = %v", err)
+	}
+	if got := GetKarma(chatID, 1); got != 0 {
+		t.Fatalf("GetKarma() after reset = %d, want 0", got)
+	}
+}
+
+func TestKarmaVotes(t *testing.T) {
+	skipIfNoDb(t)
+
+	chatID := time.Now().UnixNano()
+	cleanupKarma(t, chatID)
+
+	if points, given, err := GiveVote(chatID, 100, 1, 2); err != nil || !given || points != 1 {
+		t.Fatalf("GiveVote() = %d, %v, %v, want 1, true", points, given, err)
+	}
+	if _, given, _ := GiveVote(chatID, 100, 1, 2); given {
+		t.Fatal("GiveVote() for the same message twice gave karma again")
+	}
+	if !HasVote(chatID, 100, 1) || HasVote(chatID, 100, 3) {
+		t.Fatal("HasVote() does not match the votes given")
+	}
+	if points, _, _ := GiveVote(chatID, 100, 3, 2); points != 2 {
+		t.Fatalf("karma = %d after a second giver, want 2", points)
+	}
+
+	receiver, points, taken, err := TakeVote(chatID, 100, 1)
+	if err != nil || !taken || receiver != 2 || points != 1 {
+		t.Fatalf("TakeVote() = %d, %d, %v, %v, want 2, 1, true", receiver, points, taken, err)
+	}
+	if _, _, taken, _ := TakeVote(chatID, 100, 1); taken {
+		t.Fatal("TakeVote() took the same vote back twice")
+	}
+	if _, given, _ := GiveVote(chatID, 100, 1, 2); !given {
+		t.Fatal("GiveVote() after taking the vote back was refused")
+	}
+
+	if err := ResetKarma(chatID); err != nil {
+		t.Fatalf("ResetKarma() error = %v", err)
+	}
+	if HasVote(chatID, 100, 1) {
+		t.Fatal("votes survived ResetKarma()")
+	}
+}
@@ -10,7 +10,7 @@ import (
 	log "github.com/sirupsen/logrus"
 )
 
-// GetChatLocksOptimized retrieves all locks for a chat with minimal column selection.
+// GetChatLocksOptimized retrieves all chat-wide locks for a chat with minimal column selection.
 // Returns a map of lock types to their boolean status for improved performance.
 func GetChatLocksOptimized(chatID int64) (map[string]bool, error) {
 	if db.DB == nil {
@@ -25,7 +25,7 @@ func GetChatLocksOptimized(chatID int64) (map[string]bool, error) {
 	var locks []LockResult
 	err := db.DB.Model(&models.LockSettings{}).
 		Select("lock_type, locked").
-		Where("chat_id = ?", chatID).
+		Where("chat_id = ? AND thread_id = 0", chatID).
 		Find(&locks).Error
 	if err != nil {
 		log.Errorf("[OptimizedLockQueries] GetChatLocksOptimized: %v", err)
@@ -55,3 +55,46 @@ func GetChatLocksCached(chatID int64) (map[string]bool, error) {
 
 	return cached, nil
 }
+
+// GetTopicLocksOptimized retrieves the locks set in the forum topics of a chat,
+// keyed by topic ID, with minimal column selection.
+func GetTopicLocksOptimized(chatID int64) (map[int64]map[string]bool, error) {
+	if db.DB == nil {
+		return nil, errors.New("database not initialized")
+	}
+
+	var locks []models.LockSettings
+	err := db.DB.Model(&models.LockSettings{}).
+		Select("thread_id, lock_type, locked").
+		Where("chat_id = ? AND thread_id <> 0", chatID).
+		Find(&locks).Error
+	if err != nil {
+		log.Errorf("[OptimizedLockQueries] GetTopicLocksOptimized: %v", err)
+		return nil, err
+	}
+
+	result := make(map[int64]map[string]bool)
+	for _, lock := range locks {
+		if result[lock.ThreadId] == nil {
+			result[lock.ThreadId] = make(map[string]bool)
+		}
+		result[lock.ThreadId][lock.LockType] = lock.Locked
+	}
+
+	return result, nil
+}
+
+// GetTopicLocksCached retrieves the topic locks of a chat with the same caching
+// as GetChatLocksCached.
+func GetTopicLocksCached(chatID int64) (map[int64]map[string]bool, error) {
+	cacheKey := cache.CacheKey("topic_locks", chatID)
+
+	cached, err := cache.GetFromCacheOrLoad(cacheKey, 1*time.Hour, func() (map[int64]map[string]bool, error) {
+		return GetTopicLocksOptimized(chatID)
+	})
+	if err != nil {
+		return GetTopicLocksOptimized(chatID)
+	}
+
+	return cached, nil
+}
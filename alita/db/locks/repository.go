@@ -22,23 +22,59 @@ func GetChatLocks(chatID int64) map[string]bool {
 	return locks
 }
 
-// UpdateLock atomically upserts a lock record for the given chat and permission type.
+// GetTopicLocks returns the locks that apply in a forum topic: the chat-wide
+// locks, overridden by the ones set in the topic itself. A threadID of 0
+// returns the chat-wide locks.
+func GetTopicLocks(chatID, threadID int64) map[string]bool {
+	chatLocks := GetChatLocks(chatID)
+	if threadID == 0 {
+		return chatLocks
+	}
+
+	topicLocks, err := GetTopicLocksCached(chatID)
+	if err != nil {
+		log.Errorf("[Database] GetTopicLocks: %v - %d", err, chatID)
+		return chatLocks
+	}
+	if len(topicLocks[threadID]) == 0 {
+		return chatLocks
+	}
+
+	locks := make(map[string]bool, len(chatLocks)+len(topicLocks[threadID]))
+	for perm, locked := range chatLocks {
+		locks[perm] = locked
+	}
+	for perm, locked := range topicLocks[threadID] {
+		locks[perm] = locked
+	}
+	return locks
+}
+
+// UpdateLock atomically upserts a chat-wide lock record for the given chat and permission type.
+// Returns an error if the database operation fails.
+func UpdateLock(chatID int64, perm string, val bool) error {
+	return UpdateTopicLock(chatID, 0, perm, val)
+}
+
+// UpdateTopicLock atomically upserts a lock record for the given chat, forum topic and permission type.
+// A threadID of 0 sets the chat-wide lock.
 // Uses INSERT ... ON CONFLICT DO UPDATE for atomicity under concurrent writes.
 // Invalidates the cache after successful update to ensure immediate enforcement.
 // Returns an error if the database operation fails.
-func UpdateLock(chatID int64, perm string, val bool) error {
+func UpdateTopicLock(chatID, threadID int64, perm string, val bool) error {
 	record := models.LockSettings{
 		ChatId:   chatID,
+		ThreadId: threadID,
 		LockType: perm,
 		Locked:   val,
 	}
 
 	err := db.DB.Clauses(clause.OnConflict{
-		Columns:   []clause.Column{{Name: "chat_id"}, {Name: "lock_type"}},
+		Columns:   []clause.Column{{Name: "chat_id"}, {Name: "thread_id"}, {Name: "lock_type"}},
 		DoUpdates: clause.AssignmentColumns([]string{"locked"}),
 	}).Create(&record).Error
 	if err != nil {
-		log.Errorf("[Database] UpdateLock: %v", err)
+		log.Errorf("[Database] UpdateTopicLock: %v", err)
 		return err
 	}
 
@@ -47,11 +83,12 @@ func UpdateLock(chatID int64, perm string, val bool) error {
 	return nil
 }
 
-// InvalidateLockCache removes the cached whole-map lock status for a chat so that
-// GetChatLocks reflects the change immediately.
+// InvalidateLockCache removes the cached lock maps for a chat so that
+// GetChatLocks and GetTopicLocks reflect the change immediately.
 // Should be called after updating a lock to ensure immediate enforcement.
 func InvalidateLockCache(chatID int64) {
 	cache.DeleteCache(cache.CacheKey("locks_map", chatID))
+	cache.DeleteCache(cache.CacheKey("topic_locks", chatID))
 }
 
 // IsPermLocked checks whether a specific permission type is locked in the given chat.
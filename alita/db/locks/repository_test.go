@@ -323,3 +323,43 @@ func TestGetChatLocksCacheInvalidation(t *testing.T) {
 		t.Fatalf("GetChatLocks() after second UpdateLock = %v, want unlocked %q (cache invalidation failed)", locks, perm)
 	}
 }
+
+func TestGetTopicLocksOverridesChatLocks(t *testing.T) {
+	skipIfNoDb(t)
+	cache.SetupTestMemoryMarshaler(t)
+
+	chatID := time.Now().UnixNano()
+	const topic = int64(42)
+
+	t.Cleanup(func() {
+		if err := db.DB.Where("chat_id = ?", chatID).Delete(&models.LockSettings{}).Error; err != nil {
+			t.Fatalf("cleanup Delete error: %v", err)
+		}
+	})
+
+	if err := UpdateLock(chatID, "media", true); err != nil {
+		t.Fatalf("UpdateLock() error = %v", err)
+	}
+	if err := UpdateLock(chatID, "url", true); err != nil {
+		t.Fatalf("UpdateLock() error = %v", err)
+	}
+	if err := UpdateTopicLock(chatID, topic, "media", false); err != nil {
+		t.Fatalf("UpdateTopicLock(false) error = %v", err)
+	}
+	if err := UpdateTopicLock(chatID, topic, "sticker", true); err != nil {
+		t.Fatalf("UpdateTopicLock(true) error = %v", err)
+	}
+
+	topicLocks := GetTopicLocks(chatID, topic)
+	if topicLocks["media"] || !topicLocks["url"] || !topicLocks["sticker"] {
+		t.Fatalf("GetTopicLocks() = %v, want media unlocked, url and sticker locked", topicLocks)
+	}
+
+	chatLocks := GetChatLocks(chatID)
+	if !chatLocks["media"] || chatLocks["sticker"] {
+		t.Fatalf("GetChatLocks() = %v, want topic locks kept out of the chat", chatLocks)
+	}
+	if other := GetTopicLocks(chatID, topic+1); !other["media"] || other["sticker"] {
+		t.Fatalf("GetTopicLocks(other topic) = %v, want chat-wide locks", other)
+	}
+}
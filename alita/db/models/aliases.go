@@ -0,0 +1,18 @@
+package models
+
+import "time"
+
+// CommandAlias maps a per-chat alias command to a target command or note.
+// Target is either a bare command name ("rules") or a note reference ("#wiki").
+type CommandAlias struct {
+	ID        uint      `gorm:"primaryKey;autoIncrement" json:"-"`
+	ChatID    int64     `gorm:"column:chat_id;not null;uniqueIndex:idx_command_aliases_chat_alias" json:"chat_id,omitempty"`
+	Alias     string    `gorm:"column:alias;not null;uniqueIndex:idx_command_aliases_chat_alias" json:"alias,omitempty"`
+	Target    string    `gorm:"column:target;not null" json:"target,omitempty"`
+	CreatedAt time.Time `gorm:"column:created_at" json:"created_at,omitempty"`
+	UpdatedAt time.Time `gorm:"column:updated_at" json:"updated_at,omitempty"`
+}
+
+func (CommandAlias) TableName() string {
+	return "command_aliases"
+}
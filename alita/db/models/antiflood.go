@@ -16,3 +16,17 @@ type AntifloodSettings struct {
 func (AntifloodSettings) TableName() string {
 	return "antiflood_settings"
 }
+
+// AntifloodTopicLimit overrides the flood limit of a chat in one forum topic.
+type AntifloodTopicLimit struct {
+	ID        uint      `gorm:"primaryKey;autoIncrement" json:"-"`
+	ChatId    int64     `gorm:"column:chat_id;not null;uniqueIndex:uk_antiflood_topic_limits_chat_thread" json:"chat_id,omitempty"`
+	ThreadId  int64     `gorm:"column:thread_id;not null;uniqueIndex:uk_antiflood_topic_limits_chat_thread" json:"thread_id,omitempty"`
+	Limit     int       `gorm:"column:flood_limit;not null;default:0;check:chk_antiflood_topic_limit,flood_limit >= 0" json:"limit"` // 0 = no flood control in the topic
+	CreatedAt time.Time `gorm:"column:created_at" json:"created_at,omitempty"`
+	UpdatedAt time.Time `gorm:"column:updated_at" json:"updated_at,omitempty"`
+}
+
+func (AntifloodTopicLimit) TableName() string {
+	return "antiflood_topic_limits"
+}
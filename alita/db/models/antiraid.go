@@ -4,15 +4,37 @@ import "time"
 
 // AntiRaidSettings stores per-chat anti-raid configuration.
 type AntiRaidSettings struct {
-	ID                    uint      `gorm:"primaryKey;autoIncrement" json:"-"`
-	ChatID                int64     `gorm:"column:chat_id;uniqueIndex;not null" json:"chat_id,omitempty"`
-	RaidTime              int       `gorm:"column:raid_time;default:21600" json:"raid_time,omitempty"`
-	RaidActionTime        int       `gorm:"column:raid_action_time;default:3600" json:"raid_action_time,omitempty"`
-	AutoAntiRaidThreshold int       `gorm:"column:auto_antiraid_threshold;default:0" json:"auto_antiraid_threshold,omitempty"`
-	CreatedAt             time.Time `gorm:"column:created_at" json:"created_at,omitempty"`
-	UpdatedAt             time.Time `gorm:"column:updated_at" json:"updated_at,omitempty"`
+	ID                    uint  `gorm:"primaryKey;autoIncrement" json:"-"`
+	ChatID                int64 `gorm:"column:chat_id;uniqueIndex;not null" json:"chat_id,omitempty"`
+	RaidTime              int   `gorm:"column:raid_time;default:21600" json:"raid_time,omitempty"`
+	RaidActionTime        int   `gorm:"column:raid_action_time;default:3600" json:"raid_action_time,omitempty"`
+	AutoAntiRaidThreshold int   `gorm:"column:auto_antiraid_threshold;default:0" json:"auto_antiraid_threshold,omitempty"`
+	// Weights of the raid detectors, in points per hit added to the join
+	// count that AutoAntiRaidThreshold is compared with. 0 turns one off.
+	NameWeight    int       `gorm:"column:name_weight;not null;default:0;check:chk_antiraid_name_weight,name_weight >= 0" json:"name_weight,omitempty"`
+	ProfileWeight int       `gorm:"column:profile_weight;not null;default:0;check:chk_antiraid_profile_weight,profile_weight >= 0" json:"profile_weight,omitempty"`
+	MessageWeight int       `gorm:"column:message_weight;not null;default:0;check:chk_antiraid_message_weight,message_weight >= 0" json:"message_weight,omitempty"`
+	InviteWeight  int       `gorm:"column:invite_weight;not null;default:0;check:chk_antiraid_invite_weight,invite_weight >= 0" json:"invite_weight,omitempty"`
+	CreatedAt     time.Time `gorm:"column:created_at" json:"created_at,omitempty"`
+	UpdatedAt     time.Time `gorm:"column:updated_at" json:"updated_at,omitempty"`
 }
 
 func (AntiRaidSettings) TableName() string {
 	return "antiraid_settings"
 }
+
+// DetectorWeight returns the weight of the named raid detector, 0 for
+// detectors without one.
+func (s *AntiRaidSettings) DetectorWeight(detector string) int {
+	switch detector {
+	case "names":
+		return s.NameWeight
+	case "noprofile":
+		return s.ProfileWeight
+	case "messages":
+		return s.MessageWeight
+	case "invite":
+		return s.InviteWeight
+	}
+	return 0
+}
@@ -0,0 +1,81 @@
+package models
+
+import "time"
+
+// Actions the spam classifier can take on a flagged message. They mirror the
+// blacklist actions; delete only removes the message, like the blacklist's
+// "none".
+const (
+	SpamActionDelete = "delete"
+	SpamActionWarn   = "warn"
+	SpamActionMute   = "mute"
+	SpamActionKick   = "kick"
+	SpamActionBan    = "ban"
+	// SpamActionFlag only points the message out to the admins. It is only
+	// offered for duplicate messages.
+	SpamActionFlag = "flag"
+)
+
+const (
+	// DefaultSpamThreshold is the spam probability, in percent, from which a
+	// message is acted on.
+	DefaultSpamThreshold = 90
+	// SpamGlobalModel is the chat ID the global spam model is stored under.
+	// Only chats the bot owner trusts train it alongside their own model.
+	SpamGlobalModel int64 = 0
+	// DefaultDupeChats is in how many chats the same message has to show up
+	// before it is acted on as a duplicate.
+	DefaultDupeChats = 3
+)
+
+// AntispamSettings stores the per-chat configuration of the spam classifier
+// and of the cross-chat duplicate message detection.
+type AntispamSettings struct {
+	ID        uint   `gorm:"primaryKey;autoIncrement" json:"-"`
+	ChatID    int64  `gorm:"column:chat_id;uniqueIndex;not null" json:"chat_id,omitempty"`
+	Enabled   bool   `gorm:"column:enabled;default:false" json:"enabled,omitempty"`
+	Action    string `gorm:"column:action;default:'delete'" json:"action,omitempty"`
+	Threshold int    `gorm:"column:threshold;default:90" json:"threshold,omitempty"`
+	UseGlobal bool   `gorm:"column:use_global;default:false" json:"use_global,omitempty"`
+	// TrainGlobal is set by the bot owner for trusted chats. Only their /spam,
+	// /notspam and "Not spam" marks train the global model.
+	TrainGlobal bool `gorm:"column:train_global;default:false" json:"train_global,omitempty"`
+	// DupeEnabled opts the chat into the shared duplicate message store.
+	DupeEnabled bool      `gorm:"column:dupe_enabled;default:false" json:"dupe_enabled,omitempty"`
+	DupeAction  string    `gorm:"column:dupe_action;default:'mute'" json:"dupe_action,omitempty"`
+	DupeChats   int       `gorm:"column:dupe_chats;default:3" json:"dupe_chats,omitempty"`
+	CreatedAt   time.Time `gorm:"column:created_at" json:"created_at,omitempty"`
+	UpdatedAt   time.Time `gorm:"column:updated_at" json:"updated_at,omitempty"`
+}
+
+func (AntispamSettings) TableName() string {
+	return "antispam_settings"
+}
+
+// SpamModel counts the messages a spam model was trained on. ChatID is
+// SpamGlobalModel for the global model.
+type SpamModel struct {
+	ID        uint      `gorm:"primaryKey;autoIncrement" json:"-"`
+	ChatID    int64     `gorm:"column:chat_id;uniqueIndex;not null" json:"chat_id,omitempty"`
+	SpamDocs  int64     `gorm:"column:spam_docs;not null;default:0" json:"spam_docs,omitempty"`
+	HamDocs   int64     `gorm:"column:ham_docs;not null;default:0" json:"ham_docs,omitempty"`
+	UpdatedAt time.Time `gorm:"column:updated_at" json:"updated_at,omitempty"`
+}
+
+func (SpamModel) TableName() string {
+	return "spam_models"
+}
+
+// SpamToken counts how many spam and non-spam training messages of a model
+// contained a token.
+type SpamToken struct {
+	ID        uint   `gorm:"primaryKey;autoIncrement" json:"-"`
+	ChatID    int64  `gorm:"column:chat_id;not null;uniqueIndex:uk_spam_tokens_chat_token" json:"chat_id,omitempty"`
+	Token     string `gorm:"column:token;size:64;not null;uniqueIndex:uk_spam_tokens_chat_token" json:"token,omitempty"`
+	SpamCount int64  `gorm:"column:spam_count;not null;default:0" json:"spam_count,omitempty"`
+	HamCount  int64  `gorm:"column:ham_count;not null;default:0" json:"ham_count,omitempty"`
+}
+
+func (SpamToken) TableName() string {
+	return "spam_tokens"
+}
@@ -2,17 +2,62 @@ package models
 
 import "time"
 
-// ApprovedUsers represents approved users per chat who are immune to anti-spam measures
+// ApprovedUsers represents approved users per chat who are immune to anti-spam measures.
+// An empty Scopes list exempts the user from every protection; otherwise only the
+// listed scopes are bypassed. A nil ExpiresAt means the approval never expires.
 type ApprovedUsers struct {
-	ID         uint      `gorm:"primaryKey;autoIncrement" json:"-"`
-	UserID     int64     `gorm:"column:user_id;not null;uniqueIndex:idx_approved_users_chat_user" json:"user_id,omitempty"`
-	ChatID     int64     `gorm:"column:chat_id;not null;uniqueIndex:idx_approved_users_chat_user" json:"chat_id,omitempty"`
-	Reason     string    `gorm:"column:reason;default:''" json:"reason,omitempty"`
-	ApprovedBy int64     `gorm:"column:approved_by;not null;default:0" json:"approved_by,omitempty"`
-	CreatedAt  time.Time `gorm:"column:created_at" json:"created_at,omitempty"`
-	UpdatedAt  time.Time `gorm:"column:updated_at" json:"updated_at,omitempty"`
+	ID         uint        `gorm:"primaryKey;autoIncrement" json:"-"`
+	UserID     int64       `gorm:"column:user_id;not null;uniqueIndex:idx_approved_users_chat_user" json:"user_id,omitempty"`
+	ChatID     int64       `gorm:"column:chat_id;not null;uniqueIndex:idx_approved_users_chat_user" json:"chat_id,omitempty"`
+	Reason     string      `gorm:"column:reason;default:''" json:"reason,omitempty"`
+	ApprovedBy int64       `gorm:"column:approved_by;not null;default:0" json:"approved_by,omitempty"`
+	Scopes     StringArray `gorm:"column:scopes;type:jsonb;not null;default:'[]'" json:"scopes,omitempty"`
+	ExpiresAt  *time.Time  `gorm:"column:expires_at" json:"expires_at,omitempty"`
+	CreatedAt  time.Time   `gorm:"column:created_at" json:"created_at,omitempty"`
+	UpdatedAt  time.Time   `gorm:"column:updated_at" json:"updated_at,omitempty"`
 }
 
 func (ApprovedUsers) TableName() string {
 	return "approved_users"
 }
+
+// Approval scopes select which protections an approved user bypasses.
+const (
+	ApprovalScopeLocks     = "locks"
+	ApprovalScopeMedia     = "media"
+	ApprovalScopeFlood     = "flood"
+	ApprovalScopeBlacklist = "blacklist"
+	ApprovalScopeCaptcha   = "captcha"
+	ApprovalScopeAntispam  = "antispam"
+	ApprovalScopeAntiraid  = "antiraid"
+)
+
+// ApprovalScopes lists every valid approval scope in display order.
+var ApprovalScopes = []string{
+	ApprovalScopeLocks,
+	ApprovalScopeMedia,
+	ApprovalScopeFlood,
+	ApprovalScopeBlacklist,
+	ApprovalScopeCaptcha,
+	ApprovalScopeAntispam,
+	ApprovalScopeAntiraid,
+}
+
+// IsExpired reports whether the approval has passed its expiry time.
+func (a *ApprovedUsers) IsExpired(now time.Time) bool {
+	return a.ExpiresAt != nil && !now.Before(*a.ExpiresAt)
+}
+
+// Covers reports whether the approval exempts the user from the given scope.
+// Unscoped approvals cover everything, and the locks scope includes media locks.
+func (a *ApprovedUsers) Covers(scope string) bool {
+	if len(a.Scopes) == 0 {
+		return true
+	}
+	for _, s := range a.Scopes {
+		if s == scope || (s == ApprovalScopeLocks && scope == ApprovalScopeMedia) {
+			return true
+		}
+	}
+	return false
+}
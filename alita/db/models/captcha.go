@@ -2,13 +2,23 @@ package models
 
 import "time"
 
+// CaptchaModes are the challenge types a chat can pick with /captchamode.
+var CaptchaModes = []string{"math", "text", "emoji", "custom", "rules"}
+
+const (
+	// MaxCaptchaQuestions is how many custom captcha questions a chat can have.
+	MaxCaptchaQuestions = 20
+	// MaxCaptchaWrongAnswers is how many wrong answers a custom question can list.
+	MaxCaptchaWrongAnswers = 5
+)
+
 // CaptchaSettings represents captcha settings for a chat
 type CaptchaSettings struct {
 	ID            uint      `gorm:"primaryKey;autoIncrement" json:"-"`
 	ChatID        int64     `gorm:"column:chat_id;uniqueIndex;not null" json:"chat_id,omitempty"`
 	Enabled       bool      `gorm:"column:enabled;default:false" json:"enabled,omitempty"`
-	CaptchaMode   string    `gorm:"column:captcha_mode;default:'math';check:chk_captcha_mode,captcha_mode IN ('math','text')" json:"captcha_mode,omitempty"` // math or text
-	Timeout       int       `gorm:"column:timeout;default:2;check:chk_captcha_timeout_range,timeout BETWEEN 1 AND 10" json:"timeout,omitempty"`              // minutes
+	CaptchaMode   string    `gorm:"column:captcha_mode;default:'math';check:chk_captcha_mode,captcha_mode IN ('math','text','emoji','custom','rules')" json:"captcha_mode,omitempty"` // one of CaptchaModes
+	Timeout       int       `gorm:"column:timeout;default:2;check:chk_captcha_timeout_range,timeout BETWEEN 1 AND 10" json:"timeout,omitempty"`                                       // minutes
 	FailureAction string    `gorm:"column:failure_action;default:'kick';check:chk_captcha_failure_action,failure_action IN ('kick','ban','mute')" json:"failure_action,omitempty"`
 	MaxAttempts   int       `gorm:"column:max_attempts;default:3;check:chk_captcha_max_attempts_range,max_attempts BETWEEN 1 AND 10" json:"max_attempts,omitempty"`
 	CreatedAt     time.Time `gorm:"column:created_at" json:"created_at,omitempty"`
@@ -69,3 +79,48 @@ type CaptchaMutedUsers struct {
 func (CaptchaMutedUsers) TableName() string {
 	return "captcha_muted_users"
 }
+
+// CaptchaQuestion is an admin-defined question of the custom captcha mode.
+// New members pick Answer among it and the WrongAnswers.
+type CaptchaQuestion struct {
+	ID           uint        `gorm:"primaryKey;autoIncrement" json:"-"`
+	ChatID       int64       `gorm:"column:chat_id;not null;index" json:"chat_id,omitempty"`
+	Question     string      `gorm:"column:question;type:text;not null" json:"question,omitempty"`
+	Answer       string      `gorm:"column:answer;type:text;not null" json:"answer,omitempty"`
+	WrongAnswers StringArray `gorm:"column:wrong_answers;type:jsonb" json:"wrong_answers,omitempty"`
+	CreatedAt    time.Time   `gorm:"column:created_at" json:"created_at,omitempty"`
+	UpdatedAt    time.Time   `gorm:"column:updated_at" json:"updated_at,omitempty"`
+}
+
+func (CaptchaQuestion) TableName() string {
+	return "captcha_questions"
+}
+
+// Captcha outcomes record how a captcha attempt ended.
+const (
+	CaptchaOutcomeSolved  = "solved"
+	CaptchaOutcomeFailed  = "failed" // ran out of answers
+	CaptchaOutcomeTimeout = "timeout"
+	CaptchaOutcomeLeft    = "left" // left the chat before answering
+)
+
+// CaptchaEventRetention is how long captcha outcomes are kept for
+// /captchastats, whose longest window is 30 days.
+const CaptchaEventRetention = 30 * 24 * time.Hour
+
+// CaptchaEvent is the outcome of one finished captcha attempt.
+type CaptchaEvent struct {
+	ID           uint      `gorm:"primaryKey;autoIncrement" json:"-"`
+	ChatID       int64     `gorm:"column:chat_id;not null;index:idx_captcha_events_chat_created" json:"chat_id,omitempty"`
+	UserID       int64     `gorm:"column:user_id;not null" json:"user_id,omitempty"`
+	Mode         string    `gorm:"column:mode;not null" json:"mode,omitempty"`
+	Outcome      string    `gorm:"column:outcome;not null" json:"outcome,omitempty"`
+	WrongAnswers int       `gorm:"column:wrong_answers;not null;default:0" json:"wrong_answers,omitempty"`
+	Refreshes    int       `gorm:"column:refreshes;not null;default:0" json:"refreshes,omitempty"`
+	SolveSeconds int       `gorm:"column:solve_seconds;not null;default:0" json:"solve_seconds,omitempty"` // from join to the right answer
+	CreatedAt    time.Time `gorm:"column:created_at;index:idx_captcha_events_chat_created" json:"created_at,omitempty"`
+}
+
+func (CaptchaEvent) TableName() string {
+	return "captcha_events"
+}
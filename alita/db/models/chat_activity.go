@@ -0,0 +1,36 @@
+package models
+
+import "time"
+
+// ChatActivityRetention is how long chat activity rollups are kept for
+// /chatstats, whose longest window is 30 days.
+const ChatActivityRetention = 30 * 24 * time.Hour
+
+// ChatActivityHour sums the activity of a chat over one hour (UTC).
+type ChatActivityHour struct {
+	ID            uint      `gorm:"primaryKey;autoIncrement" json:"-"`
+	ChatID        int64     `gorm:"column:chat_id;not null;uniqueIndex:uk_chat_activity_hours_chat_hour" json:"chat_id,omitempty"`
+	Hour          time.Time `gorm:"column:hour;not null;uniqueIndex:uk_chat_activity_hours_chat_hour" json:"hour,omitempty"`
+	Messages      int64     `gorm:"column:messages;not null;default:0" json:"messages,omitempty"`
+	MediaMessages int64     `gorm:"column:media_messages;not null;default:0" json:"media_messages,omitempty"` // part of Messages
+	Joins         int64     `gorm:"column:joins;not null;default:0" json:"joins,omitempty"`
+	Leaves        int64     `gorm:"column:leaves;not null;default:0" json:"leaves,omitempty"`
+}
+
+func (ChatActivityHour) TableName() string {
+	return "chat_activity_hours"
+}
+
+// ChatActivityUser counts the messages one member sent in a chat on one day
+// (UTC).
+type ChatActivityUser struct {
+	ID       uint      `gorm:"primaryKey;autoIncrement" json:"-"`
+	ChatID   int64     `gorm:"column:chat_id;not null;uniqueIndex:uk_chat_activity_users_chat_day_user" json:"chat_id,omitempty"`
+	Day      time.Time `gorm:"column:day;not null;uniqueIndex:uk_chat_activity_users_chat_day_user" json:"day,omitempty"`
+	UserID   int64     `gorm:"column:user_id;not null;uniqueIndex:uk_chat_activity_users_chat_day_user" json:"user_id,omitempty"`
+	Messages int64     `gorm:"column:messages;not null;default:0" json:"messages,omitempty"`
+}
+
+func (ChatActivityUser) TableName() string {
+	return "chat_activity_users"
+}
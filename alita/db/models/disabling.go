@@ -28,3 +28,55 @@ type DisableChatSettings struct {
 func (DisableChatSettings) TableName() string {
 	return "disable_chat_settings"
 }
+
+// Command permission levels accepted by CommandPermission.Level.
+const (
+	CommandPermEveryone = "everyone"
+	CommandPermApproved = "approved"
+	CommandPermAdmins   = "admins"
+)
+
+// Admin rights that can be required on top of the admins level.
+const (
+	CommandRightRestrict   = "can_restrict"
+	CommandRightDelete     = "can_delete"
+	CommandRightPin        = "can_pin"
+	CommandRightPromote    = "can_promote"
+	CommandRightChangeInfo = "can_change_info"
+	CommandRightInvite     = "can_invite"
+)
+
+// CommandRights lists every admin right accepted in an admins:<right> policy.
+var CommandRights = []string{
+	CommandRightRestrict,
+	CommandRightDelete,
+	CommandRightPin,
+	CommandRightPromote,
+	CommandRightChangeInfo,
+	CommandRightInvite,
+}
+
+// CommandPermission represents a per-chat override of who may run a command.
+// Overrides only tighten access; a command's built-in checks still apply.
+type CommandPermission struct {
+	ID            uint      `gorm:"primaryKey;autoIncrement" json:"-"`
+	ChatId        int64     `gorm:"column:chat_id;not null;uniqueIndex:idx_command_permissions_chat_command" json:"chat_id,omitempty"`
+	Command       string    `gorm:"column:command;not null;uniqueIndex:idx_command_permissions_chat_command" json:"command,omitempty"`
+	Level         string    `gorm:"column:level;not null;default:'everyone'" json:"level,omitempty"`
+	RequiredRight string    `gorm:"column:required_right" json:"required_right,omitempty"`
+	CreatedAt     time.Time `gorm:"column:created_at" json:"created_at,omitempty"`
+	UpdatedAt     time.Time `gorm:"column:updated_at" json:"updated_at,omitempty"`
+}
+
+func (CommandPermission) TableName() string {
+	return "command_permissions"
+}
+
+// Policy returns the override in the same form admins type it,
+// e.g. "approved" or "admins:can_restrict".
+func (p *CommandPermission) Policy() string {
+	if p.RequiredRight != "" {
+		return p.Level + ":" + p.RequiredRight
+	}
+	return p.Level
+}
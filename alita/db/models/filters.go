@@ -4,16 +4,18 @@ import "time"
 
 // ChatFilters represents chat filters
 type ChatFilters struct {
-	ID          uint        `gorm:"primaryKey;autoIncrement" json:"-"`
-	ChatId      int64       `gorm:"column:chat_id;not null;uniqueIndex:uk_filters_chat_keyword" json:"chat_id,omitempty"`
-	KeyWord     string      `gorm:"column:keyword;not null;uniqueIndex:uk_filters_chat_keyword" json:"keyword,omitempty"`
-	FilterReply string      `gorm:"column:filter_reply" json:"filter_reply,omitempty"`
-	MsgType     int         `gorm:"column:msgtype" json:"msgtype,omitempty"`
-	FileID      string      `gorm:"column:fileid" json:"fileid,omitempty"`
-	NoNotif     bool        `gorm:"column:nonotif;default:false" json:"nonotif,omitempty"`
-	Buttons     ButtonArray `gorm:"column:filter_buttons;type:jsonb" json:"filter_buttons,omitempty"`
-	CreatedAt   time.Time   `gorm:"column:created_at" json:"created_at,omitempty"`
-	UpdatedAt   time.Time   `gorm:"column:updated_at" json:"updated_at,omitempty"`
+	ID          uint           `gorm:"primaryKey;autoIncrement" json:"-"`
+	ChatId      int64          `gorm:"column:chat_id;not null;uniqueIndex:uk_filters_chat_keyword" json:"chat_id,omitempty"`
+	KeyWord     string         `gorm:"column:keyword;not null;uniqueIndex:uk_filters_chat_keyword" json:"keyword,omitempty"`
+	ThreadId    int64          `gorm:"column:thread_id;not null;default:0" json:"thread_id,omitempty"` // forum topic the filter answers in; 0 = the whole chat
+	FilterReply string         `gorm:"column:filter_reply" json:"filter_reply,omitempty"`
+	MsgType     int            `gorm:"column:msgtype" json:"msgtype,omitempty"`
+	FileID      string         `gorm:"column:fileid" json:"fileid,omitempty"`
+	NoNotif     bool           `gorm:"column:nonotif;default:false" json:"nonotif,omitempty"`
+	Buttons     ButtonArray    `gorm:"column:filter_buttons;type:jsonb" json:"filter_buttons,omitempty"`
+	Media       MediaItemArray `gorm:"column:media;type:jsonb" json:"media,omitempty"` // album items; FileID and MsgType mirror the first one
+	CreatedAt   time.Time      `gorm:"column:created_at" json:"created_at,omitempty"`
+	UpdatedAt   time.Time      `gorm:"column:updated_at" json:"updated_at,omitempty"`
 }
 
 func (ChatFilters) TableName() string {
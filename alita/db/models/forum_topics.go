@@ -0,0 +1,34 @@
+package models
+
+import "time"
+
+// ForumTopicSettings holds the forum topic options of a chat.
+type ForumTopicSettings struct {
+	ID            uint      `gorm:"primaryKey;autoIncrement" json:"-"`
+	ChatId        int64     `gorm:"column:chat_id;uniqueIndex;not null" json:"chat_id,omitempty"`
+	AutoCloseDays int       `gorm:"column:auto_close_days;not null;default:0;check:chk_forum_topic_settings_auto_close,auto_close_days >= 0" json:"auto_close_days,omitempty"` // 0 = never close idle topics
+	CreatedAt     time.Time `gorm:"column:created_at" json:"created_at,omitempty"`
+	UpdatedAt     time.Time `gorm:"column:updated_at" json:"updated_at,omitempty"`
+}
+
+func (ForumTopicSettings) TableName() string {
+	return "forum_topic_settings"
+}
+
+// ForumTopic is a forum topic the bot has seen, with the time of its last
+// message, which idle topic auto-closing goes by.
+type ForumTopic struct {
+	ID             uint       `gorm:"primaryKey;autoIncrement" json:"-"`
+	ChatId         int64      `gorm:"column:chat_id;not null;uniqueIndex:uk_forum_topics_chat_thread" json:"chat_id,omitempty"`
+	ThreadId       int64      `gorm:"column:thread_id;not null;uniqueIndex:uk_forum_topics_chat_thread" json:"thread_id,omitempty"`
+	Name           string     `gorm:"column:name" json:"name,omitempty"`
+	Closed         bool       `gorm:"column:closed;not null;default:false" json:"closed,omitempty"`
+	LastActivityAt time.Time  `gorm:"column:last_activity_at;not null;index:idx_forum_topics_activity" json:"last_activity_at,omitempty"`
+	CloseFailedAt  *time.Time `gorm:"column:close_failed_at" json:"close_failed_at,omitempty"` // last failed auto-close attempt; nil = none since it was last closed or reopened
+	CreatedAt      time.Time  `gorm:"column:created_at" json:"created_at,omitempty"`
+	UpdatedAt      time.Time  `gorm:"column:updated_at" json:"updated_at,omitempty"`
+}
+
+func (ForumTopic) TableName() string {
+	return "forum_topics"
+}
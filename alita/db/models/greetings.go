@@ -26,14 +26,17 @@ type GoodbyeSettings struct {
 
 // GreetingSettings represents greeting settings for a chat
 type GreetingSettings struct {
-	ID                 uint             `gorm:"primaryKey;autoIncrement" json:"-"`
-	ChatID             int64            `gorm:"column:chat_id;uniqueIndex;not null" json:"_id,omitempty"`
-	ShouldCleanService bool             `gorm:"column:clean_service_settings;default:false" json:"clean_service_settings" default:"false"`
-	WelcomeSettings    *WelcomeSettings `gorm:"embedded;embeddedPrefix:welcome_" json:"welcome_settings" default:"false"`
-	GoodbyeSettings    *GoodbyeSettings `gorm:"embedded;embeddedPrefix:goodbye_" json:"goodbye_settings" default:"false"`
-	ShouldAutoApprove  bool             `gorm:"column:auto_approve;default:false" json:"auto_approve" default:"false"`
-	CreatedAt          time.Time        `gorm:"column:created_at" json:"created_at,omitempty"`
-	UpdatedAt          time.Time        `gorm:"column:updated_at" json:"updated_at,omitempty"`
+	ID                   uint             `gorm:"primaryKey;autoIncrement" json:"-"`
+	ChatID               int64            `gorm:"column:chat_id;uniqueIndex;not null" json:"_id,omitempty"`
+	ShouldCleanService   bool             `gorm:"column:clean_service_settings;default:false" json:"clean_service_settings" default:"false"`
+	WelcomeSettings      *WelcomeSettings `gorm:"embedded;embeddedPrefix:welcome_" json:"welcome_settings" default:"false"`
+	GoodbyeSettings      *GoodbyeSettings `gorm:"embedded;embeddedPrefix:goodbye_" json:"goodbye_settings" default:"false"`
+	ShouldAutoApprove    bool             `gorm:"column:auto_approve;default:false" json:"auto_approve" default:"false"`
+	JoinReviewChat       int64            `gorm:"column:join_review_chat;default:0" json:"-"`                                       // chat join requests are reviewed in; 0 = off. Not backed up: it points outside the chat.
+	JoinQuestionsTimeout int              `gorm:"column:join_questions_timeout;default:60" json:"join_questions_timeout,omitempty"` // minutes applicants have to answer the join questions
+	WelcomeTopic         int64            `gorm:"column:welcome_topic;default:0" json:"welcome_topic,omitempty"`                    // forum topic welcome and captcha messages go to; 0 = where the member joined
+	CreatedAt            time.Time        `gorm:"column:created_at" json:"created_at,omitempty"`
+	UpdatedAt            time.Time        `gorm:"column:updated_at" json:"updated_at,omitempty"`
 }
 
 func (GreetingSettings) TableName() string {
@@ -0,0 +1,77 @@
+package models
+
+import "time"
+
+// Join request statuses. A request stays pending until an admin decides on
+// it from its review card or the user is seen joining the chat.
+const (
+	JoinRequestPending  = "pending"
+	JoinRequestApproved = "approved"
+	JoinRequestDeclined = "declined"
+	JoinRequestBanned   = "banned"
+)
+
+// JoinRequest is a chat join request posted for admin review, kept after the
+// decision as an audit record of who decided what and when.
+type JoinRequest struct {
+	ID              uint       `gorm:"primaryKey;autoIncrement" json:"-"`
+	ChatId          int64      `gorm:"column:chat_id;not null;index:idx_join_requests_chat_status" json:"chat_id,omitempty"`
+	UserId          int64      `gorm:"column:user_id;not null;index" json:"user_id,omitempty"`
+	FirstName       string     `gorm:"column:first_name" json:"first_name,omitempty"`
+	Username        string     `gorm:"column:username" json:"username,omitempty"`
+	Bio             string     `gorm:"column:bio;type:text" json:"bio,omitempty"`
+	InviteLink      string     `gorm:"column:invite_link" json:"invite_link,omitempty"` // name or URL of the link used, if any
+	Status          string     `gorm:"column:status;not null;index:idx_join_requests_chat_status" json:"status,omitempty"`
+	ReviewChatId    int64      `gorm:"column:review_chat_id" json:"review_chat_id,omitempty"`
+	ReviewMessageId int64      `gorm:"column:review_message_id" json:"review_message_id,omitempty"`
+	DecidedBy       int64      `gorm:"column:decided_by" json:"decided_by,omitempty"` // 0 when decided outside the bot, the bot ID when automatic
+	DecidedAt       *time.Time `gorm:"column:decided_at" json:"decided_at,omitempty"`
+	RequestedAt     time.Time  `gorm:"column:requested_at;not null" json:"requested_at,omitempty"`
+	CreatedAt       time.Time  `gorm:"column:created_at" json:"created_at,omitempty"`
+	UpdatedAt       time.Time  `gorm:"column:updated_at" json:"updated_at,omitempty"`
+}
+
+func (JoinRequest) TableName() string {
+	return "join_requests"
+}
+
+// MaxJoinQuestions is how many questions a chat can ask join applicants.
+const MaxJoinQuestions = 5
+
+// JoinQuestion is one question applicants to a chat answer in a private chat
+// with the bot before their join request is decided.
+type JoinQuestion struct {
+	ID        uint      `gorm:"primaryKey;autoIncrement" json:"-"`
+	ChatId    int64     `gorm:"column:chat_id;not null;uniqueIndex:uk_join_questions_chat_position" json:"chat_id,omitempty"`
+	Position  int       `gorm:"column:position;not null;uniqueIndex:uk_join_questions_chat_position" json:"position,omitempty"`
+	Question  string    `gorm:"column:question;type:text;not null" json:"question,omitempty"`
+	Answer    string    `gorm:"column:answer;type:text" json:"answer,omitempty"` // accepted answers separated by ";", empty = read by admins
+	CreatedAt time.Time `gorm:"column:created_at" json:"created_at,omitempty"`
+	UpdatedAt time.Time `gorm:"column:updated_at" json:"updated_at,omitempty"`
+}
+
+func (JoinQuestion) TableName() string {
+	return "join_questions"
+}
+
+// JoinApplication is a join request whose applicant is answering the chat's
+// questions. The questions are copied in when the application starts, so
+// edits by admins do not affect applicants already answering.
+type JoinApplication struct {
+	ID         uint        `gorm:"primaryKey;autoIncrement" json:"-"`
+	ChatId     int64       `gorm:"column:chat_id;not null;uniqueIndex:uk_join_applications_chat_user" json:"chat_id,omitempty"`
+	UserId     int64       `gorm:"column:user_id;not null;uniqueIndex:uk_join_applications_chat_user;index" json:"user_id,omitempty"`
+	UserChatId int64       `gorm:"column:user_chat_id;not null" json:"user_chat_id,omitempty"`
+	ChatTitle  string      `gorm:"column:chat_title" json:"chat_title,omitempty"`
+	Questions  StringArray `gorm:"column:questions;type:jsonb" json:"questions,omitempty"`
+	Expected   StringArray `gorm:"column:expected;type:jsonb" json:"expected,omitempty"`
+	Answers    StringArray `gorm:"column:answers;type:jsonb" json:"answers,omitempty"`
+	Started    bool        `gorm:"column:started;not null;default:false" json:"started,omitempty"` // set once the applicant opens the questionnaire
+	ExpiresAt  time.Time   `gorm:"column:expires_at;not null;index" json:"expires_at,omitempty"`
+	CreatedAt  time.Time   `gorm:"column:created_at" json:"created_at,omitempty"`
+	UpdatedAt  time.Time   `gorm:"column:updated_at" json:"updated_at,omitempty"`
+}
+
+func (JoinApplication) TableName() string {
+	return "join_applications"
+}
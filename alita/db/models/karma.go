@@ -0,0 +1,53 @@
+package models
+
+import "time"
+
+// KarmaSettings configures karma in a chat: what gives karma, how often a
+// member can give it to the same person, and whether enough karma approves
+// a member.
+type KarmaSettings struct {
+	ID              uint        `gorm:"primaryKey;autoIncrement" json:"-"`
+	ChatId          int64       `gorm:"column:chat_id;uniqueIndex;not null" json:"chat_id,omitempty"`
+	Enabled         bool        `gorm:"column:enabled;not null;default:false" json:"enabled,omitempty"`
+	Reactions       bool        `gorm:"column:reactions;not null;default:true" json:"reactions,omitempty"`          // 👍 reactions give karma
+	Triggers        StringArray `gorm:"column:triggers;type:jsonb;not null;default:'[]'" json:"triggers,omitempty"` // in addition to the built-in ones
+	CooldownSeconds int         `gorm:"column:cooldown_seconds;not null;check:chk_karma_settings_cooldown,cooldown_seconds >= 0" json:"cooldown_seconds,omitempty"`
+	ApproveAt       int         `gorm:"column:approve_at;not null;default:0;check:chk_karma_settings_approve_at,approve_at >= 0" json:"approve_at,omitempty"` // 0 = never approve
+	ApproveScopes   StringArray `gorm:"column:approve_scopes;type:jsonb;not null;default:'[]'" json:"approve_scopes,omitempty"`                               // required with approve_at
+	CreatedAt       time.Time   `gorm:"column:created_at" json:"created_at,omitempty"`
+	UpdatedAt       time.Time   `gorm:"column:updated_at" json:"updated_at,omitempty"`
+}
+
+func (KarmaSettings) TableName() string {
+	return "karma_settings"
+}
+
+// KarmaUser is the karma of a member in a chat.
+type KarmaUser struct {
+	ID        uint      `gorm:"primaryKey;autoIncrement" json:"-"`
+	ChatId    int64     `gorm:"column:chat_id;not null;uniqueIndex:uk_karma_users_chat_user;index:idx_karma_users_chat_karma,priority:1" json:"chat_id,omitempty"`
+	UserId    int64     `gorm:"column:user_id;not null;uniqueIndex:uk_karma_users_chat_user" json:"user_id,omitempty"`
+	Karma     int       `gorm:"column:karma;not null;default:0;index:idx_karma_users_chat_karma,priority:2" json:"karma,omitempty"`
+	CreatedAt time.Time `gorm:"column:created_at" json:"created_at,omitempty"`
+	UpdatedAt time.Time `gorm:"column:updated_at" json:"updated_at,omitempty"`
+}
+
+func (KarmaUser) TableName() string {
+	return "karma_users"
+}
+
+// KarmaVote is the point of karma a member gave for one message. A member
+// gives each message karma at most once, and taking a 👍 back removes the
+// vote and its point.
+type KarmaVote struct {
+	ID         uint      `gorm:"primaryKey;autoIncrement" json:"-"`
+	ChatId     int64     `gorm:"column:chat_id;not null;uniqueIndex:uk_karma_votes_chat_message_giver,priority:1" json:"chat_id,omitempty"`
+	MessageId  int64     `gorm:"column:message_id;not null;uniqueIndex:uk_karma_votes_chat_message_giver,priority:2" json:"message_id,omitempty"`
+	GiverId    int64     `gorm:"column:giver_id;not null;uniqueIndex:uk_karma_votes_chat_message_giver,priority:3" json:"giver_id,omitempty"`
+	ReceiverId int64     `gorm:"column:receiver_id;not null" json:"receiver_id,omitempty"`
+	CreatedAt  time.Time `gorm:"column:created_at" json:"created_at,omitempty"`
+}
+
+func (KarmaVote) TableName() string {
+	return "karma_votes"
+}
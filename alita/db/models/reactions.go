@@ -2,16 +2,37 @@ package models
 
 import "time"
 
-// Reactions stores per-chat keyword-to-emoji reaction mappings.
+// Reactions stores a per-chat keyword reaction rule: the reactions to pick
+// from when a message contains the keyword, how likely the bot is to react
+// and how long it waits before reacting to the keyword again.
 type Reactions struct {
-	ID        uint      `gorm:"primaryKey;autoIncrement" json:"-"`
-	ChatID    int64     `gorm:"column:chat_id;not null;uniqueIndex:idx_reactions_chat_keyword" json:"chat_id,omitempty"`
-	Keyword   string    `gorm:"column:keyword;not null;uniqueIndex:idx_reactions_chat_keyword" json:"keyword,omitempty"`
-	Emoji     string    `gorm:"column:emoji;not null" json:"emoji,omitempty"`
-	CreatedAt time.Time `gorm:"column:created_at" json:"created_at,omitempty"`
-	UpdatedAt time.Time `gorm:"column:updated_at" json:"updated_at,omitempty"`
+	ID              uint               `gorm:"primaryKey;autoIncrement" json:"-"`
+	ChatID          int64              `gorm:"column:chat_id;not null;uniqueIndex:idx_reactions_chat_keyword" json:"chat_id,omitempty"`
+	Keyword         string             `gorm:"column:keyword;not null;uniqueIndex:idx_reactions_chat_keyword" json:"keyword,omitempty"`
+	Emojis          ReactionEmojiArray `gorm:"column:emojis;type:jsonb;not null;default:'[]'" json:"emojis,omitempty"`
+	Probability     int                `gorm:"column:probability;not null;default:100;check:chk_reactions_probability,probability BETWEEN 1 AND 100" json:"probability,omitempty"` // percent of matching messages reacted to
+	CooldownSeconds int                `gorm:"column:cooldown_seconds;not null;default:0;check:chk_reactions_cooldown,cooldown_seconds >= 0" json:"cooldown_seconds,omitempty"`    // 0 = no cooldown
+	CreatedAt       time.Time          `gorm:"column:created_at" json:"created_at,omitempty"`
+	UpdatedAt       time.Time          `gorm:"column:updated_at" json:"updated_at,omitempty"`
 }
 
 func (Reactions) TableName() string {
 	return "reactions"
 }
+
+// ReactionAction maps a reaction to a moderation action: when an admin
+// reacts to a message with it, the bot acts on the author of the message.
+type ReactionAction struct {
+	ID              uint      `gorm:"primaryKey;autoIncrement" json:"-"`
+	ChatID          int64     `gorm:"column:chat_id;not null;uniqueIndex:uk_reaction_actions_chat_emoji" json:"chat_id,omitempty"`
+	Emoji           string    `gorm:"column:emoji;not null;uniqueIndex:uk_reaction_actions_chat_emoji" json:"emoji,omitempty"`
+	CustomEmojiID   string    `gorm:"column:custom_emoji_id;not null;default:'';uniqueIndex:uk_reaction_actions_chat_emoji" json:"custom_emoji_id,omitempty"`
+	Action          string    `gorm:"column:action;not null" json:"action,omitempty"`
+	DurationSeconds int       `gorm:"column:duration_seconds;not null;default:0;check:chk_reaction_actions_duration,duration_seconds >= 0" json:"duration_seconds,omitempty"` // mutes and bans only; 0 = permanent
+	CreatedAt       time.Time `gorm:"column:created_at" json:"created_at,omitempty"`
+	UpdatedAt       time.Time `gorm:"column:updated_at" json:"updated_at,omitempty"`
+}
+
+func (ReactionAction) TableName() string {
+	return "reaction_actions"
+}
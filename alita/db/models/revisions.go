@@ -0,0 +1,68 @@
+package models
+
+import "time"
+
+// Revision actions record why a snapshot of a note or filter was taken.
+const (
+	RevisionActionOverwrite = "overwrite"
+	RevisionActionDelete    = "delete"
+	RevisionActionClearAll  = "clearall"
+	RevisionActionRestore   = "restore"
+)
+
+// RevisionRetention is how long note and filter revisions are kept, and so
+// how long an overwrite, /clear, /clearall or /stopall can be rolled back.
+const RevisionRetention = 30 * 24 * time.Hour
+
+// NoteRevision is the full content of a note as it was before an admin
+// overwrote, deleted or restored over it.
+type NoteRevision struct {
+	ID          uint           `gorm:"primaryKey;autoIncrement" json:"-"`
+	ChatId      int64          `gorm:"column:chat_id;not null;uniqueIndex:uk_note_revisions_chat_name_rev" json:"chat_id,omitempty"`
+	NoteName    string         `gorm:"column:note_name;not null;uniqueIndex:uk_note_revisions_chat_name_rev" json:"note_name,omitempty"`
+	Revision    int            `gorm:"column:revision;not null;uniqueIndex:uk_note_revisions_chat_name_rev" json:"revision,omitempty"`
+	Action      string         `gorm:"column:action;not null" json:"action,omitempty"`
+	EditorId    int64          `gorm:"column:editor_id" json:"editor_id,omitempty"`
+	Batch       int64          `gorm:"column:batch;not null;default:0" json:"batch,omitempty"` // shared by the revisions of one /clearall
+	Category    string         `gorm:"column:category;not null;default:''" json:"category,omitempty"`
+	NoteContent string         `gorm:"column:note_content;type:text" json:"note_content,omitempty"`
+	FileID      string         `gorm:"column:file_id" json:"file_id,omitempty"`
+	MsgType     int            `gorm:"column:msg_type" json:"msg_type,omitempty"`
+	Buttons     ButtonArray    `gorm:"column:buttons;type:jsonb" json:"buttons,omitempty"`
+	Media       MediaItemArray `gorm:"column:media;type:jsonb" json:"media,omitempty"`
+	AdminOnly   bool           `gorm:"column:admin_only;default:false" json:"admin_only,omitempty"`
+	PrivateOnly bool           `gorm:"column:private_only;default:false" json:"private_only,omitempty"`
+	GroupOnly   bool           `gorm:"column:group_only;default:false" json:"group_only,omitempty"`
+	WebPreview  bool           `gorm:"column:web_preview" json:"web_preview,omitempty"` // no default: a snapshot keeps false as is
+	IsProtected bool           `gorm:"column:is_protected;default:false" json:"is_protected,omitempty"`
+	NoNotif     bool           `gorm:"column:no_notif;default:false" json:"no_notif,omitempty"`
+	CreatedAt   time.Time      `gorm:"column:created_at;index" json:"created_at,omitempty"`
+}
+
+func (NoteRevision) TableName() string {
+	return "note_revisions"
+}
+
+// FilterRevision is the full content of a filter as it was before an admin
+// overwrote, deleted or restored over it.
+type FilterRevision struct {
+	ID          uint           `gorm:"primaryKey;autoIncrement" json:"-"`
+	ChatId      int64          `gorm:"column:chat_id;not null;uniqueIndex:uk_filter_revisions_chat_keyword_rev" json:"chat_id,omitempty"`
+	KeyWord     string         `gorm:"column:keyword;not null;uniqueIndex:uk_filter_revisions_chat_keyword_rev" json:"keyword,omitempty"`
+	Revision    int            `gorm:"column:revision;not null;uniqueIndex:uk_filter_revisions_chat_keyword_rev" json:"revision,omitempty"`
+	Action      string         `gorm:"column:action;not null" json:"action,omitempty"`
+	EditorId    int64          `gorm:"column:editor_id" json:"editor_id,omitempty"`
+	Batch       int64          `gorm:"column:batch;not null;default:0" json:"batch,omitempty"` // shared by the revisions of one /stopall
+	ThreadId    int64          `gorm:"column:thread_id;not null;default:0" json:"thread_id,omitempty"`
+	FilterReply string         `gorm:"column:filter_reply" json:"filter_reply,omitempty"`
+	MsgType     int            `gorm:"column:msgtype" json:"msgtype,omitempty"`
+	FileID      string         `gorm:"column:fileid" json:"fileid,omitempty"`
+	NoNotif     bool           `gorm:"column:nonotif;default:false" json:"nonotif,omitempty"`
+	Buttons     ButtonArray    `gorm:"column:filter_buttons;type:jsonb" json:"filter_buttons,omitempty"`
+	Media       MediaItemArray `gorm:"column:media;type:jsonb" json:"media,omitempty"`
+	CreatedAt   time.Time      `gorm:"column:created_at;index" json:"created_at,omitempty"`
+}
+
+func (FilterRevision) TableName() string {
+	return "filter_revisions"
+}
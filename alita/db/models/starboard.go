@@ -0,0 +1,39 @@
+package models
+
+import "time"
+
+// StarboardSettings configures the starboard of a chat: messages that get
+// Threshold reactions of Emoji are reposted to the starboard chat.
+type StarboardSettings struct {
+	ID            uint      `gorm:"primaryKey;autoIncrement" json:"-"`
+	ChatId        int64     `gorm:"column:chat_id;uniqueIndex;not null" json:"chat_id,omitempty"`
+	ChannelId     int64     `gorm:"column:channel_id;not null;default:0" json:"channel_id,omitempty"` // 0 = starboard off
+	Emoji         string    `gorm:"column:emoji;not null" json:"emoji,omitempty"`
+	CustomEmojiID string    `gorm:"column:custom_emoji_id;not null;default:''" json:"custom_emoji_id,omitempty"`
+	Threshold     int       `gorm:"column:threshold;not null;check:chk_starboard_settings_threshold,threshold >= 1" json:"threshold,omitempty"`
+	CreatedAt     time.Time `gorm:"column:created_at" json:"created_at,omitempty"`
+	UpdatedAt     time.Time `gorm:"column:updated_at" json:"updated_at,omitempty"`
+}
+
+func (StarboardSettings) TableName() string {
+	return "starboard_settings"
+}
+
+// StarboardEntry tracks the starboard reactions of a message and, once it
+// was reposted, where its starboard post is.
+type StarboardEntry struct {
+	ID               uint      `gorm:"primaryKey;autoIncrement" json:"-"`
+	ChatId           int64     `gorm:"column:chat_id;not null;uniqueIndex:uk_starboard_entries_chat_message" json:"chat_id,omitempty"`
+	MessageId        int64     `gorm:"column:message_id;not null;uniqueIndex:uk_starboard_entries_chat_message" json:"message_id,omitempty"`
+	Stars            int       `gorm:"column:stars;not null;default:0;check:chk_starboard_entries_stars,stars >= 0" json:"stars,omitempty"`
+	Excluded         bool      `gorm:"column:excluded;not null;default:false" json:"excluded,omitempty"`               // locked or blacklisted content, never reposted
+	StarboardChatId  int64     `gorm:"column:starboard_chat_id;not null;default:0" json:"starboard_chat_id,omitempty"` // set while posting, 0 = not reposted
+	ForwardMessageId int64     `gorm:"column:forward_message_id;not null;default:0" json:"forward_message_id,omitempty"`
+	CounterMessageId int64     `gorm:"column:counter_message_id;not null;default:0" json:"counter_message_id,omitempty"` // edited as the count changes
+	CreatedAt        time.Time `gorm:"column:created_at" json:"created_at,omitempty"`
+	UpdatedAt        time.Time `gorm:"column:updated_at" json:"updated_at,omitempty"`
+}
+
+func (StarboardEntry) TableName() string {
+	return "starboard_entries"
+}
@@ -0,0 +1,37 @@
+package models
+
+import "time"
+
+// TrustSettings configures the newcomer profile of a chat: who counts as a
+// newcomer, and the locks and flood limit that apply only to them.
+type TrustSettings struct {
+	ID               uint        `gorm:"primaryKey;autoIncrement" json:"-"`
+	ChatId           int64       `gorm:"column:chat_id;uniqueIndex;not null" json:"chat_id,omitempty"`
+	NewcomerHours    int         `gorm:"column:newcomer_hours;not null;check:chk_trust_settings_hours,newcomer_hours >= 0" json:"newcomer_hours,omitempty"`             // 0 = no time rule
+	NewcomerMessages int         `gorm:"column:newcomer_messages;not null;check:chk_trust_settings_messages,newcomer_messages >= 0" json:"newcomer_messages,omitempty"` // 0 = no message rule
+	NewcomerLocks    StringArray `gorm:"column:newcomer_locks;type:jsonb;not null;default:'[]'" json:"newcomer_locks,omitempty"`
+	NewcomerFlood    int         `gorm:"column:newcomer_flood;not null;default:0;check:chk_trust_settings_flood,newcomer_flood >= 0" json:"newcomer_flood,omitempty"` // 0 = the chat's own limit
+	CreatedAt        time.Time   `gorm:"column:created_at" json:"created_at,omitempty"`
+	UpdatedAt        time.Time   `gorm:"column:updated_at" json:"updated_at,omitempty"`
+}
+
+func (TrustSettings) TableName() string {
+	return "trust_settings"
+}
+
+// TrustMember is what the bot knows about a member it saw join a chat. A
+// member who leaves and joins again starts over.
+type TrustMember struct {
+	ID            uint      `gorm:"primaryKey;autoIncrement" json:"-"`
+	ChatId        int64     `gorm:"column:chat_id;not null;uniqueIndex:uk_trust_members_chat_user" json:"chat_id,omitempty"`
+	UserId        int64     `gorm:"column:user_id;not null;uniqueIndex:uk_trust_members_chat_user" json:"user_id,omitempty"`
+	JoinedAt      time.Time `gorm:"column:joined_at;not null" json:"joined_at,omitempty"`
+	Messages      int       `gorm:"column:messages;not null;default:0" json:"messages,omitempty"` // counted up to trust.MaxCountedMessages
+	CaptchaPassed bool      `gorm:"column:captcha_passed;not null;default:false" json:"captcha_passed,omitempty"`
+	CreatedAt     time.Time `gorm:"column:created_at" json:"created_at,omitempty"`
+	UpdatedAt     time.Time `gorm:"column:updated_at" json:"updated_at,omitempty"`
+}
+
+func (TrustMember) TableName() string {
+	return "trust_members"
+}
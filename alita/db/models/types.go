@@ -103,3 +103,73 @@ func (ia Int64Array) Value() (driver.Value, error) {
 	}
 	return json.Marshal(ia)
 }
+
+// MediaItem is one photo, video, document or audio of a media album saved as
+// a note or filter.
+type MediaItem struct {
+	FileID  string `json:"file_id"`
+	MsgType int    `json:"msg_type"`
+	Caption string `json:"caption,omitempty"` // HTML
+}
+
+// MediaItemArray is a custom type for handling the ordered items of a media
+// album as JSONB
+type MediaItemArray []MediaItem
+
+// Scan implements the Scanner interface for database deserialization of MediaItemArray.
+func (ma *MediaItemArray) Scan(value any) error {
+	if value == nil {
+		*ma = MediaItemArray{}
+		return nil
+	}
+
+	data, err := jsonbBytes(value)
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(data, ma)
+}
+
+// Value implements the driver Valuer interface for database serialization of MediaItemArray.
+func (ma MediaItemArray) Value() (driver.Value, error) {
+	if len(ma) == 0 {
+		return "[]", nil
+	}
+	return json.Marshal(ma)
+}
+
+// ReactionEmoji is one reaction of a keyword reaction rule: a regular emoji,
+// or a custom emoji when CustomEmojiID is set, in which case Emoji is the
+// emoji it stands in for.
+type ReactionEmoji struct {
+	Emoji         string `json:"emoji"`
+	CustomEmojiID string `json:"custom_emoji_id,omitempty"`
+}
+
+// ReactionEmojiArray is a custom type for handling the reactions of a keyword
+// reaction rule as JSONB
+type ReactionEmojiArray []ReactionEmoji
+
+// Scan implements the Scanner interface for database deserialization of ReactionEmojiArray.
+func (ra *ReactionEmojiArray) Scan(value any) error {
+	if value == nil {
+		*ra = ReactionEmojiArray{}
+		return nil
+	}
+
+	data, err := jsonbBytes(value)
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(data, ra)
+}
+
+// Value implements the driver Valuer interface for database serialization of ReactionEmojiArray.
+func (ra ReactionEmojiArray) Value() (driver.Value, error) {
+	if len(ra) == 0 {
+		return "[]", nil
+	}
+	return json.Marshal(ra)
+}
@@ -1,7 +1,10 @@
 package notes
 
 import (
+	"cmp"
 	"errors"
+	"slices"
+	"strings"
 	"time"
 
 	log "github.com/sirupsen/logrus"
@@ -62,6 +65,21 @@ func getAllChatNotes(chatId int64) (notes []*models.Notes) {
 	return
 }
 
+// GetAllNotes returns every note saved in a chat, including admin-only notes.
+// Returns an empty slice if no notes are found or an error occurs.
+// Results are cached until the notes of the chat change.
+func GetAllNotes(chatID int64) []*models.Notes {
+	allNotes, err := cache.GetFromCacheOrLoad(allNotesCacheKey(chatID), cache.CacheTTLNotesList, func() ([]*models.Notes, error) {
+		var notes []*models.Notes
+		err := db.GetRecords(&notes, models.Notes{ChatId: chatID})
+		return notes, err
+	})
+	if err != nil {
+		return getAllChatNotes(chatID)
+	}
+	return allNotes
+}
+
 // GetNotes returns the notes settings for the specified chat ID.
 // This is the public interface to access notes settings.
 func GetNotes(chatID int64) *models.NotesSettings {
@@ -86,15 +104,45 @@ func GetNote(chatID int64, keyword string) (noteSrc *models.Notes) {
 // cachedNoteInfo is the cache payload for notes_list to preserve adminOnly filtering.
 type cachedNoteInfo struct {
 	Name      string
+	Category  string
 	AdminOnly bool
 }
 
+// NoteIndexEntry is a note name with the category it is filed under.
+type NoteIndexEntry struct {
+	Name     string
+	Category string
+}
+
+// categorySeparator separates the category from the note name in /save.
+const categorySeparator = "/"
+
+// SplitNoteCategory splits a "/save" keyword of the form "category/name" into
+// its category and note name. Keywords without a separator have no category.
+// ok is false when either side of the separator is empty.
+func SplitNoteCategory(keyword string) (category, name string, ok bool) {
+	category, name, found := strings.Cut(keyword, categorySeparator)
+	if !found {
+		return "", keyword, keyword != ""
+	}
+	category, name = strings.TrimSpace(category), strings.TrimSpace(name)
+	if category == "" || name == "" || strings.Contains(name, categorySeparator) {
+		return "", "", false
+	}
+	return category, name, true
+}
+
 func notesListCacheKey(chatID int64) string {
 	return cache.CacheKey("notes_list", chatID)
 }
 
+func allNotesCacheKey(chatID int64) string {
+	return cache.CacheKey("notes_all", chatID)
+}
+
 func invalidateNotesCache(chatID int64) {
 	cache.DeleteCache(notesListCacheKey(chatID))
+	cache.DeleteCache(allNotesCacheKey(chatID))
 }
 
 // GetNotesList retrieves a list of all note names for a specific chat ID.
@@ -102,33 +150,93 @@ func invalidateNotesCache(chatID int64) {
 // Returns an empty slice if no notes are found.
 // Results are cached with stampede protection for performance.
 func GetNotesList(chatID int64, admin bool) []string {
-	cacheKey := notesListCacheKey(chatID)
-	entries, err := cache.GetFromCacheOrLoad(cacheKey, cache.CacheTTLNotesList, func() ([]cachedNoteInfo, error) {
+	entries := getNoteInfos(chatID)
+	out := make([]string, 0, len(entries))
+	for _, e := range entries {
+		if admin || !e.AdminOnly {
+			out = append(out, e.Name)
+		}
+	}
+	return out
+}
+
+// GetNoteIndex returns the notes of a chat with their categories, sorted by
+// category and then by name. Uncategorized notes sort first.
+// The admin parameter determines whether to include admin-only notes.
+func GetNoteIndex(chatID int64, admin bool) []NoteIndexEntry {
+	entries := getNoteInfos(chatID)
+	out := make([]NoteIndexEntry, 0, len(entries))
+	for _, e := range entries {
+		if admin || !e.AdminOnly {
+			out = append(out, NoteIndexEntry{Name: e.Name, Category: e.Category})
+		}
+	}
+	slices.SortFunc(out, func(a, b NoteIndexEntry) int {
+		return cmp.Or(strings.Compare(a.Category, b.Category), strings.Compare(a.Name, b.Name))
+	})
+	return out
+}
+
+// getNoteInfos loads the cached name, category and admin flag of every note
+// in a chat, falling back to the database on cache errors.
+func getNoteInfos(chatID int64) []cachedNoteInfo {
+	load := func() ([]cachedNoteInfo, error) {
 		notes := getAllChatNotes(chatID)
 		infos := make([]cachedNoteInfo, 0, len(notes))
 		for _, n := range notes {
-			infos = append(infos, cachedNoteInfo{Name: n.NoteName, AdminOnly: n.AdminOnly})
+			infos = append(infos, cachedNoteInfo{Name: n.NoteName, Category: n.Category, AdminOnly: n.AdminOnly})
 		}
 		return infos, nil
-	})
+	}
+	entries, err := cache.GetFromCacheOrLoad(notesListCacheKey(chatID), cache.CacheTTLNotesList, load)
 	if err != nil {
 		// Fallback to direct DB load on cache error
-		noteSrc := getAllChatNotes(chatID)
-		var out []string
-		for _, note := range noteSrc {
-			if admin || !note.AdminOnly {
-				out = append(out, note.NoteName)
-			}
-		}
-		return out
+		entries, _ = load()
 	}
-	out := make([]string, 0, len(entries))
-	for _, e := range entries {
-		if admin || !e.AdminOnly {
-			out = append(out, e.Name)
+	return entries
+}
+
+// SearchNotes returns the names of up to limit notes whose content matches
+// query. On PostgreSQL this is a full-text search backed by the
+// idx_notes_content_fts index, ranked by relevance; other dialects fall back
+// to a case-insensitive match of every word.
+// The admin parameter determines whether to include admin-only notes.
+func SearchNotes(chatID int64, query string, admin bool, limit int) ([]string, error) {
+	words := strings.Fields(query)
+	if len(words) == 0 {
+		return []string{}, nil
+	}
+
+	tx := db.DB.Model(&models.Notes{}).Where("chat_id = ?", chatID)
+	if !admin {
+		tx = tx.Where("admin_only = ?", false)
+	}
+	if tx.Name() == "postgres" {
+		const document = "to_tsvector('simple', coalesce(note_content, ''))"
+		tx = tx.Where(document+" @@ plainto_tsquery('simple', ?)", query).
+			Order(clause.OrderBy{Expression: clause.Expr{
+				SQL:  "ts_rank(" + document + ", plainto_tsquery('simple', ?)) DESC, note_name",
+				Vars: []any{query},
+			}})
+	} else {
+		for _, word := range words {
+			tx = tx.Where(`LOWER(note_content) LIKE ? ESCAPE '\'`, "%"+escapeLike(strings.ToLower(word))+"%")
 		}
+		tx = tx.Order("note_name")
 	}
-	return out
+
+	var names []string
+	err := tx.Limit(limit).Pluck("note_name", &names).Error
+	if err != nil {
+		log.Errorf("[Database][SearchNotes]: %d - %v", chatID, err)
+		return nil, err
+	}
+	return names, nil
+}
+
+// escapeLike escapes the LIKE wildcards in s.
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(s)
 }
 
 // DoesNoteExists checks whether a note with the given name exists in the specified chat.
@@ -151,15 +259,17 @@ func DoesNoteExists(chatID int64, noteName string) bool {
 // Explicit overwrite confirmation uses UpdateNote.
 // Returns an error if the operation fails.
 // Supports various note types including text, media, and custom buttons.
-func AddNote(chatID int64, noteName, replyText, fileID string, buttons models.ButtonArray, filtType int, pvtOnly, grpOnly, adminOnly, webPrev, isProtected, noNotif bool) error {
+func AddNote(chatID int64, noteName, category, replyText, fileID string, buttons models.ButtonArray, media models.MediaItemArray, filtType int, pvtOnly, grpOnly, adminOnly, webPrev, isProtected, noNotif bool) error {
 	now := time.Now().UTC()
 	noterc := map[string]any{
 		"chat_id":      chatID,
 		"note_name":    noteName,
+		"category":     category,
 		"note_content": replyText,
 		"msg_type":     filtType,
 		"file_id":      fileID,
 		"buttons":      buttons,
+		"media":        media,
 		"admin_only":   adminOnly,
 		"private_only": pvtOnly,
 		"group_only":   grpOnly,
@@ -185,52 +295,80 @@ func AddNote(chatID int64, noteName, replyText, fileID string, buttons models.Bu
 }
 
 // UpdateNote replaces an existing note without recreating one removed while an
-// overwrite confirmation was pending.
-func UpdateNote(chatID int64, noteName, replyText, fileID string, buttons models.ButtonArray, filtType int, pvtOnly, grpOnly, adminOnly, webPrev, isProtected, noNotif bool) (bool, error) {
-	result := db.DB.Model(&models.Notes{}).
-		Where("chat_id = ? AND note_name = ?", chatID, noteName).
-		Updates(map[string]any{
-			"note_content": replyText,
-			"msg_type":     filtType,
-			"file_id":      fileID,
-			"buttons":      buttons,
-			"admin_only":   adminOnly,
-			"private_only": pvtOnly,
-			"group_only":   grpOnly,
-			"web_preview":  webPrev,
-			"is_protected": isProtected,
-			"no_notif":     noNotif,
-			"updated_at":   time.Now().UTC(),
-		})
-	if result.Error != nil {
-		log.Errorf("[Database][UpdateNote]: %d - %v", chatID, result.Error)
-		return false, result.Error
+// overwrite confirmation was pending. The previous content is kept as a
+// revision attributed to editorID.
+func UpdateNote(chatID, editorID int64, noteName, category, replyText, fileID string, buttons models.ButtonArray, media models.MediaItemArray, filtType int, pvtOnly, grpOnly, adminOnly, webPrev, isProtected, noNotif bool) (bool, error) {
+	var updated bool
+	err := db.DB.Transaction(func(tx *gorm.DB) error {
+		archived, err := archiveNotes(tx, chatID, editorID, models.RevisionActionOverwrite, 0, noteName)
+		if err != nil || archived == 0 {
+			return err
+		}
+		result := tx.Model(&models.Notes{}).
+			Where("chat_id = ? AND note_name = ?", chatID, noteName).
+			Updates(map[string]any{
+				"category":     category,
+				"note_content": replyText,
+				"msg_type":     filtType,
+				"file_id":      fileID,
+				"buttons":      buttons,
+				"media":        media,
+				"admin_only":   adminOnly,
+				"private_only": pvtOnly,
+				"group_only":   grpOnly,
+				"web_preview":  webPrev,
+				"is_protected": isProtected,
+				"no_notif":     noNotif,
+				"updated_at":   time.Now().UTC(),
+			})
+		updated = result.RowsAffected > 0
+		return result.Error
+	})
+	if err != nil {
+		log.Errorf("[Database][UpdateNote]: %d - %v", chatID, err)
+		return false, err
 	}
-	if result.RowsAffected > 0 {
+	if updated {
 		invalidateNotesCache(chatID)
 	}
-	return result.RowsAffected > 0, nil
+	return updated, nil
 }
 
-// RemoveNote deletes a note with the specified name from the chat.
+// RemoveNote deletes a note with the specified name from the chat, keeping
+// its content as a revision attributed to editorID.
 // Returns an error if the operation fails.
-func RemoveNote(chatID int64, noteName string) error {
-	// Directly attempt to delete the note without checking existence first
-	result := db.DB.Where("chat_id = ? AND note_name = ?", chatID, noteName).Delete(&models.Notes{})
-	if result.Error != nil {
-		log.Errorf("[Database][RemoveNote]: %d - %v", chatID, result.Error)
+func RemoveNote(chatID, editorID int64, noteName string) error {
+	var removed bool
+	err := db.DB.Transaction(func(tx *gorm.DB) error {
+		archived, err := archiveNotes(tx, chatID, editorID, models.RevisionActionDelete, 0, noteName)
+		if err != nil || archived == 0 {
+			return err
+		}
+		result := tx.Where("chat_id = ? AND note_name = ?", chatID, noteName).Delete(&models.Notes{})
+		removed = result.RowsAffected > 0
 		return result.Error
+	})
+	if err != nil {
+		log.Errorf("[Database][RemoveNote]: %d - %v", chatID, err)
+		return err
 	}
-	if result.RowsAffected > 0 {
+	if removed {
 		invalidateNotesCache(chatID)
 	}
 	return nil
 }
 
 // RemoveAllNotes deletes all notes for the specified chat ID from the database.
+// Every note is kept as a revision of the same batch, so the whole /clearall
+// can be undone with RestoreClearedNotes.
 // Returns an error if the operation fails.
-func RemoveAllNotes(chatID int64) error {
-	err := db.DB.Where("chat_id = ?", chatID).Delete(&models.Notes{}).Error
+func RemoveAllNotes(chatID, editorID int64) error {
+	err := db.DB.Transaction(func(tx *gorm.DB) error {
+		if _, err := archiveNotes(tx, chatID, editorID, models.RevisionActionClearAll, time.Now().UnixNano()); err != nil {
+			return err
+		}
+		return tx.Where("chat_id = ?", chatID).Delete(&models.Notes{}).Error
+	})
 	if err != nil {
 		log.Errorf("[Database][RemoveAllNotes]: %d - %v", chatID, err)
 		return err
@@ -70,7 +70,7 @@ func TestSaveNote(t *testing.T) {
 	})
 
 	buttons := models.ButtonArray{{Name: "click", Url: "https://example.com", SameLine: false}}
-	err := AddNote(chatID, "testnote", "note content", "", buttons, db.TEXT, false, false, false, false, false, false)
+	err := AddNote(chatID, "testnote", "", "note content", "", buttons, nil, db.TEXT, false, false, false, false, false, false)
 	if err != nil {
 		t.Fatalf("AddNote() error = %v", err)
 	}
@@ -119,7 +119,7 @@ func TestGetAllNotes(t *testing.T) {
 
 	noteNames := []string{"alpha", "beta", "gamma"}
 	for _, name := range noteNames {
-		if err := AddNote(chatID, name, "content for "+name, "", models.ButtonArray{}, db.TEXT, false, false, false, false, false, false); err != nil {
+		if err := AddNote(chatID, name, "", "content for "+name, "", models.ButtonArray{}, nil, db.TEXT, false, false, false, false, false, false); err != nil {
 			t.Fatalf("AddNote(%q) error = %v", name, err)
 		}
 	}
@@ -161,7 +161,7 @@ func TestRemoveNote(t *testing.T) {
 		}
 	})
 
-	if err := AddNote(chatID, "to-delete", "will be removed", "", models.ButtonArray{}, db.TEXT, false, false, false, false, false, false); err != nil {
+	if err := AddNote(chatID, "to-delete", "", "will be removed", "", models.ButtonArray{}, nil, db.TEXT, false, false, false, false, false, false); err != nil {
 		t.Fatalf("AddNote() error = %v", err)
 	}
 
@@ -170,7 +170,7 @@ func TestRemoveNote(t *testing.T) {
 		t.Fatalf("GetNote() returned nil before removal")
 	}
 
-	if err := RemoveNote(chatID, "to-delete"); err != nil {
+	if err := RemoveNote(chatID, 0, "to-delete"); err != nil {
 		t.Fatalf("RemoveNote() error = %v", err)
 	}
 
@@ -267,12 +267,12 @@ func TestAddNotePreservesExistingNote(t *testing.T) {
 	})
 
 	// First add
-	if err := AddNote(chatID, "dupnote", "original content", "", models.ButtonArray{}, db.TEXT, false, false, false, false, false, false); err != nil {
+	if err := AddNote(chatID, "dupnote", "", "original content", "", models.ButtonArray{}, nil, db.TEXT, false, false, false, false, false, false); err != nil {
 		t.Fatalf("AddNote() first call error = %v", err)
 	}
 
 	// A repeated add must not bypass overwrite confirmation.
-	if err := AddNote(chatID, "dupnote", "updated content", "", models.ButtonArray{}, db.TEXT, false, false, false, false, false, false); err != nil {
+	if err := AddNote(chatID, "dupnote", "", "updated content", "", models.ButtonArray{}, nil, db.TEXT, false, false, false, false, false, false); err != nil {
 		t.Fatalf("AddNote() second call error = %v", err)
 	}
 
@@ -334,7 +334,7 @@ func TestRemoveNonExistentNote(t *testing.T) {
 	})
 
 	// Removing a non-existent note should not return an error
-	if err := RemoveNote(chatID, "ghost-note"); err != nil {
+	if err := RemoveNote(chatID, 0, "ghost-note"); err != nil {
 		t.Fatalf("RemoveNote() non-existent note error = %v", err)
 	}
 }
@@ -364,7 +364,7 @@ func TestDoesNoteExists(t *testing.T) {
 		t.Fatalf("expected DoesNoteExists=false before creation")
 	}
 
-	if err := AddNote(chatID, "new-note", "hello", "", models.ButtonArray{}, db.TEXT, false, false, false, false, false, false); err != nil {
+	if err := AddNote(chatID, "new-note", "", "hello", "", models.ButtonArray{}, nil, db.TEXT, false, false, false, false, false, false); err != nil {
 		t.Fatalf("AddNote() error = %v", err)
 	}
 
@@ -402,7 +402,7 @@ func TestLoadNotesStats(t *testing.T) {
 		t.Fatalf("LoadNotesStats() chatsUsingNotes should be >= 0, got %d", chatsBefore)
 	}
 
-	if err := AddNote(chatID, "stats-note", "content", "", models.ButtonArray{}, db.TEXT, false, false, false, false, false, false); err != nil {
+	if err := AddNote(chatID, "stats-note", "", "content", "", models.ButtonArray{}, nil, db.TEXT, false, false, false, false, false, false); err != nil {
 		t.Fatalf("AddNote() error = %v", err)
 	}
 	if !DoesNoteExists(chatID, "stats-note") {
@@ -437,7 +437,7 @@ func TestLoadNotesStatsErrorBranch(t *testing.T) {
 		_ = db.DB.AutoMigrate(&models.Notes{})
 	})
 
-	if err := AddNote(1, "missing-table", "text", "", nil, db.TEXT, false, false, false, false, false, false); err == nil {
+	if err := AddNote(1, "missing-table", "", "text", "", nil, nil, db.TEXT, false, false, false, false, false, false); err == nil {
 		t.Fatal("AddNote() error = nil after notes table was dropped")
 	}
 	notes, chats := LoadNotesStats()
@@ -468,12 +468,12 @@ func TestRemoveAllNotes(t *testing.T) {
 	})
 
 	for _, name := range []string{"n1", "n2", "n3"} {
-		if err := AddNote(chatID, name, "text", "", models.ButtonArray{}, db.TEXT, false, false, false, false, false, false); err != nil {
+		if err := AddNote(chatID, name, "", "text", "", models.ButtonArray{}, nil, db.TEXT, false, false, false, false, false, false); err != nil {
 			t.Fatalf("AddNote(%q) error = %v", name, err)
 		}
 	}
 
-	if err := RemoveAllNotes(chatID); err != nil {
+	if err := RemoveAllNotes(chatID, 0); err != nil {
 		t.Fatalf("RemoveAllNotes() error = %v", err)
 	}
 
@@ -505,7 +505,7 @@ func TestAddNoteWithAdminOnly(t *testing.T) {
 	})
 
 	// Add a note with adminOnly=true
-	if err := AddNote(chatID, "admin-note", "secret content", "", models.ButtonArray{}, db.TEXT, false, false, true, false, false, false); err != nil {
+	if err := AddNote(chatID, "admin-note", "", "secret content", "", models.ButtonArray{}, nil, db.TEXT, false, false, true, false, false, false); err != nil {
 		t.Fatalf("AddNote() adminOnly error = %v", err)
 	}
 
@@ -546,10 +546,11 @@ func TestAddNotePreservesExistingUntilExplicitUpdate(t *testing.T) {
 	// Add is insert-only so an unconfirmed duplicate cannot overwrite the note.
 	if err := AddNote(
 		chatID,
-		"my-note",
+		"my-note", "",
 		"v1 content",
 		"old-file",
 		models.ButtonArray{{Name: "old", Url: "https://example.com"}},
+		nil,
 		db.PHOTO,
 		true,
 		true,
@@ -561,7 +562,7 @@ func TestAddNotePreservesExistingUntilExplicitUpdate(t *testing.T) {
 		t.Fatalf("AddNote() v1 error = %v", err)
 	}
 
-	if err := AddNote(chatID, "my-note", "v2 content", "", models.ButtonArray{}, db.TEXT, false, false, false, false, false, false); err != nil {
+	if err := AddNote(chatID, "my-note", "", "v2 content", "", models.ButtonArray{}, nil, db.TEXT, false, false, false, false, false, false); err != nil {
 		t.Fatalf("AddNote() v2 error = %v", err)
 	}
 
@@ -575,7 +576,7 @@ func TestAddNotePreservesExistingUntilExplicitUpdate(t *testing.T) {
 		t.Fatalf("duplicate AddNote() changed existing note: %+v", note)
 	}
 
-	updated, err := UpdateNote(chatID, "my-note", "v2 content", "", models.ButtonArray{}, db.TEXT, false, false, false, false, false, false)
+	updated, err := UpdateNote(chatID, 0, "my-note", "", "v2 content", "", models.ButtonArray{}, nil, db.TEXT, false, false, false, false, false, false)
 	if err != nil {
 		t.Fatalf("UpdateNote() error = %v", err)
 	}
@@ -624,7 +625,7 @@ func TestAddNoteConcurrentInsert(t *testing.T) {
 		wg.Add(1)
 		go func() {
 			defer wg.Done()
-			errs <- AddNote(chatID, "shared", "concurrent", "", nil, db.TEXT, false, false, false, false, false, false)
+			errs <- AddNote(chatID, "shared", "", "concurrent", "", nil, nil, db.TEXT, false, false, false, false, false, false)
 		}()
 	}
 	wg.Wait()
@@ -635,7 +636,7 @@ func TestAddNoteConcurrentInsert(t *testing.T) {
 		}
 	}
 
-	if err := AddNote(chatID, "shared", "final", "", nil, db.TEXT, false, false, false, false, false, false); err != nil {
+	if err := AddNote(chatID, "shared", "", "final", "", nil, nil, db.TEXT, false, false, false, false, false, false); err != nil {
 		t.Fatalf("final AddNote() error = %v", err)
 	}
 	var count int64
@@ -761,3 +762,303 @@ func TestNotesSettingsCacheDefaultsForMissingChat(t *testing.T) {
 		t.Fatalf("expected ChatId=%d, got %d", chatID, settings.ChatId)
 	}
 }
+
+func TestSplitNoteCategory(t *testing.T) {
+	tests := []struct {
+		keyword      string
+		wantCategory string
+		wantName     string
+		wantOK       bool
+	}{
+		{keyword: "install", wantName: "install", wantOK: true},
+		{keyword: "faq/install", wantCategory: "faq", wantName: "install", wantOK: true},
+		{keyword: "faq/", wantOK: false},
+		{keyword: "/install", wantOK: false},
+		{keyword: "faq/setup/install", wantOK: false},
+		{keyword: "", wantOK: false},
+	}
+	for _, tc := range tests {
+		category, name, ok := SplitNoteCategory(tc.keyword)
+		if category != tc.wantCategory || name != tc.wantName || ok != tc.wantOK {
+			t.Errorf("SplitNoteCategory(%q) = (%q, %q, %v), want (%q, %q, %v)",
+				tc.keyword, category, name, ok, tc.wantCategory, tc.wantName, tc.wantOK)
+		}
+	}
+}
+
+func TestGetNoteIndexGroupsByCategory(t *testing.T) {
+	skipIfNoDb(t)
+
+	chatID := time.Now().UnixNano()
+	if err := chats.EnsureChatInDb(chatID, "test-note-index"); err != nil {
+		t.Fatalf("EnsureChatInDb() error = %v", err)
+	}
+	t.Cleanup(func() {
+		if err := db.DB.Where("chat_id = ?", chatID).Delete(&models.Notes{}).Error; err != nil {
+			t.Fatalf("cleanup Notes failed: %v", err)
+		}
+		if err := db.DB.Where("chat_id = ?", chatID).Delete(&models.Chat{}).Error; err != nil {
+			t.Fatalf("cleanup Chat failed: %v", err)
+		}
+	})
+
+	for _, n := range []struct {
+		name, category string
+		adminOnly      bool
+	}{
+		{name: "zeta", category: "faq"},
+		{name: "alpha", category: "faq"},
+		{name: "welcome"},
+		{name: "secret", category: "staff", adminOnly: true},
+	} {
+		if err := AddNote(chatID, n.name, n.category, "content", "", nil, nil, db.TEXT, false, false, n.adminOnly, false, false, false); err != nil {
+			t.Fatalf("AddNote(%s) error = %v", n.name, err)
+		}
+	}
+
+	want := []NoteIndexEntry{
+		{Name: "welcome"},
+		{Name: "alpha", Category: "faq"},
+		{Name: "zeta", Category: "faq"},
+	}
+	if got := GetNoteIndex(chatID, false); !slices.Equal(got, want) {
+		t.Fatalf("GetNoteIndex(member) = %v, want %v", got, want)
+	}
+
+	// Moving a note to another category must invalidate the cached index.
+	if _, err := UpdateNote(chatID, 0, "welcome", "general", "content", "", nil, nil, db.TEXT, false, false, false, false, false, false); err != nil {
+		t.Fatalf("UpdateNote() error = %v", err)
+	}
+	want = []NoteIndexEntry{
+		{Name: "alpha", Category: "faq"},
+		{Name: "zeta", Category: "faq"},
+		{Name: "welcome", Category: "general"},
+		{Name: "secret", Category: "staff"},
+	}
+	if got := GetNoteIndex(chatID, true); !slices.Equal(got, want) {
+		t.Fatalf("GetNoteIndex(admin) = %v, want %v", got, want)
+	}
+}
+
+func TestSearchNotesMatchesContent(t *testing.T) {
+	skipIfNoDb(t)
+
+	chatID := time.Now().UnixNano()
+	if err := chats.EnsureChatInDb(chatID, "test-note-search"); err != nil {
+		t.Fatalf("EnsureChatInDb() error = %v", err)
+	}
+	t.Cleanup(func() {
+		if err := db.DB.Where("chat_id = ?", chatID).Delete(&models.Notes{}).Error; err != nil {
+			t.Fatalf("cleanup Notes failed: %v", err)
+		}
+		if err := db.DB.Where("chat_id = ?", chatID).Delete(&models.Chat{}).Error; err != nil {
+			t.Fatalf("cleanup Chat failed: %v", err)
+		}
+	})
+
+	for _, n := range []struct {
+		name, content string
+		adminOnly     bool
+	}{
+		{name: "install", content: "Run the Installer and restart"},
+		{name: "update", content: "Restart after every update"},
+		{name: "discount", content: "Use code 100% off"},
+		{name: "staff", content: "Restart the installer server", adminOnly: true},
+	} {
+		if err := AddNote(chatID, n.name, "", n.content, "", nil, nil, db.TEXT, false, false, n.adminOnly, false, false, false); err != nil {
+			t.Fatalf("AddNote(%s) error = %v", n.name, err)
+		}
+	}
+
+	tests := []struct {
+		query string
+		admin bool
+		want  []string
+	}{
+		{query: "restart", want: []string{"install", "update"}},
+		{query: "restart", admin: true, want: []string{"install", "staff", "update"}},
+		{query: "installer restart", want: []string{"install"}},
+		{query: "100%", want: []string{"discount"}},
+		{query: "missing", want: []string{}},
+		{query: "  ", want: []string{}},
+	}
+	for _, tc := range tests {
+		got, err := SearchNotes(chatID, tc.query, tc.admin, 10)
+		if err != nil {
+			t.Fatalf("SearchNotes(%q) error = %v", tc.query, err)
+		}
+		if len(got) != len(tc.want) || (len(got) > 0 && !slices.Equal(got, tc.want)) {
+			t.Errorf("SearchNotes(%q, admin=%v) = %v, want %v", tc.query, tc.admin, got, tc.want)
+		}
+	}
+
+	if got, _ := SearchNotes(chatID, "restart", true, 2); len(got) != 2 {
+		t.Fatalf("SearchNotes() with limit 2 returned %d notes", len(got))
+	}
+}
+
+func TestNoteRevisionsRestoreOverwriteAndClearAll(t *testing.T) {
+	skipIfNoDb(t)
+
+	chatID := time.Now().UnixNano()
+	if err := chats.EnsureChatInDb(chatID, "test-note-revisions"); err != nil {
+		t.Fatalf("EnsureChatInDb() error = %v", err)
+	}
+	t.Cleanup(func() {
+		if err := db.DB.Where("chat_id = ?", chatID).Delete(&models.NoteRevision{}).Error; err != nil {
+			t.Fatalf("cleanup NoteRevision failed: %v", err)
+		}
+		if err := db.DB.Where("chat_id = ?", chatID).Delete(&models.Notes{}).Error; err != nil {
+			t.Fatalf("cleanup Notes failed: %v", err)
+		}
+		if err := db.DB.Where("chat_id = ?", chatID).Delete(&models.Chat{}).Error; err != nil {
+			t.Fatalf("cleanup Chat failed: %v", err)
+		}
+	})
+
+	// web_preview defaults to true, so a false value must survive the round trip.
+	if err := AddNote(chatID, "faq", "help", "line one\nline two", "", models.ButtonArray{}, nil, db.TEXT, false, false, false, false, false, false); err != nil {
+		t.Fatalf("AddNote() error = %v", err)
+	}
+	updated, err := UpdateNote(chatID, 42, "faq", "", "wiped", "", models.ButtonArray{}, nil, db.TEXT, false, false, false, true, false, false)
+	if err != nil || !updated {
+		t.Fatalf("UpdateNote() = %v, %v", updated, err)
+	}
+
+	revisions := GetNoteRevisions(chatID, "faq")
+	if len(revisions) != 1 {
+		t.Fatalf("expected 1 revision after overwrite, got %d", len(revisions))
+	}
+	rev := revisions[0]
+	if rev.Revision != 1 || rev.Action != models.RevisionActionOverwrite || rev.EditorId != 42 || rev.NoteContent != "line one\nline two" {
+		t.Fatalf("unexpected revision %+v", rev)
+	}
+	if GetNoteRevisionByID(chatID, rev.ID) == nil || GetNoteRevisionByID(chatID+1, rev.ID) != nil {
+		t.Fatal("GetNoteRevisionByID() must only find revisions of its own chat")
+	}
+
+	restored, err := RestoreNoteRevision(chatID, 43, "faq", 1)
+	if err != nil || !restored {
+		t.Fatalf("RestoreNoteRevision() = %v, %v", restored, err)
+	}
+	note := GetNote(chatID, "faq")
+	if note == nil || note.NoteContent != "line one\nline two" || note.Category != "help" || note.WebPreview {
+		t.Fatalf("restored note = %+v", note)
+	}
+	revisions = GetNoteRevisions(chatID, "faq")
+	if len(revisions) != 2 || revisions[0].Revision != 2 || revisions[0].Action != models.RevisionActionRestore || revisions[0].NoteContent != "wiped" {
+		t.Fatalf("restore must archive the replaced content, got %+v", revisions)
+	}
+	if restored, err := RestoreNoteRevision(chatID, 43, "faq", 9); err != nil || restored {
+		t.Fatalf("RestoreNoteRevision(missing) = %v, %v", restored, err)
+	}
+
+	if err := AddNote(chatID, "rules", "", "be nice", "", models.ButtonArray{}, nil, db.TEXT, false, false, false, true, false, false); err != nil {
+		t.Fatalf("AddNote() error = %v", err)
+	}
+	if err := RemoveAllNotes(chatID, 42); err != nil {
+		t.Fatalf("RemoveAllNotes() error = %v", err)
+	}
+	if len(GetNotesList(chatID, true)) != 0 {
+		t.Fatal("expected no notes after RemoveAllNotes")
+	}
+	// A note saved again after /clearall is not replaced by the restore.
+	if err := AddNote(chatID, "rules", "", "new rules", "", models.ButtonArray{}, nil, db.TEXT, false, false, false, true, false, false); err != nil {
+		t.Fatalf("AddNote() error = %v", err)
+	}
+
+	count, err := RestoreClearedNotes(chatID, 42)
+	if err != nil || count != 1 {
+		t.Fatalf("RestoreClearedNotes() = %d, %v; want 1", count, err)
+	}
+	if note := GetNote(chatID, "faq"); note == nil || note.NoteContent != "line one\nline two" {
+		t.Fatalf("faq after RestoreClearedNotes = %+v", note)
+	}
+	if note := GetNote(chatID, "rules"); note == nil || note.NoteContent != "new rules" {
+		t.Fatalf("rules after RestoreClearedNotes = %+v", note)
+	}
+}
+
+func TestNoteRevisionsExpireAfterRetention(t *testing.T) {
+	skipIfNoDb(t)
+
+	chatID := time.Now().UnixNano()
+	if err := chats.EnsureChatInDb(chatID, "test-note-revisions-expiry"); err != nil {
+		t.Fatalf("EnsureChatInDb() error = %v", err)
+	}
+	t.Cleanup(func() {
+		if err := db.DB.Where("chat_id = ?", chatID).Delete(&models.NoteRevision{}).Error; err != nil {
+			t.Fatalf("cleanup NoteRevision failed: %v", err)
+		}
+		if err := db.DB.Where("chat_id = ?", chatID).Delete(&models.Notes{}).Error; err != nil {
+			t.Fatalf("cleanup Notes failed: %v", err)
+		}
+		if err := db.DB.Where("chat_id = ?", chatID).Delete(&models.Chat{}).Error; err != nil {
+			t.Fatalf("cleanup Chat failed: %v", err)
+		}
+	})
+
+	if err := AddNote(chatID, "old", "", "text", "", models.ButtonArray{}, nil, db.TEXT, false, false, false, true, false, false); err != nil {
+		t.Fatalf("AddNote() error = %v", err)
+	}
+	if err := RemoveNote(chatID, 1, "old"); err != nil {
+		t.Fatalf("RemoveNote() error = %v", err)
+	}
+	expired := time.Now().UTC().Add(-models.RevisionRetention - time.Hour)
+	if err := db.DB.Model(&models.NoteRevision{}).Where("chat_id = ?", chatID).Update("created_at", expired).Error; err != nil {
+		t.Fatalf("backdate revision error = %v", err)
+	}
+
+	if revisions := GetNoteRevisions(chatID, "old"); len(revisions) != 0 {
+		t.Fatalf("expected expired revisions to be hidden, got %d", len(revisions))
+	}
+	if restored, err := RestoreNoteRevision(chatID, 1, "old", 1); err != nil || restored {
+		t.Fatalf("RestoreNoteRevision(expired) = %v, %v", restored, err)
+	}
+}
+
+func TestNoteMediaAlbumRoundTrip(t *testing.T) {
+	skipIfNoDb(t)
+
+	chatID := time.Now().UnixNano()
+	if err := chats.EnsureChatInDb(chatID, "test-note-media"); err != nil {
+		t.Fatalf("EnsureChatInDb() error = %v", err)
+	}
+	t.Cleanup(func() {
+		if err := db.DB.Where("chat_id = ?", chatID).Delete(&models.NoteRevision{}).Error; err != nil {
+			t.Fatalf("cleanup NoteRevision failed: %v", err)
+		}
+		if err := db.DB.Where("chat_id = ?", chatID).Delete(&models.Notes{}).Error; err != nil {
+			t.Fatalf("cleanup Notes failed: %v", err)
+		}
+		if err := db.DB.Where("chat_id = ?", chatID).Delete(&models.Chat{}).Error; err != nil {
+			t.Fatalf("cleanup Chat failed: %v", err)
+		}
+	})
+
+	album := models.MediaItemArray{
+		{FileID: "photo-1", MsgType: db.PHOTO},
+		{FileID: "photo-2", MsgType: db.PHOTO, Caption: "<b>second</b>"},
+	}
+	if err := AddNote(chatID, "gallery", "", "album", "photo-1", models.ButtonArray{}, album, db.PHOTO, false, false, false, true, false, false); err != nil {
+		t.Fatalf("AddNote() error = %v", err)
+	}
+	note := GetNote(chatID, "gallery")
+	if note == nil || len(note.Media) != 2 || note.Media[1] != album[1] {
+		t.Fatalf("saved note media = %+v", note)
+	}
+
+	if _, err := UpdateNote(chatID, 1, "gallery", "", "plain", "", models.ButtonArray{}, nil, db.TEXT, false, false, false, true, false, false); err != nil {
+		t.Fatalf("UpdateNote() error = %v", err)
+	}
+	if note := GetNote(chatID, "gallery"); note == nil || len(note.Media) != 0 {
+		t.Fatalf("overwritten note must drop the album, got %+v", note)
+	}
+
+	if restored, err := RestoreNoteRevision(chatID, 1, "gallery", 1); err != nil || !restored {
+		t.Fatalf("RestoreNoteRevision() = %v, %v", restored, err)
+	}
+	if note := GetNote(chatID, "gallery"); note == nil || len(note.Media) != 2 || note.Media[0].FileID != "photo-1" {
+		t.Fatalf("restored note media = %+v", note)
+	}
+}
@@ -0,0 +1,224 @@
+package notes
+
+import (
+	"errors"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+
+	"github.com/divkix/Alita_Robot/alita/db"
+	"github.com/divkix/Alita_Robot/alita/db/models"
+)
+
+// archiveNotes snapshots notes of a chat into note_revisions before they are
+// changed: the named notes, or every note of the chat when no name is given.
+// Revisions older than the retention window are pruned on the way.
+// Returns how many notes were archived.
+func archiveNotes(tx *gorm.DB, chatID, editorID int64, action string, batch int64, names ...string) (int, error) {
+	query := tx.Where("chat_id = ?", chatID)
+	if len(names) > 0 {
+		query = query.Where("note_name IN ?", names)
+	}
+	var current []models.Notes
+	if err := query.Find(&current).Error; err != nil {
+		return 0, err
+	}
+	if len(current) == 0 {
+		return 0, nil
+	}
+
+	now := time.Now().UTC()
+	if err := tx.Where("chat_id = ? AND created_at < ?", chatID, now.Add(-models.RevisionRetention)).
+		Delete(&models.NoteRevision{}).Error; err != nil {
+		return 0, err
+	}
+
+	var latest []struct {
+		NoteName string
+		Revision int
+	}
+	if err := tx.Model(&models.NoteRevision{}).
+		Select("note_name, MAX(revision) AS revision").
+		Where("chat_id = ?", chatID).
+		Group("note_name").
+		Scan(&latest).Error; err != nil {
+		return 0, err
+	}
+	lastRevision := make(map[string]int, len(latest))
+	for _, l := range latest {
+		lastRevision[l.NoteName] = l.Revision
+	}
+
+	revisions := make([]models.NoteRevision, 0, len(current))
+	for _, note := range current {
+		revisions = append(revisions, models.NoteRevision{
+			ChatId:      chatID,
+			NoteName:    note.NoteName,
+			Revision:    lastRevision[note.NoteName] + 1,
+			Action:      action,
+			EditorId:    editorID,
+			Batch:       batch,
+			Category:    note.Category,
+			NoteContent: note.NoteContent,
+			FileID:      note.FileID,
+			MsgType:     note.MsgType,
+			Buttons:     note.Buttons,
+			Media:       note.Media,
+			AdminOnly:   note.AdminOnly,
+			PrivateOnly: note.PrivateOnly,
+			GroupOnly:   note.GroupOnly,
+			WebPreview:  note.WebPreview,
+			IsProtected: note.IsProtected,
+			NoNotif:     note.NoNotif,
+			CreatedAt:   now,
+		})
+	}
+	if err := tx.Create(&revisions).Error; err != nil {
+		return 0, err
+	}
+	return len(revisions), nil
+}
+
+// revisionCutoff returns the oldest creation time of a revision that can
+// still be restored.
+func revisionCutoff() time.Time {
+	return time.Now().UTC().Add(-models.RevisionRetention)
+}
+
+// GetNoteRevisions returns the restorable revisions of a note, newest first.
+// Returns an empty slice if there are none or an error occurs.
+func GetNoteRevisions(chatID int64, noteName string) []*models.NoteRevision {
+	var revisions []*models.NoteRevision
+	err := db.DB.Where("chat_id = ? AND note_name = ? AND created_at >= ?", chatID, noteName, revisionCutoff()).
+		Order("revision DESC").
+		Find(&revisions).Error
+	if err != nil {
+		log.Errorf("[Database][GetNoteRevisions]: %d - %v", chatID, err)
+		return []*models.NoteRevision{}
+	}
+	return revisions
+}
+
+// GetNoteRevisionByID returns a restorable revision by its row ID, or nil if
+// it does not exist, belongs to another chat or has expired.
+func GetNoteRevisionByID(chatID int64, id uint) *models.NoteRevision {
+	revision := &models.NoteRevision{}
+	err := db.DB.Where("id = ? AND chat_id = ? AND created_at >= ?", id, chatID, revisionCutoff()).Take(revision).Error
+	if err != nil {
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			log.Errorf("[Database][GetNoteRevisionByID]: %d - %v", chatID, err)
+		}
+		return nil
+	}
+	return revision
+}
+
+// restoreNote writes the content of a revision back as the current note,
+// archiving the note it replaces, if any.
+func restoreNote(tx *gorm.DB, editorID int64, revision *models.NoteRevision) error {
+	if _, err := archiveNotes(tx, revision.ChatId, editorID, models.RevisionActionRestore, 0, revision.NoteName); err != nil {
+		return err
+	}
+	now := time.Now().UTC()
+	content := map[string]any{
+		"category":     revision.Category,
+		"note_content": revision.NoteContent,
+		"msg_type":     revision.MsgType,
+		"file_id":      revision.FileID,
+		"buttons":      revision.Buttons,
+		"media":        revision.Media,
+		"admin_only":   revision.AdminOnly,
+		"private_only": revision.PrivateOnly,
+		"group_only":   revision.GroupOnly,
+		"web_preview":  revision.WebPreview,
+		"is_protected": revision.IsProtected,
+		"no_notif":     revision.NoNotif,
+		"updated_at":   now,
+	}
+	result := tx.Model(&models.Notes{}).
+		Where("chat_id = ? AND note_name = ?", revision.ChatId, revision.NoteName).
+		Updates(content)
+	if result.Error != nil || result.RowsAffected > 0 {
+		return result.Error
+	}
+	content["chat_id"] = revision.ChatId
+	content["note_name"] = revision.NoteName
+	content["created_at"] = now
+	return tx.Model(&models.Notes{}).Create(content).Error
+}
+
+// RestoreNoteRevision makes revision rev of a note its current content again.
+// The content it replaces is kept as a new revision, so a restore can itself
+// be undone. Returns false if the revision does not exist or has expired.
+func RestoreNoteRevision(chatID, editorID int64, noteName string, rev int) (bool, error) {
+	var restored bool
+	err := db.DB.Transaction(func(tx *gorm.DB) error {
+		revision := &models.NoteRevision{}
+		err := tx.Where("chat_id = ? AND note_name = ? AND revision = ? AND created_at >= ?", chatID, noteName, rev, revisionCutoff()).
+			Take(revision).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		restored = true
+		return restoreNote(tx, editorID, revision)
+	})
+	if err != nil {
+		log.Errorf("[Database][RestoreNoteRevision]: %d - %v", chatID, err)
+		return false, err
+	}
+	if restored {
+		invalidateNotesCache(chatID)
+	}
+	return restored, nil
+}
+
+// RestoreClearedNotes brings back the notes removed by the latest /clearall
+// that is still within the retention window. Notes saved again since then
+// are left untouched. Returns how many notes were restored.
+func RestoreClearedNotes(chatID, editorID int64) (int, error) {
+	restored := 0
+	err := db.DB.Transaction(func(tx *gorm.DB) error {
+		var latest models.NoteRevision
+		err := tx.Where("chat_id = ? AND action = ? AND created_at >= ?", chatID, models.RevisionActionClearAll, revisionCutoff()).
+			Order("batch DESC").
+			Take(&latest).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		} else if err != nil {
+			return err
+		}
+
+		var revisions []*models.NoteRevision
+		if err := tx.Where("chat_id = ? AND action = ? AND batch = ?", chatID, models.RevisionActionClearAll, latest.Batch).
+			Find(&revisions).Error; err != nil {
+			return err
+		}
+		for _, revision := range revisions {
+			var existing int64
+			if err := tx.Model(&models.Notes{}).
+				Where("chat_id = ? AND note_name = ?", chatID, revision.NoteName).
+				Count(&existing).Error; err != nil {
+				return err
+			}
+			if existing > 0 {
+				continue
+			}
+			if err := restoreNote(tx, editorID, revision); err != nil {
+				return err
+			}
+			restored++
+		}
+		return nil
+	})
+	if err != nil {
+		log.Errorf("[Database][RestoreClearedNotes]: %d - %v", chatID, err)
+		return 0, err
+	}
+	if restored > 0 {
+		invalidateNotesCache(chatID)
+	}
+	return restored, nil
+}
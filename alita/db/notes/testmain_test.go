@@ -33,7 +33,7 @@ func TestMain(m *testing.M) {
 			fmt.Printf("SQLite init failed: %v\n", err)
 			os.Exit(1)
 		}
-		if err := db.DB.AutoMigrate(&models.Chat{}, &models.NotesSettings{}, &models.Notes{}); err != nil {
+		if err := db.DB.AutoMigrate(&models.Chat{}, &models.NotesSettings{}, &models.Notes{}, &models.NoteRevision{}); err != nil {
 			fmt.Printf("AutoMigrate failed: %v\n", err)
 			os.Exit(1)
 		}
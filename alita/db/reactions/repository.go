@@ -15,44 +15,39 @@ func reactionsCacheKey(chatID int64) string {
 	return cache.CacheKey("reactions", chatID)
 }
 
-// GetReactions returns the keyword->emoji map for a chat, read-through cache.
-// Returns an empty (non-nil) map when no reactions are configured.
-func GetReactions(chatID int64) map[string]string {
+// GetReactions returns the reaction rules of a chat by keyword, read-through
+// cache. Returns an empty (non-nil) map when no reactions are configured.
+func GetReactions(chatID int64) map[string]*models.Reactions {
 	cacheKey := reactionsCacheKey(chatID)
-	result, err := cache.GetFromCacheOrLoad(cacheKey, cache.CacheTTLReactions, func() (map[string]string, error) {
+	result, err := cache.GetFromCacheOrLoad(cacheKey, cache.CacheTTLReactions, func() (map[string]*models.Reactions, error) {
 		var rows []*models.Reactions
 		if err := db.GetRecords(&rows, models.Reactions{ChatID: chatID}); err != nil {
 			log.Errorf("[Database] GetReactions: %v - chat:%d", err, chatID)
-			return map[string]string{}, err
+			return map[string]*models.Reactions{}, err
 		}
-		out := make(map[string]string, len(rows))
+		out := make(map[string]*models.Reactions, len(rows))
 		for _, r := range rows {
-			out[r.Keyword] = r.Emoji
+			out[r.Keyword] = r
 		}
 		return out, nil
 	})
 	if err != nil || result == nil {
-		return map[string]string{}
+		return map[string]*models.Reactions{}
 	}
 	return result
 }
 
-// AddReaction adds or updates a keyword->emoji reaction for a chat.
-func AddReaction(chatID int64, keyword, emoji string) error {
-	r := &models.Reactions{
-		ChatID:  chatID,
-		Keyword: keyword,
-		Emoji:   emoji,
-	}
+// AddReaction adds or replaces the reaction rule of a keyword.
+func AddReaction(rule *models.Reactions) error {
 	err := db.DB.Clauses(clause.OnConflict{
 		Columns:   []clause.Column{{Name: "chat_id"}, {Name: "keyword"}},
-		DoUpdates: clause.AssignmentColumns([]string{"emoji", "updated_at"}),
-	}).Create(r).Error
+		DoUpdates: clause.AssignmentColumns([]string{"emojis", "probability", "cooldown_seconds", "updated_at"}),
+	}).Create(rule).Error
 	if err != nil {
-		log.Errorf("[Database] AddReaction: %v - chat:%d keyword:%s", err, chatID, keyword)
+		log.Errorf("[Database] AddReaction: %v - chat:%d keyword:%s", err, rule.ChatID, rule.Keyword)
 		return err
 	}
-	cache.DeleteCache(reactionsCacheKey(chatID))
+	cache.DeleteCache(reactionsCacheKey(rule.ChatID))
 	return nil
 }
 
@@ -76,3 +71,62 @@ func ResetReactions(chatID int64) error {
 	cache.DeleteCache(reactionsCacheKey(chatID))
 	return nil
 }
+
+// reactionActionsCacheKey returns the cache key for a chat's reaction
+// moderation actions.
+func reactionActionsCacheKey(chatID int64) string {
+	return cache.CacheKey("reaction_actions", chatID)
+}
+
+// GetReactionActions returns the moderation actions of a chat, read-through
+// cache. Returns an empty (non-nil) slice when none are configured.
+func GetReactionActions(chatID int64) []*models.ReactionAction {
+	result, err := cache.GetFromCacheOrLoad(reactionActionsCacheKey(chatID), cache.CacheTTLReactions, func() ([]*models.ReactionAction, error) {
+		var rows []*models.ReactionAction
+		if err := db.DB.Where("chat_id = ?", chatID).Order("id").Find(&rows).Error; err != nil {
+			log.Errorf("[Database] GetReactionActions: %v - chat:%d", err, chatID)
+			return []*models.ReactionAction{}, err
+		}
+		return rows, nil
+	})
+	if err != nil || result == nil {
+		return []*models.ReactionAction{}
+	}
+	return result
+}
+
+// SetReactionAction adds or replaces the moderation action of a reaction.
+func SetReactionAction(action *models.ReactionAction) error {
+	err := db.DB.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "chat_id"}, {Name: "emoji"}, {Name: "custom_emoji_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"action", "duration_seconds", "updated_at"}),
+	}).Create(action).Error
+	if err != nil {
+		log.Errorf("[Database] SetReactionAction: %v - chat:%d emoji:%s", err, action.ChatID, action.Emoji)
+		return err
+	}
+	cache.DeleteCache(reactionActionsCacheKey(action.ChatID))
+	return nil
+}
+
+// RemoveReactionAction removes the moderation action of a reaction. It
+// reports whether there was one.
+func RemoveReactionAction(chatID int64, emoji, customEmojiID string) (bool, error) {
+	result := db.DB.Where("chat_id = ? AND emoji = ? AND custom_emoji_id = ?", chatID, emoji, customEmojiID).Delete(&models.ReactionAction{})
+	if result.Error != nil {
+		log.Errorf("[Database] RemoveReactionAction: %v - chat:%d emoji:%s", result.Error, chatID, emoji)
+		return false, result.Error
+	}
+	cache.DeleteCache(reactionActionsCacheKey(chatID))
+	return result.RowsAffected > 0, nil
+}
+
+// ResetReactionActions removes all moderation actions of a chat.
+func ResetReactionActions(chatID int64) error {
+	if err := db.DB.Where("chat_id = ?", chatID).Delete(&models.ReactionAction{}).Error; err != nil {
+		log.Errorf("[Database] ResetReactionActions: %v - chat:%d", err, chatID)
+		return err
+	}
+	cache.DeleteCache(reactionActionsCacheKey(chatID))
+	return nil
+}
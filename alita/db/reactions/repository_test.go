@@ -12,7 +12,7 @@ import (
 
 func TestMain(m *testing.M) {
 	if db.DB != nil && db.DB.Name() == "sqlite" {
-		_ = db.DB.AutoMigrate(&models.Reactions{})
+		_ = db.DB.AutoMigrate(&models.Reactions{}, &models.ReactionAction{})
 	}
 	os.Exit(m.Run())
 }
@@ -37,28 +37,45 @@ func TestReactionsRoundTrip(t *testing.T) {
 		t.Fatalf("GetReactions on fresh chat = %v, want empty", got)
 	}
 
-	if err := AddReaction(chatID, "hello", "👋"); err != nil {
+	rule := func(keyword string, emojis ...string) *models.Reactions {
+		r := &models.Reactions{ChatID: chatID, Keyword: keyword, Probability: 100}
+		for _, emoji := range emojis {
+			r.Emojis = append(r.Emojis, models.ReactionEmoji{Emoji: emoji})
+		}
+		return r
+	}
+
+	if err := AddReaction(rule("hello", "👋")); err != nil {
 		t.Fatalf("AddReaction() error = %v", err)
 	}
-	if err := AddReaction(chatID, "bye", "🚀"); err != nil {
+	if err := AddReaction(rule("bye", "🚀")); err != nil {
 		t.Fatalf("AddReaction(second) error = %v", err)
 	}
-	if got := GetReactions(chatID); got["hello"] != "👋" || got["bye"] != "🚀" {
+	if got := GetReactions(chatID); got["hello"].Emojis[0].Emoji != "👋" || got["bye"].Emojis[0].Emoji != "🚀" {
 		t.Fatalf("GetReactions = %v, want hello=👋 bye=🚀", got)
 	}
 
-	// Upsert: re-adding the same keyword updates the emoji, not a duplicate.
-	if err := AddReaction(chatID, "hello", "😀"); err != nil {
+	// Upsert: re-adding the same keyword replaces the rule, not a duplicate.
+	updated := rule("hello", "😀", "🔥")
+	updated.Emojis = append(updated.Emojis, models.ReactionEmoji{Emoji: "👍", CustomEmojiID: "5368324170671202286"})
+	updated.Probability = 25
+	updated.CooldownSeconds = 300
+	if err := AddReaction(updated); err != nil {
 		t.Fatalf("AddReaction(upsert) error = %v", err)
 	}
-	if got := GetReactions(chatID); got["hello"] != "😀" || len(got) != 2 {
-		t.Fatalf("GetReactions after upsert = %v, want hello=😀 and 2 entries", got)
+	got := GetReactions(chatID)
+	if len(got) != 2 {
+		t.Fatalf("GetReactions after upsert = %v, want 2 entries", got)
+	}
+	hello := got["hello"]
+	if len(hello.Emojis) != 3 || hello.Emojis[2].CustomEmojiID != "5368324170671202286" || hello.Probability != 25 || hello.CooldownSeconds != 300 {
+		t.Fatalf("hello after upsert = %+v, want 3 reactions at 25%% with a 300s cooldown", hello)
 	}
 
 	if err := RemoveReaction(chatID, "bye"); err != nil {
 		t.Fatalf("RemoveReaction() error = %v", err)
 	}
-	got := GetReactions(chatID)
+	got = GetReactions(chatID)
 	if _, ok := got["bye"]; ok || len(got) != 1 {
 		t.Fatalf("GetReactions after remove = %v, want only hello", got)
 	}
@@ -70,3 +87,48 @@ func TestReactionsRoundTrip(t *testing.T) {
 		t.Fatalf("GetReactions after reset = %v, want empty", got)
 	}
 }
+
+func TestReactionActionsRoundTrip(t *testing.T) {
+	skipIfNoDb(t)
+
+	chatID := time.Now().UnixNano()
+	t.Cleanup(func() {
+		_ = ResetReactionActions(chatID)
+	})
+
+	if got := GetReactionActions(chatID); len(got) != 0 {
+		t.Fatalf("GetReactionActions on fresh chat = %v, want empty", got)
+	}
+	if err := SetReactionAction(&models.ReactionAction{ChatID: chatID, Emoji: "💩", Action: "delete"}); err != nil {
+		t.Fatalf("SetReactionAction() error = %v", err)
+	}
+	if err := SetReactionAction(&models.ReactionAction{ChatID: chatID, Emoji: "🤬", CustomEmojiID: "5368324170671202286", Action: "ban"}); err != nil {
+		t.Fatalf("SetReactionAction(custom) error = %v", err)
+	}
+	// Upsert: a reaction has one action.
+	if err := SetReactionAction(&models.ReactionAction{ChatID: chatID, Emoji: "💩", Action: "mute", DurationSeconds: 3600}); err != nil {
+		t.Fatalf("SetReactionAction(upsert) error = %v", err)
+	}
+	got := GetReactionActions(chatID)
+	if len(got) != 2 || got[0].Action != "mute" || got[0].DurationSeconds != 3600 || got[1].CustomEmojiID != "5368324170671202286" {
+		t.Fatalf("GetReactionActions = %+v, want a 1h mute on 💩 and a ban on the custom emoji", got)
+	}
+
+	// The custom emoji is its own reaction, not the emoji it stands in for.
+	if removed, err := RemoveReactionAction(chatID, "🤬", ""); err != nil || removed {
+		t.Fatalf("RemoveReactionAction(plain 🤬) = %v, %v, want nothing removed", removed, err)
+	}
+	if removed, err := RemoveReactionAction(chatID, "🤬", "5368324170671202286"); err != nil || !removed {
+		t.Fatalf("RemoveReactionAction(custom) = %v, %v, want removed", removed, err)
+	}
+	if got := GetReactionActions(chatID); len(got) != 1 {
+		t.Fatalf("GetReactionActions after remove = %+v, want 1 entry", got)
+	}
+
+	if err := ResetReactionActions(chatID); err != nil {
+		t.Fatalf("ResetReactionActions() error = %v", err)
+	}
+	if got := GetReactionActions(chatID); len(got) != 0 {
+		t.Fatalf("GetReactionActions after reset = %v, want empty", got)
+	}
+}
@@ -0,0 +1,171 @@
+package starboard
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/divkix/Alita_Robot/alita/db"
+	"github.com/divkix/Alita_Robot/alita/db/cache"
+	"github.com/divkix/Alita_Robot/alita/db/models"
+)
+
+const (
+	// DefaultEmoji is the reaction that stars a message until /starboard
+	// emoji sets another. ⭐ is not among the reactions Telegram offers.
+	DefaultEmoji     = "🔥"
+	DefaultThreshold = 5
+	MaxThreshold     = 1000
+)
+
+// settingsCacheKey returns the cache key for a chat's starboard settings.
+func settingsCacheKey(chatID int64) string {
+	return cache.CacheKey("starboard_settings", chatID)
+}
+
+// GetStarboardSettings returns the starboard settings of a chat, read-through
+// cache, or the defaults, with the starboard off, when none are stored.
+func GetStarboardSettings(chatID int64) *models.StarboardSettings {
+	result, err := cache.GetFromCacheOrLoad(settingsCacheKey(chatID), cache.CacheTTLChatSettings, func() (*models.StarboardSettings, error) {
+		settings := &models.StarboardSettings{}
+		err := db.DB.Where("chat_id = ?", chatID).First(settings).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return &models.StarboardSettings{ChatId: chatID, Emoji: DefaultEmoji, Threshold: DefaultThreshold}, nil
+		}
+		if err != nil {
+			log.Errorf("[Database] GetStarboardSettings: %v - chat:%d", err, chatID)
+			return nil, err
+		}
+		return settings, nil
+	})
+	if err != nil || result == nil {
+		return &models.StarboardSettings{ChatId: chatID, Emoji: DefaultEmoji, Threshold: DefaultThreshold}
+	}
+	return result
+}
+
+// updateSettings stores the given columns of a chat's starboard settings,
+// creating them with the defaults first when needed.
+func updateSettings(chatID int64, columns map[string]any) error {
+	err := db.DB.Where("chat_id = ?", chatID).
+		Attrs(models.StarboardSettings{Emoji: DefaultEmoji, Threshold: DefaultThreshold}).
+		Assign(columns).
+		FirstOrCreate(&models.StarboardSettings{ChatId: chatID}).Error
+	if err != nil {
+		log.Errorf("[Database] updateSettings(starboard): %v - chat:%d", err, chatID)
+		return err
+	}
+	cache.DeleteCache(settingsCacheKey(chatID))
+	return nil
+}
+
+// SetStarboardChannel sets the chat starred messages are reposted to. 0
+// turns the starboard off.
+func SetStarboardChannel(chatID, channelID int64) error {
+	return updateSettings(chatID, map[string]any{"channel_id": channelID})
+}
+
+// SetStarboardEmoji sets the reaction that stars a message.
+func SetStarboardEmoji(chatID int64, emoji, customEmojiID string) error {
+	return updateSettings(chatID, map[string]any{"emoji": emoji, "custom_emoji_id": customEmojiID})
+}
+
+// SetStarboardThreshold sets how many reactions a message needs to be
+// reposted.
+func SetStarboardThreshold(chatID int64, threshold int) error {
+	if threshold < 1 || threshold > MaxThreshold {
+		return fmt.Errorf("starboard threshold must be between 1 and %d, got %d", MaxThreshold, threshold)
+	}
+	return updateSettings(chatID, map[string]any{"threshold": threshold})
+}
+
+// upsertEntry creates the entry of a message or applies the given
+// assignments to it, and returns the entry as stored.
+func upsertEntry(entry *models.StarboardEntry, assignments map[string]any) (*models.StarboardEntry, error) {
+	assignments["updated_at"] = time.Now()
+	err := db.DB.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "chat_id"}, {Name: "message_id"}},
+		DoUpdates: clause.Assignments(assignments),
+	}).Create(entry).Error
+	if err != nil {
+		return nil, err
+	}
+	stored := &models.StarboardEntry{}
+	if err := db.DB.Where("chat_id = ? AND message_id = ?", entry.ChatId, entry.MessageId).First(stored).Error; err != nil {
+		return nil, err
+	}
+	return stored, nil
+}
+
+// AddStars changes the star count of a message by delta, never going below
+// 0, and returns its entry.
+func AddStars(chatID, messageID int64, delta int) (*models.StarboardEntry, error) {
+	entry, err := upsertEntry(&models.StarboardEntry{ChatId: chatID, MessageId: messageID, Stars: max(delta, 0)}, map[string]any{
+		"stars": gorm.Expr("CASE WHEN starboard_entries.stars + ? < 0 THEN 0 ELSE starboard_entries.stars + ? END", delta, delta),
+	})
+	if err != nil {
+		log.Errorf("[Database] AddStars: %v - chat:%d message:%d", err, chatID, messageID)
+	}
+	return entry, err
+}
+
+// SetStars sets the star count of a message, as reported by Telegram for
+// anonymous reactions, and returns its entry.
+func SetStars(chatID, messageID int64, stars int) (*models.StarboardEntry, error) {
+	stars = max(stars, 0)
+	entry, err := upsertEntry(&models.StarboardEntry{ChatId: chatID, MessageId: messageID, Stars: stars}, map[string]any{"stars": stars})
+	if err != nil {
+		log.Errorf("[Database] SetStars: %v - chat:%d message:%d", err, chatID, messageID)
+	}
+	return entry, err
+}
+
+// ExcludeMessage marks a message as one that is never reposted.
+func ExcludeMessage(chatID, messageID int64) error {
+	_, err := upsertEntry(&models.StarboardEntry{ChatId: chatID, MessageId: messageID, Excluded: true}, map[string]any{"excluded": true})
+	if err != nil {
+		log.Errorf("[Database] ExcludeMessage: %v - chat:%d message:%d", err, chatID, messageID)
+	}
+	return err
+}
+
+// ClaimStarboardPost records that a message is being reposted to
+// starboardChatID. It reports false when the message is excluded or was
+// already claimed, so concurrent reactions repost it only once.
+func ClaimStarboardPost(chatID, messageID, starboardChatID int64) (bool, error) {
+	result := db.DB.Model(&models.StarboardEntry{}).
+		Where("chat_id = ? AND message_id = ? AND starboard_chat_id = 0 AND excluded = ?", chatID, messageID, false).
+		Updates(map[string]any{"starboard_chat_id": starboardChatID, "updated_at": time.Now()})
+	if result.Error != nil {
+		log.Errorf("[Database] ClaimStarboardPost: %v - chat:%d message:%d", result.Error, chatID, messageID)
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// SetStarboardPost records the messages a claimed message was reposted as.
+func SetStarboardPost(chatID, messageID, forwardMessageID, counterMessageID int64) error {
+	err := db.DB.Model(&models.StarboardEntry{}).
+		Where("chat_id = ? AND message_id = ?", chatID, messageID).
+		Updates(map[string]any{"forward_message_id": forwardMessageID, "counter_message_id": counterMessageID, "updated_at": time.Now()}).Error
+	if err != nil {
+		log.Errorf("[Database] SetStarboardPost: %v - chat:%d message:%d", err, chatID, messageID)
+	}
+	return err
+}
+
+// ReleaseStarboardPost drops the claim on a message that could not be
+// reposted, so a later reaction tries again.
+func ReleaseStarboardPost(chatID, messageID int64) error {
+	err := db.DB.Model(&models.StarboardEntry{}).
+		Where("chat_id = ? AND message_id = ? AND counter_message_id = 0", chatID, messageID).
+		Updates(map[string]any{"starboard_chat_id": 0, "updated_at": time.Now()}).Error
+	if err != nil {
+		log.Errorf("[Database] ReleaseStarboardPost: %v - chat:%d message:%d", err, chatID, messageID)
+	}
+	return err
+}
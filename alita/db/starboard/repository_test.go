@@ -0,0 +1,117 @@
+package starboard
+
+import (
+	"testing"
+	"time"
+
+	"github.com/divkix/Alita_Robot/alita/db"
+	"github.com/divkix/Alita_Robot/alita/db/cache"
+	"github.com/divkix/Alita_Robot/alita/db/models"
+)
+
+func skipIfNoDb(t *testing.T) {
+	if db.DB == nil {
+		t.Skip("DB not initialized")
+	}
+}
+
+func cleanupStarboard(t *testing.T, chatID int64) {
+	t.Cleanup(func() {
+		_ = db.DB.Where("chat_id = ?", chatID).Delete(&models.StarboardSettings{}).Error
+		_ = db.DB.Where("chat_id = ?", chatID).Delete(&models.StarboardEntry{}).Error
+		cache.DeleteCache(settingsCacheKey(chatID))
+	})
+}
+
+func TestStarboardSettings(t *testing.T) {
+	skipIfNoDb(t)
+
+	chatID := time.Now().UnixNano()
+	cleanupStarboard(t, chatID)
+
+	settings := GetStarboardSettings(chatID)
+	if settings.ChannelId != 0 || settings.Emoji != DefaultEmoji || settings.Threshold != DefaultThreshold {
+		t.Fatalf("default settings = %+v, want off with %s × %d", settings, DefaultEmoji, DefaultThreshold)
+	}
+
+	if err := SetStarboardThreshold(chatID, 3); err != nil {
+		t.Fatalf("SetStarboardThreshold(3) error = %v", err)
+	}
+	if err := SetStarboardChannel(chatID, -100123); err != nil {
+		t.Fatalf("SetStarboardChannel error = %v", err)
+	}
+	if err := SetStarboardEmoji(chatID, "👍", "5368324170671202286"); err != nil {
+		t.Fatalf("SetStarboardEmoji error = %v", err)
+	}
+	settings = GetStarboardSettings(chatID)
+	if settings.ChannelId != -100123 || settings.Emoji != "👍" || settings.CustomEmojiID != "5368324170671202286" || settings.Threshold != 3 {
+		t.Fatalf("settings = %+v, want channel -100123, custom 👍 and threshold 3", settings)
+	}
+
+	if err := SetStarboardThreshold(chatID, MaxThreshold+1); err == nil {
+		t.Fatal("SetStarboardThreshold above the maximum succeeded")
+	}
+	if err := SetStarboardChannel(chatID, 0); err != nil {
+		t.Fatalf("SetStarboardChannel(0) error = %v", err)
+	}
+	if settings := GetStarboardSettings(chatID); settings.ChannelId != 0 || settings.Threshold != 3 {
+		t.Fatalf("settings after turning off = %+v, want channel 0 and the threshold kept", settings)
+	}
+}
+
+func TestStarboardEntries(t *testing.T) {
+	skipIfNoDb(t)
+
+	chatID := time.Now().UnixNano()
+	cleanupStarboard(t, chatID)
+
+	// Taking back a reaction the bot never saw does not go below zero.
+	entry, err := AddStars(chatID, 10, -1)
+	if err != nil || entry.Stars != 0 {
+		t.Fatalf("AddStars(-1) = %+v, %v, want 0 stars", entry, err)
+	}
+	for range 3 {
+		entry, err = AddStars(chatID, 10, 1)
+	}
+	if err != nil || entry.Stars != 3 {
+		t.Fatalf("AddStars(+1 ×3) = %+v, %v, want 3 stars", entry, err)
+	}
+	if entry, err = SetStars(chatID, 10, 7); err != nil || entry.Stars != 7 {
+		t.Fatalf("SetStars(7) = %+v, %v, want 7 stars", entry, err)
+	}
+
+	// Only one claim wins; a released claim can be taken again.
+	if ok, err := ClaimStarboardPost(chatID, 10, -100123); !ok || err != nil {
+		t.Fatalf("ClaimStarboardPost() = %v, %v, want the claim", ok, err)
+	}
+	if ok, _ := ClaimStarboardPost(chatID, 10, -100123); ok {
+		t.Fatal("second ClaimStarboardPost() succeeded")
+	}
+	if err := ReleaseStarboardPost(chatID, 10); err != nil {
+		t.Fatalf("ReleaseStarboardPost() error = %v", err)
+	}
+	if ok, _ := ClaimStarboardPost(chatID, 10, -100123); !ok {
+		t.Fatal("ClaimStarboardPost() after release failed")
+	}
+	if err := SetStarboardPost(chatID, 10, 55, 56); err != nil {
+		t.Fatalf("SetStarboardPost() error = %v", err)
+	}
+	if err := ReleaseStarboardPost(chatID, 10); err != nil {
+		t.Fatalf("ReleaseStarboardPost() error = %v", err)
+	}
+	entry, _ = AddStars(chatID, 10, 1)
+	if entry.StarboardChatId != -100123 || entry.ForwardMessageId != 55 || entry.CounterMessageId != 56 || entry.Stars != 8 {
+		t.Fatalf("posted entry = %+v, want the post kept and 8 stars", entry)
+	}
+
+	// Excluded messages count stars but are never claimed.
+	if err := ExcludeMessage(chatID, 11); err != nil {
+		t.Fatalf("ExcludeMessage() error = %v", err)
+	}
+	if entry, _ = AddStars(chatID, 11, 1); !entry.Excluded || entry.Stars != 1 {
+		t.Fatalf("excluded entry = %+v, want excluded with 1 star", entry)
+	}
+	if ok, _ := ClaimStarboardPost(chatID, 11, -100123); ok {
+		t.Fatal("ClaimStarboardPost() of an excluded message succeeded")
+	}
+}
@@ -50,10 +50,12 @@ func TestMain(m *testing.M) {
 			&DevSettings{},
 			&ChannelSettings{},
 			&AntifloodSettings{},
+			&AntifloodTopicLimit{},
 			&ConnectionSettings{},
 			&ConnectionChatSettings{},
 			&DisableSettings{},
 			&DisableChatSettings{},
+			&CommandPermission{},
 			&RulesSettings{},
 			&LockSettings{},
 			&NotesSettings{},
@@ -65,6 +67,29 @@ func TestMain(m *testing.M) {
 			&ApprovedUsers{},
 			&AntiRaidSettings{},
 			&Reactions{},
+			&CommandAlias{},
+			&NoteRevision{},
+			&FilterRevision{},
+			&JoinRequest{},
+			&JoinQuestion{},
+			&JoinApplication{},
+			&CaptchaQuestion{},
+			&CaptchaEvent{},
+			&ChatActivityHour{},
+			&ChatActivityUser{},
+			&AntispamSettings{},
+			&SpamModel{},
+			&SpamToken{},
+			&ForumTopicSettings{},
+			&ForumTopic{},
+			&ReactionAction{},
+			&StarboardSettings{},
+			&StarboardEntry{},
+			&KarmaSettings{},
+			&KarmaUser{},
+			&KarmaVote{},
+			&TrustSettings{},
+			&TrustMember{},
 		)
 		if err != nil {
 			fmt.Printf("AutoMigrate failed: %v\n", err)
@@ -0,0 +1,201 @@
+package trust
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/divkix/Alita_Robot/alita/db"
+	"github.com/divkix/Alita_Robot/alita/db/cache"
+	"github.com/divkix/Alita_Robot/alita/db/models"
+)
+
+const (
+	// DefaultNewcomerHours and DefaultNewcomerMessages make members newcomers
+	// for their first day or first 10 messages, unless /newcomers says
+	// otherwise.
+	DefaultNewcomerHours    = 24
+	DefaultNewcomerMessages = 10
+	MaxNewcomerHours        = 30 * 24
+	MaxNewcomerMessages     = 1000
+	// MaxCountedMessages is where counting the messages of a member stops:
+	// past it, the count can no longer make them a newcomer.
+	MaxCountedMessages = MaxNewcomerMessages
+)
+
+// defaultSettings returns the settings of a chat that never configured its
+// newcomer profile.
+func defaultSettings(chatID int64) *models.TrustSettings {
+	return &models.TrustSettings{ChatId: chatID, NewcomerHours: DefaultNewcomerHours, NewcomerMessages: DefaultNewcomerMessages}
+}
+
+// settingsCacheKey returns the cache key for a chat's trust settings.
+func settingsCacheKey(chatID int64) string {
+	return cache.CacheKey("trust_settings", chatID)
+}
+
+// memberCacheKey returns the cache key for what is known about a member.
+func memberCacheKey(chatID, userID int64) string {
+	return cache.CacheKey("trust_member", chatID, userID)
+}
+
+// GetTrustSettings returns the trust settings of a chat, read-through cache,
+// or the defaults, with no newcomer locks or flood limit, when none are
+// stored.
+func GetTrustSettings(chatID int64) *models.TrustSettings {
+	result, err := cache.GetFromCacheOrLoad(settingsCacheKey(chatID), cache.CacheTTLChatSettings, func() (*models.TrustSettings, error) {
+		settings := &models.TrustSettings{}
+		err := db.DB.Where("chat_id = ?", chatID).First(settings).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return defaultSettings(chatID), nil
+		}
+		if err != nil {
+			log.Errorf("[Database] GetTrustSettings: %v - chat:%d", err, chatID)
+			return nil, err
+		}
+		return settings, nil
+	})
+	if err != nil || result == nil {
+		return defaultSettings(chatID)
+	}
+	return result
+}
+
+// updateSettings stores the given columns of a chat's trust settings,
+// creating them with the defaults first when needed. The columns are written
+// separately, so that zero values are stored as given.
+func updateSettings(chatID int64, columns map[string]any) error {
+	settings := &models.TrustSettings{}
+	err := db.DB.Where("chat_id = ?", chatID).
+		Attrs(defaultSettings(chatID)).
+		FirstOrCreate(settings).Error
+	if err == nil {
+		err = db.DB.Model(settings).Updates(columns).Error
+	}
+	if err != nil {
+		log.Errorf("[Database] updateSettings(trust): %v - chat:%d", err, chatID)
+		return err
+	}
+	cache.DeleteCache(settingsCacheKey(chatID))
+	return nil
+}
+
+// SetNewcomerHours sets for how many hours after joining members count as
+// newcomers. 0 turns the time rule off.
+func SetNewcomerHours(chatID int64, hours int) error {
+	if hours < 0 || hours > MaxNewcomerHours {
+		return fmt.Errorf("newcomer hours must be between 0 and %d, got %d", MaxNewcomerHours, hours)
+	}
+	return updateSettings(chatID, map[string]any{"newcomer_hours": hours})
+}
+
+// SetNewcomerMessages sets how many messages members send before they stop
+// counting as newcomers. 0 turns the message rule off.
+func SetNewcomerMessages(chatID int64, messages int) error {
+	if messages < 0 || messages > MaxNewcomerMessages {
+		return fmt.Errorf("newcomer messages must be between 0 and %d, got %d", MaxNewcomerMessages, messages)
+	}
+	return updateSettings(chatID, map[string]any{"newcomer_messages": messages})
+}
+
+// SetNewcomerLocks sets the lock types that apply only to newcomers.
+func SetNewcomerLocks(chatID int64, lockTypes []string) error {
+	return updateSettings(chatID, map[string]any{"newcomer_locks": models.StringArray(lockTypes)})
+}
+
+// SetNewcomerFlood sets the flood limit of newcomers. 0 leaves them to the
+// chat's own limit.
+func SetNewcomerFlood(chatID int64, limit int) error {
+	if limit < 0 {
+		return fmt.Errorf("newcomer flood limit must not be negative, got %d", limit)
+	}
+	return updateSettings(chatID, map[string]any{"newcomer_flood": limit})
+}
+
+// IsNewcomer reports whether a member counts as a newcomer under a chat's
+// settings. Members the bot did not see join (nil) never do.
+func IsNewcomer(settings *models.TrustSettings, member *models.TrustMember, now time.Time) bool {
+	if member == nil {
+		return false
+	}
+	if settings.NewcomerHours > 0 && now.Sub(member.JoinedAt) < time.Duration(settings.NewcomerHours)*time.Hour {
+		return true
+	}
+	return settings.NewcomerMessages > 0 && member.Messages < settings.NewcomerMessages
+}
+
+// RecordJoin records that a member joined a chat, starting over for members
+// who were there before.
+func RecordJoin(chatID, userID int64, joinedAt time.Time) error {
+	err := db.DB.Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "chat_id"}, {Name: "user_id"}},
+		DoUpdates: clause.Assignments(map[string]any{
+			"joined_at":      joinedAt,
+			"messages":       0,
+			"captcha_passed": false,
+			"updated_at":     time.Now(),
+		}),
+	}).Create(&models.TrustMember{ChatId: chatID, UserId: userID, JoinedAt: joinedAt}).Error
+	if err != nil {
+		log.Errorf("[Database] RecordJoin: %v - chat:%d user:%d", err, chatID, userID)
+		return err
+	}
+	cache.DeleteCache(memberCacheKey(chatID, userID))
+	return nil
+}
+
+// GetMember returns what is known about a member, read-through cache, or nil
+// when the bot did not see them join.
+func GetMember(chatID, userID int64) *models.TrustMember {
+	result, err := cache.GetFromCacheOrLoad(memberCacheKey(chatID, userID), cache.CacheTTLChatSettings, func() (*models.TrustMember, error) {
+		member := &models.TrustMember{}
+		err := db.DB.Where("chat_id = ? AND user_id = ?", chatID, userID).First(member).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			// Cached as an empty row, so members who joined before the bot
+			// tracked joins cost one lookup per TTL.
+			return &models.TrustMember{}, nil
+		}
+		if err != nil {
+			log.Errorf("[Database] GetMember(trust): %v - chat:%d user:%d", err, chatID, userID)
+			return nil, err
+		}
+		return member, nil
+	})
+	if err != nil || result == nil || result.ID == 0 {
+		return nil
+	}
+	return result
+}
+
+// CountMessage counts a message of a tracked member, up to
+// MaxCountedMessages.
+func CountMessage(chatID, userID int64) error {
+	result := db.DB.Model(&models.TrustMember{}).
+		Where("chat_id = ? AND user_id = ? AND messages < ?", chatID, userID, MaxCountedMessages).
+		UpdateColumns(map[string]any{"messages": gorm.Expr("messages + 1"), "updated_at": time.Now()})
+	if result.Error != nil {
+		log.Errorf("[Database] CountMessage: %v - chat:%d user:%d", result.Error, chatID, userID)
+		return result.Error
+	}
+	if result.RowsAffected > 0 {
+		cache.DeleteCache(memberCacheKey(chatID, userID))
+	}
+	return nil
+}
+
+// SetCaptchaPassed records that a tracked member solved the captcha.
+func SetCaptchaPassed(chatID, userID int64) error {
+	err := db.DB.Model(&models.TrustMember{}).
+		Where("chat_id = ? AND user_id = ?", chatID, userID).
+		Update("captcha_passed", true).Error
+	if err != nil {
+		log.Errorf("[Database] SetCaptchaPassed: %v - chat:%d user:%d", err, chatID, userID)
+		return err
+	}
+	cache.DeleteCache(memberCacheKey(chatID, userID))
+	return nil
+}
@@ -0,0 +1,143 @@
+package trust
+
+import (
+	"testing"
+	"time"
+
+	"github.com/divkix/Alita_Robot/alita/db"
+	"github.com/divkix/Alita_Robot/alita/db/cache"
+	"github.com/divkix/Alita_Robot/alita/db/models"
+)
+
+func skipIfNoDb(t *testing.T) {
+	if db.DB == nil {
+		t.Skip("DB not initialized")
+	}
+}
+
+func cleanupTrust(t *testing.T, chatID int64, userIDs ...int64) {
+	t.Cleanup(func() {
+		_ = db.DB.Where("chat_id = ?", chatID).Delete(&models.TrustSettings{}).Error
+		_ = db.DB.Where("chat_id = ?", chatID).Delete(&models.TrustMember{}).Error
+		cache.DeleteCache(settingsCacheKey(chatID))
+		for _, userID := range userIDs {
+			cache.DeleteCache(memberCacheKey(chatID, userID))
+		}
+	})
+}
+
+func TestTrustSettings(t *testing.T) {
+	skipIfNoDb(t)
+
+	chatID := time.Now().UnixNano()
+	cleanupTrust(t, chatID)
+
+	settings := GetTrustSettings(chatID)
+	if settings.NewcomerHours != DefaultNewcomerHours || settings.NewcomerMessages != DefaultNewcomerMessages ||
+		len(settings.NewcomerLocks) != 0 || settings.NewcomerFlood != 0 {
+		t.Fatalf("default settings = %+v, want the default rule and no locks", settings)
+	}
+
+	// Turning the time rule off first must not lose the 0 to the defaults.
+	if err := SetNewcomerHours(chatID, 0); err != nil {
+		t.Fatalf("SetNewcomerHours error = %v", err)
+	}
+	if err := SetNewcomerMessages(chatID, 5); err != nil {
+		t.Fatalf("SetNewcomerMessages error = %v", err)
+	}
+	if err := SetNewcomerLocks(chatID, []string{"url", "media"}); err != nil {
+		t.Fatalf("SetNewcomerLocks error = %v", err)
+	}
+	if err := SetNewcomerFlood(chatID, 3); err != nil {
+		t.Fatalf("SetNewcomerFlood error = %v", err)
+	}
+	settings = GetTrustSettings(chatID)
+	if settings.NewcomerHours != 0 || settings.NewcomerMessages != 5 || len(settings.NewcomerLocks) != 2 || settings.NewcomerFlood != 3 {
+		t.Fatalf("settings = %+v, want them as set", settings)
+	}
+
+	if err := SetNewcomerHours(chatID, MaxNewcomerHours+1); err == nil {
+		t.Fatal("SetNewcomerHours above the maximum succeeded")
+	}
+	if err := SetNewcomerMessages(chatID, -1); err == nil {
+		t.Fatal("SetNewcomerMessages with a negative count succeeded")
+	}
+	if err := SetNewcomerFlood(chatID, -1); err == nil {
+		t.Fatal("SetNewcomerFlood with a negative limit succeeded")
+	}
+}
+
+func TestTrustMembers(t *testing.T) {
+	skipIfNoDb(t)
+
+	chatID := time.Now().UnixNano()
+	const userID = 42
+	cleanupTrust(t, chatID, userID)
+
+	if member := GetMember(chatID, userID); member != nil {
+		t.Fatalf("GetMember before a join = %+v, want nil", member)
+	}
+	if err := CountMessage(chatID, userID); err != nil {
+		t.Fatalf("CountMessage for an untracked member error = %v", err)
+	}
+
+	joined := time.Now().Add(-time.Hour)
+	if err := RecordJoin(chatID, userID, joined); err != nil {
+		t.Fatalf("RecordJoin error = %v", err)
+	}
+	for range 3 {
+		if err := CountMessage(chatID, userID); err != nil {
+			t.Fatalf("CountMessage error = %v", err)
+		}
+	}
+	if err := SetCaptchaPassed(chatID, userID); err != nil {
+		t.Fatalf("SetCaptchaPassed error = %v", err)
+	}
+	member := GetMember(chatID, userID)
+	if member == nil || member.Messages != 3 || !member.CaptchaPassed || member.JoinedAt.Sub(joined).Abs() > time.Second {
+		t.Fatalf("member = %+v, want 3 messages, the captcha passed and joined an hour ago", member)
+	}
+
+	// Joining again starts over.
+	if err := RecordJoin(chatID, userID, time.Now()); err != nil {
+		t.Fatalf("RecordJoin again error = %v", err)
+	}
+	if member = GetMember(chatID, userID); member == nil || member.Messages != 0 || member.CaptchaPassed {
+		t.Fatalf("member after joining again = %+v, want a fresh start", member)
+	}
+
+	if err := db.DB.Model(&models.TrustMember{}).Where("chat_id = ?", chatID).Update("messages", MaxCountedMessages).Error; err != nil {
+		t.Fatalf("setting messages error = %v", err)
+	}
+	cache.DeleteCache(memberCacheKey(chatID, userID))
+	if err := CountMessage(chatID, userID); err != nil {
+		t.Fatalf("CountMessage at the maximum error = %v", err)
+	}
+	if member = GetMember(chatID, userID); member.Messages != MaxCountedMessages {
+		t.Fatalf("messages = %d, want counting to stop at %d", member.Messages, MaxCountedMessages)
+	}
+}
+
+func TestIsNewcomer(t *testing.T) {
+	now := time.Now()
+	settings := &models.TrustSettings{NewcomerHours: 24, NewcomerMessages: 10}
+	for _, tc := range []struct {
+		name   string
+		member *models.TrustMember
+		want   bool
+	}{
+		{"untracked", nil, false},
+		{"just joined", &models.TrustMember{JoinedAt: now, Messages: 50}, true},
+		{"few messages", &models.TrustMember{JoinedAt: now.Add(-48 * time.Hour), Messages: 9}, true},
+		{"established", &models.TrustMember{JoinedAt: now.Add(-48 * time.Hour), Messages: 10}, false},
+	} {
+		if got := IsNewcomer(settings, tc.member, now); got != tc.want {
+			t.Errorf("IsNewcomer(%s) = %v, want %v", tc.name, got, tc.want)
+		}
+	}
+
+	off := &models.TrustSettings{}
+	if IsNewcomer(off, &models.TrustMember{JoinedAt: now}, now) {
+		t.Error("IsNewcomer with both rules off = true, want false")
+	}
+}
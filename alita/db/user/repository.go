@@ -156,6 +156,30 @@ func LoadUsersStats() (count int64) {
 	return
 }
 
+// IDNewerThan returns the percentage of users known to the bot whose ID is
+// lower than userID. Telegram hands out IDs roughly in sign-up order, so a
+// high value hints at a recently created account. ok is false when the bot
+// knows too few users for the figure to mean anything.
+func IDNewerThan(userID int64) (percent int, ok bool) {
+	var total, older int64
+	if err := db.DB.Model(&models.User{}).Count(&total).Error; err != nil {
+		log.Errorf("[Database][IDNewerThan]: %v", err)
+		return 0, false
+	}
+	if total < minUsersForIDRank {
+		return 0, false
+	}
+	if err := db.DB.Model(&models.User{}).Where("user_id < ?", userID).Count(&older).Error; err != nil {
+		log.Errorf("[Database][IDNewerThan]: %v", err)
+		return 0, false
+	}
+	return int(older * 100 / total), true
+}
+
+// minUsersForIDRank is how many users the bot must know before IDNewerThan
+// gives an answer.
+const minUsersForIDRank = 100
+
 // LoadUserActivityStats returns Daily Active Users, Weekly Active Users, and Monthly Active Users.
 // These metrics are based on last_activity timestamps within the respective time periods.
 func LoadUserActivityStats() (dau, wau, mau int64) {
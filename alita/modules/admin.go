@@ -14,7 +14,6 @@ import (
 
 	"github.com/PaulSonOfLars/gotgbot/v2"
 	"github.com/PaulSonOfLars/gotgbot/v2/ext"
-	"github.com/PaulSonOfLars/gotgbot/v2/ext/handlers"
 
 	"github.com/divkix/Alita_Robot/alita/utils/chat_status"
 	"github.com/divkix/Alita_Robot/alita/utils/error_handling"
@@ -775,14 +774,14 @@ func LoadAdmin(dispatcher *ext.Dispatcher) {
 
 	// adminCache uses custom permission checking (direct member status lookup),
 	// so it remains a raw handler.
-	dispatcher.AddHandler(handlers.NewCommand("admincache", func(b *gotgbot.Bot, ctx *ext.Context) error {
+	helpers.AddCommand(dispatcher, "admincache", func(b *gotgbot.Bot, ctx *ext.Context) error {
 		defer error_handling.RecoverFromPanic("admincache", "admin")
 		c, err := helpers.BuildCommandContext(b, ctx)
 		if err != nil {
 			return ext.EndGroups
 		}
 		return adminModule.adminCache(c)
-	}))
+	})
 }
 
 // clearAdminCache handles the /clearadmincache command to delete the cached admin list.
@@ -0,0 +1,261 @@
+package modules
+
+import (
+	"fmt"
+	"html"
+	"maps"
+	"slices"
+	"strings"
+	"unicode/utf16"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/PaulSonOfLars/gotgbot/v2"
+	"github.com/PaulSonOfLars/gotgbot/v2/ext"
+	"github.com/PaulSonOfLars/gotgbot/v2/ext/handlers"
+
+	"github.com/divkix/Alita_Robot/alita/db/aliases"
+	"github.com/divkix/Alita_Robot/alita/db/lang"
+	"github.com/divkix/Alita_Robot/alita/i18n"
+	"github.com/divkix/Alita_Robot/alita/utils/chat_status"
+	"github.com/divkix/Alita_Robot/alita/utils/formatting"
+	"github.com/divkix/Alita_Robot/alita/utils/helpers"
+)
+
+var aliasesModule = moduleStruct{moduleName: "Aliases"}
+
+// aliasResolvedKey marks an update that was already rewritten from an alias,
+// so a rewritten update is never resolved a second time.
+const aliasResolvedKey = "alias_resolved"
+
+// isAliasRedispatch reports whether the update is the rewritten copy of an
+// aliased command. Message watchers below the default group already saw the
+// original message and skip the copy so it is only counted once.
+func isAliasRedispatch(ctx *ext.Context) bool {
+	resolved, _ := ctx.Data[aliasResolvedKey].(bool)
+	return resolved
+}
+
+/*
+	To add or remove a command alias
+
+# Connection - true, true
+
+Only admins can use this command to map an alias to a command or a note.
+*/
+// alias handles /alias add <alias> <command|#note> and /alias rm <alias>.
+// An alias may not reuse a registered command name, since the command would
+// always win, and a command target must be a registered command.
+func (moduleStruct) alias(b *gotgbot.Bot, ctx *ext.Context) error {
+	msg := ctx.EffectiveMessage
+	// connection status
+	connectedChat := chat_status.IsUserConnected(b, ctx, true, true)
+	if connectedChat == nil {
+		return ext.EndGroups
+	}
+	ctx.EffectiveChat = connectedChat
+	chat := ctx.EffectiveChat
+	args := ctx.Args()[1:]
+	tr := i18n.MustNewTranslator(lang.GetLanguage(ctx))
+
+	var text string
+	switch {
+	case len(args) == 3 && strings.ToLower(args[0]) == "add":
+		name, okName := aliases.NormalizeAlias(args[1])
+		target, okTarget := aliases.NormalizeTarget(args[2])
+		existing := aliases.GetAliases(chat.Id)
+		_, replacing := existing[name]
+		switch {
+		case !okName:
+			text, _ = tr.GetString("aliases_invalid_alias", i18n.TranslationParams{"alias": html.EscapeString(args[1])})
+		case !okTarget:
+			text, _ = tr.GetString("aliases_invalid_target", i18n.TranslationParams{"target": html.EscapeString(args[2])})
+		case helpers.IsRegisteredCommand(name):
+			text, _ = tr.GetString("aliases_shadows_command", i18n.TranslationParams{"alias": name})
+		case !strings.HasPrefix(target, aliases.NotePrefix) && !helpers.IsRegisteredCommand(target):
+			text, _ = tr.GetString("aliases_unknown_command", i18n.TranslationParams{"target": target})
+		case name == target:
+			text, _ = tr.GetString("aliases_self_target")
+		case !replacing && len(existing) >= aliases.MaxAliasesPerChat:
+			text, _ = tr.GetString("aliases_limit_reached", i18n.TranslationParams{"limit": aliases.MaxAliasesPerChat})
+		default:
+			if err := aliases.AddAlias(chat.Id, name, target); err != nil {
+				text, _ = tr.GetString("common_settings_save_failed")
+				break
+			}
+			text, _ = tr.GetString("aliases_added", i18n.TranslationParams{
+				"alias":  name,
+				"target": formatAliasTarget(target),
+			})
+		}
+	case len(args) == 2 && slices.Contains([]string{"rm", "remove"}, strings.ToLower(args[0])):
+		name, _ := aliases.NormalizeAlias(args[1])
+		removed, err := aliases.RemoveAlias(chat.Id, name)
+		switch {
+		case err != nil:
+			text, _ = tr.GetString("common_settings_save_failed")
+		case !removed:
+			text, _ = tr.GetString("aliases_not_found", i18n.TranslationParams{"alias": html.EscapeString(name)})
+		default:
+			text, _ = tr.GetString("aliases_removed", i18n.TranslationParams{"alias": html.EscapeString(name)})
+		}
+	default:
+		text, _ = tr.GetString("aliases_usage")
+	}
+
+	_, err := msg.Reply(b, text, formatting.Shtml())
+	if err != nil {
+		log.Error(err)
+		return err
+	}
+	return ext.EndGroups
+}
+
+/*
+	To list the aliases of a chat
+
+# Connection - false, true
+
+Any user can use this command to see the aliases configured in the chat.
+*/
+// listAliases lists every alias configured in the chat with its target.
+func (moduleStruct) listAliases(b *gotgbot.Bot, ctx *ext.Context) error {
+	msg := ctx.EffectiveMessage
+	// connection status
+	connectedChat := chat_status.IsUserConnected(b, ctx, false, true)
+	if connectedChat == nil {
+		return ext.EndGroups
+	}
+	ctx.EffectiveChat = connectedChat
+	chat := ctx.EffectiveChat
+	tr := i18n.MustNewTranslator(lang.GetLanguage(ctx))
+
+	chatAliases := aliases.GetAliases(chat.Id)
+	var text string
+	if len(chatAliases) == 0 {
+		text, _ = tr.GetString("aliases_none")
+	} else {
+		text, _ = tr.GetString("aliases_list_header")
+		var sb strings.Builder
+		for _, name := range slices.Sorted(maps.Keys(chatAliases)) {
+			fmt.Fprintf(&sb, "\n - /%s → %s", name, formatAliasTarget(chatAliases[name]))
+		}
+		text += sb.String()
+	}
+
+	_, err := msg.Reply(b, text, formatting.Shtml())
+	if err != nil {
+		log.Error(err)
+		return err
+	}
+	return ext.EndGroups
+}
+
+// formatAliasTarget renders an alias target for chat output.
+func formatAliasTarget(target string) string {
+	if strings.HasPrefix(target, aliases.NotePrefix) {
+		return fmt.Sprintf("<code>%s</code>", html.EscapeString(target))
+	}
+	return "/" + html.EscapeString(target)
+}
+
+// rewriteAliasMessage returns a copy of msg whose leading command is replaced
+// by the alias target. Command targets become "/target", note targets become
+// "/get note"; any arguments after the alias are kept and entity offsets are
+// shifted to match the new text.
+func rewriteAliasMessage(msg *gotgbot.Message, target string) *gotgbot.Message {
+	cmdToken, rest, _ := strings.Cut(msg.Text, " ")
+	if rest != "" {
+		rest = " " + rest
+	}
+
+	newCmd := "/" + target
+	newPrefix := newCmd
+	if note, isNote := strings.CutPrefix(target, aliases.NotePrefix); isNote {
+		newCmd = "/get"
+		newPrefix = newCmd + " " + note
+	}
+
+	rewritten := *msg
+	rewritten.Text = newPrefix + rest
+	delta := utf16Len(newPrefix) - utf16Len(cmdToken)
+	rewritten.Entities = make([]gotgbot.MessageEntity, 0, len(msg.Entities))
+	for _, ent := range msg.Entities {
+		switch {
+		case ent.Offset == 0 && ent.Type == "bot_command":
+			ent.Length = utf16Len(newCmd)
+		case ent.Offset >= utf16Len(cmdToken):
+			ent.Offset += delta
+		}
+		rewritten.Entities = append(rewritten.Entities, ent)
+	}
+	return &rewritten
+}
+
+// utf16Len returns the length of s in UTF-16 code units, the unit Telegram
+// uses for entity offsets.
+func utf16Len(s string) int64 {
+	return int64(len(utf16.Encode([]rune(s))))
+}
+
+// resolveAlias rewrites a message that starts with a chat alias into the
+// target command and dispatches it again, so disabling, /cmdperm overrides
+// and anonymous admin routing all apply to the target command. Watchers that
+// count or record messages skip the rewritten copy, see isAliasRedispatch.
+// It is the last handler of the default group, so it only sees commands no
+// other handler matched and can never shadow a built-in command.
+func (moduleStruct) resolveAlias(dispatcher *ext.Dispatcher) func(*gotgbot.Bot, *ext.Context) error {
+	return func(b *gotgbot.Bot, ctx *ext.Context) error {
+		msg := ctx.Message
+		chat := ctx.EffectiveChat
+		if msg == nil || chat == nil || chat.Type == "private" {
+			return ext.ContinueGroups
+		}
+		if isAliasRedispatch(ctx) {
+			return ext.ContinueGroups
+		}
+
+		name := helpers.ExtractCommand(b, msg)
+		if name == "" {
+			return ext.ContinueGroups
+		}
+		target, ok := aliases.ResolveAlias(chat.Id, name)
+		if !ok {
+			return ext.ContinueGroups
+		}
+
+		update := *ctx.Update
+		update.Message = rewriteAliasMessage(msg, target)
+		data := make(map[string]any, len(ctx.Data)+1)
+		maps.Copy(data, ctx.Data)
+		data[aliasResolvedKey] = true
+
+		if err := dispatcher.ProcessUpdate(b, &update, data); err != nil {
+			log.Errorf("[Aliases] Failed to dispatch alias %s -> %s in chat %d: %v", name, target, chat.Id, err)
+		}
+		return ext.EndGroups
+	}
+}
+
+// LoadAliases registers the alias management commands with the dispatcher.
+// The resolver itself is registered by LoadHelp once every other command
+// handler has been added.
+func LoadAliases(dispatcher *ext.Dispatcher) {
+	DefaultHelpRegistry().AbleMap[aliasesModule.moduleName] = true
+
+	helpers.AddCommand(dispatcher, "alias", aliasesModule.alias)
+	helpers.AddCommand(dispatcher, "aliases", aliasesModule.listAliases)
+	helpers.AddCmdToDisableable("aliases")
+}
+
+// registerAliasResolver adds the alias resolver to the default group. It must
+// be called after all other command handlers are registered.
+func registerAliasResolver(dispatcher *ext.Dispatcher) {
+	dispatcher.AddHandler(handlers.NewMessage(func(msg *gotgbot.Message) bool {
+		return strings.HasPrefix(msg.Text, "/")
+	}, aliasesModule.resolveAlias(dispatcher)))
+}
+
+func init() {
+	RegisterLegacyModule("Aliases", 280, LoadAliases)
+}
@@ -0,0 +1,195 @@
+//go:build testtools
+
+package modules
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/PaulSonOfLars/gotgbot/v2"
+	"github.com/PaulSonOfLars/gotgbot/v2/ext"
+	"github.com/PaulSonOfLars/gotgbot/v2/ext/handlers"
+
+	"github.com/divkix/Alita_Robot/alita/db/aliases"
+	"github.com/divkix/Alita_Robot/alita/db/trust"
+	"github.com/divkix/Alita_Robot/alita/utils/cache"
+	"github.com/divkix/Alita_Robot/alita/utils/helpers"
+)
+
+func TestAliasAddListAndRemove(t *testing.T) {
+	client := newModuleBotClient()
+	bot := newModuleTestBot(client)
+	chatID := uniqueModuleChatID()
+	chat := gotgbot.Chat{Id: chatID, Type: "supergroup", Title: "Alias Chat"}
+	admin := gotgbot.User{Id: 777000, FirstName: "Telegram"}
+	helpers.SetRegisteredCmdsForTest(t, []string{"alias", "rules", "ban"})
+
+	for _, text := range []string{"/alias add /Regeln rules", "/alias add wiki #Wiki"} {
+		if err := aliasesModule.alias(bot, newModuleMessageContext(bot, chat, admin, text)); err != ext.EndGroups {
+			t.Fatalf("alias(%q) error = %v, want EndGroups", text, err)
+		}
+	}
+	if target, ok := aliases.ResolveAlias(chatID, "regeln"); !ok || target != "rules" {
+		t.Fatalf("regeln alias = %q, %v, want rules", target, ok)
+	}
+	if target, ok := aliases.ResolveAlias(chatID, "wiki"); !ok || target != "#wiki" {
+		t.Fatalf("wiki alias = %q, %v, want #wiki", target, ok)
+	}
+
+	for _, text := range []string{
+		"/alias add bad! rules",
+		"/alias add same same",
+		"/alias add ban rules",
+		"/alias add kick nosuchcommand",
+		"/alias add chain regeln",
+	} {
+		if err := aliasesModule.alias(bot, newModuleMessageContext(bot, chat, admin, text)); err != ext.EndGroups {
+			t.Fatalf("alias(%q) error = %v, want EndGroups", text, err)
+		}
+	}
+	if got := aliases.GetAliases(chatID); len(got) != 2 {
+		t.Fatalf("aliases = %v, want two entries after rejected adds", got)
+	}
+
+	if err := aliasesModule.listAliases(bot, newModuleMessageContext(bot, chat, admin, "/aliases")); err != ext.EndGroups {
+		t.Fatalf("listAliases() error = %v, want EndGroups", err)
+	}
+	calls := client.callsFor("sendMessage")
+	list := calls[len(calls)-1].Params["text"].(string)
+	if !strings.Contains(list, "/regeln → /rules") || !strings.Contains(list, "<code>#wiki</code>") {
+		t.Fatalf("aliases list = %q, want both aliases", list)
+	}
+
+	if err := aliasesModule.alias(bot, newModuleMessageContext(bot, chat, admin, "/alias rm regeln")); err != ext.EndGroups {
+		t.Fatalf("alias rm error = %v, want EndGroups", err)
+	}
+	if _, ok := aliases.ResolveAlias(chatID, "regeln"); ok {
+		t.Fatal("regeln alias still resolves after removal")
+	}
+}
+
+func TestAliasResolverDispatchesTargetCommand(t *testing.T) {
+	client := newModuleBotClient()
+	bot := newModuleTestBot(client)
+	chatID := uniqueModuleChatID()
+	chat := gotgbot.Chat{Id: chatID, Type: "supergroup", Title: "Alias Chat"}
+	member := gotgbot.User{Id: 42, FirstName: "Member"}
+
+	if err := aliases.AddAlias(chatID, "regeln", "rules"); err != nil {
+		t.Fatalf("AddAlias() error = %v", err)
+	}
+	if err := aliases.AddAlias(chatID, "loop", "regeln"); err != nil {
+		t.Fatalf("AddAlias() error = %v", err)
+	}
+
+	var got []string
+	dispatcher := ext.NewDispatcher(&ext.DispatcherOpts{MaxRoutines: -1})
+	dispatcher.AddHandler(handlers.NewCommand("rules", func(_ *gotgbot.Bot, ctx *ext.Context) error {
+		got = append(got, ctx.EffectiveMessage.Text)
+		return ext.EndGroups
+	}))
+	registerAliasResolver(dispatcher)
+
+	send := func(text string) {
+		msg := &gotgbot.Message{
+			MessageId: 101,
+			Date:      1,
+			Chat:      chat,
+			From:      &member,
+			Text:      text,
+			Entities:  []gotgbot.MessageEntity{{Type: "bot_command", Offset: 0, Length: int64(len(strings.Fields(text)[0]))}},
+		}
+		if err := dispatcher.ProcessUpdate(bot, &gotgbot.Update{UpdateId: 1, Message: msg}, nil); err != nil {
+			t.Fatalf("ProcessUpdate(%q) error = %v", text, err)
+		}
+	}
+	send("/regeln section 2")
+	send("/rules")
+	send("/loop")
+	send("/unknown")
+
+	want := []string{"/rules section 2", "/rules"}
+	if strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Fatalf("rules handler saw %q, want %q", got, want)
+	}
+}
+
+func TestAliasedCommandIsCountedOnce(t *testing.T) {
+	withMiniredis(t)
+
+	client := newModuleBotClient()
+	bot := newModuleTestBot(client)
+	chat := gotgbot.Chat{Id: uniqueModuleChatID(), Type: "supergroup", Title: "Alias Chat"}
+	cleanupTrustChat(t, chat.Id)
+	member := gotgbot.User{Id: 43, FirstName: "Member"}
+
+	if err := aliases.AddAlias(chat.Id, "regeln", "rules"); err != nil {
+		t.Fatalf("AddAlias() error = %v", err)
+	}
+	if err := trust.RecordJoin(chat.Id, member.Id, time.Now()); err != nil {
+		t.Fatalf("RecordJoin() error = %v", err)
+	}
+
+	runs := 0
+	dispatcher := ext.NewDispatcher(&ext.DispatcherOpts{MaxRoutines: -1})
+	LoadUsers(dispatcher)
+	LoadTrust(dispatcher)
+	LoadPurges(dispatcher)
+	dispatcher.AddHandler(handlers.NewCommand("rules", func(_ *gotgbot.Bot, _ *ext.Context) error {
+		runs++
+		return ext.EndGroups
+	}))
+	registerAliasResolver(dispatcher)
+
+	msg := &gotgbot.Message{
+		MessageId: 101,
+		Date:      time.Now().Unix(),
+		Chat:      chat,
+		From:      &member,
+		Text:      "/regeln",
+		Entities:  []gotgbot.MessageEntity{{Type: "bot_command", Offset: 0, Length: 7}},
+	}
+	if err := dispatcher.ProcessUpdate(bot, &gotgbot.Update{UpdateId: 1, Message: msg}, nil); err != nil {
+		t.Fatalf("ProcessUpdate() error = %v", err)
+	}
+
+	if runs != 1 {
+		t.Fatalf("rules handler ran %d times, want 1", runs)
+	}
+	if got := trust.GetMember(chat.Id, member.Id); got == nil || got.Messages != 1 {
+		t.Fatalf("trust member = %+v, want 1 counted message", got)
+	}
+	rdb := cache.GetRedisClient()
+	counted := int64(0)
+	for _, key := range rdb.SMembers(cache.Context, chatActivityPendingKey).Val() {
+		n, _ := rdb.HGet(cache.Context, key, chatActivityMessages).Int64()
+		counted += n
+	}
+	if counted != 1 {
+		t.Fatalf("chat stats messages = %d, want 1", counted)
+	}
+	if indexed := recentMessagesFrom(chat.Id, member.Id, 0, 0); len(indexed) != 1 {
+		t.Fatalf("indexed messages = %d, want 1", len(indexed))
+	}
+}
+
+func TestRewriteAliasMessageForNoteTarget(t *testing.T) {
+	msg := &gotgbot.Message{
+		Text: "/wiki@Bot *extra*",
+		Entities: []gotgbot.MessageEntity{
+			{Type: "bot_command", Offset: 0, Length: 9},
+			{Type: "bold", Offset: 10, Length: 7},
+		},
+	}
+	rewritten := rewriteAliasMessage(msg, "#faq")
+	if rewritten.Text != "/get faq *extra*" {
+		t.Fatalf("rewritten text = %q, want /get faq *extra*", rewritten.Text)
+	}
+	if rewritten.Entities[0].Length != 4 || rewritten.Entities[1].Offset != 9 {
+		t.Fatalf("rewritten entities = %+v, want command length 4 and bold offset 9", rewritten.Entities)
+	}
+	if msg.Text != "/wiki@Bot *extra*" || msg.Entities[1].Offset != 10 {
+		t.Fatal("rewriteAliasMessage modified the original message")
+	}
+}
@@ -0,0 +1,441 @@
+package modules
+
+import (
+	"fmt"
+	"html"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/PaulSonOfLars/gotgbot/v2"
+	"github.com/PaulSonOfLars/gotgbot/v2/ext"
+	"github.com/redis/go-redis/v9"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/divkix/Alita_Robot/alita/config"
+	"github.com/divkix/Alita_Robot/alita/db/antispam"
+	"github.com/divkix/Alita_Robot/alita/db/lang"
+	"github.com/divkix/Alita_Robot/alita/db/models"
+	"github.com/divkix/Alita_Robot/alita/i18n"
+	"github.com/divkix/Alita_Robot/alita/utils/cache"
+	"github.com/divkix/Alita_Robot/alita/utils/chat_status"
+	"github.com/divkix/Alita_Robot/alita/utils/formatting"
+	"github.com/divkix/Alita_Robot/alita/utils/helpers"
+	"github.com/divkix/Alita_Robot/alita/utils/spamfilter"
+)
+
+const (
+	antidupeWindow     = 15 * time.Minute
+	antidupeKeyPrefix  = "alita:antidupe" // format: antidupe:text:band:value or antidupe:file:file_unique_id (sorted sets)
+	antidupeMaxMembers = 200              // sightings read back per fingerprint key
+	antidupeReportText = 300              // runes of the message quoted in the report
+)
+
+// trackDupeScript records a sighting under every key of a fingerprint, drops
+// the sightings older than the window and returns the latest ones of all keys.
+var trackDupeScript = redis.NewScript(`
+	local sightings = {}
+	for _, key in ipairs(KEYS) do
+		redis.call('ZREMRANGEBYSCORE', key, 0, ARGV[1])
+		redis.call('ZADD', key, ARGV[2], ARGV[3])
+		redis.call('EXPIRE', key, ARGV[4])
+		for _, member in ipairs(redis.call('ZRANGE', key, -tonumber(ARGV[5]), -1)) do
+			table.insert(sightings, member)
+		end
+	end
+	return sightings
+`)
+
+// dupeFingerprint identifies the content of a message across chats: the
+// SimHash of its text and the file_unique_id of its media.
+type dupeFingerprint struct {
+	fileID  string
+	simHash uint64
+	hasText bool
+}
+
+// messageFingerprint returns the fingerprint of a message, or false if it has
+// neither enough text nor media worth tracking. Stickers are left out: the
+// same popular sticker shows up everywhere.
+func messageFingerprint(msg *gotgbot.Message) (dupeFingerprint, bool) {
+	var fp dupeFingerprint
+	fp.simHash, fp.hasText = spamfilter.SimHash(spamText(msg))
+	switch {
+	case len(msg.Photo) > 0:
+		fp.fileID = msg.Photo[len(msg.Photo)-1].FileUniqueId
+	case msg.Video != nil:
+		fp.fileID = msg.Video.FileUniqueId
+	case msg.Animation != nil:
+		fp.fileID = msg.Animation.FileUniqueId
+	case msg.Document != nil:
+		fp.fileID = msg.Document.FileUniqueId
+	case msg.Audio != nil:
+		fp.fileID = msg.Audio.FileUniqueId
+	case msg.Voice != nil:
+		fp.fileID = msg.Voice.FileUniqueId
+	case msg.VideoNote != nil:
+		fp.fileID = msg.VideoNote.FileUniqueId
+	}
+	return fp, fp.hasText || fp.fileID != ""
+}
+
+// keys returns the Redis keys a fingerprint is looked up by: one per SimHash
+// band, so near copies of a text meet under at least one of them, and one
+// for the file.
+func (fp dupeFingerprint) keys() []string {
+	keys := make([]string, 0, spamfilter.SimHashBands+1)
+	if fp.hasText {
+		for i, band := range spamfilter.Bands(fp.simHash) {
+			keys = append(keys, fmt.Sprintf("%s:text:%d:%04x", antidupeKeyPrefix, i, band))
+		}
+	}
+	if fp.fileID != "" {
+		keys = append(keys, fmt.Sprintf("%s:file:%s", antidupeKeyPrefix, fp.fileID))
+	}
+	return keys
+}
+
+// id names the content of a fingerprint in reports.
+func (fp dupeFingerprint) id() string {
+	if fp.fileID != "" {
+		return "file:" + fp.fileID
+	}
+	return fmt.Sprintf("text:%016x", fp.simHash)
+}
+
+// matches reports whether two fingerprints are of the same content.
+func (fp dupeFingerprint) matches(other dupeFingerprint) bool {
+	if fp.fileID != "" && fp.fileID == other.fileID {
+		return true
+	}
+	return fp.hasText && other.hasText && spamfilter.SimHashDistance(fp.simHash, other.simHash) <= spamfilter.SimHashMaxDistance
+}
+
+// dupeSighting is one message seen in an opted-in chat.
+type dupeSighting struct {
+	chatID      int64
+	userID      int64
+	messageID   int64
+	fingerprint dupeFingerprint
+}
+
+// member encodes a sighting as a sorted set member:
+// chat_id:user_id:message_id:simhash:file_unique_id, with "-" for a missing
+// hash or file.
+func (s dupeSighting) member() string {
+	hash, file := "-", "-"
+	if s.fingerprint.hasText {
+		hash = strconv.FormatUint(s.fingerprint.simHash, 16)
+	}
+	if s.fingerprint.fileID != "" {
+		file = s.fingerprint.fileID
+	}
+	return fmt.Sprintf("%d:%d:%d:%s:%s", s.chatID, s.userID, s.messageID, hash, file)
+}
+
+// parseDupeSighting decodes a sorted set member written by member.
+func parseDupeSighting(member string) (dupeSighting, bool) {
+	parts := strings.Split(member, ":")
+	if len(parts) != 5 {
+		return dupeSighting{}, false
+	}
+	var (
+		s    dupeSighting
+		errs [3]error
+	)
+	s.chatID, errs[0] = strconv.ParseInt(parts[0], 10, 64)
+	s.userID, errs[1] = strconv.ParseInt(parts[1], 10, 64)
+	s.messageID, errs[2] = strconv.ParseInt(parts[2], 10, 64)
+	for _, err := range errs {
+		if err != nil {
+			return dupeSighting{}, false
+		}
+	}
+	if parts[3] != "-" {
+		hash, err := strconv.ParseUint(parts[3], 16, 64)
+		if err != nil {
+			return dupeSighting{}, false
+		}
+		s.fingerprint.simHash, s.fingerprint.hasText = hash, true
+	}
+	if parts[4] != "-" {
+		s.fingerprint.fileID = parts[4]
+	}
+	return s, true
+}
+
+// trackDuplicate records a sighting in the shared store and returns the
+// earlier sightings of the same content within the window, each once.
+func trackDuplicate(sighting dupeSighting, now time.Time) ([]dupeSighting, error) {
+	if !cache.IsRedisAvailable() {
+		return nil, fmt.Errorf("cache not initialized")
+	}
+	cutoff := now.Add(-antidupeWindow).UnixMilli()
+	members, err := trackDupeScript.Run(cache.Context, cache.GetRedisClient(), sighting.fingerprint.keys(),
+		cutoff, now.UnixMilli(), sighting.member(), int(antidupeWindow.Seconds()), antidupeMaxMembers).StringSlice()
+	if err != nil {
+		return nil, err
+	}
+
+	seen := map[string]bool{sighting.member(): true}
+	var matches []dupeSighting
+	for _, member := range members {
+		if seen[member] {
+			continue
+		}
+		seen[member] = true
+		other, ok := parseDupeSighting(member)
+		if ok && sighting.fingerprint.matches(other.fingerprint) {
+			matches = append(matches, other)
+		}
+	}
+	return matches, nil
+}
+
+// dupeChatCount returns the number of chats a sighting and its matches were
+// seen in.
+func dupeChatCount(sighting dupeSighting, matches []dupeSighting) int {
+	chats := map[int64]struct{}{sighting.chatID: {}}
+	for _, match := range matches {
+		chats[match.chatID] = struct{}{}
+	}
+	return len(chats)
+}
+
+// claimDupeSighting marks a stored message as handled and reports whether it
+// was not handled before, so every copy is acted on once.
+func claimDupeSighting(sighting dupeSighting) bool {
+	key := fmt.Sprintf("%s:handled:%d:%d", antidupeKeyPrefix, sighting.chatID, sighting.messageID)
+	first, err := cache.GetRedisClient().SetNX(cache.Context, key, 1, antidupeWindow).Result()
+	return err == nil && first
+}
+
+// actOnEarlierDuplicates handles the copies of duplicate content that were
+// sent before it crossed the threshold, in whatever opted-in chat they are:
+// each sender's copies are deleted and the sender is acted on once, as that
+// chat's settings say.
+func actOnEarlierDuplicates(b *gotgbot.Bot, matches []dupeSighting, chats int) {
+	type chatSender struct{ chatID, userID int64 }
+	pending := map[chatSender][]int64{}
+	var order []chatSender
+	for _, match := range matches {
+		if !claimDupeSighting(match) {
+			continue
+		}
+		key := chatSender{match.chatID, match.userID}
+		if _, ok := pending[key]; !ok {
+			order = append(order, key)
+		}
+		pending[key] = append(pending[key], match.messageID)
+	}
+	for _, key := range order {
+		actOnDuplicateSender(b, key.chatID, key.userID, pending[key], chats)
+	}
+}
+
+// actOnDuplicateSender deletes or flags the given messages of one sender in
+// a chat and applies the chat's duplicate action to the sender.
+func actOnDuplicateSender(b *gotgbot.Bot, chatID, senderID int64, messageIDs []int64, chats int) {
+	settings := antispam.GetAntispamSettings(chatID)
+	if !settings.DupeEnabled || chats < settings.DupeChats {
+		return
+	}
+	chat := &gotgbot.Chat{Id: chatID, Type: "supergroup"}
+	name := extractDisplayName(senderID)
+	sender := &gotgbot.Sender{User: &gotgbot.User{Id: senderID, FirstName: name}, ChatId: chatID}
+	if chat_status.IsChannelId(senderID) {
+		sender = &gotgbot.Sender{Chat: &gotgbot.Chat{Id: senderID, Type: "channel", Title: name}, ChatId: chatID}
+	}
+	last := messageIDs[len(messageIDs)-1]
+	ctx := &ext.Context{
+		EffectiveChat:    chat,
+		EffectiveSender:  sender,
+		EffectiveUser:    sender.User,
+		EffectiveMessage: &gotgbot.Message{MessageId: last, Chat: *chat},
+	}
+	tr := i18n.MustNewTranslator(lang.GetLanguage(ctx))
+
+	if settings.DupeAction == models.SpamActionFlag {
+		text, _ := tr.GetString("antidupe_flagged", i18n.TranslationParams{
+			"user":  formatting.MentionHtml(senderID, name),
+			"chats": chats,
+		})
+		opts := formatting.Shtml()
+		opts.ReplyParameters = &gotgbot.ReplyParameters{MessageId: last, AllowSendingWithoutReply: true}
+		_, _ = b.SendMessage(chatID, text, opts)
+		return
+	}
+	if !chat_status.IsBotAdmin(b, ctx, chat) {
+		return
+	}
+	deleteMessageIDs(b, chat, messageIDs)
+	reason, _ := tr.GetString("antidupe_reason", i18n.TranslationParams{"chats": chats})
+	_ = applyModerationAction(b, ctx, settings.DupeAction, reason, "antidupe_", nil)
+}
+
+// checkDuplicate looks a message from a member up in the fingerprints shared
+// by the chats that turned on duplicate detection, and acts on it once the
+// same content showed up in as many chats as the chat asks for. The earlier
+// copies in every opted-in chat are acted on too. It reports whether the
+// message was removed.
+func (moduleStruct) checkDuplicate(b *gotgbot.Bot, ctx *ext.Context) bool {
+	chat := ctx.EffectiveChat
+	msg := ctx.EffectiveMessage
+	if msg == nil || chat.Type == "private" || !cache.IsRedisAvailable() {
+		return false
+	}
+	settings := antispam.GetAntispamSettings(chat.Id)
+	if !settings.DupeEnabled || strings.HasPrefix(spamText(msg), "/") {
+		return false
+	}
+	fp, ok := messageFingerprint(msg)
+	if !ok {
+		return false
+	}
+
+	user := ctx.EffectiveUser
+	sighting := dupeSighting{chatID: chat.Id, userID: user.Id, messageID: msg.MessageId, fingerprint: fp}
+	matches, err := trackDuplicate(sighting, time.Now())
+	if err != nil {
+		log.Errorf("[Antidupe] Failed to track message %d of chat %d: %v", msg.MessageId, chat.Id, err)
+		return false
+	}
+	chats := dupeChatCount(sighting, matches)
+	if chats < settings.DupeChats {
+		return false
+	}
+	log.Infof("[Antidupe] Message %d of user %d in chat %d seen in %d chats (%s)", msg.MessageId, user.Id, chat.Id, chats, fp.id())
+	reportDuplicate(b, ctx, fp, chats)
+	claimDupeSighting(sighting)
+	actOnEarlierDuplicates(b, matches, chats)
+
+	tr := i18n.MustNewTranslator(lang.GetLanguage(ctx))
+	if settings.DupeAction == models.SpamActionFlag {
+		text, _ := tr.GetString("antidupe_flagged", i18n.TranslationParams{
+			"user":  formatting.MentionHtml(user.Id, user.FirstName),
+			"chats": chats,
+		})
+		_, _ = msg.Reply(b, text, formatting.Shtml())
+		return false
+	}
+
+	if !chat_status.IsBotAdmin(b, ctx, chat) {
+		return false
+	}
+	_ = helpers.DeleteMessageWithErrorHandling(b, chat.Id, msg.MessageId)
+	reason, _ := tr.GetString("antidupe_reason", i18n.TranslationParams{"chats": chats})
+	_ = applyModerationAction(b, ctx, settings.DupeAction, reason, "antidupe_", nil)
+	return true
+}
+
+// reportDuplicate tells the log channel about duplicate content, once per
+// content and window.
+func reportDuplicate(b *gotgbot.Bot, ctx *ext.Context, fp dupeFingerprint, chats int) {
+	if config.AppConfig == nil || config.AppConfig.MessageDump == 0 {
+		return
+	}
+	reportKey := fmt.Sprintf("%s:reported:%s", antidupeKeyPrefix, fp.id())
+	first, err := cache.GetRedisClient().SetNX(cache.Context, reportKey, 1, antidupeWindow).Result()
+	if err != nil || !first {
+		return
+	}
+
+	chat := ctx.EffectiveChat
+	user := ctx.EffectiveUser
+	content := truncateRunes(spamText(ctx.EffectiveMessage), antidupeReportText)
+	if fp.fileID != "" {
+		content = strings.TrimSpace(fp.fileID + " " + content)
+	}
+	tr := i18n.MustNewTranslator("en")
+	text, _ := tr.GetString("antidupe_report", i18n.TranslationParams{
+		"chats":   chats,
+		"chat":    html.EscapeString(chat.Title),
+		"chat_id": chat.Id,
+		"user":    formatting.MentionHtml(user.Id, user.FirstName),
+		"user_id": user.Id,
+		"content": html.EscapeString(content),
+		"window":  int(antidupeWindow.Minutes()),
+	})
+	if _, err := b.SendMessage(config.AppConfig.MessageDump, text, formatting.Shtml()); err != nil {
+		log.Errorf("[Antidupe] Failed to report duplicate %s to the log channel: %v", fp.id(), err)
+	}
+}
+
+// antidupeCommand shows or changes the duplicate detection settings of a
+// chat.
+func (moduleStruct) antidupeCommand(b *gotgbot.Bot, ctx *ext.Context) error {
+	msg := ctx.EffectiveMessage
+	// connection status
+	connectedChat := chat_status.IsUserConnected(b, ctx, true, true)
+	if connectedChat == nil {
+		return ext.EndGroups
+	}
+	chat := connectedChat
+	args := ctx.Args()[1:]
+	tr := i18n.MustNewTranslator(lang.GetLanguage(ctx))
+
+	reply := func(key string, params i18n.TranslationParams) error {
+		text, _ := tr.GetString(key, params)
+		_, _ = msg.Reply(b, text, formatting.Shtml())
+		return ext.EndGroups
+	}
+
+	settings := antispam.GetAntispamSettings(chat.Id)
+	if len(args) == 0 {
+		status, _ := tr.GetString("antispam_off")
+		if settings.DupeEnabled {
+			status, _ = tr.GetString("antispam_on")
+		}
+		return reply("antidupe_status", i18n.TranslationParams{
+			"status": status,
+			"action": settings.DupeAction,
+			"chats":  settings.DupeChats,
+			"window": int(antidupeWindow.Minutes()),
+		})
+	}
+
+	var err error
+	switch strings.ToLower(args[0]) {
+	case "on", "yes", "true":
+		if err = antispam.SetDupeEnabled(chat.Id, true); err != nil {
+			break
+		}
+		return reply("antidupe_enabled", i18n.TranslationParams{
+			"chats":  settings.DupeChats,
+			"window": int(antidupeWindow.Minutes()),
+			"action": settings.DupeAction,
+		})
+	case "off", "no", "false":
+		if err = antispam.SetDupeEnabled(chat.Id, false); err != nil {
+			break
+		}
+		return reply("antidupe_disabled", nil)
+	case "action":
+		if len(args) < 2 || !slices.Contains(antispam.DupeActions, strings.ToLower(args[1])) {
+			return reply("antidupe_invalid_action", i18n.TranslationParams{"actions": strings.Join(antispam.DupeActions, ", ")})
+		}
+		action := strings.ToLower(args[1])
+		if err = antispam.SetDupeAction(chat.Id, action); err != nil {
+			break
+		}
+		return reply("antidupe_action_set", i18n.TranslationParams{"action": action})
+	case "chats":
+		var chats int
+		if len(args) >= 2 {
+			chats, _ = strconv.Atoi(args[1])
+		}
+		if chats < antispam.MinDupeChats || chats > antispam.MaxDupeChats {
+			return reply("antidupe_invalid_chats", i18n.TranslationParams{"min": antispam.MinDupeChats, "max": antispam.MaxDupeChats})
+		}
+		if err = antispam.SetDupeChats(chat.Id, chats); err != nil {
+			break
+		}
+		return reply("antidupe_chats_set", i18n.TranslationParams{"chats": chats, "window": int(antidupeWindow.Minutes())})
+	default:
+		return reply("antidupe_usage", nil)
+	}
+
+	log.Errorf("[Antidupe] Failed to update settings of chat %d: %v", chat.Id, err)
+	return reply("error_generic", nil)
+}
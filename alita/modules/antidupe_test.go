@@ -0,0 +1,173 @@
+//go:build testtools
+
+package modules
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/PaulSonOfLars/gotgbot/v2"
+	"github.com/PaulSonOfLars/gotgbot/v2/ext"
+	"github.com/PaulSonOfLars/gotgbot/v2/ext/handlers"
+	"github.com/PaulSonOfLars/gotgbot/v2/ext/handlers/filters/message"
+
+	"github.com/divkix/Alita_Robot/alita/config"
+	"github.com/divkix/Alita_Robot/alita/db"
+	"github.com/divkix/Alita_Robot/alita/db/antispam"
+	"github.com/divkix/Alita_Robot/alita/db/models"
+)
+
+func TestDupeSightingMemberRoundTrip(t *testing.T) {
+	for _, fp := range []dupeFingerprint{
+		{simHash: 0xdeadbeef, hasText: true},
+		{fileID: "AQADxyz-_1"},
+		{fileID: "AQADxyz-_1", simHash: 1, hasText: true},
+	} {
+		want := dupeSighting{chatID: -1001234, userID: 42, messageID: 7, fingerprint: fp}
+		got, ok := parseDupeSighting(want.member())
+		if !ok || got != want {
+			t.Fatalf("parseDupeSighting(%q) = %+v, %v; want %+v", want.member(), got, ok, want)
+		}
+	}
+	if _, ok := parseDupeSighting("1:2:3"); ok {
+		t.Fatal("parseDupeSighting() accepted a malformed member")
+	}
+}
+
+func TestMessageFingerprintSkipsShortTextAndStickers(t *testing.T) {
+	if _, ok := messageFingerprint(&gotgbot.Message{Text: "hello there"}); ok {
+		t.Fatal("short text got a fingerprint")
+	}
+	if _, ok := messageFingerprint(&gotgbot.Message{Sticker: &gotgbot.Sticker{FileUniqueId: "sticker"}}); ok {
+		t.Fatal("sticker got a fingerprint")
+	}
+	fp, ok := messageFingerprint(&gotgbot.Message{Photo: []gotgbot.PhotoSize{{FileUniqueId: "small"}, {FileUniqueId: "large"}}})
+	if !ok || fp.fileID != "large" || fp.hasText {
+		t.Fatalf("photo fingerprint = %+v, %v; want the largest size only", fp, ok)
+	}
+}
+
+func TestCheckDuplicateActsOnceSeenInEnoughChats(t *testing.T) {
+	resetAntiSpamMapForTest(t)
+	withMiniredis(t)
+
+	previousDump := config.AppConfig.MessageDump
+	config.AppConfig.MessageDump = -100999
+	t.Cleanup(func() { config.AppConfig.MessageDump = previousDump })
+
+	client := newModuleBotClient()
+	bot := newModuleTestBot(client)
+	dispatcher := ext.NewDispatcher(&ext.DispatcherOpts{MaxRoutines: -1})
+	LoadAntispam(dispatcher)
+	processed := 0
+	dispatcher.AddHandler(handlers.NewMessage(message.All, func(*gotgbot.Bot, *ext.Context) error {
+		processed++
+		return ext.ContinueGroups
+	}))
+
+	chats := make([]gotgbot.Chat, 3)
+	for i := range chats {
+		chats[i] = gotgbot.Chat{Id: uniqueModuleChatID(), Type: "supergroup", Title: fmt.Sprintf("Chat %d", i)}
+		chatID := chats[i].Id
+		t.Cleanup(func() { _ = db.DB.Where("chat_id = ?", chatID).Delete(&models.AntispamSettings{}).Error })
+	}
+	for _, chat := range chats[1:] {
+		if err := antispam.SetDupeEnabled(chat.Id, true); err != nil {
+			t.Fatalf("SetDupeEnabled() error = %v", err)
+		}
+	}
+
+	member := &gotgbot.User{Id: 42, FirstName: "Member"}
+	send := func(id int64, chat gotgbot.Chat, text string) {
+		t.Helper()
+		update := &gotgbot.Update{UpdateId: id, Message: &gotgbot.Message{MessageId: id, Date: 1, Chat: chat, From: member, Text: text}}
+		if err := dispatcher.ProcessUpdate(bot, update, nil); err != nil {
+			t.Fatalf("ProcessUpdate(%q) error = %v", text, err)
+		}
+	}
+
+	// The first chat did not opt in, so it neither counts nor acts.
+	send(1, chats[0], "Earn 500 dollars a day from home, message me now https://t.me/+abc")
+	send(2, chats[1], "Earn 500 dollars a day from home, message me now https://t.me/+abc")
+	send(3, chats[2], "earn 500 DOLLARS a day from home!! message me now t.me/+xyz")
+	if processed != 3 || len(client.callsFor("deleteMessage")) != 0 {
+		t.Fatalf("processed=%d deletes=%d, want every message through while seen in 2 chats", processed, len(client.callsFor("deleteMessage")))
+	}
+
+	third := gotgbot.Chat{Id: uniqueModuleChatID(), Type: "supergroup", Title: "Chat 3"}
+	t.Cleanup(func() { _ = db.DB.Where("chat_id = ?", third.Id).Delete(&models.AntispamSettings{}).Error })
+	if err := antispam.SetDupeEnabled(third.Id, true); err != nil {
+		t.Fatalf("SetDupeEnabled() error = %v", err)
+	}
+	send(4, third, "Earn 500 dollars a day from home, message me now https://t.me/+def")
+	if processed != 3 {
+		t.Fatalf("duplicate reached downstream handlers, processed = %d", processed)
+	}
+	// The earlier copies in the opted-in chats go too; the first chat keeps its copy.
+	deleted := map[string]bool{}
+	for _, call := range client.callsFor("deleteMessage") {
+		deleted[fmt.Sprint(call.Params["message_id"])] = true
+	}
+	if len(deleted) != 3 || !deleted["2"] || !deleted["3"] || !deleted["4"] {
+		t.Fatalf("deleteMessage calls = %+v, want every copy in the opted-in chats deleted", client.callsFor("deleteMessage"))
+	}
+	restricted := map[string]bool{}
+	for _, call := range client.callsFor("restrictChatMember") {
+		if fmt.Sprint(call.Params["user_id"]) != "42" {
+			t.Fatalf("restrictChatMember call = %+v, want only the sender muted", call)
+		}
+		restricted[fmt.Sprint(call.Params["chat_id"])] = true
+	}
+	if len(restricted) != 3 || !restricted[fmt.Sprint(chats[1].Id)] || !restricted[fmt.Sprint(chats[2].Id)] || !restricted[fmt.Sprint(third.Id)] {
+		t.Fatalf("restrictChatMember calls = %+v, want the sender muted in each opted-in chat", client.callsFor("restrictChatMember"))
+	}
+	reports := 0
+	for _, call := range client.callsFor("sendMessage") {
+		if fmt.Sprint(call.Params["chat_id"]) == "-100999" {
+			reports++
+		}
+	}
+	if reports != 1 {
+		t.Fatalf("log channel reports = %d, want 1", reports)
+	}
+
+	// Flagging keeps the message, does not report the same content again and
+	// leaves the copies that were already handled alone.
+	if err := antispam.SetDupeAction(chats[1].Id, models.SpamActionFlag); err != nil {
+		t.Fatalf("SetDupeAction() error = %v", err)
+	}
+	send(5, chats[1], "Earn 500 dollars a day from home, message me now https://t.me/+ghi")
+	if processed != 4 || len(client.callsFor("deleteMessage")) != 3 || len(client.callsFor("restrictChatMember")) != 3 {
+		t.Fatalf("flag action: processed=%d deletes=%d, want the message kept", processed, len(client.callsFor("deleteMessage")))
+	}
+	reports = 0
+	for _, call := range client.callsFor("sendMessage") {
+		if fmt.Sprint(call.Params["chat_id"]) == "-100999" {
+			reports++
+		}
+	}
+	if reports != 1 {
+		t.Fatalf("log channel reports = %d, want still 1", reports)
+	}
+}
+
+func TestAntidupeCommandUpdatesSettings(t *testing.T) {
+	client := newModuleBotClient()
+	bot := newModuleTestBot(client)
+	chat := gotgbot.Chat{Id: uniqueModuleChatID(), Type: "supergroup", Title: "Dupe Chat"}
+	admin := gotgbot.User{Id: 777000, FirstName: "Telegram"}
+	t.Cleanup(func() { _ = db.DB.Where("chat_id = ?", chat.Id).Delete(&models.AntispamSettings{}).Error })
+
+	for _, text := range []string{"/antidupe on", "/antidupe action ban", "/antidupe chats 4", "/antidupe chats 40", "/antidupe action explode"} {
+		if err := antispamModule.antidupeCommand(bot, newModuleMessageContext(bot, chat, admin, text)); err != ext.EndGroups {
+			t.Fatalf("antidupeCommand(%q) error = %v", text, err)
+		}
+	}
+	settings := antispam.GetAntispamSettings(chat.Id)
+	if !settings.DupeEnabled || settings.DupeAction != models.SpamActionBan || settings.DupeChats != 4 {
+		t.Fatalf("settings = %+v, want duplicate detection on, banning from 4 chats", settings)
+	}
+	if replies := client.callsFor("sendMessage"); len(replies) != 5 {
+		t.Fatalf("replies = %d, want one per command", len(replies))
+	}
+}
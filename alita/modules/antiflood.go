@@ -17,6 +17,7 @@ import (
 
 	"github.com/divkix/Alita_Robot/alita/db"
 	"github.com/divkix/Alita_Robot/alita/db/antiflood"
+	"github.com/divkix/Alita_Robot/alita/db/approvals"
 	"github.com/divkix/Alita_Robot/alita/db/lang"
 	"github.com/divkix/Alita_Robot/alita/i18n"
 	"github.com/divkix/Alita_Robot/alita/utils/chat_status"
@@ -27,15 +28,15 @@ import (
 
 // Concurrency limits for flood protection operations
 const (
-	maxConcurrentAdminChecks  = 50 // Maximum concurrent admin permission checks
-	maxConcurrentMsgDeletions = 5  // Maximum concurrent message deletions during flood cleanup
+	maxConcurrentAdminChecks = 50 // Maximum concurrent admin permission checks
 )
 
 // floodKey is a type-safe composite key for flood tracking
 // Uses struct instead of string concatenation to avoid collisions
 type floodKey struct {
-	chatId int64
-	userId int64
+	chatId   int64
+	threadId int64 // forum topic with its own flood limit; 0 otherwise
+	userId   int64
 }
 
 type antifloodStruct struct {
@@ -139,14 +140,24 @@ func (a *antifloodStruct) cleanupLoop(ctx context.Context) {
 // Returns true if user has exceeded flood limit and should be restricted,
 // along with the flood control data and flood settings from the database.
 // This eliminates redundant database calls by fetching settings once.
-func (a *antifloodStruct) updateFlood(chatId, userId, msgId int64) (shouldPunish bool, floodCrc floodControl, floodSettings *db.AntifloodSettings) {
-	floodSettings = antiflood.GetFlood(chatId)
+func (a *antifloodStruct) updateFlood(chatId, threadId, userId, msgId int64) (shouldPunish bool, floodCrc floodControl, floodSettings *db.AntifloodSettings) {
+	floodSettings, topicLimit := antiflood.GetTopicFlood(chatId, threadId)
+	if !topicLimit {
+		// Messages are counted per topic only where the topic has its own limit
+		threadId = 0
+	}
+	if limit := newcomerFloodLimit(chatId, userId, floodSettings.Limit); limit != floodSettings.Limit {
+		// The settings may be shared through the cache, so newcomers get a copy.
+		newcomerSettings := *floodSettings
+		newcomerSettings.Limit = limit
+		floodSettings = &newcomerSettings
+	}
 
 	if floodSettings.Limit != 0 {
 		currentTime := time.Now().Unix()
 
 		// Use type-safe struct key instead of string concatenation
-		key := floodKey{chatId: chatId, userId: userId}
+		key := floodKey{chatId: chatId, threadId: threadId, userId: userId}
 
 		// Acquire per-key mutex to protect the Load → mutate → Store RMW cycle
 		muVal, _ := floodMu.LoadOrStore(key, &sync.Mutex{})
@@ -298,13 +309,13 @@ func (m *moduleStruct) checkFlood(b *gotgbot.Bot, ctx *ext.Context) error {
 	}
 
 	// Check if user is approved (immune to anti-spam)
-	if chat_status.IsApproved(b, chatId, userId) {
+	if chat_status.IsApprovedFor(b, chatId, userId, approvals.ScopeFlood) {
 		return ext.ContinueGroups
 	}
 
 	// PERFORMANCE FIX: Update flood and get settings in one call to eliminate redundant DB query
 	// Previously this was calling antiflood.GetFlood again after updateFlood, doubling the DB load
-	flooded, floodCrc, flood := antifloodModule.updateFlood(chatId, userId, msg.MessageId)
+	flooded, floodCrc, flood := antifloodModule.updateFlood(chatId, messageTopic(msg), userId, msg.MessageId)
 	if !flooded {
 		return ext.ContinueGroups
 	}
@@ -320,49 +331,8 @@ func (m *moduleStruct) checkFlood(b *gotgbot.Bot, ctx *ext.Context) error {
 	}
 
 	if flood.DeleteAntifloodMessage {
-		var firstError error
-		var errorMu sync.Mutex
-
-		recordError := func(err error, msgId int64) {
-			if err != nil {
-				log.Errorf("Failed to delete flood message %d: %v", msgId, err)
-				errorMu.Lock()
-				if firstError == nil {
-					firstError = err
-				}
-				errorMu.Unlock()
-			}
-		}
-
-		if len(floodCrc.messageIDs) <= 3 {
-			// Sequential deletion - continue on error
-			for _, i := range floodCrc.messageIDs {
-				err := helpers.DeleteMessageWithErrorHandling(b, chatId, i)
-				recordError(err, i)
-			}
-		} else {
-			// Concurrent deletion with rate limiting
-			sem := make(chan struct{}, maxConcurrentMsgDeletions)
-			var wg sync.WaitGroup
-
-			for _, msgId := range floodCrc.messageIDs {
-				wg.Add(1)
-				sem <- struct{}{}
-
-				go func(messageId int64) {
-					defer wg.Done()
-					defer func() { <-sem }()
-
-					err := helpers.DeleteMessageWithErrorHandling(b, chatId, messageId)
-					recordError(err, messageId)
-				}(msgId)
-			}
-
-			wg.Wait()
-		}
-
-		if firstError != nil {
-			log.Warnf("[Antiflood] Some flood messages could not be deleted (may be too old): %v", firstError)
+		if err := helpers.DeleteMessagesBatched(b, chatId, floodCrc.messageIDs, nil); err != nil {
+			log.Warnf("[Antiflood] Some flood messages could not be deleted (may be too old): %v", err)
 		}
 	} else {
 		_ = helpers.DeleteMessageWithErrorHandling(b, chatId, msg.MessageId)
@@ -463,17 +433,34 @@ func (m *moduleStruct) setFlood(b *gotgbot.Bot, ctx *ext.Context) error {
 
 	var replyText string
 
+	// A limit set in a forum topic only applies there
+	threadID := messageTopic(msg)
+	setLimit := func(limit int) error {
+		if threadID != 0 {
+			return antiflood.SetTopicFlood(chat.Id, threadID, limit)
+		}
+		return antiflood.SetFlood(chat.Id, limit)
+	}
+
 	if len(args) == 0 {
 		replyText, _ = tr.GetString(strings.ToLower(m.moduleName) + "_errors_expected_args")
+	} else if threadID != 0 && strings.ToLower(args[0]) == "reset" {
+		if err := antiflood.RemoveTopicFlood(chat.Id, threadID); err != nil {
+			errText, _ := tr.GetString("common_settings_save_failed")
+			_, _ = msg.Reply(b, errText, formatting.Shtml())
+			return ext.EndGroups
+		}
+		replyText, _ = tr.GetString(strings.ToLower(m.moduleName) + "_setflood_topic_reset")
 	} else {
 		if slices.Contains([]string{"off", "no", "false", "0"}, strings.ToLower(args[0])) {
-			if err := antiflood.SetFlood(chat.Id, 0); err != nil {
+			if err := setLimit(0); err != nil {
 				log.Errorf("[Antiflood] SetFlood failed for chat %d: %v", chat.Id, err)
 				errText, _ := tr.GetString("common_settings_save_failed")
 				_, _ = msg.Reply(b, errText, formatting.Shtml())
 				return ext.EndGroups
 			}
 			replyText, _ = tr.GetString(strings.ToLower(m.moduleName) + "_setflood_disabled")
+			replyText += topicScopeNote(tr, threadID)
 		} else {
 			num, err := strconv.Atoi(args[0])
 			if err != nil {
@@ -482,14 +469,14 @@ func (m *moduleStruct) setFlood(b *gotgbot.Bot, ctx *ext.Context) error {
 				if num < 3 || num > 100 {
 					replyText, _ = tr.GetString(strings.ToLower(m.moduleName) + "_errors_set_in_limit")
 				} else {
-					if err := antiflood.SetFlood(chat.Id, num); err != nil {
+					if err := setLimit(num); err != nil {
 						log.Errorf("[Antiflood] SetFlood failed for chat %d: %v", chat.Id, err)
 						errText, _ := tr.GetString("common_settings_save_failed")
 						_, _ = msg.Reply(b, errText, formatting.Shtml())
 						return ext.EndGroups
 					}
 					temp, _ := tr.GetString(strings.ToLower(m.moduleName) + "_setflood_success")
-					replyText = fmt.Sprintf(temp, num)
+					replyText = fmt.Sprintf(temp, num) + topicScopeNote(tr, threadID)
 				}
 			}
 		}
@@ -524,7 +511,7 @@ func (m *moduleStruct) flood(b *gotgbot.Bot, ctx *ext.Context) error {
 
 	tr := i18n.MustNewTranslator(lang.GetLanguage(ctx))
 
-	flood := antiflood.GetFlood(chat.Id)
+	flood, _ := antiflood.GetTopicFlood(chat.Id, messageTopic(msg))
 	if flood.Limit == 0 {
 		text, _ = tr.GetString(strings.ToLower(m.moduleName) + "_flood_disabled")
 	} else {
@@ -651,10 +638,10 @@ func (m *moduleStruct) setFloodDeleter(b *gotgbot.Bot, ctx *ext.Context) error {
 func LoadAntiflood(dispatcher *ext.Dispatcher) {
 	DefaultHelpRegistry().AbleMap[antifloodModule.moduleName] = true
 
-	dispatcher.AddHandler(handlers.NewCommand("setflood", antifloodModule.setFlood))
-	dispatcher.AddHandler(handlers.NewCommand("setfloodmode", antifloodModule.setFloodMode))
-	dispatcher.AddHandler(handlers.NewCommand("delflood", antifloodModule.setFloodDeleter))
-	dispatcher.AddHandler(handlers.NewCommand("flood", antifloodModule.flood))
+	helpers.AddCommand(dispatcher, "setflood", antifloodModule.setFlood)
+	helpers.AddCommand(dispatcher, "setfloodmode", antifloodModule.setFloodMode)
+	helpers.AddCommand(dispatcher, "delflood", antifloodModule.setFloodDeleter)
+	helpers.AddCommand(dispatcher, "flood", antifloodModule.flood)
 	helpers.AddCmdToDisableable("flood")
 	dispatcher.AddHandlerToGroup(handlers.NewMessage(message.All, antifloodModule.checkFlood), antifloodModule.handlerGroup)
 }
@@ -111,7 +111,7 @@ func TestAntifloodUpdateFloodTracksLimitAndResetsAfterPunishment(t *testing.T) {
 		t.Fatalf("SetFlood() error = %v", err)
 	}
 
-	flooded, state, settings := antifloodModule.updateFlood(chatID, 42, 100)
+	flooded, state, settings := antifloodModule.updateFlood(chatID, 0, 42, 100)
 	if flooded {
 		t.Fatal("first message flooded = true, want false")
 	}
@@ -122,7 +122,7 @@ func TestAntifloodUpdateFloodTracksLimitAndResetsAfterPunishment(t *testing.T) {
 		t.Fatalf("settings limit = %d, want 2", settings.Limit)
 	}
 
-	flooded, state, _ = antifloodModule.updateFlood(chatID, 42, 101)
+	flooded, state, _ = antifloodModule.updateFlood(chatID, 0, 42, 101)
 	if flooded {
 		t.Fatal("second message flooded = true, want false at limit")
 	}
@@ -130,7 +130,7 @@ func TestAntifloodUpdateFloodTracksLimitAndResetsAfterPunishment(t *testing.T) {
 		t.Fatalf("second message count = %d, want 2", state.messageCount)
 	}
 
-	flooded, _, _ = antifloodModule.updateFlood(chatID, 42, 102)
+	flooded, _, _ = antifloodModule.updateFlood(chatID, 0, 42, 102)
 	if !flooded {
 		t.Fatal("third message flooded = false, want true over limit")
 	}
@@ -248,8 +248,11 @@ func TestAntifloodWatcherBulkDeletesTrackedMessages(t *testing.T) {
 		}
 	}
 
-	if calls := client.callsFor("deleteMessage"); len(calls) != 5 {
-		t.Fatalf("deleteMessage calls = %d, want all tracked flood messages deleted", len(calls))
+	if ids := client.deletedMessageIDs(); len(ids) != 5 {
+		t.Fatalf("deleted messages = %v, want all tracked flood messages deleted", ids)
+	}
+	if calls := client.callsFor("deleteMessages"); len(calls) != 1 {
+		t.Fatalf("deleteMessages calls = %d, want the flood messages deleted in one batch", len(calls))
 	}
 	if calls := client.callsFor("banChatMember"); len(calls) != 1 {
 		t.Fatalf("banChatMember calls = %d, want ban action", len(calls))
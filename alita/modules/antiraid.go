@@ -21,7 +21,9 @@ import (
 	log "github.com/sirupsen/logrus"
 
 	"github.com/divkix/Alita_Robot/alita/db/antiraid"
+	"github.com/divkix/Alita_Robot/alita/db/approvals"
 	"github.com/divkix/Alita_Robot/alita/db/lang"
+	"github.com/divkix/Alita_Robot/alita/db/models"
 	"github.com/divkix/Alita_Robot/alita/i18n"
 	"github.com/divkix/Alita_Robot/alita/utils/cache"
 	"github.com/divkix/Alita_Robot/alita/utils/chat_status"
@@ -51,7 +53,7 @@ var (
 
 	deleteRaidStateScript = redis.NewScript(`
 		if redis.call("GET", KEYS[1]) == ARGV[1] then
-			redis.call("DEL", KEYS[1], KEYS[2])
+			redis.call("DEL", KEYS[1], KEYS[2], KEYS[3])
 			return 1
 		end
 		return 0
@@ -65,7 +67,7 @@ var (
 	`)
 	disableRaidScript = redis.NewScript(`
 		local existed = redis.call("DEL", KEYS[1])
-		redis.call("DEL", KEYS[2])
+		redis.call("DEL", KEYS[2], KEYS[3])
 		return existed
 	`)
 	trackJoinScript = redis.NewScript(`
@@ -85,6 +87,10 @@ type raidState struct {
 	Active    bool  `json:"active"`
 	StartedAt int64 `json:"started_at"` // unix seconds
 	ExpiresAt int64 `json:"expires_at"` // unix seconds
+	// Reasons lists the detections of an automatic raid, empty when an admin
+	// turned it on. Left out when empty, so states stored before it existed
+	// compare equal.
+	Reasons string `json:"reasons,omitempty" msgpack:",omitempty"`
 }
 
 // StartAntiRaidExpiryPoller starts the background expiry poller after cache is available.
@@ -152,7 +158,7 @@ func clearJoinTracking(chatID int64) {
 	}
 	ctx := cache.Context
 	rdb := cache.GetRedisClient()
-	_ = rdb.Del(ctx, joinsKey(chatID)).Err()
+	_ = rdb.Del(ctx, joinsKey(chatID), recentJoinsKey(chatID)).Err()
 }
 
 func getRaidState(chatID int64) *raidState {
@@ -203,7 +209,7 @@ func deleteRaidStateIfUnchanged(chatID int64, expected *raidState) (bool, error)
 		deleted, err := deleteRaidStateScript.Run(
 			cache.Context,
 			rdb,
-			[]string{stateKey(chatID), joinsKey(chatID)},
+			[]string{stateKey(chatID), joinsKey(chatID), recentJoinsKey(chatID)},
 			expectedJSON,
 		).Int()
 		return deleted == 1, err
@@ -325,6 +331,12 @@ func (a *antiRaidStruct) isRaidActive(chatID int64) bool {
 }
 
 func (a *antiRaidStruct) enableRaid(chatID int64, durationSeconds int) (bool, error) {
+	return a.startRaid(chatID, durationSeconds, "")
+}
+
+// startRaid declares a raid unless one is active, recording the detections
+// behind it (see encodeRaidReasons).
+func (a *antiRaidStruct) startRaid(chatID int64, durationSeconds int, reasons string) (bool, error) {
 	if durationSeconds <= 0 || durationSeconds > maxAntiRaidDuration {
 		return false, fmt.Errorf("duration must be between 1 second and 366 days")
 	}
@@ -333,6 +345,7 @@ func (a *antiRaidStruct) enableRaid(chatID int64, durationSeconds int) (bool, er
 		Active:    true,
 		StartedAt: now,
 		ExpiresAt: now + int64(durationSeconds),
+		Reasons:   reasons,
 	}
 	if rdb := cache.GetRedisClient(); rdb != nil {
 		current := getRaidState(chatID)
@@ -372,7 +385,7 @@ func (a *antiRaidStruct) disableRaid(chatID int64) (bool, error) {
 		deleted, err := disableRaidScript.Run(
 			cache.Context,
 			rdb,
-			[]string{stateKey(chatID), joinsKey(chatID)},
+			[]string{stateKey(chatID), joinsKey(chatID), recentJoinsKey(chatID)},
 		).Int()
 		if err != nil {
 			log.WithError(err).Warnf("[AntiRaid] Failed to disable raid for chat %d", chatID)
@@ -467,7 +480,7 @@ func (a *antiRaidStruct) onJoin(bot *gotgbot.Bot, ctx *ext.Context) error {
 		if member.Id == bot.Id {
 			continue
 		}
-		if chat_status.IsApproved(bot, chat.Id, member.Id) {
+		if chat_status.IsApprovedFor(bot, chat.Id, member.Id, approvals.ScopeAntiraid) {
 			continue
 		}
 		if chat_status.IsUserAdmin(bot, chat.Id, member.Id) {
@@ -488,33 +501,54 @@ func (a *antiRaidStruct) onJoin(bot *gotgbot.Bot, ctx *ext.Context) error {
 			log.WithError(err).Warnf("[AntiRaid] Failed to track join for chat %d", chat.Id)
 			continue
 		}
+		if err := recordRecentJoin(chat.Id, member.Id, newcomerTraits(bot, &member, settings)); err != nil {
+			log.WithError(err).Warnf("[AntiRaid] Failed to record join for chat %d", chat.Id)
+		}
 
-		if count >= settings.AutoAntiRaidThreshold {
-			enabled, err := a.enableRaid(chat.Id, settings.RaidTime)
-			if err != nil {
-				log.WithError(err).Warnf("[AntiRaid] Failed to auto-enable raid in chat %d", chat.Id)
-				continue
-			}
+		if a.autoTriggerRaid(bot, ctx, chat, settings, member.Id, detectRaid(chat.Id, settings, count)) {
 			isActive = true
-			if !enabled {
-				// Another update crossed the threshold first. Apply the active
-				// raid without resetting its expiry or sending a duplicate alert.
-				banRaidMember(bot, chat, member.Id, settings.RaidActionTime)
-				continue
-			}
-			log.Infof("[AntiRaid] Auto-triggered raid in chat %d (joins=%d >= threshold=%d)", chat.Id, count, settings.AutoAntiRaidThreshold)
-
-			tr := i18n.MustNewTranslator(lang.GetLanguage(ctx))
-			text, _ := tr.GetString("antiraid_auto_triggered", i18n.TranslationParams{"count": strconv.Itoa(count)})
-			_, _ = chat.SendMessage(bot, text, formatting.Shtml())
-
-			banRaidMember(bot, chat, member.Id, settings.RaidActionTime)
 		}
 	}
 
 	return ext.ContinueGroups
 }
 
+// autoTriggerRaid declares a raid when the detections reach the chat's auto
+// antiraid threshold, announcing why and banning the member who tipped the
+// score. It reports whether a raid is now active.
+func (a *antiRaidStruct) autoTriggerRaid(bot *gotgbot.Bot, ctx *ext.Context, chat *gotgbot.Chat, settings *models.AntiRaidSettings, userID int64, detections []raidDetection) bool {
+	score := raidScore(detections)
+	if settings.AutoAntiRaidThreshold <= 0 || score < settings.AutoAntiRaidThreshold {
+		return false
+	}
+
+	reasons := encodeRaidReasons(detections)
+	enabled, err := a.startRaid(chat.Id, settings.RaidTime, reasons)
+	if err != nil {
+		log.WithError(err).Warnf("[AntiRaid] Failed to auto-enable raid in chat %d", chat.Id)
+		return false
+	}
+	if !enabled {
+		// Another update crossed the threshold first. Apply the active
+		// raid without resetting its expiry or sending a duplicate alert.
+		banRaidMember(bot, chat, userID, settings.RaidActionTime)
+		return true
+	}
+	log.Infof("[AntiRaid] Auto-triggered raid in chat %d (score=%d >= threshold=%d, %s)", chat.Id, score, settings.AutoAntiRaidThreshold, reasons)
+
+	tr := i18n.MustNewTranslator(lang.GetLanguage(ctx))
+	var text string
+	if len(detections) == 1 && detections[0].detector == raidDetectorJoins {
+		text, _ = tr.GetString("antiraid_auto_triggered", i18n.TranslationParams{"count": strconv.Itoa(detections[0].hits)})
+	} else {
+		text, _ = tr.GetString("antiraid_auto_triggered_detectors", i18n.TranslationParams{"reasons": raidReasonsText(tr, reasons)})
+	}
+	_, _ = chat.SendMessage(bot, text, formatting.Shtml())
+
+	banRaidMember(bot, chat, userID, settings.RaidActionTime)
+	return true
+}
+
 func (a *antiRaidStruct) antiraid(bot *gotgbot.Bot, ctx *ext.Context) error {
 	chat := ctx.EffectiveChat
 	msg := ctx.EffectiveMessage
@@ -618,6 +652,12 @@ func (a *antiRaidStruct) antiraid(bot *gotgbot.Bot, ctx *ext.Context) error {
 		text, _ := tr.GetString("antiraid_disabled")
 		_, _ = msg.Reply(bot, text, formatting.Shtml())
 
+	case "status":
+		_, _ = msg.Reply(bot, a.raidStatus(tr, chat.Id), formatting.Shtml())
+
+	case "weight":
+		a.setDetectorWeight(bot, msg, tr, chat.Id, args[1:])
+
 	default:
 		dur, ok := parseDuration(arg)
 		if !ok {
@@ -930,10 +970,10 @@ func formatDuration(seconds int) string {
 func LoadAntiRaid(dispatcher *ext.Dispatcher) {
 	DefaultHelpRegistry().AbleMap[antiRaidModule.moduleName] = true
 
-	dispatcher.AddHandler(handlers.NewCommand("antiraid", antiRaidModule.antiraid))
-	dispatcher.AddHandler(handlers.NewCommand("raidtime", antiRaidModule.raidTime))
-	dispatcher.AddHandler(handlers.NewCommand("raidactiontime", antiRaidModule.raidActionTime))
-	dispatcher.AddHandler(handlers.NewCommand("autoantiraid", antiRaidModule.autoAntiRaid))
+	helpers.AddCommand(dispatcher, "antiraid", antiRaidModule.antiraid)
+	helpers.AddCommand(dispatcher, "raidtime", antiRaidModule.raidTime)
+	helpers.AddCommand(dispatcher, "raidactiontime", antiRaidModule.raidActionTime)
+	helpers.AddCommand(dispatcher, "autoantiraid", antiRaidModule.autoAntiRaid)
 
 	dispatcher.AddHandlerToGroup(
 		handlers.NewMessage(
@@ -945,6 +985,26 @@ func LoadAntiRaid(dispatcher *ext.Dispatcher) {
 		antiRaidModule.handlerGroup,
 	)
 
+	dispatcher.AddHandlerToGroup(
+		handlers.NewMessage(
+			func(msg *gotgbot.Message) bool {
+				return msg.NewChatMembers == nil && msg.LeftChatMember == nil
+			},
+			antiRaidModule.onFirstMessage,
+		),
+		antiRaidModule.handlerGroup,
+	)
+	dispatcher.AddHandlerToGroup(
+		handlers.NewChatMember(
+			func(u *gotgbot.ChatMemberUpdated) bool {
+				wasMember, isMember := chat_status.ExtractJoinLeftStatusChange(u)
+				return !wasMember && isMember && u.InviteLink != nil
+			},
+			antiRaidModule.onInviteJoin,
+		),
+		antiRaidModule.handlerGroup,
+	)
+
 	dispatcher.AddHandler(handlers.NewCallback(callbackquery.Prefix("antiraid"), antiRaidModule.callbackHandler))
 
 	helpers.AddCmdToDisableable("antiraid")
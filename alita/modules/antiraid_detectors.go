@@ -0,0 +1,536 @@
+package modules
+
+import (
+	"fmt"
+	"hash/fnv"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/PaulSonOfLars/gotgbot/v2"
+	"github.com/PaulSonOfLars/gotgbot/v2/ext"
+	"github.com/redis/go-redis/v9"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/divkix/Alita_Robot/alita/db/antiraid"
+	"github.com/divkix/Alita_Robot/alita/db/approvals"
+	"github.com/divkix/Alita_Robot/alita/db/models"
+	"github.com/divkix/Alita_Robot/alita/i18n"
+	"github.com/divkix/Alita_Robot/alita/utils/cache"
+	"github.com/divkix/Alita_Robot/alita/utils/chat_status"
+	"github.com/divkix/Alita_Robot/alita/utils/formatting"
+)
+
+const (
+	// antiraidDetectorWindowSeconds is how far back the raid detectors look
+	// for joins that are alike.
+	antiraidDetectorWindowSeconds = 10 * 60
+	antiraidRecentKey             = "alita:antiraid:recent" // format: recent:chat_id (hash of <user_id>:<trait>)
+	// raidRecentJoinsMax caps the joins the detectors compare, newest first.
+	raidRecentJoinsMax = 100
+	// raidClusterMin is the smallest group of alike joins a detector counts.
+	raidClusterMin = 3
+	// raidNameMaxRunes and raidMessageMinRunes keep name comparisons cheap
+	// and short greetings like "hi" out of the messages detector.
+	raidNameMaxRunes    = 32
+	raidMessageMinRunes = 8
+	// raidDetectorJoins is the join count, which always adds one point per
+	// join in the last minute.
+	raidDetectorJoins = "joins"
+)
+
+// recentJoin is what the raid detectors know about a member who joined
+// recently.
+type recentJoin struct {
+	userID  int64
+	at      int64  // unix seconds
+	name    string // letters only, lower case
+	bare    bool   // no username and no profile photo
+	invite  string // the invite link they joined through
+	message string // hash of their first message
+}
+
+// raidDetector finds one kind of likeness among recent joins, returning how
+// many joins share it.
+type raidDetector struct {
+	name string
+	hits func(joins []recentJoin) int
+}
+
+// raidDetectors are the weighted detectors, in the order /antiraid status
+// shows them.
+var raidDetectors = []raidDetector{
+	{name: antiraid.DetectorNames, hits: similarNameHits},
+	{name: antiraid.DetectorNoProfile, hits: bareProfileHits},
+	{name: antiraid.DetectorMessages, hits: func(joins []recentJoin) int {
+		return largestGroup(joins, func(j recentJoin) string { return j.message })
+	}},
+	{name: antiraid.DetectorInvite, hits: func(joins []recentJoin) int {
+		return largestGroup(joins, func(j recentJoin) string { return j.invite })
+	}},
+}
+
+// raidDetection is what one detector found, and the points it adds to the
+// raid score.
+type raidDetection struct {
+	detector string
+	hits     int
+	points   int
+}
+
+// recordFirstMessageScript stores the first message of a member only while
+// their join is remembered.
+var recordFirstMessageScript = redis.NewScript(`
+	if redis.call('HEXISTS', KEYS[1], ARGV[1] .. ':at') == 0 then
+		return 0
+	end
+	return redis.call('HSETNX', KEYS[1], ARGV[1] .. ':msg', ARGV[2])
+`)
+
+func recentJoinsKey(chatID int64) string {
+	return fmt.Sprintf("%s:%d", antiraidRecentKey, chatID)
+}
+
+// newcomerTraits returns what the detectors compare about a joining member.
+// Profile photos are only looked up when the noprofile detector is on.
+func newcomerTraits(bot *gotgbot.Bot, user *gotgbot.User, settings *models.AntiRaidSettings) map[string]string {
+	traits := map[string]string{"name": normalizeRaidName(user.FirstName + user.LastName)}
+	if user.Username == "" && settings.ProfileWeight > 0 && !hasProfilePhoto(bot, user.Id) {
+		traits["bare"] = "1"
+	}
+	return traits
+}
+
+func hasProfilePhoto(bot *gotgbot.Bot, userID int64) bool {
+	photos, err := bot.GetUserProfilePhotos(userID, &gotgbot.GetUserProfilePhotosOpts{Limit: 1})
+	if err != nil {
+		// Unknown is not evidence of a raid.
+		return true
+	}
+	return photos.TotalCount > 0
+}
+
+// recordRecentJoin remembers traits of a member who just joined for the
+// detector window.
+func recordRecentJoin(chatID, userID int64, traits map[string]string) error {
+	rdb := cache.GetRedisClient()
+	if rdb == nil {
+		return fmt.Errorf("cache not initialized")
+	}
+	values := []any{fmt.Sprintf("%d:at", userID), time.Now().Unix()}
+	for trait, value := range traits {
+		values = append(values, fmt.Sprintf("%d:%s", userID, trait), value)
+	}
+	key := recentJoinsKey(chatID)
+	pipe := rdb.TxPipeline()
+	pipe.HSet(cache.Context, key, values...)
+	pipe.Expire(cache.Context, key, antiraidDetectorWindowSeconds*time.Second)
+	_, err := pipe.Exec(cache.Context)
+	return err
+}
+
+// recordFirstMessage remembers the first message of a recent joiner,
+// reporting whether it was their first.
+func recordFirstMessage(chatID, userID int64, hash string) (bool, error) {
+	rdb := cache.GetRedisClient()
+	if rdb == nil {
+		return false, fmt.Errorf("cache not initialized")
+	}
+	stored, err := recordFirstMessageScript.Run(cache.Context, rdb, []string{recentJoinsKey(chatID)}, userID, hash).Int()
+	return stored == 1, err
+}
+
+// getRecentJoins returns the joins of the detector window, newest first,
+// forgetting older ones.
+func getRecentJoins(chatID int64) []recentJoin {
+	rdb := cache.GetRedisClient()
+	if rdb == nil {
+		return nil
+	}
+	key := recentJoinsKey(chatID)
+	fields, err := rdb.HGetAll(cache.Context, key).Result()
+	if err != nil {
+		log.WithError(err).Warnf("[AntiRaid] Failed to read recent joins for chat %d", chatID)
+		return nil
+	}
+
+	byUser := map[int64]*recentJoin{}
+	for field, value := range fields {
+		idText, trait, ok := strings.Cut(field, ":")
+		userID, err := strconv.ParseInt(idText, 10, 64)
+		if !ok || err != nil {
+			continue
+		}
+		join := byUser[userID]
+		if join == nil {
+			join = &recentJoin{userID: userID}
+			byUser[userID] = join
+		}
+		switch trait {
+		case "at":
+			join.at, _ = strconv.ParseInt(value, 10, 64)
+		case "name":
+			join.name = value
+		case "bare":
+			join.bare = value == "1"
+		case "invite":
+			join.invite = value
+		case "msg":
+			join.message = value
+		}
+	}
+
+	cutoff := time.Now().Unix() - antiraidDetectorWindowSeconds
+	joins := make([]recentJoin, 0, len(byUser))
+	var stale []string
+	for _, join := range byUser {
+		if join.at < cutoff {
+			for _, trait := range []string{"at", "name", "bare", "invite", "msg"} {
+				stale = append(stale, fmt.Sprintf("%d:%s", join.userID, trait))
+			}
+			continue
+		}
+		joins = append(joins, *join)
+	}
+	if len(stale) > 0 {
+		_ = rdb.HDel(cache.Context, key, stale...).Err()
+	}
+
+	sort.Slice(joins, func(i, j int) bool { return joins[i].at > joins[j].at })
+	if len(joins) > raidRecentJoinsMax {
+		joins = joins[:raidRecentJoinsMax]
+	}
+	return joins
+}
+
+// countRecentJoins returns the joins of the last minute without counting a
+// new one.
+func countRecentJoins(chatID int64) int {
+	rdb := cache.GetRedisClient()
+	if rdb == nil {
+		return 0
+	}
+	cutoff := time.Now().Unix() - antiraidJoinWindowSeconds
+	count, err := rdb.ZCount(cache.Context, joinsKey(chatID), strconv.FormatInt(cutoff, 10), "+inf").Result()
+	if err != nil {
+		return 0
+	}
+	return int(count)
+}
+
+// normalizeRaidName keeps the letters of a name, lower case, so that
+// "J0hn_Smith" and "john smith" compare alike.
+func normalizeRaidName(name string) string {
+	var b strings.Builder
+	n := 0
+	for _, r := range name {
+		if !unicode.IsLetter(r) {
+			continue
+		}
+		b.WriteRune(unicode.ToLower(r))
+		if n++; n == raidNameMaxRunes {
+			break
+		}
+	}
+	return b.String()
+}
+
+// hashRaidMessage returns a short hash of a message, ignoring case and
+// spacing, or "" for messages too short to tell raiders apart.
+func hashRaidMessage(text string) string {
+	text = strings.Join(strings.Fields(strings.ToLower(text)), " ")
+	if len([]rune(text)) < raidMessageMinRunes {
+		return ""
+	}
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(text))
+	return strconv.FormatUint(h.Sum64(), 36)
+}
+
+// similarNames reports whether two normalized names are equal or differ in
+// at most a quarter of their letters.
+func similarNames(a, b []rune) bool {
+	allowed := max(len(a), len(b)) / 4
+	if max(len(a), len(b))-min(len(a), len(b)) > allowed {
+		return false
+	}
+	return editDistance(a, b) <= allowed
+}
+
+// editDistance returns the Levenshtein distance between two names.
+func editDistance(a, b []rune) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
+
+// similarNameHits returns the size of the largest group of joins with names
+// similar to one of them.
+func similarNameHits(joins []recentJoin) int {
+	var names [][]rune
+	for _, join := range joins {
+		if join.name != "" {
+			names = append(names, []rune(join.name))
+		}
+	}
+	best := 0
+	for i := range names {
+		n := 0
+		for j := range names {
+			if similarNames(names[i], names[j]) {
+				n++
+			}
+		}
+		best = max(best, n)
+	}
+	if best < raidClusterMin {
+		return 0
+	}
+	return best
+}
+
+// bareProfileHits returns how many joins have no username and no photo.
+func bareProfileHits(joins []recentJoin) int {
+	n := 0
+	for _, join := range joins {
+		if join.bare {
+			n++
+		}
+	}
+	if n < raidClusterMin {
+		return 0
+	}
+	return n
+}
+
+// largestGroup returns the size of the largest group of joins sharing a
+// non-empty key.
+func largestGroup(joins []recentJoin, key func(recentJoin) string) int {
+	counts := map[string]int{}
+	best := 0
+	for _, join := range joins {
+		if k := key(join); k != "" {
+			counts[k]++
+			best = max(best, counts[k])
+		}
+	}
+	if best < raidClusterMin {
+		return 0
+	}
+	return best
+}
+
+// detectRaid runs the detectors a chat weighs over its recent joins. The
+// join count comes first and always weighs 1.
+func detectRaid(chatID int64, settings *models.AntiRaidSettings, joins int) []raidDetection {
+	var detections []raidDetection
+	if joins > 0 {
+		detections = append(detections, raidDetection{detector: raidDetectorJoins, hits: joins, points: joins})
+	}
+	var recent []recentJoin
+	loaded := false
+	for _, d := range raidDetectors {
+		weight := settings.DetectorWeight(d.name)
+		if weight <= 0 {
+			continue
+		}
+		if !loaded {
+			recent, loaded = getRecentJoins(chatID), true
+		}
+		if hits := d.hits(recent); hits > 0 {
+			detections = append(detections, raidDetection{detector: d.name, hits: hits, points: hits * weight})
+		}
+	}
+	return detections
+}
+
+func raidScore(detections []raidDetection) int {
+	score := 0
+	for _, d := range detections {
+		score += d.points
+	}
+	return score
+}
+
+// encodeRaidReasons stores detections as "detector=hits" pairs, like
+// "joins=4,names=3", in the raid state.
+func encodeRaidReasons(detections []raidDetection) string {
+	parts := make([]string, 0, len(detections))
+	for _, d := range detections {
+		parts = append(parts, fmt.Sprintf("%s=%d", d.detector, d.hits))
+	}
+	return strings.Join(parts, ",")
+}
+
+// raidReasonsText renders stored reasons, one line per detection.
+func raidReasonsText(tr *i18n.Translator, reasons string) string {
+	var lines []string
+	for part := range strings.SplitSeq(reasons, ",") {
+		detector, hits, ok := strings.Cut(part, "=")
+		if !ok {
+			continue
+		}
+		line, _ := tr.GetString("antiraid_reason_"+detector, i18n.TranslationParams{"count": hits})
+		lines = append(lines, line)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// exemptFromRaid reports whether a joining member is left alone: the bot
+// itself, members approved for antiraid and admins.
+func exemptFromRaid(bot *gotgbot.Bot, chatID, userID int64) bool {
+	return userID == bot.Id ||
+		chat_status.IsApprovedFor(bot, chatID, userID, approvals.ScopeAntiraid) ||
+		chat_status.IsUserAdmin(bot, chatID, userID)
+}
+
+// onInviteJoin remembers the invite link a member joined through, which only
+// chat_member updates carry.
+func (a *antiRaidStruct) onInviteJoin(bot *gotgbot.Bot, ctx *ext.Context) error {
+	update := ctx.ChatMember
+	chat := update.Chat
+	if chat.Type != "group" && chat.Type != "supergroup" {
+		return ext.ContinueGroups
+	}
+	settings := antiraid.GetAntiRaidSettings(chat.Id)
+	if settings.AutoAntiRaidThreshold <= 0 || settings.InviteWeight <= 0 || a.isRaidActive(chat.Id) {
+		return ext.ContinueGroups
+	}
+	user := update.NewChatMember.MergeChatMember().User
+	if !chat_status.IsBotAdmin(bot, ctx, &chat) || !chat_status.CanBotRestrict(bot, ctx, &chat) || exemptFromRaid(bot, chat.Id, user.Id) {
+		return ext.ContinueGroups
+	}
+
+	if err := recordRecentJoin(chat.Id, user.Id, map[string]string{"invite": update.InviteLink.InviteLink}); err != nil {
+		log.WithError(err).Warnf("[AntiRaid] Failed to record invite join for chat %d", chat.Id)
+		return ext.ContinueGroups
+	}
+	a.autoTriggerRaid(bot, ctx, &chat, settings, user.Id, detectRaid(chat.Id, settings, countRecentJoins(chat.Id)))
+	return ext.ContinueGroups
+}
+
+// onFirstMessage remembers the first message of recent joiners for the
+// messages detector.
+func (a *antiRaidStruct) onFirstMessage(bot *gotgbot.Bot, ctx *ext.Context) error {
+	chat := ctx.EffectiveChat
+	msg := ctx.EffectiveMessage
+	if chat == nil || msg == nil || (chat.Type != "group" && chat.Type != "supergroup") || msg.From == nil || msg.SenderChat != nil || isAliasRedispatch(ctx) {
+		return ext.ContinueGroups
+	}
+	hash := hashRaidMessage(msg.Text + msg.Caption)
+	if hash == "" {
+		return ext.ContinueGroups
+	}
+	settings := antiraid.GetAntiRaidSettings(chat.Id)
+	if settings.AutoAntiRaidThreshold <= 0 || settings.MessageWeight <= 0 {
+		return ext.ContinueGroups
+	}
+
+	first, err := recordFirstMessage(chat.Id, msg.From.Id, hash)
+	if err != nil || !first || a.isRaidActive(chat.Id) {
+		return ext.ContinueGroups
+	}
+	if !chat_status.IsBotAdmin(bot, ctx, chat) || !chat_status.CanBotRestrict(bot, ctx, chat) {
+		return ext.ContinueGroups
+	}
+	a.autoTriggerRaid(bot, ctx, chat, settings, msg.From.Id, detectRaid(chat.Id, settings, countRecentJoins(chat.Id)))
+	return ext.ContinueGroups
+}
+
+// raidStatus explains what auto antiraid weighs, what the detectors see now
+// and why a raid is active.
+func (a *antiRaidStruct) raidStatus(tr *i18n.Translator, chatID int64) string {
+	settings := antiraid.GetAntiRaidSettings(chatID)
+	var lines []string
+	if settings.AutoAntiRaidThreshold > 0 {
+		line, _ := tr.GetString("antiraid_status_threshold", i18n.TranslationParams{"threshold": strconv.Itoa(settings.AutoAntiRaidThreshold)})
+		lines = append(lines, line)
+	} else {
+		lines = append(lines, trS(tr, "antiraid_status_auto_off"))
+	}
+
+	hits := map[string]int{}
+	for _, d := range detectRaid(chatID, settings, countRecentJoins(chatID)) {
+		hits[d.detector] = d.hits
+	}
+	lines = append(lines, "", trS(tr, "antiraid_status_detectors"))
+	line, _ := tr.GetString("antiraid_status_joins", i18n.TranslationParams{"count": strconv.Itoa(hits[raidDetectorJoins])})
+	lines = append(lines, line)
+	for _, d := range raidDetectors {
+		params := i18n.TranslationParams{
+			"detector": d.name,
+			"weight":   strconv.Itoa(settings.DetectorWeight(d.name)),
+			"hits":     strconv.Itoa(hits[d.name]),
+		}
+		key := "antiraid_status_detector"
+		if settings.DetectorWeight(d.name) <= 0 {
+			key = "antiraid_status_detector_off"
+		}
+		line, _ := tr.GetString(key, params)
+		lines = append(lines, line)
+	}
+
+	lines = append(lines, "")
+	st := getRaidState(chatID)
+	switch {
+	case !a.isRaidActive(chatID):
+		lines = append(lines, trS(tr, "antiraid_status_inactive"))
+	case st.Reasons == "":
+		line, _ := tr.GetString("antiraid_status_active_manual", i18n.TranslationParams{"expires_in": formatDuration(int(st.ExpiresAt - time.Now().Unix()))})
+		lines = append(lines, line)
+	default:
+		line, _ := tr.GetString("antiraid_status_active", i18n.TranslationParams{
+			"expires_in": formatDuration(int(st.ExpiresAt - time.Now().Unix())),
+			"reasons":    raidReasonsText(tr, st.Reasons),
+		})
+		lines = append(lines, line)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// setDetectorWeight handles /antiraid weight <detector> <0-10>.
+func (a *antiRaidStruct) setDetectorWeight(bot *gotgbot.Bot, msg *gotgbot.Message, tr *i18n.Translator, chatID int64, args []string) {
+	reply := func(key string, params i18n.TranslationParams) {
+		text, _ := tr.GetString(key, params)
+		_, _ = msg.Reply(bot, text, formatting.Shtml())
+	}
+	usage := i18n.TranslationParams{"max": strconv.Itoa(antiraid.MaxDetectorWeight)}
+	if len(args) != 2 {
+		reply("antiraid_weight_usage", usage)
+		return
+	}
+	detector := strings.ToLower(args[0])
+	weight, err := strconv.Atoi(args[1])
+	if !slices.Contains(antiraid.Detectors(), detector) || err != nil || weight < 0 || weight > antiraid.MaxDetectorWeight {
+		reply("antiraid_weight_usage", usage)
+		return
+	}
+
+	if err := antiraid.SetDetectorWeight(chatID, detector, weight); err != nil {
+		log.WithError(err).Errorf("[AntiRaid] SetDetectorWeight(%s, %d) failed for chat %d", detector, weight, chatID)
+		reply("common_settings_save_failed", nil)
+		return
+	}
+	if weight == 0 {
+		reply("antiraid_weight_off", i18n.TranslationParams{"detector": detector})
+		return
+	}
+	reply("antiraid_weight_set", i18n.TranslationParams{"detector": detector, "weight": strconv.Itoa(weight)})
+}
@@ -0,0 +1,251 @@
+package modules
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/PaulSonOfLars/gotgbot/v2"
+	"github.com/PaulSonOfLars/gotgbot/v2/ext"
+
+	"github.com/divkix/Alita_Robot/alita/db"
+	"github.com/divkix/Alita_Robot/alita/db/antiraid"
+	"github.com/divkix/Alita_Robot/alita/db/models"
+)
+
+func cleanupAntiRaidChat(t *testing.T, chatID int64) {
+	t.Cleanup(func() {
+		StopAntiRaidExpiryPoller()
+		_, _ = antiRaidModule.disableRaid(chatID)
+		_ = db.DB.Where("chat_id = ?", chatID).Delete(&models.AntiRaidSettings{}).Error
+	})
+}
+
+func TestRaidDetectorHits(t *testing.T) {
+	if got := normalizeRaidName("J0hn_Smith 🚀"); got != "jhnsmith" {
+		t.Fatalf("normalizeRaidName() = %q, want %q", got, "jhnsmith")
+	}
+	for _, tc := range []struct {
+		a, b string
+		want bool
+	}{
+		{"cryptoqueen", "cryptoqueen", true},
+		{"cryptoqueen", "cryptoqueeen", true},
+		{"cryptoqueen", "kryptoqween", true},
+		{"cryptoqueen", "gardenlover", false},
+		{"ana", "ann", false},
+	} {
+		if got := similarNames([]rune(tc.a), []rune(tc.b)); got != tc.want {
+			t.Errorf("similarNames(%q, %q) = %v, want %v", tc.a, tc.b, got, tc.want)
+		}
+	}
+
+	joins := []recentJoin{
+		{name: "cryptoqueen", bare: true, invite: "a", message: "x"},
+		{name: "cryptoqueeen", bare: true, invite: "a", message: "x"},
+		{name: "kryptoqween", invite: "b", message: "x"},
+		{name: "gardenlover", bare: true, invite: "a"},
+	}
+	if got := similarNameHits(joins); got != 3 {
+		t.Errorf("similarNameHits() = %d, want 3", got)
+	}
+	if got := bareProfileHits(joins); got != 3 {
+		t.Errorf("bareProfileHits() = %d, want 3", got)
+	}
+	if got := largestGroup(joins[1:], func(j recentJoin) string { return j.invite }); got != 0 {
+		t.Errorf("largestGroup(invite) = %d for 2 alike joins, want 0", got)
+	}
+	if got := largestGroup(joins, func(j recentJoin) string { return j.message }); got != 3 {
+		t.Errorf("largestGroup(message) = %d, want 3", got)
+	}
+
+	if hashRaidMessage("hi all") != "" {
+		t.Error("hashRaidMessage() hashed a short greeting")
+	}
+	if hashRaidMessage("Free  CRYPTO in my bio") != hashRaidMessage("free crypto in my bio") {
+		t.Error("hashRaidMessage() differs by case and spacing")
+	}
+
+	reasons := encodeRaidReasons([]raidDetection{{detector: raidDetectorJoins, hits: 4}, {detector: antiraid.DetectorNames, hits: 3}})
+	if reasons != "joins=4,names=3" {
+		t.Fatalf("encodeRaidReasons() = %q", reasons)
+	}
+}
+
+func TestSimilarNamesAndBareProfilesDeclareRaid(t *testing.T) {
+	withMiniredis(t)
+
+	client := newModuleBotClient()
+	client.responses["getUserProfilePhotos"] = json.RawMessage(`{"total_count":0,"photos":[]}`)
+	bot := newModuleTestBot(client)
+	chat := gotgbot.Chat{Id: uniqueModuleChatID(), Type: "supergroup", Title: "Raid Chat"}
+	cleanupAntiRaidChat(t, chat.Id)
+	if err := antiraid.SetAutoAntiRaidThreshold(chat.Id, 10); err != nil {
+		t.Fatalf("SetAutoAntiRaidThreshold() error = %v", err)
+	}
+	if err := antiraid.SetDetectorWeight(chat.Id, antiraid.DetectorNames, 2); err != nil {
+		t.Fatalf("SetDetectorWeight() error = %v", err)
+	}
+	if err := antiraid.SetDetectorWeight(chat.Id, antiraid.DetectorNoProfile, 1); err != nil {
+		t.Fatalf("SetDetectorWeight() error = %v", err)
+	}
+
+	join := func(id int64, name string) {
+		t.Helper()
+		member := gotgbot.User{Id: id, FirstName: name}
+		msg := &gotgbot.Message{MessageId: id, Date: 1, Chat: chat, From: &member, NewChatMembers: []gotgbot.User{member}}
+		if err := antiRaidModule.onJoin(bot, ext.NewContext(bot, &gotgbot.Update{UpdateId: id, Message: msg}, nil)); err != ext.ContinueGroups {
+			t.Fatalf("onJoin() error = %v, want ContinueGroups", err)
+		}
+	}
+
+	join(4301, "Crypto Queen")
+	join(4302, "Garden Lover")
+	join(4303, "Crypto Queeen")
+	if antiRaidModule.isRaidActive(chat.Id) {
+		t.Fatal("raid declared below the threshold")
+	}
+	// 4 joins, 3 similar names weighing 2 and 4 bare profiles weighing 1.
+	join(4304, "Kryptoqueen")
+	if !antiRaidModule.isRaidActive(chat.Id) {
+		t.Fatal("raid not declared at a score of 14")
+	}
+	if st := getRaidState(chat.Id); st.Reasons != "joins=4,names=3,noprofile=4" {
+		t.Fatalf("raid reasons = %q", st.Reasons)
+	}
+	bans := client.callsFor("banChatMember")
+	if len(bans) != 1 || fmt.Sprint(bans[0].Params["user_id"]) != "4304" {
+		t.Fatalf("banChatMember calls = %+v, want the member who tipped the score", bans)
+	}
+	if sent := client.callsFor("sendMessage"); len(sent) != 1 {
+		t.Fatalf("sendMessage calls = %d, want the raid announced once", len(sent))
+	}
+
+	join(4305, "Crypto Queen")
+	if bans := client.callsFor("banChatMember"); len(bans) != 2 {
+		t.Fatalf("banChatMember calls = %d, want later joins banned by the raid", len(bans))
+	}
+	if sent := client.callsFor("sendMessage"); len(sent) != 1 {
+		t.Fatalf("sendMessage calls = %d, want no second announcement", len(sent))
+	}
+
+	statusCtx := newModuleMessageContext(bot, chat, gotgbot.User{Id: 777000, FirstName: "Telegram"}, "/antiraid status")
+	if err := antiRaidModule.antiraid(bot, statusCtx); err != ext.EndGroups {
+		t.Fatalf("antiraid(status) error = %v, want EndGroups", err)
+	}
+	if sent := client.callsFor("sendMessage"); len(sent) != 2 {
+		t.Fatalf("sendMessage calls = %d, want the status sent", len(sent))
+	}
+}
+
+func TestFirstMessagesAndInviteLinksFeedDetectors(t *testing.T) {
+	withMiniredis(t)
+
+	client := newModuleBotClient()
+	bot := newModuleTestBot(client)
+	dispatcher := ext.NewDispatcher(&ext.DispatcherOpts{MaxRoutines: -1})
+	LoadAntiRaid(dispatcher)
+
+	chat := gotgbot.Chat{Id: uniqueModuleChatID(), Type: "supergroup", Title: "Raid Chat"}
+	cleanupAntiRaidChat(t, chat.Id)
+	if err := antiraid.SetAutoAntiRaidThreshold(chat.Id, 8); err != nil {
+		t.Fatalf("SetAutoAntiRaidThreshold() error = %v", err)
+	}
+	if err := antiraid.SetDetectorWeight(chat.Id, antiraid.DetectorMessages, 2); err != nil {
+		t.Fatalf("SetDetectorWeight() error = %v", err)
+	}
+	if err := antiraid.SetDetectorWeight(chat.Id, antiraid.DetectorInvite, 1); err != nil {
+		t.Fatalf("SetDetectorWeight() error = %v", err)
+	}
+
+	updateID := int64(0)
+	process := func(update *gotgbot.Update) {
+		t.Helper()
+		updateID++
+		update.UpdateId = updateID
+		if err := dispatcher.ProcessUpdate(bot, update, nil); err != nil {
+			t.Fatalf("ProcessUpdate() error = %v", err)
+		}
+	}
+	joinByLink := func(member gotgbot.User) {
+		process(&gotgbot.Update{ChatMember: &gotgbot.ChatMemberUpdated{
+			Chat: chat, From: member, Date: 1,
+			OldChatMember: gotgbot.ChatMemberLeft{User: member},
+			NewChatMember: gotgbot.ChatMemberMember{User: member},
+			InviteLink:    &gotgbot.ChatInviteLink{InviteLink: "https://t.me/+promo"},
+		}})
+		process(&gotgbot.Update{Message: &gotgbot.Message{
+			MessageId: 500 + updateID, Date: 1, Chat: chat, From: &member, NewChatMembers: []gotgbot.User{member},
+		}})
+	}
+	say := func(member gotgbot.User, text string) {
+		process(&gotgbot.Update{Message: &gotgbot.Message{MessageId: 500 + updateID, Date: 1, Chat: chat, From: &member, Text: text}})
+	}
+
+	members := []gotgbot.User{{Id: 4401, FirstName: "Ann"}, {Id: 4402, FirstName: "Bob"}, {Id: 4403, FirstName: "Cat"}}
+	for _, member := range members {
+		joinByLink(member)
+	}
+	// 3 joins and 3 through the same link weighing 1.
+	if antiRaidModule.isRaidActive(chat.Id) {
+		t.Fatal("raid declared at a score of 6")
+	}
+
+	say(members[0], "Join my channel for free signals")
+	say(members[1], "join my channel  for FREE signals")
+	say(members[0], "Join my channel for free signals")
+	say(gotgbot.User{Id: 42, FirstName: "Member"}, "Join my channel for free signals")
+	if antiRaidModule.isRaidActive(chat.Id) {
+		t.Fatal("raid declared before 3 joins sent the same first message")
+	}
+	say(members[2], "Join my channel for free signals")
+	if !antiRaidModule.isRaidActive(chat.Id) {
+		t.Fatal("raid not declared at a score of 12")
+	}
+	if st := getRaidState(chat.Id); st.Reasons != "joins=3,messages=3,invite=3" {
+		t.Fatalf("raid reasons = %q", st.Reasons)
+	}
+	if bans := client.callsFor("banChatMember"); len(bans) != 1 || fmt.Sprint(bans[0].Params["user_id"]) != "4403" {
+		t.Fatalf("banChatMember calls = %+v, want the third sender banned", bans)
+	}
+}
+
+func TestAntiRaidWeightCommand(t *testing.T) {
+	client := newModuleBotClient()
+	bot := newModuleTestBot(client)
+	chat := gotgbot.Chat{Id: uniqueModuleChatID(), Type: "supergroup", Title: "Raid Chat"}
+	admin := gotgbot.User{Id: 777000, FirstName: "Telegram"}
+	cleanupAntiRaidChat(t, chat.Id)
+
+	run := func(text string) {
+		t.Helper()
+		if err := antiRaidModule.antiraid(bot, newModuleMessageContext(bot, chat, admin, text)); err != ext.EndGroups {
+			t.Fatalf("antiraid(%q) error = %v, want EndGroups", text, err)
+		}
+	}
+
+	run("/antiraid weight Names 4")
+	run("/antiraid weight invite 2")
+	for _, text := range []string{
+		"/antiraid weight",
+		"/antiraid weight names",
+		"/antiraid weight joins 2",
+		"/antiraid weight names 11",
+		"/antiraid weight names -1",
+		"/antiraid weight names lots",
+	} {
+		run(text)
+	}
+	settings := antiraid.GetAntiRaidSettings(chat.Id)
+	if settings.NameWeight != 4 || settings.InviteWeight != 2 {
+		t.Fatalf("settings = %+v, want names 4 and invite 2", settings)
+	}
+
+	run("/antiraid weight names 0")
+	if got := antiraid.GetAntiRaidSettings(chat.Id).NameWeight; got != 0 {
+		t.Fatalf("NameWeight = %d, want 0", got)
+	}
+	if antiRaidModule.isRaidActive(chat.Id) {
+		t.Fatal("/antiraid weight declared a raid")
+	}
+}
@@ -7,11 +7,14 @@ import (
 	"github.com/PaulSonOfLars/gotgbot/v2"
 	"github.com/PaulSonOfLars/gotgbot/v2/ext"
 	"github.com/PaulSonOfLars/gotgbot/v2/ext/handlers"
+	"github.com/PaulSonOfLars/gotgbot/v2/ext/handlers/filters/callbackquery"
 	"github.com/PaulSonOfLars/gotgbot/v2/ext/handlers/filters/message"
 	log "github.com/sirupsen/logrus"
 
+	"github.com/divkix/Alita_Robot/alita/db/approvals"
 	"github.com/divkix/Alita_Robot/alita/utils/chat_status"
 	"github.com/divkix/Alita_Robot/alita/utils/error_handling"
+	"github.com/divkix/Alita_Robot/alita/utils/helpers"
 )
 
 // spamKey is a composite key for rate limiting per user per chat
@@ -74,7 +77,9 @@ func antiSpamCleanupLoop() {
 	for range ticker.C {
 		func() {
 			defer error_handling.RecoverFromPanic("antiSpamCleanupTick", "antispam")
-			cleanupExpiredAntiSpam(time.Now())
+			now := time.Now()
+			cleanupExpiredAntiSpam(now)
+			cleanupSpamTexts(now)
 		}()
 	}
 }
@@ -100,8 +105,9 @@ func cleanupExpiredAntiSpam(now time.Time) {
 	}
 }
 
-// spamCheck performs spam detection for a specific user in a chat.
-// The eighteenth message within one second is spam.
+// spamCheck performs burst detection for a specific user in a chat.
+// The eighteenth message within one second is a burst, which the spam
+// filter takes as a hint.
 func spamCheck(key spamKey) bool {
 	shard := shardFor(key)
 	shard.mu.Lock()
@@ -126,9 +132,12 @@ func spamCheck(key spamKey) bool {
 	return info.Count >= antiSpamLimit
 }
 
-// LoadAntispam registers the antispam message handler with the dispatcher.
-// Sets up spam detection monitoring for all incoming messages.
+// LoadAntispam registers the antispam message handler and commands with the
+// dispatcher. Every message is rate checked and, in chats that enabled them,
+// looked up in the cross-chat duplicate store and scored by the spam filter.
 func LoadAntispam(dispatcher *ext.Dispatcher) {
+	DefaultHelpRegistry().AbleMap[antispamModule.moduleName] = true
+
 	dispatcher.AddHandlerToGroup(
 		handlers.NewMessage(
 			message.All,
@@ -137,8 +146,12 @@ func LoadAntispam(dispatcher *ext.Dispatcher) {
 				if ctx.EffectiveUser == nil {
 					return ext.ContinueGroups
 				}
+				// Skip rewritten aliases, the original was already rate checked
+				if isAliasRedispatch(ctx) {
+					return ext.ContinueGroups
+				}
 				// Skip approved users (immune to anti-spam)
-				if chat_status.IsApproved(bot, ctx.EffectiveChat.Id, ctx.EffectiveUser.Id) {
+				if chat_status.IsApprovedFor(bot, ctx.EffectiveChat.Id, ctx.EffectiveUser.Id, approvals.ScopeAntispam) {
 					return ext.ContinueGroups
 				}
 				// Skip admins: every other anti-abuse module exempts admins, and
@@ -153,12 +166,23 @@ func LoadAntispam(dispatcher *ext.Dispatcher) {
 					userId: ctx.EffectiveUser.Id,
 				}
 
-				if spamCheck(key) {
+				burst := spamCheck(key)
+				if burst {
 					log.Debugf("[Antispam] Rate limited user=%d chat=%d",
 						ctx.EffectiveUser.Id, ctx.EffectiveChat.Id)
 				}
-				return ext.ContinueGroups
+				if antispamModule.checkDuplicate(bot, ctx) {
+					return ext.EndGroups
+				}
+				return antispamModule.checkSpam(bot, ctx, burst)
 			},
 		), -2,
 	)
+
+	helpers.AddCommand(dispatcher, "antispam", antispamModule.antispamCommand)
+	helpers.AddCommand(dispatcher, "spam", antispamModule.markSpam)
+	helpers.AddCommand(dispatcher, "notspam", antispamModule.markNotSpam)
+	helpers.AddCommand(dispatcher, "antidupe", antispamModule.antidupeCommand)
+	helpers.AddCommand(dispatcher, "spamtrust", antispamModule.spamTrust)
+	dispatcher.AddHandler(handlers.NewCallback(callbackquery.Prefix("antispam"), antispamModule.notSpamCallback))
 }
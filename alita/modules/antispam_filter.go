@@ -0,0 +1,498 @@
+package modules
+
+import (
+	"hash/fnv"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/PaulSonOfLars/gotgbot/v2"
+	"github.com/PaulSonOfLars/gotgbot/v2/ext"
+	"github.com/eko/gocache/lib/v4/store"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/divkix/Alita_Robot/alita/config"
+	"github.com/divkix/Alita_Robot/alita/db/antispam"
+	dbcache "github.com/divkix/Alita_Robot/alita/db/cache"
+	"github.com/divkix/Alita_Robot/alita/db/lang"
+	"github.com/divkix/Alita_Robot/alita/db/user"
+	"github.com/divkix/Alita_Robot/alita/i18n"
+	"github.com/divkix/Alita_Robot/alita/utils/cache"
+	"github.com/divkix/Alita_Robot/alita/utils/chat_status"
+	"github.com/divkix/Alita_Robot/alita/utils/formatting"
+	"github.com/divkix/Alita_Robot/alita/utils/helpers"
+	"github.com/divkix/Alita_Robot/alita/utils/spamfilter"
+)
+
+var antispamModule = moduleStruct{moduleName: "Antispam"}
+
+const (
+	// antiSpamRepeatWindow is how long a member's last text is remembered to
+	// spot them sending it again.
+	antiSpamRepeatWindow = 10 * time.Minute
+	// antiSpamContextTTL is how long the repeat and burst signals of a
+	// message are kept for /spam and /notspam, which cannot see them.
+	antiSpamContextTTL = time.Hour
+	// antiSpamFlaggedTTL is how long the "Not spam" button of a flagged
+	// message keeps working.
+	antiSpamFlaggedTTL = 24 * time.Hour
+	// antiSpamNewAccountPercent is how much newer than the other known users
+	// an account must be to count as new.
+	antiSpamNewAccountPercent = 95
+	antiSpamNewAccountTTL     = 6 * time.Hour
+)
+
+// recentSpamText is the last text a member sent in a chat.
+type recentSpamText struct {
+	hash uint64
+	seen time.Time
+}
+
+// spamMessageKey identifies a message in a chat.
+type spamMessageKey struct {
+	chatId    int64
+	messageId int64
+}
+
+// spamMessageContext holds the signals of a message that only the spam
+// check sees. Messages without any are not stored.
+type spamMessageContext struct {
+	repeated bool
+	burst    bool
+	seen     time.Time
+}
+
+var (
+	antiSpamTextMutex   sync.Mutex
+	recentSpamTexts     = make(map[spamKey]recentSpamText)
+	spamMessageContexts = make(map[spamMessageKey]spamMessageContext)
+)
+
+// trackRepeatedText records the text a member sent and reports whether it is
+// the same as the one they sent before, within antiSpamRepeatWindow.
+func trackRepeatedText(key spamKey, text string, now time.Time) bool {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(strings.ToLower(strings.Join(strings.Fields(text), " "))))
+	hash := h.Sum64()
+
+	antiSpamTextMutex.Lock()
+	defer antiSpamTextMutex.Unlock()
+	last, ok := recentSpamTexts[key]
+	recentSpamTexts[key] = recentSpamText{hash: hash, seen: now}
+	return ok && last.hash == hash && now.Sub(last.seen) < antiSpamRepeatWindow
+}
+
+// rememberSpamContext keeps the repeat and burst signals of a message for
+// training.
+func rememberSpamContext(chatID, messageID int64, repeated, burst bool, now time.Time) {
+	if !repeated && !burst {
+		return
+	}
+	antiSpamTextMutex.Lock()
+	spamMessageContexts[spamMessageKey{chatId: chatID, messageId: messageID}] = spamMessageContext{repeated: repeated, burst: burst, seen: now}
+	antiSpamTextMutex.Unlock()
+}
+
+// cleanupSpamTexts drops remembered texts and message signals that expired.
+func cleanupSpamTexts(now time.Time) {
+	antiSpamTextMutex.Lock()
+	defer antiSpamTextMutex.Unlock()
+	for key, text := range recentSpamTexts {
+		if now.Sub(text.seen) >= antiSpamRepeatWindow {
+			delete(recentSpamTexts, key)
+		}
+	}
+	for key, signals := range spamMessageContexts {
+		if now.Sub(signals.seen) >= antiSpamContextTTL {
+			delete(spamMessageContexts, key)
+		}
+	}
+}
+
+// isNewAccount reports whether an account looks recently created. The
+// answer is cached as it costs two counts over the users table.
+func isNewAccount(userID int64) bool {
+	isNew, err := dbcache.GetFromCacheOrLoad(dbcache.CacheKey("antispam", "new_account", userID), antiSpamNewAccountTTL, func() (bool, error) {
+		percent, ok := user.IDNewerThan(userID)
+		return ok && percent >= antiSpamNewAccountPercent, nil
+	})
+	return err == nil && isNew
+}
+
+// spamText returns the text or caption of a message.
+func spamText(msg *gotgbot.Message) string {
+	if msg.Text != "" {
+		return msg.Text
+	}
+	return msg.Caption
+}
+
+// spamMessage describes a message to the classifier.
+func spamMessage(msg *gotgbot.Message, newAccount, repeated, burst bool) spamfilter.Message {
+	m := spamfilter.Message{
+		Text:       spamText(msg),
+		Forwarded:  msg.ForwardOrigin != nil,
+		NewAccount: newAccount,
+		Repeated:   repeated,
+		Burst:      burst,
+	}
+	for _, entity := range slices.Concat(msg.Entities, msg.CaptionEntities) {
+		switch entity.Type {
+		case "text_link":
+			m.HiddenLinks++
+		case "text_mention":
+			m.HiddenMentions++
+		}
+	}
+	return m
+}
+
+// spamTrainingTokens returns the tokens of a message an admin replied to,
+// with the signals the spam check saw when it arrived.
+func spamTrainingTokens(msg *gotgbot.Message) []string {
+	if strings.TrimSpace(spamText(msg)) == "" {
+		return nil
+	}
+	antiSpamTextMutex.Lock()
+	signals := spamMessageContexts[spamMessageKey{chatId: msg.Chat.Id, messageId: msg.MessageId}]
+	antiSpamTextMutex.Unlock()
+
+	newAccount := msg.From != nil && !msg.From.IsBot && isNewAccount(msg.From.Id)
+	return spamfilter.Tokens(spamMessage(msg, newAccount, signals.repeated, signals.burst))
+}
+
+func flaggedSpamKey(chatID, messageID int64) string {
+	return dbcache.CacheKey("antispam", "flagged", chatID, messageID)
+}
+
+// checkSpam scores a message from a member with the spam models of the chat
+// and acts on it if it looks like spam. burst is set when the member is
+// sending messages very quickly.
+func (m moduleStruct) checkSpam(b *gotgbot.Bot, ctx *ext.Context, burst bool) error {
+	chat := ctx.EffectiveChat
+	msg := ctx.EffectiveMessage
+	if msg == nil || chat.Type == "private" {
+		return ext.ContinueGroups
+	}
+	text := spamText(msg)
+	if strings.TrimSpace(text) == "" || strings.HasPrefix(text, "/") {
+		return ext.ContinueGroups
+	}
+
+	now := time.Now()
+	key := spamKey{chatId: chat.Id, userId: ctx.EffectiveUser.Id}
+	repeated := trackRepeatedText(key, text, now)
+	rememberSpamContext(chat.Id, msg.MessageId, repeated, burst, now)
+
+	settings := antispam.GetAntispamSettings(chat.Id)
+	if !settings.Enabled {
+		return ext.ContinueGroups
+	}
+
+	tokens := spamfilter.Tokens(spamMessage(msg, isNewAccount(ctx.EffectiveUser.Id), repeated, burst))
+	counts, err := antispam.GetSpamCounts(chat.Id, tokens, settings.UseGlobal)
+	if err != nil {
+		return ext.ContinueGroups
+	}
+	probability, ok := spamfilter.Score(counts, tokens)
+	if !ok {
+		return ext.ContinueGroups
+	}
+	score := int(probability * 100)
+	if score < settings.Threshold {
+		return ext.ContinueGroups
+	}
+	log.Infof("[Antispam] Flagged message %d of user %d in chat %d as spam (%d%%)", msg.MessageId, ctx.EffectiveUser.Id, chat.Id, score)
+
+	if !chat_status.IsBotAdmin(b, ctx, chat) {
+		return ext.ContinueGroups
+	}
+	_ = helpers.DeleteMessageWithErrorHandling(b, chat.Id, msg.MessageId)
+
+	tr := i18n.MustNewTranslator(lang.GetLanguage(ctx))
+	reason, _ := tr.GetString("antispam_reason", i18n.TranslationParams{"score": score})
+
+	// Keep the tokens so admins can correct a false positive once the
+	// message is gone.
+	var markup gotgbot.ReplyMarkup
+	if c := cache.GetMarshal(); c != nil {
+		if err := c.Set(cache.Context, flaggedSpamKey(chat.Id, msg.MessageId), tokens, store.WithExpiration(antiSpamFlaggedTTL)); err != nil {
+			log.Errorf("[Antispam] Failed to keep flagged message %d of chat %d: %v", msg.MessageId, chat.Id, err)
+		} else {
+			notSpamText, _ := tr.GetString("antispam_btn_not_spam")
+			markup = gotgbot.InlineKeyboardMarkup{InlineKeyboard: [][]gotgbot.InlineKeyboardButton{{{
+				Text:         notSpamText,
+				CallbackData: encodeCallbackData("antispam", map[string]string{"a": "ham", "m": strconv.FormatInt(msg.MessageId, 10)}),
+			}}}}
+		}
+	}
+
+	if err := applyModerationAction(b, ctx, settings.Action, reason, strings.ToLower(m.moduleName)+"_", markup); err != nil {
+		return err
+	}
+	return ext.EndGroups
+}
+
+// antispamCommand shows or changes the spam filter settings of a chat.
+func (moduleStruct) antispamCommand(b *gotgbot.Bot, ctx *ext.Context) error {
+	msg := ctx.EffectiveMessage
+	// connection status
+	connectedChat := chat_status.IsUserConnected(b, ctx, true, true)
+	if connectedChat == nil {
+		return ext.EndGroups
+	}
+	chat := connectedChat
+	args := ctx.Args()[1:]
+	tr := i18n.MustNewTranslator(lang.GetLanguage(ctx))
+
+	reply := func(key string, params i18n.TranslationParams) error {
+		text, _ := tr.GetString(key, params)
+		_, _ = msg.Reply(b, text, formatting.Shtml())
+		return ext.EndGroups
+	}
+	onOff := func(arg string) (bool, bool) {
+		switch strings.ToLower(arg) {
+		case "on", "yes", "true":
+			return true, true
+		case "off", "no", "false":
+			return false, true
+		}
+		return false, false
+	}
+
+	if len(args) == 0 {
+		return reply("antispam_status", antispamStatusParams(tr, chat.Id))
+	}
+
+	var err error
+	switch strings.ToLower(args[0]) {
+	case "on", "off":
+		enabled, _ := onOff(args[0])
+		if err = antispam.SetAntispamEnabled(chat.Id, enabled); err != nil {
+			break
+		}
+		if !enabled {
+			return reply("antispam_disabled", nil)
+		}
+		settings := antispam.GetAntispamSettings(chat.Id)
+		return reply("antispam_enabled", i18n.TranslationParams{"threshold": settings.Threshold, "action": settings.Action})
+	case "action":
+		if len(args) < 2 || !slices.Contains(antispam.Actions, strings.ToLower(args[1])) {
+			return reply("antispam_invalid_action", i18n.TranslationParams{"actions": strings.Join(antispam.Actions, ", ")})
+		}
+		action := strings.ToLower(args[1])
+		if err = antispam.SetAntispamAction(chat.Id, action); err != nil {
+			break
+		}
+		return reply("antispam_action_set", i18n.TranslationParams{"action": action})
+	case "threshold":
+		var threshold int
+		if len(args) >= 2 {
+			threshold, _ = strconv.Atoi(strings.TrimSuffix(args[1], "%"))
+		}
+		if threshold < antispam.MinThreshold || threshold > antispam.MaxThreshold {
+			return reply("antispam_invalid_threshold", i18n.TranslationParams{"min": antispam.MinThreshold, "max": antispam.MaxThreshold})
+		}
+		if err = antispam.SetAntispamThreshold(chat.Id, threshold); err != nil {
+			break
+		}
+		return reply("antispam_threshold_set", i18n.TranslationParams{"threshold": threshold})
+	case "global":
+		var useGlobal, ok bool
+		if len(args) >= 2 {
+			useGlobal, ok = onOff(args[1])
+		}
+		if !ok {
+			return reply("antispam_usage", nil)
+		}
+		if err = antispam.SetAntispamUseGlobal(chat.Id, useGlobal); err != nil {
+			break
+		}
+		if useGlobal {
+			return reply("antispam_global_on", nil)
+		}
+		return reply("antispam_global_off", nil)
+	default:
+		return reply("antispam_usage", nil)
+	}
+
+	log.Errorf("[Antispam] Failed to update settings of chat %d: %v", chat.Id, err)
+	return reply("error_generic", nil)
+}
+
+// spamTrust handles /spamtrust: the bot owner chooses which chats are trusted
+// to train the global spam model. Anyone else is ignored.
+func (moduleStruct) spamTrust(b *gotgbot.Bot, ctx *ext.Context) error {
+	user := chat_status.RequireUser(b, ctx)
+	if user == nil {
+		return ext.EndGroups
+	}
+	if user.Id != config.AppConfig.OwnerId {
+		return ext.ContinueGroups
+	}
+	msg := ctx.EffectiveMessage
+	args := ctx.Args()[1:]
+	tr := i18n.MustNewTranslator(lang.GetLanguage(ctx))
+	reply := func(key string, params i18n.TranslationParams) error {
+		text, _ := tr.GetString(key, params)
+		_, _ = msg.Reply(b, text, formatting.Shtml())
+		return ext.EndGroups
+	}
+
+	// The chat is given by ID, or is the group the command is sent in.
+	chatID := ctx.EffectiveChat.Id
+	if len(args) == 2 {
+		var err error
+		if chatID, err = strconv.ParseInt(args[0], 10, 64); err != nil {
+			return reply("antispam_trust_usage", nil)
+		}
+		args = args[1:]
+	}
+	if len(args) != 1 || chatID >= 0 {
+		return reply("antispam_trust_usage", nil)
+	}
+	var trusted bool
+	switch strings.ToLower(args[0]) {
+	case "on", "yes", "true":
+		trusted = true
+	case "off", "no", "false":
+	default:
+		return reply("antispam_trust_usage", nil)
+	}
+
+	if err := antispam.SetAntispamTrainGlobal(chatID, trusted); err != nil {
+		log.Errorf("[Antispam] Failed to set global training of chat %d: %v", chatID, err)
+		return reply("error_generic", nil)
+	}
+	if trusted {
+		return reply("antispam_trust_on", i18n.TranslationParams{"chat": chatID})
+	}
+	return reply("antispam_trust_off", i18n.TranslationParams{"chat": chatID})
+}
+
+// antispamStatusParams fills the /antispam status message of a chat.
+func antispamStatusParams(tr *i18n.Translator, chatID int64) i18n.TranslationParams {
+	settings := antispam.GetAntispamSettings(chatID)
+	chatModel, globalModel, _ := antispam.GetSpamModels(chatID)
+	onOff := func(on bool) string {
+		key := "antispam_off"
+		if on {
+			key = "antispam_on"
+		}
+		text, _ := tr.GetString(key)
+		return text
+	}
+	return i18n.TranslationParams{
+		"status":      onOff(settings.Enabled),
+		"action":      settings.Action,
+		"threshold":   settings.Threshold,
+		"global":      onOff(settings.UseGlobal),
+		"chat_spam":   chatModel.SpamDocs,
+		"chat_ham":    chatModel.HamDocs,
+		"global_spam": globalModel.SpamDocs,
+		"global_ham":  globalModel.HamDocs,
+		"min":         spamfilter.MinTrainingDocs,
+	}
+}
+
+// markSpam handles /spam: the replied message trains the spam filter as spam
+// and is deleted.
+func (m moduleStruct) markSpam(b *gotgbot.Bot, ctx *ext.Context) error {
+	return m.trainSpamFilter(b, ctx, true)
+}
+
+// markNotSpam handles /notspam: the replied message trains the spam filter
+// as not spam.
+func (m moduleStruct) markNotSpam(b *gotgbot.Bot, ctx *ext.Context) error {
+	return m.trainSpamFilter(b, ctx, false)
+}
+
+func (moduleStruct) trainSpamFilter(b *gotgbot.Bot, ctx *ext.Context, spam bool) error {
+	msg := ctx.EffectiveMessage
+	chat := ctx.EffectiveChat
+	if _, ok := checkPurgePermissions(b, ctx); !ok {
+		return ext.EndGroups
+	}
+	tr := i18n.MustNewTranslator(lang.GetLanguage(ctx))
+
+	target := msg.ReplyToMessage
+	if target == nil {
+		text, _ := tr.GetString("antispam_reply_required")
+		_, _ = msg.Reply(b, text, formatting.Shtml())
+		return ext.EndGroups
+	}
+	tokens := spamTrainingTokens(target)
+	if len(tokens) == 0 {
+		text, _ := tr.GetString("antispam_no_text")
+		_, _ = msg.Reply(b, text, formatting.Shtml())
+		return ext.EndGroups
+	}
+	if err := antispam.TrainSpamModel(chat.Id, tokens, spam); err != nil {
+		text, _ := tr.GetString("error_generic")
+		_, _ = msg.Reply(b, text, formatting.Shtml())
+		return ext.EndGroups
+	}
+
+	key := "antispam_trained_ham"
+	if spam {
+		key = "antispam_trained_spam"
+		_ = helpers.DeleteMessageWithErrorHandling(b, chat.Id, target.MessageId)
+	}
+	chatModel, _, _ := antispam.GetSpamModels(chat.Id)
+	text, _ := tr.GetString(key, i18n.TranslationParams{"spam": chatModel.SpamDocs, "ham": chatModel.HamDocs})
+	_, _ = msg.Reply(b, text, formatting.Shtml())
+	return ext.EndGroups
+}
+
+// notSpamCallback handles the "Not spam" button of a flagged message: the
+// message trains the spam filter as not spam.
+func (moduleStruct) notSpamCallback(b *gotgbot.Bot, ctx *ext.Context) error {
+	query, ok := callbackQueryFromContext(ctx)
+	if !ok {
+		return ext.EndGroups
+	}
+	tr := i18n.MustNewTranslator(lang.GetLanguage(ctx))
+
+	var messageID int64
+	if decoded, ok := decodeCallbackData(query.Data, "antispam"); ok {
+		if action, _ := decoded.Field("a"); action == "ham" {
+			raw, _ := decoded.Field("m")
+			messageID, _ = strconv.ParseInt(raw, 10, 64)
+		}
+	}
+	if messageID == 0 || query.Message == nil {
+		text, _ := tr.GetString("common_callback_invalid_request")
+		_, _ = query.Answer(b, &gotgbot.AnswerCallbackQueryOpts{Text: text})
+		return ext.EndGroups
+	}
+	if !chat_status.CanUserDelete(b, ctx, nil, query.From.Id) {
+		chat_status.NewPermissionResponder(b).Respond(ctx, "chat_status_delete_cmd_error", "chat_status_delete_button_error")
+		return ext.EndGroups
+	}
+	chatID := query.Message.GetChat().Id
+
+	var tokens []string
+	c := cache.GetMarshal()
+	if c != nil {
+		_, _ = c.Get(cache.Context, flaggedSpamKey(chatID, messageID), &tokens)
+	}
+	if len(tokens) == 0 {
+		text, _ := tr.GetString("antispam_flagged_expired")
+		_, _ = query.Answer(b, &gotgbot.AnswerCallbackQueryOpts{Text: text})
+		return ext.EndGroups
+	}
+	if err := antispam.TrainSpamModel(chatID, tokens, false); err != nil {
+		text, _ := tr.GetString("error_generic")
+		_, _ = query.Answer(b, &gotgbot.AnswerCallbackQueryOpts{Text: text})
+		return ext.EndGroups
+	}
+	// The message only counts once.
+	_ = c.Delete(cache.Context, flaggedSpamKey(chatID, messageID))
+
+	_, _, _ = query.Message.EditReplyMarkup(b, &gotgbot.EditMessageReplyMarkupOpts{})
+	text, _ := tr.GetString("antispam_marked_not_spam")
+	_, _ = query.Answer(b, &gotgbot.AnswerCallbackQueryOpts{Text: text})
+	return ext.EndGroups
+}
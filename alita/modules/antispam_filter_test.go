@@ -0,0 +1,293 @@
+//go:build testtools
+
+package modules
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/PaulSonOfLars/gotgbot/v2"
+	"github.com/PaulSonOfLars/gotgbot/v2/ext"
+	"github.com/PaulSonOfLars/gotgbot/v2/ext/handlers"
+	"github.com/PaulSonOfLars/gotgbot/v2/ext/handlers/filters/message"
+	"github.com/eko/gocache/lib/v4/store"
+
+	"github.com/divkix/Alita_Robot/alita/db"
+	"github.com/divkix/Alita_Robot/alita/db/antispam"
+	"github.com/divkix/Alita_Robot/alita/db/models"
+	"github.com/divkix/Alita_Robot/alita/utils/cache"
+	"github.com/divkix/Alita_Robot/alita/utils/spamfilter"
+)
+
+// trainSpamFilterForTest trains the model of a chat on crypto spam and on
+// ordinary chatter, enough for the filter to act.
+func trainSpamFilterForTest(t *testing.T, chatID int64) {
+	t.Helper()
+	for range spamfilter.MinTrainingDocs {
+		if err := antispam.TrainSpamModel(chatID, spamfilter.Tokens(spamfilter.Message{Text: "free crypto airdrop https://t.me/drop"}), true); err != nil {
+			t.Fatalf("TrainSpamModel(spam) error = %v", err)
+		}
+		if err := antispam.TrainSpamModel(chatID, spamfilter.Tokens(spamfilter.Message{Text: "see you at the meeting tomorrow"}), false); err != nil {
+			t.Fatalf("TrainSpamModel(ham) error = %v", err)
+		}
+	}
+	t.Cleanup(func() {
+		_ = db.DB.Where("chat_id = ?", chatID).Delete(&models.AntispamSettings{}).Error
+		_ = db.DB.Where("chat_id = ?", chatID).Delete(&models.SpamModel{}).Error
+		_ = db.DB.Where("chat_id = ?", chatID).Delete(&models.SpamToken{}).Error
+	})
+}
+
+func spamModelForTest(t *testing.T, chatID int64) models.SpamModel {
+	t.Helper()
+	chatModel, _, err := antispam.GetSpamModels(chatID)
+	if err != nil {
+		t.Fatalf("GetSpamModels() error = %v", err)
+	}
+	return chatModel
+}
+
+func TestTrackRepeatedTextSpotsSameTextWithinWindow(t *testing.T) {
+	key := spamKey{chatId: uniqueModuleChatID(), userId: 42}
+	now := time.Now()
+
+	if trackRepeatedText(key, "Join my channel", now) {
+		t.Fatal("first message reported as repeated")
+	}
+	if !trackRepeatedText(key, "join   my CHANNEL", now.Add(time.Minute)) {
+		t.Fatal("same text with other case and spacing not reported as repeated")
+	}
+	if trackRepeatedText(key, "something else", now.Add(2*time.Minute)) {
+		t.Fatal("different text reported as repeated")
+	}
+	if trackRepeatedText(key, "something else", now.Add(2*time.Minute+antiSpamRepeatWindow)) {
+		t.Fatal("text repeated after the window reported as repeated")
+	}
+
+	rememberSpamContext(key.chatId, 7, true, false, now)
+	cleanupSpamTexts(now.Add(antiSpamContextTTL))
+	antiSpamTextMutex.Lock()
+	_, textKept := recentSpamTexts[key]
+	_, contextKept := spamMessageContexts[spamMessageKey{chatId: key.chatId, messageId: 7}]
+	antiSpamTextMutex.Unlock()
+	if textKept || contextKept {
+		t.Fatalf("cleanup kept text=%v context=%v, want both dropped", textKept, contextKept)
+	}
+}
+
+func TestCheckSpamActsOnTrainedSpamOnly(t *testing.T) {
+	resetAntiSpamMapForTest(t)
+	withMiniredis(t)
+
+	client := newModuleBotClient()
+	bot := newModuleTestBot(client)
+	chat := gotgbot.Chat{Id: uniqueModuleChatID(), Type: "supergroup", Title: "Spam Chat"}
+	trainSpamFilterForTest(t, chat.Id)
+	for _, set := range []error{
+		antispam.SetAntispamEnabled(chat.Id, true),
+		antispam.SetAntispamAction(chat.Id, models.SpamActionMute),
+		antispam.SetAntispamUseGlobal(chat.Id, false),
+	} {
+		if set != nil {
+			t.Fatalf("antispam settings error = %v", set)
+		}
+	}
+
+	dispatcher := ext.NewDispatcher(&ext.DispatcherOpts{MaxRoutines: -1})
+	LoadAntispam(dispatcher)
+	processed := 0
+	dispatcher.AddHandler(handlers.NewMessage(message.All, func(*gotgbot.Bot, *ext.Context) error {
+		processed++
+		return ext.ContinueGroups
+	}))
+	member := &gotgbot.User{Id: 42, FirstName: "Member"}
+	send := func(id int64, text string) {
+		t.Helper()
+		update := &gotgbot.Update{UpdateId: id, Message: &gotgbot.Message{MessageId: id, Date: 1, Chat: chat, From: member, Text: text}}
+		if err := dispatcher.ProcessUpdate(bot, update, nil); err != nil {
+			t.Fatalf("ProcessUpdate(%q) error = %v", text, err)
+		}
+	}
+
+	send(1, "is the meeting still tomorrow?")
+	if processed != 1 || len(client.callsFor("deleteMessage")) != 0 {
+		t.Fatalf("ordinary message: processed=%d deletes=%d, want it passed through", processed, len(client.callsFor("deleteMessage")))
+	}
+
+	send(2, "FREE crypto airdrop, claim at https://t.me/claimnow")
+	if processed != 1 {
+		t.Fatalf("spam reached downstream handlers, processed = %d", processed)
+	}
+	deletes := client.callsFor("deleteMessage")
+	if len(deletes) != 1 || fmt.Sprint(deletes[0].Params["message_id"]) != "2" {
+		t.Fatalf("deleteMessage calls = %+v, want the spam message deleted", deletes)
+	}
+	if restricts := client.callsFor("restrictChatMember"); len(restricts) != 1 || fmt.Sprint(restricts[0].Params["user_id"]) != "42" {
+		t.Fatalf("restrictChatMember calls = %+v, want the sender muted", restricts)
+	}
+	sends := client.callsFor("sendMessage")
+	if len(sends) != 1 || !strings.Contains(fmt.Sprint(sends[0].Params["reply_markup"]), "antispam") {
+		t.Fatalf("sendMessage calls = %+v, want a notice with the not-spam button", sends)
+	}
+
+	// The filter stays quiet while it is off.
+	if err := antispam.SetAntispamEnabled(chat.Id, false); err != nil {
+		t.Fatalf("SetAntispamEnabled(false) error = %v", err)
+	}
+	send(3, "FREE crypto airdrop, claim at https://t.me/claimnow")
+	if processed != 2 || len(client.callsFor("deleteMessage")) != 1 {
+		t.Fatalf("disabled filter: processed=%d deletes=%d, want the message passed through", processed, len(client.callsFor("deleteMessage")))
+	}
+}
+
+func TestMarkSpamTrainsAndDeletesRepliedMessage(t *testing.T) {
+	client := newModuleBotClient()
+	bot := newModuleTestBot(client)
+	chat := gotgbot.Chat{Id: uniqueModuleChatID(), Type: "supergroup", Title: "Spam Chat"}
+	admin := gotgbot.User{Id: 777000, FirstName: "Telegram"}
+	t.Cleanup(func() {
+		_ = db.DB.Where("chat_id = ?", chat.Id).Delete(&models.SpamModel{}).Error
+		_ = db.DB.Where("chat_id = ?", chat.Id).Delete(&models.SpamToken{}).Error
+	})
+
+	ctx := newPurgeReplyContext(bot, chat, admin, "/spam", 20, 19)
+	ctx.EffectiveMessage.ReplyToMessage.Text = "cheap followers, dm me"
+	if err := antispamModule.markSpam(bot, ctx); !errors.Is(err, ext.EndGroups) {
+		t.Fatalf("markSpam() error = %v", err)
+	}
+	if got := spamModelForTest(t, chat.Id); got.SpamDocs != 1 || got.HamDocs != 0 {
+		t.Fatalf("chat model = %+v, want one spam message", got)
+	}
+	if deletes := client.callsFor("deleteMessage"); len(deletes) != 1 || fmt.Sprint(deletes[0].Params["message_id"]) != "19" {
+		t.Fatalf("deleteMessage calls = %+v, want the replied message deleted", deletes)
+	}
+
+	ctx = newPurgeReplyContext(bot, chat, admin, "/notspam", 22, 21)
+	if err := antispamModule.markNotSpam(bot, ctx); !errors.Is(err, ext.EndGroups) {
+		t.Fatalf("markNotSpam() error = %v", err)
+	}
+	if got := spamModelForTest(t, chat.Id); got.SpamDocs != 1 || got.HamDocs != 1 {
+		t.Fatalf("chat model = %+v, want one spam and one other message", got)
+	}
+	if deletes := client.callsFor("deleteMessage"); len(deletes) != 1 {
+		t.Fatalf("deleteMessage calls = %d, want /notspam to keep the message", len(deletes))
+	}
+
+	// Without a reply nothing is trained.
+	if err := antispamModule.markSpam(bot, newModuleMessageContext(bot, chat, admin, "/spam")); !errors.Is(err, ext.EndGroups) {
+		t.Fatalf("markSpam(no reply) error = %v", err)
+	}
+	if got := spamModelForTest(t, chat.Id); got.SpamDocs != 1 {
+		t.Fatalf("chat model = %+v, want /spam without a reply to train nothing", got)
+	}
+}
+
+func TestNotSpamCallbackTrainsFlaggedMessageOnce(t *testing.T) {
+	withMiniredis(t)
+
+	client := newModuleBotClient()
+	bot := newModuleTestBot(client)
+	chat := gotgbot.Chat{Id: uniqueModuleChatID(), Type: "supergroup", Title: "Spam Chat"}
+	admin := gotgbot.User{Id: 777000, FirstName: "Telegram"}
+	t.Cleanup(func() {
+		_ = db.DB.Where("chat_id = ?", chat.Id).Delete(&models.SpamModel{}).Error
+		_ = db.DB.Where("chat_id = ?", chat.Id).Delete(&models.SpamToken{}).Error
+	})
+	if err := cache.GetMarshal().Set(cache.Context, flaggedSpamKey(chat.Id, 55), []string{"hello", spamfilter.TokenLink}, store.WithExpiration(time.Minute)); err != nil {
+		t.Fatalf("cache Set() error = %v", err)
+	}
+	data := encodeCallbackData("antispam", map[string]string{"a": "ham", "m": "55"})
+
+	for range 2 {
+		if err := antispamModule.notSpamCallback(bot, newModuleCallbackContext(bot, chat, admin, data)); !errors.Is(err, ext.EndGroups) {
+			t.Fatalf("notSpamCallback() error = %v", err)
+		}
+	}
+	if got := spamModelForTest(t, chat.Id); got.SpamDocs != 0 || got.HamDocs != 1 {
+		t.Fatalf("chat model = %+v, want the flagged message trained as not spam once", got)
+	}
+	if edits := client.callsFor("editMessageReplyMarkup"); len(edits) != 1 {
+		t.Fatalf("editMessageReplyMarkup calls = %d, want the button removed once", len(edits))
+	}
+	if answers := client.callsFor("answerCallbackQuery"); len(answers) != 2 {
+		t.Fatalf("answerCallbackQuery calls = %d, want both presses answered", len(answers))
+	}
+}
+
+func TestAntispamCommandUpdatesSettings(t *testing.T) {
+	client := newModuleBotClient()
+	bot := newModuleTestBot(client)
+	chat := gotgbot.Chat{Id: uniqueModuleChatID(), Type: "supergroup", Title: "Spam Chat"}
+	admin := gotgbot.User{Id: 777000, FirstName: "Telegram"}
+	t.Cleanup(func() {
+		_ = db.DB.Where("chat_id = ?", chat.Id).Delete(&models.AntispamSettings{}).Error
+	})
+
+	for _, text := range []string{
+		"/antispam on",
+		"/antispam action ban",
+		"/antispam action explode",
+		"/antispam threshold 70%",
+		"/antispam threshold 100",
+		"/antispam global off",
+		"/antispam",
+	} {
+		if err := antispamModule.antispamCommand(bot, newModuleMessageContext(bot, chat, admin, text)); !errors.Is(err, ext.EndGroups) {
+			t.Fatalf("antispamCommand(%q) error = %v", text, err)
+		}
+	}
+
+	settings := antispam.GetAntispamSettings(chat.Id)
+	if !settings.Enabled || settings.Action != models.SpamActionBan || settings.Threshold != 70 || settings.UseGlobal {
+		t.Fatalf("settings = %+v, want enabled, ban at 70%% without the global model", settings)
+	}
+	if sends := client.callsFor("sendMessage"); len(sends) != 7 {
+		t.Fatalf("sendMessage calls = %d, want one reply per command", len(sends))
+	}
+}
+
+func TestSpamTrustIsOwnerOnly(t *testing.T) {
+	withOwnerID(t, 777000)
+	client := newModuleBotClient()
+	bot := newModuleTestBot(client)
+	chat := gotgbot.Chat{Id: uniqueModuleChatID(), Type: "supergroup", Title: "Spam Chat"}
+	owner := gotgbot.User{Id: 777000, FirstName: "Owner"}
+	admin := gotgbot.User{Id: 42, FirstName: "Admin"}
+	t.Cleanup(func() {
+		_ = db.DB.Where("chat_id = ?", chat.Id).Delete(&models.AntispamSettings{}).Error
+	})
+
+	if err := antispamModule.spamTrust(bot, newModuleMessageContext(bot, chat, admin, "/spamtrust on")); !errors.Is(err, ext.ContinueGroups) {
+		t.Fatalf("spamTrust(non-owner) error = %v, want ContinueGroups", err)
+	}
+	if antispam.GetAntispamSettings(chat.Id).TrainGlobal {
+		t.Fatal("a non-owner made the chat train the global model")
+	}
+
+	if err := antispamModule.spamTrust(bot, newModuleMessageContext(bot, chat, owner, "/spamtrust on")); !errors.Is(err, ext.EndGroups) {
+		t.Fatalf("spamTrust(on) error = %v", err)
+	}
+	if !antispam.GetAntispamSettings(chat.Id).TrainGlobal {
+		t.Fatal("/spamtrust on did not trust the chat")
+	}
+
+	private := gotgbot.Chat{Id: owner.Id, Type: "private", FirstName: "Owner"}
+	if err := antispamModule.spamTrust(bot, newModuleMessageContext(bot, private, owner, fmt.Sprintf("/spamtrust %d off", chat.Id))); !errors.Is(err, ext.EndGroups) {
+		t.Fatalf("spamTrust(id off) error = %v", err)
+	}
+	if antispam.GetAntispamSettings(chat.Id).TrainGlobal {
+		t.Fatal("/spamtrust <chat id> off did not untrust the chat")
+	}
+	if err := antispamModule.spamTrust(bot, newModuleMessageContext(bot, private, owner, "/spamtrust on")); !errors.Is(err, ext.EndGroups) {
+		t.Fatalf("spamTrust(private on) error = %v", err)
+	}
+	if antispam.GetAntispamSettings(private.Id).TrainGlobal {
+		t.Fatal("/spamtrust on in private trusted the private chat")
+	}
+	if sends := client.callsFor("sendMessage"); len(sends) != 3 {
+		t.Fatalf("sendMessage calls = %d, want a reply to each owner command", len(sends))
+	}
+}
@@ -1,6 +1,7 @@
 package modules
 
 import (
+	"errors"
 	"fmt"
 	"html"
 	"os"
@@ -14,7 +15,6 @@ import (
 	"github.com/PaulSonOfLars/gotgbot/v2/ext/handlers/filters/callbackquery"
 	log "github.com/sirupsen/logrus"
 
-	"github.com/divkix/Alita_Robot/alita/db"
 	"github.com/divkix/Alita_Robot/alita/db/approvals"
 	dbcaptcha "github.com/divkix/Alita_Robot/alita/db/captcha"
 	"github.com/divkix/Alita_Robot/alita/db/lang"
@@ -32,6 +32,11 @@ var approvalsModule = moduleStruct{
 
 const approvedUsersInlineLimit = 50
 
+var (
+	errInvalidApprovalScopes   = errors.New("unknown approval scope")
+	errInvalidApprovalDuration = errors.New("invalid approval duration")
+)
+
 /*
 	Used to approve a user in the group!
 
@@ -74,8 +79,25 @@ func (m moduleStruct) approveUser(b *gotgbot.Bot, ctx *ext.Context) error {
 		return ext.EndGroups
 	}
 
-	// Check if already approved
-	if approvals.IsUserApproved(chat.Id, targetUserID) {
+	scopes, expiresAt, reason, parseErr := parseApprovalArgs(reason, time.Now())
+	if parseErr != nil {
+		text, _ := tr.GetString(strings.ToLower(m.moduleName) + "_invalid_duration")
+		if errors.Is(parseErr, errInvalidApprovalScopes) {
+			text, _ = tr.GetString(strings.ToLower(m.moduleName) + "_invalid_scopes")
+			text = fmt.Sprintf(text, strings.Join(approvals.AllScopes(), ", "))
+		}
+		_, err := msg.Reply(b, text, formatting.Shtml())
+		if err != nil {
+			log.Error(err)
+			return err
+		}
+		return ext.EndGroups
+	}
+
+	// A plain /approve is a no-op only for a full, permanent approval; a scoped or
+	// expiring approval is upgraded, and scopes or an expiry replace the approval
+	existing := approvals.GetApproval(chat.Id, targetUserID)
+	if existing != nil && len(existing.Scopes) == 0 && existing.ExpiresAt == nil && scopes == nil && expiresAt == nil {
 		text, _ := tr.GetString(strings.ToLower(m.moduleName) + "_already_approved")
 		_, err := msg.Reply(b, fmt.Sprintf(text, formatting.MentionHtml(targetUserID, "")), formatting.Shtml())
 		if err != nil {
@@ -92,28 +114,36 @@ func (m moduleStruct) approveUser(b *gotgbot.Bot, ctx *ext.Context) error {
 	}
 
 	// Reason is optional; default to empty string
-	if err := approvals.AddApprovedUser(chat.Id, targetUserID, user.Id, reason); err != nil {
+	if err := approvals.SetApproval(chat.Id, targetUserID, user.Id, reason, scopes, expiresAt); err != nil {
 		log.Errorf("[Approvals] Failed to approve user %d in chat %d: %v", targetUserID, chat.Id, err)
 		text, _ := tr.GetString(strings.ToLower(m.moduleName) + "_approve_error")
 		_, _ = msg.Reply(b, text, nil)
 		return ext.EndGroups
 	}
-	if attempt, err := dbcaptcha.GetCaptchaAttemptIncludingExpired(targetUserID, chat.Id); err != nil {
-		log.Errorf("[Approvals] Failed to load captcha attempt for approved user %d: %v", targetUserID, err)
-	} else if attempt != nil {
-		released, releaseErr := releaseIncompleteCaptchaAttempt(b, attempt)
-		if released && attempt.MessageID > 0 {
-			_ = helpers.DeleteMessageWithErrorHandling(b, chat.Id, attempt.MessageID)
-		}
-		if releaseErr != nil {
-			log.Errorf("[Approvals] Approved user %d but captcha release will retry: %v", targetUserID, releaseErr)
+	// Only approvals covering captcha release a pending challenge
+	if approvals.IsUserApprovedFor(chat.Id, targetUserID, approvals.ScopeCaptcha) {
+		if attempt, err := dbcaptcha.GetCaptchaAttemptIncludingExpired(targetUserID, chat.Id); err != nil {
+			log.Errorf("[Approvals] Failed to load captcha attempt for approved user %d: %v", targetUserID, err)
+		} else if attempt != nil {
+			released, releaseErr := releaseIncompleteCaptchaAttempt(b, attempt)
+			if released && attempt.MessageID > 0 {
+				_ = helpers.DeleteMessageWithErrorHandling(b, chat.Id, attempt.MessageID)
+			}
+			if releaseErr != nil {
+				log.Errorf("[Approvals] Approved user %d but captcha release will retry: %v", targetUserID, releaseErr)
+			}
 		}
 	}
 
+	details := formatApprovalDetails(tr, scopes, expiresAt)
+	if details != "" {
+		details = "\n" + details
+	}
 	text, _ := tr.GetString(strings.ToLower(m.moduleName) + "_user_approved")
 	baseStr := fmt.Sprintf(text,
 		formatting.MentionHtml(targetUserID, extractDisplayName(targetUserID)),
 		html.EscapeString(approverName),
+		details,
 	)
 	if reason != "" {
 		temp, _ := tr.GetString(strings.ToLower(m.moduleName) + "_reason")
@@ -238,15 +268,7 @@ func (m moduleStruct) checkApprovalStatus(b *gotgbot.Bot, ctx *ext.Context) erro
 		return ext.EndGroups
 	}
 
-	approvedUsers := approvals.GetApprovedUsers(chat.Id)
-	var foundUser *db.ApprovedUsers
-	for _, a := range approvedUsers {
-		if a.UserID == targetUserID {
-			foundUser = a
-			break
-		}
-	}
-
+	foundUser := approvals.GetApproval(chat.Id, targetUserID)
 	if foundUser == nil {
 		text, _ := tr.GetString(strings.ToLower(m.moduleName) + "_check_not_approved")
 		_, err := msg.Reply(b, fmt.Sprintf(text,
@@ -276,6 +298,7 @@ func (m moduleStruct) checkApprovalStatus(b *gotgbot.Bot, ctx *ext.Context) erro
 		html.EscapeString(targetName),
 		html.EscapeString(dateStr),
 		html.EscapeString(approverName),
+		formatApprovalDetails(tr, foundUser.Scopes, foundUser.ExpiresAt),
 	)
 	if foundUser.Reason != "" {
 		temp, _ := tr.GetString(strings.ToLower(m.moduleName) + "_reason")
@@ -333,6 +356,8 @@ func (m moduleStruct) listApprovedUsers(b *gotgbot.Bot, ctx *ext.Context) error
 		listHeader, _ := tr.GetString(strings.ToLower(m.moduleName) + "_list_header")
 		listItem, _ := tr.GetString(strings.ToLower(m.moduleName) + "_list_item")
 		listReason, _ := tr.GetString(strings.ToLower(m.moduleName) + "_list_reason")
+		listScopes, _ := tr.GetString(strings.ToLower(m.moduleName) + "_list_scopes")
+		listExpires, _ := tr.GetString(strings.ToLower(m.moduleName) + "_list_expires")
 		var sb strings.Builder
 		sb.WriteString(listHeader)
 		for _, a := range approvedUsers {
@@ -341,6 +366,12 @@ func (m moduleStruct) listApprovedUsers(b *gotgbot.Bot, ctx *ext.Context) error
 				name = strconv.FormatInt(a.UserID, 10)
 			}
 			item := fmt.Sprintf(listItem, html.EscapeString(name))
+			if len(a.Scopes) > 0 {
+				item += fmt.Sprintf(listScopes, strings.Join(a.Scopes, ", "))
+			}
+			if a.ExpiresAt != nil {
+				item += fmt.Sprintf(listExpires, formatApprovalExpiry(*a.ExpiresAt))
+			}
 			if a.Reason != "" {
 				item += fmt.Sprintf(listReason, html.EscapeString(a.Reason))
 			}
@@ -368,6 +399,8 @@ func (m moduleStruct) listApprovedUsers(b *gotgbot.Bot, ctx *ext.Context) error
 	fileHeader, _ := tr.GetString(strings.ToLower(m.moduleName) + "_list_file_header")
 	fileItem, _ := tr.GetString(strings.ToLower(m.moduleName) + "_list_file_item")
 	fileReason, _ := tr.GetString(strings.ToLower(m.moduleName) + "_list_reason")
+	fileScopes, _ := tr.GetString(strings.ToLower(m.moduleName) + "_list_scopes")
+	fileExpires, _ := tr.GetString(strings.ToLower(m.moduleName) + "_list_expires")
 	var fileSb strings.Builder
 	fmt.Fprintf(&fileSb, fileHeader, chat.Id)
 	fmt.Fprintf(&fileSb, "%s\n\n", time.Now().Format(time.RFC3339))
@@ -377,6 +410,12 @@ func (m moduleStruct) listApprovedUsers(b *gotgbot.Bot, ctx *ext.Context) error
 			name = strconv.FormatInt(a.UserID, 10)
 		}
 		item := fmt.Sprintf(fileItem, i+1, name)
+		if len(a.Scopes) > 0 {
+			item += fmt.Sprintf(fileScopes, strings.Join(a.Scopes, ", "))
+		}
+		if a.ExpiresAt != nil {
+			item += fmt.Sprintf(fileExpires, formatApprovalExpiry(*a.ExpiresAt))
+		}
 		if a.Reason != "" {
 			item += fmt.Sprintf(fileReason, a.Reason)
 		}
@@ -557,6 +596,83 @@ func (m moduleStruct) unapproveAllCallback(b *gotgbot.Bot, ctx *ext.Context) err
 	return ext.EndGroups
 }
 
+// parseApprovalArgs splits the text following the target user of /approve into
+// an optional scope list, an optional expiry, and the free-form reason.
+// A leading scope list and duration are read in either order, for example
+// "locks,blacklist 7d trusted helper". The scopes= and duration= options are
+// accepted as aliases and report an error when their value is invalid. The
+// reason starts at the first word that is neither, so "media spammer fixed"
+// approves the media scope with the reason "spammer fixed".
+func parseApprovalArgs(text string, now time.Time) (scopes []string, expiresAt *time.Time, reason string, err error) {
+	fields := strings.Fields(text)
+	consumed := 0
+	for ; consumed < len(fields); consumed++ {
+		token := strings.ToLower(fields[consumed])
+		if value, found := strings.CutPrefix(token, "scopes="); found {
+			parsed, valid := approvals.ParseScopes(value)
+			if !valid {
+				return nil, nil, "", errInvalidApprovalScopes
+			}
+			scopes = parsed
+			continue
+		}
+		if value, found := strings.CutPrefix(token, "duration="); found {
+			expiry, valid := parseApprovalExpiry(value, now)
+			if !valid {
+				return nil, nil, "", errInvalidApprovalDuration
+			}
+			expiresAt = &expiry
+			continue
+		}
+		if scopes == nil {
+			if parsed, valid := approvals.ParseScopes(token); valid {
+				scopes = parsed
+				continue
+			}
+		}
+		if expiresAt == nil {
+			if expiry, valid := parseApprovalExpiry(token, now); valid {
+				expiresAt = &expiry
+				continue
+			}
+		}
+		break
+	}
+	if consumed == 0 {
+		return nil, nil, text, nil
+	}
+	return scopes, expiresAt, strings.Join(fields[consumed:], " "), nil
+}
+
+// parseApprovalExpiry turns a duration such as "7d" into the expiry time.
+func parseApprovalExpiry(token string, now time.Time) (time.Time, bool) {
+	until, _, valid := extraction.ParseTemporaryDuration(token, now.Unix())
+	if !valid {
+		return time.Time{}, false
+	}
+	return time.Unix(until, 0).UTC(), true
+}
+
+// formatApprovalDetails renders the scope and expiry lines shown for an approval.
+// Returns an empty string for a permanent approval covering every scope.
+func formatApprovalDetails(tr *i18n.Translator, scopes []string, expiresAt *time.Time) string {
+	var lines []string
+	if len(scopes) > 0 {
+		text, _ := tr.GetString("approvals_scopes")
+		lines = append(lines, fmt.Sprintf(text, strings.Join(scopes, ", ")))
+	}
+	if expiresAt != nil {
+		text, _ := tr.GetString("approvals_expires")
+		lines = append(lines, fmt.Sprintf(text, formatApprovalExpiry(*expiresAt)))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// formatApprovalExpiry formats an approval expiry timestamp for display.
+func formatApprovalExpiry(t time.Time) string {
+	return t.UTC().Format("2006-01-02 15:04 UTC")
+}
+
 // extractDisplayName returns a display name for a user ID by looking up in DB.
 // Falls back to the raw numeric ID if not found in database.
 func extractDisplayName(userID int64) string {
@@ -573,11 +689,11 @@ func extractDisplayName(userID int64) string {
 func LoadApprovals(dispatcher *ext.Dispatcher) {
 	DefaultHelpRegistry().AbleMap[approvalsModule.moduleName] = true
 
-	dispatcher.AddHandler(handlers.NewCommand("approve", approvalsModule.approveUser))
-	dispatcher.AddHandler(handlers.NewCommand("unapprove", approvalsModule.unapproveUser))
-	dispatcher.AddHandler(handlers.NewCommand("approval", approvalsModule.checkApprovalStatus))
-	dispatcher.AddHandler(handlers.NewCommand("approved", approvalsModule.listApprovedUsers))
-	dispatcher.AddHandler(handlers.NewCommand("unapproveall", approvalsModule.unapproveAllHandler))
+	helpers.AddCommand(dispatcher, "approve", approvalsModule.approveUser)
+	helpers.AddCommand(dispatcher, "unapprove", approvalsModule.unapproveUser)
+	helpers.AddCommand(dispatcher, "approval", approvalsModule.checkApprovalStatus)
+	helpers.AddCommand(dispatcher, "approved", approvalsModule.listApprovedUsers)
+	helpers.AddCommand(dispatcher, "unapproveall", approvalsModule.unapproveAllHandler)
 
 	dispatcher.AddHandler(handlers.NewCallback(callbackquery.Prefix("rmAllApprovals"), approvalsModule.unapproveAllCallback))
 }
@@ -4,6 +4,7 @@ import (
 	"errors"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/PaulSonOfLars/gotgbot/v2"
 	"github.com/PaulSonOfLars/gotgbot/v2/ext"
@@ -88,6 +89,25 @@ func TestApprovalCommandsHandleMissingAndDuplicateUsers(t *testing.T) {
 	}
 }
 
+func TestPlainApproveUpgradesScopedApproval(t *testing.T) {
+	client := newModuleBotClient()
+	bot := newModuleTestBot(client)
+	chat := gotgbot.Chat{Id: uniqueModuleChatID(), Type: "supergroup", Title: "Approval Chat"}
+	admin := gotgbot.User{Id: 777000, FirstName: "Telegram"}
+	expiry := time.Now().Add(time.Hour).UTC()
+	if err := approvals.SetApproval(chat.Id, 42, admin.Id, "", []string{approvals.ScopeLocks}, &expiry); err != nil {
+		t.Fatalf("SetApproval setup error = %v", err)
+	}
+
+	if err := approvalsModule.approveUser(bot, newModuleMessageContext(bot, chat, admin, "/approve 42")); err != ext.EndGroups {
+		t.Fatalf("approveUser error = %v, want EndGroups", err)
+	}
+	approval := approvals.GetApproval(chat.Id, 42)
+	if approval == nil || len(approval.Scopes) != 0 || approval.ExpiresAt != nil {
+		t.Fatalf("approval = %+v, want it upgraded to full and permanent", approval)
+	}
+}
+
 func TestApprovedListHandlesEmptyAndLargeLists(t *testing.T) {
 	client := newModuleBotClient()
 	bot := newModuleTestBot(client)
@@ -2,6 +2,7 @@ package modules
 
 import (
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -18,3 +19,38 @@ func TestExtractDisplayName(t *testing.T) {
 	name := extractDisplayName(99999999999)
 	assert.NotEmpty(t, name)
 }
+
+func TestParseApprovalArgs(t *testing.T) {
+	now := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+	week := now.Add(7 * 24 * time.Hour)
+	tests := []struct {
+		name    string
+		text    string
+		scopes  []string
+		expires *time.Time
+		reason  string
+		err     error
+	}{
+		{name: "reason only", text: "trusted helper", reason: "trusted helper"},
+		{name: "media spammer fixed", text: "media spammer fixed", scopes: []string{"media"}, reason: "spammer fixed"},
+		{name: "7d ban appeal", text: "7d ban appeal", expires: &week, reason: "ban appeal"},
+		{name: "scopes", text: "locks,blacklist", scopes: []string{"locks", "blacklist"}},
+		{name: "duration and scopes in either order", text: "7d Media trusted", scopes: []string{"media"}, expires: &week, reason: "trusted"},
+		{name: "second scope list is reason", text: "media locks", scopes: []string{"media"}, reason: "locks"},
+		{name: "unknown scope starts the reason", text: "media,nope trusted", reason: "media,nope trusted"},
+		{name: "options are aliases", text: "duration=7d Scopes=media trusted", scopes: []string{"media"}, expires: &week, reason: "trusted"},
+		{name: "options after the reason are reason", text: "trusted scopes=media", reason: "trusted scopes=media"},
+		{name: "unknown scope option", text: "scopes=media,nope", err: errInvalidApprovalScopes},
+		{name: "bad duration option", text: "duration=soon", err: errInvalidApprovalDuration},
+		{name: "option overrides a positional duration", text: "1d duration=7d", expires: &week},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			scopes, expires, reason, err := parseApprovalArgs(tt.text, now)
+			assert.ErrorIs(t, err, tt.err)
+			assert.Equal(t, tt.scopes, scopes)
+			assert.Equal(t, tt.expires, expires)
+			assert.Equal(t, tt.reason, reason)
+		})
+	}
+}
@@ -920,9 +920,9 @@ func LoadBackup(dispatcher *ext.Dispatcher) {
 	DefaultHelpRegistry().AbleMap[backupModule.moduleName] = true
 
 	// Register command handlers
-	dispatcher.AddHandler(handlers.NewCommand("export", backupModule.exportHandler))
-	dispatcher.AddHandler(handlers.NewCommand("import", backupModule.importHandler))
-	dispatcher.AddHandler(handlers.NewCommand("reset", backupModule.resetHandler))
+	helpers.AddCommand(dispatcher, "export", backupModule.exportHandler)
+	helpers.AddCommand(dispatcher, "import", backupModule.importHandler)
+	helpers.AddCommand(dispatcher, "reset", backupModule.resetHandler)
 
 	// Register callback query handlers
 	dispatcher.AddHandler(handlers.NewCallback(
@@ -656,7 +656,7 @@ func TestExportHandlerSendsRequestedModuleBackupDocument(t *testing.T) {
 	chat := gotgbot.Chat{Id: uniqueModuleChatID(), Type: "supergroup", Title: "Backup Chat"}
 	owner := gotgbot.User{Id: 777000, FirstName: "Telegram"}
 	require.NoError(t, chats.EnsureChatInDb(chat.Id, chat.Title))
-	require.NoError(t, notes.AddNote(chat.Id, "welcome", "hello", "", nil, db.TEXT, false, false, false, true, false, false))
+	require.NoError(t, notes.AddNote(chat.Id, "welcome", "", "hello", "", nil, nil, db.TEXT, false, false, false, true, false, false))
 
 	ctx := newModuleMessageContext(bot, chat, owner, "/export notes invalid notes")
 	err := backupModule.exportHandler(bot, ctx)
@@ -672,7 +672,7 @@ func TestExportHandlerFallsBackToTextWhenDocumentSendFails(t *testing.T) {
 	chat := gotgbot.Chat{Id: uniqueModuleChatID(), Type: "supergroup", Title: "Backup Chat"}
 	owner := gotgbot.User{Id: 777000, FirstName: "Telegram"}
 	require.NoError(t, chats.EnsureChatInDb(chat.Id, chat.Title))
-	require.NoError(t, notes.AddNote(chat.Id, "fallback", "hello", "", nil, db.TEXT, false, false, false, true, false, false))
+	require.NoError(t, notes.AddNote(chat.Id, "fallback", "", "hello", "", nil, nil, db.TEXT, false, false, false, true, false, false))
 
 	ctx := newModuleMessageContext(bot, chat, owner, "/export notes")
 	err := backupModule.exportHandler(bot, ctx)
@@ -12,6 +12,7 @@ import (
 	"github.com/divkix/Alita_Robot/alita/db/lang"
 	"github.com/divkix/Alita_Robot/alita/i18n"
 	"github.com/divkix/Alita_Robot/alita/utils/formatting"
+	"github.com/divkix/Alita_Robot/alita/utils/helpers"
 
 	"github.com/PaulSonOfLars/gotgbot/v2"
 	"github.com/PaulSonOfLars/gotgbot/v2/ext"
@@ -27,6 +28,24 @@ func kickMember(b *gotgbot.Bot, chatID, userID int64) error {
 	return err
 }
 
+// kickTarget kicks the target out of the chat. It is the kick used by every
+// kick command and by reaction moderation.
+func kickTarget(c *moderationCtx, t *target) error {
+	return kickMember(c.Bot, c.Chat.Id, t.userID)
+}
+
+// banTarget bans the target until t.until, or for good when it is 0.
+// Anonymous channels are banned as sender chats. It is the ban used by every
+// ban command and by reaction moderation.
+func banTarget(c *moderationCtx, t *target) error {
+	if t.isChannel {
+		_, err := c.Bot.BanChatSenderChat(c.Chat.Id, t.userID, nil)
+		return err
+	}
+	_, err := c.Chat.BanMember(c.Bot, t.userID, &gotgbot.BanChatMemberOpts{UntilDate: t.until})
+	return err
+}
+
 /* Used to Kick a user from group
 
 The Bot, Kicker should be admin with ban permissions in order to use this */
@@ -145,12 +164,12 @@ func moderationDkick(m *moduleStruct) *moderationCommand {
 		extract:  extractFromReply,
 		validate: kickTargetValidation,
 		execute: func(c *moderationCtx, t *target) error {
-			_, err := c.Msg.ReplyToMessage.Delete(c.Bot, nil)
+			err := helpers.DeleteMessagesBatched(c.Bot, c.Chat.Id, []int64{c.Msg.ReplyToMessage.MessageId}, nil)
 			if err != nil {
 				log.Error(err)
 				return err
 			}
-			return kickMember(c.Bot, c.Chat.Id, t.userID)
+			return kickTarget(c, t)
 		},
 		reply: kickReply,
 	}
@@ -170,8 +189,8 @@ func moderationTban(m *moduleStruct) *moderationCommand {
 			}
 			t.timeVal = timeVal
 			t.reason = reason
-			_, err := c.Chat.BanMember(c.Bot, t.userID, &gotgbot.BanChatMemberOpts{UntilDate: _time})
-			return err
+			t.until = _time
+			return banTarget(c, t)
 		},
 		reply: func(c *moderationCtx, t *target) error {
 			banUser, err := c.Bot.GetChat(t.userID, nil)
@@ -214,7 +233,7 @@ func banReplyWithButton(c *moderationCtx, t *target) error {
 		baseStr += fmt.Sprintf(temp, t.reason)
 	}
 
-	text := fmt.Sprintf(baseStr, formatting.MentionHtml(banUser.Id, banUser.FirstName))
+	text := fmt.Sprintf(baseStr, formatting.MentionHtml(banUser.Id, banUser.FirstName)) + historyDeletedText(c, t)
 
 	_, err = c.Msg.Reply(c.Bot, text,
 		&gotgbot.SendMessageOpts{
@@ -259,7 +278,11 @@ func moderationBan(m *moduleStruct) *moderationCommand {
 				}
 				return target{}, fmt.Errorf("no user")
 			}
-			return target{userID: uid, reason: reason, isChannel: chat_status.IsChannelId(uid)}, nil
+			reason, history := takeFlag(reason, "--history")
+			if history && !canDeleteGates(c) {
+				return target{}, fmt.Errorf("cannot delete history")
+			}
+			return target{userID: uid, reason: reason, isChannel: chat_status.IsChannelId(uid), history: history}, nil
 		},
 		validate: func(c *moderationCtx, t *target) error {
 			if t.isChannel {
@@ -271,13 +294,24 @@ func moderationBan(m *moduleStruct) *moderationCommand {
 			if t.isChannel {
 				if c.Msg.ReplyToMessage != nil {
 					t.userID = c.Msg.ReplyToMessage.GetSender().Id()
-					_, err := c.Bot.BanChatSenderChat(c.Chat.Id, t.userID, nil)
-					return err
+					if err := banTarget(c, t); err != nil {
+						return err
+					}
+					if t.history {
+						deleteHistory(c, t)
+					}
+					return nil
 				}
+				t.history = false
 				return nil
 			}
-			_, err := c.Chat.BanMember(c.Bot, t.userID, nil)
-			return err
+			if err := banTarget(c, t); err != nil {
+				return err
+			}
+			if t.history {
+				deleteHistory(c, t)
+			}
+			return nil
 		},
 		reply: func(c *moderationCtx, t *target) error {
 			var text string
@@ -286,7 +320,7 @@ func moderationBan(m *moduleStruct) *moderationCommand {
 			if t.isChannel {
 				if c.Msg.ReplyToMessage != nil {
 					temp, _ := c.Tr.GetString("bans_anonymous_ban_user")
-					text = fmt.Sprintf(temp, formatting.MentionHtml(t.userID, c.Msg.ReplyToMessage.GetSender().Name()))
+					text = fmt.Sprintf(temp, formatting.MentionHtml(t.userID, c.Msg.ReplyToMessage.GetSender().Name())) + historyDeletedText(c, t)
 				} else {
 					text, _ = c.Tr.GetString("bans_anonymous_ban_reply_only")
 				}
@@ -312,10 +346,8 @@ func moderationKick(m *moduleStruct) *moderationCommand {
 		gates:    []gateFn{standardModGates},
 		extract:  extractFromArgs,
 		validate: kickTargetValidation,
-		execute: func(c *moderationCtx, t *target) error {
-			return kickMember(c.Bot, c.Chat.Id, t.userID)
-		},
-		reply: kickReply,
+		execute:  kickTarget,
+		reply:    kickReply,
 	}
 }
 
@@ -349,9 +381,7 @@ func moderationKickme(m *moduleStruct) *moderationCommand {
 			}
 			return target{userID: c.User.Id}, nil
 		},
-		execute: func(c *moderationCtx, t *target) error {
-			return kickMember(c.Bot, c.Chat.Id, t.userID)
-		},
+		execute: kickTarget,
 		reply: func(c *moderationCtx, t *target) error {
 			text, _ := c.Tr.GetString(strings.ToLower(c.Module.moduleName) + "_kickme_ok_out")
 			_, err := c.Msg.Reply(c.Bot, text, formatting.Shtml())
@@ -372,11 +402,10 @@ func moderationSban(m *moduleStruct) *moderationCommand {
 		extract:  extractUserOnly,
 		validate: banTargetValidation,
 		execute: func(c *moderationCtx, t *target) error {
-			_, err := c.Chat.BanMember(c.Bot, t.userID, nil)
-			if err != nil {
+			if err := banTarget(c, t); err != nil {
 				return err
 			}
-			_, err = c.Msg.Delete(c.Bot, nil)
+			_, err := c.Msg.Delete(c.Bot, nil)
 			return err
 		},
 		reply: nil,
@@ -405,13 +434,12 @@ func moderationDban(m *moduleStruct) *moderationCommand {
 			return banTargetValidation(c, t)
 		},
 		execute: func(c *moderationCtx, t *target) error {
-			_, err := c.Msg.ReplyToMessage.Delete(c.Bot, nil)
+			err := helpers.DeleteMessagesBatched(c.Bot, c.Chat.Id, []int64{c.Msg.ReplyToMessage.MessageId}, nil)
 			if err != nil {
 				log.Error(err)
 				return err
 			}
-			_, err = c.Chat.BanMember(c.Bot, t.userID, nil)
-			return err
+			return banTarget(c, t)
 		},
 		reply: banReplyWithButton,
 	}
@@ -1022,21 +1050,21 @@ func LoadBans(dispatcher *ext.Dispatcher) {
 	DefaultHelpRegistry().AbleMap[bansModule.moduleName] = true
 
 	// ban cmds
-	dispatcher.AddHandler(handlers.NewCommand("ban", bansModule.ban))
-	dispatcher.AddHandler(handlers.NewCommand("sban", bansModule.sBan))
-	dispatcher.AddHandler(handlers.NewCommand("tban", bansModule.tBan))
-	dispatcher.AddHandler(handlers.NewCommand("dban", bansModule.dBan))
-	dispatcher.AddHandler(handlers.NewCommand("unban", bansModule.unban))
+	helpers.AddCommand(dispatcher, "ban", bansModule.ban)
+	helpers.AddCommand(dispatcher, "sban", bansModule.sBan)
+	helpers.AddCommand(dispatcher, "tban", bansModule.tBan)
+	helpers.AddCommand(dispatcher, "dban", bansModule.dBan)
+	helpers.AddCommand(dispatcher, "unban", bansModule.unban)
 
 	// kick cmds
-	dispatcher.AddHandler(handlers.NewCommand("kick", bansModule.kick))
-	dispatcher.AddHandler(handlers.NewCommand("dkick", bansModule.dkick))
-	dispatcher.AddHandler(handlers.NewCommand("kickme", bansModule.kickme))
+	helpers.AddCommand(dispatcher, "kick", bansModule.kick)
+	helpers.AddCommand(dispatcher, "dkick", bansModule.dkick)
+	helpers.AddCommand(dispatcher, "kickme", bansModule.kickme)
 
 	// special commands
-	dispatcher.AddHandler(handlers.NewCommand("restrict", bansModule.restrict))
+	helpers.AddCommand(dispatcher, "restrict", bansModule.restrict)
 	dispatcher.AddHandler(handlers.NewCallback(callbackquery.Prefix("restrict"), bansModule.restrictButtonHandler))
-	dispatcher.AddHandler(handlers.NewCommand("unrestrict", bansModule.unrestrict))
+	helpers.AddCommand(dispatcher, "unrestrict", bansModule.unrestrict)
 	dispatcher.AddHandler(handlers.NewCallback(callbackquery.Prefix("unrestrict"), bansModule.unrestrictButtonHandler))
 }
 
@@ -12,6 +12,7 @@ import (
 	"github.com/PaulSonOfLars/gotgbot/v2/ext/handlers/filters/callbackquery"
 	log "github.com/sirupsen/logrus"
 
+	"github.com/divkix/Alita_Robot/alita/db/approvals"
 	"github.com/divkix/Alita_Robot/alita/db/blacklists"
 	"github.com/divkix/Alita_Robot/alita/db/lang"
 	"github.com/divkix/Alita_Robot/alita/i18n"
@@ -512,6 +513,19 @@ func (m moduleStruct) buttonHandler(b *gotgbot.Bot, ctx *ext.Context) error {
 	return ext.EndGroups
 }
 
+// blacklistedTrigger returns the first of a chat's blacklist triggers that
+// the text or caption of a message contain, links included.
+func blacklistedTrigger(chatID int64, triggers []string, msg *gotgbot.Message) (string, bool) {
+	matchText := buildModerationMatchText(msg)
+	if matchText == "" {
+		return "", false
+	}
+
+	// Use Aho-Corasick for efficient multi-pattern matching
+	matcher := keyword_matcher.GetNamedCache("blacklists").GetOrCreateMatcher(chatID, triggers)
+	return matcher.FirstMatch(matchText)
+}
+
 /*
 	Blacklist watcher
 
@@ -541,7 +555,7 @@ func (m moduleStruct) blacklistWatcher(b *gotgbot.Bot, ctx *ext.Context) error {
 	if !user.IsAnonymousChannel() && user.IsUser() && user.Id() > 0 && chat_status.IsUserAdmin(b, chat.Id, user.Id()) {
 		return ext.ContinueGroups
 	}
-	if !user.IsAnonymousChannel() && user.IsUser() && user.Id() > 0 && chat_status.IsApproved(b, chat.Id, user.Id()) {
+	if !user.IsAnonymousChannel() && user.IsUser() && user.Id() > 0 && chat_status.IsApprovedFor(b, chat.Id, user.Id(), approvals.ScopeBlacklist) {
 		return ext.ContinueGroups
 	}
 
@@ -552,107 +566,16 @@ func (m moduleStruct) blacklistWatcher(b *gotgbot.Bot, ctx *ext.Context) error {
 	}
 
 	msg := ctx.EffectiveMessage
-	matchText := buildModerationMatchText(msg)
-	if matchText == "" {
-		return ext.ContinueGroups
-	}
-	tr := i18n.MustNewTranslator(lang.GetLanguage(ctx))
-
-	// Use Aho-Corasick for efficient multi-pattern matching
-	cache := keyword_matcher.GetNamedCache("blacklists")
-	matcher := cache.GetOrCreateMatcher(chat.Id, triggers)
-
-	// Find first matching blacklist trigger using optimized path
-	firstPattern, found := matcher.FirstMatch(matchText)
+	i, found := blacklistedTrigger(chat.Id, triggers, msg)
 	if !found {
 		return ext.ContinueGroups
 	}
-	i := firstPattern
 
 	_ = helpers.DeleteMessageWithErrorHandling(b, chat.Id, msg.MessageId)
-	var err error
-	switch blSettings.Action() {
-	case "mute":
-		// don't work on anonymous channels
-		if user.IsAnonymousChannel() {
-			return ext.ContinueGroups
-		}
-
-		_, err = b.RestrictChatMember(chat.Id, user.Id(), gotgbot.ChatPermissions{CanSendMessages: false}, nil)
-		if err != nil {
-			log.Error(err)
-			return err
-		}
-
-		_, err = b.SendMessage(chat.Id,
-			func() string {
-				temp, _ := tr.GetString(strings.ToLower(m.moduleName) + "_bl_watcher_muted_user")
-				return fmt.Sprintf(temp, formatting.MentionHtml(user.Id(), user.Name()), fmt.Sprintf(blSettings.Reason(), i))
-			}(),
-			formatting.Shtml())
-		if err != nil {
-			log.Error(err)
-			return err
-		}
-	case "ban":
-		// ban anonymous channels as well
-		if user.IsAnonymousChannel() {
-			_, err = b.BanChatSenderChat(chat.Id, user.Id(), nil)
-		} else {
-			_, err = b.BanChatMember(chat.Id, user.Id(), nil)
-		}
-		if err != nil {
-			log.Error(err)
-			return err
-		}
-
-		_, err = b.SendMessage(chat.Id,
-			func() string {
-				temp, _ := tr.GetString(strings.ToLower(m.moduleName) + "_bl_watcher_banned_user")
-				return fmt.Sprintf(temp, formatting.MentionHtml(user.Id(), user.Name()), fmt.Sprintf(blSettings.Reason(), i))
-			}(),
-			formatting.Shtml())
-		if err != nil {
-			log.Error(err)
-			return err
-		}
-	case "kick":
-		// don't work on anonymous channels
-		if user.IsAnonymousChannel() {
-			return ext.ContinueGroups
-		}
-
-		if err = kickMember(b, chat.Id, user.Id()); err != nil {
-			log.Error(err)
-			return err
-		}
-
-		_, err = b.SendMessage(chat.Id,
-			func() string {
-				temp, _ := tr.GetString(strings.ToLower(m.moduleName) + "_bl_watcher_kicked_user")
-				return fmt.Sprintf(temp, formatting.MentionHtml(user.Id(), user.Name()), fmt.Sprintf(blSettings.Reason(), i))
-			}(),
-			formatting.Shtml())
-		if err != nil {
-			log.Error(err)
-			return err
-		}
-	case "warn":
-		// don't work on anonymous channels
-		if user.IsAnonymousChannel() {
-			return ext.ContinueGroups
-		}
-
-		err = warnsModule.warnThisUser(b, ctx, user.Id(), fmt.Sprintf(blSettings.Reason(), i), "warn")
-		if err != nil {
-			log.Error(err)
-			return err
-		}
-	case "none":
-		// Message already deleted, no further action needed
-		return ext.ContinueGroups
+	reason := fmt.Sprintf(blSettings.Reason(), i)
+	if err := applyModerationAction(b, ctx, blSettings.Action(), reason, strings.ToLower(m.moduleName)+"_bl_watcher_", nil); err != nil {
+		return err
 	}
-
 	return ext.ContinueGroups
 }
 
@@ -661,13 +584,13 @@ func (m moduleStruct) blacklistWatcher(b *gotgbot.Bot, ctx *ext.Context) error {
 func LoadBlacklists(dispatcher *ext.Dispatcher) {
 	DefaultHelpRegistry().AbleMap[blacklistsModule.moduleName] = true
 
-	dispatcher.AddHandler(handlers.NewCommand("blacklists", blacklistsModule.listBlacklists))
+	helpers.AddCommand(dispatcher, "blacklists", blacklistsModule.listBlacklists)
 	helpers.AddCmdToDisableable("blacklists")
-	dispatcher.AddHandler(handlers.NewCommand("addblacklist", blacklistsModule.addBlacklist))
-	dispatcher.AddHandler(handlers.NewCommand("blacklist", blacklistsModule.addBlacklist))
-	dispatcher.AddHandler(handlers.NewCommand("rmblacklist", blacklistsModule.removeBlacklist))
-	dispatcher.AddHandler(handlers.NewCommand("blaction", blacklistsModule.setBlacklistAction))
-	dispatcher.AddHandler(handlers.NewCommand("blacklistaction", blacklistsModule.setBlacklistAction))
+	helpers.AddCommand(dispatcher, "addblacklist", blacklistsModule.addBlacklist)
+	helpers.AddCommand(dispatcher, "blacklist", blacklistsModule.addBlacklist)
+	helpers.AddCommand(dispatcher, "rmblacklist", blacklistsModule.removeBlacklist)
+	helpers.AddCommand(dispatcher, "blaction", blacklistsModule.setBlacklistAction)
+	helpers.AddCommand(dispatcher, "blacklistaction", blacklistsModule.setBlacklistAction)
 	helpers.MultiCommand(dispatcher, []string{"remallbl", "rmallbl"}, blacklistsModule.rmAllBlacklists)
 	dispatcher.AddHandler(handlers.NewCallback(callbackquery.Prefix("rmAllBlacklist"), blacklistsModule.buttonHandler))
 	dispatcher.AddHandlerToGroup(handlers.NewMessage(func(msg *gotgbot.Message) bool {
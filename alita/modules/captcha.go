@@ -20,9 +20,12 @@ import (
 	"github.com/PaulSonOfLars/gotgbot/v2/ext/handlers"
 	"github.com/PaulSonOfLars/gotgbot/v2/ext/handlers/filters/callbackquery"
 	"github.com/divkix/Alita_Robot/alita/db"
+	"github.com/divkix/Alita_Robot/alita/db/approvals"
 	"github.com/divkix/Alita_Robot/alita/db/captcha"
 	"github.com/divkix/Alita_Robot/alita/db/chats"
 	"github.com/divkix/Alita_Robot/alita/db/lang"
+	"github.com/divkix/Alita_Robot/alita/db/models"
+	"github.com/divkix/Alita_Robot/alita/db/rules"
 	"github.com/divkix/Alita_Robot/alita/db/user"
 	"github.com/divkix/Alita_Robot/alita/i18n"
 	"github.com/divkix/Alita_Robot/alita/utils/cache"
@@ -31,6 +34,7 @@ import (
 	"github.com/divkix/Alita_Robot/alita/utils/extraction"
 	"github.com/divkix/Alita_Robot/alita/utils/formatting"
 	"github.com/divkix/Alita_Robot/alita/utils/helpers"
+	"github.com/divkix/Alita_Robot/alita/utils/monitoring"
 	"github.com/eko/gocache/lib/v4/store"
 	"github.com/mojocn/base64Captcha"
 	log "github.com/sirupsen/logrus"
@@ -617,7 +621,7 @@ func disableCaptchaForChat(bot *gotgbot.Bot, chatID int64) error {
 }
 
 // captchaModeCommand handles the /captchamode command to set captcha type.
-// Admins can choose between math and text captcha modes.
+// Admins can choose between the math, text, emoji and custom captcha modes.
 func (moduleStruct) captchaModeCommand(bot *gotgbot.Bot, ctx *ext.Context) error {
 	msg := ctx.EffectiveMessage
 	chat := ctx.EffectiveChat
@@ -634,7 +638,7 @@ func (moduleStruct) captchaModeCommand(bot *gotgbot.Bot, ctx *ext.Context) error
 	}
 
 	mode := strings.ToLower(args[0])
-	if mode != "math" && mode != "text" {
+	if !slices.Contains(models.CaptchaModes, mode) {
 		tr := i18n.MustNewTranslator(lang.GetLanguage(ctx))
 		text, _ := tr.GetString("captcha_mode_invalid")
 		_, err := msg.Reply(bot, text, formatting.Shtml())
@@ -657,13 +661,18 @@ func (moduleStruct) captchaModeCommand(bot *gotgbot.Bot, ctx *ext.Context) error
 	}
 
 	tr := i18n.MustNewTranslator(lang.GetLanguage(ctx))
-	modeDesc, _ := tr.GetString("captcha_mode_math_desc")
-	if mode == "text" {
-		modeDesc, _ = tr.GetString("captcha_mode_text_desc")
-	}
+	modeDesc, _ := tr.GetString("captcha_mode_" + mode + "_desc")
 
 	textTemplate, _ := tr.GetString("captcha_mode_set_formatted")
 	text := fmt.Sprintf(textTemplate, mode, modeDesc)
+	if mode == "custom" && len(captcha.GetCaptchaQuestions(chat.Id)) == 0 {
+		hint, _ := tr.GetString("captcha_mode_custom_no_questions")
+		text += "\n\n" + hint
+	}
+	if mode == "rules" && normalizeRulesForHTML(rules.GetChatRulesInfo(chat.Id).Rules) == "" {
+		hint, _ := tr.GetString("captcha_mode_rules_no_rules")
+		text += "\n\n" + hint
+	}
 	_, err = msg.Reply(bot, text, formatting.Shtml())
 	return err
 }
@@ -1013,12 +1022,84 @@ func generateMathImageCaptcha() (string, []byte, []string, error) {
 	return answer, imageBytes, options, nil
 }
 
+// captchaChallenge is one generated captcha: what the new member is shown
+// and the answer buttons they pick from.
+type captchaChallenge struct {
+	mode     string   // mode the challenge was made in; math when the chat's mode fell back
+	question string   // shown in the message text when there is no image
+	answer   string   // option that solves the challenge
+	image    []byte   // rendered challenge, nil for text-only challenges
+	options  []string // callback values of the answer buttons
+	labels   []string // button texts; the options themselves when nil
+	columns  int      // answer buttons per row
+
+	rules       string // chat rules quoted by a rules challenge
+	rulesURL    string // deep link to the rules when they are not quoted
+	rulesButton string // label of the rulesURL button
+}
+
+// generateCaptchaChallenge creates a challenge in the given captcha mode. A
+// mode whose image cannot be rendered, a custom mode without questions or a
+// rules mode without rules falls back to a math image and then to a plain
+// math question.
+func generateCaptchaChallenge(bot *gotgbot.Bot, chatID int64, mode string) (*captchaChallenge, error) {
+	switch mode {
+	case "text":
+		answer, imageBytes, options, err := generateTextCaptcha()
+		if err == nil && imageBytes != nil {
+			return &captchaChallenge{mode: "text", answer: answer, image: imageBytes, options: options, columns: 1}, nil
+		}
+		log.Errorf("Failed to generate text captcha: %v", err)
+	case "emoji":
+		answer, imageBytes, options, err := generateEmojiCaptcha()
+		if err == nil && imageBytes != nil {
+			return &captchaChallenge{mode: "emoji", answer: answer, image: imageBytes, options: options, columns: captchaEmojiColumns}, nil
+		}
+		log.Errorf("Failed to generate emoji captcha: %v", err)
+	case "custom":
+		challenge, err := generateCustomCaptcha(chatID)
+		if err == nil {
+			return challenge, nil
+		}
+		log.Debugf("[Captcha] No custom captcha for chat %d, using math: %v", chatID, err)
+	case "rules":
+		challenge, err := generateRulesCaptcha(bot, chatID)
+		if err == nil {
+			return challenge, nil
+		}
+		log.Debugf("[Captcha] No rules captcha for chat %d, using math: %v", chatID, err)
+	}
+
+	answer, imageBytes, options, err := generateMathImageCaptcha()
+	if err == nil && imageBytes != nil {
+		return &captchaChallenge{mode: "math", answer: answer, image: imageBytes, options: options, columns: 1}, nil
+	}
+	log.Errorf("Failed to generate math image captcha: %v", err)
+	// Fallback to text-based math question (fail-closed on entropy error)
+	question, answer, options, err := generateMathCaptcha()
+	if err != nil {
+		log.Errorf("Failed to generate fallback math captcha: %v", err)
+		return nil, fmt.Errorf("generate captcha: %w", err)
+	}
+	return &captchaChallenge{mode: "math", question: question, answer: answer, options: options, columns: 1}, nil
+}
+
 // buildCaptchaKeyboard builds the inline keyboard for a captcha challenge:
-// one button per answer option (captcha_verify) and, when includeRefresh is
-// set, a trailing refresh button (captcha_refresh) labelled refreshBtnText.
-func buildCaptchaKeyboard(attemptID uint, userID int64, refreshCount int, options []string, includeRefresh bool, refreshBtnText string) gotgbot.InlineKeyboardMarkup {
+// a leading link button when the challenge links its rules, one button per
+// answer option (captcha_verify), challenge.columns to a row, and, when
+// includeRefresh is set, a trailing refresh button (captcha_refresh)
+// labelled refreshBtnText.
+func buildCaptchaKeyboard(attemptID uint, userID int64, refreshCount int, challenge *captchaChallenge, includeRefresh bool, refreshBtnText string) gotgbot.InlineKeyboardMarkup {
+	columns := max(challenge.columns, 1)
 	var buttons [][]gotgbot.InlineKeyboardButton
-	for _, option := range options {
+	if challenge.rulesURL != "" {
+		buttons = append(buttons, []gotgbot.InlineKeyboardButton{{
+			Text: challenge.rulesButton,
+			Url:  challenge.rulesURL,
+		}})
+	}
+	var row []gotgbot.InlineKeyboardButton
+	for i, option := range challenge.options {
 		data, ok := mustCallbackData(
 			"captcha_verify",
 			map[string]string{
@@ -1035,11 +1116,21 @@ func buildCaptchaKeyboard(attemptID uint, userID int64, refreshCount int, option
 			}).Warn("[Captcha] Failed to encode verify button, omitting")
 			continue
 		}
-		button := gotgbot.InlineKeyboardButton{
-			Text:         option,
+		label := option
+		if i < len(challenge.labels) {
+			label = challenge.labels[i]
+		}
+		row = append(row, gotgbot.InlineKeyboardButton{
+			Text:         label,
 			CallbackData: data,
+		})
+		if len(row) == columns {
+			buttons = append(buttons, row)
+			row = nil
 		}
-		buttons = append(buttons, []gotgbot.InlineKeyboardButton{button})
+	}
+	if len(row) > 0 {
+		buttons = append(buttons, row)
 	}
 	if includeRefresh {
 		if data, ok := mustCallbackData(
@@ -1061,6 +1152,37 @@ func buildCaptchaKeyboard(attemptID uint, userID int64, refreshCount int, option
 	return gotgbot.InlineKeyboardMarkup{InlineKeyboard: buttons}
 }
 
+// captchaWelcomeText returns the text sent with a new challenge for the
+// member mentioned by mention, who has timeout minutes to answer.
+func captchaWelcomeText(tr *i18n.Translator, challenge *captchaChallenge, mention string, timeout int) string {
+	params := i18n.TranslationParams{
+		"first":  mention,
+		"number": timeout,
+	}
+	key := "captcha_welcome_math_image"
+	switch {
+	case challenge.mode == "custom":
+		key = "captcha_welcome_custom"
+		params["question"] = html.EscapeString(challenge.question)
+	case challenge.mode == "rules" && challenge.rulesURL != "":
+		key = "captcha_welcome_rules_link"
+	case challenge.mode == "rules":
+		key = "captcha_welcome_rules"
+		// Passed as question so the legacy %s placeholders keep their order.
+		params["question"] = challenge.rules
+	case challenge.image == nil:
+		// Text-based fallback for math
+		key = "captcha_welcome_math_text"
+		params["question"] = challenge.question
+	case challenge.mode == "text":
+		key = "captcha_welcome_text_image"
+	case challenge.mode == "emoji":
+		key = "captcha_welcome_emoji_image"
+	}
+	text, _ := tr.GetString(key, params)
+	return text
+}
+
 // SendCaptcha sends a captcha challenge to a new member.
 // Called when a new member joins a group with captcha enabled.
 //
@@ -1091,46 +1213,9 @@ func SendCaptcha(bot *gotgbot.Bot, ctx *ext.Context, userID int64, userName stri
 		return errCaptchaDisabled
 	}
 
-	var question string
-	var answer string
-	var options []string
-	var imageBytes []byte
-	isImage := false
-
-	if settings.CaptchaMode == "math" {
-		// Prefer image captcha for math mode
-		var err error
-		answer, imageBytes, options, err = generateMathImageCaptcha()
-		if err != nil || imageBytes == nil {
-			log.Errorf("Failed to generate math image captcha: %v", err)
-			// Fallback to text-based math question (fail-closed on entropy error)
-			var fbErr error
-			question, answer, options, fbErr = generateMathCaptcha()
-			if fbErr != nil {
-				log.Errorf("Failed to generate fallback math captcha: %v", fbErr)
-				return fmt.Errorf("generate captcha: %w", fbErr)
-			}
-			isImage = false
-		} else {
-			isImage = true
-		}
-	} else {
-		// Text mode: image captcha with text content
-		var err error
-		answer, imageBytes, options, err = generateTextCaptcha()
-		if err != nil {
-			log.Errorf("Failed to generate text captcha: %v", err)
-			// Fallback to text-based math question (fail-closed on entropy error)
-			var fbErr error
-			question, answer, options, fbErr = generateMathCaptcha()
-			if fbErr != nil {
-				log.Errorf("Failed to generate fallback math captcha: %v", fbErr)
-				return fmt.Errorf("generate captcha: %w", fbErr)
-			}
-			isImage = false
-		} else {
-			isImage = true
-		}
+	challenge, err := generateCaptchaChallenge(bot, chat.Id, settings.CaptchaMode)
+	if err != nil {
+		return err
 	}
 
 	// Validate user and chat exist in Telegram before creating DB records
@@ -1173,7 +1258,7 @@ func SendCaptcha(bot *gotgbot.Bot, ctx *ext.Context, userID int64, userName stri
 		return err
 	}
 
-	preAttempt, err = captcha.CreateCaptchaAttemptPreMessageIfEnabled(userID, chat.Id, answer, settings.Timeout)
+	preAttempt, err = captcha.CreateCaptchaAttemptPreMessageIfEnabled(userID, chat.Id, challenge.answer, settings.Timeout)
 	if err != nil || preAttempt == nil {
 		if errors.Is(err, captcha.ErrCaptchaDisabled) {
 			return errCaptchaDisabled
@@ -1192,16 +1277,27 @@ func SendCaptcha(bot *gotgbot.Bot, ctx *ext.Context, userID int64, userName stri
 	muted = true
 
 	// Create inline keyboard with options including attempt ID.
-	// Add refresh button for image-based captcha (text or math) with attempt ID.
-	includeRefresh := isImage && imageBytes != nil
+	// Add refresh button for image-based captchas with attempt ID.
+	tr := i18n.MustNewTranslator(lang.GetLanguage(ctx))
+	includeRefresh := challenge.image != nil
 	refreshBtnText := ""
 	if includeRefresh {
-		tr := i18n.MustNewTranslator(lang.GetLanguage(ctx))
 		refreshBtnText, _ = tr.GetString("captcha_refresh_button")
 	}
-	keyboard := buildCaptchaKeyboard(preAttempt.ID, userID, preAttempt.RefreshCount, options, includeRefresh, refreshBtnText)
+	if challenge.mode == "rules" {
+		acceptBtnText, _ := tr.GetString("captcha_rules_accept_button")
+		challenge.labels = []string{acceptBtnText}
+	}
+	keyboard := buildCaptchaKeyboard(preAttempt.ID, userID, preAttempt.RefreshCount, challenge, includeRefresh, refreshBtnText)
 	// If no verify options could be encoded, fail before sending — rollback mute
-	verifyCount := len(keyboard.InlineKeyboard)
+	verifyCount := 0
+	for _, row := range keyboard.InlineKeyboard {
+		for _, button := range row {
+			if button.CallbackData != "" {
+				verifyCount++
+			}
+		}
+	}
 	if includeRefresh && verifyCount > 0 {
 		verifyCount--
 	}
@@ -1211,49 +1307,26 @@ func SendCaptcha(bot *gotgbot.Bot, ctx *ext.Context, userID int64, userName stri
 	}
 
 	// Prepare message text/caption
-	tr := i18n.MustNewTranslator(lang.GetLanguage(ctx))
-	var msgText string
-	if isImage {
-		if settings.CaptchaMode == "math" {
-			text, _ := tr.GetString("captcha_welcome_math_image", i18n.TranslationParams{
-				"first":  formatting.MentionHtml(userID, userName),
-				"number": settings.Timeout,
-			})
-			msgText = text
-		} else {
-			text, _ := tr.GetString("captcha_welcome_text_image", i18n.TranslationParams{
-				"first":  formatting.MentionHtml(userID, userName),
-				"number": settings.Timeout,
+	msgText := captchaWelcomeText(tr, challenge, formatting.MentionHtml(userID, userName), settings.Timeout)
+
+	// Send the captcha message, to the welcome topic if the chat has one
+	sent, err := sendToGreetingTopic(chat.Id, contextThread(ctx), func(threadID int64) (*gotgbot.Message, error) {
+		if challenge.image != nil {
+			// Send photo with the rendered challenge
+			return bot.SendPhoto(chat.Id, gotgbot.InputFileByReader("captcha.png", bytes.NewReader(challenge.image)), &gotgbot.SendPhotoOpts{
+				Caption:         msgText,
+				ParseMode:       formatting.HTML,
+				ReplyMarkup:     keyboard,
+				MessageThreadId: threadID,
 			})
-			msgText = text
 		}
-	} else {
-		// Text-based fallback for math
-		text, _ := tr.GetString("captcha_welcome_math_text", i18n.TranslationParams{
-			"first":    formatting.MentionHtml(userID, userName),
-			"question": question,
-			"number":   settings.Timeout,
-		})
-		msgText = text
-	}
-
-	// Send the captcha message
-	var sent *gotgbot.Message
-
-	if isImage && imageBytes != nil {
-		// Send photo with text captcha
-		sent, err = bot.SendPhoto(chat.Id, gotgbot.InputFileByReader("captcha.png", bytes.NewReader(imageBytes)), &gotgbot.SendPhotoOpts{
-			Caption:     msgText,
-			ParseMode:   formatting.HTML,
-			ReplyMarkup: keyboard,
-		})
-	} else {
-		// Send text message for math captcha
-		sent, err = helpers.SendMessageWithErrorHandling(bot, chat.Id, msgText, &gotgbot.SendMessageOpts{
-			ParseMode:   formatting.HTML,
-			ReplyMarkup: keyboard,
+		// Send text message for math fallback, custom questions and rules
+		return helpers.SendMessageWithErrorHandling(bot, chat.Id, msgText, &gotgbot.SendMessageOpts{
+			ParseMode:       formatting.HTML,
+			ReplyMarkup:     keyboard,
+			MessageThreadId: threadID,
 		})
-	}
+	})
 
 	if err != nil || sent == nil || sent.MessageId <= 0 {
 		if err == nil {
@@ -1318,7 +1391,7 @@ func scheduleCaptchaTimeout(bot *gotgbot.Bot, attempt *db.CaptchaAttempts) {
 }
 
 func expireCaptchaAttempt(bot *gotgbot.Bot, attempt *db.CaptchaAttempts) (bool, error) {
-	if chat_status.IsApproved(bot, attempt.ChatID, attempt.UserID) {
+	if chat_status.IsApprovedFor(bot, attempt.ChatID, attempt.UserID, approvals.ScopeCaptcha) {
 		if attempt.MessageID > 0 {
 			_ = helpers.DeleteMessageWithErrorHandling(bot, attempt.ChatID, attempt.MessageID)
 		}
@@ -1374,6 +1447,11 @@ func handleCaptchaTimeout(bot *gotgbot.Bot, chatID, userID int64, attemptID uint
 		log.Debugf("[Captcha] Timeout handler skipped - attempt already handled for attempt_id=%d", attemptID)
 		return false, false
 	}
+	outcome := models.CaptchaOutcomeTimeout
+	if settings, err := captcha.GetCaptchaSettings(chatID); err == nil && attempt.Attempts >= settings.MaxAttempts {
+		outcome = models.CaptchaOutcomeFailed
+	}
+	recordCaptchaOutcome(attempt, outcome)
 
 	// Delete the captcha message
 	messageID := attempt.MessageID
@@ -1416,7 +1494,12 @@ func handleCaptchaTimeout(bot *gotgbot.Bot, chatID, userID int64, attemptID uint
 
 	tr := i18n.MustNewTranslator(lang.GetLanguage(&ext.Context{EffectiveChat: &gotgbot.Chat{Id: chatID}}))
 	failureMsg := buildCaptchaFailureMessage(tr, action, userID, userName, storedMsgCount)
-	sent, err := helpers.SendMessageWithErrorHandling(bot, chatID, failureMsg, &gotgbot.SendMessageOpts{ParseMode: formatting.HTML})
+	sent, err := sendToGreetingTopic(chatID, 0, func(threadID int64) (*gotgbot.Message, error) {
+		return helpers.SendMessageWithErrorHandling(bot, chatID, failureMsg, &gotgbot.SendMessageOpts{
+			ParseMode:       formatting.HTML,
+			MessageThreadId: threadID,
+		})
+	})
 	if err != nil {
 		log.Errorf("Failed to send captcha failure message: %v", err)
 	}
@@ -1622,6 +1705,7 @@ func (moduleStruct) captchaVerifyCallback(bot *gotgbot.Bot, ctx *ext.Context) er
 			_, err = query.Answer(bot, &gotgbot.AnswerCallbackQueryOpts{Text: text})
 			return err
 		}
+		recordCaptchaOutcome(attempt, models.CaptchaOutcomeSolved)
 
 		if err = restoreCaptchaPermissions(bot, chat.Id, targetUserID); err != nil {
 			// The attempt is already claimed (single-winner), so the timeout
@@ -1673,7 +1757,12 @@ func (moduleStruct) captchaVerifyCallback(bot *gotgbot.Bot, ctx *ext.Context) er
 		tr := i18n.MustNewTranslator(lang.GetLanguage(ctx))
 		msgTemplate, _ := tr.GetString("greetings_captcha_verified_success")
 		successMsg := fmt.Sprintf(msgTemplate, formatting.MentionHtml(targetUserID, user.FirstName))
-		sent, _ := helpers.SendMessageWithErrorHandling(bot, chat.Id, successMsg, &gotgbot.SendMessageOpts{ParseMode: formatting.HTML})
+		sent, _ := sendToGreetingTopic(chat.Id, contextThread(ctx), func(threadID int64) (*gotgbot.Message, error) {
+			return helpers.SendMessageWithErrorHandling(bot, chat.Id, successMsg, &gotgbot.SendMessageOpts{
+				ParseMode:       formatting.HTML,
+				MessageThreadId: threadID,
+			})
+		})
 
 		// Delete success message after 5 seconds
 		if sent != nil {
@@ -1712,6 +1801,7 @@ func (moduleStruct) captchaVerifyCallback(bot *gotgbot.Bot, ctx *ext.Context) er
 			_, err = query.Answer(bot, &gotgbot.AnswerCallbackQueryOpts{Text: text})
 			return err
 		}
+		monitoring.RecordCaptchaWrongAnswer(settings.CaptchaMode)
 
 		if attempt.Attempts >= settings.MaxAttempts {
 			// Max attempts reached - execute failure action. handleCaptchaTimeout
@@ -1867,17 +1957,15 @@ func (moduleStruct) captchaRefreshCallback(bot *gotgbot.Bot, ctx *ext.Context) e
 		// Fall through with nil settings — the nil guard below handles it safely
 	}
 
-	// Generate a new image/options based on current mode
-	var newAnswer string
-	var imageBytes []byte
-	var options []string
-	var genErr error
-	if settings != nil && settings.CaptchaMode == "text" {
-		newAnswer, imageBytes, options, genErr = generateTextCaptcha()
-	} else {
-		newAnswer, imageBytes, options, genErr = generateMathImageCaptcha()
+	// Generate a new image/options based on current mode. Custom questions
+	// and rules have no image to refresh, so a chat switched to either
+	// meanwhile gets math.
+	mode := "math"
+	if settings != nil && settings.CaptchaMode != "custom" && settings.CaptchaMode != "rules" {
+		mode = settings.CaptchaMode
 	}
-	if genErr != nil || imageBytes == nil {
+	challenge, genErr := generateCaptchaChallenge(bot, chat.Id, mode)
+	if genErr != nil || challenge.image == nil {
 		tr := i18n.MustNewTranslator(lang.GetLanguage(ctx))
 		text, _ := tr.GetString("captcha_failed_generate")
 		_, err = query.Answer(bot, &gotgbot.AnswerCallbackQueryOpts{Text: text})
@@ -1887,33 +1975,34 @@ func (moduleStruct) captchaRefreshCallback(bot *gotgbot.Bot, ctx *ext.Context) e
 	// Build keyboard with new options and refresh button
 	tr := i18n.MustNewTranslator(lang.GetLanguage(ctx))
 	refreshBtnText, _ := tr.GetString("captcha_refresh_button")
-	keyboard := buildCaptchaKeyboard(attempt.ID, targetUserID, attempt.RefreshCount+1, options, true, refreshBtnText)
+	keyboard := buildCaptchaKeyboard(attempt.ID, targetUserID, attempt.RefreshCount+1, challenge, true, refreshBtnText)
 
 	// Build caption for the new message
 	remainingMinutes := int(time.Until(attempt.ExpiresAt).Minutes())
 	if remainingMinutes < 0 {
 		remainingMinutes = 0
 	}
-	var caption string
-	if settings != nil && settings.CaptchaMode == "text" {
-		template, _ := tr.GetString("captcha_welcome_text_detailed")
-		caption = fmt.Sprintf(
-			template,
-			formatting.MentionHtml(targetUserID, user.FirstName), remainingMinutes,
-		)
-	} else {
-		template, _ := tr.GetString("captcha_welcome_math_detailed")
-		caption = fmt.Sprintf(
-			template,
-			formatting.MentionHtml(targetUserID, user.FirstName), remainingMinutes,
-		)
-	}
+	templateKey := "captcha_welcome_math_detailed"
+	switch challenge.mode {
+	case "text":
+		templateKey = "captcha_welcome_text_detailed"
+	case "emoji":
+		templateKey = "captcha_welcome_emoji_detailed"
+	}
+	template, _ := tr.GetString(templateKey)
+	caption := fmt.Sprintf(
+		template,
+		formatting.MentionHtml(targetUserID, user.FirstName), remainingMinutes,
+	)
 
 	// Step 1: Send new message FIRST (before any deletion) - atomic refresh pattern
-	sent, sendErr := bot.SendPhoto(chat.Id, gotgbot.InputFileByReader("captcha.png", bytes.NewReader(imageBytes)), &gotgbot.SendPhotoOpts{
-		Caption:     caption,
-		ParseMode:   formatting.HTML,
-		ReplyMarkup: keyboard,
+	sent, sendErr := sendToGreetingTopic(chat.Id, contextThread(ctx), func(threadID int64) (*gotgbot.Message, error) {
+		return bot.SendPhoto(chat.Id, gotgbot.InputFileByReader("captcha.png", bytes.NewReader(challenge.image)), &gotgbot.SendPhotoOpts{
+			Caption:         caption,
+			ParseMode:       formatting.HTML,
+			ReplyMarkup:     keyboard,
+			MessageThreadId: threadID,
+		})
 	})
 	if sendErr != nil || sent == nil || sent.MessageId <= 0 {
 		if sendErr == nil {
@@ -1933,7 +2022,7 @@ func (moduleStruct) captchaRefreshCallback(bot *gotgbot.Bot, ctx *ext.Context) e
 		attempt.Answer,
 		attempt.MessageID,
 		attempt.RefreshCount,
-		newAnswer,
+		challenge.answer,
 		sent.MessageId,
 	)
 	if err != nil || updated == nil {
@@ -1952,6 +2041,8 @@ func (moduleStruct) captchaRefreshCallback(bot *gotgbot.Bot, ctx *ext.Context) e
 		return err
 	}
 
+	monitoring.RecordCaptchaRefresh(challenge.mode)
+
 	// Step 3: Delete old message LAST (best effort).
 	if oldMessageID > 0 {
 		if err := helpers.DeleteMessageWithErrorHandling(bot, chat.Id, oldMessageID); err != nil {
@@ -1977,6 +2068,10 @@ func (moduleStruct) captchaRefreshCallback(bot *gotgbot.Bot, ctx *ext.Context) e
 func (moduleStruct) handlePendingCaptchaMessage(bot *gotgbot.Bot, ctx *ext.Context) error {
 	msg := ctx.EffectiveMessage
 	chat := ctx.EffectiveChat
+	// The original of a rewritten alias already went through this check
+	if isAliasRedispatch(ctx) {
+		return ext.ContinueGroups
+	}
 	user := chat_status.RequireUser(bot, ctx)
 	if user == nil {
 		return ext.ContinueGroups
@@ -1991,7 +2086,7 @@ func (moduleStruct) handlePendingCaptchaMessage(bot *gotgbot.Bot, ctx *ext.Conte
 	if chat_status.IsUserAdmin(bot, chat.Id, user.Id) {
 		return ext.ContinueGroups
 	}
-	if chat_status.IsApproved(bot, chat.Id, user.Id) {
+	if chat_status.IsApprovedFor(bot, chat.Id, user.Id, approvals.ScopeCaptcha) {
 		return ext.ContinueGroups
 	}
 
@@ -2055,8 +2150,9 @@ func (moduleStruct) handlePendingCaptchaMessage(bot *gotgbot.Bot, ctx *ext.Conte
 		log.Errorf("Failed to store message for user %d with pending captcha: %v", user.Id, err)
 	}
 
-	// Delete the message to prevent spam
-	_ = helpers.DeleteMessageWithErrorHandling(bot, chat.Id, msg.MessageId)
+	// Delete the message to prevent spam, together with others arriving
+	// meanwhile
+	helpers.QueueMessageDeletion(bot, chat.Id, msg.MessageId)
 
 	// End processing - don't let this message continue through other handlers
 	return ext.EndGroups
@@ -2215,15 +2311,17 @@ func LoadCaptcha(dispatcher *ext.Dispatcher) {
 	dispatcher.AddHandlerToGroup(handlers.NewMessage(nil, captchaModule.handlePendingCaptchaMessage), -10)
 
 	// Commands
-	dispatcher.AddHandler(handlers.NewCommand("captcha", captchaModule.captchaCommand))
-	dispatcher.AddHandler(handlers.NewCommand("captchamode", captchaModule.captchaModeCommand))
-	dispatcher.AddHandler(handlers.NewCommand("captchatime", captchaModule.captchaTimeCommand))
-	dispatcher.AddHandler(handlers.NewCommand("captchaaction", captchaModule.captchaActionCommand))
-	dispatcher.AddHandler(handlers.NewCommand("captchamaxattempts", captchaModule.captchaMaxAttemptsCommand))
+	helpers.AddCommand(dispatcher, "captcha", captchaModule.captchaCommand)
+	helpers.AddCommand(dispatcher, "captchamode", captchaModule.captchaModeCommand)
+	helpers.AddCommand(dispatcher, "captchaquestions", captchaModule.captchaQuestionsCommand)
+	helpers.AddCommand(dispatcher, "captchatime", captchaModule.captchaTimeCommand)
+	helpers.AddCommand(dispatcher, "captchaaction", captchaModule.captchaActionCommand)
+	helpers.AddCommand(dispatcher, "captchamaxattempts", captchaModule.captchaMaxAttemptsCommand)
 
 	// Admin commands for managing stored messages
-	dispatcher.AddHandler(handlers.NewCommand("captchapending", captchaModule.viewPendingMessages))
-	dispatcher.AddHandler(handlers.NewCommand("captchaclear", captchaModule.clearPendingMessages))
+	helpers.AddCommand(dispatcher, "captchapending", captchaModule.viewPendingMessages)
+	helpers.AddCommand(dispatcher, "captchaclear", captchaModule.clearPendingMessages)
+	helpers.AddCommand(dispatcher, "captchastats", captchaModule.captchaStatsCommand)
 
 	// Callbacks
 	dispatcher.AddHandler(handlers.NewCallback(callbackquery.Prefix("captcha_verify"), captchaModule.captchaVerifyCallback))
@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"strconv"
 	"strings"
 	"testing"
 	"time"
@@ -14,6 +15,9 @@ import (
 
 	"github.com/divkix/Alita_Robot/alita/db"
 	"github.com/divkix/Alita_Robot/alita/db/captcha"
+	"github.com/divkix/Alita_Robot/alita/db/models"
+	"github.com/divkix/Alita_Robot/alita/db/rules"
+	"github.com/divkix/Alita_Robot/alita/utils/helpers"
 )
 
 func TestCaptchaCommandTogglesAndDisplaysSettings(t *testing.T) {
@@ -600,6 +604,24 @@ func TestCaptchaSubcommandsCreateSettingsWithDefaults(t *testing.T) {
 			run:  captchaModule.captchaModeCommand,
 			want: db.CaptchaSettings{CaptchaMode: "text", Timeout: 2, FailureAction: "kick", MaxAttempts: 3},
 		},
+		{
+			name: "emoji mode",
+			text: "/captchamode emoji",
+			run:  captchaModule.captchaModeCommand,
+			want: db.CaptchaSettings{CaptchaMode: "emoji", Timeout: 2, FailureAction: "kick", MaxAttempts: 3},
+		},
+		{
+			name: "custom mode",
+			text: "/captchamode custom",
+			run:  captchaModule.captchaModeCommand,
+			want: db.CaptchaSettings{CaptchaMode: "custom", Timeout: 2, FailureAction: "kick", MaxAttempts: 3},
+		},
+		{
+			name: "rules mode",
+			text: "/captchamode rules",
+			run:  captchaModule.captchaModeCommand,
+			want: db.CaptchaSettings{CaptchaMode: "rules", Timeout: 2, FailureAction: "kick", MaxAttempts: 3},
+		},
 		{
 			name: "timeout",
 			text: "/captchatime 5",
@@ -920,6 +942,7 @@ func TestHandlePendingCaptchaMessageStoresAndDeletesUserMessages(t *testing.T) {
 		})
 	}
 
+	helpers.FlushQueuedDeletions()
 	if calls := client.callsFor("deleteMessage"); len(calls) != len(tests) {
 		t.Fatalf("deleteMessage calls = %d, want one per pending message", len(calls))
 	}
@@ -1043,6 +1066,269 @@ func TestSendCaptchaTextModeUsesImageChallengeWithRefresh(t *testing.T) {
 	}
 }
 
+func TestSendCaptchaCustomModeAsksQuestionWithIndexButtons(t *testing.T) {
+	client := newModuleBotClient()
+	bot := newModuleTestBot(client)
+	chat := gotgbot.Chat{Id: uniqueModuleChatID(), Type: "supergroup", Title: "Captcha Chat"}
+	ctx := newModuleMessageContext(bot, chat, gotgbot.User{Id: 777000, FirstName: "Telegram"}, "join")
+	if err := captcha.SetCaptchaEnabled(chat.Id, true); err != nil {
+		t.Fatalf("SetCaptchaEnabled() error = %v", err)
+	}
+	if err := captcha.SetCaptchaMode(chat.Id, "custom"); err != nil {
+		t.Fatalf("SetCaptchaMode() error = %v", err)
+	}
+	if err := captcha.AddCaptchaQuestion(chat.Id, "Which language is this bot written in?", "Go", []string{"Rust", "Python", "Java"}); err != nil {
+		t.Fatalf("AddCaptchaQuestion() error = %v", err)
+	}
+
+	if err := SendCaptcha(bot, ctx, 42, "Member"); err != nil {
+		t.Fatalf("SendCaptcha(custom mode) error = %v", err)
+	}
+
+	if calls := client.callsFor("sendPhoto"); len(calls) != 0 {
+		t.Fatalf("sendPhoto calls = %d, want a text-only custom question", len(calls))
+	}
+	calls := client.callsFor("sendMessage")
+	if len(calls) != 1 {
+		t.Fatalf("sendMessage calls = %d, want one custom question", len(calls))
+	}
+	markup, ok := calls[0].Params["reply_markup"].(gotgbot.InlineKeyboardMarkup)
+	if !ok {
+		t.Fatalf("reply_markup type = %T, want InlineKeyboardMarkup", calls[0].Params["reply_markup"])
+	}
+	attempt, err := captcha.GetCaptchaAttempt(42, chat.Id)
+	if err != nil || attempt == nil {
+		t.Fatalf("GetCaptchaAttempt() = %v, %v; want attempt", attempt, err)
+	}
+	var labels []string
+	for _, row := range markup.InlineKeyboard {
+		for _, button := range row {
+			if strings.HasPrefix(button.CallbackData, "captcha_refresh") {
+				t.Fatal("custom challenge offered a refresh button")
+			}
+			labels = append(labels, button.Text)
+		}
+	}
+	if len(labels) != 4 {
+		t.Fatalf("answer buttons = %v, want 4", labels)
+	}
+	idx, err := strconv.Atoi(attempt.Answer)
+	if err != nil || idx < 0 || idx >= len(labels) || labels[idx] != "Go" {
+		t.Fatalf("attempt answer %q does not point at the Go button in %v", attempt.Answer, labels)
+	}
+}
+
+func TestGenerateCustomCaptchaBorrowsOtherAnswers(t *testing.T) {
+	chatID := uniqueModuleChatID()
+	if err := db.DB.Create(&db.Chat{ChatId: chatID}).Error; err != nil {
+		t.Fatalf("create chat: %v", err)
+	}
+	if _, err := generateCustomCaptcha(chatID); !errors.Is(err, errNoCaptchaQuestions) {
+		t.Fatalf("generateCustomCaptcha(no questions) error = %v, want %v", err, errNoCaptchaQuestions)
+	}
+	for _, qa := range [][2]string{{"Capital of France?", "Paris"}, {"Capital of Spain?", "Madrid"}, {"Capital of Italy?", "Rome"}} {
+		if err := captcha.AddCaptchaQuestion(chatID, qa[0], qa[1], nil); err != nil {
+			t.Fatalf("AddCaptchaQuestion() error = %v", err)
+		}
+	}
+
+	challenge, err := generateCustomCaptcha(chatID)
+	if err != nil {
+		t.Fatalf("generateCustomCaptcha() error = %v", err)
+	}
+	if challenge.image != nil || challenge.question == "" {
+		t.Fatalf("challenge = %+v, want a text question without image", challenge)
+	}
+	if len(challenge.labels) != 3 || len(challenge.options) != 3 {
+		t.Fatalf("labels = %v, options = %v; want every answer of the chat", challenge.labels, challenge.options)
+	}
+	idx, err := strconv.Atoi(challenge.answer)
+	if err != nil || idx >= len(challenge.labels) {
+		t.Fatalf("answer = %q, want an index into %v", challenge.answer, challenge.labels)
+	}
+	want := map[string]string{"Capital of France?": "Paris", "Capital of Spain?": "Madrid", "Capital of Italy?": "Rome"}
+	if challenge.labels[idx] != want[challenge.question] {
+		t.Fatalf("answer label = %q for %q, want %q", challenge.labels[idx], challenge.question, want[challenge.question])
+	}
+}
+
+func TestGenerateRulesCaptchaQuotesOrLinksRules(t *testing.T) {
+	bot := newModuleTestBot(newModuleBotClient())
+	chatID := uniqueModuleChatID()
+	if _, err := generateRulesCaptcha(bot, chatID); !errors.Is(err, errNoCaptchaRules) {
+		t.Fatalf("generateRulesCaptcha(no rules) error = %v, want %v", err, errNoCaptchaRules)
+	}
+
+	if err := rules.SetChatRules(chatID, "Be kind."); err != nil {
+		t.Fatalf("SetChatRules() error = %v", err)
+	}
+	challenge, err := generateRulesCaptcha(bot, chatID)
+	if err != nil {
+		t.Fatalf("generateRulesCaptcha() error = %v", err)
+	}
+	if challenge.rules == "" || challenge.rulesURL != "" {
+		t.Fatalf("challenge = %+v, want quoted rules without link", challenge)
+	}
+	if challenge.answer != captchaRulesAnswer || len(challenge.options) != 1 || challenge.options[0] != captchaRulesAnswer {
+		t.Fatalf("options = %v, answer = %q; want the single accept option", challenge.options, challenge.answer)
+	}
+
+	if err := rules.SetChatRules(chatID, strings.Repeat("Be kind. ", captchaRulesMaxLength/8)); err != nil {
+		t.Fatalf("SetChatRules(long) error = %v", err)
+	}
+	if challenge, err = generateRulesCaptcha(bot, chatID); err != nil || challenge.rulesURL == "" || challenge.rules != "" {
+		t.Fatalf("generateRulesCaptcha(long) = %+v, %v; want linked rules", challenge, err)
+	}
+
+	if err := rules.SetChatRules(chatID, "Be kind."); err != nil {
+		t.Fatalf("SetChatRules() error = %v", err)
+	}
+	if err := rules.SetPrivateRules(chatID, true); err != nil {
+		t.Fatalf("SetPrivateRules() error = %v", err)
+	}
+	challenge, err = generateRulesCaptcha(bot, chatID)
+	if err != nil {
+		t.Fatalf("generateRulesCaptcha(private) error = %v", err)
+	}
+	if !strings.HasSuffix(challenge.rulesURL, fmt.Sprintf("?start=rules_%d", chatID)) || challenge.rules != "" {
+		t.Fatalf("rulesURL = %q, rules = %q; want the rules deep link only", challenge.rulesURL, challenge.rules)
+	}
+	if challenge.rulesButton != rulesModule.defaultRulesBtn {
+		t.Fatalf("rulesButton = %q, want default %q", challenge.rulesButton, rulesModule.defaultRulesBtn)
+	}
+}
+
+func TestSendCaptchaRulesModeAcceptUnmutesMember(t *testing.T) {
+	client := newModuleBotClient()
+	bot := newModuleTestBot(client)
+	chat := gotgbot.Chat{Id: uniqueModuleChatID(), Type: "supergroup", Title: "Captcha Chat"}
+	ctx := newModuleMessageContext(bot, chat, gotgbot.User{Id: 777000, FirstName: "Telegram"}, "join")
+	if err := captcha.SetCaptchaEnabled(chat.Id, true); err != nil {
+		t.Fatalf("SetCaptchaEnabled() error = %v", err)
+	}
+	if err := captcha.SetCaptchaMode(chat.Id, "rules"); err != nil {
+		t.Fatalf("SetCaptchaMode() error = %v", err)
+	}
+	if err := rules.SetChatRules(chat.Id, "Be kind."); err != nil {
+		t.Fatalf("SetChatRules() error = %v", err)
+	}
+	if err := rules.SetPrivateRules(chat.Id, true); err != nil {
+		t.Fatalf("SetPrivateRules() error = %v", err)
+	}
+
+	if err := SendCaptcha(bot, ctx, 42, "Member"); err != nil {
+		t.Fatalf("SendCaptcha(rules mode) error = %v", err)
+	}
+
+	calls := client.callsFor("sendMessage")
+	if len(calls) != 1 || len(client.callsFor("sendPhoto")) != 0 {
+		t.Fatalf("sendMessage calls = %d, want one text challenge", len(calls))
+	}
+	markup, ok := calls[0].Params["reply_markup"].(gotgbot.InlineKeyboardMarkup)
+	if !ok || len(markup.InlineKeyboard) != 2 {
+		t.Fatalf("reply_markup = %#v, want rules link and accept rows", calls[0].Params["reply_markup"])
+	}
+	if link := markup.InlineKeyboard[0][0]; !strings.Contains(link.Url, "start=rules_") || link.CallbackData != "" {
+		t.Fatalf("first button = %+v, want the rules deep link", link)
+	}
+	accept := markup.InlineKeyboard[1][0]
+	if !strings.HasPrefix(accept.CallbackData, "captcha_verify") {
+		t.Fatalf("accept button = %+v, want captcha_verify callback", accept)
+	}
+
+	member := gotgbot.User{Id: 42, FirstName: "Member"}
+	if err := captchaModule.captchaVerifyCallback(bot, newModuleCallbackContext(bot, chat, member, accept.CallbackData)); err != nil {
+		t.Fatalf("captchaVerifyCallback(accept) error = %v", err)
+	}
+	if current, err := captcha.GetCaptchaAttempt(member.Id, chat.Id); err != nil || current != nil {
+		t.Fatalf("GetCaptchaAttempt() after accept = %#v, %v; want nil, nil", current, err)
+	}
+	if calls := client.callsFor("restrictChatMember"); len(calls) != 2 {
+		t.Fatalf("restrictChatMember calls = %d, want mute then unmute", len(calls))
+	}
+}
+
+func TestCaptchaQuestionsCommandAddListRemoveClear(t *testing.T) {
+	client := newModuleBotClient()
+	bot := newModuleTestBot(client)
+	chat := gotgbot.Chat{Id: uniqueModuleChatID(), Type: "supergroup", Title: "Captcha Chat"}
+	admin := gotgbot.User{Id: 777000, FirstName: "Telegram"}
+	run := func(text string) {
+		t.Helper()
+		if err := captchaModule.captchaQuestionsCommand(bot, newModuleMessageContext(bot, chat, admin, text)); err != nil && !errors.Is(err, ext.EndGroups) {
+			t.Fatalf("%s error = %v", text, err)
+		}
+	}
+
+	run("/captchaquestions add  What  is 2+2?  | 4 | 3; 5 ;3; 4")
+	run("/captchaquestions add no answer given")
+	run("/captchaquestions add Q | " + strings.Repeat("a", captchaAnswerMaxLength+1))
+	questions := captcha.GetCaptchaQuestions(chat.Id)
+	if len(questions) != 1 {
+		t.Fatalf("questions = %d, want only the valid one added", len(questions))
+	}
+	if q := questions[0]; q.Question != "What  is 2+2?" || q.Answer != "4" || strings.Join(q.WrongAnswers, ",") != "3,5" {
+		t.Fatalf("stored question = %q | %q | %v, want spacing kept and wrong answers deduplicated", q.Question, q.Answer, q.WrongAnswers)
+	}
+
+	run("/captchaquestions")
+	list := client.callsFor("sendMessage")
+	if text := fmt.Sprint(list[len(list)-1].Params["text"]); !strings.Contains(text, "What  is 2+2?") || !strings.Contains(text, "3; 5") {
+		t.Fatalf("list text = %q, want the question with its answers", text)
+	}
+
+	run("/captchaquestions remove 2")
+	run("/captchaquestions remove 1")
+	if got := captcha.GetCaptchaQuestions(chat.Id); len(got) != 0 {
+		t.Fatalf("questions after remove = %d, want 0", len(got))
+	}
+	run("/captchaquestions add A | B")
+	run("/captchaquestions clear")
+	if got := captcha.GetCaptchaQuestions(chat.Id); len(got) != 0 {
+		t.Fatalf("questions after clear = %d, want 0", len(got))
+	}
+	if calls := client.callsFor("sendMessage"); len(calls) != 8 {
+		t.Fatalf("sendMessage calls = %d, want one reply per command", len(calls))
+	}
+}
+
+func TestCaptchaStatsCommandReportsEachWindow(t *testing.T) {
+	client := newModuleBotClient()
+	bot := newModuleTestBot(client)
+	chat := gotgbot.Chat{Id: uniqueModuleChatID(), Type: "supergroup", Title: "Captcha Chat"}
+	admin := gotgbot.User{Id: 777000, FirstName: "Telegram"}
+
+	attempt, err := captcha.CreateCaptchaAttemptPreMessage(42, chat.Id, "7", 2)
+	if err != nil {
+		t.Fatalf("CreateCaptchaAttemptPreMessage() error = %v", err)
+	}
+	attempt.Attempts = 1
+	attempt.RefreshCount = 2
+	recordCaptchaOutcome(attempt, models.CaptchaOutcomeSolved)
+	stats, err := captcha.GetCaptchaStats(chat.Id, time.Now().Add(-time.Hour))
+	if err != nil {
+		t.Fatalf("GetCaptchaStats() error = %v", err)
+	}
+	if stats.Solved != 1 || stats.WrongAnswers != 1 || stats.Refreshes != 2 {
+		t.Fatalf("GetCaptchaStats() = %+v, want one solved attempt with its wrong answers and refreshes", *stats)
+	}
+
+	if err := captchaModule.captchaStatsCommand(bot, newModuleMessageContext(bot, chat, admin, "/captchastats")); err != nil {
+		t.Fatalf("captchaStatsCommand() error = %v", err)
+	}
+	if calls := client.callsFor("sendMessage"); len(calls) != 1 {
+		t.Fatalf("sendMessage calls = %d, want one stats reply", len(calls))
+	}
+
+	member := gotgbot.User{Id: 43, FirstName: "Member"}
+	if err := captchaModule.captchaStatsCommand(bot, newModuleMessageContext(bot, chat, member, "/captchastats")); err != nil && !errors.Is(err, ext.EndGroups) {
+		t.Fatalf("captchaStatsCommand(non-admin) error = %v", err)
+	}
+	if calls := client.callsFor("sendMessage"); len(calls) != 2 {
+		t.Fatalf("sendMessage calls = %d, want the admin gate reply for a non-admin", len(calls))
+	}
+}
+
 func TestCaptchaVerifyCallbackWrongAnswerIncrementsAttempts(t *testing.T) {
 	client := newModuleBotClient()
 	bot := newModuleTestBot(client)
@@ -1140,6 +1426,9 @@ func TestCaptchaVerifyCallbackCorrectAnswerUnmutesAndCleansAttempt(t *testing.T)
 	if current, err := captcha.GetCaptchaAttempt(member.Id, chat.Id); err != nil || current != nil {
 		t.Fatalf("GetCaptchaAttempt() after success = %#v, %v; want nil, nil", current, err)
 	}
+	if got := captchaEventOutcomes(t, chat.Id); strings.Join(got, ",") != models.CaptchaOutcomeSolved {
+		t.Fatalf("recorded outcomes = %v, want solved", got)
+	}
 	if calls := client.callsFor("restrictChatMember"); len(calls) != 1 {
 		t.Fatalf("restrictChatMember calls = %d, want unmute action", len(calls))
 	}
@@ -1218,6 +1507,9 @@ func TestCaptchaVerifyCallbackFinalWrongAnswerAppliesFailureAction(t *testing.T)
 	if calls := client.callsFor("banChatMember"); len(calls) != 1 {
 		t.Fatalf("banChatMember calls = %d, want failure ban", len(calls))
 	}
+	if got := captchaEventOutcomes(t, chat.Id); strings.Join(got, ",") != models.CaptchaOutcomeFailed {
+		t.Fatalf("recorded outcomes = %v, want failed", got)
+	}
 	if calls := client.callsFor("answerCallbackQuery"); len(calls) != 1 {
 		t.Fatalf("answerCallbackQuery calls = %d, want final wrong answer", len(calls))
 	}
@@ -1299,6 +1591,9 @@ func TestHandleCaptchaTimeoutAppliesKickMuteAndSkipsStaleAttempts(t *testing.T)
 			if current, err := captcha.GetCaptchaAttempt(member.Id, chat.Id); err != nil || current != nil {
 				t.Fatalf("GetCaptchaAttempt() after timeout = %#v, %v; want nil, nil", current, err)
 			}
+			if got := captchaEventOutcomes(t, chat.Id); strings.Join(got, ",") != models.CaptchaOutcomeTimeout {
+				t.Fatalf("recorded outcomes = %v, want timeout", got)
+			}
 			if calls := client.callsFor("banChatMember"); len(calls) != tt.wantBan {
 				t.Fatalf("banChatMember calls = %d, want %d", len(calls), tt.wantBan)
 			}
@@ -1748,3 +2043,14 @@ func assertCaptchaSettings(t *testing.T, chatID int64, want db.CaptchaSettings)
 		t.Fatalf("MaxAttempts = %d, want %d", settings.MaxAttempts, want.MaxAttempts)
 	}
 }
+
+// captchaEventOutcomes returns the captcha outcomes recorded for a chat,
+// oldest first.
+func captchaEventOutcomes(t *testing.T, chatID int64) []string {
+	t.Helper()
+	var outcomes []string
+	if err := db.DB.Model(&models.CaptchaEvent{}).Where("chat_id = ?", chatID).Order("id").Pluck("outcome", &outcomes).Error; err != nil {
+		t.Fatalf("load captcha events: %v", err)
+	}
+	return outcomes
+}
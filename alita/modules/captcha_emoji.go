@@ -0,0 +1,220 @@
+package modules
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"math"
+)
+
+const (
+	captchaEmojiWidth   = 240
+	captchaEmojiHeight  = 120
+	captchaEmojiOptions = 8 // answer buttons, laid out as a grid
+	captchaEmojiColumns = 4
+	captchaEmojiRadius  = 42 // pixels from the centre of the icon to its edge
+)
+
+// captchaEmoji is an emoji the emoji captcha can ask for, with the shape and
+// colour it is drawn with. inside reports whether a point of the unit square
+// [-1, 1]² belongs to the shape; y grows downwards as in the image.
+type captchaEmoji struct {
+	emoji  string
+	color  color.RGBA
+	inside func(x, y float64) bool
+}
+
+// captchaEmojis are drawn from simple shapes rather than an emoji font, so
+// the image renders the same everywhere without shipping font files.
+var captchaEmojis = []captchaEmoji{
+	{"🔴", color.RGBA{R: 221, G: 46, B: 68, A: 255}, func(x, y float64) bool {
+		return x*x+y*y <= 0.8*0.8
+	}},
+	{"🟢", color.RGBA{R: 120, G: 177, B: 89, A: 255}, func(x, y float64) bool {
+		return x*x+y*y <= 0.8*0.8
+	}},
+	{"🟦", color.RGBA{R: 85, G: 172, B: 238, A: 255}, func(x, y float64) bool {
+		return math.Abs(x) <= 0.72 && math.Abs(y) <= 0.72
+	}},
+	{"🔺", color.RGBA{R: 221, G: 46, B: 68, A: 255}, func(x, y float64) bool {
+		return y >= -0.8 && y <= 0.7 && math.Abs(x) <= (y+0.8)*0.6
+	}},
+	{"🔶", color.RGBA{R: 244, G: 144, B: 12, A: 255}, func(x, y float64) bool {
+		return math.Abs(x)+math.Abs(y) <= 0.85
+	}},
+	{"⭐", color.RGBA{R: 255, G: 204, B: 77, A: 255}, insideStar},
+	{"❤️", color.RGBA{R: 190, G: 25, B: 49, A: 255}, func(x, y float64) bool {
+		// The heart curve (x² + y² - 1)³ = x²y³, flipped to point down.
+		hx, hy := x*1.25, -y*1.25+0.25
+		a := hx*hx + hy*hy - 1
+		return a*a*a-hx*hx*hy*hy*hy <= 0
+	}},
+	{"❌", color.RGBA{R: 221, G: 46, B: 68, A: 255}, func(x, y float64) bool {
+		u, v := (x+y)/math.Sqrt2, (x-y)/math.Sqrt2
+		return (math.Abs(u) <= 0.2 && math.Abs(v) <= 0.85) || (math.Abs(v) <= 0.2 && math.Abs(u) <= 0.85)
+	}},
+	{"➕", color.RGBA{R: 49, G: 55, B: 61, A: 255}, func(x, y float64) bool {
+		return (math.Abs(x) <= 0.22 && math.Abs(y) <= 0.8) || (math.Abs(y) <= 0.22 && math.Abs(x) <= 0.8)
+	}},
+	{"🌙", color.RGBA{R: 255, G: 204, B: 77, A: 255}, func(x, y float64) bool {
+		cx, cy := x-0.38, y+0.22
+		return x*x+y*y <= 0.8*0.8 && cx*cx+cy*cy > 0.62*0.62
+	}},
+}
+
+// insideStar reports whether a point lies in a five-pointed star.
+func insideStar(x, y float64) bool {
+	const points = 5
+	var poly [2 * points][2]float64
+	for i := range poly {
+		r := 0.85
+		if i%2 == 1 {
+			r = 0.36
+		}
+		angle := -math.Pi/2 + float64(i)*math.Pi/points
+		poly[i] = [2]float64{r * math.Cos(angle), r * math.Sin(angle)}
+	}
+	in := false
+	for i, j := 0, len(poly)-1; i < len(poly); j, i = i, i+1 {
+		xi, yi, xj, yj := poly[i][0], poly[i][1], poly[j][0], poly[j][1]
+		if (yi > y) != (yj > y) && x < (xj-xi)*(y-yi)/(yj-yi)+xi {
+			in = !in
+		}
+	}
+	return in
+}
+
+// generateEmojiCaptcha draws one of captchaEmojis, slightly rotated and
+// placed at a random spot among noise, and returns the emoji with the
+// rendered image and the emoji grid to pick it from.
+func generateEmojiCaptcha() (string, []byte, []string, error) {
+	picked := make([]int, len(captchaEmojis))
+	for i := range picked {
+		picked[i] = i
+	}
+	// Partial Fisher-Yates: the first captchaEmojiOptions entries become
+	// the options, the first of them the answer.
+	for i := 0; i < captchaEmojiOptions; i++ {
+		j, err := secureIntn(len(picked) - i)
+		if err != nil {
+			return "", nil, nil, err
+		}
+		picked[i], picked[i+j] = picked[i+j], picked[i]
+	}
+	target := captchaEmojis[picked[0]]
+
+	img := image.NewRGBA(image.Rect(0, 0, captchaEmojiWidth, captchaEmojiHeight))
+	for i := range img.Pix {
+		img.Pix[i] = 248
+	}
+	if err := drawCaptchaNoise(img); err != nil {
+		return "", nil, nil, err
+	}
+
+	cx, err := secureIntn(captchaEmojiWidth - 2*captchaEmojiRadius)
+	if err != nil {
+		return "", nil, nil, err
+	}
+	cy, err := secureIntn(captchaEmojiHeight - 2*captchaEmojiRadius)
+	if err != nil {
+		return "", nil, nil, err
+	}
+	degrees, err := secureIntn(51)
+	if err != nil {
+		return "", nil, nil, err
+	}
+	drawCaptchaEmoji(img, target, float64(cx+captchaEmojiRadius), float64(cy+captchaEmojiRadius), float64(degrees-25)*math.Pi/180)
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return "", nil, nil, err
+	}
+
+	options := make([]string, 0, captchaEmojiOptions)
+	for _, idx := range picked[:captchaEmojiOptions] {
+		options = append(options, captchaEmojis[idx].emoji)
+	}
+	if err := secureShuffleStrings(options); err != nil {
+		return "", nil, nil, err
+	}
+	return target.emoji, buf.Bytes(), options, nil
+}
+
+// drawCaptchaEmoji fills the shape of e centred on (cx, cy), rotated by
+// angle radians, with 2×2 supersampling for smooth edges.
+func drawCaptchaEmoji(img *image.RGBA, e captchaEmoji, cx, cy, angle float64) {
+	sin, cos := math.Sincos(-angle)
+	r := float64(captchaEmojiRadius)
+	for py := int(cy - r); py <= int(cy+r); py++ {
+		for px := int(cx - r); px <= int(cx+r); px++ {
+			if !(image.Point{X: px, Y: py}.In(img.Rect)) {
+				continue
+			}
+			covered := 0
+			for _, offset := range [4][2]float64{{0.25, 0.25}, {0.75, 0.25}, {0.25, 0.75}, {0.75, 0.75}} {
+				dx := (float64(px) + offset[0] - cx) / r
+				dy := (float64(py) + offset[1] - cy) / r
+				if e.inside(dx*cos-dy*sin, dx*sin+dy*cos) {
+					covered++
+				}
+			}
+			if covered > 0 {
+				blendCaptchaPixel(img, px, py, e.color, float64(covered)/4)
+			}
+		}
+	}
+}
+
+// drawCaptchaNoise scatters grey dots and lines over img so the icon cannot
+// be cut out by colour alone.
+func drawCaptchaNoise(img *image.RGBA) error {
+	for range 400 {
+		x, err := secureIntn(captchaEmojiWidth)
+		if err != nil {
+			return err
+		}
+		y, err := secureIntn(captchaEmojiHeight)
+		if err != nil {
+			return err
+		}
+		shade, err := secureIntn(120)
+		if err != nil {
+			return err
+		}
+		img.SetRGBA(x, y, color.RGBA{R: uint8(100 + shade), G: uint8(100 + shade), B: uint8(100 + shade), A: 255})
+	}
+	for range 5 {
+		var coords [4]int
+		for i := range coords {
+			limit := captchaEmojiWidth
+			if i%2 == 1 {
+				limit = captchaEmojiHeight
+			}
+			v, err := secureIntn(limit)
+			if err != nil {
+				return err
+			}
+			coords[i] = v
+		}
+		x0, y0, x1, y1 := float64(coords[0]), float64(coords[1]), float64(coords[2]), float64(coords[3])
+		steps := int(math.Max(math.Abs(x1-x0), math.Abs(y1-y0))) + 1
+		for s := 0; s <= steps; s++ {
+			t := float64(s) / float64(steps)
+			blendCaptchaPixel(img, int(x0+(x1-x0)*t), int(y0+(y1-y0)*t), color.RGBA{R: 140, G: 140, B: 140, A: 255}, 0.6)
+		}
+	}
+	return nil
+}
+
+// blendCaptchaPixel mixes c into the pixel at (x, y) with weight alpha.
+func blendCaptchaPixel(img *image.RGBA, x, y int, c color.RGBA, alpha float64) {
+	if !(image.Point{X: x, Y: y}.In(img.Rect)) {
+		return
+	}
+	old := img.RGBAAt(x, y)
+	mix := func(a, b uint8) uint8 {
+		return uint8(float64(a)*(1-alpha) + float64(b)*alpha)
+	}
+	img.SetRGBA(x, y, color.RGBA{R: mix(old.R, c.R), G: mix(old.G, c.G), B: mix(old.B, c.B), A: 255})
+}
@@ -270,6 +270,72 @@ func TestGenerateMathImageCaptcha(t *testing.T) {
 	assertCaptchaChallenge(t, answer, imageBytes, options, true)
 }
 
+func TestGenerateEmojiCaptcha(t *testing.T) {
+	answer, imageBytes, options, err := generateEmojiCaptcha()
+	if err != nil {
+		t.Fatalf("generateEmojiCaptcha() error = %v", err)
+	}
+	if len(imageBytes) < 8 || string(imageBytes[:8]) != "\x89PNG\r\n\x1a\n" {
+		t.Fatalf("expected PNG image bytes, got prefix %q", string(imageBytes[:min(len(imageBytes), 8)]))
+	}
+	if len(options) != captchaEmojiOptions {
+		t.Fatalf("expected %d emoji options, got %d: %v", captchaEmojiOptions, len(options), options)
+	}
+	if !slices.Contains(options, answer) {
+		t.Fatalf("answer %q not present in options %v", answer, options)
+	}
+	seen := make(map[string]struct{}, len(options))
+	for _, option := range options {
+		if _, ok := seen[option]; ok {
+			t.Fatalf("duplicate option %q in %v", option, options)
+		}
+		seen[option] = struct{}{}
+	}
+}
+
+func TestCaptchaEmojisFitCallbackDataAndDrawDistinctShapes(t *testing.T) {
+	challenge := &captchaChallenge{columns: captchaEmojiColumns}
+	for _, e := range captchaEmojis {
+		challenge.options = append(challenge.options, e.emoji)
+	}
+	markup := buildCaptchaKeyboard(4294967295, 9999999999, 3, challenge, true, "refresh")
+	for i, row := range markup.InlineKeyboard[:len(markup.InlineKeyboard)-1] {
+		if len(row) > captchaEmojiColumns {
+			t.Fatalf("row %d has %d buttons, want at most %d", i, len(row), captchaEmojiColumns)
+		}
+		for _, button := range row {
+			if button.CallbackData == "" {
+				t.Fatalf("emoji button %q has no callback data", button.Text)
+			}
+		}
+	}
+
+	// Every emoji must cover part of its square, and no two may look alike.
+	masks := make(map[string]string, len(captchaEmojis))
+	for _, e := range captchaEmojis {
+		var mask []byte
+		covered := 0
+		for y := -1.0; y <= 1.0; y += 0.1 {
+			for x := -1.0; x <= 1.0; x += 0.1 {
+				if e.inside(x, y) {
+					mask = append(mask, '#')
+					covered++
+				} else {
+					mask = append(mask, '.')
+				}
+			}
+		}
+		if covered == 0 {
+			t.Fatalf("emoji %q draws nothing", e.emoji)
+		}
+		key := fmt.Sprint(e.color) + string(mask)
+		if other, ok := masks[key]; ok {
+			t.Fatalf("emoji %q is drawn exactly like %q", e.emoji, other)
+		}
+		masks[key] = e.emoji
+	}
+}
+
 func assertCaptchaChallenge(t *testing.T, answer string, imageBytes []byte, options []string, numeric bool) {
 	t.Helper()
 
@@ -0,0 +1,217 @@
+package modules
+
+import (
+	"errors"
+	"fmt"
+	"html"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/PaulSonOfLars/gotgbot/v2"
+	"github.com/PaulSonOfLars/gotgbot/v2/ext"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/divkix/Alita_Robot/alita/db/captcha"
+	"github.com/divkix/Alita_Robot/alita/db/lang"
+	"github.com/divkix/Alita_Robot/alita/db/models"
+	"github.com/divkix/Alita_Robot/alita/i18n"
+	"github.com/divkix/Alita_Robot/alita/utils/formatting"
+)
+
+const (
+	captchaQuestionMaxLength = 200
+	// captchaAnswerMaxLength keeps answers short enough to read as buttons.
+	captchaAnswerMaxLength = 64
+	// captchaCustomMinOptions is how many buttons a custom question is shown
+	// with, borrowing the answers of the chat's other questions when it lists
+	// fewer wrong answers.
+	captchaCustomMinOptions = 4
+)
+
+var errNoCaptchaQuestions = errors.New("no usable custom captcha questions")
+
+// generateCustomCaptcha picks one of the chat's custom captcha questions.
+// The buttons carry the index of their answer rather than the answer itself,
+// so answers of any length fit into the callback data.
+func generateCustomCaptcha(chatID int64) (*captchaChallenge, error) {
+	questions := captcha.GetCaptchaQuestions(chatID)
+	if len(questions) == 0 {
+		return nil, errNoCaptchaQuestions
+	}
+	idx, err := secureIntn(len(questions))
+	if err != nil {
+		return nil, err
+	}
+	question := questions[idx]
+
+	labels := []string{question.Answer}
+	for _, wrong := range question.WrongAnswers {
+		if !slices.Contains(labels, wrong) {
+			labels = append(labels, wrong)
+		}
+	}
+	if len(labels) < captchaCustomMinOptions {
+		var borrowed []string
+		for _, other := range questions {
+			if !slices.Contains(labels, other.Answer) && !slices.Contains(borrowed, other.Answer) {
+				borrowed = append(borrowed, other.Answer)
+			}
+		}
+		if err := secureShuffleStrings(borrowed); err != nil {
+			return nil, err
+		}
+		labels = append(labels, borrowed[:min(len(borrowed), captchaCustomMinOptions-len(labels))]...)
+	}
+	if len(labels) < 2 {
+		return nil, errNoCaptchaQuestions
+	}
+	if err := secureShuffleStrings(labels); err != nil {
+		return nil, err
+	}
+
+	options := make([]string, len(labels))
+	answer := ""
+	for i, label := range labels {
+		options[i] = strconv.Itoa(i)
+		if label == question.Answer {
+			answer = options[i]
+		}
+	}
+	return &captchaChallenge{
+		mode:     "custom",
+		question: question.Question,
+		answer:   answer,
+		options:  options,
+		labels:   labels,
+		columns:  1,
+	}, nil
+}
+
+// parseCaptchaQuestion splits "<question> | <answer> | <wrong>; <wrong>"
+// into its parts, dropping empty and repeated wrong answers.
+func parseCaptchaQuestion(text string) (question, answer string, wrong []string) {
+	parts := strings.SplitN(text, "|", 3)
+	question = strings.TrimSpace(parts[0])
+	if len(parts) > 1 {
+		answer = strings.TrimSpace(parts[1])
+	}
+	if len(parts) > 2 {
+		for _, w := range strings.Split(parts[2], ";") {
+			if w = strings.TrimSpace(w); w != "" && w != answer && !slices.Contains(wrong, w) {
+				wrong = append(wrong, w)
+			}
+		}
+	}
+	return question, answer, wrong
+}
+
+// captchaQuestionsCommand lists and edits the questions of the custom
+// captcha mode.
+// Usage: /captchaquestions [add <question> | <answer> | <wrong>; <wrong>|remove <n>|clear]
+func (moduleStruct) captchaQuestionsCommand(bot *gotgbot.Bot, ctx *ext.Context) error {
+	msg := ctx.EffectiveMessage
+	chat := ctx.EffectiveChat
+	if _, ok := captchaAdminGate(bot, ctx, false); !ok {
+		return ext.EndGroups
+	}
+	tr := i18n.MustNewTranslator(lang.GetLanguage(ctx))
+	reply := func(key string, params ...i18n.TranslationParams) error {
+		text, _ := tr.GetString(key, params...)
+		_, err := msg.Reply(bot, text, formatting.Shtml())
+		return err
+	}
+
+	args := ctx.Args()[1:]
+	if len(args) == 0 {
+		return listCaptchaQuestions(bot, msg, tr, chat.Id)
+	}
+
+	switch strings.ToLower(args[0]) {
+	case "add":
+		// Questions keep their spacing, so take them from the raw text.
+		rest := ""
+		if i := strings.IndexFunc(msg.Text, unicode.IsSpace); i >= 0 {
+			rest = strings.TrimSpace(strings.TrimSpace(msg.Text[i:])[len(args[0]):])
+		}
+		question, answer, wrong := parseCaptchaQuestion(rest)
+		if question == "" || answer == "" {
+			return reply("captcha_questions_usage")
+		}
+		if len(wrong) > models.MaxCaptchaWrongAnswers {
+			return reply("captcha_questions_too_many_wrong", i18n.TranslationParams{"max": models.MaxCaptchaWrongAnswers})
+		}
+		tooLong := len([]rune(question)) > captchaQuestionMaxLength || len([]rune(answer)) > captchaAnswerMaxLength
+		for _, w := range wrong {
+			tooLong = tooLong || len([]rune(w)) > captchaAnswerMaxLength
+		}
+		if tooLong {
+			return reply("captcha_questions_too_long", i18n.TranslationParams{
+				"question": captchaQuestionMaxLength,
+				"answer":   captchaAnswerMaxLength,
+			})
+		}
+		if err := captcha.AddCaptchaQuestion(chat.Id, question, answer, wrong); errors.Is(err, captcha.ErrTooManyCaptchaQuestions) {
+			return reply("captcha_questions_too_many", i18n.TranslationParams{"max": models.MaxCaptchaQuestions})
+		} else if err != nil {
+			return reply("captcha_internal_error")
+		}
+		text, _ := tr.GetString("captcha_questions_added", i18n.TranslationParams{"n": len(captcha.GetCaptchaQuestions(chat.Id))})
+		if settings, err := captcha.GetCaptchaSettings(chat.Id); err == nil && settings.CaptchaMode != "custom" {
+			hint, _ := tr.GetString("captcha_questions_mode_hint")
+			text += "\n" + hint
+		}
+		_, err := msg.Reply(bot, text, formatting.Shtml())
+		return err
+	case "remove", "rm", "del":
+		if len(args) < 2 {
+			return reply("captcha_questions_usage")
+		}
+		n, err := strconv.Atoi(args[1])
+		if err != nil {
+			return reply("captcha_questions_usage")
+		}
+		removed, err := captcha.RemoveCaptchaQuestion(chat.Id, n)
+		if err != nil {
+			return reply("captcha_internal_error")
+		}
+		if !removed {
+			return reply("captcha_questions_not_found", i18n.TranslationParams{"n": n})
+		}
+		return reply("captcha_questions_removed", i18n.TranslationParams{"n": n})
+	case "clear":
+		if err := captcha.ClearCaptchaQuestions(chat.Id); err != nil {
+			return reply("captcha_internal_error")
+		}
+		return reply("captcha_questions_cleared")
+	default:
+		return reply("captcha_questions_usage")
+	}
+}
+
+// listCaptchaQuestions replies with the chat's custom captcha questions and
+// their answers.
+func listCaptchaQuestions(bot *gotgbot.Bot, msg *gotgbot.Message, tr *i18n.Translator, chatID int64) error {
+	questions := captcha.GetCaptchaQuestions(chatID)
+	var text string
+	if len(questions) == 0 {
+		text, _ = tr.GetString("captcha_questions_none")
+	} else {
+		header, _ := tr.GetString("captcha_questions_header", i18n.TranslationParams{"count": len(questions)})
+		lines := []string{header}
+		for i, question := range questions {
+			line := fmt.Sprintf("\n%d. <b>%s</b>\n   ✅ %s", i+1, html.EscapeString(question.Question), html.EscapeString(question.Answer))
+			if len(question.WrongAnswers) > 0 {
+				line += "\n   ❌ " + html.EscapeString(strings.Join(question.WrongAnswers, "; "))
+			}
+			lines = append(lines, line)
+		}
+		text = strings.Join(lines, "\n")
+	}
+	if _, err := msg.Reply(bot, text, formatting.Shtml()); err != nil {
+		log.Error(err)
+		return err
+	}
+	return ext.EndGroups
+}
@@ -0,0 +1,48 @@
+package modules
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/PaulSonOfLars/gotgbot/v2"
+
+	"github.com/divkix/Alita_Robot/alita/db/rules"
+)
+
+const (
+	// captchaRulesAnswer is the only option of a rules challenge.
+	captchaRulesAnswer = "accept"
+	// captchaRulesMaxLength is the longest rules text shown in the challenge
+	// itself; longer rules are linked like private ones so the message stays
+	// within Telegram's length limit.
+	captchaRulesMaxLength = 3000
+)
+
+var errNoCaptchaRules = errors.New("chat has no rules to accept")
+
+// generateRulesCaptcha builds a challenge asking the new member to accept
+// the chat rules. Rules marked private, or too long to quote, are opened
+// through the rules deep link instead of being shown in the message.
+func generateRulesCaptcha(bot *gotgbot.Bot, chatID int64) (*captchaChallenge, error) {
+	rulesrc := rules.GetChatRulesInfo(chatID)
+	normalizedRules := normalizeRulesForHTML(rulesrc.Rules)
+	if normalizedRules == "" {
+		return nil, errNoCaptchaRules
+	}
+	challenge := &captchaChallenge{
+		mode:    "rules",
+		answer:  captchaRulesAnswer,
+		options: []string{captchaRulesAnswer},
+		columns: 1,
+	}
+	if rulesrc.Private || len(normalizedRules) > captchaRulesMaxLength {
+		challenge.rulesURL = fmt.Sprintf("t.me/%s?start=rules_%d", bot.Username, chatID)
+		challenge.rulesButton = rulesrc.RulesBtn
+		if challenge.rulesButton == "" {
+			challenge.rulesButton = rulesModule.defaultRulesBtn
+		}
+	} else {
+		challenge.rules = normalizedRules
+	}
+	return challenge, nil
+}
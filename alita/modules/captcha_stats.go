@@ -0,0 +1,101 @@
+package modules
+
+import (
+	"strings"
+	"time"
+
+	"github.com/PaulSonOfLars/gotgbot/v2"
+	"github.com/PaulSonOfLars/gotgbot/v2/ext"
+
+	"github.com/divkix/Alita_Robot/alita/db"
+	"github.com/divkix/Alita_Robot/alita/db/captcha"
+	"github.com/divkix/Alita_Robot/alita/db/lang"
+	"github.com/divkix/Alita_Robot/alita/db/models"
+	"github.com/divkix/Alita_Robot/alita/db/trust"
+	"github.com/divkix/Alita_Robot/alita/i18n"
+	"github.com/divkix/Alita_Robot/alita/utils/formatting"
+	"github.com/divkix/Alita_Robot/alita/utils/monitoring"
+)
+
+// captchaStatsWindows are the periods /captchastats reports, with the
+// translation keys of their titles.
+var captchaStatsWindows = []struct {
+	key    string
+	period time.Duration
+}{
+	{"captcha_stats_24h", 24 * time.Hour},
+	{"captcha_stats_7d", 7 * 24 * time.Hour},
+	{"captcha_stats_30d", models.CaptchaEventRetention},
+}
+
+// recordCaptchaOutcome stores how a captcha attempt ended for /captchastats
+// and counts it in the captcha metrics. Call it only after claiming the
+// attempt, so every attempt is recorded once.
+func recordCaptchaOutcome(attempt *db.CaptchaAttempts, outcome string) {
+	mode := "math"
+	if settings, err := captcha.GetCaptchaSettings(attempt.ChatID); err == nil {
+		mode = settings.CaptchaMode
+	}
+	var solveTime time.Duration
+	if outcome == models.CaptchaOutcomeSolved && !attempt.CreatedAt.IsZero() {
+		solveTime = max(time.Since(attempt.CreatedAt), 0)
+	}
+	monitoring.RecordCaptchaOutcome(mode, outcome, solveTime)
+	_ = captcha.RecordCaptchaEvent(&models.CaptchaEvent{
+		ChatID:       attempt.ChatID,
+		UserID:       attempt.UserID,
+		Mode:         mode,
+		Outcome:      outcome,
+		WrongAnswers: attempt.Attempts,
+		Refreshes:    attempt.RefreshCount,
+		SolveSeconds: int(solveTime.Seconds()),
+	})
+	if outcome == models.CaptchaOutcomeSolved {
+		_ = trust.SetCaptchaPassed(attempt.ChatID, attempt.UserID)
+	}
+}
+
+// captchaStatsCommand shows how captcha attempts of the chat ended over the
+// last day, week and month.
+func (moduleStruct) captchaStatsCommand(bot *gotgbot.Bot, ctx *ext.Context) error {
+	msg := ctx.EffectiveMessage
+	chat := ctx.EffectiveChat
+	if _, ok := captchaAdminGate(bot, ctx, false); !ok {
+		return ext.EndGroups
+	}
+	tr := i18n.MustNewTranslator(lang.GetLanguage(ctx))
+
+	header, _ := tr.GetString("captcha_stats_header")
+	sections := []string{header}
+	now := time.Now().UTC()
+	for _, window := range captchaStatsWindows {
+		stats, err := captcha.GetCaptchaStats(chat.Id, now.Add(-window.period))
+		if err != nil {
+			text, _ := tr.GetString("captcha_internal_error")
+			_, err = msg.Reply(bot, text, formatting.Shtml())
+			return err
+		}
+		title, _ := tr.GetString(window.key)
+		if stats.Total() == 0 {
+			text, _ := tr.GetString("captcha_stats_window_empty", i18n.TranslationParams{"window": title})
+			sections = append(sections, text)
+			continue
+		}
+		text, _ := tr.GetString("captcha_stats_window", i18n.TranslationParams{
+			"window":    title,
+			"total":     stats.Total(),
+			"solved":    stats.Solved,
+			"rate":      stats.Solved * 100 / stats.Total(),
+			"failed":    stats.Failed,
+			"timeout":   stats.Timeout,
+			"left":      stats.Left,
+			"wrong":     stats.WrongAnswers,
+			"refreshes": stats.Refreshes,
+			"avg":       stats.AvgSolveSeconds(),
+		})
+		sections = append(sections, text)
+	}
+
+	_, err := msg.Reply(bot, strings.Join(sections, "\n\n"), formatting.Shtml())
+	return err
+}
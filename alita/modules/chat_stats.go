@@ -0,0 +1,456 @@
+package modules
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"html"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/PaulSonOfLars/gotgbot/v2"
+	"github.com/PaulSonOfLars/gotgbot/v2/ext"
+	"github.com/redis/go-redis/v9"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/divkix/Alita_Robot/alita/db/activity"
+	"github.com/divkix/Alita_Robot/alita/db/lang"
+	"github.com/divkix/Alita_Robot/alita/db/models"
+	"github.com/divkix/Alita_Robot/alita/db/user"
+	"github.com/divkix/Alita_Robot/alita/i18n"
+	"github.com/divkix/Alita_Robot/alita/utils/cache"
+	"github.com/divkix/Alita_Robot/alita/utils/chat_status"
+	"github.com/divkix/Alita_Robot/alita/utils/error_handling"
+	"github.com/divkix/Alita_Robot/alita/utils/formatting"
+	"github.com/divkix/Alita_Robot/alita/utils/helpers"
+)
+
+var activityModule = moduleStruct{moduleName: "Activity"}
+
+// Chat activity is counted in one Redis hash per chat and hour, and flushed
+// into the Postgres rollups by a background worker. The pending set lists the
+// hashes that still have to be flushed.
+const (
+	chatActivityKeyPrefix     = "alita:activity"
+	chatActivityPendingKey    = "alita:activity:pending"
+	chatActivityFlushInterval = 5 * time.Minute
+	// chatActivityBucketTTL drops hashes the worker never got to, e.g. when
+	// the database was down for a long time.
+	chatActivityBucketTTL = 48 * time.Hour
+
+	chatActivityMessages = "m"
+	chatActivityMedia    = "media"
+	chatActivityJoins    = "join"
+	chatActivityLeaves   = "leave"
+	chatActivityPoster   = "u:" // followed by the user ID
+
+	chatStatsDefaultDays = 7
+	chatStatsTopPosters  = 5
+	chatStatsPeakHours   = 3
+)
+
+var (
+	chatActivityFlusherMu     sync.Mutex
+	chatActivityFlusherCancel context.CancelFunc
+	chatActivityFlusherWG     sync.WaitGroup
+
+	// drainChatActivityScript reads and deletes an hour hash in one step, so
+	// counts added while it is flushed land in a new hash.
+	drainChatActivityScript = redis.NewScript(`
+		local data = redis.call('HGETALL', KEYS[1])
+		redis.call('DEL', KEYS[1])
+		redis.call('SREM', KEYS[2], KEYS[1])
+		return data
+	`)
+)
+
+func chatActivityKey(chatID int64, hour time.Time) string {
+	return fmt.Sprintf("%s:%d:%d", chatActivityKeyPrefix, chatID, hour.UTC().Truncate(time.Hour).Unix())
+}
+
+// parseChatActivityKey returns the chat and hour of an activity hash key.
+func parseChatActivityKey(key string) (int64, time.Time, bool) {
+	rest, ok := strings.CutPrefix(key, chatActivityKeyPrefix+":")
+	if !ok {
+		return 0, time.Time{}, false
+	}
+	chatPart, hourPart, ok := strings.Cut(rest, ":")
+	if !ok {
+		return 0, time.Time{}, false
+	}
+	chatID, err := strconv.ParseInt(chatPart, 10, 64)
+	if err != nil {
+		return 0, time.Time{}, false
+	}
+	hour, err := strconv.ParseInt(hourPart, 10, 64)
+	if err != nil {
+		return 0, time.Time{}, false
+	}
+	return chatID, time.Unix(hour, 0).UTC(), true
+}
+
+// chatActivityCounts returns what a group message adds to the chat activity:
+// a join or leave for service messages, otherwise a text or media message
+// credited to its sender. Other service messages count for nothing.
+func chatActivityCounts(msg *gotgbot.Message, sender *gotgbot.Sender) map[string]int64 {
+	counts := make(map[string]int64)
+	switch {
+	case len(msg.NewChatMembers) > 0:
+		counts[chatActivityJoins] = int64(len(msg.NewChatMembers))
+		return counts
+	case msg.LeftChatMember != nil:
+		counts[chatActivityLeaves] = 1
+		return counts
+	case msg.Text != "":
+		counts[chatActivityMessages] = 1
+	case msg.Photo != nil, msg.Video != nil, msg.Animation != nil, msg.Document != nil,
+		msg.Sticker != nil, msg.Audio != nil, msg.Voice != nil, msg.VideoNote != nil:
+		counts[chatActivityMessages] = 1
+		counts[chatActivityMedia] = 1
+	default:
+		return counts
+	}
+	if sender != nil && sender.User != nil && !sender.IsAnonymousChannel() {
+		counts[chatActivityPoster+strconv.FormatInt(sender.User.Id, 10)] = 1
+	}
+	return counts
+}
+
+// trackChatActivity adds a group message to the current hour of the chat
+// activity in Redis. Without Redis the message is not counted.
+func trackChatActivity(chat *gotgbot.Chat, msg *gotgbot.Message, sender *gotgbot.Sender) {
+	if chat == nil || msg == nil || (chat.Type != "group" && chat.Type != "supergroup") {
+		return
+	}
+	rdb := cache.GetRedisClient()
+	if rdb == nil {
+		return
+	}
+	counts := chatActivityCounts(msg, sender)
+	if len(counts) == 0 {
+		return
+	}
+
+	key := chatActivityKey(chat.Id, time.Now())
+	ctx, cancel := cache.ContextWithTimeout()
+	defer cancel()
+	_, err := rdb.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		for field, n := range counts {
+			pipe.HIncrBy(ctx, key, field, n)
+		}
+		pipe.Expire(ctx, key, chatActivityBucketTTL)
+		pipe.SAdd(ctx, chatActivityPendingKey, key)
+		return nil
+	})
+	if err != nil {
+		log.WithError(err).Debugf("[Activity] Failed to count message in chat %d", chat.Id)
+	}
+}
+
+// flushChatActivity moves the hour hashes collected in Redis into the
+// database rollups. With onlyChat set, only that chat's hashes are flushed.
+func flushChatActivity(onlyChat int64) {
+	rdb := cache.GetRedisClient()
+	if rdb == nil {
+		return
+	}
+	ctx, cancel := cache.ContextWithTimeout()
+	keys, err := rdb.SMembers(ctx, chatActivityPendingKey).Result()
+	cancel()
+	if err != nil {
+		log.WithError(err).Warn("[Activity] Failed to list pending activity")
+		return
+	}
+
+	for _, key := range keys {
+		chatID, _, ok := parseChatActivityKey(key)
+		if onlyChat != 0 && (!ok || chatID != onlyChat) {
+			continue
+		}
+		flushChatActivityKey(rdb, key)
+	}
+}
+
+// flushChatActivityKey moves one hour hash into the database rollups. A hash
+// whose write fails is put back so the next flush retries it.
+func flushChatActivityKey(rdb *redis.Client, key string) {
+	ctx, cancel := cache.ContextWithTimeout()
+	defer cancel()
+	chatID, hour, ok := parseChatActivityKey(key)
+	if !ok {
+		_ = rdb.SRem(ctx, chatActivityPendingKey, key).Err()
+		return
+	}
+	values, err := drainChatActivityScript.Run(ctx, rdb, []string{key, chatActivityPendingKey}).StringSlice()
+	if err != nil {
+		log.WithError(err).Warnf("[Activity] Failed to drain %s", key)
+		return
+	}
+	fields := make(map[string]int64, len(values)/2)
+	for i := 0; i+1 < len(values); i += 2 {
+		if n, err := strconv.ParseInt(values[i+1], 10, 64); err == nil {
+			fields[values[i]] = n
+		}
+	}
+	if len(fields) == 0 {
+		return
+	}
+
+	hourActivity := &activity.HourActivity{
+		Hour:          hour,
+		Messages:      fields[chatActivityMessages],
+		MediaMessages: fields[chatActivityMedia],
+		Joins:         fields[chatActivityJoins],
+		Leaves:        fields[chatActivityLeaves],
+		Posters:       make(map[int64]int64),
+	}
+	for field, n := range fields {
+		if id, ok := strings.CutPrefix(field, chatActivityPoster); ok {
+			if userID, err := strconv.ParseInt(id, 10, 64); err == nil {
+				hourActivity.Posters[userID] = n
+			}
+		}
+	}
+	if err := activity.AddChatActivity(chatID, hourActivity); err != nil {
+		restoreChatActivity(ctx, rdb, key, fields)
+	}
+}
+
+// restoreChatActivity adds drained counts back to their hash.
+func restoreChatActivity(ctx context.Context, rdb *redis.Client, key string, fields map[string]int64) {
+	_, err := rdb.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		for field, n := range fields {
+			pipe.HIncrBy(ctx, key, field, n)
+		}
+		pipe.Expire(ctx, key, chatActivityBucketTTL)
+		pipe.SAdd(ctx, chatActivityPendingKey, key)
+		return nil
+	})
+	if err != nil {
+		log.WithError(err).Errorf("[Activity] Lost activity counts of %s", key)
+	}
+}
+
+// StartChatActivityFlusher starts the worker that flushes chat activity from
+// Redis into the database.
+func StartChatActivityFlusher() {
+	if !cache.IsRedisAvailable() {
+		log.Warn("[Activity] Redis not available, skipping activity flusher start")
+		return
+	}
+	chatActivityFlusherMu.Lock()
+	defer chatActivityFlusherMu.Unlock()
+	if chatActivityFlusherCancel != nil {
+		return
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	chatActivityFlusherCancel = cancel
+	chatActivityFlusherWG.Add(1)
+	go func() {
+		defer chatActivityFlusherWG.Done()
+		ticker := time.NewTicker(chatActivityFlushInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				func() {
+					defer error_handling.RecoverFromPanic("chatActivityFlusher", "activity")
+					flushChatActivity(0)
+				}()
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+}
+
+// StopChatActivityFlusher stops the flush worker and flushes what is left, so
+// a restart loses no counts.
+func StopChatActivityFlusher() {
+	chatActivityFlusherMu.Lock()
+	defer chatActivityFlusherMu.Unlock()
+	if chatActivityFlusherCancel != nil {
+		chatActivityFlusherCancel()
+		chatActivityFlusherWG.Wait()
+		chatActivityFlusherCancel = nil
+		flushChatActivity(0)
+	}
+}
+
+// chatStats shows the activity of a chat over the last days.
+// Usage: /chatstats [days]
+func (moduleStruct) chatStats(b *gotgbot.Bot, ctx *ext.Context) error {
+	msg := ctx.EffectiveMessage
+	// connection status
+	connectedChat := chat_status.IsUserConnected(b, ctx, true, false)
+	if connectedChat == nil {
+		return ext.EndGroups
+	}
+	ctx.EffectiveChat = connectedChat
+	chat := ctx.EffectiveChat
+	args := ctx.Args()[1:]
+	tr := i18n.MustNewTranslator(lang.GetLanguage(ctx))
+	maxDays := int(models.ChatActivityRetention / (24 * time.Hour))
+
+	days := chatStatsDefaultDays
+	if len(args) > 0 {
+		n, err := strconv.Atoi(strings.TrimSuffix(strings.ToLower(args[0]), "d"))
+		if err != nil || n < 1 || n > maxDays {
+			text, _ := tr.GetString("activity_invalid_days", i18n.TranslationParams{"max": maxDays})
+			_, err = msg.Reply(b, text, formatting.Shtml())
+			return err
+		}
+		days = n
+	}
+
+	flushChatActivity(chat.Id)
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+	since := today.AddDate(0, 0, 1-days)
+	hours, err := activity.GetChatActivityHours(chat.Id, since)
+	if err != nil {
+		text, _ := tr.GetString("activity_load_failed")
+		_, err = msg.Reply(b, text, formatting.Shtml())
+		return err
+	}
+	posters, active, err := activity.GetTopPosters(chat.Id, since, chatStatsTopPosters)
+	if err != nil {
+		text, _ := tr.GetString("activity_load_failed")
+		_, err = msg.Reply(b, text, formatting.Shtml())
+		return err
+	}
+
+	text, _ := tr.GetString("activity_header", i18n.TranslationParams{"days": days})
+	if len(hours) == 0 {
+		empty, _ := tr.GetString("activity_empty")
+		_, err = msg.Reply(b, text+"\n\n"+empty, formatting.Shtml())
+		return err
+	}
+	_, err = msg.Reply(b, text+"\n\n"+renderChatStats(tr, hours, posters, active, since, days), formatting.Shtml())
+	return err
+}
+
+// renderChatStats formats the rollups of a chat as the /chatstats reply.
+func renderChatStats(tr *i18n.Translator, hours []models.ChatActivityHour, posters []activity.Poster, active int64, since time.Time, days int) string {
+	var messages, media, joins, leaves int64
+	var byHourOfDay [24]int64
+	byDay := make([]models.ChatActivityHour, days)
+	for _, h := range hours {
+		messages += h.Messages
+		media += h.MediaMessages
+		joins += h.Joins
+		leaves += h.Leaves
+		byHourOfDay[h.Hour.UTC().Hour()] += h.Messages
+		if i := int(h.Hour.UTC().Sub(since) / (24 * time.Hour)); i >= 0 && i < days {
+			byDay[i].Messages += h.Messages
+			byDay[i].Joins += h.Joins
+			byDay[i].Leaves += h.Leaves
+		}
+	}
+
+	mediaPercent := int64(0)
+	if messages > 0 {
+		mediaPercent = media * 100 / messages
+	}
+	summary, _ := tr.GetString("activity_summary", i18n.TranslationParams{
+		"messages": messages,
+		"per_day":  messages / int64(days),
+		"text":     100 - mediaPercent,
+		"media":    mediaPercent,
+		"active":   active,
+		"joins":    joins,
+		"leaves":   leaves,
+	})
+	sections := []string{summary}
+
+	peakOrder := make([]int, 24)
+	for i := range peakOrder {
+		peakOrder[i] = i
+	}
+	slices.SortStableFunc(peakOrder, func(a, b int) int {
+		return cmp.Compare(byHourOfDay[b], byHourOfDay[a])
+	})
+	var peaks []string
+	for _, h := range peakOrder[:chatStatsPeakHours] {
+		if byHourOfDay[h] > 0 {
+			peaks = append(peaks, fmt.Sprintf("%02d:00 (%d)", h, byHourOfDay[h]))
+		}
+	}
+	if len(peaks) > 0 {
+		peakText, _ := tr.GetString("activity_peak_hours", i18n.TranslationParams{"hours": strings.Join(peaks, ", ")})
+		sections = append(sections, peakText)
+	}
+
+	if len(posters) > 0 {
+		var sb strings.Builder
+		header, _ := tr.GetString("activity_top_posters")
+		sb.WriteString(header)
+		for i, p := range posters {
+			name := fmt.Sprintf("<code>%d</code>", p.UserID)
+			if _, fullName, found := user.GetUserInfoById(p.UserID); found && fullName != "" {
+				name = html.EscapeString(fullName)
+			}
+			fmt.Fprintf(&sb, "\n%d. %s — %d", i+1, name, p.Messages)
+		}
+		sections = append(sections, sb.String())
+	}
+
+	rows := [][]string{make([]string, 4)}
+	for i, key := range []string{"activity_col_day", "activity_col_messages", "activity_col_joins", "activity_col_leaves"} {
+		rows[0][i], _ = tr.GetString(key)
+	}
+	for i, d := range byDay {
+		rows = append(rows, []string{
+			since.AddDate(0, 0, i).Format("01-02"),
+			strconv.FormatInt(d.Messages, 10),
+			strconv.FormatInt(d.Joins, 10),
+			strconv.FormatInt(d.Leaves, 10),
+		})
+	}
+	sections = append(sections, "<pre>"+html.EscapeString(formatTextTable(rows))+"</pre>")
+
+	return strings.Join(sections, "\n\n")
+}
+
+// formatTextTable lays rows out in columns for a monospace block: the first
+// column is left aligned, the others right aligned.
+func formatTextTable(rows [][]string) string {
+	var widths []int
+	for _, row := range rows {
+		for i, cell := range row {
+			if i == len(widths) {
+				widths = append(widths, 0)
+			}
+			widths[i] = max(widths[i], utf8.RuneCountInString(cell))
+		}
+	}
+	lines := make([]string, 0, len(rows))
+	for _, row := range rows {
+		var sb strings.Builder
+		for i, cell := range row {
+			pad := strings.Repeat(" ", widths[i]-utf8.RuneCountInString(cell))
+			switch {
+			case i == 0:
+				sb.WriteString(cell + pad)
+			default:
+				sb.WriteString("  " + pad + cell)
+			}
+		}
+		lines = append(lines, strings.TrimRight(sb.String(), " "))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// LoadActivity registers the chat activity commands.
+func LoadActivity(dispatcher *ext.Dispatcher) {
+	DefaultHelpRegistry().AbleMap[activityModule.moduleName] = true
+
+	helpers.AddCommand(dispatcher, "chatstats", activityModule.chatStats)
+}
+
+func init() {
+	RegisterLegacyModule("Activity", 290, LoadActivity)
+}
@@ -0,0 +1,158 @@
+//go:build testtools
+
+package modules
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/PaulSonOfLars/gotgbot/v2"
+	"github.com/PaulSonOfLars/gotgbot/v2/ext"
+
+	"github.com/divkix/Alita_Robot/alita/db"
+	"github.com/divkix/Alita_Robot/alita/db/activity"
+	"github.com/divkix/Alita_Robot/alita/utils/cache"
+)
+
+func TestChatActivityCountsClassifiesMessages(t *testing.T) {
+	member := &gotgbot.Sender{User: &gotgbot.User{Id: 42}}
+	tests := []struct {
+		name string
+		msg  *gotgbot.Message
+		want map[string]int64
+	}{
+		{name: "text", msg: &gotgbot.Message{Text: "hi"}, want: map[string]int64{"m": 1, "u:42": 1}},
+		{name: "photo", msg: &gotgbot.Message{Photo: []gotgbot.PhotoSize{{FileId: "p"}}}, want: map[string]int64{"m": 1, "media": 1, "u:42": 1}},
+		{name: "joins", msg: &gotgbot.Message{NewChatMembers: []gotgbot.User{{Id: 1}, {Id: 2}}}, want: map[string]int64{"join": 2}},
+		{name: "leave", msg: &gotgbot.Message{LeftChatMember: &gotgbot.User{Id: 1}}, want: map[string]int64{"leave": 1}},
+		{name: "other service message", msg: &gotgbot.Message{NewChatTitle: "title"}, want: map[string]int64{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := chatActivityCounts(tt.msg, member)
+			if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+				t.Fatalf("chatActivityCounts() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	anonymous := &gotgbot.Sender{Chat: &gotgbot.Chat{Id: -100, Type: "channel"}}
+	if got := chatActivityCounts(&gotgbot.Message{Text: "hi"}, anonymous); fmt.Sprint(got) != "map[m:1]" {
+		t.Fatalf("chatActivityCounts(channel) = %v, want the message without a poster", got)
+	}
+}
+
+func TestParseChatActivityKeyRoundTrips(t *testing.T) {
+	hour := time.Date(2026, 10, 18, 13, 0, 0, 0, time.UTC)
+	chatID, gotHour, ok := parseChatActivityKey(chatActivityKey(-1001234, hour.Add(25*time.Minute)))
+	if !ok || chatID != -1001234 || !gotHour.Equal(hour) {
+		t.Fatalf("parseChatActivityKey() = %d, %v, %v; want -1001234, %v, true", chatID, gotHour, ok, hour)
+	}
+	for _, key := range []string{"alita:activity:pending", "alita:activity:x:1", "other:1:2"} {
+		if _, _, ok := parseChatActivityKey(key); ok {
+			t.Fatalf("parseChatActivityKey(%q) ok = true, want false", key)
+		}
+	}
+}
+
+func TestTrackAndFlushChatActivity(t *testing.T) {
+	withMiniredis(t)
+
+	chat := &gotgbot.Chat{Id: uniqueModuleChatID(), Type: "supergroup"}
+	t.Cleanup(func() {
+		_ = db.DB.Where("chat_id = ?", chat.Id).Delete(&db.ChatActivityHour{}).Error
+		_ = db.DB.Where("chat_id = ?", chat.Id).Delete(&db.ChatActivityUser{}).Error
+	})
+	alice := &gotgbot.Sender{User: &gotgbot.User{Id: 1}}
+	bob := &gotgbot.Sender{User: &gotgbot.User{Id: 2}}
+	trackChatActivity(chat, &gotgbot.Message{Text: "a"}, alice)
+	trackChatActivity(chat, &gotgbot.Message{Text: "b"}, alice)
+	trackChatActivity(chat, &gotgbot.Message{Sticker: &gotgbot.Sticker{FileId: "s"}}, bob)
+	trackChatActivity(chat, &gotgbot.Message{NewChatMembers: []gotgbot.User{{Id: 3}}}, bob)
+	trackChatActivity(&gotgbot.Chat{Id: 42, Type: "private"}, &gotgbot.Message{Text: "pm"}, alice)
+
+	rdb := cache.GetRedisClient()
+	if pending := rdb.SCard(cache.Context, chatActivityPendingKey).Val(); pending != 1 {
+		t.Fatalf("pending hashes = %d, want one for the group only", pending)
+	}
+
+	flushChatActivity(chat.Id)
+	if pending := rdb.SCard(cache.Context, chatActivityPendingKey).Val(); pending != 0 {
+		t.Fatalf("pending hashes after flush = %d, want 0", pending)
+	}
+	hours, err := activity.GetChatActivityHours(chat.Id, time.Now().Add(-time.Hour))
+	if err != nil || len(hours) != 1 {
+		t.Fatalf("GetChatActivityHours() = %v, %v; want one hour", hours, err)
+	}
+	if h := hours[0]; h.Messages != 3 || h.MediaMessages != 1 || h.Joins != 1 {
+		t.Fatalf("flushed hour = %+v, want 3 messages, 1 media and 1 join", h)
+	}
+	posters, active, err := activity.GetTopPosters(chat.Id, time.Now(), 5)
+	if err != nil || active != 2 || len(posters) != 2 || posters[0].UserID != 1 || posters[0].Messages != 2 {
+		t.Fatalf("GetTopPosters() = %+v, %d, %v; want alice first with 2 messages", posters, active, err)
+	}
+
+	trackChatActivity(chat, &gotgbot.Message{Text: "c"}, bob)
+	flushChatActivity(0)
+	hours, _ = activity.GetChatActivityHours(chat.Id, time.Now().Add(-time.Hour))
+	if len(hours) != 1 || hours[0].Messages != 4 {
+		t.Fatalf("hours after second flush = %+v, want the new message added to the hour", hours)
+	}
+}
+
+func TestChatStatsCommandRendersActivity(t *testing.T) {
+	withMiniredis(t)
+
+	client := newModuleBotClient()
+	bot := newModuleTestBot(client)
+	chat := gotgbot.Chat{Id: uniqueModuleChatID(), Type: "supergroup", Title: "Stats Chat"}
+	admin := gotgbot.User{Id: 777000, FirstName: "Telegram"}
+	t.Cleanup(func() {
+		_ = db.DB.Where("chat_id = ?", chat.Id).Delete(&db.ChatActivityHour{}).Error
+		_ = db.DB.Where("chat_id = ?", chat.Id).Delete(&db.ChatActivityUser{}).Error
+	})
+	run := func(from gotgbot.User, text string) string {
+		t.Helper()
+		if err := activityModule.chatStats(bot, newModuleMessageContext(bot, chat, from, text)); err != nil && !errors.Is(err, ext.EndGroups) {
+			t.Fatalf("%s error = %v", text, err)
+		}
+		calls := client.callsFor("sendMessage")
+		if len(calls) == 0 {
+			t.Fatalf("%s sent no reply", text)
+		}
+		return fmt.Sprint(calls[len(calls)-1].Params["text"])
+	}
+
+	if text := run(admin, "/chatstats"); strings.Contains(text, "<pre>") {
+		t.Fatalf("/chatstats without activity = %q, want no table", text)
+	}
+
+	for i := 0; i < 3; i++ {
+		trackChatActivity(&chat, &gotgbot.Message{Text: "hello"}, &gotgbot.Sender{User: &gotgbot.User{Id: int64(10 + i)}})
+	}
+	text := run(admin, "/chatstats 3")
+	today := time.Now().UTC().Format("01-02")
+	if !strings.Contains(text, "<pre>") || !strings.Contains(text, today) {
+		t.Fatalf("/chatstats 3 = %q, want a daily table ending today", text)
+	}
+	if got := strings.Count(text, "\n"+time.Now().UTC().AddDate(0, 0, -3).Format("01-02")); got != 0 {
+		t.Fatalf("/chatstats 3 = %q, want only the last 3 days", text)
+	}
+
+	before := len(client.callsFor("sendMessage"))
+	run(admin, "/chatstats 31")
+	if after := len(client.callsFor("sendMessage")); after != before+1 {
+		t.Fatalf("sendMessage calls = %d, want one reply for invalid days", after)
+	}
+}
+
+func TestFormatTextTableAlignsColumns(t *testing.T) {
+	got := formatTextTable([][]string{{"Day", "Msgs"}, {"10-18", "5"}, {"10-19", "120"}})
+	want := "Day    Msgs\n10-18     5\n10-19   120"
+	if got != want {
+		t.Fatalf("formatTextTable() = %q, want %q", got, want)
+	}
+}
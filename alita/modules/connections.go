@@ -18,6 +18,7 @@ import (
 	"github.com/divkix/Alita_Robot/alita/utils/chat_status"
 	"github.com/divkix/Alita_Robot/alita/utils/extraction"
 	"github.com/divkix/Alita_Robot/alita/utils/formatting"
+	"github.com/divkix/Alita_Robot/alita/utils/helpers"
 	"github.com/divkix/Alita_Robot/alita/utils/keyboard"
 )
 
@@ -405,11 +406,11 @@ func (m moduleStruct) reconnect(b *gotgbot.Bot, ctx *ext.Context) error {
 func LoadConnections(dispatcher *ext.Dispatcher) {
 	DefaultHelpRegistry().AbleMap[ConnectionsModule.moduleName] = true
 
-	dispatcher.AddHandler(handlers.NewCommand("connect", ConnectionsModule.connect))
-	dispatcher.AddHandler(handlers.NewCommand("disconnect", ConnectionsModule.disconnect))
-	dispatcher.AddHandler(handlers.NewCommand("connection", ConnectionsModule.connection))
-	dispatcher.AddHandler(handlers.NewCommand("reconnect", ConnectionsModule.reconnect))
-	dispatcher.AddHandler(handlers.NewCommand("allowconnect", ConnectionsModule.allowConnect))
+	helpers.AddCommand(dispatcher, "connect", ConnectionsModule.connect)
+	helpers.AddCommand(dispatcher, "disconnect", ConnectionsModule.disconnect)
+	helpers.AddCommand(dispatcher, "connection", ConnectionsModule.connection)
+	helpers.AddCommand(dispatcher, "reconnect", ConnectionsModule.reconnect)
+	helpers.AddCommand(dispatcher, "allowconnect", ConnectionsModule.allowConnect)
 	dispatcher.AddHandler(handlers.NewCallback(callbackquery.Prefix("connbtns"), ConnectionsModule.connectionButtons))
 }
 
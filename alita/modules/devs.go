@@ -6,7 +6,6 @@ import (
 	"strconv"
 	"strings"
 
-	"github.com/PaulSonOfLars/gotgbot/v2/ext/handlers"
 	log "github.com/sirupsen/logrus"
 
 	"github.com/PaulSonOfLars/gotgbot/v2"
@@ -20,6 +19,7 @@ import (
 	"github.com/divkix/Alita_Robot/alita/utils/chat_status"
 	"github.com/divkix/Alita_Robot/alita/utils/extraction"
 	"github.com/divkix/Alita_Robot/alita/utils/formatting"
+	"github.com/divkix/Alita_Robot/alita/utils/helpers"
 )
 
 var devsModule = moduleStruct{moduleName: "Dev"}
@@ -446,15 +446,15 @@ func (moduleStruct) getStats(b *gotgbot.Bot, ctx *ext.Context) error {
 // LoadDev registers all development-related command handlers with the dispatcher.
 // Sets up admin commands for bot management, user management, and statistics.
 func LoadDev(dispatcher *ext.Dispatcher) {
-	dispatcher.AddHandler(handlers.NewCommand("stats", devsModule.getStats))
-	dispatcher.AddHandler(handlers.NewCommand("addsudo", devsModule.addSudo))
-	dispatcher.AddHandler(handlers.NewCommand("adddev", devsModule.addDev))
-	dispatcher.AddHandler(handlers.NewCommand("remsudo", devsModule.remSudo))
-	dispatcher.AddHandler(handlers.NewCommand("remdev", devsModule.remDev))
-	dispatcher.AddHandler(handlers.NewCommand("teamusers", devsModule.listTeam))
-	dispatcher.AddHandler(handlers.NewCommand("chatinfo", devsModule.chatInfo))
-	dispatcher.AddHandler(handlers.NewCommand("chatlist", devsModule.chatList))
-	dispatcher.AddHandler(handlers.NewCommand("leavechat", devsModule.leaveChat))
+	helpers.AddCommand(dispatcher, "stats", devsModule.getStats)
+	helpers.AddCommand(dispatcher, "addsudo", devsModule.addSudo)
+	helpers.AddCommand(dispatcher, "adddev", devsModule.addDev)
+	helpers.AddCommand(dispatcher, "remsudo", devsModule.remSudo)
+	helpers.AddCommand(dispatcher, "remdev", devsModule.remDev)
+	helpers.AddCommand(dispatcher, "teamusers", devsModule.listTeam)
+	helpers.AddCommand(dispatcher, "chatinfo", devsModule.chatInfo)
+	helpers.AddCommand(dispatcher, "chatlist", devsModule.chatList)
+	helpers.AddCommand(dispatcher, "leavechat", devsModule.leaveChat)
 }
 
 func init() {
@@ -2,6 +2,8 @@ package modules
 
 import (
 	"fmt"
+	"html"
+	"regexp"
 	"slices"
 	"strings"
 
@@ -11,6 +13,7 @@ import (
 	"github.com/PaulSonOfLars/gotgbot/v2/ext"
 	"github.com/PaulSonOfLars/gotgbot/v2/ext/handlers"
 
+	"github.com/divkix/Alita_Robot/alita/db"
 	"github.com/divkix/Alita_Robot/alita/db/disabling"
 	"github.com/divkix/Alita_Robot/alita/db/lang"
 	"github.com/divkix/Alita_Robot/alita/i18n"
@@ -19,7 +22,10 @@ import (
 	"github.com/divkix/Alita_Robot/alita/utils/helpers"
 )
 
-var disablingModule = moduleStruct{moduleName: "Disabling"}
+var disablingModule = moduleStruct{moduleName: "Disabling", handlerGroup: -2}
+
+// cmdPermNameRegex matches a bare Telegram command name accepted by /cmdperm.
+var cmdPermNameRegex = regexp.MustCompile(`^[a-z0-9_]{1,32}$`)
 
 /*
 	To disable or enable commands
@@ -311,17 +317,185 @@ func (moduleStruct) disabledel(b *gotgbot.Bot, ctx *ext.Context) error {
 	return ext.EndGroups
 }
 
+/*
+	To set who may use a command
+
+# Connection - true, true
+
+Only admins can use this command to restrict a command to approved users,
+admins, or admins holding a specific right. Setting "everyone" removes the override.
+*/
+// cmdPerm shows or changes the permission override for a single command.
+// With one argument it shows the current policy; with two it sets it.
+func (moduleStruct) cmdPerm(b *gotgbot.Bot, ctx *ext.Context) error {
+	msg := ctx.EffectiveMessage
+	// connection status
+	connectedChat := chat_status.IsUserConnected(b, ctx, true, true)
+	if connectedChat == nil {
+		return ext.EndGroups
+	}
+	ctx.EffectiveChat = connectedChat
+	chat := ctx.EffectiveChat
+	args := ctx.Args()[1:]
+	tr := i18n.MustNewTranslator(lang.GetLanguage(ctx))
+
+	var text string
+	if len(args) == 0 {
+		text, _ = tr.GetString("disabling_cmdperm_usage")
+		_, err := msg.Reply(b, text, formatting.Shtml())
+		if err != nil {
+			log.Error(err)
+			return err
+		}
+		return ext.EndGroups
+	}
+
+	cmd := strings.TrimPrefix(strings.ToLower(args[0]), "/")
+	if !cmdPermNameRegex.MatchString(cmd) {
+		temp, _ := tr.GetString("disabling_cmdperm_invalid_command")
+		text = fmt.Sprintf(temp, html.EscapeString(args[0]))
+	} else {
+		if name, ok := helpers.PipelineCommandName(cmd); ok {
+			cmd = name
+		}
+		switch {
+		case !helpers.IsRegisteredCommand(cmd):
+			temp, _ := tr.GetString("disabling_cmdperm_unknown_command")
+			text = fmt.Sprintf(temp, cmd)
+		case len(args) == 1:
+			policy := disabling.LevelEveryone
+			if perm := disabling.GetCommandPermission(chat.Id, cmd); perm != nil {
+				policy = perm.Policy()
+			}
+			temp, _ := tr.GetString("disabling_cmdperm_current")
+			text = fmt.Sprintf(temp, cmd, policy)
+		default:
+			level, right, ok := disabling.ParseCommandPolicy(args[1])
+			if !ok {
+				text, _ = tr.GetString("disabling_cmdperm_invalid_policy")
+				break
+			}
+			if err := disabling.SetCommandPermission(chat.Id, cmd, level, right); err != nil {
+				log.Errorf("[Disabling] Failed to set permission for command '%s' in chat %d: %v", cmd, chat.Id, err)
+				text, _ = tr.GetString("error_generic")
+				break
+			}
+			if level == disabling.LevelEveryone {
+				temp, _ := tr.GetString("disabling_cmdperm_reset")
+				text = fmt.Sprintf(temp, cmd)
+				break
+			}
+			policy := (&db.CommandPermission{Level: level, RequiredRight: right}).Policy()
+			temp, _ := tr.GetString("disabling_cmdperm_set")
+			text = fmt.Sprintf(temp, cmd, policy)
+		}
+	}
+
+	_, err := msg.Reply(b, text, formatting.Shtml())
+	if err != nil {
+		log.Error(err)
+		return err
+	}
+	return ext.EndGroups
+}
+
+/*
+	To list command permissions
+
+# Connection - false, true
+
+Any user can use this command to see who may use each command in the chat.
+*/
+// cmdPerms lists who may use each command in the chat: the /cmdperm
+// override where one is set, everyone otherwise. Aliases of pipeline
+// commands share the policy of their command and are left out.
+func (moduleStruct) cmdPerms(b *gotgbot.Bot, ctx *ext.Context) error {
+	msg := ctx.EffectiveMessage
+	// connection status
+	connectedChat := chat_status.IsUserConnected(b, ctx, false, true)
+	if connectedChat == nil {
+		return ext.EndGroups
+	}
+	ctx.EffectiveChat = connectedChat
+	chat := ctx.EffectiveChat
+	tr := i18n.MustNewTranslator(lang.GetLanguage(ctx))
+
+	overrides := make(map[string]string)
+	for _, perm := range disabling.GetCommandPermissions(chat.Id) {
+		overrides[perm.Command] = perm.Policy()
+	}
+
+	text, _ := tr.GetString("disabling_cmdperms_header")
+	var sb strings.Builder
+	for _, cmd := range helpers.RegisteredCommands() {
+		if name, ok := helpers.PipelineCommandName(cmd); ok && name != cmd {
+			continue
+		}
+		policy, ok := overrides[cmd]
+		if !ok {
+			policy = disabling.LevelEveryone
+		}
+		fmt.Fprintf(&sb, "\n - /%s: <code>%s</code>", cmd, policy)
+	}
+	text += sb.String()
+
+	for _, msgText := range formatting.SplitMessage(text) {
+		_, err := msg.Reply(b, msgText, formatting.Shtml())
+		if err != nil {
+			log.Error(err)
+			return err
+		}
+	}
+	return ext.EndGroups
+}
+
+// commandPermissionGate enforces /cmdperm overrides for commands that are not
+// registered through the helpers.WrapCommand pipeline. It runs before the
+// command handlers and stops the update when the sender is not permitted.
+func (moduleStruct) commandPermissionGate(b *gotgbot.Bot, ctx *ext.Context) error {
+	msg := ctx.EffectiveMessage
+	chat := ctx.EffectiveChat
+	if msg == nil || chat == nil || chat.Type == "private" {
+		return ext.ContinueGroups
+	}
+
+	cmd := helpers.ExtractCommand(b, msg)
+	if cmd == "" {
+		return ext.ContinueGroups
+	}
+	if _, ok := helpers.PipelineCommandName(cmd); ok {
+		return ext.ContinueGroups
+	}
+	if disabling.GetCommandPermission(chat.Id, cmd) == nil {
+		return ext.ContinueGroups
+	}
+
+	c, err := helpers.BuildCommandContext(b, ctx)
+	if err != nil {
+		return ext.EndGroups
+	}
+	if !helpers.CheckCommandPermission(cmd)(c) {
+		return ext.EndGroups
+	}
+	return ext.ContinueGroups
+}
+
 // LoadDisabling registers all disabling-related command handlers with the dispatcher.
 // Sets up commands for managing which bot commands are enabled or disabled in chats.
 func LoadDisabling(dispatcher *ext.Dispatcher) {
 	DefaultHelpRegistry().AbleMap[disablingModule.moduleName] = true
 
-	dispatcher.AddHandler(handlers.NewCommand("disable", disablingModule.disable))
-	dispatcher.AddHandler(handlers.NewCommand("disableable", disablingModule.disableable))
-	dispatcher.AddHandler(handlers.NewCommand("disabled", disablingModule.disabled))
+	helpers.AddCommand(dispatcher, "disable", disablingModule.disable)
+	helpers.AddCommand(dispatcher, "disableable", disablingModule.disableable)
+	helpers.AddCommand(dispatcher, "disabled", disablingModule.disabled)
 	helpers.AddCmdToDisableable("disabled")
-	dispatcher.AddHandler(handlers.NewCommand("disabledel", disablingModule.disabledel))
-	dispatcher.AddHandler(handlers.NewCommand("enable", disablingModule.enable))
+	helpers.AddCommand(dispatcher, "disabledel", disablingModule.disabledel)
+	helpers.AddCommand(dispatcher, "enable", disablingModule.enable)
+	helpers.AddCommand(dispatcher, "cmdperm", disablingModule.cmdPerm)
+	helpers.AddCommand(dispatcher, "cmdperms", disablingModule.cmdPerms)
+	dispatcher.AddHandlerToGroup(handlers.NewMessage(func(msg *gotgbot.Message) bool {
+		return strings.HasPrefix(msg.GetText(), "/")
+	}, disablingModule.commandPermissionGate), disablingModule.handlerGroup)
 }
 
 func init() {
@@ -9,6 +9,7 @@ import (
 	"github.com/PaulSonOfLars/gotgbot/v2"
 	"github.com/PaulSonOfLars/gotgbot/v2/ext"
 
+	"github.com/divkix/Alita_Robot/alita/db/approvals"
 	"github.com/divkix/Alita_Robot/alita/db/disabling"
 	"github.com/divkix/Alita_Robot/alita/utils/helpers"
 )
@@ -166,3 +167,92 @@ func containsString(values []string, target string) bool {
 	}
 	return false
 }
+
+func TestCmdPermSetsPolicyAndGateEnforcesIt(t *testing.T) {
+	client := newModuleBotClient()
+	bot := newModuleTestBot(client)
+	chatID := uniqueModuleChatID()
+	chat := gotgbot.Chat{Id: chatID, Type: "supergroup", Title: "Cmdperm Chat"}
+	admin := gotgbot.User{Id: 777000, FirstName: "Telegram"}
+	member := gotgbot.User{Id: 42, FirstName: "Member"}
+	helpers.SetRegisteredCmdsForTest(t, []string{"cmdperm", "notes", "report"})
+
+	setCtx := newModuleMessageContext(bot, chat, admin, "/cmdperm report approved")
+	if err := disablingModule.cmdPerm(bot, setCtx); err != ext.EndGroups {
+		t.Fatalf("cmdPerm() error = %v, want EndGroups", err)
+	}
+	perm := disabling.GetCommandPermission(chatID, "report")
+	if perm == nil || perm.Policy() != disabling.LevelApproved {
+		t.Fatalf("report permission = %+v, want approved", perm)
+	}
+
+	gateCtx := newModuleMessageContext(bot, chat, member, "/report spam")
+	if err := disablingModule.commandPermissionGate(bot, gateCtx); err != ext.EndGroups {
+		t.Fatalf("commandPermissionGate() error = %v, want EndGroups for unapproved member", err)
+	}
+	if err := approvals.SetApproval(chatID, member.Id, admin.Id, "", nil, nil); err != nil {
+		t.Fatalf("SetApproval() error = %v", err)
+	}
+	gateCtx = newModuleMessageContext(bot, chat, member, "/report spam")
+	if err := disablingModule.commandPermissionGate(bot, gateCtx); err != ext.ContinueGroups {
+		t.Fatalf("commandPermissionGate() error = %v, want ContinueGroups for approved member", err)
+	}
+	otherCtx := newModuleMessageContext(bot, chat, member, "/notes")
+	if err := disablingModule.commandPermissionGate(bot, otherCtx); err != ext.ContinueGroups {
+		t.Fatalf("commandPermissionGate() error = %v, want ContinueGroups without override", err)
+	}
+
+	resetCtx := newModuleMessageContext(bot, chat, admin, "/cmdperm report everyone")
+	if err := disablingModule.cmdPerm(bot, resetCtx); err != ext.EndGroups {
+		t.Fatalf("cmdPerm() reset error = %v, want EndGroups", err)
+	}
+	if perm := disabling.GetCommandPermission(chatID, "report"); perm != nil {
+		t.Fatalf("report permission = %+v after reset, want nil", perm)
+	}
+}
+
+func TestCmdPermRejectsInvalidPolicyAndListsOverrides(t *testing.T) {
+	client := newModuleBotClient()
+	bot := newModuleTestBot(client)
+	chatID := uniqueModuleChatID()
+	chat := gotgbot.Chat{Id: chatID, Type: "supergroup", Title: "Cmdperm Chat"}
+	admin := gotgbot.User{Id: 777000, FirstName: "Telegram"}
+	helpers.SetRegisteredCmdsForTest(t, []string{"cmdperm", "cmdperms", "notes", "warn"})
+
+	unknownCtx := newModuleMessageContext(bot, chat, admin, "/cmdperm nosuchcommand admins")
+	if err := disablingModule.cmdPerm(bot, unknownCtx); err != ext.EndGroups {
+		t.Fatalf("cmdPerm() error = %v, want EndGroups", err)
+	}
+	if perm := disabling.GetCommandPermission(chatID, "nosuchcommand"); perm != nil {
+		t.Fatalf("nosuchcommand permission = %+v, want nil for unknown command", perm)
+	}
+
+	invalidCtx := newModuleMessageContext(bot, chat, admin, "/cmdperm warn admins:can_fly")
+	if err := disablingModule.cmdPerm(bot, invalidCtx); err != ext.EndGroups {
+		t.Fatalf("cmdPerm() error = %v, want EndGroups", err)
+	}
+	if perm := disabling.GetCommandPermission(chatID, "warn"); perm != nil {
+		t.Fatalf("warn permission = %+v, want nil for invalid right", perm)
+	}
+
+	setCtx := newModuleMessageContext(bot, chat, admin, "/cmdperm /warn admins:can_restrict")
+	if err := disablingModule.cmdPerm(bot, setCtx); err != ext.EndGroups {
+		t.Fatalf("cmdPerm() error = %v, want EndGroups", err)
+	}
+
+	listCtx := newModuleMessageContext(bot, chat, admin, "/cmdperms")
+	if err := disablingModule.cmdPerms(bot, listCtx); err != ext.EndGroups {
+		t.Fatalf("cmdPerms() error = %v, want EndGroups", err)
+	}
+	calls := client.callsFor("sendMessage")
+	if len(calls) == 0 {
+		t.Fatal("cmdPerms() sent no message")
+	}
+	text := calls[len(calls)-1].Params["text"].(string)
+	if !strings.Contains(text, "/warn: <code>admins:can_restrict</code>") {
+		t.Fatalf("cmdPerms() text = %q, want warn override listed", text)
+	}
+	if !strings.Contains(text, "/notes: <code>everyone</code>") {
+		t.Fatalf("cmdPerms() text = %q, want notes listed with the default policy", text)
+	}
+}
@@ -138,6 +138,12 @@ func (m moduleStruct) addFilter(b *gotgbot.Bot, ctx *ext.Context) error {
 		return ext.EndGroups
 	}
 
+	// A reply to any message of an album saves the whole album.
+	media := albumMedia(msg.ReplyToMessage)
+	if len(media) > 0 {
+		fileid, dataType = media[0].FileID, media[0].MsgType
+	}
+
 	filterWord = strings.ToLower(filterWord) // convert string to it's lower form
 
 	// Validate keyword length - max 100 characters
@@ -152,7 +158,18 @@ func (m moduleStruct) addFilter(b *gotgbot.Bot, ctx *ext.Context) error {
 		return ext.EndGroups
 	}
 
-	if db_filters.DoesFilterExists(chat.Id, filterWord) {
+	// Keywords are unique per chat: a filter set in another topic (or for the
+	// whole chat) has to be stopped before it can be set here.
+	if filterTopic, exists := db_filters.GetFilterTopic(chat.Id, filterWord); exists && filterTopic != messageTopic(msg) {
+		tr := i18n.MustNewTranslator(lang.GetLanguage(ctx))
+		text, _ := tr.GetString("filters_other_topic")
+		_, err := msg.Reply(b, fmt.Sprintf(text, html.EscapeString(filterWord)), formatting.Shtml())
+		if err != nil {
+			log.Error(err)
+			return err
+		}
+		return ext.EndGroups
+	} else if exists {
 		token, tokenErr := newOverwriteToken()
 		if tokenErr != nil {
 			log.Errorf("[Filters] Failed to generate overwrite token: %v", tokenErr)
@@ -171,8 +188,10 @@ func (m moduleStruct) addFilter(b *gotgbot.Bot, ctx *ext.Context) error {
 				Text:     text,
 				FileID:   fileid,
 				Buttons:  buttons,
+				Media:    media,
 				DataType: dataType,
 			},
+			ThreadID: messageTopic(msg),
 		})
 		if err != nil {
 			log.Errorf("[Filters] Failed to cache overwrite data: %v", err)
@@ -221,7 +240,7 @@ func (m moduleStruct) addFilter(b *gotgbot.Bot, ctx *ext.Context) error {
 	}
 
 	// Perform DB operation synchronously to ensure completion before confirmation
-	if err := db_filters.AddFilter(chat.Id, filterWord, text, fileid, buttons, dataType); err != nil {
+	if err := db_filters.AddTopicFilter(chat.Id, messageTopic(msg), filterWord, text, fileid, buttons, media, dataType); err != nil {
 		log.Errorf("[Filters] AddFilter failed for chat %d: %v", chat.Id, err)
 		tr := i18n.MustNewTranslator(lang.GetLanguage(ctx))
 		errText, _ := tr.GetString("common_settings_save_failed")
@@ -231,7 +250,7 @@ func (m moduleStruct) addFilter(b *gotgbot.Bot, ctx *ext.Context) error {
 
 	tr := i18n.MustNewTranslator(lang.GetLanguage(ctx))
 	successText, _ := tr.GetString("filters_added_success")
-	_, err := msg.Reply(b, fmt.Sprintf(successText, filterWord), formatting.Shtml())
+	_, err := msg.Reply(b, fmt.Sprintf(successText, filterWord)+topicScopeNote(tr, messageTopic(msg)), formatting.Shtml())
 	if err != nil {
 		log.Error(err)
 		return err
@@ -292,14 +311,14 @@ func (moduleStruct) rmFilter(b *gotgbot.Bot, ctx *ext.Context) error {
 			}
 		} else {
 			// Perform DB operation synchronously to ensure completion before confirmation
-			if err := db_filters.RemoveFilter(chat.Id, strings.ToLower(filterWord)); err != nil {
+			if err := db_filters.RemoveFilter(chat.Id, user.Id, strings.ToLower(filterWord)); err != nil {
 				log.Errorf("[Filters] RemoveFilter failed for chat %d: %v", chat.Id, err)
 				errText, _ := tr.GetString("common_settings_save_failed")
 				_, _ = msg.Reply(b, errText, formatting.Shtml())
 				return ext.EndGroups
 			}
 			successText, _ := tr.GetString("filters_removed_success")
-			_, err := msg.Reply(b, fmt.Sprintf(successText, filterWord), formatting.Shtml())
+			_, err := msg.Reply(b, fmt.Sprintf(successText, filterWord)+topicScopeNote(tr, messageTopic(msg)), formatting.Shtml())
 			if err != nil {
 				log.Error(err)
 				return err
@@ -465,17 +484,18 @@ func (moduleStruct) filtersButtonHandler(b *gotgbot.Bot, ctx *ext.Context) error
 		_, _ = query.Answer(b, &gotgbot.AnswerCallbackQueryOpts{Text: text})
 		return ext.EndGroups
 	}
-	var helpText string
+	var helpText, undoHint string
 
 	switch response {
 	case "yes":
-		if err := db_filters.RemoveAllFilters(chat.Id); err != nil {
+		if err := db_filters.RemoveAllFilters(chat.Id, user.Id); err != nil {
 			helpText, _ = tr.GetString("filters_clear_all_failed")
 			if helpText == "" {
 				helpText = "Failed to remove all Filters from this Chat ❌"
 			}
 		} else {
 			helpText, _ = tr.GetString("filters_clear_all_success")
+			undoHint, _ = tr.GetString("revisions_clearall_hint", i18n.TranslationParams{"command": "filterrestore"})
 		}
 	case "no":
 		helpText, _ = tr.GetString("filters_clear_all_cancelled")
@@ -491,7 +511,7 @@ func (moduleStruct) filtersButtonHandler(b *gotgbot.Bot, ctx *ext.Context) error
 	}
 
 	_, _, err := query.Message.EditText(b,
-		helpText,
+		helpText+undoHint,
 		nil,
 	)
 	if err != nil {
@@ -613,10 +633,13 @@ func (m moduleStruct) filterOverWriteHandler(b *gotgbot.Bot, ctx *ext.Context) e
 
 	updated, updateErr := db_filters.UpdateFilter(
 		chat.Id,
+		user.Id,
+		filterData.ThreadID,
 		filterData.ItemName,
 		filterData.Text,
 		filterData.FileID,
 		filterData.Buttons,
+		filterData.Media,
 		filterData.DataType,
 	)
 	if updateErr != nil {
@@ -624,6 +647,7 @@ func (m moduleStruct) filterOverWriteHandler(b *gotgbot.Bot, ctx *ext.Context) e
 		helpText, _ = tr.GetString("common_settings_save_failed")
 	} else if updated {
 		helpText, _ = tr.GetString("filters_overwrite_success")
+		helpText += topicScopeNote(tr, filterData.ThreadID)
 	} else {
 		helpText, _ = tr.GetString("filters_overwrite_cancelled")
 	}
@@ -702,7 +726,12 @@ func (moduleStruct) filtersWatcher(b *gotgbot.Bot, ctx *ext.Context) error {
 	matcher := cache.GetOrCreateMatcher(chat.Id, filterKeys)
 
 	// Find first matching filter using optimized path
-	firstPattern, found := matcher.FirstMatch(matchText)
+	// Filters set in a forum topic only answer there.
+	threadID := messageTopic(msg)
+	firstPattern, found := matcher.FirstMatchWhere(matchText, func(keyword string) bool {
+		filter := filterMap[keyword]
+		return filter != nil && (filter.ThreadId == 0 || filter.ThreadId == threadID)
+	})
 	if !found {
 		return ext.ContinueGroups
 	}
@@ -738,6 +767,7 @@ func (moduleStruct) filtersWatcher(b *gotgbot.Bot, ctx *ext.Context) error {
 			FileID:  filtData.FileID,
 			MsgType: filtData.MsgType,
 			Name:    filtData.KeyWord,
+			Media:   rawAlbumMedia(filtData.Media),
 		}, media.Options{
 			ChatID:            ctx.Message.Chat.Id,
 			ReplyMsgID:        msg.MessageId,
@@ -781,16 +811,19 @@ func LoadFilters(dispatcher *ext.Dispatcher) {
 			},
 		},
 	} // Adds Formatting kb button to Filters Menu
-	dispatcher.AddHandler(handlers.NewCommand("filter", filtersModule.addFilter))
-	dispatcher.AddHandler(handlers.NewCommand("addfilter", filtersModule.addFilter))
-	dispatcher.AddHandler(handlers.NewCommand("stop", filtersModule.rmFilter))
-	dispatcher.AddHandler(handlers.NewCommand("rmfilter", filtersModule.rmFilter))
-	dispatcher.AddHandler(handlers.NewCommand("removefilter", filtersModule.rmFilter))
-	dispatcher.AddHandler(handlers.NewCommand("filters", filtersModule.filtersList))
+	helpers.AddCommand(dispatcher, "filter", filtersModule.addFilter)
+	helpers.AddCommand(dispatcher, "addfilter", filtersModule.addFilter)
+	helpers.AddCommand(dispatcher, "stop", filtersModule.rmFilter)
+	helpers.AddCommand(dispatcher, "rmfilter", filtersModule.rmFilter)
+	helpers.AddCommand(dispatcher, "removefilter", filtersModule.rmFilter)
+	helpers.AddCommand(dispatcher, "filters", filtersModule.filtersList)
 	helpers.AddCmdToDisableable("filters")
-	dispatcher.AddHandler(handlers.NewCommand("stopall", filtersModule.rmAllFilters))
+	helpers.AddCommand(dispatcher, "stopall", filtersModule.rmAllFilters)
 	dispatcher.AddHandler(handlers.NewCallback(callbackquery.Prefix("rmAllFilters"), filtersModule.filtersButtonHandler))
 	dispatcher.AddHandler(handlers.NewCallback(callbackquery.Prefix("filters_overwrite"), filtersModule.filterOverWriteHandler))
+	helpers.AddCommand(dispatcher, "filterhistory", filtersModule.revisionHistory(filterRevisionKind))
+	helpers.AddCommand(dispatcher, "filterrestore", filtersModule.revisionRestore(filterRevisionKind))
+	dispatcher.AddHandler(handlers.NewCallback(callbackquery.Prefix(filterRevisionKind.namespace), filtersModule.revisionCallback(filterRevisionKind)))
 	dispatcher.AddHandlerToGroup(handlers.NewMessage(func(msg *gotgbot.Message) bool {
 		return msg.Text != "" || msg.Caption != ""
 	}, filtersModule.filtersWatcher), filtersModule.handlerGroup)
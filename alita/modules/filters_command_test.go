@@ -105,7 +105,7 @@ func TestFilterCommandValidationBranches(t *testing.T) {
 		t.Fatal("100-rune filter keyword was rejected")
 	}
 
-	if err := filters.AddFilter(chat.Id, "dupe", "old", "", nil, db.TEXT); err != nil {
+	if err := filters.AddFilter(chat.Id, "dupe", "old", "", nil, nil, db.TEXT); err != nil {
 		t.Fatalf("AddFilter setup error = %v", err)
 	}
 	overwriteCtx := newModuleMessageContext(bot, chat, admin, "/filter dupe new")
@@ -125,7 +125,7 @@ func TestAddFilterRejectsLimitAndNonAdmin(t *testing.T) {
 		chat := gotgbot.Chat{Id: uniqueModuleChatID(), Type: "supergroup", Title: "Filter Chat"}
 		admin := gotgbot.User{Id: 777000, FirstName: "Telegram"}
 		for i := 0; i < 150; i++ {
-			if err := filters.AddFilter(chat.Id, "word"+strconv.Itoa(i), "reply", "", nil, db.TEXT); err != nil {
+			if err := filters.AddFilter(chat.Id, "word"+strconv.Itoa(i), "reply", "", nil, nil, db.TEXT); err != nil {
 				t.Fatalf("AddFilter setup %d error = %v", i, err)
 			}
 		}
@@ -194,7 +194,7 @@ func TestFiltersWatcherNoFormatPathRequiresAdmin(t *testing.T) {
 	chat := gotgbot.Chat{Id: uniqueModuleChatID(), Type: "supergroup", Title: "Filter Chat"}
 	admin := gotgbot.User{Id: 777000, FirstName: "Telegram"}
 	member := gotgbot.User{Id: 42, FirstName: "Member"}
-	if err := filters.AddFilter(chat.Id, "raw", "<b>Raw</b>", "", nil, db.TEXT); err != nil {
+	if err := filters.AddFilter(chat.Id, "raw", "<b>Raw</b>", "", nil, nil, db.TEXT); err != nil {
 		t.Fatalf("AddFilter setup error = %v", err)
 	}
 
@@ -224,7 +224,7 @@ func TestFilterOverwriteCallbackReplacesExistingFilter(t *testing.T) {
 	bot := newModuleTestBot(client)
 	chat := gotgbot.Chat{Id: uniqueModuleChatID(), Type: "supergroup", Title: "Filter Chat"}
 	admin := gotgbot.User{Id: 777000, FirstName: "Telegram"}
-	if err := filters.AddFilter(chat.Id, "hello", "old reply", "", nil, db.TEXT); err != nil {
+	if err := filters.AddFilter(chat.Id, "hello", "old reply", "", nil, nil, db.TEXT); err != nil {
 		t.Fatalf("AddFilter setup error = %v", err)
 	}
 	if err := setFilterOverwriteCache("token-1", overwriteFilter{
@@ -289,10 +289,10 @@ func TestRemoveAllFiltersConfirmationAndCallback(t *testing.T) {
 	bot := newModuleTestBot(client)
 	chat := gotgbot.Chat{Id: uniqueModuleChatID(), Type: "supergroup", Title: "Filter Chat"}
 	owner := gotgbot.User{Id: 777000, FirstName: "Telegram"}
-	if err := filters.AddFilter(chat.Id, "one", "1", "", nil, db.TEXT); err != nil {
+	if err := filters.AddFilter(chat.Id, "one", "1", "", nil, nil, db.TEXT); err != nil {
 		t.Fatalf("AddFilter setup error = %v", err)
 	}
-	if err := filters.AddFilter(chat.Id, "two", "2", "", nil, db.TEXT); err != nil {
+	if err := filters.AddFilter(chat.Id, "two", "2", "", nil, nil, db.TEXT); err != nil {
 		t.Fatalf("AddFilter setup error = %v", err)
 	}
 
@@ -326,7 +326,7 @@ func TestRemoveAllFiltersEmptyCancelAndInvalidCallback(t *testing.T) {
 		t.Fatalf("rmAllFilters empty error = %v, want EndGroups", err)
 	}
 
-	if err := filters.AddFilter(chat.Id, "keep", "reply", "", nil, db.TEXT); err != nil {
+	if err := filters.AddFilter(chat.Id, "keep", "reply", "", nil, nil, db.TEXT); err != nil {
 		t.Fatalf("AddFilter setup error = %v", err)
 	}
 	cancelCtx := newModuleCallbackContext(bot, chat, owner, "rmAllFilters.no")
@@ -374,7 +374,7 @@ func TestFilterCommandsPropagateGotgbotRequestErrors(t *testing.T) {
 			text: "/filter hello New",
 			setup: func(t *testing.T, chat gotgbot.Chat) {
 				t.Helper()
-				if err := filters.AddFilter(chat.Id, "hello", "old", "", nil, db.TEXT); err != nil {
+				if err := filters.AddFilter(chat.Id, "hello", "old", "", nil, nil, db.TEXT); err != nil {
 					t.Fatalf("AddFilter setup error = %v", err)
 				}
 			},
@@ -388,7 +388,7 @@ func TestFilterCommandsPropagateGotgbotRequestErrors(t *testing.T) {
 			text: "/stop hello",
 			setup: func(t *testing.T, chat gotgbot.Chat) {
 				t.Helper()
-				if err := filters.AddFilter(chat.Id, "hello", "old", "", nil, db.TEXT); err != nil {
+				if err := filters.AddFilter(chat.Id, "hello", "old", "", nil, nil, db.TEXT); err != nil {
 					t.Fatalf("AddFilter setup error = %v", err)
 				}
 			},
@@ -401,7 +401,7 @@ func TestFilterCommandsPropagateGotgbotRequestErrors(t *testing.T) {
 			text: "/filters",
 			setup: func(t *testing.T, chat gotgbot.Chat) {
 				t.Helper()
-				if err := filters.AddFilter(chat.Id, "hello", "old", "", nil, db.TEXT); err != nil {
+				if err := filters.AddFilter(chat.Id, "hello", "old", "", nil, nil, db.TEXT); err != nil {
 					t.Fatalf("AddFilter setup error = %v", err)
 				}
 			},
@@ -414,7 +414,7 @@ func TestFilterCommandsPropagateGotgbotRequestErrors(t *testing.T) {
 			text: "/stopall",
 			setup: func(t *testing.T, chat gotgbot.Chat) {
 				t.Helper()
-				if err := filters.AddFilter(chat.Id, "hello", "old", "", nil, db.TEXT); err != nil {
+				if err := filters.AddFilter(chat.Id, "hello", "old", "", nil, nil, db.TEXT); err != nil {
 					t.Fatalf("AddFilter setup error = %v", err)
 				}
 			},
@@ -426,7 +426,7 @@ func TestFilterCommandsPropagateGotgbotRequestErrors(t *testing.T) {
 			text: "hello",
 			setup: func(t *testing.T, chat gotgbot.Chat) {
 				t.Helper()
-				if err := filters.AddFilter(chat.Id, "hello", "old", "", nil, db.TEXT); err != nil {
+				if err := filters.AddFilter(chat.Id, "hello", "old", "", nil, nil, db.TEXT); err != nil {
 					t.Fatalf("AddFilter setup error = %v", err)
 				}
 			},
@@ -438,7 +438,7 @@ func TestFilterCommandsPropagateGotgbotRequestErrors(t *testing.T) {
 			text: "hello noformat",
 			setup: func(t *testing.T, chat gotgbot.Chat) {
 				t.Helper()
-				if err := filters.AddFilter(chat.Id, "hello", "old", "", nil, db.TEXT); err != nil {
+				if err := filters.AddFilter(chat.Id, "hello", "old", "", nil, nil, db.TEXT); err != nil {
 					t.Fatalf("AddFilter setup error = %v", err)
 				}
 			},
@@ -482,7 +482,7 @@ func TestFilterCallbackHandlersPropagateGotgbotRequestErrors(t *testing.T) {
 			data:   encodeCallbackData("rmAllFilters", map[string]string{"a": "yes"}),
 			setup: func(t *testing.T, chat gotgbot.Chat) {
 				t.Helper()
-				if err := filters.AddFilter(chat.Id, "hello", "old", "", nil, db.TEXT); err != nil {
+				if err := filters.AddFilter(chat.Id, "hello", "old", "", nil, nil, db.TEXT); err != nil {
 					t.Fatalf("AddFilter setup error = %v", err)
 				}
 			},
@@ -494,7 +494,7 @@ func TestFilterCallbackHandlersPropagateGotgbotRequestErrors(t *testing.T) {
 			data:   encodeCallbackData("rmAllFilters", map[string]string{"a": "no"}),
 			setup: func(t *testing.T, chat gotgbot.Chat) {
 				t.Helper()
-				if err := filters.AddFilter(chat.Id, "hello", "old", "", nil, db.TEXT); err != nil {
+				if err := filters.AddFilter(chat.Id, "hello", "old", "", nil, nil, db.TEXT); err != nil {
 					t.Fatalf("AddFilter setup error = %v", err)
 				}
 			},
@@ -506,7 +506,7 @@ func TestFilterCallbackHandlersPropagateGotgbotRequestErrors(t *testing.T) {
 			data:   encodeCallbackData("filters_overwrite", map[string]string{"a": "yes", "t": "token-edit"}),
 			setup: func(t *testing.T, chat gotgbot.Chat) {
 				t.Helper()
-				if err := filters.AddFilter(chat.Id, "hello", "old", "", nil, db.TEXT); err != nil {
+				if err := filters.AddFilter(chat.Id, "hello", "old", "", nil, nil, db.TEXT); err != nil {
 					t.Fatalf("AddFilter setup error = %v", err)
 				}
 				if err := setFilterOverwriteCache("token-edit", overwriteFilter{
@@ -528,7 +528,7 @@ func TestFilterCallbackHandlersPropagateGotgbotRequestErrors(t *testing.T) {
 			data:   encodeCallbackData("filters_overwrite", map[string]string{"a": "yes", "t": "token-answer"}),
 			setup: func(t *testing.T, chat gotgbot.Chat) {
 				t.Helper()
-				if err := filters.AddFilter(chat.Id, "hello", "old", "", nil, db.TEXT); err != nil {
+				if err := filters.AddFilter(chat.Id, "hello", "old", "", nil, nil, db.TEXT); err != nil {
 					t.Fatalf("AddFilter setup error = %v", err)
 				}
 				if err := setFilterOverwriteCache("token-answer", overwriteFilter{
@@ -588,3 +588,33 @@ func TestFilterCallbackHandlersReturnEarlyWithoutChat(t *testing.T) {
 		t.Fatalf("answerCallbackQuery calls = %d, want none when chat is missing", len(calls))
 	}
 }
+
+func TestFilterRestoreQuotedKeyword(t *testing.T) {
+	client := newModuleBotClient()
+	bot := newModuleTestBot(client)
+	chat := gotgbot.Chat{Id: uniqueModuleChatID(), Type: "supergroup", Title: "Filters Chat"}
+	admin := gotgbot.User{Id: 777000, FirstName: "Telegram"}
+	if err := filters.AddFilter(chat.Id, "hello world", "hi there", "", nil, nil, db.TEXT); err != nil {
+		t.Fatalf("AddFilter() setup error = %v", err)
+	}
+	if err := filters.RemoveFilter(chat.Id, admin.Id, "hello world"); err != nil {
+		t.Fatalf("RemoveFilter() setup error = %v", err)
+	}
+
+	historyCtx := newModuleMessageContext(bot, chat, admin, `/filterhistory "Hello World"`)
+	if err := filtersModule.revisionHistory(filterRevisionKind)(bot, historyCtx); err != ext.EndGroups {
+		t.Fatalf("filterhistory error = %v, want EndGroups", err)
+	}
+	calls := client.callsFor("sendMessage")
+	if text := calls[len(calls)-1].Params["text"].(string); !strings.Contains(text, "<b>#1</b>") {
+		t.Fatalf("history = %q, want revision #1 listed", text)
+	}
+
+	restoreCtx := newModuleMessageContext(bot, chat, admin, `/filterrestore "hello world" 1`)
+	if err := filtersModule.revisionRestore(filterRevisionKind)(bot, restoreCtx); err != ext.EndGroups {
+		t.Fatalf("filterrestore error = %v, want EndGroups", err)
+	}
+	if !filters.DoesFilterExists(chat.Id, "hello world") {
+		t.Fatal("expected /filterrestore to bring back the removed filter")
+	}
+}
@@ -18,9 +18,12 @@ import (
 	log "github.com/sirupsen/logrus"
 
 	"github.com/divkix/Alita_Robot/alita/db"
+	"github.com/divkix/Alita_Robot/alita/db/approvals"
 	"github.com/divkix/Alita_Robot/alita/db/captcha"
 	"github.com/divkix/Alita_Robot/alita/db/greetings"
+	"github.com/divkix/Alita_Robot/alita/db/joinrequests"
 	"github.com/divkix/Alita_Robot/alita/db/lang"
+	"github.com/divkix/Alita_Robot/alita/db/models"
 	"github.com/divkix/Alita_Robot/alita/i18n"
 	"github.com/divkix/Alita_Robot/alita/utils/cache"
 	"github.com/divkix/Alita_Robot/alita/utils/chat_status"
@@ -143,6 +146,7 @@ func clearRecentJoinProcessing(chatID, userID int64) {
 
 	recentJoinProcessing.Delete(key)
 }
+
 // displayGreeting is a shared helper function that handles both welcome and goodbye greeting display/toggling.
 // It consolidates common logic between welcome() and goodbye() commands.
 //
@@ -705,11 +709,9 @@ func SendWelcomeMessage(bot *gotgbot.Bot, ctx *ext.Context, userID int64, firstN
 		)
 		kb := &gotgbot.InlineKeyboardMarkup{InlineKeyboard: keyboard.BuildKeyboard(buttons)}
 
-		var threadID int64
-		if ctx.EffectiveMessage != nil {
-			threadID = ctx.EffectiveMessage.MessageThreadId
-		}
-		sent, err := media.SendGreeting(bot, chat.Id, res, greetPrefs.WelcomeSettings.FileID, greetPrefs.WelcomeSettings.WelcomeType, kb, threadID)
+		sent, err := sendToGreetingTopic(chat.Id, contextThread(ctx), func(threadID int64) (*gotgbot.Message, error) {
+			return media.SendGreeting(bot, chat.Id, res, greetPrefs.WelcomeSettings.FileID, greetPrefs.WelcomeSettings.WelcomeType, kb, threadID)
+		})
 		if err != nil {
 			log.Error(err)
 			return err
@@ -734,6 +736,11 @@ func (moduleStruct) newMember(bot *gotgbot.Bot, ctx *ext.Context) error {
 		threadID = ctx.EffectiveMessage.MessageThreadId
 	}
 	chatCopy := *chat
+	if ctx.ChatMember.ViaJoinRequest {
+		// Approved outside the bot, e.g. from the Telegram app.
+		joinrequests.MarkJoined(chat.Id, newMember.Id)
+		joinrequests.DeleteApplication(chat.Id, newMember.Id)
+	}
 	captchaSettings, err := captcha.GetCaptchaSettings(chat.Id)
 	if err != nil {
 		log.Errorf("[Greetings][newMember] Failed to get captcha settings for chat %d: %v", chat.Id, err)
@@ -770,8 +777,10 @@ func (moduleStruct) leftMember(bot *gotgbot.Bot, ctx *ext.Context) error {
 				log.Debugf("Failed to delete captcha message for leaving user %d: %v", leftMember.Id, delErr)
 			}
 		}
-		if _, delErr := captcha.DeleteCaptchaAttemptByIDAtomic(captchaAttempt.ID, leftMember.Id, chat.Id); delErr != nil {
+		if deleted, delErr := captcha.DeleteCaptchaAttemptByIDAtomic(captchaAttempt.ID, leftMember.Id, chat.Id); delErr != nil {
 			log.Errorf("Failed to delete captcha attempt for leaving user %d: %v", leftMember.Id, delErr)
+		} else if deleted {
+			recordCaptchaOutcome(captchaAttempt, models.CaptchaOutcomeLeft)
 		}
 	}
 	if err := captcha.DeleteMutedUser(leftMember.Id, chat.Id); err != nil {
@@ -819,7 +828,7 @@ func processSingleNewMember(bot *gotgbot.Bot, chat *gotgbot.Chat, threadID int64
 		return nil
 	}
 
-	if captchaEnabled && !chat_status.IsApproved(bot, chat.Id, newMember.Id) {
+	if captchaEnabled && !chat_status.IsApprovedFor(bot, chat.Id, newMember.Id, approvals.ScopeCaptcha) {
 		ctxCopy := ext.Context{EffectiveChat: chat}
 		if threadID != 0 {
 			ctxCopy.EffectiveMessage = &gotgbot.Message{Chat: *chat, MessageThreadId: threadID}
@@ -925,18 +934,19 @@ func (moduleStruct) cleanService(bot *gotgbot.Bot, ctx *ext.Context) error {
 }
 
 // pendingJoins handles chat join requests and creates approval buttons for admins.
-// Auto-approves if enabled, otherwise presents approve/decline/ban options to admins.
+// Auto-approves if enabled, otherwise presents approve/decline/ban options to admins,
+// in the review chat with a profile summary of the requester when one is set.
 func (m moduleStruct) pendingJoins(bot *gotgbot.Bot, ctx *ext.Context) error {
 	defer error_handling.RecoverFromPanic("Greetings", "pendingJoins")
 
 	chat := ctx.ChatJoinRequest.Chat
 	user := ctx.ChatJoinRequest.From
-	joinReqStr := "join_request"
 
 	if !m.loadPendingJoins(chat.Id, user.Id) {
+		settings := greetings.GetGreetingSettings(chat.Id)
 
 		// auto approve join requests
-		if greetings.GetGreetingSettings(chat.Id).ShouldAutoApprove {
+		if settings.ShouldAutoApprove {
 			if _, err := bot.ApproveChatJoinRequest(chat.Id, user.Id, nil); err != nil {
 				if helpers.IsExpectedTelegramError(err) {
 					log.Debugf("[Greetings] Expected error auto-approving join for user %d in chat %d: %v", user.Id, chat.Id, err)
@@ -949,50 +959,21 @@ func (m moduleStruct) pendingJoins(bot *gotgbot.Bot, ctx *ext.Context) error {
 		}
 
 		tr := i18n.MustNewTranslator(lang.GetLanguage(ctx))
-		newUserText, _ := tr.GetString("greetings_join_request_new")
-		approveText, _ := tr.GetString("greetings_join_request_approve_btn")
-		declineText, _ := tr.GetString("greetings_join_request_decline_btn")
-		banText, _ := tr.GetString("greetings_join_request_ban_btn")
-		userInfoTemplate, _ := tr.GetString("format_user_info")
-		userIdTemplate, _ := tr.GetString("format_user_id")
-
-		_, err := helpers.SendMessageWithErrorHandling(
-			bot,
-			chat.Id,
-			fmt.Sprint(
-				newUserText,
-				"\n"+fmt.Sprintf(userInfoTemplate, formatting.MentionHtml(user.Id, user.FirstName)),
-				"\n"+fmt.Sprintf(userIdTemplate, user.Id),
-			),
-			&gotgbot.SendMessageOpts{
-				ParseMode: formatting.HTML,
-				ReplyMarkup: gotgbot.InlineKeyboardMarkup{
-					InlineKeyboard: [][]gotgbot.InlineKeyboardButton{
-						{
-							{
-								Text:         approveText,
-								CallbackData: encodeCallbackData(joinReqStr, map[string]string{"a": "accept", "u": fmt.Sprint(user.Id)}),
-							},
-							{
-								Text:         declineText,
-								CallbackData: encodeCallbackData(joinReqStr, map[string]string{"a": "decline", "u": fmt.Sprint(user.Id)}),
-							},
-						},
-						{
-							{
-								Text:         banText,
-								CallbackData: encodeCallbackData(joinReqStr, map[string]string{"a": "ban", "u": fmt.Sprint(user.Id)}),
-							},
-						},
-					},
-				},
-			},
-		)
-		if err != nil {
-			log.Error(err)
+		record := newJoinRequestRecord(ctx.ChatJoinRequest)
+
+		// Applicants answer the join questions first; admins are asked
+		// once they are done.
+		if questions := joinrequests.GetQuestions(chat.Id); len(questions) > 0 {
+			if startJoinApplication(bot, tr, ctx.ChatJoinRequest, questions, settings.JoinQuestionsTimeout) {
+				recordJoinRequest(record, nil)
+				m.setPendingJoins(chat.Id, user.Id)
+				return ext.ContinueGroups
+			}
+		}
+
+		if err := m.postJoinRequestCard(bot, tr, chat.Id, chat.Title, record, ""); err != nil {
 			return err
 		}
-		m.setPendingJoins(chat.Id, user.Id)
 	}
 
 	return ext.ContinueGroups
@@ -1016,17 +997,42 @@ func (m moduleStruct) joinRequestHandler(b *gotgbot.Bot, ctx *ext.Context) error
 	chat := ctx.EffectiveChat
 	msg := query.Message
 
-	// permission checks
-	if !chat_status.RequireUserAdmin(b, ctx, chat, user.Id) {
-		chat_status.NewPermissionResponder(b).Respond(ctx, "chat_status_user_admin_cmd_error", "chat_status_user_admin_button_error", chat_status.WithReplyFallback())
-		return ext.EndGroups
-	}
-
 	response := ""
 	joinUserIDRaw := ""
+	chatIDRaw := ""
 	if decoded, ok := decodeCallbackData(query.Data, "join_request"); ok {
 		response, _ = decoded.Field("a")
 		joinUserIDRaw, _ = decoded.Field("u")
+		chatIDRaw, _ = decoded.Field("c")
+	}
+
+	// Cards posted to a review chat act on the chat the request was made to.
+	if chatIDRaw != "" {
+		requestChatID, err := strconv.ParseInt(chatIDRaw, 10, 64)
+		if err != nil {
+			tr := i18n.MustNewTranslator(lang.GetLanguage(ctx))
+			text, _ := tr.GetString("common_callback_invalid_request")
+			_, _ = query.Answer(b, &gotgbot.AnswerCallbackQueryOpts{Text: text})
+			return ext.EndGroups
+		}
+		if chat == nil || requestChatID != chat.Id {
+			requestChat, err := b.GetChat(requestChatID, nil)
+			if err != nil {
+				log.Errorf("[Greetings] Failed to get chat %d for join request: %v", requestChatID, err)
+				tr := i18n.MustNewTranslator(lang.GetLanguage(ctx))
+				text, _ := tr.GetString("common_callback_invalid_request")
+				_, _ = query.Answer(b, &gotgbot.AnswerCallbackQueryOpts{Text: text})
+				return ext.EndGroups
+			}
+			resolved := requestChat.ToChat()
+			chat = &resolved
+		}
+	}
+
+	// permission checks
+	if !chat_status.RequireUserAdmin(b, ctx, chat, user.Id) {
+		chat_status.NewPermissionResponder(b).Respond(ctx, "chat_status_user_admin_cmd_error", "chat_status_user_admin_button_error", chat_status.WithReplyFallback())
+		return ext.EndGroups
 	}
 	if response == "" || joinUserIDRaw == "" {
 		log.Warnf("[Greetings] Invalid callback data format: %s", query.Data)
@@ -1072,16 +1078,24 @@ func (m moduleStruct) joinRequestHandler(b *gotgbot.Bot, ctx *ext.Context) error
 		}
 	}
 
-	joinUser, err := b.GetChat(joinUserId, nil)
-	if err != nil {
-		log.Error(err)
-		return err
+	// The recorded request already has the name; users who never talked to
+	// the bot cannot be looked up.
+	joinUser := &gotgbot.ChatFullInfo{Id: joinUserId}
+	if pending := joinrequests.GetPendingRequest(chat.Id, joinUserId); pending != nil && pending.FirstName != "" {
+		joinUser.FirstName = pending.FirstName
+	} else {
+		joinUser, err = b.GetChat(joinUserId, nil)
+		if err != nil {
+			log.Error(err)
+			return err
+		}
 	}
-	var helpText string
+	var helpText, status string
 	tr := i18n.MustNewTranslator(lang.GetLanguage(ctx))
 
 	switch response {
 	case "accept":
+		status = models.JoinRequestApproved
 		if _, err = b.ApproveChatJoinRequest(chat.Id, joinUser.Id, nil); err != nil {
 			if helpers.IsExpectedTelegramError(err) {
 				log.Debugf("[Greetings] Expected error approving join for user %d in chat %d: %v", joinUser.Id, chat.Id, err)
@@ -1092,6 +1106,7 @@ func (m moduleStruct) joinRequestHandler(b *gotgbot.Bot, ctx *ext.Context) error
 		}
 		helpText, _ = tr.GetString("greetings_join_request_accepted")
 	case "decline":
+		status = models.JoinRequestDeclined
 		if _, err = b.DeclineChatJoinRequest(chat.Id, joinUser.Id, nil); err != nil {
 			if helpers.IsExpectedTelegramError(err) {
 				log.Debugf("[Greetings] Expected error declining join for user %d in chat %d: %v", joinUser.Id, chat.Id, err)
@@ -1102,6 +1117,7 @@ func (m moduleStruct) joinRequestHandler(b *gotgbot.Bot, ctx *ext.Context) error
 		}
 		helpText, _ = tr.GetString("greetings_join_request_declined")
 	case "ban":
+		status = models.JoinRequestBanned
 		if _, err = chat.BanMember(b, joinUser.Id, nil); err != nil {
 			if helpers.IsExpectedTelegramError(err) {
 				log.Debugf("[Greetings] Expected error banning user %d in chat %d: %v", joinUser.Id, chat.Id, err)
@@ -1121,9 +1137,12 @@ func (m moduleStruct) joinRequestHandler(b *gotgbot.Bot, ctx *ext.Context) error
 		helpText, _ = tr.GetString("greetings_join_request_banned")
 	}
 	m.clearPendingJoins(chat.Id, joinUser.Id)
+	_ = joinrequests.Decide(chat.Id, joinUser.Id, user.Id, status)
+	joinrequests.DeleteApplication(chat.Id, joinUser.Id)
 
+	decidedBy, _ := tr.GetString("join_review_decided_by", i18n.TranslationParams{"admin": formatting.MentionHtml(user.Id, user.FirstName)})
 	_, _, err = msg.EditText(b,
-		fmt.Sprintf(helpText, formatting.MentionHtml(joinUser.Id, joinUser.FirstName)),
+		fmt.Sprintf(helpText, formatting.MentionHtml(joinUser.Id, joinUser.FirstName))+"\n"+decidedBy,
 		&gotgbot.EditMessageTextOpts{
 			ParseMode: formatting.HTML,
 		},
@@ -1152,6 +1171,72 @@ func (m moduleStruct) autoApprove(bot *gotgbot.Bot, ctx *ext.Context) error {
 	return m.greetingToggle(bot, ctx, autoApproveToggleConfig)
 }
 
+// welcomeTopic shows or sets the forum topic welcome and captcha messages
+// are sent to.
+// Usage: /welcometopic [<topic id>|here|off]
+func (moduleStruct) welcomeTopic(bot *gotgbot.Bot, ctx *ext.Context) error {
+	msg := ctx.EffectiveMessage
+	connectedChat := chat_status.IsUserConnected(bot, ctx, true, true)
+	if connectedChat == nil {
+		return ext.EndGroups
+	}
+	ctx.EffectiveChat = connectedChat
+	chat := ctx.EffectiveChat
+	admin := chat_status.RequireUser(bot, ctx)
+	if admin == nil {
+		return ext.EndGroups
+	}
+	if !chat_status.CanUserChangeInfo(bot, ctx, chat, admin.Id) {
+		chat_status.NewPermissionResponder(bot).Respond(ctx, "chat_status_change_info_cmd_error", "chat_status_change_info_button_error")
+		return ext.EndGroups
+	}
+
+	tr := i18n.MustNewTranslator(lang.GetLanguage(ctx))
+	reply := func(text string) error {
+		if _, err := msg.Reply(bot, text, formatting.Shtml()); err != nil {
+			log.Error(err)
+			return err
+		}
+		return ext.EndGroups
+	}
+
+	args := ctx.Args()[1:]
+	if len(args) == 0 {
+		topic := greetings.GetGreetingSettings(chat.Id).WelcomeTopic
+		if topic == 0 {
+			return reply(trS(tr, "greetings_welcome_topic_status_off"))
+		}
+		text, _ := tr.GetString("greetings_welcome_topic_status", i18n.TranslationParams{"topic": topic})
+		return reply(text)
+	}
+
+	var topic int64
+	switch arg := strings.ToLower(args[0]); arg {
+	case "off", "no":
+		topic = 0
+	case "here":
+		topic = messageTopic(msg)
+		if topic == 0 {
+			return reply(trS(tr, "greetings_welcome_topic_not_topic"))
+		}
+	default:
+		parsed, err := strconv.ParseInt(arg, 10, 64)
+		if err != nil || parsed <= 0 {
+			return reply(trS(tr, "greetings_welcome_topic_usage"))
+		}
+		topic = parsed
+	}
+
+	if err := greetings.SetWelcomeTopic(chat.Id, topic); err != nil {
+		return reply(trS(tr, "common_settings_save_failed"))
+	}
+	if topic == 0 {
+		return reply(trS(tr, "greetings_welcome_topic_disabled"))
+	}
+	text, _ := tr.GetString("greetings_welcome_topic_enabled", i18n.TranslationParams{"topic": topic})
+	return reply(text)
+}
+
 // loadPendingJoins checks if a join request notification has already been sent for a user.
 // Prevents duplicate join request messages by checking cache for recent requests.
 func (moduleStruct) loadPendingJoins(chatId, userId int64) bool {
@@ -1240,19 +1325,33 @@ func LoadGreetings(dispatcher *ext.Dispatcher) {
 		),
 	)
 
-	dispatcher.AddHandler(handlers.NewCommand("welcome", greetingsModule.welcome))
-	dispatcher.AddHandler(handlers.NewCommand("setwelcome", greetingsModule.setWelcome))
-	dispatcher.AddHandler(handlers.NewCommand("resetwelcome", greetingsModule.resetWelcome))
-	dispatcher.AddHandler(handlers.NewCommand("goodbye", greetingsModule.goodbye))
-	dispatcher.AddHandler(handlers.NewCommand("setgoodbye", greetingsModule.setGoodbye))
-	dispatcher.AddHandler(handlers.NewCommand("resetgoodbye", greetingsModule.resetGoodbye))
-	dispatcher.AddHandler(handlers.NewCommand("cleanwelcome", greetingsModule.cleanWelcome))
-	dispatcher.AddHandler(handlers.NewCommand("cleangoodbye", greetingsModule.cleanGoodbye))
-	dispatcher.AddHandler(handlers.NewCommand("cleanservice", greetingsModule.delJoined))
-	dispatcher.AddHandler(handlers.NewCommand("autoapprove", greetingsModule.autoApprove))
+	helpers.AddCommand(dispatcher, "welcome", greetingsModule.welcome)
+	helpers.AddCommand(dispatcher, "setwelcome", greetingsModule.setWelcome)
+	helpers.AddCommand(dispatcher, "resetwelcome", greetingsModule.resetWelcome)
+	helpers.AddCommand(dispatcher, "goodbye", greetingsModule.goodbye)
+	helpers.AddCommand(dispatcher, "setgoodbye", greetingsModule.setGoodbye)
+	helpers.AddCommand(dispatcher, "resetgoodbye", greetingsModule.resetGoodbye)
+	helpers.AddCommand(dispatcher, "cleanwelcome", greetingsModule.cleanWelcome)
+	helpers.AddCommand(dispatcher, "cleangoodbye", greetingsModule.cleanGoodbye)
+	helpers.AddCommand(dispatcher, "cleanservice", greetingsModule.delJoined)
+	helpers.AddCommand(dispatcher, "autoapprove", greetingsModule.autoApprove)
+	helpers.AddCommand(dispatcher, "joinreview", greetingsModule.joinReview)
+	helpers.AddCommand(dispatcher, "pendingrequests", greetingsModule.pendingRequests)
+	helpers.AddCommand(dispatcher, "joinquestions", greetingsModule.joinQuestions)
+	helpers.AddCommand(dispatcher, "welcometopic", greetingsModule.welcomeTopic)
+	dispatcher.AddHandlerToGroup(
+		handlers.NewMessage(
+			func(msg *gotgbot.Message) bool {
+				return msg.Chat.Type == "private" && !strings.HasPrefix(msg.Text, "/")
+			},
+			greetingsModule.joinAnswer,
+		),
+		joinQuestionsHandlerGroup,
+	)
 	dispatcher.AddHandler(handlers.NewCallback(callbackquery.Prefix("join_request"), greetingsModule.joinRequestHandler))
 }
 
 func init() {
 	RegisterLegacyModule("Greetings", 210, LoadGreetings)
+	RegisterDeepLinkHandler("joinq_", joinQuestionsDeepLinkHandler)
 }
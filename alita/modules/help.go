@@ -18,6 +18,7 @@ import (
 
 	"github.com/divkix/Alita_Robot/alita/config"
 	"github.com/divkix/Alita_Robot/alita/i18n"
+	"github.com/divkix/Alita_Robot/alita/utils/helpers"
 )
 
 // cachedBotUsernameMu protects cachedBotUsername against concurrent reads/writes.
@@ -660,13 +661,16 @@ func (moduleStruct) help(b *gotgbot.Bot, ctx *ext.Context) error {
 // LoadHelp registers all help-related command and callback handlers.
 // Sets up the help system including start, about, donate, and configuration commands.
 func LoadHelp(dispatcher *ext.Dispatcher) {
-	dispatcher.AddHandler(handlers.NewCommand("start", DefaultHelpRegistry().start))
-	dispatcher.AddHandler(handlers.NewCommand("help", DefaultHelpRegistry().help))
+	helpers.AddCommand(dispatcher, "start", DefaultHelpRegistry().start)
+	helpers.AddCommand(dispatcher, "help", DefaultHelpRegistry().help)
 	dispatcher.AddHandler(handlers.NewCallback(callbackquery.Prefix("helpq"), DefaultHelpRegistry().helpButtonHandler))
-	dispatcher.AddHandler(handlers.NewCommand("donate", DefaultHelpRegistry().donate))
+	helpers.AddCommand(dispatcher, "donate", DefaultHelpRegistry().donate)
 	dispatcher.AddHandler(handlers.NewCallback(callbackquery.Prefix("configuration"), DefaultHelpRegistry().botConfig))
-	dispatcher.AddHandler(handlers.NewCommand("about", DefaultHelpRegistry().about))
+	helpers.AddCommand(dispatcher, "about", DefaultHelpRegistry().about)
 	dispatcher.AddHandler(handlers.NewCallback(callbackquery.Prefix("about"), DefaultHelpRegistry().about))
+	// Help loads after every module, so the alias resolver added here is the
+	// last handler of the default group and never shadows a real command.
+	registerAliasResolver(dispatcher)
 	initHelpButtons()
 }
 
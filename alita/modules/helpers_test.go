@@ -252,10 +252,10 @@ func TestHandleDeepLinkRoutesNotesDeepLinks(t *testing.T) {
 	if err := chats.EnsureChatInDb(chatID, "Notes Chat"); err != nil {
 		t.Fatalf("EnsureChatInDb() error = %v", err)
 	}
-	if err := notes.AddNote(chatID, "public", "Visible note", "", nil, db.TEXT, false, false, false, false, false, false); err != nil {
+	if err := notes.AddNote(chatID, "public", "", "Visible note", "", nil, nil, db.TEXT, false, false, false, false, false, false); err != nil {
 		t.Fatalf("AddNote(public) error = %v", err)
 	}
-	if err := notes.AddNote(chatID, "admin", "Hidden note", "", nil, db.TEXT, false, false, true, false, false, false); err != nil {
+	if err := notes.AddNote(chatID, "admin", "", "Hidden note", "", nil, nil, db.TEXT, false, false, true, false, false, false); err != nil {
 		t.Fatalf("AddNote(admin) error = %v", err)
 	}
 
@@ -309,7 +309,7 @@ func TestHandleDeepLinkHandlesMissingChatsAndAdminOnlyNotes(t *testing.T) {
 	if err := chats.EnsureChatInDb(chatID, "Private Notes Chat"); err != nil {
 		t.Fatalf("EnsureChatInDb() error = %v", err)
 	}
-	if err := notes.AddNote(chatID, "adminonly", "Hidden", "", nil, db.TEXT, false, false, true, false, false, false); err != nil {
+	if err := notes.AddNote(chatID, "adminonly", "", "Hidden", "", nil, nil, db.TEXT, false, false, true, false, false, false); err != nil {
 		t.Fatalf("AddNote(adminonly) error = %v", err)
 	}
 
@@ -540,12 +540,12 @@ func TestDeepLinkNonMemberDenied(t *testing.T) {
 		t.Fatalf("EnsureChatInDb() error = %v", err)
 	}
 	rules.SetChatRules(chatID, "No spam.")
-	if err := notes.AddNote(chatID, "secret", "Secret content", "", nil, db.TEXT, false, false, false, false, false, false); err != nil {
+	if err := notes.AddNote(chatID, "secret", "", "Secret content", "", nil, nil, db.TEXT, false, false, false, false, false, false); err != nil {
 		t.Fatalf("AddNote(secret) error = %v", err)
 	}
 	t.Cleanup(func() {
 		rules.SetChatRules(chatID, "")
-		_ = notes.RemoveNote(chatID, "secret")
+		_ = notes.RemoveNote(chatID, 0, "secret")
 	})
 
 	// Non-member: user ID 13 (status:"left" in test harness).
@@ -620,12 +620,12 @@ func TestDeepLinkMemberAllowed(t *testing.T) {
 		t.Fatalf("EnsureChatInDb() error = %v", err)
 	}
 	rules.SetChatRules(chatID, "Be nice.")
-	if err := notes.AddNote(chatID, "welcome", "Welcome note", "", nil, db.TEXT, false, false, false, false, false, false); err != nil {
+	if err := notes.AddNote(chatID, "welcome", "", "Welcome note", "", nil, nil, db.TEXT, false, false, false, false, false, false); err != nil {
 		t.Fatalf("AddNote(welcome) error = %v", err)
 	}
 	t.Cleanup(func() {
 		rules.SetChatRules(chatID, "")
-		_ = notes.RemoveNote(chatID, "welcome")
+		_ = notes.RemoveNote(chatID, 0, "welcome")
 	})
 
 	memberUser := gotgbot.User{Id: 42, FirstName: "Member"}
@@ -0,0 +1,238 @@
+package modules
+
+import (
+	"fmt"
+	"html"
+	"slices"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/PaulSonOfLars/gotgbot/v2"
+	"github.com/PaulSonOfLars/gotgbot/v2/ext"
+	"github.com/PaulSonOfLars/gotgbot/v2/ext/handlers"
+	"github.com/PaulSonOfLars/gotgbot/v2/ext/handlers/filters/inlinequery"
+
+	"github.com/divkix/Alita_Robot/alita/db/cache"
+	"github.com/divkix/Alita_Robot/alita/db/connections"
+	"github.com/divkix/Alita_Robot/alita/db/lang"
+	"github.com/divkix/Alita_Robot/alita/db/notes"
+	"github.com/divkix/Alita_Robot/alita/db/rules"
+	"github.com/divkix/Alita_Robot/alita/i18n"
+	"github.com/divkix/Alita_Robot/alita/utils/chat_status"
+	"github.com/divkix/Alita_Robot/alita/utils/media"
+)
+
+var inlineModule = moduleStruct{moduleName: "Inline"}
+
+// inlineMaxResults is the most results Telegram accepts in one inline answer.
+const inlineMaxResults = 50
+
+// inlineCacheTime is how long Telegram may cache an answer for the same user
+// and query, in seconds.
+const inlineCacheTime int64 = 30
+
+// inlineNote is a note rendered for one user, ready to become an inline result.
+type inlineNote struct {
+	Name        string
+	Content     media.Content
+	Keyboard    gotgbot.InlineKeyboardMarkup
+	WebPreview  bool
+	PrivateOnly bool
+	GroupOnly   bool
+}
+
+// inlineChatResults holds the notes and rules of the chat the user is
+// connected to, already filtered by what the user may see there.
+type inlineChatResults struct {
+	IsMember   bool
+	RulesTitle string
+	Rules      string
+	Notes      []inlineNote
+}
+
+// inlineChatAccess is the per-user cache payload for inline queries: the chat
+// the user is connected to and whether they are a member of it.
+type inlineChatAccess struct {
+	IsMember bool
+	Chat     gotgbot.Chat
+}
+
+// loadInlineChatAccess looks up the connected chat and the user's membership,
+// read-through cache keyed by user and chat.
+func loadInlineChatAccess(b *gotgbot.Bot, chatID int64, user *gotgbot.User) (*inlineChatAccess, error) {
+	cacheKey := cache.CacheKey("inline_access", user.Id, chatID)
+	return cache.GetFromCacheOrLoad(cacheKey, cache.CacheTTLInlineResults, func() (*inlineChatAccess, error) {
+		chatFullInfo, err := b.GetChat(chatID, nil)
+		if err != nil {
+			return nil, err
+		}
+		chat := chatFullInfo.ToChat()
+
+		isMember, err := chat_status.IsUserInChatWithError(b, &chat, user.Id)
+		if err != nil {
+			return nil, err
+		}
+		return &inlineChatAccess{IsMember: isMember, Chat: chat}, nil
+	})
+}
+
+// loadInlineResults returns the rendered notes and rules of a chat for user.
+// Only chat access is cached: admin status is checked and notes are rendered
+// on every query, so demoted admins lose admin-only notes and random variants
+// change right away.
+func loadInlineResults(b *gotgbot.Bot, ctx *ext.Context, chatID int64, user *gotgbot.User) (*inlineChatResults, error) {
+	access, err := loadInlineChatAccess(b, chatID, user)
+	if err != nil {
+		return nil, err
+	}
+	if access == nil || !access.IsMember {
+		return &inlineChatResults{}, nil
+	}
+	chat := &access.Chat
+
+	isAdmin := chat_status.IsUserAdmin(b, chat.Id, user.Id)
+	out := &inlineChatResults{IsMember: true}
+	for _, note := range notes.GetAllNotes(chat.Id) {
+		if note.AdminOnly && !isAdmin {
+			continue
+		}
+		content, keyboard := media.PrepareNote(b, chat, user, note)
+		out.Notes = append(out.Notes, inlineNote{
+			Name:        note.NoteName,
+			Content:     content,
+			Keyboard:    keyboard,
+			WebPreview:  note.WebPreview,
+			PrivateOnly: note.PrivateOnly,
+			GroupOnly:   note.GroupOnly,
+		})
+	}
+	slices.SortFunc(out.Notes, func(a, b inlineNote) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+
+	if normalizedRules := normalizeRulesForHTML(rules.GetChatRulesInfo(chat.Id).Rules); normalizedRules != "" {
+		// Rules are rendered in the chat's language, as /rules would be.
+		chatCtx := *ctx
+		chatCtx.EffectiveChat = chat
+		tr := i18n.MustNewTranslator(lang.GetLanguage(&chatCtx))
+		out.RulesTitle, _ = tr.GetString("inline_rules_title")
+		out.Rules, _ = tr.GetString("rules_for_chat", i18n.TranslationParams{
+			"first":  html.EscapeString(chat.Title),
+			"second": normalizedRules,
+		})
+	}
+	return out, nil
+}
+
+// inlineNoteAllowed reports whether a note may be posted in the kind of chat
+// the inline query was sent from. Private-only notes are only offered in the
+// user's own chat with the bot, group-only notes only in groups.
+func inlineNoteAllowed(note inlineNote, chatType string) bool {
+	switch {
+	case note.PrivateOnly && !note.GroupOnly:
+		return chatType == "sender"
+	case note.GroupOnly:
+		return chatType == "group" || chatType == "supergroup"
+	default:
+		return true
+	}
+}
+
+// buildInlineResults turns the chat results into the inline answer for
+// query. "rules" returns the chat rules, "#prefix" the notes whose name starts
+// with prefix and an empty query everything available.
+func buildInlineResults(res *inlineChatResults, query, chatType string) []gotgbot.InlineQueryResult {
+	query = strings.ToLower(strings.TrimSpace(query))
+	results := make([]gotgbot.InlineQueryResult, 0)
+
+	if (query == "" || query == "rules") && res.Rules != "" {
+		result, _ := media.InlineResult("rules", res.RulesTitle, media.Content{Text: res.Rules}, media.Options{})
+		results = append(results, result)
+	}
+
+	prefix, isNoteQuery := strings.CutPrefix(query, "#")
+	if query != "" && !isNoteQuery {
+		return results
+	}
+	for i, note := range res.Notes {
+		if len(results) >= inlineMaxResults {
+			break
+		}
+		if !strings.HasPrefix(note.Name, prefix) || !inlineNoteAllowed(note, chatType) {
+			continue
+		}
+		result, ok := media.InlineResult(fmt.Sprintf("note_%d", i), "#"+note.Name, note.Content, media.Options{
+			Keyboard:   &note.Keyboard,
+			WebPreview: note.WebPreview,
+		})
+		if ok {
+			results = append(results, result)
+		}
+	}
+	return results
+}
+
+// inlineQuery answers "@bot #note" and "@bot rules" inline queries with the
+// notes and rules of the chat the user is connected to, or last connected to.
+func (moduleStruct) inlineQuery(b *gotgbot.Bot, ctx *ext.Context) error {
+	query := ctx.InlineQuery
+	if query == nil {
+		return ext.EndGroups
+	}
+	user := &query.From
+
+	answer := func(results []gotgbot.InlineQueryResult, button *gotgbot.InlineQueryResultsButton) error {
+		cacheTime := inlineCacheTime
+		_, err := query.Answer(b, results, &gotgbot.AnswerInlineQueryOpts{
+			CacheTime:  &cacheTime,
+			IsPersonal: true,
+			Button:     button,
+		})
+		if err != nil {
+			log.Errorf("[Inline] Failed to answer inline query for user %d: %v", user.Id, err)
+			return err
+		}
+		return ext.EndGroups
+	}
+	connectButton := func() error {
+		ctx.EffectiveChat = &gotgbot.Chat{Id: user.Id, Type: "private"}
+		tr := i18n.MustNewTranslator(lang.GetLanguage(ctx))
+		text, _ := tr.GetString("inline_connect_button")
+		return answer([]gotgbot.InlineQueryResult{}, &gotgbot.InlineQueryResultsButton{
+			Text:           text,
+			StartParameter: "help_connections",
+		})
+	}
+
+	// Connection keeps the last chat even after /disconnect, so the last-used
+	// chat is served when the user is not connected right now.
+	conn := connections.Connection(user.Id)
+	if conn == nil || conn.ChatId == 0 {
+		return connectButton()
+	}
+	res, err := loadInlineResults(b, ctx, conn.ChatId, user)
+	if err != nil {
+		log.WithFields(log.Fields{
+			"userId": user.Id,
+			"chatId": conn.ChatId,
+			"error":  err,
+		}).Warn("[Inline] Connected chat lookup failed")
+		return connectButton()
+	}
+	if res == nil || !res.IsMember {
+		return connectButton()
+	}
+	return answer(buildInlineResults(res, query.Query, query.ChatType), nil)
+}
+
+// LoadInline registers the inline query handler for notes and rules.
+func LoadInline(dispatcher *ext.Dispatcher) {
+	DefaultHelpRegistry().AbleMap[inlineModule.moduleName] = true
+
+	dispatcher.AddHandler(handlers.NewInlineQuery(inlinequery.All, inlineModule.inlineQuery))
+}
+
+func init() {
+	RegisterLegacyModule("Inline", 195, LoadInline)
+}
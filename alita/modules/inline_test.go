@@ -0,0 +1,171 @@
+//go:build testtools
+
+package modules
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/PaulSonOfLars/gotgbot/v2"
+	"github.com/PaulSonOfLars/gotgbot/v2/ext"
+
+	"github.com/divkix/Alita_Robot/alita/db"
+	"github.com/divkix/Alita_Robot/alita/db/connections"
+	"github.com/divkix/Alita_Robot/alita/db/notes"
+	"github.com/divkix/Alita_Robot/alita/utils/cache"
+	"github.com/divkix/Alita_Robot/alita/utils/media"
+)
+
+func inlineResultIDs(results []gotgbot.InlineQueryResult) []string {
+	ids := make([]string, 0, len(results))
+	for _, result := range results {
+		switch r := result.(type) {
+		case gotgbot.InlineQueryResultArticle:
+			ids = append(ids, r.Id)
+		case gotgbot.InlineQueryResultCachedPhoto:
+			ids = append(ids, r.Id)
+		}
+	}
+	return ids
+}
+
+func TestBuildInlineResultsFiltersByQueryAndChatType(t *testing.T) {
+	res := &inlineChatResults{
+		IsMember:   true,
+		RulesTitle: "Rules",
+		Rules:      "Be nice",
+		Notes: []inlineNote{
+			{Name: "faq", Content: media.Content{Text: "faq", MsgType: db.TEXT}},
+			{Name: "fotos", Content: media.Content{FileID: "p", MsgType: db.PHOTO}},
+			{Name: "secret", Content: media.Content{Text: "pm only", MsgType: db.TEXT}, PrivateOnly: true},
+			{Name: "groupy", Content: media.Content{Text: "groups", MsgType: db.TEXT}, GroupOnly: true},
+		},
+	}
+
+	tests := []struct {
+		query    string
+		chatType string
+		want     []string
+	}{
+		{"rules", "supergroup", []string{"rules"}},
+		{"#f", "private", []string{"note_0", "note_1"}},
+		{"#", "sender", []string{"note_0", "note_1", "note_2"}},
+		{"#", "supergroup", []string{"note_0", "note_1", "note_3"}},
+		{"", "channel", []string{"rules", "note_0", "note_1"}},
+		{"hello", "sender", []string{}},
+	}
+	for _, tc := range tests {
+		got := inlineResultIDs(buildInlineResults(res, tc.query, tc.chatType))
+		if len(got) != len(tc.want) {
+			t.Fatalf("buildInlineResults(%q, %q) = %v, want %v", tc.query, tc.chatType, got, tc.want)
+		}
+		for i := range got {
+			if got[i] != tc.want[i] {
+				t.Fatalf("buildInlineResults(%q, %q) = %v, want %v", tc.query, tc.chatType, got, tc.want)
+			}
+		}
+	}
+}
+
+func TestInlineQueryAnswersConnectedChatNotes(t *testing.T) {
+	client := newModuleBotClient()
+	bot := newModuleTestBot(client)
+	chatID := uniqueModuleChatID()
+	client.responses["getChat"] = json.RawMessage(fmt.Sprintf(`{"id":%d,"type":"supergroup","title":"Inline Chat"}`, chatID))
+	// A fresh user id keeps connections left by other tests out of the way.
+	member := gotgbot.User{Id: -chatID, FirstName: "Member"}
+
+	if err := notes.AddNote(chatID, "wiki", "", "Read the {chatname} wiki", "", nil, nil, db.TEXT, false, false, false, true, false, false); err != nil {
+		t.Fatalf("AddNote() error = %v", err)
+	}
+	if err := notes.AddNote(chatID, "staff", "", "admins only", "", nil, nil, db.TEXT, false, false, true, true, false, false); err != nil {
+		t.Fatalf("AddNote() error = %v", err)
+	}
+
+	newInlineCtx := func(query string) *ext.Context {
+		return ext.NewContext(bot, &gotgbot.Update{UpdateId: 3, InlineQuery: &gotgbot.InlineQuery{
+			Id:       "inline-1",
+			From:     member,
+			Query:    query,
+			ChatType: "supergroup",
+		}}, nil)
+	}
+
+	if err := inlineModule.inlineQuery(bot, newInlineCtx("#")); err != ext.EndGroups {
+		t.Fatalf("inlineQuery() without connection error = %v, want EndGroups", err)
+	}
+	calls := client.callsFor("answerInlineQuery")
+	if len(calls) != 1 || !strings.Contains(fmt.Sprint(calls[0].Params["button"]), "help_connections") {
+		t.Fatalf("answerInlineQuery calls = %+v, want connect button", calls)
+	}
+
+	if err := connections.ConnectId(member.Id, chatID); err != nil {
+		t.Fatalf("ConnectId() error = %v", err)
+	}
+	if err := connections.DisconnectId(member.Id); err != nil {
+		t.Fatalf("DisconnectId() error = %v", err)
+	}
+	if err := inlineModule.inlineQuery(bot, newInlineCtx("#wi")); err != ext.EndGroups {
+		t.Fatalf("inlineQuery() error = %v, want EndGroups", err)
+	}
+	calls = client.callsFor("answerInlineQuery")
+	if len(calls) != 2 {
+		t.Fatalf("answerInlineQuery calls = %d, want 2", len(calls))
+	}
+	results := fmt.Sprint(calls[1].Params["results"])
+	if !strings.Contains(results, "Read the Inline Chat wiki") || strings.Contains(results, "admins only") {
+		t.Fatalf("inline results = %s, want formatted wiki note only", results)
+	}
+}
+
+func TestInlineQueryReflectsNoteAndAdminChangesRightAway(t *testing.T) {
+	client := newModuleBotClient()
+	bot := newModuleTestBot(client)
+	chatID := uniqueModuleChatID()
+	client.responses["getChat"] = json.RawMessage(fmt.Sprintf(`{"id":%d,"type":"supergroup","title":"Inline Chat"}`, chatID))
+	admin := gotgbot.User{Id: -chatID, FirstName: "Admin"}
+	client.responses["getChatAdministrators"] = json.RawMessage(fmt.Sprintf(
+		`[{"status":"administrator","user":{"id":999,"is_bot":true,"first_name":"Alita"}},{"status":"administrator","user":{"id":%d,"is_bot":false,"first_name":"Admin"}}]`,
+		admin.Id,
+	))
+
+	if err := notes.AddNote(chatID, "staff", "", "admins only", "", nil, nil, db.TEXT, false, false, true, true, false, false); err != nil {
+		t.Fatalf("AddNote() error = %v", err)
+	}
+	if err := connections.ConnectId(admin.Id, chatID); err != nil {
+		t.Fatalf("ConnectId() error = %v", err)
+	}
+	query := func() string {
+		t.Helper()
+		ctx := ext.NewContext(bot, &gotgbot.Update{UpdateId: 4, InlineQuery: &gotgbot.InlineQuery{
+			Id:       "inline-2",
+			From:     admin,
+			Query:    "#",
+			ChatType: "supergroup",
+		}}, nil)
+		if err := inlineModule.inlineQuery(bot, ctx); err != ext.EndGroups {
+			t.Fatalf("inlineQuery() error = %v, want EndGroups", err)
+		}
+		calls := client.callsFor("answerInlineQuery")
+		return fmt.Sprint(calls[len(calls)-1].Params["results"])
+	}
+
+	if results := query(); !strings.Contains(results, "admins only") {
+		t.Fatalf("inline results = %s, want the admin-only note for an admin", results)
+	}
+
+	if err := notes.AddNote(chatID, "wiki", "", "fresh wiki", "", nil, nil, db.TEXT, false, false, false, true, false, false); err != nil {
+		t.Fatalf("AddNote() error = %v", err)
+	}
+	if results := query(); !strings.Contains(results, "fresh wiki") {
+		t.Fatalf("inline results = %s, want a note added after the last query", results)
+	}
+
+	client.responses["getChatAdministrators"] = json.RawMessage(`[{"status":"administrator","user":{"id":999,"is_bot":true,"first_name":"Alita"}}]`)
+	cache.InvalidateAdminCache(chatID)
+	if results := query(); strings.Contains(results, "admins only") {
+		t.Fatalf("inline results = %s, want no admin-only note after demotion", results)
+	}
+}
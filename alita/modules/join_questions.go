@@ -0,0 +1,491 @@
+package modules
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"html"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"github.com/PaulSonOfLars/gotgbot/v2"
+	"github.com/PaulSonOfLars/gotgbot/v2/ext"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/divkix/Alita_Robot/alita/db/greetings"
+	"github.com/divkix/Alita_Robot/alita/db/joinrequests"
+	"github.com/divkix/Alita_Robot/alita/db/lang"
+	"github.com/divkix/Alita_Robot/alita/db/models"
+	"github.com/divkix/Alita_Robot/alita/i18n"
+	"github.com/divkix/Alita_Robot/alita/utils/chat_status"
+	"github.com/divkix/Alita_Robot/alita/utils/error_handling"
+	"github.com/divkix/Alita_Robot/alita/utils/formatting"
+)
+
+const (
+	// joinQuestionsHandlerGroup takes applicants' private messages as answers
+	// before any other module sees them.
+	joinQuestionsHandlerGroup = -4
+	// joinQuestionMaxLength caps questions, accepted answers and applicant
+	// answers, in characters.
+	joinQuestionMaxLength = 300
+	// defaultJoinQuestionsTimeout is how many minutes applicants have to
+	// answer when the chat has not set a time.
+	defaultJoinQuestionsTimeout = 60
+	// minJoinQuestionsTimeout and maxJoinQuestionsTimeout bound
+	// /joinquestions timeout, in minutes.
+	minJoinQuestionsTimeout = 5
+	maxJoinQuestionsTimeout = 7 * 24 * 60
+	// joinQuestionsPollInterval is how often timed out applications are
+	// declined.
+	joinQuestionsPollInterval = time.Minute
+)
+
+var (
+	joinQuestionsPollerMu     sync.Mutex
+	joinQuestionsPollerCancel context.CancelFunc
+	joinQuestionsPollerWG     sync.WaitGroup
+)
+
+// chatTranslator returns the translator of a group's language. The whole
+// questionnaire uses it, as the questions are written in it too.
+func chatTranslator(chatID int64) *i18n.Translator {
+	return i18n.MustNewTranslator(lang.GetLanguage(&ext.Context{EffectiveChat: &gotgbot.Chat{Id: chatID}}))
+}
+
+// joinQuestionsTimeout returns the minutes applicants to a chat have to
+// answer its questions.
+func joinQuestionsTimeout(minutes int) int {
+	if minutes <= 0 {
+		return defaultJoinQuestionsTimeout
+	}
+	return minutes
+}
+
+// normalizeJoinAnswer makes answers comparable regardless of case and
+// spacing.
+func normalizeJoinAnswer(answer string) string {
+	return strings.ToLower(strings.Join(strings.Fields(answer), " "))
+}
+
+// joinAnswerMatches reports whether answer is one of the ";"-separated
+// accepted answers.
+func joinAnswerMatches(expected, answer string) bool {
+	answer = normalizeJoinAnswer(answer)
+	for _, accepted := range strings.Split(expected, ";") {
+		if accepted = normalizeJoinAnswer(accepted); accepted != "" && accepted == answer {
+			return true
+		}
+	}
+	return false
+}
+
+// joinAnswersApproved reports whether an application can be approved without
+// an admin: every question has accepted answers and every answer matches.
+func joinAnswersApproved(application *models.JoinApplication) bool {
+	if len(application.Answers) < len(application.Questions) {
+		return false
+	}
+	for i := range application.Questions {
+		if i >= len(application.Expected) || strings.TrimSpace(application.Expected[i]) == "" {
+			return false
+		}
+		if !joinAnswerMatches(application.Expected[i], application.Answers[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// joinAnswersText renders an applicant's answers for the admins deciding on
+// the request, marking answers checked against accepted ones.
+func joinAnswersText(tr *i18n.Translator, application *models.JoinApplication) string {
+	header, _ := tr.GetString("join_questions_answers_header")
+	lines := []string{"", header}
+	for i, question := range application.Questions {
+		answer := ""
+		if i < len(application.Answers) {
+			answer = application.Answers[i]
+		}
+		mark := ""
+		if i < len(application.Expected) && strings.TrimSpace(application.Expected[i]) != "" {
+			mark = " ❌"
+			if joinAnswerMatches(application.Expected[i], answer) {
+				mark = " ✅"
+			}
+		}
+		lines = append(lines, fmt.Sprintf("%d. <i>%s</i>\n   ↳ %s%s", i+1, html.EscapeString(question), html.EscapeString(answer), mark))
+	}
+	return "\n" + strings.Join(lines, "\n")
+}
+
+// startJoinApplication stores an application for a join request and asks the
+// applicant in a private chat to open the questionnaire. Bots may message
+// applicants for a short while after they request to join, without the
+// applicant having started the bot. Returns false if the applicant could not
+// be reached, in which case admins decide without answers.
+func startJoinApplication(bot *gotgbot.Bot, tr *i18n.Translator, request *gotgbot.ChatJoinRequest, questions []*models.JoinQuestion, timeout int) bool {
+	timeout = joinQuestionsTimeout(timeout)
+	application := &models.JoinApplication{
+		ChatId:     request.Chat.Id,
+		UserId:     request.From.Id,
+		UserChatId: request.UserChatId,
+		ChatTitle:  request.Chat.Title,
+		ExpiresAt:  time.Now().UTC().Add(time.Duration(timeout) * time.Minute),
+	}
+	for _, question := range questions {
+		application.Questions = append(application.Questions, question.Question)
+		application.Expected = append(application.Expected, question.Answer)
+	}
+	if err := joinrequests.StartApplication(application); err != nil {
+		return false
+	}
+
+	intro, _ := tr.GetString("join_questions_intro", i18n.TranslationParams{
+		"chat":    html.EscapeString(request.Chat.Title),
+		"count":   len(questions),
+		"timeout": timeout,
+	})
+	startText, _ := tr.GetString("join_questions_start_btn")
+	_, err := bot.SendMessage(request.UserChatId, intro, &gotgbot.SendMessageOpts{
+		ParseMode: formatting.HTML,
+		ReplyMarkup: gotgbot.InlineKeyboardMarkup{
+			InlineKeyboard: [][]gotgbot.InlineKeyboardButton{{{
+				Text: startText,
+				Url:  fmt.Sprintf("https://t.me/%s?start=joinq_%d", bot.Username, request.Chat.Id),
+			}}},
+		},
+	})
+	if err != nil {
+		log.Debugf("[Greetings] Cannot message join applicant %d: %v", request.From.Id, err)
+		joinrequests.DeleteApplication(request.Chat.Id, request.From.Id)
+		return false
+	}
+	return true
+}
+
+// sendJoinQuestion asks the applicant the first question they have not
+// answered yet.
+func sendJoinQuestion(bot *gotgbot.Bot, tr *i18n.Translator, application *models.JoinApplication) error {
+	n := len(application.Answers)
+	text, _ := tr.GetString("join_questions_question", i18n.TranslationParams{
+		"n":        n + 1,
+		"count":    len(application.Questions),
+		"question": html.EscapeString(application.Questions[n]),
+	})
+	if _, err := bot.SendMessage(application.UserChatId, text, &gotgbot.SendMessageOpts{ParseMode: formatting.HTML}); err != nil {
+		log.Error(err)
+		return err
+	}
+	return nil
+}
+
+// joinQuestionsDeepLinkHandler opens the questionnaire of the chat in a
+// joinq_<chat id> start link and asks its first unanswered question.
+func joinQuestionsDeepLinkHandler(b *gotgbot.Bot, ctx *ext.Context, user *gotgbot.User, arg string) error {
+	msg := ctx.EffectiveMessage
+	chatID, err := strconv.ParseInt(strings.TrimPrefix(arg, "joinq_"), 10, 64)
+	if err != nil {
+		tr := i18n.MustNewTranslator(lang.GetLanguage(ctx))
+		text, _ := tr.GetString("helpers_invalid_deep_link")
+		_, _ = msg.Reply(b, text, formatting.Shtml())
+		return ext.EndGroups
+	}
+
+	application := joinrequests.GetApplication(chatID, user.Id)
+	if application == nil {
+		tr := i18n.MustNewTranslator(lang.GetLanguage(ctx))
+		text, _ := tr.GetString("join_questions_not_applying")
+		_, _ = msg.Reply(b, text, formatting.Shtml())
+		return ext.EndGroups
+	}
+	if err := joinrequests.OpenApplication(application); err != nil {
+		return err
+	}
+	tr := chatTranslator(chatID)
+	if len(application.Answers) >= len(application.Questions) {
+		return finishJoinApplication(b, tr, application, user)
+	}
+	if err := sendJoinQuestion(b, tr, application); err != nil {
+		return err
+	}
+	return ext.EndGroups
+}
+
+// joinAnswer takes a private message of an applicant with an open
+// questionnaire as the answer to their current question.
+func (moduleStruct) joinAnswer(bot *gotgbot.Bot, ctx *ext.Context) error {
+	msg := ctx.EffectiveMessage
+	user := ctx.EffectiveSender.User
+	if user == nil || !joinrequests.IsAnswering(user.Id) {
+		return ext.ContinueGroups
+	}
+	application := joinrequests.GetAnsweringApplication(user.Id)
+	if application == nil {
+		return ext.ContinueGroups
+	}
+	tr := chatTranslator(application.ChatId)
+	// A fully answered application is only left when deciding on it failed;
+	// any message retries.
+	if len(application.Answers) >= len(application.Questions) {
+		return finishJoinApplication(bot, tr, application, user)
+	}
+
+	if strings.TrimSpace(msg.Text) == "" {
+		text, _ := tr.GetString("join_questions_text_only")
+		_, _ = msg.Reply(bot, text, formatting.Shtml())
+		return ext.EndGroups
+	}
+
+	saved, err := joinrequests.AddAnswer(application, truncateRunes(strings.TrimSpace(msg.Text), joinQuestionMaxLength))
+	if err != nil || !saved {
+		return ext.EndGroups
+	}
+	if len(application.Answers) < len(application.Questions) {
+		_ = sendJoinQuestion(bot, tr, application)
+		return ext.EndGroups
+	}
+	return finishJoinApplication(bot, tr, application, user)
+}
+
+// finishJoinApplication decides on a fully answered application: it is
+// approved when every answer matches, and otherwise goes to the admins with
+// the answers attached.
+func finishJoinApplication(bot *gotgbot.Bot, tr *i18n.Translator, application *models.JoinApplication, applicant *gotgbot.User) error {
+	// Removing the application first makes sure a repeated answer or the
+	// timeout does not act on it a second time. It is put back if acting on
+	// it fails, so the applicant can retry and the timeout still declines.
+	if !joinrequests.DeleteApplication(application.ChatId, application.UserId) {
+		return ext.EndGroups
+	}
+	chatTitle := html.EscapeString(application.ChatTitle)
+
+	if joinAnswersApproved(application) {
+		if _, err := bot.ApproveChatJoinRequest(application.ChatId, application.UserId, nil); err != nil {
+			log.Warnf("[Greetings] Failed to approve join applicant %d in chat %d: %v", application.UserId, application.ChatId, err)
+			_ = joinrequests.RestoreApplication(application)
+			return ext.EndGroups
+		}
+		_ = joinrequests.Decide(application.ChatId, application.UserId, bot.Id, models.JoinRequestApproved)
+		greetingsModule.clearPendingJoins(application.ChatId, application.UserId)
+		text, _ := tr.GetString("join_questions_approved", i18n.TranslationParams{"chat": chatTitle})
+		_, _ = bot.SendMessage(application.UserChatId, text, &gotgbot.SendMessageOpts{ParseMode: formatting.HTML})
+		return ext.EndGroups
+	}
+
+	record := joinrequests.GetPendingRequest(application.ChatId, application.UserId)
+	if record == nil {
+		record = &models.JoinRequest{
+			ChatId:      application.ChatId,
+			UserId:      application.UserId,
+			FirstName:   applicant.FirstName,
+			Username:    applicant.Username,
+			RequestedAt: application.CreatedAt,
+		}
+	}
+	if err := greetingsModule.postJoinRequestCard(bot, tr, application.ChatId, application.ChatTitle, record, joinAnswersText(tr, application)); err != nil {
+		_ = joinrequests.RestoreApplication(application)
+		return err
+	}
+	text, _ := tr.GetString("join_questions_submitted", i18n.TranslationParams{"chat": chatTitle})
+	_, _ = bot.SendMessage(application.UserChatId, text, &gotgbot.SendMessageOpts{ParseMode: formatting.HTML})
+	return ext.EndGroups
+}
+
+// expireJoinApplications declines the join requests of applicants who did
+// not answer in time.
+func expireJoinApplications(bot *gotgbot.Bot) {
+	applications, err := joinrequests.GetExpiredApplications()
+	if err != nil {
+		return
+	}
+	for _, application := range applications {
+		if !joinrequests.DeleteApplication(application.ChatId, application.UserId) {
+			continue
+		}
+		if _, err := bot.DeclineChatJoinRequest(application.ChatId, application.UserId, nil); err != nil {
+			// The request is usually gone because the applicant withdrew it.
+			log.Debugf("[Greetings] Failed to decline timed out applicant %d in chat %d: %v", application.UserId, application.ChatId, err)
+		}
+		_ = joinrequests.Decide(application.ChatId, application.UserId, bot.Id, models.JoinRequestDeclined)
+		greetingsModule.clearPendingJoins(application.ChatId, application.UserId)
+
+		tr := chatTranslator(application.ChatId)
+		text, _ := tr.GetString("join_questions_expired", i18n.TranslationParams{"chat": html.EscapeString(application.ChatTitle)})
+		_, _ = bot.SendMessage(application.UserChatId, text, &gotgbot.SendMessageOpts{ParseMode: formatting.HTML})
+	}
+}
+
+// StartJoinQuestionsPoller starts declining timed out join applications.
+func StartJoinQuestionsPoller(bot *gotgbot.Bot) {
+	joinQuestionsPollerMu.Lock()
+	defer joinQuestionsPollerMu.Unlock()
+	if joinQuestionsPollerCancel != nil || bot == nil {
+		return
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	joinQuestionsPollerCancel = cancel
+	joinQuestionsPollerWG.Add(1)
+	go func() {
+		defer joinQuestionsPollerWG.Done()
+		ticker := time.NewTicker(joinQuestionsPollInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				func() {
+					defer error_handling.RecoverFromPanic("joinQuestionsPoller", "greetings")
+					expireJoinApplications(bot)
+				}()
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+}
+
+// StopJoinQuestionsPoller stops and joins the join application poller.
+func StopJoinQuestionsPoller() {
+	joinQuestionsPollerMu.Lock()
+	defer joinQuestionsPollerMu.Unlock()
+	if joinQuestionsPollerCancel != nil {
+		joinQuestionsPollerCancel()
+		joinQuestionsPollerWG.Wait()
+		joinQuestionsPollerCancel = nil
+	}
+}
+
+// joinQuestions lists and edits the questions join applicants answer.
+// Usage: /joinquestions [add <question> [| <answer>;<answer>...]|remove <n>|clear|timeout <minutes>]
+func (moduleStruct) joinQuestions(bot *gotgbot.Bot, ctx *ext.Context) error {
+	msg := ctx.EffectiveMessage
+	connectedChat := chat_status.IsUserConnected(bot, ctx, true, true)
+	if connectedChat == nil {
+		return ext.EndGroups
+	}
+	ctx.EffectiveChat = connectedChat
+	chat := ctx.EffectiveChat
+	admin := chat_status.RequireUser(bot, ctx)
+	if admin == nil {
+		return ext.EndGroups
+	}
+	if !chat_status.CanUserChangeInfo(bot, ctx, chat, admin.Id) {
+		chat_status.NewPermissionResponder(bot).Respond(ctx, "chat_status_change_info_cmd_error", "chat_status_change_info_button_error")
+		return ext.EndGroups
+	}
+
+	tr := i18n.MustNewTranslator(lang.GetLanguage(ctx))
+	reply := func(key string, params ...i18n.TranslationParams) error {
+		text, _ := tr.GetString(key, params...)
+		if _, err := msg.Reply(bot, text, formatting.Shtml()); err != nil {
+			log.Error(err)
+			return err
+		}
+		return ext.EndGroups
+	}
+
+	args := ctx.Args()[1:]
+	if len(args) == 0 {
+		return listJoinQuestions(bot, ctx, tr, chat.Id)
+	}
+
+	switch strings.ToLower(args[0]) {
+	case "add":
+		// Questions keep their spacing, so take them from the raw text.
+		rest := ""
+		if i := strings.IndexFunc(msg.Text, unicode.IsSpace); i >= 0 {
+			rest = strings.TrimSpace(strings.TrimSpace(msg.Text[i:])[len(args[0]):])
+		}
+		question, answer, _ := strings.Cut(rest, "|")
+		question = strings.TrimSpace(question)
+		var accepted []string
+		for _, a := range strings.Split(answer, ";") {
+			if a = strings.TrimSpace(a); a != "" {
+				accepted = append(accepted, a)
+			}
+		}
+		answer = strings.Join(accepted, ";")
+		if question == "" {
+			return reply("join_questions_usage")
+		}
+		if len([]rune(question)) > joinQuestionMaxLength || len([]rune(answer)) > joinQuestionMaxLength {
+			return reply("join_questions_too_long", i18n.TranslationParams{"max": joinQuestionMaxLength})
+		}
+		if err := joinrequests.AddQuestion(chat.Id, question, answer); errors.Is(err, joinrequests.ErrTooManyQuestions) {
+			return reply("join_questions_too_many", i18n.TranslationParams{"max": models.MaxJoinQuestions})
+		} else if err != nil {
+			return reply("common_settings_save_failed")
+		}
+		return reply("join_questions_added", i18n.TranslationParams{"n": len(joinrequests.GetQuestions(chat.Id))})
+	case "remove", "rm", "del":
+		if len(args) < 2 {
+			return reply("join_questions_usage")
+		}
+		n, err := strconv.Atoi(args[1])
+		if err != nil {
+			return reply("join_questions_usage")
+		}
+		removed, err := joinrequests.RemoveQuestion(chat.Id, n)
+		if err != nil {
+			return reply("common_settings_save_failed")
+		}
+		if !removed {
+			return reply("join_questions_not_found", i18n.TranslationParams{"n": n})
+		}
+		return reply("join_questions_removed", i18n.TranslationParams{"n": n})
+	case "clear":
+		if err := joinrequests.ClearQuestions(chat.Id); err != nil {
+			return reply("common_settings_save_failed")
+		}
+		return reply("join_questions_cleared")
+	case "timeout":
+		if len(args) < 2 {
+			return reply("join_questions_usage")
+		}
+		minutes, err := strconv.Atoi(args[1])
+		if err != nil || minutes < minJoinQuestionsTimeout || minutes > maxJoinQuestionsTimeout {
+			return reply("join_questions_timeout_invalid", i18n.TranslationParams{"min": minJoinQuestionsTimeout, "max": maxJoinQuestionsTimeout})
+		}
+		if err := greetings.SetJoinQuestionsTimeout(chat.Id, minutes); err != nil {
+			return reply("common_settings_save_failed")
+		}
+		return reply("join_questions_timeout_set", i18n.TranslationParams{"minutes": minutes})
+	default:
+		return reply("join_questions_usage")
+	}
+}
+
+// listJoinQuestions replies with the chat's join questions and their
+// accepted answers.
+func listJoinQuestions(bot *gotgbot.Bot, ctx *ext.Context, tr *i18n.Translator, chatID int64) error {
+	questions := joinrequests.GetQuestions(chatID)
+	var text string
+	if len(questions) == 0 {
+		text, _ = tr.GetString("join_questions_none")
+	} else {
+		header, _ := tr.GetString("join_questions_header", i18n.TranslationParams{
+			"count":   len(questions),
+			"timeout": joinQuestionsTimeout(greetings.GetGreetingSettings(chatID).JoinQuestionsTimeout),
+		})
+		lines := []string{header}
+		for i, question := range questions {
+			lines = append(lines, fmt.Sprintf("%d. %s", i+1, html.EscapeString(question.Question)))
+			if question.Answer == "" {
+				line, _ := tr.GetString("join_questions_free_answer")
+				lines = append(lines, "   ↳ "+line)
+			} else {
+				line, _ := tr.GetString("join_questions_accepted", i18n.TranslationParams{"answer": html.EscapeString(question.Answer)})
+				lines = append(lines, "   ↳ "+line)
+			}
+		}
+		text = strings.Join(lines, "\n")
+	}
+	if _, err := ctx.EffectiveMessage.Reply(bot, text, formatting.Shtml()); err != nil {
+		log.Error(err)
+		return err
+	}
+	return ext.EndGroups
+}
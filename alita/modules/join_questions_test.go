@@ -0,0 +1,231 @@
+package modules
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/PaulSonOfLars/gotgbot/v2"
+	"github.com/PaulSonOfLars/gotgbot/v2/ext"
+
+	"github.com/divkix/Alita_Robot/alita/db/greetings"
+	"github.com/divkix/Alita_Robot/alita/db/joinrequests"
+	"github.com/divkix/Alita_Robot/alita/db/models"
+)
+
+func TestJoinQuestionsCommandEditsQuestions(t *testing.T) {
+	client := newModuleBotClient()
+	bot := newModuleTestBot(client)
+	chat := gotgbot.Chat{Id: uniqueModuleChatID(), Type: "supergroup", Title: "Study Group"}
+	admin := gotgbot.User{Id: 777000, FirstName: "Telegram"}
+	run := func(text string) {
+		t.Helper()
+		if err := greetingsModule.joinQuestions(bot, newModuleMessageContext(bot, chat, admin, text)); err != ext.EndGroups {
+			t.Fatalf("%s error = %v, want EndGroups", text, err)
+		}
+	}
+
+	run("/joinquestions add Which course are you in?")
+	run("/joinquestions add  Type  the  password | Open Sesame; sesame ")
+	questions := joinrequests.GetQuestions(chat.Id)
+	if len(questions) != 2 {
+		t.Fatalf("GetQuestions() = %d questions, want 2", len(questions))
+	}
+	if questions[0].Question != "Which course are you in?" || questions[0].Answer != "" {
+		t.Fatalf("first question = %+v, want a free answer question", questions[0])
+	}
+	if questions[1].Question != "Type  the  password" || questions[1].Answer != "Open Sesame;sesame" {
+		t.Fatalf("second question = %q with answers %q", questions[1].Question, questions[1].Answer)
+	}
+
+	run("/joinquestions remove 1")
+	if questions := joinrequests.GetQuestions(chat.Id); len(questions) != 1 || questions[0].Position != 1 {
+		t.Fatalf("after remove questions = %+v, want one question at position 1", questions)
+	}
+
+	run("/joinquestions timeout 1")
+	if got := joinQuestionsTimeout(greetings.GetGreetingSettings(chat.Id).JoinQuestionsTimeout); got != defaultJoinQuestionsTimeout {
+		t.Fatalf("invalid timeout changed JoinQuestionsTimeout to %d", got)
+	}
+	run("/joinquestions timeout 30")
+	if got := greetings.GetGreetingSettings(chat.Id).JoinQuestionsTimeout; got != 30 {
+		t.Fatalf("JoinQuestionsTimeout = %d, want 30", got)
+	}
+
+	run("/joinquestions")
+	run("/joinquestions clear")
+	if questions := joinrequests.GetQuestions(chat.Id); len(questions) != 0 {
+		t.Fatalf("after clear questions = %d, want 0", len(questions))
+	}
+}
+
+// applyWithQuestions sends a join request to a chat with the given questions
+// and opens the questionnaire from the applicant's private chat.
+func applyWithQuestions(t *testing.T, client *moduleBotClient, bot *gotgbot.Bot, chat gotgbot.Chat, applicant gotgbot.User, questions map[string]string) {
+	t.Helper()
+	for _, question := range []string{"q1", "q2"} {
+		if err := joinrequests.AddQuestion(chat.Id, question, questions[question]); err != nil {
+			t.Fatalf("AddQuestion() error = %v", err)
+		}
+	}
+
+	if err := greetingsModule.pendingJoins(bot, newJoinRequestContext(bot, chat, applicant)); err != ext.ContinueGroups {
+		t.Fatalf("pendingJoins error = %v, want ContinueGroups", err)
+	}
+	calls := client.callsFor("sendMessage")
+	if len(calls) != 1 {
+		t.Fatalf("sendMessage calls = %d, want only the private invitation", len(calls))
+	}
+	if got := fmt.Sprint(calls[0].Params["chat_id"]); got != strconv.FormatInt(applicant.Id, 10) {
+		t.Fatalf("invitation sent to %s, want the applicant's private chat", got)
+	}
+	if markup := fmt.Sprint(calls[0].Params["reply_markup"]); !strings.Contains(markup, fmt.Sprintf("joinq_%d", chat.Id)) {
+		t.Fatalf("invitation markup %q has no start link", markup)
+	}
+	if joinrequests.GetPendingRequest(chat.Id, applicant.Id) == nil {
+		t.Fatal("join request was not recorded as pending")
+	}
+
+	private := gotgbot.Chat{Id: applicant.Id, Type: "private"}
+	ctx := newModuleMessageContext(bot, private, applicant, fmt.Sprintf("/start joinq_%d", chat.Id))
+	if err := joinQuestionsDeepLinkHandler(bot, ctx, &applicant, fmt.Sprintf("joinq_%d", chat.Id)); err != ext.EndGroups {
+		t.Fatalf("joinQuestionsDeepLinkHandler error = %v, want EndGroups", err)
+	}
+	if got := len(client.callsFor("sendMessage")); got != 2 {
+		t.Fatalf("sendMessage calls = %d, want the first question asked", got)
+	}
+}
+
+func answerJoinQuestions(t *testing.T, bot *gotgbot.Bot, applicant gotgbot.User, answers ...string) {
+	t.Helper()
+	private := gotgbot.Chat{Id: applicant.Id, Type: "private"}
+	for _, answer := range answers {
+		if err := greetingsModule.joinAnswer(bot, newModuleMessageContext(bot, private, applicant, answer)); err != ext.EndGroups {
+			t.Fatalf("joinAnswer(%q) error = %v, want EndGroups", answer, err)
+		}
+	}
+}
+
+func TestJoinQuestionsMatchingAnswersApprove(t *testing.T) {
+	client := newModuleBotClient()
+	bot := newModuleTestBot(client)
+	chat := gotgbot.Chat{Id: uniqueModuleChatID(), Type: "supergroup", Title: "Study Group"}
+	applicant := gotgbot.User{Id: 7171, FirstName: "Student"}
+
+	applyWithQuestions(t, client, bot, chat, applicant, map[string]string{"q1": "math;physics", "q2": "yes"})
+	answerJoinQuestions(t, bot, applicant, "  Physics ", "YES")
+
+	if got := len(client.callsFor("approveChatJoinRequest")); got != 1 {
+		t.Fatalf("approveChatJoinRequest calls = %d, want 1", got)
+	}
+	if joinrequests.GetApplication(chat.Id, applicant.Id) != nil {
+		t.Fatal("application still open after approval")
+	}
+	if counts := joinrequests.DecisionCounts(chat.Id, applicant.Id); counts[models.JoinRequestApproved] != 1 {
+		t.Fatalf("DecisionCounts() = %v, want the approval recorded", counts)
+	}
+
+	// Once the questionnaire is finished, private messages are not answers.
+	private := gotgbot.Chat{Id: applicant.Id, Type: "private"}
+	if err := greetingsModule.joinAnswer(bot, newModuleMessageContext(bot, private, applicant, "hello")); err != ext.ContinueGroups {
+		t.Fatalf("joinAnswer after approval error = %v, want ContinueGroups", err)
+	}
+}
+
+func TestJoinQuestionsFailedApprovalKeepsApplication(t *testing.T) {
+	client := newModuleBotClient()
+	bot := newModuleTestBot(client)
+	chat := gotgbot.Chat{Id: uniqueModuleChatID(), Type: "supergroup", Title: "Study Group"}
+	applicant := gotgbot.User{Id: 7373, FirstName: "Student"}
+
+	applyWithQuestions(t, client, bot, chat, applicant, map[string]string{"q1": "math", "q2": "yes"})
+	client.errors["approveChatJoinRequest"] = errors.New("Bad Request: too many requests")
+	answerJoinQuestions(t, bot, applicant, "math", "yes")
+
+	if got := len(client.callsFor("approveChatJoinRequest")); got != 1 {
+		t.Fatalf("approveChatJoinRequest calls = %d, want 1", got)
+	}
+	if joinrequests.GetApplication(chat.Id, applicant.Id) == nil {
+		t.Fatal("application was dropped although approving failed")
+	}
+
+	// Any later message retries the decision.
+	delete(client.errors, "approveChatJoinRequest")
+	answerJoinQuestions(t, bot, applicant, "hello?")
+	if got := len(client.callsFor("approveChatJoinRequest")); got != 2 {
+		t.Fatalf("approveChatJoinRequest calls = %d, want the approval retried", got)
+	}
+	if joinrequests.GetApplication(chat.Id, applicant.Id) != nil {
+		t.Fatal("application still open after approval")
+	}
+	if joinrequests.IsAnswering(applicant.Id) {
+		t.Fatal("applicant still flagged as answering after approval")
+	}
+}
+
+func TestJoinQuestionsOtherAnswersGoToAdmins(t *testing.T) {
+	client := newModuleBotClient()
+	bot := newModuleTestBot(client)
+	chat := gotgbot.Chat{Id: uniqueModuleChatID(), Type: "supergroup", Title: "Study Group"}
+	applicant := gotgbot.User{Id: 7272, FirstName: "Student"}
+	client.responses["getChat"] = []byte(fmt.Sprintf(`{"id":%d,"type":"supergroup","title":"Study Group"}`, chat.Id))
+
+	applyWithQuestions(t, client, bot, chat, applicant, map[string]string{"q1": "math", "q2": ""})
+	answerJoinQuestions(t, bot, applicant, "math", "I like proofs")
+
+	if got := len(client.callsFor("approveChatJoinRequest")); got != 0 {
+		t.Fatalf("approveChatJoinRequest calls = %d, want the request left to admins", got)
+	}
+	var card *moduleBotCall
+	for _, call := range client.callsFor("sendMessage") {
+		if strings.Contains(fmt.Sprint(call.Params["reply_markup"]), "join_request") {
+			card = &call
+		}
+	}
+	if card == nil {
+		t.Fatal("no review card was posted for the answers")
+	}
+	if text := fmt.Sprint(card.Params["text"]); !strings.Contains(text, "I like proofs") {
+		t.Fatalf("review card %q does not include the answers", text)
+	}
+	if pending := joinrequests.GetPendingRequest(chat.Id, applicant.Id); pending == nil || pending.ReviewMessageId == 0 {
+		t.Fatalf("pending request = %+v, want it linked to the review card", pending)
+	}
+}
+
+func TestExpiredJoinApplicationsAreDeclined(t *testing.T) {
+	client := newModuleBotClient()
+	bot := newModuleTestBot(client)
+	chatID := uniqueModuleChatID()
+	userID := chatID + 1
+	if err := joinrequests.StartApplication(&models.JoinApplication{
+		ChatId:     chatID,
+		UserId:     userID,
+		UserChatId: userID,
+		ChatTitle:  "Study Group",
+		Questions:  models.StringArray{"q1"},
+		Expected:   models.StringArray{""},
+		ExpiresAt:  time.Now().Add(-time.Minute).UTC(),
+	}); err != nil {
+		t.Fatalf("StartApplication() error = %v", err)
+	}
+
+	expireJoinApplications(bot)
+
+	declined := false
+	for _, call := range client.callsFor("declineChatJoinRequest") {
+		declined = declined || fmt.Sprint(call.Params["user_id"]) == strconv.FormatInt(userID, 10)
+	}
+	if !declined {
+		t.Fatal("timed out applicant was not declined")
+	}
+	if joinrequests.DeleteApplication(chatID, userID) {
+		t.Fatal("timed out application was not removed")
+	}
+	if counts := joinrequests.DecisionCounts(chatID, userID); counts[models.JoinRequestDeclined] != 1 {
+		t.Fatalf("DecisionCounts() = %v, want the decline recorded", counts)
+	}
+}
@@ -0,0 +1,325 @@
+package modules
+
+import (
+	"fmt"
+	"html"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/PaulSonOfLars/gotgbot/v2"
+	"github.com/PaulSonOfLars/gotgbot/v2/ext"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/divkix/Alita_Robot/alita/db/greetings"
+	"github.com/divkix/Alita_Robot/alita/db/joinrequests"
+	"github.com/divkix/Alita_Robot/alita/db/lang"
+	"github.com/divkix/Alita_Robot/alita/db/models"
+	"github.com/divkix/Alita_Robot/alita/db/user"
+	"github.com/divkix/Alita_Robot/alita/i18n"
+	"github.com/divkix/Alita_Robot/alita/utils/chat_status"
+	"github.com/divkix/Alita_Robot/alita/utils/formatting"
+	"github.com/divkix/Alita_Robot/alita/utils/helpers"
+)
+
+const (
+	// joinReviewBioLimit caps how much of a requester's bio a review card shows.
+	joinReviewBioLimit = 300
+	// joinReviewNewAccountPercent is the ID rank from which an account is
+	// flagged as likely new.
+	joinReviewNewAccountPercent = 90
+	// pendingRequestsLimit caps how many requests /pendingrequests lists.
+	pendingRequestsLimit = 25
+)
+
+// joinRequestKeyboard returns the Approve, Decline and Ban buttons of a join
+// request card. The chat is part of the callback data so that cards posted
+// in a separate review chat act on the chat the request was made to.
+func joinRequestKeyboard(tr *i18n.Translator, chatID, userID int64) gotgbot.InlineKeyboardMarkup {
+	approveText, _ := tr.GetString("greetings_join_request_approve_btn")
+	declineText, _ := tr.GetString("greetings_join_request_decline_btn")
+	banText, _ := tr.GetString("greetings_join_request_ban_btn")
+	button := func(text, action string) gotgbot.InlineKeyboardButton {
+		return gotgbot.InlineKeyboardButton{
+			Text: text,
+			CallbackData: encodeCallbackData("join_request", map[string]string{
+				"a": action,
+				"u": strconv.FormatInt(userID, 10),
+				"c": strconv.FormatInt(chatID, 10),
+			}),
+		}
+	}
+	return gotgbot.InlineKeyboardMarkup{
+		InlineKeyboard: [][]gotgbot.InlineKeyboardButton{
+			{button(approveText, "accept"), button(declineText, "decline")},
+			{button(banText, "ban")},
+		},
+	}
+}
+
+// joinReviewCard renders the profile summary posted for review of a join
+// request: who is asking, their bio, an account age estimate, the invite
+// link used and the outcome of their earlier requests to the chat.
+func joinReviewCard(tr *i18n.Translator, chatTitle string, request *models.JoinRequest) string {
+	none, _ := tr.GetString("join_review_none")
+
+	header, _ := tr.GetString("join_review_card_header", i18n.TranslationParams{"chat": html.EscapeString(chatTitle)})
+	lines := []string{header}
+
+	line, _ := tr.GetString("join_review_user", i18n.TranslationParams{
+		"mention": formatting.MentionHtml(request.UserId, request.FirstName),
+		"id":      request.UserId,
+	})
+	lines = append(lines, line)
+
+	username := none
+	if request.Username != "" {
+		username = "@" + request.Username
+	}
+	line, _ = tr.GetString("join_review_username", i18n.TranslationParams{"username": username})
+	lines = append(lines, line)
+
+	bio := none
+	if request.Bio != "" {
+		bio = html.EscapeString(truncateRunes(request.Bio, joinReviewBioLimit))
+	}
+	line, _ = tr.GetString("join_review_bio", i18n.TranslationParams{"bio": bio})
+	lines = append(lines, line)
+
+	if percent, ok := user.IDNewerThan(request.UserId); !ok {
+		line, _ = tr.GetString("join_review_account_unknown")
+	} else if percent >= joinReviewNewAccountPercent {
+		line, _ = tr.GetString("join_review_account_new", i18n.TranslationParams{"percent": percent})
+	} else {
+		line, _ = tr.GetString("join_review_account_age", i18n.TranslationParams{"percent": percent})
+	}
+	lines = append(lines, line)
+
+	if request.InviteLink != "" {
+		line, _ = tr.GetString("join_review_invite_link", i18n.TranslationParams{"link": html.EscapeString(request.InviteLink)})
+		lines = append(lines, line)
+	}
+
+	counts := joinrequests.DecisionCounts(request.ChatId, request.UserId)
+	if len(counts) > 0 {
+		line, _ = tr.GetString("join_review_history", i18n.TranslationParams{
+			"approved": counts[models.JoinRequestApproved],
+			"declined": counts[models.JoinRequestDeclined],
+			"banned":   counts[models.JoinRequestBanned],
+		})
+		lines = append(lines, line)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// joinRequestInviteLink returns the name of the invite link a request came
+// through, or the link itself when it has no name.
+func joinRequestInviteLink(request *gotgbot.ChatJoinRequest) string {
+	if request.InviteLink == nil {
+		return ""
+	}
+	if request.InviteLink.Name != "" {
+		return request.InviteLink.Name
+	}
+	return request.InviteLink.InviteLink
+}
+
+// truncateRunes shortens s to at most limit runes, marking the cut.
+func truncateRunes(s string, limit int) string {
+	runes := []rune(s)
+	if len(runes) <= limit {
+		return s
+	}
+	return string(runes[:limit]) + "…"
+}
+
+// newJoinRequestRecord returns the audit record of a join request.
+func newJoinRequestRecord(request *gotgbot.ChatJoinRequest) *models.JoinRequest {
+	return &models.JoinRequest{
+		ChatId:      request.Chat.Id,
+		UserId:      request.From.Id,
+		FirstName:   request.From.FirstName,
+		Username:    request.From.Username,
+		Bio:         request.Bio,
+		InviteLink:  joinRequestInviteLink(request),
+		RequestedAt: time.Unix(request.Date, 0).UTC(),
+	}
+}
+
+// recordJoinRequest keeps a join request as pending for /pendingrequests and
+// the audit trail, along with its review card once one was posted.
+func recordJoinRequest(record *models.JoinRequest, card *gotgbot.Message) {
+	if card != nil {
+		record.ReviewChatId = card.Chat.Id
+		record.ReviewMessageId = card.MessageId
+	}
+	_ = joinrequests.RecordPending(record)
+}
+
+// postJoinRequestCard asks admins to decide on a join request: with the full
+// review card in the review chat when one is set, otherwise with a short
+// notice in the chat itself. extra, such as the applicant's answers to the
+// join questions, is appended to either.
+func (m moduleStruct) postJoinRequestCard(bot *gotgbot.Bot, tr *i18n.Translator, chatID int64, chatTitle string, record *models.JoinRequest, extra string) error {
+	settings := greetings.GetGreetingSettings(chatID)
+	opts := &gotgbot.SendMessageOpts{
+		ParseMode:   formatting.HTML,
+		ReplyMarkup: joinRequestKeyboard(tr, chatID, record.UserId),
+	}
+
+	var card *gotgbot.Message
+	var err error
+	if settings.JoinReviewChat != 0 {
+		card, err = helpers.SendMessageWithErrorHandling(bot, settings.JoinReviewChat, joinReviewCard(tr, chatTitle, record)+extra, opts)
+		if err != nil || card == nil {
+			// The review chat is unreachable; fall back to asking in the chat itself.
+			log.Warnf("[Greetings] Failed to post join request of %d to review chat %d: %v", record.UserId, settings.JoinReviewChat, err)
+		}
+	}
+	if card == nil {
+		newUserText, _ := tr.GetString("greetings_join_request_new")
+		userInfoTemplate, _ := tr.GetString("format_user_info")
+		userIdTemplate, _ := tr.GetString("format_user_id")
+
+		card, err = helpers.SendMessageWithErrorHandling(
+			bot,
+			chatID,
+			fmt.Sprint(
+				newUserText,
+				"\n"+fmt.Sprintf(userInfoTemplate, formatting.MentionHtml(record.UserId, record.FirstName)),
+				"\n"+fmt.Sprintf(userIdTemplate, record.UserId),
+			)+extra,
+			opts,
+		)
+		if err != nil {
+			log.Error(err)
+			return err
+		}
+	}
+	recordJoinRequest(record, card)
+	m.setPendingJoins(chatID, record.UserId)
+	return nil
+}
+
+// joinReview shows or sets the chat join requests are reviewed in.
+// Usage: /joinreview [<chat id>|here|off]
+func (moduleStruct) joinReview(bot *gotgbot.Bot, ctx *ext.Context) error {
+	msg := ctx.EffectiveMessage
+	connectedChat := chat_status.IsUserConnected(bot, ctx, true, true)
+	if connectedChat == nil {
+		return ext.EndGroups
+	}
+	ctx.EffectiveChat = connectedChat
+	chat := ctx.EffectiveChat
+	admin := chat_status.RequireUser(bot, ctx)
+	if admin == nil {
+		return ext.EndGroups
+	}
+	if !chat_status.CanUserChangeInfo(bot, ctx, chat, admin.Id) {
+		chat_status.NewPermissionResponder(bot).Respond(ctx, "chat_status_change_info_cmd_error", "chat_status_change_info_button_error")
+		return ext.EndGroups
+	}
+
+	tr := i18n.MustNewTranslator(lang.GetLanguage(ctx))
+	reply := func(text string) error {
+		if _, err := msg.Reply(bot, text, formatting.Shtml()); err != nil {
+			log.Error(err)
+			return err
+		}
+		return ext.EndGroups
+	}
+
+	args := ctx.Args()[1:]
+	if len(args) == 0 {
+		reviewChat := greetings.GetGreetingSettings(chat.Id).JoinReviewChat
+		if reviewChat == 0 {
+			text, _ := tr.GetString("join_review_status_off")
+			return reply(text)
+		}
+		text, _ := tr.GetString("join_review_status", i18n.TranslationParams{"chat": reviewChat})
+		return reply(text)
+	}
+
+	var target int64
+	switch arg := strings.ToLower(args[0]); arg {
+	case "off", "no":
+		target = 0
+	case "here":
+		target = chat.Id
+	default:
+		parsed, err := strconv.ParseInt(arg, 10, 64)
+		if err != nil || parsed == 0 {
+			text, _ := tr.GetString("join_review_usage")
+			return reply(text)
+		}
+		target = parsed
+	}
+
+	if target != 0 && target != chat.Id {
+		// The admin must run the target chat too, or anyone could route
+		// requesters' profiles into a chat of their choosing.
+		if !chat_status.IsUserAdmin(bot, target, admin.Id) {
+			text, _ := tr.GetString("join_review_not_target_admin")
+			return reply(text)
+		}
+		notice, _ := tr.GetString("join_review_target_notice", i18n.TranslationParams{"chat": html.EscapeString(chat.Title)})
+		if _, err := bot.SendMessage(target, notice, &gotgbot.SendMessageOpts{ParseMode: formatting.HTML}); err != nil {
+			log.Debugf("[Greetings] Cannot post join reviews to %d: %v", target, err)
+			text, _ := tr.GetString("join_review_target_failed")
+			return reply(text)
+		}
+	}
+
+	if err := greetings.SetJoinReviewChat(chat.Id, target); err != nil {
+		text, _ := tr.GetString("common_settings_save_failed")
+		return reply(text)
+	}
+	if target == 0 {
+		text, _ := tr.GetString("join_review_disabled")
+		return reply(text)
+	}
+	text, _ := tr.GetString("join_review_enabled", i18n.TranslationParams{"chat": target})
+	return reply(text)
+}
+
+// pendingRequests lists the join requests of the chat still waiting for a
+// decision, oldest first.
+func (moduleStruct) pendingRequests(bot *gotgbot.Bot, ctx *ext.Context) error {
+	msg := ctx.EffectiveMessage
+	connectedChat := chat_status.IsUserConnected(bot, ctx, true, false)
+	if connectedChat == nil {
+		return ext.EndGroups
+	}
+	chat := connectedChat
+	tr := i18n.MustNewTranslator(lang.GetLanguage(ctx))
+
+	requests, total, err := joinrequests.GetPending(chat.Id, pendingRequestsLimit)
+	var text string
+	switch {
+	case err != nil:
+		text, _ = tr.GetString("error_generic")
+	case total == 0:
+		text, _ = tr.GetString("pending_requests_none")
+	default:
+		header, _ := tr.GetString("pending_requests_header", i18n.TranslationParams{"count": total})
+		lines := []string{header}
+		for _, request := range requests {
+			lines = append(lines, fmt.Sprintf(" - %s (<code>%d</code>) · %s",
+				formatting.MentionHtml(request.UserId, request.FirstName),
+				request.UserId,
+				request.RequestedAt.UTC().Format("2006-01-02 15:04 UTC"),
+			))
+		}
+		if more := total - int64(len(requests)); more > 0 {
+			moreText, _ := tr.GetString("pending_requests_more", i18n.TranslationParams{"count": more})
+			lines = append(lines, moreText)
+		}
+		text = strings.Join(lines, "\n")
+	}
+
+	if _, err := msg.Reply(bot, text, formatting.Shtml()); err != nil {
+		log.Error(err)
+		return err
+	}
+	return ext.EndGroups
+}
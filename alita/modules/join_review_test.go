@@ -0,0 +1,128 @@
+package modules
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/PaulSonOfLars/gotgbot/v2"
+	"github.com/PaulSonOfLars/gotgbot/v2/ext"
+
+	"github.com/divkix/Alita_Robot/alita/db/greetings"
+	"github.com/divkix/Alita_Robot/alita/db/joinrequests"
+	"github.com/divkix/Alita_Robot/alita/db/models"
+)
+
+func TestJoinReviewCommandSetsAndClearsReviewChat(t *testing.T) {
+	client := newModuleBotClient()
+	bot := newModuleTestBot(client)
+	chat := gotgbot.Chat{Id: uniqueModuleChatID(), Type: "supergroup", Title: "Private Group"}
+	admin := gotgbot.User{Id: 777000, FirstName: "Telegram"}
+
+	if err := greetingsModule.joinReview(bot, newModuleMessageContext(bot, chat, admin, "/joinreview here")); err != ext.EndGroups {
+		t.Fatalf("joinReview here error = %v, want EndGroups", err)
+	}
+	if got := greetings.GetGreetingSettings(chat.Id).JoinReviewChat; got != chat.Id {
+		t.Fatalf("JoinReviewChat = %d, want %d", got, chat.Id)
+	}
+
+	if err := greetingsModule.joinReview(bot, newModuleMessageContext(bot, chat, admin, "/joinreview nonsense")); err != ext.EndGroups {
+		t.Fatalf("joinReview invalid error = %v, want EndGroups", err)
+	}
+	if got := greetings.GetGreetingSettings(chat.Id).JoinReviewChat; got != chat.Id {
+		t.Fatalf("invalid argument changed JoinReviewChat to %d", got)
+	}
+
+	if err := greetingsModule.joinReview(bot, newModuleMessageContext(bot, chat, admin, "/joinreview off")); err != ext.EndGroups {
+		t.Fatalf("joinReview off error = %v, want EndGroups", err)
+	}
+	if got := greetings.GetGreetingSettings(chat.Id).JoinReviewChat; got != 0 {
+		t.Fatalf("JoinReviewChat = %d after off, want 0", got)
+	}
+}
+
+func TestJoinReviewCardIsDecidedFromReviewChat(t *testing.T) {
+	client := newModuleBotClient()
+	bot := newModuleTestBot(client)
+	chat := gotgbot.Chat{Id: uniqueModuleChatID(), Type: "supergroup", Title: "Private Group"}
+	reviewChat := gotgbot.Chat{Id: uniqueModuleChatID(), Type: "supergroup", Title: "Admin Chat"}
+	admin := gotgbot.User{Id: 42, FirstName: "Invite Admin"}
+	applicant := gotgbot.User{Id: 6161, FirstName: "Applicant", Username: "applicant"}
+	client.responses["getChat"] = []byte(fmt.Sprintf(`{"id":%d,"type":"supergroup","title":"Private Group"}`, chat.Id))
+	seedCallbackAdmins(t, chat.Id,
+		gotgbot.MergedChatMember{Status: "administrator", User: gotgbot.User{Id: 999, IsBot: true}, CanInviteUsers: true},
+		gotgbot.MergedChatMember{Status: "administrator", User: admin, CanInviteUsers: true},
+	)
+	if err := greetings.SetJoinReviewChat(chat.Id, reviewChat.Id); err != nil {
+		t.Fatalf("SetJoinReviewChat() error = %v", err)
+	}
+
+	ctx := newJoinRequestContext(bot, chat, applicant)
+	ctx.ChatJoinRequest.Bio = "hello there"
+	if err := greetingsModule.pendingJoins(bot, ctx); err != ext.ContinueGroups {
+		t.Fatalf("pendingJoins error = %v, want ContinueGroups", err)
+	}
+	calls := client.callsFor("sendMessage")
+	if len(calls) != 1 {
+		t.Fatalf("sendMessage calls = %d, want one review card", len(calls))
+	}
+	if got := fmt.Sprint(calls[0].Params["chat_id"]); got != strconv.FormatInt(reviewChat.Id, 10) {
+		t.Fatalf("review card sent to %s, want review chat %d", got, reviewChat.Id)
+	}
+	if markup := fmt.Sprint(calls[0].Params["reply_markup"]); !strings.Contains(markup, "join_request") {
+		t.Fatalf("review card markup %q has no decision buttons", markup)
+	}
+	pending := joinrequests.GetPendingRequest(chat.Id, applicant.Id)
+	if pending == nil || pending.Bio != "hello there" || pending.ReviewMessageId == 0 {
+		t.Fatalf("pending request = %+v, want recorded with bio and review card", pending)
+	}
+
+	data := encodeCallbackData("join_request", map[string]string{
+		"a": "accept",
+		"u": strconv.FormatInt(applicant.Id, 10),
+		"c": strconv.FormatInt(chat.Id, 10),
+	})
+	if err := greetingsModule.joinRequestHandler(bot, newModuleCallbackContext(bot, reviewChat, admin, data)); err != ext.EndGroups {
+		t.Fatalf("joinRequestHandler error = %v, want EndGroups", err)
+	}
+	approvals := client.callsFor("approveChatJoinRequest")
+	if len(approvals) != 1 {
+		t.Fatalf("approveChatJoinRequest calls = %d, want 1", len(approvals))
+	}
+	if got := fmt.Sprint(approvals[0].Params["chat_id"]); got != strconv.FormatInt(chat.Id, 10) {
+		t.Fatalf("request approved in %s, want requesting chat %d", got, chat.Id)
+	}
+	if joinrequests.GetPendingRequest(chat.Id, applicant.Id) != nil {
+		t.Fatal("request still pending after approval")
+	}
+	if counts := joinrequests.DecisionCounts(chat.Id, applicant.Id); counts[models.JoinRequestApproved] != 1 {
+		t.Fatalf("DecisionCounts() = %v, want the approval recorded", counts)
+	}
+}
+
+func TestPendingRequestsListsWaitingRequests(t *testing.T) {
+	client := newModuleBotClient()
+	bot := newModuleTestBot(client)
+	chat := gotgbot.Chat{Id: uniqueModuleChatID(), Type: "supergroup", Title: "Private Group"}
+	admin := gotgbot.User{Id: 777000, FirstName: "Telegram"}
+
+	if err := greetingsModule.pendingRequests(bot, newModuleMessageContext(bot, chat, admin, "/pendingrequests")); err != ext.EndGroups {
+		t.Fatalf("pendingRequests error = %v, want EndGroups", err)
+	}
+
+	recordJoinRequest(newJoinRequestRecord(&gotgbot.ChatJoinRequest{Chat: chat, From: gotgbot.User{Id: 8181, FirstName: "Waiting"}, Date: 1}), nil)
+	if err := greetingsModule.pendingRequests(bot, newModuleMessageContext(bot, chat, admin, "/pendingrequests")); err != ext.EndGroups {
+		t.Fatalf("pendingRequests error = %v, want EndGroups", err)
+	}
+	calls := client.callsFor("sendMessage")
+	if len(calls) != 2 {
+		t.Fatalf("sendMessage calls = %d, want 2", len(calls))
+	}
+	if text := calls[0].Params["text"].(string); strings.Contains(text, "8181") {
+		t.Fatalf("empty queue listed a request: %q", text)
+	}
+	if text := calls[1].Params["text"].(string); !strings.Contains(text, "<code>8181</code>") {
+		t.Fatalf("pending list %q does not include the waiting request", text)
+	}
+}
@@ -0,0 +1,492 @@
+package modules
+
+import (
+	"fmt"
+	"html"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/PaulSonOfLars/gotgbot/v2"
+	"github.com/PaulSonOfLars/gotgbot/v2/ext"
+	"github.com/PaulSonOfLars/gotgbot/v2/ext/handlers"
+	"github.com/PaulSonOfLars/gotgbot/v2/ext/handlers/filters/message"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/divkix/Alita_Robot/alita/db/approvals"
+	"github.com/divkix/Alita_Robot/alita/db/karma"
+	"github.com/divkix/Alita_Robot/alita/db/lang"
+	"github.com/divkix/Alita_Robot/alita/db/models"
+	"github.com/divkix/Alita_Robot/alita/db/user"
+	"github.com/divkix/Alita_Robot/alita/i18n"
+	"github.com/divkix/Alita_Robot/alita/utils/chat_status"
+	"github.com/divkix/Alita_Robot/alita/utils/extraction"
+	"github.com/divkix/Alita_Robot/alita/utils/formatting"
+	"github.com/divkix/Alita_Robot/alita/utils/helpers"
+)
+
+var karmaModule = moduleStruct{moduleName: "Karma", handlerGroup: 12}
+
+const (
+	karmaTopLimit = 10
+	karmaReaction = "👍"
+)
+
+// karmaTriggers are the replies that give karma in every chat with karma
+// on, in addition to the chat's own triggers.
+var karmaTriggers = []string{"+1", "thanks", "thank you"}
+
+// karmaCooldowns holds, per chat, giver and receiver, when karma was last
+// given, so a member cannot pile karma on someone by repeating themselves.
+var karmaCooldowns = &sync.Map{}
+
+type karmaCooldownKey struct {
+	chatID, giverID, receiverID int64
+}
+
+// startsWithTrigger reports whether a message starts with a karma trigger as
+// a whole word: "thanks!" does, "thanksgiving" and "+10" do not.
+func startsWithTrigger(text, trigger string) bool {
+	text = strings.ToLower(strings.TrimSpace(text))
+	rest, found := strings.CutPrefix(text, trigger)
+	if !found || trigger == "" {
+		return false
+	}
+	next, _ := utf8.DecodeRuneInString(rest)
+	return rest == "" || !unicode.IsLetter(next) && !unicode.IsDigit(next)
+}
+
+// karmaTrigger reports whether a reply gives karma in a chat.
+func karmaTrigger(settings *models.KarmaSettings, text string) bool {
+	for _, trigger := range slices.Concat(karmaTriggers, settings.Triggers) {
+		if startsWithTrigger(text, trigger) {
+			return true
+		}
+	}
+	return false
+}
+
+// giveKarma adds a point of karma from giver to the receiver, the author of
+// the message it is given for, unless the giver already gave karma for that
+// message or is still cooling down, and approves the receiver when they
+// reach the chat's approval threshold. It returns the receiver's new karma.
+func giveKarma(b *gotgbot.Bot, ctx *ext.Context, chat *gotgbot.Chat, settings *models.KarmaSettings, messageID, giverID, receiverID int64, receiverName string) (int, bool) {
+	if karma.HasVote(chat.Id, messageID, giverID) {
+		return 0, false
+	}
+	if settings.CooldownSeconds > 0 {
+		key := karmaCooldownKey{chatID: chat.Id, giverID: giverID, receiverID: receiverID}
+		if !shouldUpdateKey(karmaCooldowns, key, time.Duration(settings.CooldownSeconds)*time.Second) {
+			return 0, false
+		}
+	}
+	points, given, err := karma.GiveVote(chat.Id, messageID, giverID, receiverID)
+	if err != nil || !given {
+		return 0, false
+	}
+	// Only approve on reaching the threshold, so an admin's /unapprove of a
+	// member who stays above it sticks.
+	if settings.ApproveAt > 0 && points == settings.ApproveAt {
+		approveByKarma(b, ctx, chat, settings, receiverID, receiverName, points)
+	}
+	return points, true
+}
+
+// approveByKarma approves a member who reached the chat's karma threshold
+// for the chosen scopes, unless they are an admin or already approved.
+// Settings without scopes karma may grant approve nobody.
+func approveByKarma(b *gotgbot.Bot, ctx *ext.Context, chat *gotgbot.Chat, settings *models.KarmaSettings, userID int64, name string, points int) {
+	if !karma.ApproveScopesAllowed(settings.ApproveScopes) {
+		return
+	}
+	if chat_status.IsUserAdmin(b, chat.Id, userID) || approvals.GetApproval(chat.Id, userID) != nil {
+		return
+	}
+	if err := approvals.SetApproval(chat.Id, userID, b.Id, "karma", settings.ApproveScopes, nil); err != nil {
+		log.Errorf("[Karma] Failed to approve user %d in chat %d: %v", userID, chat.Id, err)
+		return
+	}
+	tr := i18n.MustNewTranslator(lang.GetLanguage(ctx))
+	text, _ := tr.GetString("karma_approved", i18n.TranslationParams{
+		"user":  formatting.MentionHtml(userID, name),
+		"karma": points,
+	})
+	if _, err := b.SendMessage(chat.Id, text, &gotgbot.SendMessageOpts{ParseMode: formatting.HTML}); err != nil {
+		log.Warnf("[Karma] Failed to announce the approval of user %d in chat %d: %v", userID, chat.Id, err)
+	}
+}
+
+// karmaWatcher gives karma for replies that start with a karma trigger.
+func (moduleStruct) karmaWatcher(b *gotgbot.Bot, ctx *ext.Context) error {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Errorf("[Karma][karmaWatcher] Recovered from panic: %v", r)
+		}
+	}()
+
+	chat := ctx.EffectiveChat
+	msg := ctx.EffectiveMessage
+	if chat == nil || msg == nil || chat.Type == "private" || msg.From == nil || msg.SenderChat != nil {
+		return ext.ContinueGroups
+	}
+	// In forum topics, messages that reply to nothing reply to the message
+	// that created the topic.
+	reply := msg.ReplyToMessage
+	if reply == nil || reply.ForumTopicCreated != nil || reply.SenderChat != nil || reply.From == nil {
+		return ext.ContinueGroups
+	}
+	receiver := reply.From
+	if receiver.IsBot || receiver.Id == msg.From.Id || receiver.Id == 777000 {
+		return ext.ContinueGroups
+	}
+	settings := karma.GetKarmaSettings(chat.Id)
+	if !settings.Enabled || !karmaTrigger(settings, msg.Text) {
+		return ext.ContinueGroups
+	}
+
+	points, given := giveKarma(b, ctx, chat, settings, reply.MessageId, msg.From.Id, receiver.Id, receiver.FirstName)
+	if !given {
+		return ext.ContinueGroups
+	}
+	tr := i18n.MustNewTranslator(lang.GetLanguage(ctx))
+	text, _ := tr.GetString("karma_given", i18n.TranslationParams{
+		"user":  formatting.MentionHtml(receiver.Id, receiver.FirstName),
+		"karma": points,
+	})
+	if _, err := msg.Reply(b, text, formatting.Shtml()); err != nil {
+		log.Error(err)
+	}
+	return ext.ContinueGroups
+}
+
+// countKarmaReaction gives karma to the author of a message a member reacts
+// to with 👍, and takes it back when the 👍 is removed. Reaction karma is
+// given silently.
+func (moduleStruct) countKarmaReaction(b *gotgbot.Bot, ctx *ext.Context) error {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Errorf("[Karma][countKarmaReaction] Recovered from panic: %v", r)
+		}
+	}()
+
+	update := ctx.MessageReaction
+	chat := &update.Chat
+	if chat.Type == "private" || update.User == nil || update.User.IsBot {
+		return ext.ContinueGroups
+	}
+	thumbsUp := func(emoji models.ReactionEmoji) bool {
+		return sameReaction(emoji, models.ReactionEmoji{Emoji: karmaReaction})
+	}
+	// The vote remembers who got the point, so it can be taken back even
+	// after the author was forgotten or karma was turned off.
+	if slices.ContainsFunc(removedReactions(update), thumbsUp) {
+		_, _, _, _ = karma.TakeVote(chat.Id, update.MessageId, update.User.Id)
+		return ext.ContinueGroups
+	}
+	settings := karma.GetKarmaSettings(chat.Id)
+	if !settings.Enabled || !settings.Reactions {
+		return ext.ContinueGroups
+	}
+	if !slices.ContainsFunc(addedReactions(update), thumbsUp) {
+		return ext.ContinueGroups
+	}
+	author, ok := reactModAuthorOf(chat.Id, update.MessageId)
+	if !ok || author.Bot || author.SenderID < 0 || author.SenderID == update.User.Id || author.SenderID == 777000 {
+		return ext.ContinueGroups
+	}
+	giveKarma(b, ctx, chat, settings, update.MessageId, update.User.Id, author.SenderID, author.Name)
+	return ext.ContinueGroups
+}
+
+// karma handles /karma, which shows the karma of the replied-to or given
+// member, or of the sender.
+func (moduleStruct) karma(b *gotgbot.Bot, ctx *ext.Context) error {
+	msg := ctx.EffectiveMessage
+	chat := ctx.EffectiveChat
+	user := chat_status.RequireUser(b, ctx)
+	if user == nil {
+		return ext.EndGroups
+	}
+	if !chat_status.RequireGroup(b, ctx, chat) {
+		return ext.EndGroups
+	}
+	tr := i18n.MustNewTranslator(lang.GetLanguage(ctx))
+
+	var text string
+	if !karma.GetKarmaSettings(chat.Id).Enabled {
+		text, _ = tr.GetString("karma_off")
+	} else {
+		targetID, name := user.Id, user.FirstName
+		if len(ctx.Args()) > 1 || msg.ReplyToMessage != nil && msg.ReplyToMessage.ForumTopicCreated == nil {
+			targetID = extraction.ExtractUser(b, ctx)
+			if targetID == -1 {
+				return ext.EndGroups
+			}
+			if targetID == 0 {
+				text, _ = tr.GetString("common_no_user_specified")
+				_, err := msg.Reply(b, text, formatting.Shtml())
+				if err != nil {
+					log.Error(err)
+					return err
+				}
+				return ext.EndGroups
+			}
+			name = extractDisplayName(targetID)
+		}
+		text, _ = tr.GetString("karma_user", i18n.TranslationParams{
+			"user":  formatting.MentionHtml(targetID, name),
+			"karma": karma.GetKarma(chat.Id, targetID),
+		})
+	}
+	_, err := msg.Reply(b, text, formatting.Shtml())
+	if err != nil {
+		log.Error(err)
+		return err
+	}
+	return ext.EndGroups
+}
+
+// topKarma handles /topkarma, which lists the members with the most karma.
+func (moduleStruct) topKarma(b *gotgbot.Bot, ctx *ext.Context) error {
+	msg := ctx.EffectiveMessage
+	chat := ctx.EffectiveChat
+	if chat_status.RequireUser(b, ctx) == nil {
+		return ext.EndGroups
+	}
+	if !chat_status.RequireGroup(b, ctx, chat) {
+		return ext.EndGroups
+	}
+	tr := i18n.MustNewTranslator(lang.GetLanguage(ctx))
+
+	var text string
+	var top []models.KarmaUser
+	enabled := karma.GetKarmaSettings(chat.Id).Enabled
+	if enabled {
+		var err error
+		if top, err = karma.TopKarma(chat.Id, karmaTopLimit); err != nil {
+			return ext.EndGroups
+		}
+	}
+	switch {
+	case !enabled:
+		text, _ = tr.GetString("karma_off")
+	case len(top) == 0:
+		text, _ = tr.GetString("karma_top_empty")
+	default:
+		var sb strings.Builder
+		header, _ := tr.GetString("karma_top_header", i18n.TranslationParams{"chat": html.EscapeString(chat.Title)})
+		sb.WriteString(header)
+		for i, row := range top {
+			name := fmt.Sprintf("<code>%d</code>", row.UserId)
+			if _, fullName, found := user.GetUserInfoById(row.UserId); found && fullName != "" {
+				name = html.EscapeString(fullName)
+			}
+			fmt.Fprintf(&sb, "\n%d. %s — %d", i+1, name, row.Karma)
+		}
+		text = sb.String()
+	}
+	_, err := msg.Reply(b, text, formatting.Shtml())
+	if err != nil {
+		log.Error(err)
+		return err
+	}
+	return ext.EndGroups
+}
+
+// karmaStatus describes the karma settings of a chat.
+func karmaStatus(tr *i18n.Translator, settings *models.KarmaSettings) string {
+	onOff := func(on bool) string {
+		if on {
+			return trS(tr, "common_status_on")
+		}
+		return trS(tr, "common_status_off")
+	}
+	cooldown := onOff(false)
+	if settings.CooldownSeconds > 0 {
+		cooldown = formatDuration(settings.CooldownSeconds)
+	}
+	approve := onOff(false)
+	if settings.ApproveAt > 0 && karma.ApproveScopesAllowed(settings.ApproveScopes) {
+		scopes := strings.Join(settings.ApproveScopes, ", ")
+		approve, _ = tr.GetString("karma_status_approve", i18n.TranslationParams{"karma": settings.ApproveAt, "scopes": scopes})
+	}
+	triggers := make([]string, 0, len(karmaTriggers)+len(settings.Triggers))
+	for _, trigger := range slices.Concat(karmaTriggers, settings.Triggers) {
+		triggers = append(triggers, "<code>"+html.EscapeString(trigger)+"</code>")
+	}
+	text, _ := tr.GetString("karma_status", i18n.TranslationParams{
+		"enabled":   onOff(settings.Enabled),
+		"reactions": onOff(settings.Reactions),
+		"cooldown":  cooldown,
+		"triggers":  strings.Join(triggers, ", "),
+		"approve":   approve,
+	})
+	return text
+}
+
+// karmaConfig handles /karmaconfig, which shows and changes how karma works
+// in a chat.
+func (moduleStruct) karmaConfig(b *gotgbot.Bot, ctx *ext.Context) error {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Errorf("[Karma][karmaConfig] Recovered from panic: %v", r)
+		}
+	}()
+
+	msg := ctx.EffectiveMessage
+	chat := ctx.EffectiveChat
+	user := chat_status.RequireUser(b, ctx)
+	if user == nil {
+		return ext.EndGroups
+	}
+	if !chat_status.RequireGroup(b, ctx, chat) {
+		return ext.EndGroups
+	}
+	tr := i18n.MustNewTranslator(lang.GetLanguage(ctx))
+	reply := func(key string, params i18n.TranslationParams) error {
+		text, _ := tr.GetString(key, params)
+		_, err := msg.Reply(b, text, formatting.Shtml())
+		if err != nil {
+			log.Error(err)
+			return err
+		}
+		return ext.EndGroups
+	}
+	save := func(err error, key string, params i18n.TranslationParams) error {
+		if err != nil {
+			return reply("common_settings_save_failed", nil)
+		}
+		return reply(key, params)
+	}
+
+	settings := karma.GetKarmaSettings(chat.Id)
+	args := ctx.Args()[1:]
+	if len(args) == 0 {
+		_, err := msg.Reply(b, karmaStatus(tr, settings), formatting.Shtml())
+		if err != nil {
+			log.Error(err)
+			return err
+		}
+		return ext.EndGroups
+	}
+
+	if !chat_status.CanUserChangeInfo(b, ctx, chat, user.Id) {
+		chat_status.NewPermissionResponder(b).Respond(ctx, "chat_status_change_info_cmd_error", "chat_status_change_info_button_error")
+		return ext.EndGroups
+	}
+
+	switch strings.ToLower(args[0]) {
+	case "on", "off":
+		if len(args) != 1 {
+			return reply("karma_usage", nil)
+		}
+		enabled := strings.EqualFold(args[0], "on")
+		key := "karma_disabled"
+		if enabled {
+			key = "karma_enabled"
+		}
+		return save(karma.SetKarmaEnabled(chat.Id, enabled), key, nil)
+
+	case "reactions":
+		if len(args) != 2 || !slices.Contains([]string{"on", "off"}, strings.ToLower(args[1])) {
+			return reply("karma_usage", nil)
+		}
+		if strings.EqualFold(args[1], "on") {
+			return save(karma.SetKarmaReactions(chat.Id, true), "karma_reactions_on", i18n.TranslationParams{"emoji": karmaReaction})
+		}
+		return save(karma.SetKarmaReactions(chat.Id, false), "karma_reactions_off", i18n.TranslationParams{"emoji": karmaReaction})
+
+	case "cooldown":
+		if len(args) != 2 {
+			return reply("karma_usage", nil)
+		}
+		seconds, ok := 0, strings.EqualFold(args[1], "off") || args[1] == "0"
+		if !ok {
+			seconds, ok = parseDuration(args[1])
+		}
+		if !ok || seconds > karma.MaxCooldownSeconds {
+			return reply("karma_cooldown_invalid", i18n.TranslationParams{"max": formatDuration(karma.MaxCooldownSeconds)})
+		}
+		if seconds == 0 {
+			return save(karma.SetKarmaCooldown(chat.Id, 0), "karma_cooldown_off", nil)
+		}
+		return save(karma.SetKarmaCooldown(chat.Id, seconds), "karma_cooldown_set", i18n.TranslationParams{"cooldown": formatDuration(seconds)})
+
+	case "trigger":
+		if len(args) < 3 {
+			return reply("karma_usage", nil)
+		}
+		trigger := strings.ToLower(strings.Join(args[2:], " "))
+		params := i18n.TranslationParams{"trigger": html.EscapeString(trigger)}
+		switch strings.ToLower(args[1]) {
+		case "add":
+			if utf8.RuneCountInString(trigger) > karma.MaxTriggerLength {
+				return reply("karma_trigger_too_long", i18n.TranslationParams{"max": karma.MaxTriggerLength})
+			}
+			if slices.Contains(karmaTriggers, trigger) || slices.Contains(settings.Triggers, trigger) {
+				return reply("karma_trigger_exists", params)
+			}
+			if len(settings.Triggers) >= karma.MaxTriggers {
+				return reply("karma_triggers_full", i18n.TranslationParams{"max": karma.MaxTriggers})
+			}
+			return save(karma.SetKarmaTriggers(chat.Id, append(slices.Clone(settings.Triggers), trigger)), "karma_trigger_added", params)
+		case "remove":
+			index := slices.Index(settings.Triggers, trigger)
+			if index < 0 {
+				return reply("karma_trigger_not_found", params)
+			}
+			return save(karma.SetKarmaTriggers(chat.Id, slices.Delete(slices.Clone(settings.Triggers), index, index+1)), "karma_trigger_removed", params)
+		}
+		return reply("karma_usage", nil)
+
+	case "approve":
+		if len(args) < 2 || len(args) > 3 {
+			return reply("karma_usage", nil)
+		}
+		if strings.EqualFold(args[1], "off") && len(args) == 2 {
+			return save(karma.SetKarmaApproval(chat.Id, 0, nil), "karma_approve_off", nil)
+		}
+		approveAt, err := strconv.Atoi(args[1])
+		if err != nil || approveAt < 1 {
+			return reply("karma_approve_invalid", nil)
+		}
+		// Karma only approves for scopes the admins chose, and never for
+		// captcha or antiraid.
+		var scopes []string
+		if len(args) == 3 {
+			scopes, _ = approvals.ParseScopes(args[2])
+		}
+		if !karma.ApproveScopesAllowed(scopes) {
+			return reply("karma_approve_scopes", i18n.TranslationParams{"scopes": strings.Join(karma.ApproveScopes(), ", ")})
+		}
+		return save(karma.SetKarmaApproval(chat.Id, approveAt, scopes), "karma_approve_set", i18n.TranslationParams{"karma": approveAt, "scopes": strings.Join(scopes, ", ")})
+
+	case "reset":
+		if len(args) != 1 {
+			return reply("karma_usage", nil)
+		}
+		return save(karma.ResetKarma(chat.Id), "karma_reset", nil)
+	}
+	return reply("karma_usage", nil)
+}
+
+// LoadKarma registers the karma commands and the handlers that give karma
+// for thankful replies and 👍 reactions.
+func LoadKarma(dispatcher *ext.Dispatcher) {
+	DefaultHelpRegistry().AbleMap[karmaModule.moduleName] = true
+
+	helpers.AddCommand(dispatcher, "karma", karmaModule.karma)
+	helpers.AddCommand(dispatcher, "topkarma", karmaModule.topKarma)
+	helpers.AddCommand(dispatcher, "karmaconfig", karmaModule.karmaConfig)
+
+	dispatcher.AddHandlerToGroup(handlers.NewMessage(message.Text, karmaModule.karmaWatcher), karmaModule.handlerGroup)
+	dispatcher.AddHandlerToGroup(handlers.NewReaction(nil, karmaModule.countKarmaReaction), karmaModule.handlerGroup)
+}
+
+func init() {
+	RegisterLegacyModule("Karma", 320, LoadKarma)
+}
@@ -0,0 +1,246 @@
+package modules
+
+import (
+	"testing"
+
+	"github.com/PaulSonOfLars/gotgbot/v2"
+	"github.com/PaulSonOfLars/gotgbot/v2/ext"
+
+	"github.com/divkix/Alita_Robot/alita/db"
+	"github.com/divkix/Alita_Robot/alita/db/approvals"
+	"github.com/divkix/Alita_Robot/alita/db/cache"
+	"github.com/divkix/Alita_Robot/alita/db/karma"
+	"github.com/divkix/Alita_Robot/alita/db/models"
+)
+
+func cleanupKarmaChat(t *testing.T, chatID int64) {
+	t.Cleanup(func() {
+		_ = karma.SetKarmaEnabled(chatID, false)
+		_ = karma.ResetKarma(chatID)
+		_ = db.DB.Where("chat_id = ?", chatID).Delete(&models.KarmaSettings{}).Error
+		_ = approvals.RemoveAllApprovedUsers(chatID)
+	})
+}
+
+func TestStartsWithTrigger(t *testing.T) {
+	for text, want := range map[string]bool{
+		"thanks":                true,
+		"  Thanks! that worked": true,
+		"+1":                    true,
+		"+1 from me":            true,
+		"+10":                   false,
+		"thanksgiving":          false,
+		"no thanks":             false,
+		"":                      false,
+	} {
+		if got := startsWithTrigger(text, "thanks") || startsWithTrigger(text, "+1"); got != want {
+			t.Errorf("startsWithTrigger(%q) = %v, want %v", text, got, want)
+		}
+	}
+}
+
+func TestKarmaConfigChangesSettings(t *testing.T) {
+	client := newModuleBotClient()
+	bot := newModuleTestBot(client)
+	chat := gotgbot.Chat{Id: uniqueModuleChatID(), Type: "supergroup", Title: "Karma Chat"}
+	admin := gotgbot.User{Id: 777000, FirstName: "Telegram"}
+	member := gotgbot.User{Id: 42, FirstName: "Member"}
+	cleanupKarmaChat(t, chat.Id)
+
+	run := func(from gotgbot.User, text string) {
+		t.Helper()
+		if err := karmaModule.karmaConfig(bot, newModuleMessageContext(bot, chat, from, text)); err != ext.EndGroups {
+			t.Fatalf("karmaConfig(%q) error = %v, want EndGroups", text, err)
+		}
+	}
+
+	run(admin, "/karmaconfig on")
+	run(admin, "/karmaconfig reactions off")
+	run(admin, "/karmaconfig cooldown 30m")
+	run(admin, "/karmaconfig trigger add Gracias")
+	run(admin, "/karmaconfig trigger add merci beaucoup")
+	run(admin, "/karmaconfig approve 10 locks,media")
+	settings := karma.GetKarmaSettings(chat.Id)
+	if !settings.Enabled || settings.Reactions || settings.CooldownSeconds != 1800 ||
+		len(settings.Triggers) != 2 || settings.Triggers[0] != "gracias" || settings.Triggers[1] != "merci beaucoup" ||
+		settings.ApproveAt != 10 || len(settings.ApproveScopes) != 2 {
+		t.Fatalf("settings = %+v, want them as set", settings)
+	}
+
+	for _, text := range []string{
+		"/karmaconfig cooldown 9w",          // above the maximum
+		"/karmaconfig cooldown soon",        // not a duration
+		"/karmaconfig trigger add thanks",   // built in
+		"/karmaconfig trigger remove danke", // not a trigger
+		"/karmaconfig approve 0",
+		"/karmaconfig approve 5 everything",
+		"/karmaconfig approve 5",               // no scopes
+		"/karmaconfig approve 5 locks,captcha", // karma never approves for captcha
+		"/karmaconfig explode",
+	} {
+		run(admin, text)
+	}
+	run(member, "/karmaconfig off")
+	run(member, "/karmaconfig")
+	if got := karma.GetKarmaSettings(chat.Id); !got.Enabled || got.CooldownSeconds != 1800 || len(got.Triggers) != 2 || got.ApproveAt != 10 {
+		t.Fatalf("settings after invalid input = %+v, want them unchanged", got)
+	}
+
+	run(admin, "/karmaconfig trigger remove gracias")
+	run(admin, "/karmaconfig approve off")
+	run(admin, "/karmaconfig cooldown off")
+	settings = karma.GetKarmaSettings(chat.Id)
+	if len(settings.Triggers) != 1 || settings.ApproveAt != 0 || settings.CooldownSeconds != 0 {
+		t.Fatalf("settings = %+v, want one trigger, no approvals and no cooldown", settings)
+	}
+}
+
+func TestRepliesAndReactionsGiveKarma(t *testing.T) {
+	withMiniredis(t)
+
+	client := newModuleBotClient()
+	bot := newModuleTestBot(client)
+	dispatcher := ext.NewDispatcher(&ext.DispatcherOpts{MaxRoutines: -1})
+	LoadReactions(dispatcher)
+	LoadKarma(dispatcher)
+
+	chat := gotgbot.Chat{Id: uniqueModuleChatID(), Type: "supergroup", Title: "Karma Chat"}
+	cleanupKarmaChat(t, chat.Id)
+	if err := karma.SetKarmaEnabled(chat.Id, true); err != nil {
+		t.Fatalf("SetKarmaEnabled() error = %v", err)
+	}
+	if err := karma.SetKarmaApproval(chat.Id, 2, []string{approvals.ScopeLocks}); err != nil {
+		t.Fatalf("SetKarmaApproval() error = %v", err)
+	}
+
+	helped := &gotgbot.User{Id: 42, FirstName: "Member"}
+	robot := &gotgbot.User{Id: 4242, FirstName: "Robot", IsBot: true}
+	original := func(from *gotgbot.User) *gotgbot.Message {
+		return &gotgbot.Message{MessageId: 100, Date: 1, Chat: chat, From: from, Text: "try restarting it"}
+	}
+
+	updateID := int64(0)
+	process := func(update *gotgbot.Update) {
+		t.Helper()
+		updateID++
+		update.UpdateId = updateID
+		if err := dispatcher.ProcessUpdate(bot, update, nil); err != nil {
+			t.Fatalf("ProcessUpdate() error = %v", err)
+		}
+	}
+	reply := func(fromID int64, to *gotgbot.Message, text string) {
+		process(&gotgbot.Update{Message: &gotgbot.Message{
+			MessageId: 200 + updateID, Date: 1, Chat: chat, From: &gotgbot.User{Id: fromID, FirstName: "Giver"},
+			Text: text, ReplyToMessage: to,
+		}})
+	}
+	react := func(id, fromID int64, emoji string) {
+		process(&gotgbot.Update{MessageReaction: &gotgbot.MessageReactionUpdated{
+			Chat: chat, MessageId: id, User: &gotgbot.User{Id: fromID, FirstName: "Reactor"}, Date: 1,
+			NewReaction: []gotgbot.ReactionType{gotgbot.ReactionTypeEmoji{Emoji: emoji}},
+		}})
+	}
+	unreact := func(id, fromID int64, emoji string) {
+		process(&gotgbot.Update{MessageReaction: &gotgbot.MessageReactionUpdated{
+			Chat: chat, MessageId: id, User: &gotgbot.User{Id: fromID, FirstName: "Reactor"}, Date: 1,
+			OldReaction: []gotgbot.ReactionType{gotgbot.ReactionTypeEmoji{Emoji: emoji}},
+		}})
+	}
+
+	reply(43, original(helped), "Thanks! that worked")
+	if got := karma.GetKarma(chat.Id, 42); got != 1 {
+		t.Fatalf("karma = %d after a thanks, want 1", got)
+	}
+	if n := len(client.callsFor("sendMessage")); n != 1 {
+		t.Fatalf("sendMessage calls = %d, want the new karma announced", n)
+	}
+
+	reply(43, original(helped), "+1")            // cooling down
+	reply(44, original(helped), "thanksgiving?") // not a trigger
+	reply(42, original(helped), "thanks")        // self
+	reply(44, original(robot), "thanks")         // a bot
+	if got := karma.GetKarma(chat.Id, 42); got != 1 {
+		t.Fatalf("karma = %d, want still 1", got)
+	}
+	if approvals.GetApproval(chat.Id, 42) != nil {
+		t.Fatal("member approved below the threshold")
+	}
+
+	reply(44, original(helped), "thank you")
+	approval := approvals.GetApproval(chat.Id, 42)
+	if approval == nil || approval.ApprovedBy != bot.Id || len(approval.Scopes) != 1 || approval.Scopes[0] != approvals.ScopeLocks {
+		t.Fatalf("approval = %+v, want 42 approved by the bot for locks", approval)
+	}
+
+	// Reactions need the author, which the bot remembers as messages arrive.
+	process(&gotgbot.Update{Message: &gotgbot.Message{MessageId: 301, Date: 1, Chat: chat, From: helped, Text: "here is a fix"}})
+	react(301, 45, "🔥")
+	react(301, 42, "👍")
+	if got := karma.GetKarma(chat.Id, 42); got != 2 {
+		t.Fatalf("karma = %d after other and own reactions, want 2", got)
+	}
+	react(301, 45, "👍")
+	if got := karma.GetKarma(chat.Id, 42); got != 3 {
+		t.Fatalf("karma = %d after a 👍, want 3", got)
+	}
+
+	if err := karma.SetKarmaReactions(chat.Id, false); err != nil {
+		t.Fatalf("SetKarmaReactions() error = %v", err)
+	}
+	react(301, 46, "👍")
+	if got := karma.GetKarma(chat.Id, 42); got != 3 {
+		t.Fatalf("karma = %d with reactions off, want still 3", got)
+	}
+
+	// Karma is one vote per giver and message, and a removed 👍 takes its
+	// point back, so toggling it never adds up.
+	if err := karma.SetKarmaReactions(chat.Id, true); err != nil {
+		t.Fatalf("SetKarmaReactions() error = %v", err)
+	}
+	if err := karma.SetKarmaCooldown(chat.Id, 0); err != nil {
+		t.Fatalf("SetKarmaCooldown() error = %v", err)
+	}
+	for range 3 {
+		unreact(301, 45, "👍")
+		if got := karma.GetKarma(chat.Id, 42); got != 2 {
+			t.Fatalf("karma = %d after the 👍 was removed, want 2", got)
+		}
+		react(301, 45, "👍")
+		if got := karma.GetKarma(chat.Id, 42); got != 3 {
+			t.Fatalf("karma = %d after the 👍 came back, want 3", got)
+		}
+	}
+	reply(45, &gotgbot.Message{MessageId: 301, Date: 1, Chat: chat, From: helped, Text: "here is a fix"}, "+1")
+	reply(43, original(helped), "thanks")
+	if got := karma.GetKarma(chat.Id, 42); got != 3 {
+		t.Fatalf("karma = %d after thanking the same messages again, want still 3", got)
+	}
+}
+
+func TestKarmaNeverApprovesWithoutScopes(t *testing.T) {
+	client := newModuleBotClient()
+	bot := newModuleTestBot(client)
+	chat := gotgbot.Chat{Id: uniqueModuleChatID(), Type: "supergroup", Title: "Karma Chat"}
+	cleanupKarmaChat(t, chat.Id)
+
+	// Settings stored before scopes were required must not approve anyone.
+	if err := karma.SetKarmaEnabled(chat.Id, true); err != nil {
+		t.Fatalf("SetKarmaEnabled() error = %v", err)
+	}
+	if err := db.DB.Model(&models.KarmaSettings{}).Where("chat_id = ?", chat.Id).Update("approve_at", 1).Error; err != nil {
+		t.Fatalf("storing an old threshold failed: %v", err)
+	}
+	cache.DeleteCache(cache.CacheKey("karma_settings", chat.Id))
+
+	ctx := newModuleMessageContext(bot, chat, gotgbot.User{Id: 43, FirstName: "Giver"}, "thanks")
+	ctx.EffectiveMessage.ReplyToMessage = &gotgbot.Message{MessageId: 100, Date: 1, Chat: chat, From: &gotgbot.User{Id: 42, FirstName: "Member"}}
+	if err := karmaModule.karmaWatcher(bot, ctx); err != ext.ContinueGroups {
+		t.Fatalf("karmaWatcher() error = %v, want ContinueGroups", err)
+	}
+	if got := karma.GetKarma(chat.Id, 42); got != 1 {
+		t.Fatalf("karma = %d, want 1", got)
+	}
+	if approvals.GetApproval(chat.Id, 42) != nil {
+		t.Fatal("karma approved a member for every scope")
+	}
+}
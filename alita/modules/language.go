@@ -14,6 +14,7 @@ import (
 	"github.com/divkix/Alita_Robot/alita/i18n"
 	"github.com/divkix/Alita_Robot/alita/utils/chat_status"
 	"github.com/divkix/Alita_Robot/alita/utils/formatting"
+	"github.com/divkix/Alita_Robot/alita/utils/helpers"
 	"github.com/divkix/Alita_Robot/alita/utils/keyboard"
 )
 
@@ -194,7 +195,7 @@ func LoadLanguage(dispatcher *ext.Dispatcher) {
 	DefaultHelpRegistry().helpableKb[languagesModule.moduleName] = languagesModule.genFullLanguageKb()
 
 	dispatcher.AddHandler(handlers.NewCallback(callbackquery.Prefix("change_language"), languagesModule.langBtnHandler))
-	dispatcher.AddHandler(handlers.NewCommand("lang", languagesModule.changeLanguage))
+	helpers.AddCommand(dispatcher, "lang", languagesModule.changeLanguage)
 }
 
 func init() {
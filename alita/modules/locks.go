@@ -15,6 +15,7 @@ import (
 	"github.com/PaulSonOfLars/gotgbot/v2/ext"
 	"github.com/PaulSonOfLars/gotgbot/v2/ext/handlers/filters"
 
+	"github.com/divkix/Alita_Robot/alita/db/approvals"
 	"github.com/divkix/Alita_Robot/alita/db/lang"
 	"github.com/divkix/Alita_Robot/alita/db/locks"
 	"github.com/divkix/Alita_Robot/alita/utils/chat_status"
@@ -91,6 +92,30 @@ var (
 		"all":      message.All,
 	}
 
+	// mediaLockTypes are the locks bypassed by a media-scoped approval.
+	mediaLockTypes = map[string]bool{
+		"sticker":   true,
+		"audio":     true,
+		"voice":     true,
+		"document":  true,
+		"video":     true,
+		"videonote": true,
+		"photo":     true,
+		"gif":       true,
+		"media":     true,
+		"other":     true,
+	}
+
+	// senderLockTypes are the locks about who may post rather than what a
+	// message contains; lockedContent leaves them out.
+	senderLockTypes = map[string]bool{
+		"messages":    true,
+		"comments":    true,
+		"all":         true,
+		"bots":        true,
+		"anonchannel": true,
+	}
+
 	// Cached lock types - computed once and reused
 	cachedLockTypes     []string
 	cachedLockTypesOnce sync.Once
@@ -122,9 +147,9 @@ func (moduleStruct) getLockMapAsArray() []string {
 }
 
 // buildLockTypesMessage constructs a formatted string showing all locks
-// currently enabled in the specified chat.
-func (moduleStruct) buildLockTypesMessage(chatID int64) (res string) {
-	chatLocks := locks.GetChatLocks(chatID)
+// currently enabled in the specified chat, or in one of its forum topics.
+func (moduleStruct) buildLockTypesMessage(chatID, threadID int64) (res string) {
+	chatLocks := locks.GetTopicLocks(chatID, threadID)
 
 	newMapLocks := chatLocks
 	tr := i18n.MustNewTranslator(lang.GetLanguage(&ext.Context{EffectiveChat: &gotgbot.Chat{Id: chatID}}))
@@ -186,7 +211,7 @@ func (m moduleStruct) locks(b *gotgbot.Bot, ctx *ext.Context) error {
 	}
 	ctx.EffectiveChat = chat
 
-	_, err := msg.Reply(b, m.buildLockTypesMessage(chat.Id), formatting.Shtml())
+	_, err := msg.Reply(b, m.buildLockTypesMessage(chat.Id, messageTopic(msg)), formatting.Shtml())
 	if err != nil {
 		log.Error(err)
 		return err
@@ -253,10 +278,13 @@ func (m moduleStruct) lockPerm(b *gotgbot.Bot, ctx *ext.Context) error {
 		toLock = append(toLock, perm)
 	}
 
+	// Locks set in a forum topic only apply there
+	threadID := messageTopic(msg)
+
 	// Update locks synchronously to ensure success before sending confirmation
 	failedLocks := make([]string, 0, len(toLock))
 	for _, perm := range toLock {
-		if err := locks.UpdateLock(chat.Id, perm, true); err != nil {
+		if err := locks.UpdateTopicLock(chat.Id, threadID, perm, true); err != nil {
 			log.Warnf("[Locks] Failed to lock %s in chat %d: %v", perm, chat.Id, err)
 			failedLocks = append(failedLocks, perm)
 		}
@@ -275,7 +303,7 @@ func (m moduleStruct) lockPerm(b *gotgbot.Bot, ctx *ext.Context) error {
 	} else {
 		// All locks succeeded
 		temp, _ := tr.GetString("locks_locked_successfully")
-		text := fmt.Sprintf(temp, strings.Join(toLock, "\n - "))
+		text := fmt.Sprintf(temp, strings.Join(toLock, "\n - ")) + topicScopeNote(tr, threadID)
 		_, err := msg.Reply(b, text, formatting.Shtml())
 		if err != nil {
 			log.Error(err)
@@ -344,10 +372,13 @@ func (m moduleStruct) unlockPerm(b *gotgbot.Bot, ctx *ext.Context) error {
 		toUnlock = append(toUnlock, perm)
 	}
 
+	// Locks set in a forum topic only apply there
+	threadID := messageTopic(msg)
+
 	// Update locks synchronously to ensure success before sending confirmation
 	failedLocks := make([]string, 0, len(toUnlock))
 	for _, perm := range toUnlock {
-		if err := locks.UpdateLock(chat.Id, perm, false); err != nil {
+		if err := locks.UpdateTopicLock(chat.Id, threadID, perm, false); err != nil {
 			log.Warnf("[Locks] Failed to unlock %s in chat %d: %v", perm, chat.Id, err)
 			failedLocks = append(failedLocks, perm)
 		}
@@ -366,7 +397,7 @@ func (m moduleStruct) unlockPerm(b *gotgbot.Bot, ctx *ext.Context) error {
 	} else {
 		// All unlocks succeeded
 		temp, _ := tr.GetString("locks_unlocked_successfully")
-		text := fmt.Sprintf(temp, strings.Join(toUnlock, "\n - "))
+		text := fmt.Sprintf(temp, strings.Join(toUnlock, "\n - ")) + topicScopeNote(tr, threadID)
 		_, err := msg.Reply(b, text, formatting.Shtml())
 		if err != nil {
 			log.Error(err)
@@ -377,6 +408,22 @@ func (m moduleStruct) unlockPerm(b *gotgbot.Bot, ctx *ext.Context) error {
 	return ext.EndGroups
 }
 
+// lockedContent reports whether a message contains something the locks of
+// its chat or topic forbid, like a locked media type or a link, no matter
+// who sent it. Modules that pass messages on use it to leave such content
+// out.
+func lockedContent(chatID int64, msg *gotgbot.Message) bool {
+	chatLocks := locks.GetTopicLocks(chatID, messageTopic(msg))
+	for _, lockTypes := range []map[string]filters.Message{lockMap, restrMap} {
+		for name, filter := range lockTypes {
+			if chatLocks[name] && !senderLockTypes[name] && filter(msg) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
 // restHandler monitors messages and deletes them if they match
 // restricted content types that are locked in the chat.
 func (moduleStruct) restHandler(b *gotgbot.Bot, ctx *ext.Context) error {
@@ -389,8 +436,12 @@ func (moduleStruct) restHandler(b *gotgbot.Bot, ctx *ext.Context) error {
 		return ext.ContinueGroups
 	}
 
-	// Early exit: skip API call if no restriction-type locks are active for this chat.
-	chatLocks := locks.GetChatLocks(chat.Id)
+	// Get sender ID - works for both users and channels
+	senderID := sender.Id()
+
+	// Early exit: skip API call if no restriction-type locks are active for this
+	// chat, or for newcomers when the sender is one.
+	chatLocks := withNewcomerLocks(chat.Id, senderID, locks.GetTopicLocks(chat.Id, messageTopic(msg)))
 	hasActiveLock := false
 	for restrKey := range restrMap {
 		if chatLocks[restrKey] {
@@ -402,16 +453,14 @@ func (moduleStruct) restHandler(b *gotgbot.Bot, ctx *ext.Context) error {
 		return ext.ContinueGroups
 	}
 
-	// Get sender ID - works for both users and channels
-	senderID := sender.Id()
-
 	// Skip for admins and approved users (IsUserAdmin handles channel IDs safely)
 	if chat_status.IsUserAdmin(b, chat.Id, senderID) {
 		return ext.ContinueGroups
 	}
-	if senderID > 0 && chat_status.IsApproved(b, chat.Id, senderID) {
+	if senderID > 0 && chat_status.IsApprovedFor(b, chat.Id, senderID, approvals.ScopeLocks) {
 		return ext.ContinueGroups
 	}
+	mediaApproved := senderID > 0 && chat_status.IsApprovedFor(b, chat.Id, senderID, approvals.ScopeMedia)
 
 	// Check bot delete permission once before scanning restrictions
 	if !chat_status.CanBotDelete(b, ctx, nil) {
@@ -419,7 +468,10 @@ func (moduleStruct) restHandler(b *gotgbot.Bot, ctx *ext.Context) error {
 	}
 
 	for restr, filter := range restrMap {
-		if !filter(msg) || !locks.IsPermLocked(chat.Id, restr) {
+		if !filter(msg) || !chatLocks[restr] {
+			continue
+		}
+		if mediaApproved && mediaLockTypes[restr] {
 			continue
 		}
 
@@ -457,8 +509,12 @@ func (moduleStruct) permHandler(b *gotgbot.Bot, ctx *ext.Context) error {
 		return ext.ContinueGroups
 	}
 
-	// Early exit: skip API call if no permission-type locks are active for this chat.
-	chatLocks := locks.GetChatLocks(chat.Id)
+	// Get sender ID - works for both users and channels
+	senderID := sender.Id()
+
+	// Early exit: skip API call if no permission-type locks are active for this
+	// chat, or for newcomers when the sender is one.
+	chatLocks := withNewcomerLocks(chat.Id, senderID, locks.GetTopicLocks(chat.Id, messageTopic(msg)))
 	hasActiveLock := false
 	for permKey := range lockMap {
 		if chatLocks[permKey] {
@@ -470,16 +526,14 @@ func (moduleStruct) permHandler(b *gotgbot.Bot, ctx *ext.Context) error {
 		return ext.ContinueGroups
 	}
 
-	// Get sender ID - works for both users and channels
-	senderID := sender.Id()
-
 	// Skip for admins and approved users (IsUserAdmin handles channel IDs safely)
 	if chat_status.IsUserAdmin(b, chat.Id, senderID) {
 		return ext.ContinueGroups
 	}
-	if senderID > 0 && chat_status.IsApproved(b, chat.Id, senderID) {
+	if senderID > 0 && chat_status.IsApprovedFor(b, chat.Id, senderID, approvals.ScopeLocks) {
 		return ext.ContinueGroups
 	}
+	mediaApproved := senderID > 0 && chat_status.IsApprovedFor(b, chat.Id, senderID, approvals.ScopeMedia)
 
 	// Check bot delete permission once before scanning locks
 	if !chat_status.CanBotDelete(b, ctx, nil) {
@@ -487,7 +541,10 @@ func (moduleStruct) permHandler(b *gotgbot.Bot, ctx *ext.Context) error {
 	}
 
 	for perm, filter := range lockMap {
-		if !filter(msg) || !locks.IsPermLocked(chat.Id, perm) {
+		if !filter(msg) || !chatLocks[perm] {
+			continue
+		}
+		if mediaApproved && mediaLockTypes[perm] {
 			continue
 		}
 
@@ -526,7 +583,7 @@ func (moduleStruct) botLockHandler(b *gotgbot.Bot, ctx *ext.Context) error {
 	if senderID > 0 && chat_status.IsUserAdmin(b, chat.Id, senderID) {
 		return ext.ContinueGroups
 	}
-	if senderID > 0 && chat_status.IsApproved(b, chat.Id, senderID) {
+	if senderID > 0 && chat_status.IsApprovedFor(b, chat.Id, senderID, approvals.ScopeLocks) {
 		return ext.ContinueGroups
 	}
 
@@ -569,11 +626,11 @@ func (moduleStruct) botLockHandler(b *gotgbot.Bot, ctx *ext.Context) error {
 func LoadLocks(dispatcher *ext.Dispatcher) {
 	DefaultHelpRegistry().AbleMap[locksModule.moduleName] = true
 
-	dispatcher.AddHandler(handlers.NewCommand("lock", locksModule.lockPerm))
-	dispatcher.AddHandler(handlers.NewCommand("unlock", locksModule.unlockPerm))
-	dispatcher.AddHandler(handlers.NewCommand("locktypes", locksModule.locktypes))
+	helpers.AddCommand(dispatcher, "lock", locksModule.lockPerm)
+	helpers.AddCommand(dispatcher, "unlock", locksModule.unlockPerm)
+	helpers.AddCommand(dispatcher, "locktypes", locksModule.locktypes)
 	helpers.AddCmdToDisableable("locktypes")
-	dispatcher.AddHandler(handlers.NewCommand("locks", locksModule.locks))
+	helpers.AddCommand(dispatcher, "locks", locksModule.locks)
 	helpers.AddCmdToDisableable("locks")
 	dispatcher.AddHandlerToGroup(handlers.NewMessage(message.All, locksModule.permHandler), locksModule.permHandlerGroup)
 	dispatcher.AddHandlerToGroup(handlers.NewMessage(message.All, locksModule.restHandler), locksModule.restrHandlerGroup)
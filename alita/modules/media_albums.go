@@ -0,0 +1,151 @@
+package modules
+
+import (
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/PaulSonOfLars/gotgbot/v2"
+	"github.com/PaulSonOfLars/gotgbot/v2/ext"
+	"github.com/PaulSonOfLars/gotgbot/v2/ext/handlers"
+	"github.com/eko/gocache/lib/v4/store"
+
+	"github.com/divkix/Alita_Robot/alita/db"
+	"github.com/divkix/Alita_Robot/alita/utils/cache"
+	"github.com/divkix/Alita_Robot/alita/utils/content"
+	"github.com/divkix/Alita_Robot/alita/utils/formatting"
+)
+
+const (
+	// mediaAlbumHandlerGroup records album messages before any module can
+	// end the update's groups.
+	mediaAlbumHandlerGroup = -3
+	// mediaAlbumTTL is how long after it was sent an album can still be
+	// saved as a whole by replying to one of its messages.
+	mediaAlbumTTL = 48 * time.Hour
+	// mediaAlbumMaxItems is the most items Telegram allows in one album.
+	mediaAlbumMaxItems = 10
+)
+
+// mediaAlbumMu serialises the read-modify-write of album records, since the
+// messages of one album arrive as separate updates at nearly the same time.
+var mediaAlbumMu sync.Mutex
+
+// mediaAlbumMember is one recorded message of an album.
+type mediaAlbumMember struct {
+	MessageID int64        `json:"message_id"`
+	Item      db.MediaItem `json:"item"`
+}
+
+// mediaAlbum is the cache record of the messages seen for one media group.
+type mediaAlbum struct {
+	Members []mediaAlbumMember `json:"members"`
+}
+
+func mediaAlbumKey(chatID int64, groupID string) string {
+	return fmt.Sprintf("alita:media_album:%d:%s", chatID, groupID)
+}
+
+func getMediaAlbum(chatID int64, groupID string) *mediaAlbum {
+	album := &mediaAlbum{}
+	m := cache.GetMarshal()
+	if m == nil {
+		return album
+	}
+	if _, err := m.Get(cache.Context, mediaAlbumKey(chatID, groupID), album); err != nil {
+		return &mediaAlbum{}
+	}
+	return album
+}
+
+// recordAlbumMessage adds msg to the record of its media group.
+func recordAlbumMessage(msg *gotgbot.Message) {
+	item, ok := content.MediaItemFromMessage(msg)
+	if !ok {
+		return
+	}
+	m := cache.GetMarshal()
+	if m == nil {
+		return
+	}
+
+	mediaAlbumMu.Lock()
+	defer mediaAlbumMu.Unlock()
+	album := getMediaAlbum(msg.Chat.Id, msg.MediaGroupId)
+	if len(album.Members) >= mediaAlbumMaxItems || slices.ContainsFunc(album.Members, func(member mediaAlbumMember) bool {
+		return member.MessageID == msg.MessageId
+	}) {
+		return
+	}
+	album.Members = append(album.Members, mediaAlbumMember{MessageID: msg.MessageId, Item: item})
+	_ = m.Set(cache.Context, mediaAlbumKey(msg.Chat.Id, msg.MediaGroupId), album, store.WithExpiration(mediaAlbumTTL))
+}
+
+// albumMedia returns the items of the album replyMsg belongs to, in the order
+// they were sent, or nil if replyMsg is not part of an album the bot has seen
+// at least two messages of. The caption of replyMsg itself is left out, as it
+// becomes the text of the note or filter.
+func albumMedia(replyMsg *gotgbot.Message) []db.MediaItem {
+	if replyMsg == nil || replyMsg.MediaGroupId == "" {
+		return nil
+	}
+	album := getMediaAlbum(replyMsg.Chat.Id, replyMsg.MediaGroupId)
+	members := album.Members
+	if !slices.ContainsFunc(members, func(member mediaAlbumMember) bool {
+		return member.MessageID == replyMsg.MessageId
+	}) {
+		item, ok := content.MediaItemFromMessage(replyMsg)
+		if !ok {
+			return nil
+		}
+		members = append(members, mediaAlbumMember{MessageID: replyMsg.MessageId, Item: item})
+	}
+	if len(members) < 2 {
+		return nil
+	}
+	slices.SortFunc(members, func(a, b mediaAlbumMember) int {
+		return int(a.MessageID - b.MessageID)
+	})
+
+	items := make([]db.MediaItem, 0, len(members))
+	for _, member := range members {
+		item := member.Item
+		if member.MessageID == replyMsg.MessageId {
+			item.Caption = ""
+		}
+		items = append(items, item)
+	}
+	return items
+}
+
+// rawAlbumMedia returns a copy of items with their captions shown as the
+// markdown they were saved with, for /get <name> noformat.
+func rawAlbumMedia(items []db.MediaItem) []db.MediaItem {
+	if len(items) == 0 {
+		return nil
+	}
+	raw := make([]db.MediaItem, len(items))
+	for i, item := range items {
+		item.Caption = formatting.ReverseHTML2MD(item.Caption)
+		raw[i] = item
+	}
+	return raw
+}
+
+// albumRecorder remembers the messages of media groups so that replying to
+// any one of them with /save or /filter stores the whole album.
+func (moduleStruct) albumRecorder(_ *gotgbot.Bot, ctx *ext.Context) error {
+	if isAliasRedispatch(ctx) {
+		return ext.ContinueGroups
+	}
+	recordAlbumMessage(ctx.EffectiveMessage)
+	return ext.ContinueGroups
+}
+
+// loadMediaAlbums registers the album recorder shared by notes and filters.
+func loadMediaAlbums(dispatcher *ext.Dispatcher) {
+	dispatcher.AddHandlerToGroup(handlers.NewMessage(func(msg *gotgbot.Message) bool {
+		return msg.MediaGroupId != ""
+	}, notesModule.albumRecorder), mediaAlbumHandlerGroup)
+}
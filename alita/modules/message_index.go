@@ -0,0 +1,202 @@
+package modules
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/PaulSonOfLars/gotgbot/v2"
+	"github.com/PaulSonOfLars/gotgbot/v2/ext"
+	"github.com/redis/go-redis/v9"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/divkix/Alita_Robot/alita/utils/cache"
+)
+
+const (
+	// messageIndexWindow is how long messages stay in the index: the 48 hours
+	// in which bots may delete messages.
+	messageIndexWindow = 48 * time.Hour
+	// messageIndexChatMax and messageIndexSenderMax bound the rings, so a busy
+	// chat keeps its most recent messages only.
+	messageIndexChatMax   = 5000
+	messageIndexSenderMax = 1000
+	messageIndexKey       = "alita:msgindex" // format: msgindex:chat_id and msgindex:chat_id:sender_id (sorted sets)
+	// messageIndexHandlerGroup runs after the captcha and before anything
+	// that may end the groups, so every message is indexed.
+	messageIndexHandlerGroup = -6
+)
+
+// Kinds of indexed messages, stored as flags.
+const (
+	indexedViaBot = 1 << iota
+	indexedMedia
+)
+
+// indexedMessage is a message in the recent message index.
+type indexedMessage struct {
+	id       int64
+	senderID int64
+	flags    int
+}
+
+// indexMessageScript adds a message to the chat and sender rings, dropping
+// what fell out of the window or past their size.
+var indexMessageScript = redis.NewScript(`
+	for i = 1, 2 do
+		redis.call('ZADD', KEYS[i], ARGV[1], ARGV[i + 1])
+		redis.call('ZREMRANGEBYSCORE', KEYS[i], '-inf', '(' .. ARGV[4])
+		redis.call('ZREMRANGEBYRANK', KEYS[i], 0, -tonumber(ARGV[i + 4]) - 1)
+		redis.call('EXPIRE', KEYS[i], ARGV[7])
+	end
+	return 1
+`)
+
+func chatIndexKey(chatID int64) string {
+	return fmt.Sprintf("%s:%d", messageIndexKey, chatID)
+}
+
+func senderIndexKey(chatID, senderID int64) string {
+	return fmt.Sprintf("%s:%d:%d", messageIndexKey, chatID, senderID)
+}
+
+// chatIndexMember and senderIndexMember encode a message in the rings. The
+// sender ring keeps the flags too, so entries can be removed from both.
+func chatIndexMember(m indexedMessage) string {
+	return fmt.Sprintf("%d:%d:%d", m.id, m.senderID, m.flags)
+}
+
+func senderIndexMember(m indexedMessage) string {
+	return fmt.Sprintf("%d:%d", m.id, m.flags)
+}
+
+// isMediaMessage reports whether a message carries a photo, video, file,
+// sound or sticker.
+func isMediaMessage(msg *gotgbot.Message) bool {
+	return len(msg.Photo) > 0 || msg.Video != nil || msg.Animation != nil || msg.Document != nil ||
+		msg.Audio != nil || msg.Voice != nil || msg.VideoNote != nil || msg.Sticker != nil
+}
+
+// indexMessage records a message in the recent message index of its chat.
+func indexMessage(msg *gotgbot.Message) error {
+	rdb := cache.GetRedisClient()
+	if rdb == nil {
+		return fmt.Errorf("cache not initialized")
+	}
+	m := indexedMessage{id: msg.MessageId, senderID: msg.GetSender().Id()}
+	if msg.ViaBot != nil {
+		m.flags |= indexedViaBot
+	}
+	if isMediaMessage(msg) {
+		m.flags |= indexedMedia
+	}
+	date := msg.Date
+	if date == 0 {
+		date = time.Now().Unix()
+	}
+	cutoff := time.Now().Add(-messageIndexWindow).Unix()
+	return indexMessageScript.Run(cache.Context, rdb,
+		[]string{chatIndexKey(msg.Chat.Id), senderIndexKey(msg.Chat.Id, m.senderID)},
+		date, chatIndexMember(m), senderIndexMember(m), cutoff,
+		messageIndexChatMax, messageIndexSenderMax, int(messageIndexWindow.Seconds()),
+	).Err()
+}
+
+// recentMessagesFrom returns up to limit messages of a sender, newest first,
+// sent since the given unix time.
+func recentMessagesFrom(chatID, senderID int64, since int64, limit int) []indexedMessage {
+	members := readIndex(senderIndexKey(chatID, senderID), since, limit)
+	messages := make([]indexedMessage, 0, len(members))
+	for _, member := range members {
+		idText, flagsText, _ := strings.Cut(member, ":")
+		id, err := strconv.ParseInt(idText, 10, 64)
+		if err != nil {
+			continue
+		}
+		flags, _ := strconv.Atoi(flagsText)
+		messages = append(messages, indexedMessage{id: id, senderID: senderID, flags: flags})
+	}
+	return messages
+}
+
+// recentMessagesOfKind returns up to limit messages with the given flag,
+// newest first, sent since the given unix time.
+func recentMessagesOfKind(chatID int64, flag int, since int64, limit int) []indexedMessage {
+	var messages []indexedMessage
+	for _, member := range readIndex(chatIndexKey(chatID), since, 0) {
+		parts := strings.Split(member, ":")
+		if len(parts) != 3 {
+			continue
+		}
+		id, err1 := strconv.ParseInt(parts[0], 10, 64)
+		senderID, err2 := strconv.ParseInt(parts[1], 10, 64)
+		flags, err3 := strconv.Atoi(parts[2])
+		if err1 != nil || err2 != nil || err3 != nil || flags&flag == 0 {
+			continue
+		}
+		messages = append(messages, indexedMessage{id: id, senderID: senderID, flags: flags})
+		if len(messages) == limit {
+			break
+		}
+	}
+	return messages
+}
+
+// readIndex returns the members of a ring scored since the given unix time,
+// newest first. A limit of 0 returns them all.
+func readIndex(key string, since int64, limit int) []string {
+	rdb := cache.GetRedisClient()
+	if rdb == nil {
+		return nil
+	}
+	members, err := rdb.ZRevRangeByScore(cache.Context, key, &redis.ZRangeBy{
+		Min:   strconv.FormatInt(since, 10),
+		Max:   "+inf",
+		Count: int64(limit),
+	}).Result()
+	if err != nil {
+		log.WithError(err).Warnf("[Purges] Failed to read message index %s", key)
+		return nil
+	}
+	return members
+}
+
+// forgetMessages removes deleted messages from the index of a chat.
+func forgetMessages(chatID int64, messages []indexedMessage) {
+	rdb := cache.GetRedisClient()
+	if rdb == nil || len(messages) == 0 {
+		return
+	}
+	pipe := rdb.Pipeline()
+	for _, m := range messages {
+		pipe.ZRem(cache.Context, chatIndexKey(chatID), chatIndexMember(m))
+		pipe.ZRem(cache.Context, senderIndexKey(chatID, m.senderID), senderIndexMember(m))
+	}
+	if _, err := pipe.Exec(cache.Context); err != nil {
+		log.WithError(err).Warnf("[Purges] Failed to forget deleted messages in chat %d", chatID)
+	}
+}
+
+// messageIDs returns the IDs of indexed messages.
+func messageIDs(messages []indexedMessage) []int64 {
+	ids := make([]int64, len(messages))
+	for i, m := range messages {
+		ids[i] = m.id
+	}
+	return ids
+}
+
+// recordMessage indexes every group message for /purgeuser, /purgebots,
+// /purgemedia and /ban --history.
+func (moduleStruct) recordMessage(_ *gotgbot.Bot, ctx *ext.Context) error {
+	msg := ctx.EffectiveMessage
+	chat := ctx.EffectiveChat
+	if msg == nil || chat == nil || (chat.Type != "group" && chat.Type != "supergroup") || isAliasRedispatch(ctx) {
+		return ext.ContinueGroups
+	}
+	if err := indexMessage(msg); err != nil {
+		log.WithError(err).Debugf("[Purges] Failed to index message %d in chat %d", msg.MessageId, chat.Id)
+	}
+	return ext.ContinueGroups
+}
@@ -0,0 +1,144 @@
+package modules
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/PaulSonOfLars/gotgbot/v2"
+	"github.com/PaulSonOfLars/gotgbot/v2/ext"
+)
+
+func TestPurgeLimit(t *testing.T) {
+	windowStart := time.Now().Add(-messageIndexWindow).Unix()
+	for _, tc := range []struct {
+		arg       string
+		wantLimit int
+		wantOK    bool
+	}{
+		{"", maxPurgeMessages, true},
+		{"20", 20, true},
+		{"0", 0, false},
+		{"1001", 0, false},
+		{"6h", maxPurgeMessages, true},
+		{"48h", maxPurgeMessages, true},
+		{"3d", 0, false},
+		{"soon", 0, false},
+	} {
+		limit, since, ok := purgeLimit(tc.arg)
+		if limit != tc.wantLimit || ok != tc.wantOK {
+			t.Errorf("purgeLimit(%q) = %d, %v, want %d, %v", tc.arg, limit, ok, tc.wantLimit, tc.wantOK)
+		}
+		if ok && since < windowStart-1 {
+			t.Errorf("purgeLimit(%q) since = %d, before the index window", tc.arg, since)
+		}
+	}
+	if _, since, _ := purgeLimit("6h"); since < time.Now().Add(-6*time.Hour).Unix()-1 {
+		t.Errorf("purgeLimit(6h) since = %d, want the last 6 hours", since)
+	}
+}
+
+func TestPurgeIndexedMessages(t *testing.T) {
+	withMiniredis(t)
+
+	client := newModuleBotClient()
+	bot := newModuleTestBot(client)
+	dispatcher := ext.NewDispatcher(&ext.DispatcherOpts{MaxRoutines: -1})
+	LoadPurges(dispatcher)
+
+	chat := gotgbot.Chat{Id: uniqueModuleChatID(), Type: "supergroup", Title: "Purge Chat"}
+	now := time.Now().Unix()
+	spammer := gotgbot.User{Id: 4501, FirstName: "Spammer"}
+	member := gotgbot.User{Id: 4502, FirstName: "Member"}
+	helper := gotgbot.User{Id: 4503, FirstName: "Helper", IsBot: true}
+	admin := gotgbot.User{Id: 777000, FirstName: "Telegram"}
+
+	updateID := int64(0)
+	process := func(msg *gotgbot.Message) {
+		t.Helper()
+		updateID++
+		msg.Chat = chat
+		if err := dispatcher.ProcessUpdate(bot, &gotgbot.Update{UpdateId: updateID, Message: msg}, nil); err != nil {
+			t.Fatalf("ProcessUpdate() error = %v", err)
+		}
+	}
+	first := &gotgbot.Message{MessageId: 1, Date: now, From: &spammer, Text: "buy"}
+	process(first)
+	process(&gotgbot.Message{MessageId: 2, Date: now, From: &spammer, Text: "buy now"})
+	process(&gotgbot.Message{MessageId: 3, Date: now, From: &member, Photo: []gotgbot.PhotoSize{{FileId: "p"}}})
+	process(&gotgbot.Message{MessageId: 4, Date: now, From: &spammer, Sticker: &gotgbot.Sticker{FileId: "s"}})
+	process(&gotgbot.Message{MessageId: 5, Date: now, From: &helper, Text: "beep"})
+	process(&gotgbot.Message{MessageId: 6, Date: now, From: &member, ViaBot: &helper, Text: "inline"})
+	process(&gotgbot.Message{MessageId: 7, Date: now - int64(messageIndexWindow.Seconds()) - 60, From: &spammer, Text: "old"})
+
+	commandID := int64(100)
+	command := func(text string, reply *gotgbot.Message) []string {
+		t.Helper()
+		commandID++
+		from := len(client.deletedMessageIDs())
+		process(&gotgbot.Message{MessageId: commandID, Date: 1, From: &admin, Text: text, ReplyToMessage: reply})
+		return client.deletedMessageIDs()[from:]
+	}
+
+	if got := command("/purgeuser 1", first); fmt.Sprint(got) != "[4 101]" {
+		t.Fatalf("/purgeuser 1 deleted %v, want the newest message of the user and the command", got)
+	}
+	if got := command("/purgeuser", first); fmt.Sprint(got) != "[2 1 102]" {
+		t.Fatalf("/purgeuser deleted %v, want the rest of the user's recent messages", got)
+	}
+	if got := command("/purgemedia", nil); fmt.Sprint(got) != "[3 103]" {
+		t.Fatalf("/purgemedia deleted %v, want the photo left", got)
+	}
+	if got := command("/purgebots", nil); fmt.Sprint(got) != "[6 104]" {
+		t.Fatalf("/purgebots deleted %v, want the message sent via an inline bot", got)
+	}
+
+	sent := len(client.callsFor("sendMessage"))
+	if got := command("/purgebots", nil); len(got) != 0 {
+		t.Fatalf("/purgebots deleted %v with nothing left", got)
+	}
+	if got := command("/purgeuser 3d", first); len(got) != 0 {
+		t.Fatalf("/purgeuser 3d deleted %v, want it refused past the index window", got)
+	}
+	if got := len(client.callsFor("sendMessage")); got != sent+2 {
+		t.Fatalf("sendMessage calls = %d, want %d replies", got, sent+2)
+	}
+}
+
+func TestBanHistoryDeletesRecentMessages(t *testing.T) {
+	withMiniredis(t)
+
+	client := newModuleBotClient()
+	bot := newModuleTestBot(client)
+	chat := gotgbot.Chat{Id: uniqueModuleChatID(), Type: "supergroup", Title: "Ban Chat"}
+	now := time.Now().Unix()
+	spammer := gotgbot.User{Id: 4601, FirstName: "Spammer"}
+	member := gotgbot.User{Id: 4602, FirstName: "Member"}
+
+	var last *gotgbot.Message
+	for i, from := range []gotgbot.User{spammer, member, spammer} {
+		last = &gotgbot.Message{MessageId: int64(11 + i), Date: now, Chat: chat, From: &from, Text: "hi"}
+		if err := indexMessage(last); err != nil {
+			t.Fatalf("indexMessage() error = %v", err)
+		}
+	}
+
+	ctx := newModuleMessageContext(bot, chat, gotgbot.User{Id: 777000, FirstName: "Telegram"}, "/ban --history spam")
+	ctx.EffectiveMessage.ReplyToMessage = last
+	if err := bansModule.ban(bot, ctx); err != ext.EndGroups {
+		t.Fatalf("ban() error = %v, want EndGroups", err)
+	}
+
+	if bans := client.callsFor("banChatMember"); len(bans) != 1 || fmt.Sprint(bans[0].Params["user_id"]) != "4601" {
+		t.Fatalf("banChatMember calls = %+v, want the spammer banned", bans)
+	}
+	if got := client.deletedMessageIDs(); fmt.Sprint(got) != "[13 11]" {
+		t.Fatalf("deleted messages = %v, want the spammer's recent messages only", got)
+	}
+	if left := recentMessagesFrom(chat.Id, spammer.Id, 0, 0); len(left) != 0 {
+		t.Fatalf("index still has %d messages of the banned user", len(left))
+	}
+	if left := recentMessagesFrom(chat.Id, member.Id, 0, 0); len(left) != 1 {
+		t.Fatalf("index has %d messages of the member, want 1", len(left))
+	}
+}
@@ -23,7 +23,6 @@ import (
 
 	"github.com/PaulSonOfLars/gotgbot/v2"
 	"github.com/PaulSonOfLars/gotgbot/v2/ext"
-	"github.com/PaulSonOfLars/gotgbot/v2/ext/handlers"
 
 	"github.com/divkix/Alita_Robot/alita/utils/extraction"
 	"github.com/divkix/Alita_Robot/alita/utils/helpers"
@@ -519,18 +518,18 @@ func (moduleStruct) stat(b *gotgbot.Bot, ctx *ext.Context) error {
 func LoadMisc(dispatcher *ext.Dispatcher) {
 	DefaultHelpRegistry().AbleMap[miscModule.moduleName] = true
 
-	dispatcher.AddHandler(handlers.NewCommand("stat", miscModule.stat))
+	helpers.AddCommand(dispatcher, "stat", miscModule.stat)
 	helpers.AddCmdToDisableable("stat")
-	dispatcher.AddHandler(handlers.NewCommand("id", miscModule.getId))
+	helpers.AddCommand(dispatcher, "id", miscModule.getId)
 	helpers.AddCmdToDisableable("id")
-	dispatcher.AddHandler(handlers.NewCommand("tell", miscModule.echomsg))
-	dispatcher.AddHandler(handlers.NewCommand("ping", miscModule.ping))
+	helpers.AddCommand(dispatcher, "tell", miscModule.echomsg)
+	helpers.AddCommand(dispatcher, "ping", miscModule.ping)
 	helpers.AddCmdToDisableable("ping")
-	dispatcher.AddHandler(handlers.NewCommand("info", miscModule.info))
+	helpers.AddCommand(dispatcher, "info", miscModule.info)
 	helpers.AddCmdToDisableable("info")
-	dispatcher.AddHandler(handlers.NewCommand("tr", miscModule.translate))
+	helpers.AddCommand(dispatcher, "tr", miscModule.translate)
 	helpers.AddCmdToDisableable("tr")
-	dispatcher.AddHandler(handlers.NewCommand("removebotkeyboard", miscModule.removeBotKeyboard))
+	helpers.AddCommand(dispatcher, "removebotkeyboard", miscModule.removeBotKeyboard)
 }
 
 func init() {
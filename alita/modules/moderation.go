@@ -2,6 +2,9 @@ package modules
 
 import (
 	"fmt"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/PaulSonOfLars/gotgbot/v2"
 	"github.com/PaulSonOfLars/gotgbot/v2/ext"
@@ -59,9 +62,12 @@ func standardModGates(c *moderationCtx) bool {
 // deleteModGates extends standardModGates with delete permissions.
 // Used by purge-like commands.
 func deleteModGates(c *moderationCtx) bool {
-	if !standardModGates(c) {
-		return false
-	}
+	return standardModGates(c) && canDeleteGates(c)
+}
+
+// canDeleteGates checks that both the bot and the user may delete messages.
+// Used on its own by commands that only delete with a flag.
+func canDeleteGates(c *moderationCtx) bool {
 	if !chat_status.CanBotDelete(c.Bot, c.Ctx, nil) {
 		chat_status.NewPermissionResponder(c.Bot).Respond(c.Ctx, "chat_status_bot_delete_error", "", chat_status.WithReply())
 		return false
@@ -78,7 +84,52 @@ type target struct {
 	userID    int64
 	reason    string
 	timeVal   string // used by time-based commands (e.g., tban)
+	until     int64  // end of a timed ban or mute as a Unix time; 0 = permanent
 	isChannel bool   // true if original target was a channel ID
+	history   bool   // delete the target's recent messages too (--history)
+	deleted   int    // how many recent messages were deleted
+}
+
+// takeFlag removes a flag like --history from the reason text and reports
+// whether it was given.
+func takeFlag(reason, flag string) (string, bool) {
+	words := strings.Fields(reason)
+	found := false
+	kept := words[:0]
+	for _, word := range words {
+		if strings.EqualFold(word, flag) {
+			found = true
+			continue
+		}
+		kept = append(kept, word)
+	}
+	if !found {
+		return reason, false
+	}
+	return strings.Join(kept, " "), true
+}
+
+// deleteHistory deletes the indexed recent messages of the target, for
+// commands given --history.
+func deleteHistory(c *moderationCtx, t *target) {
+	since := time.Now().Add(-messageIndexWindow).Unix()
+	messages := recentMessagesFrom(c.Chat.Id, t.userID, since, messageIndexSenderMax)
+	if len(messages) == 0 {
+		return
+	}
+	deleteMessageIDs(c.Bot, c.Chat, messageIDs(messages))
+	forgetMessages(c.Chat.Id, messages)
+	t.deleted = len(messages)
+}
+
+// historyDeletedText returns the line telling how many recent messages
+// --history deleted, or "" when it was not given.
+func historyDeletedText(c *moderationCtx, t *target) string {
+	if !t.history {
+		return ""
+	}
+	text, _ := c.Tr.GetString("bans_history_deleted", i18n.TranslationParams{"count": strconv.Itoa(t.deleted)})
+	return "\n" + text
 }
 
 // extractFromArgs resolves the target from command arguments using ExtractUserAndText.
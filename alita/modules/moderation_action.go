@@ -0,0 +1,79 @@
+package modules
+
+import (
+	"fmt"
+
+	"github.com/PaulSonOfLars/gotgbot/v2"
+	"github.com/PaulSonOfLars/gotgbot/v2/ext"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/divkix/Alita_Robot/alita/db/lang"
+	"github.com/divkix/Alita_Robot/alita/i18n"
+	"github.com/divkix/Alita_Robot/alita/utils/formatting"
+)
+
+// applyModerationAction punishes the sender of a message that was already
+// deleted with one of the blacklist actions: mute, ban, kick, warn or none.
+// delete does nothing more than none but announces the deletion. Mutes, bans,
+// kicks and deletions are announced with the locale key noticePrefix followed
+// by "muted_user", "banned_user", "kicked_user" or "deleted_user", filled with
+// the mention of the sender and the reason; markup, if not nil, is attached
+// to the notice. Anonymous channels can only be banned.
+func applyModerationAction(b *gotgbot.Bot, ctx *ext.Context, action, reason, noticePrefix string, markup gotgbot.ReplyMarkup) error {
+	chat := ctx.EffectiveChat
+	user := ctx.EffectiveSender
+	tr := i18n.MustNewTranslator(lang.GetLanguage(ctx))
+
+	var (
+		noticeKey string
+		err       error
+	)
+	switch action {
+	case "mute":
+		if user.IsAnonymousChannel() {
+			return nil
+		}
+		_, err = b.RestrictChatMember(chat.Id, user.Id(), gotgbot.ChatPermissions{CanSendMessages: false}, nil)
+		noticeKey = "muted_user"
+	case "ban":
+		if user.IsAnonymousChannel() {
+			_, err = b.BanChatSenderChat(chat.Id, user.Id(), nil)
+		} else {
+			_, err = b.BanChatMember(chat.Id, user.Id(), nil)
+		}
+		noticeKey = "banned_user"
+	case "kick":
+		if user.IsAnonymousChannel() {
+			return nil
+		}
+		err = kickMember(b, chat.Id, user.Id())
+		noticeKey = "kicked_user"
+	case "delete":
+		noticeKey = "deleted_user"
+	case "warn":
+		if user.IsAnonymousChannel() {
+			return nil
+		}
+		err = warnsModule.warnThisUser(b, ctx, user.Id(), reason, "warn")
+	}
+	if err != nil {
+		log.Error(err)
+		return err
+	}
+	if noticeKey == "" {
+		// Warnings announce themselves, and "none" only deletes the message.
+		return nil
+	}
+
+	temp, _ := tr.GetString(noticePrefix + noticeKey)
+	opts := formatting.Shtml()
+	if markup != nil {
+		opts.ReplyMarkup = markup
+	}
+	_, err = b.SendMessage(chat.Id, fmt.Sprintf(temp, formatting.MentionHtml(user.Id(), user.Name()), reason), opts)
+	if err != nil {
+		log.Error(err)
+		return err
+	}
+	return nil
+}
@@ -5,7 +5,6 @@ import (
 
 	"github.com/PaulSonOfLars/gotgbot/v2"
 	"github.com/PaulSonOfLars/gotgbot/v2/ext"
-	"github.com/PaulSonOfLars/gotgbot/v2/ext/handlers"
 	log "github.com/sirupsen/logrus"
 
 	"github.com/divkix/Alita_Robot/alita/utils/chat_status"
@@ -16,6 +15,13 @@ import (
 
 var mutesModule = moduleStruct{moduleName: "Mutes"}
 
+// muteTarget mutes the target until t.until, or for good when it is 0. It is
+// the mute used by every mute command and by reaction moderation.
+func muteTarget(c *moderationCtx, t *target) error {
+	_, err := c.Chat.RestrictMember(c.Bot, t.userID, MutedPermissions, &gotgbot.RestrictChatMemberOpts{UntilDate: t.until})
+	return err
+}
+
 // muteTargetValidation validates the target for mute commands.
 // Checks: user is in chat, not ban-protected, not the bot itself.
 func muteTargetValidation(c *moderationCtx, t *target) error {
@@ -136,10 +142,8 @@ func moderationTmute(m *moduleStruct) *moderationCommand {
 			}
 			t.timeVal = timeVal
 			t.reason = reason
-			_, err := c.Chat.RestrictMember(c.Bot, t.userID, MutedPermissions,
-				&gotgbot.RestrictChatMemberOpts{UntilDate: _time},
-			)
-			return err
+			t.until = _time
+			return muteTarget(c, t)
 		},
 		reply: func(c *moderationCtx, t *target) error {
 			muteUser, err := c.Bot.GetChat(t.userID, nil)
@@ -172,11 +176,8 @@ func moderationMute(m *moduleStruct) *moderationCommand {
 		gates:    []gateFn{standardModGates},
 		extract:  extractFromArgs,
 		validate: muteTargetValidation,
-		execute: func(c *moderationCtx, t *target) error {
-			_, err := c.Chat.RestrictMember(c.Bot, t.userID, MutedPermissions, nil)
-			return err
-		},
-		reply: muteReplyWithButton,
+		execute:  muteTarget,
+		reply:    muteReplyWithButton,
 	}
 }
 
@@ -187,10 +188,7 @@ func moderationSmute(m *moduleStruct) *moderationCommand {
 		gates:    []gateFn{deleteModGates},
 		extract:  extractUserOnly,
 		validate: muteTargetValidation,
-		execute: func(c *moderationCtx, t *target) error {
-			_, err := c.Chat.RestrictMember(c.Bot, t.userID, MutedPermissions, nil)
-			return err
-		},
+		execute:  muteTarget,
 		reply: func(c *moderationCtx, t *target) error {
 			_ = helpers.DeleteMessageWithErrorHandling(c.Bot, c.Chat.Id, c.Msg.MessageId)
 			return nil
@@ -220,13 +218,12 @@ func moderationDmute(m *moduleStruct) *moderationCommand {
 			return muteTargetValidation(c, t)
 		},
 		execute: func(c *moderationCtx, t *target) error {
-			_, err := c.Msg.ReplyToMessage.Delete(c.Bot, nil)
+			err := helpers.DeleteMessagesBatched(c.Bot, c.Chat.Id, []int64{c.Msg.ReplyToMessage.MessageId}, nil)
 			if err != nil {
 				log.Error(err)
 				return err
 			}
-			_, err = c.Chat.RestrictMember(c.Bot, t.userID, MutedPermissions, nil)
-			return err
+			return muteTarget(c, t)
 		},
 		reply: muteReplyWithButton,
 	}
@@ -328,11 +325,11 @@ func (m moduleStruct) unmute(b *gotgbot.Bot, ctx *ext.Context) error {
 func LoadMutes(dispatcher *ext.Dispatcher) {
 	DefaultHelpRegistry().AbleMap[mutesModule.moduleName] = true
 
-	dispatcher.AddHandler(handlers.NewCommand("mute", mutesModule.mute))
-	dispatcher.AddHandler(handlers.NewCommand("smute", mutesModule.sMute))
-	dispatcher.AddHandler(handlers.NewCommand("tmute", mutesModule.tMute))
-	dispatcher.AddHandler(handlers.NewCommand("dmute", mutesModule.dMute))
-	dispatcher.AddHandler(handlers.NewCommand("unmute", mutesModule.unmute))
+	helpers.AddCommand(dispatcher, "mute", mutesModule.mute)
+	helpers.AddCommand(dispatcher, "smute", mutesModule.sMute)
+	helpers.AddCommand(dispatcher, "tmute", mutesModule.tMute)
+	helpers.AddCommand(dispatcher, "dmute", mutesModule.dMute)
+	helpers.AddCommand(dispatcher, "unmute", mutesModule.unmute)
 }
 
 func init() {
@@ -2,6 +2,7 @@ package modules
 
 import (
 	"fmt"
+	"html"
 	"slices"
 	"strconv"
 	"strings"
@@ -109,6 +110,12 @@ func (m moduleStruct) addNote(b *gotgbot.Bot, ctx *ext.Context) error {
 		return ext.EndGroups
 	}
 
+	// A reply to any message of an album saves the whole album.
+	media := albumMedia(msg.ReplyToMessage)
+	if len(media) > 0 {
+		fileid, dataType = media[0].FileID, media[0].MsgType
+	}
+
 	// if user specifies both noprivate and private, the note will be sent to default.
 	// If privatenotes is enabled, the private else group
 	if grpOnly && pvtOnly {
@@ -118,6 +125,16 @@ func (m moduleStruct) addNote(b *gotgbot.Bot, ctx *ext.Context) error {
 	}
 
 	noteWord = strings.ToLower(noteWord)
+	category, noteWord, ok := notes.SplitNoteCategory(noteWord)
+	if !ok {
+		text, _ := tr.GetString("notes_invalid_category")
+		_, err := msg.Reply(b, text, formatting.Shtml())
+		if err != nil {
+			log.Error(err)
+			return err
+		}
+		return ext.EndGroups
+	}
 
 	// check if note already exists or not
 	if notes.DoesNoteExists(chat.Id, noteWord) {
@@ -137,8 +154,10 @@ func (m moduleStruct) addNote(b *gotgbot.Bot, ctx *ext.Context) error {
 				Text:     text,
 				FileID:   fileid,
 				Buttons:  buttons,
+				Media:    media,
 				DataType: dataType,
 			},
+			Category:    category,
 			PvtOnly:     pvtOnly,
 			GrpOnly:     grpOnly,
 			AdminOnly:   adminOnly,
@@ -190,7 +209,7 @@ func (m moduleStruct) addNote(b *gotgbot.Bot, ctx *ext.Context) error {
 	}
 
 	// Fix Issue 1: Remove go keyword and handle error synchronously
-	if err := notes.AddNote(chat.Id, noteWord, text, fileid, buttons, dataType, pvtOnly, grpOnly, adminOnly, webPrev, isProtected, noNotif); err != nil {
+	if err := notes.AddNote(chat.Id, noteWord, category, text, fileid, buttons, media, dataType, pvtOnly, grpOnly, adminOnly, webPrev, isProtected, noNotif); err != nil {
 		log.Errorf("[Notes] Failed to add note %s in chat %d: %v", noteWord, chat.Id, err)
 		tr := i18n.MustNewTranslator(lang.GetLanguage(ctx))
 		errorText, _ := tr.GetString("notes_save_failed")
@@ -202,7 +221,12 @@ func (m moduleStruct) addNote(b *gotgbot.Bot, ctx *ext.Context) error {
 		return ext.EndGroups
 	}
 
-	_, err := msg.Reply(b, fmt.Sprintf(noteString, noteWord, noteWord, noteWord), formatting.Shtml())
+	savedText := fmt.Sprintf(noteString, noteWord, noteWord, noteWord)
+	if category != "" {
+		categoryText, _ := tr.GetString("notes_saved_in_category", i18n.TranslationParams{"category": html.EscapeString(category)})
+		savedText += categoryText
+	}
+	_, err := msg.Reply(b, savedText, formatting.Shtml())
 	if err != nil {
 		log.Error(err)
 		return err
@@ -266,7 +290,7 @@ func (moduleStruct) rmNote(b *gotgbot.Bot, ctx *ext.Context) error {
 	noteWord, _ = extraction.ExtractQuotes(noteWord, false, true)
 
 	// Fix Issue 2: Add error handling for RemoveNote
-	if err := notes.RemoveNote(chat.Id, strings.ToLower(noteWord)); err != nil {
+	if err := notes.RemoveNote(chat.Id, user.Id, strings.ToLower(noteWord)); err != nil {
 		log.Errorf("[Notes] Failed to remove note %s in chat %d: %v", noteWord, chat.Id, err)
 		tr := i18n.MustNewTranslator(lang.GetLanguage(ctx))
 		errorText, _ := tr.GetString("error_generic")
@@ -362,7 +386,11 @@ func (moduleStruct) notesList(b *gotgbot.Bot, ctx *ext.Context) error {
 		return ext.EndGroups
 	}
 
-	noteKeys := notes.GetNotesList(chat.Id, chat_status.RequireUserAdmin(b, ctx, nil, user.Id))
+	if args := ctx.Args(); len(args) > 1 && strings.ToLower(args[1]) == "search" {
+		return notesModule.searchNotes(b, ctx, chat, user, strings.Join(args[2:], " "))
+	}
+
+	noteKeys := notes.GetNoteIndex(chat.Id, chat_status.RequireUserAdmin(b, ctx, nil, user.Id))
 	tr := i18n.MustNewTranslator(lang.GetLanguage(ctx))
 	info, _ := tr.GetString("notes_none_in_chat")
 
@@ -380,21 +408,7 @@ func (moduleStruct) notesList(b *gotgbot.Bot, ctx *ext.Context) error {
 	if ctx.Message.Chat.Type == "private" {
 		// check if want admin notes or not
 		admin := chat_status.IsUserAdmin(b, chat.Id, user.Id)
-		noteKeys := notes.GetNotesList(chat.Id, admin)
-		listText, _ := tr.GetString("notes_list_for_chat")
-		info = fmt.Sprintf(listText, chat.Title)
-		var sb strings.Builder
-		for _, note := range noteKeys {
-			fmt.Fprintf(&sb, "\n - <a href='https://t.me/%s?start=note_%d_%s'>%s</a>",
-				b.Username, chat.Id, note, note)
-		}
-		info += sb.String()
-		_, err := msg.Reply(b, info, formatting.Shtml())
-		if err != nil {
-			log.Error(err)
-			return err
-		}
-		return ext.EndGroups
+		return sendNotesPage(b, msg, tr, chat, notes.GetNoteIndex(chat.Id, admin), true)
 	}
 
 	privNote := notes.GetNotes(chat.Id).PrivateNotesEnabled()
@@ -422,23 +436,23 @@ func (moduleStruct) notesList(b *gotgbot.Bot, ctx *ext.Context) error {
 			log.Error(err)
 			return err
 		}
-	} else {
-		currentNotesText, _ := tr.GetString("notes_current_in_chat")
-		info = currentNotesText
-		var sb strings.Builder
-		for _, note := range noteKeys {
-			fmt.Fprintf(&sb, " - <code>#%s</code>\n", note)
-		}
-		info += sb.String()
-		instructionText, _ := tr.GetString("notes_get_instruction")
-		info += instructionText
-		_, err := msg.Reply(b, info, formatting.Shtml())
-		if err != nil {
-			log.Error(err)
-			return err
-		}
+		return ext.EndGroups
 	}
 
+	return sendNotesPage(b, msg, tr, chat, noteKeys, false)
+}
+
+// sendNotesPage replies to msg with the first page of the notes browser.
+func sendNotesPage(b *gotgbot.Bot, msg *gotgbot.Message, tr *i18n.Translator, chat *gotgbot.Chat, entries []notes.NoteIndexEntry, private bool) error {
+	text, keyboard := notesPage(b, tr, chat, entries, 0, private)
+	opts := formatting.Shtml()
+	if keyboard != nil {
+		opts.ReplyMarkup = *keyboard
+	}
+	if _, err := msg.Reply(b, text, opts); err != nil {
+		log.Error(err)
+		return err
+	}
 	return ext.EndGroups
 }
 
@@ -588,10 +602,13 @@ func (m moduleStruct) noteOverWriteHandler(b *gotgbot.Bot, ctx *ext.Context) err
 
 		updated, err := notes.UpdateNote(
 			chatId,
+			user.Id,
 			noteData.ItemName,
+			noteData.Category,
 			noteData.Text,
 			noteData.FileID,
 			noteData.Buttons,
+			noteData.Media,
 			noteData.DataType,
 			noteData.PvtOnly,
 			noteData.GrpOnly,
@@ -672,7 +689,7 @@ func (moduleStruct) notesButtonHandler(b *gotgbot.Bot, ctx *ext.Context) error {
 		_, _ = query.Answer(b, &gotgbot.AnswerCallbackQueryOpts{Text: text})
 		return ext.EndGroups
 	}
-	var helpText string
+	var helpText, undoHint string
 
 	tr := i18n.MustNewTranslator(lang.GetLanguage(ctx))
 	chat := ctx.EffectiveChat
@@ -683,11 +700,12 @@ func (moduleStruct) notesButtonHandler(b *gotgbot.Bot, ctx *ext.Context) error {
 			helpText, _ = tr.GetString("error_generic")
 			break
 		}
-		if err := notes.RemoveAllNotes(chat.Id); err != nil {
+		if err := notes.RemoveAllNotes(chat.Id, user.Id); err != nil {
 			log.Errorf("[Notes] Failed to remove all notes: %v", err)
 			helpText, _ = tr.GetString("error_generic")
 		} else {
 			helpText, _ = tr.GetString("notes_clear_all_success")
+			undoHint, _ = tr.GetString("revisions_clearall_hint", i18n.TranslationParams{"command": "noterestore"})
 		}
 	case "no":
 		helpText, _ = tr.GetString("notes_clear_all_cancelled")
@@ -707,7 +725,7 @@ func (moduleStruct) notesButtonHandler(b *gotgbot.Bot, ctx *ext.Context) error {
 
 	_, _, err := query.Message.EditText(
 		b,
-		helpText,
+		helpText+undoHint,
 		nil,
 	)
 	if err != nil {
@@ -989,6 +1007,7 @@ func (moduleStruct) sendNoFormatNote(b *gotgbot.Bot, ctx *ext.Context, replyMsgI
 		FileID:  noteData.FileID,
 		MsgType: noteData.MsgType,
 		Name:    noteData.NoteName,
+		Media:   rawAlbumMedia(noteData.Media),
 	}, media.Options{
 		ChatID:            ctx.Message.Chat.Id,
 		ReplyMsgID:        replyMsgId,
@@ -1021,17 +1040,21 @@ func LoadNotes(dispatcher *ext.Dispatcher) {
 			},
 		},
 	} // Adds Formatting kb button to Notes Menu
-	dispatcher.AddHandler(handlers.NewCommand("save", notesModule.addNote))
-	dispatcher.AddHandler(handlers.NewCommand("addnote", notesModule.addNote))
-	dispatcher.AddHandler(handlers.NewCommand("clear", notesModule.rmNote))
-	dispatcher.AddHandler(handlers.NewCommand("rmnote", notesModule.rmNote))
-	dispatcher.AddHandler(handlers.NewCommand("notes", notesModule.notesList))
+	helpers.AddCommand(dispatcher, "save", notesModule.addNote)
+	helpers.AddCommand(dispatcher, "addnote", notesModule.addNote)
+	helpers.AddCommand(dispatcher, "clear", notesModule.rmNote)
+	helpers.AddCommand(dispatcher, "rmnote", notesModule.rmNote)
+	helpers.AddCommand(dispatcher, "notes", notesModule.notesList)
 	// Alias: /saved should behave like /notes per documentation
-	dispatcher.AddHandler(handlers.NewCommand("saved", notesModule.notesList))
+	helpers.AddCommand(dispatcher, "saved", notesModule.notesList)
 	helpers.AddCmdToDisableable("notes")
-	dispatcher.AddHandler(handlers.NewCommand("clearall", notesModule.rmAllNotes))
+	helpers.AddCommand(dispatcher, "clearall", notesModule.rmAllNotes)
 	dispatcher.AddHandler(handlers.NewCallback(callbackquery.Prefix("rmAllNotes"), notesModule.notesButtonHandler))
 	dispatcher.AddHandler(handlers.NewCallback(callbackquery.Prefix("notes.overwrite"), notesModule.noteOverWriteHandler))
+	dispatcher.AddHandler(handlers.NewCallback(callbackquery.Prefix("notes.page"), notesModule.notesPageHandler))
+	helpers.AddCommand(dispatcher, "notehistory", notesModule.revisionHistory(noteRevisionKind))
+	helpers.AddCommand(dispatcher, "noterestore", notesModule.revisionRestore(noteRevisionKind))
+	dispatcher.AddHandler(handlers.NewCallback(callbackquery.Prefix(noteRevisionKind.namespace), notesModule.revisionCallback(noteRevisionKind)))
 	dispatcher.AddHandler(
 		handlers.NewMessage(
 			func(msg *gotgbot.Message) bool {
@@ -1041,8 +1064,9 @@ func LoadNotes(dispatcher *ext.Dispatcher) {
 		),
 	)
 	helpers.MultiCommand(dispatcher, []string{"privnote", "privatenotes"}, notesModule.privNote)
-	dispatcher.AddHandler(handlers.NewCommand("get", notesModule.getNotes))
+	helpers.AddCommand(dispatcher, "get", notesModule.getNotes)
 	helpers.AddCmdToDisableable("get")
+	loadMediaAlbums(dispatcher)
 }
 
 func init() {
@@ -0,0 +1,209 @@
+package modules
+
+import (
+	"fmt"
+	"html"
+	"strconv"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/PaulSonOfLars/gotgbot/v2"
+	"github.com/PaulSonOfLars/gotgbot/v2/ext"
+
+	"github.com/divkix/Alita_Robot/alita/db/connections"
+	"github.com/divkix/Alita_Robot/alita/db/lang"
+	"github.com/divkix/Alita_Robot/alita/db/notes"
+	"github.com/divkix/Alita_Robot/alita/i18n"
+	"github.com/divkix/Alita_Robot/alita/utils/chat_status"
+	"github.com/divkix/Alita_Robot/alita/utils/formatting"
+)
+
+// notesPageSize is how many notes one page of the /notes browser lists.
+const notesPageSize = 25
+
+// notesSearchLimit caps how many matches /notes search lists.
+const notesSearchLimit = 20
+
+// noteListLine renders one note of a notes list. Deep links fetch the note
+// in the user's private chat with the bot; otherwise the #name hint is shown.
+func noteListLine(b *gotgbot.Bot, chatID int64, name string, deepLink bool) string {
+	if deepLink {
+		return fmt.Sprintf(" - <a href='https://t.me/%s?start=note_%d_%s'>%s</a>", b.Username, chatID, name, name)
+	}
+	return fmt.Sprintf(" - <code>#%s</code>", name)
+}
+
+// notesPage renders one page of the category-grouped notes list of chat.
+// Notes are listed as deep links when private is set, as /notes in a private
+// chat does. The returned keyboard pages through the list and is nil when
+// the list fits on one page.
+func notesPage(b *gotgbot.Bot, tr *i18n.Translator, chat *gotgbot.Chat, entries []notes.NoteIndexEntry, page int, private bool) (string, *gotgbot.InlineKeyboardMarkup) {
+	pages := max((len(entries)+notesPageSize-1)/notesPageSize, 1)
+	page = min(max(page, 0), pages-1)
+	pageEntries := entries[page*notesPageSize : min((page+1)*notesPageSize, len(entries))]
+
+	lines := make([]string, 0, len(pageEntries))
+	category := ""
+	for i, entry := range pageEntries {
+		// Headers are repeated at the top of a page that continues a category.
+		if entry.Category != "" && (i == 0 || entry.Category != category) {
+			if i > 0 {
+				lines = append(lines, "")
+			}
+			lines = append(lines, fmt.Sprintf("<b>%s</b>", html.EscapeString(entry.Category)))
+		}
+		category = entry.Category
+		lines = append(lines, noteListLine(b, chat.Id, entry.Name, private))
+	}
+
+	var text string
+	if private {
+		listText, _ := tr.GetString("notes_list_for_chat")
+		text = fmt.Sprintf(listText, html.EscapeString(chat.Title)) + "\n" + strings.Join(lines, "\n")
+	} else {
+		currentNotesText, _ := tr.GetString("notes_current_in_chat")
+		instructionText, _ := tr.GetString("notes_get_instruction")
+		text = currentNotesText + strings.Join(lines, "\n") + "\n" + instructionText
+	}
+	if pages == 1 {
+		return text, nil
+	}
+
+	pageText, _ := tr.GetString("notes_page_indicator", i18n.TranslationParams{"page": page + 1, "pages": pages})
+	text += "\n\n" + pageText
+
+	pageButton := func(key string, target int) gotgbot.InlineKeyboardButton {
+		label, _ := tr.GetString(key)
+		return gotgbot.InlineKeyboardButton{
+			Text: label,
+			CallbackData: encodeCallbackData("notes.page", map[string]string{
+				"c": strconv.FormatInt(chat.Id, 10),
+				"p": strconv.Itoa(target),
+			}),
+		}
+	}
+	row := make([]gotgbot.InlineKeyboardButton, 0, 2)
+	if page > 0 {
+		row = append(row, pageButton("notes_page_prev", page-1))
+	}
+	if page < pages-1 {
+		row = append(row, pageButton("notes_page_next", page+1))
+	}
+	return text, &gotgbot.InlineKeyboardMarkup{InlineKeyboard: [][]gotgbot.InlineKeyboardButton{row}}
+}
+
+// notesPageHandler processes callback queries from the /notes browser
+// keyboard and shows the requested page to the user who clicked.
+// Callback format:
+// - v1 codec: notes.page|v1|c={chatID}&p={page}
+func (moduleStruct) notesPageHandler(b *gotgbot.Bot, ctx *ext.Context) error {
+	query, ok := callbackQueryFromContext(ctx)
+	if !ok {
+		return ext.EndGroups
+	}
+	user := query.From
+	tr := i18n.MustNewTranslator(lang.GetLanguage(ctx))
+	invalid := func() error {
+		text, _ := tr.GetString("common_callback_invalid_request")
+		_, _ = query.Answer(b, &gotgbot.AnswerCallbackQueryOpts{Text: text})
+		return ext.EndGroups
+	}
+
+	decoded, ok := decodeCallbackData(query.Data, "notes.page")
+	if !ok || query.Message == nil {
+		return invalid()
+	}
+	chatIDText, _ := decoded.Field("c")
+	pageText, _ := decoded.Field("p")
+	chatID, err := strconv.ParseInt(chatIDText, 10, 64)
+	if err != nil {
+		return invalid()
+	}
+	page, err := strconv.Atoi(pageText)
+	if err != nil {
+		return invalid()
+	}
+
+	// A group browser only pages its own chat; a private one only the chat
+	// the user is still connected to.
+	msgChat := query.Message.GetChat()
+	private := msgChat.Type == "private"
+	chat := &msgChat
+	if private {
+		conn := connections.Connection(user.Id)
+		if conn == nil || !conn.Connected || conn.ChatId != chatID {
+			return invalid()
+		}
+		chatInfo, err := b.GetChat(chatID, nil)
+		if err != nil {
+			log.Errorf("[Notes] Failed to get chat %d for notes page: %v", chatID, err)
+			return invalid()
+		}
+		connectedChat := chatInfo.ToChat()
+		chat = &connectedChat
+	} else if msgChat.Id != chatID {
+		return invalid()
+	}
+
+	entries := notes.GetNoteIndex(chat.Id, chat_status.IsUserAdmin(b, chat.Id, user.Id))
+	if len(entries) == 0 {
+		text, _ := tr.GetString("notes_none_in_chat")
+		_, _ = query.Answer(b, &gotgbot.AnswerCallbackQueryOpts{Text: text})
+		return ext.EndGroups
+	}
+
+	text, keyboard := notesPage(b, tr, chat, entries, page, private)
+	opts := &gotgbot.EditMessageTextOpts{
+		ParseMode:          formatting.HTML,
+		LinkPreviewOptions: &gotgbot.LinkPreviewOptions{IsDisabled: true},
+	}
+	if keyboard != nil {
+		opts.ReplyMarkup = *keyboard
+	}
+	if _, _, err := query.Message.EditText(b, text, opts); err != nil {
+		log.Error(err)
+		return err
+	}
+	if _, err := query.Answer(b, nil); err != nil {
+		log.Error(err)
+		return err
+	}
+	return ext.EndGroups
+}
+
+// searchNotes handles /notes search <text>, listing the notes whose content
+// matches text. Matches are deep links in private chats and in chats with
+// private notes enabled, where notes are fetched from the bot's DM.
+func (moduleStruct) searchNotes(b *gotgbot.Bot, ctx *ext.Context, chat *gotgbot.Chat, user *gotgbot.User, text string) error {
+	msg := ctx.EffectiveMessage
+	tr := i18n.MustNewTranslator(lang.GetLanguage(ctx))
+
+	var reply string
+	if text == "" {
+		reply, _ = tr.GetString("notes_search_usage")
+	} else {
+		names, err := notes.SearchNotes(chat.Id, text, chat_status.IsUserAdmin(b, chat.Id, user.Id), notesSearchLimit)
+		switch {
+		case err != nil:
+			reply, _ = tr.GetString("error_generic")
+		case len(names) == 0:
+			reply, _ = tr.GetString("notes_search_no_results", i18n.TranslationParams{"query": html.EscapeString(text)})
+		default:
+			deepLink := msg.Chat.Type == "private" || notes.GetNotes(chat.Id).PrivateNotesEnabled()
+			lines := make([]string, 0, len(names))
+			for _, name := range names {
+				lines = append(lines, noteListLine(b, chat.Id, name, deepLink))
+			}
+			reply, _ = tr.GetString("notes_search_results", i18n.TranslationParams{"query": html.EscapeString(text)})
+			reply += "\n" + strings.Join(lines, "\n")
+		}
+	}
+
+	_, err := msg.Reply(b, reply, formatting.Shtml())
+	if err != nil {
+		log.Error(err)
+		return err
+	}
+	return ext.EndGroups
+}
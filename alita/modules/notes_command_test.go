@@ -87,7 +87,7 @@ func TestAddExistingNoteUsesOverwriteConfirmation(t *testing.T) {
 	bot := newModuleTestBot(client)
 	chat := gotgbot.Chat{Id: uniqueModuleChatID(), Type: "supergroup", Title: "Notes Chat"}
 	admin := gotgbot.User{Id: 777000, FirstName: "Telegram"}
-	if err := notes.AddNote(chat.Id, "rules", "old", "", nil, db.TEXT, false, false, false, false, false, false); err != nil {
+	if err := notes.AddNote(chat.Id, "rules", "", "old", "", nil, nil, db.TEXT, false, false, false, false, false, false); err != nil {
 		t.Fatalf("AddNote() setup error = %v", err)
 	}
 
@@ -180,7 +180,7 @@ func TestNoteCommandsPropagateGotgbotReplyErrors(t *testing.T) {
 			text: "/clear cleanup",
 			setup: func(t *testing.T) {
 				t.Helper()
-				if err := notes.AddNote(chat.Id, "cleanup", "be kind", "", nil, db.TEXT, false, false, false, false, false, false); err != nil {
+				if err := notes.AddNote(chat.Id, "cleanup", "", "be kind", "", nil, nil, db.TEXT, false, false, false, false, false, false); err != nil {
 					t.Fatalf("AddNote setup error = %v", err)
 				}
 			},
@@ -204,7 +204,7 @@ func TestNoteCommandsPropagateGotgbotReplyErrors(t *testing.T) {
 			text: "/notes",
 			setup: func(t *testing.T) {
 				t.Helper()
-				if err := notes.AddNote(chat.Id, "listed", "be kind", "", nil, db.TEXT, false, false, false, false, false, false); err != nil {
+				if err := notes.AddNote(chat.Id, "listed", "", "be kind", "", nil, nil, db.TEXT, false, false, false, false, false, false); err != nil {
 					t.Fatalf("AddNote setup error = %v", err)
 				}
 			},
@@ -240,7 +240,7 @@ func TestRmAllNotesConfirmationAndCallback(t *testing.T) {
 	bot := newModuleTestBot(client)
 	chat := gotgbot.Chat{Id: uniqueModuleChatID(), Type: "supergroup", Title: "Notes Chat"}
 	admin := gotgbot.User{Id: 777000, FirstName: "Telegram"}
-	if err := notes.AddNote(chat.Id, "rules", "be kind", "", nil, db.TEXT, false, false, false, false, false, false); err != nil {
+	if err := notes.AddNote(chat.Id, "rules", "", "be kind", "", nil, nil, db.TEXT, false, false, false, false, false, false); err != nil {
 		t.Fatalf("AddNote() setup error = %v", err)
 	}
 	data := encodeCallbackData("rmAllNotes", map[string]string{"a": "yes"})
@@ -285,7 +285,7 @@ func TestRemoveNoteAndClearAllValidationBranches(t *testing.T) {
 		t.Fatalf("rmAllNotes empty error = %v, want EndGroups", err)
 	}
 
-	if err := notes.AddNote(chat.Id, "rules", "be kind", "", nil, db.TEXT, false, false, false, false, false, false); err != nil {
+	if err := notes.AddNote(chat.Id, "rules", "", "be kind", "", nil, nil, db.TEXT, false, false, false, false, false, false); err != nil {
 		t.Fatalf("AddNote() setup error = %v", err)
 	}
 	memberClearAllCtx := newModuleMessageContext(bot, chat, member, "/clearall")
@@ -302,7 +302,7 @@ func TestNotesButtonHandlerCancelAndInvalidCallbacks(t *testing.T) {
 	bot := newModuleTestBot(client)
 	chat := gotgbot.Chat{Id: uniqueModuleChatID(), Type: "supergroup", Title: "Notes Chat"}
 	admin := gotgbot.User{Id: 777000, FirstName: "Telegram"}
-	if err := notes.AddNote(chat.Id, "rules", "be kind", "", nil, db.TEXT, false, false, false, false, false, false); err != nil {
+	if err := notes.AddNote(chat.Id, "rules", "", "be kind", "", nil, nil, db.TEXT, false, false, false, false, false, false); err != nil {
 		t.Fatalf("AddNote() setup error = %v", err)
 	}
 
@@ -388,7 +388,7 @@ func TestNoteOverwriteHandlerCancelAndSuccess(t *testing.T) {
 	bot := newModuleTestBot(client)
 	chat := gotgbot.Chat{Id: uniqueModuleChatID(), Type: "supergroup", Title: "Notes Chat"}
 	admin := gotgbot.User{Id: 777000, FirstName: "Telegram"}
-	if err := notes.AddNote(chat.Id, "rules", "old", "", nil, db.TEXT, false, false, false, false, false, false); err != nil {
+	if err := notes.AddNote(chat.Id, "rules", "", "old", "", nil, nil, db.TEXT, false, false, false, false, false, false); err != nil {
 		t.Fatalf("AddNote() setup error = %v", err)
 	}
 
@@ -438,7 +438,7 @@ func TestNoteOverwriteHandlerMissingMalformedAndRequestErrors(t *testing.T) {
 	requestErr := errors.New("telegram request failed")
 	chat := gotgbot.Chat{Id: uniqueModuleChatID(), Type: "supergroup", Title: "Notes Chat"}
 	admin := gotgbot.User{Id: 777000, FirstName: "Telegram"}
-	if err := notes.AddNote(chat.Id, "rules", "old", "", nil, db.TEXT, false, false, false, false, false, false); err != nil {
+	if err := notes.AddNote(chat.Id, "rules", "", "old", "", nil, nil, db.TEXT, false, false, false, false, false, false); err != nil {
 		t.Fatalf("AddNote setup error = %v", err)
 	}
 
@@ -514,7 +514,7 @@ func TestNotesWatcherSendsMatchingNote(t *testing.T) {
 	bot := newModuleTestBot(client)
 	chat := gotgbot.Chat{Id: uniqueModuleChatID(), Type: "supergroup", Title: "Notes Chat"}
 	member := gotgbot.User{Id: 42, FirstName: "Member"}
-	if err := notes.AddNote(chat.Id, "rules", "be kind", "", nil, db.TEXT, false, false, false, false, false, false); err != nil {
+	if err := notes.AddNote(chat.Id, "rules", "", "be kind", "", nil, nil, db.TEXT, false, false, false, false, false, false); err != nil {
 		t.Fatalf("AddNote() setup error = %v", err)
 	}
 
@@ -538,7 +538,7 @@ func TestNotesWatcherPrivateAdminOnlyAndNoFormatBranches(t *testing.T) {
 	member := gotgbot.User{Id: 42, FirstName: "Member"}
 	admin := gotgbot.User{Id: 777000, FirstName: "Telegram"}
 
-	if err := notes.AddNote(chat.Id, "secret", "private text", "", nil, db.TEXT, true, false, false, false, false, false); err != nil {
+	if err := notes.AddNote(chat.Id, "secret", "", "private text", "", nil, nil, db.TEXT, true, false, false, false, false, false); err != nil {
 		t.Fatalf("AddNote(private) setup error = %v", err)
 	}
 	privateCtx := newModuleMessageContext(bot, chat, member, "#secret")
@@ -549,7 +549,7 @@ func TestNotesWatcherPrivateAdminOnlyAndNoFormatBranches(t *testing.T) {
 		t.Fatalf("private note calls = %+v, want click-through button", calls)
 	}
 
-	if err := notes.AddNote(chat.Id, "admin", "admin text", "", nil, db.TEXT, false, false, true, false, false, false); err != nil {
+	if err := notes.AddNote(chat.Id, "admin", "", "admin text", "", nil, nil, db.TEXT, false, false, true, false, false, false); err != nil {
 		t.Fatalf("AddNote(admin) setup error = %v", err)
 	}
 	memberAdminCtx := newModuleMessageContext(bot, chat, member, "#admin")
@@ -584,7 +584,7 @@ func TestGetNotesValidationPrivateAndNoFormatBranches(t *testing.T) {
 		}
 	}
 
-	if err := notes.AddNote(chat.Id, "private", "private text", "", nil, db.TEXT, true, false, false, false, false, false); err != nil {
+	if err := notes.AddNote(chat.Id, "private", "", "private text", "", nil, nil, db.TEXT, true, false, false, false, false, false); err != nil {
 		t.Fatalf("AddNote(private) setup error = %v", err)
 	}
 	privateCtx := newModuleMessageContext(bot, chat, member, "/get private")
@@ -592,7 +592,7 @@ func TestGetNotesValidationPrivateAndNoFormatBranches(t *testing.T) {
 		t.Fatalf("getNotes private note error = %v, want EndGroups", err)
 	}
 
-	if err := notes.AddNote(chat.Id, "raw", "<b>raw</b>", "", nil, db.TEXT, false, false, false, false, false, false); err != nil {
+	if err := notes.AddNote(chat.Id, "raw", "", "<b>raw</b>", "", nil, nil, db.TEXT, false, false, false, false, false, false); err != nil {
 		t.Fatalf("AddNote(raw) setup error = %v", err)
 	}
 	memberNoFormatCtx := newModuleMessageContext(bot, chat, member, "/get raw noformat")
@@ -619,16 +619,16 @@ func TestGetNotesAndWatcherPropagateGotgbotSendErrors(t *testing.T) {
 	chat := gotgbot.Chat{Id: uniqueModuleChatID(), Type: "supergroup", Title: "Notes Chat"}
 	member := gotgbot.User{Id: 42, FirstName: "Member"}
 	admin := gotgbot.User{Id: 777000, FirstName: "Telegram"}
-	if err := notes.AddNote(chat.Id, "rules", "be kind", "", nil, db.TEXT, false, false, false, false, false, false); err != nil {
+	if err := notes.AddNote(chat.Id, "rules", "", "be kind", "", nil, nil, db.TEXT, false, false, false, false, false, false); err != nil {
 		t.Fatalf("AddNote(rules) setup error = %v", err)
 	}
-	if err := notes.AddNote(chat.Id, "private", "private text", "", nil, db.TEXT, true, false, false, false, false, false); err != nil {
+	if err := notes.AddNote(chat.Id, "private", "", "private text", "", nil, nil, db.TEXT, true, false, false, false, false, false); err != nil {
 		t.Fatalf("AddNote(private) setup error = %v", err)
 	}
-	if err := notes.AddNote(chat.Id, "admin", "admin text", "", nil, db.TEXT, false, false, true, false, false, false); err != nil {
+	if err := notes.AddNote(chat.Id, "admin", "", "admin text", "", nil, nil, db.TEXT, false, false, true, false, false, false); err != nil {
 		t.Fatalf("AddNote(admin) setup error = %v", err)
 	}
-	if err := notes.AddNote(chat.Id, "broken", "", "", nil, db.TEXT, false, false, false, false, false, false); err != nil {
+	if err := notes.AddNote(chat.Id, "broken", "", "", "", nil, nil, db.TEXT, false, false, false, false, false, false); err != nil {
 		t.Fatalf("AddNote(broken) setup error = %v", err)
 	}
 
@@ -671,10 +671,10 @@ func TestNotesWatcherAdminOnlyAndMalformedNotes(t *testing.T) {
 	chat := gotgbot.Chat{Id: uniqueModuleChatID(), Type: "supergroup", Title: "Notes Chat"}
 	member := gotgbot.User{Id: 42, FirstName: "Member"}
 	admin := gotgbot.User{Id: 777000, FirstName: "Telegram"}
-	if err := notes.AddNote(chat.Id, "admin", "admin-only", "", nil, db.TEXT, false, false, true, false, false, false); err != nil {
+	if err := notes.AddNote(chat.Id, "admin", "", "admin-only", "", nil, nil, db.TEXT, false, false, true, false, false, false); err != nil {
 		t.Fatalf("AddNote(admin) setup error = %v", err)
 	}
-	if err := notes.AddNote(chat.Id, "broken", "", "", nil, db.TEXT, false, false, false, false, false, false); err != nil {
+	if err := notes.AddNote(chat.Id, "broken", "", "", "", nil, nil, db.TEXT, false, false, false, false, false, false); err != nil {
 		t.Fatalf("AddNote(broken) setup error = %v", err)
 	}
 
@@ -703,7 +703,7 @@ func TestNotesWatcherPrivateOnlyNoteSendsDeepLinkInGroup(t *testing.T) {
 	bot := newModuleTestBot(client)
 	chat := gotgbot.Chat{Id: uniqueModuleChatID(), Type: "supergroup", Title: "Notes Chat"}
 	member := gotgbot.User{Id: 42, FirstName: "Member"}
-	if err := notes.AddNote(chat.Id, "secret", "private", "", nil, db.TEXT, true, false, false, false, false, false); err != nil {
+	if err := notes.AddNote(chat.Id, "secret", "", "private", "", nil, nil, db.TEXT, true, false, false, false, false, false); err != nil {
 		t.Fatalf("AddNote() setup error = %v", err)
 	}
 
@@ -725,7 +725,7 @@ func TestNotesListPrivateAndPrivateNotesButton(t *testing.T) {
 	bot := newModuleTestBot(client)
 	chat := gotgbot.Chat{Id: uniqueModuleChatID(), Type: "supergroup", Title: "Notes Chat"}
 	admin := gotgbot.User{Id: 777000, FirstName: "Telegram"}
-	if err := notes.AddNote(chat.Id, "rules", "be kind", "", nil, db.TEXT, false, false, false, false, false, false); err != nil {
+	if err := notes.AddNote(chat.Id, "rules", "", "be kind", "", nil, nil, db.TEXT, false, false, false, false, false, false); err != nil {
 		t.Fatalf("AddNote() setup error = %v", err)
 	}
 
@@ -754,7 +754,7 @@ func TestGetNoteNoFormatRequiresAdminAndSendsRawNote(t *testing.T) {
 	bot := newModuleTestBot(client)
 	chat := gotgbot.Chat{Id: uniqueModuleChatID(), Type: "supergroup", Title: "Notes Chat"}
 	admin := gotgbot.User{Id: 777000, FirstName: "Telegram"}
-	if err := notes.AddNote(chat.Id, "raw", "<b>raw</b>", "", nil, db.TEXT, false, false, false, false, false, false); err != nil {
+	if err := notes.AddNote(chat.Id, "raw", "", "<b>raw</b>", "", nil, nil, db.TEXT, false, false, false, false, false, false); err != nil {
 		t.Fatalf("AddNote() setup error = %v", err)
 	}
 
@@ -770,3 +770,204 @@ func TestGetNoteNoFormatRequiresAdminAndSendsRawNote(t *testing.T) {
 		t.Fatalf("raw note text was not reversed from HTML markdown: %q", got)
 	}
 }
+
+func TestSaveNoteWithCategoryAndBrowsePages(t *testing.T) {
+	client := newModuleBotClient()
+	bot := newModuleTestBot(client)
+	chat := gotgbot.Chat{Id: uniqueModuleChatID(), Type: "supergroup", Title: "Notes Chat"}
+	admin := gotgbot.User{Id: 777000, FirstName: "Telegram"}
+
+	saveCtx := newModuleMessageContext(bot, chat, admin, "/save FAQ/install Run the installer")
+	if err := notesModule.addNote(bot, saveCtx); err != ext.EndGroups {
+		t.Fatalf("addNote(category) error = %v, want EndGroups", err)
+	}
+	note := notes.GetNote(chat.Id, "install")
+	if note == nil || note.Category != "faq" {
+		t.Fatalf("saved note = %+v, want note install in category faq", note)
+	}
+
+	invalidCtx := newModuleMessageContext(bot, chat, admin, "/save faq/ Missing name")
+	if err := notesModule.addNote(bot, invalidCtx); err != ext.EndGroups {
+		t.Fatalf("addNote(invalid category) error = %v, want EndGroups", err)
+	}
+	if notes.DoesNoteExists(chat.Id, "faq/") || notes.DoesNoteExists(chat.Id, "") {
+		t.Fatal("note with an invalid category was stored")
+	}
+
+	for i := range notesPageSize {
+		name := "bulk" + strconv.Itoa(i+10)
+		if err := notes.AddNote(chat.Id, name, "bulk", "text", "", nil, nil, db.TEXT, false, false, false, false, false, false); err != nil {
+			t.Fatalf("AddNote(%s) setup error = %v", name, err)
+		}
+	}
+
+	listCtx := newModuleMessageContext(bot, chat, admin, "/notes")
+	if err := notesModule.notesList(bot, listCtx); err != ext.EndGroups {
+		t.Fatalf("notesList() error = %v, want EndGroups", err)
+	}
+	calls := client.callsFor("sendMessage")
+	first := calls[len(calls)-1]
+	if text := first.Params["text"].(string); !strings.Contains(text, "<b>bulk</b>") || strings.Contains(text, "#install") {
+		t.Fatalf("first notes page = %q, want only the bulk category", text)
+	}
+	markup, ok := first.Params["reply_markup"].(gotgbot.InlineKeyboardMarkup)
+	if !ok || len(markup.InlineKeyboard) != 1 || len(markup.InlineKeyboard[0]) != 1 {
+		t.Fatalf("first notes page markup = %#v, want a single next button", first.Params["reply_markup"])
+	}
+
+	nextCtx := newModuleCallbackContext(bot, chat, admin, markup.InlineKeyboard[0][0].CallbackData)
+	if err := notesModule.notesPageHandler(bot, nextCtx); err != ext.EndGroups {
+		t.Fatalf("notesPageHandler() error = %v, want EndGroups", err)
+	}
+	edits := client.callsFor("editMessageText")
+	if len(edits) != 1 {
+		t.Fatalf("editMessageText calls = %d, want 1", len(edits))
+	}
+	text := edits[0].Params["text"].(string)
+	if !strings.Contains(text, "<b>faq</b>") || !strings.Contains(text, "#install") {
+		t.Fatalf("second notes page = %q, want faq category with install", text)
+	}
+
+	forged := encodeCallbackData("notes.page", map[string]string{"c": strconv.FormatInt(chat.Id+1, 10), "p": "1"})
+	forgedCtx := newModuleCallbackContext(bot, chat, admin, forged)
+	if err := notesModule.notesPageHandler(bot, forgedCtx); err != ext.EndGroups {
+		t.Fatalf("notesPageHandler(forged) error = %v, want EndGroups", err)
+	}
+	if got := len(client.callsFor("editMessageText")); got != 1 {
+		t.Fatalf("editMessageText calls after forged page = %d, want 1", got)
+	}
+}
+
+func TestNotesSearchListsMatchingNotes(t *testing.T) {
+	client := newModuleBotClient()
+	bot := newModuleTestBot(client)
+	chat := gotgbot.Chat{Id: uniqueModuleChatID(), Type: "supergroup", Title: "Notes Chat"}
+	admin := gotgbot.User{Id: 777000, FirstName: "Telegram"}
+	for name, content := range map[string]string{
+		"install": "Run the installer, then restart",
+		"rules":   "Be kind",
+	} {
+		if err := notes.AddNote(chat.Id, name, "", content, "", nil, nil, db.TEXT, false, false, false, false, false, false); err != nil {
+			t.Fatalf("AddNote(%s) setup error = %v", name, err)
+		}
+	}
+
+	searchCtx := newModuleMessageContext(bot, chat, admin, "/notes search restart")
+	if err := notesModule.notesList(bot, searchCtx); err != ext.EndGroups {
+		t.Fatalf("notesList(search) error = %v, want EndGroups", err)
+	}
+	calls := client.callsFor("sendMessage")
+	text := calls[len(calls)-1].Params["text"].(string)
+	if !strings.Contains(text, "#install") || strings.Contains(text, "#rules") {
+		t.Fatalf("search results = %q, want only install", text)
+	}
+
+	usageCtx := newModuleMessageContext(bot, chat, admin, "/notes search")
+	if err := notesModule.notesList(bot, usageCtx); err != ext.EndGroups {
+		t.Fatalf("notesList(search usage) error = %v, want EndGroups", err)
+	}
+	calls = client.callsFor("sendMessage")
+	if text := calls[len(calls)-1].Params["text"].(string); strings.Contains(text, "#") {
+		t.Fatalf("search usage = %q, want usage hint instead of results", text)
+	}
+}
+
+func TestNoteHistoryDiffAndRestore(t *testing.T) {
+	client := newModuleBotClient()
+	bot := newModuleTestBot(client)
+	chat := gotgbot.Chat{Id: uniqueModuleChatID(), Type: "supergroup", Title: "Notes Chat"}
+	admin := gotgbot.User{Id: 777000, FirstName: "Telegram"}
+	if err := notes.AddNote(chat.Id, "faq", "", "Step one\nStep two", "", nil, nil, db.TEXT, false, false, false, true, false, false); err != nil {
+		t.Fatalf("AddNote() setup error = %v", err)
+	}
+	if _, err := notes.UpdateNote(chat.Id, admin.Id, "faq", "", "Step one\nWiped <b>", "", nil, nil, db.TEXT, false, false, false, true, false, false); err != nil {
+		t.Fatalf("UpdateNote() setup error = %v", err)
+	}
+
+	historyCtx := newModuleMessageContext(bot, chat, admin, "/notehistory faq")
+	if err := notesModule.revisionHistory(noteRevisionKind)(bot, historyCtx); err != ext.EndGroups {
+		t.Fatalf("notehistory error = %v, want EndGroups", err)
+	}
+	calls := client.callsFor("sendMessage")
+	history := calls[len(calls)-1]
+	if text := history.Params["text"].(string); !strings.Contains(text, "<b>#1</b>") {
+		t.Fatalf("history = %q, want revision #1 listed", text)
+	}
+	markup, ok := history.Params["reply_markup"].(gotgbot.InlineKeyboardMarkup)
+	if !ok || len(markup.InlineKeyboard) != 1 || len(markup.InlineKeyboard[0]) != 1 {
+		t.Fatalf("history keyboard = %#v, want one diff button", history.Params["reply_markup"])
+	}
+
+	diffCtx := newModuleCallbackContext(bot, chat, admin, markup.InlineKeyboard[0][0].CallbackData)
+	if err := notesModule.revisionCallback(noteRevisionKind)(bot, diffCtx); err != ext.EndGroups {
+		t.Fatalf("diff callback error = %v, want EndGroups", err)
+	}
+	edits := client.callsFor("editMessageText")
+	diff := edits[len(edits)-1].Params["text"].(string)
+	for _, want := range []string{"  Step one", "- Step two", "+ Wiped &lt;b&gt;"} {
+		if !strings.Contains(diff, want) {
+			t.Fatalf("diff = %q, want line %q", diff, want)
+		}
+	}
+
+	restoreCtx := newModuleMessageContext(bot, chat, admin, "/noterestore faq 1")
+	if err := notesModule.revisionRestore(noteRevisionKind)(bot, restoreCtx); err != ext.EndGroups {
+		t.Fatalf("noterestore error = %v, want EndGroups", err)
+	}
+	if note := notes.GetNote(chat.Id, "faq"); note == nil || note.NoteContent != "Step one\nStep two" {
+		t.Fatalf("note after restore = %+v", note)
+	}
+
+	if err := notes.RemoveAllNotes(chat.Id, admin.Id); err != nil {
+		t.Fatalf("RemoveAllNotes() error = %v", err)
+	}
+	undoCtx := newModuleMessageContext(bot, chat, admin, "/noterestore all")
+	if err := notesModule.revisionRestore(noteRevisionKind)(bot, undoCtx); err != ext.EndGroups {
+		t.Fatalf("noterestore all error = %v, want EndGroups", err)
+	}
+	if !notes.DoesNoteExists(chat.Id, "faq") {
+		t.Fatal("expected /noterestore all to bring back the cleared note")
+	}
+}
+
+func TestSaveNoteFromAlbumReplyStoresWholeAlbum(t *testing.T) {
+	client := newModuleBotClient()
+	bot := newModuleTestBot(client)
+	chat := gotgbot.Chat{Id: uniqueModuleChatID(), Type: "supergroup", Title: "Notes Chat"}
+	admin := gotgbot.User{Id: 777000, FirstName: "Telegram"}
+
+	album := []*gotgbot.Message{
+		{MessageId: 10, Chat: chat, MediaGroupId: "album-1", Photo: []gotgbot.PhotoSize{{FileId: "photo-1"}}, Caption: "first"},
+		{MessageId: 11, Chat: chat, MediaGroupId: "album-1", Photo: []gotgbot.PhotoSize{{FileId: "photo-2"}}, Caption: "middle"},
+		{MessageId: 12, Chat: chat, MediaGroupId: "album-1", Video: &gotgbot.Video{FileId: "video-3"}},
+	}
+	for _, msg := range album {
+		recordAlbumMessage(msg)
+	}
+
+	saveCtx := newModuleMessageContext(bot, chat, admin, "/save gallery")
+	saveCtx.EffectiveMessage.ReplyToMessage = album[1]
+	if err := notesModule.addNote(bot, saveCtx); err != ext.EndGroups {
+		t.Fatalf("addNote(album) error = %v, want EndGroups", err)
+	}
+
+	note := notes.GetNote(chat.Id, "gallery")
+	if note == nil || len(note.Media) != 3 {
+		t.Fatalf("saved note = %+v, want three album items", note)
+	}
+	if note.FileID != "photo-1" || note.MsgType != db.PHOTO || note.NoteContent != "middle" {
+		t.Fatalf("saved note = %+v, want first item mirrored and replied caption as text", note)
+	}
+	if note.Media[0].Caption != "first" || note.Media[1].Caption != "" || note.Media[2].MsgType != db.VIDEO {
+		t.Fatalf("saved media = %+v", note.Media)
+	}
+
+	client.responses["sendMediaGroup"] = []byte(`[{"message_id":20,"date":1,"chat":{"id":1,"type":"supergroup"}},{"message_id":21,"date":1,"chat":{"id":1,"type":"supergroup"}}]`)
+	getCtx := newModuleMessageContext(bot, chat, admin, "/get gallery")
+	if err := notesModule.getNotes(bot, getCtx); err != ext.EndGroups {
+		t.Fatalf("getNotes(album) error = %v, want EndGroups", err)
+	}
+	if calls := client.callsFor("sendMediaGroup"); len(calls) != 1 {
+		t.Fatalf("sendMediaGroup calls = %d, want 1", len(calls))
+	}
+}
@@ -23,17 +23,20 @@ type overwriteBase struct {
 	Text     string
 	FileID   string
 	Buttons  []db.Button
+	Media    []db.MediaItem // album items, if an album was saved
 	DataType int
 }
 
 // struct for filters module
 type overwriteFilter struct {
 	overwriteBase
+	ThreadID int64 // forum topic the filter is scoped to; 0 = the whole chat
 }
 
 // struct for notes module
 type overwriteNote struct {
 	overwriteBase
+	Category    string
 	PvtOnly     bool
 	GrpOnly     bool
 	AdminOnly   bool
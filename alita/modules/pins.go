@@ -789,8 +789,8 @@ func LoadPin(dispatcher *ext.Dispatcher) {
 	helpers.WrapCommand(dispatcher, pinnedDesc, pinsModule.pinned)
 
 	// antichannelpin and cleanlinked modify ctx.EffectiveChat so keep raw
-	dispatcher.AddHandler(handlers.NewCommand("antichannelpin", pinsModule.antichannelpin))
-	dispatcher.AddHandler(handlers.NewCommand("cleanlinked", pinsModule.cleanlinked))
+	helpers.AddCommand(dispatcher, "antichannelpin", pinsModule.antichannelpin)
+	helpers.AddCommand(dispatcher, "cleanlinked", pinsModule.cleanlinked)
 
 	dispatcher.AddHandler(handlers.NewCallback(callbackquery.Prefix("unpinallbtn"), pinsModule.unpinallCallback))
 	dispatcher.AddHandlerToGroup(
@@ -17,9 +17,11 @@ import (
 	"github.com/PaulSonOfLars/gotgbot/v2/ext"
 	"github.com/PaulSonOfLars/gotgbot/v2/ext/handlers"
 	"github.com/PaulSonOfLars/gotgbot/v2/ext/handlers/filters/callbackquery"
+	"github.com/PaulSonOfLars/gotgbot/v2/ext/handlers/filters/message"
 
 	"github.com/divkix/Alita_Robot/alita/i18n"
 	"github.com/divkix/Alita_Robot/alita/utils/chat_status"
+	"github.com/divkix/Alita_Robot/alita/utils/extraction"
 )
 
 type delMsgEntry struct {
@@ -28,6 +30,9 @@ type delMsgEntry struct {
 	msgID     int64
 }
 
+// maxPurgeMessages is the most messages one purge deletes.
+const maxPurgeMessages = 1000
+
 var (
 	purgesModule = moduleStruct{moduleName: "Purges"}
 	delMsgs      = sync.Map{} // Concurrent-safe map for tracking messages to delete (value is delMsgEntry)
@@ -64,15 +69,8 @@ func checkPurgePermissions(bot *gotgbot.Bot, ctx *ext.Context) (*gotgbot.User, b
 	return user, true
 }
 
-// PurgeWorker collects errors from concurrent message deletion.
-type PurgeWorker struct {
-	errors     []error // Collect errors
-	errorCount int     // Count of errors
-	mu         sync.Mutex
-}
-
-// purgeMsgsConcurrent performs concurrent message deletion with rate limiting.
-// Uses a fixed worker pool to avoid spawning up to 1000 goroutines.
+// purgeMsgsConcurrent deletes a range of messages in batches, handling the
+// starting message separately to tell when it is too old to delete.
 func (moduleStruct) purgeMsgsConcurrent(bot *gotgbot.Bot, chat *gotgbot.Chat, pFrom bool, msgId, deleteTo int64) bool {
 	// Handle the starting message if not pFrom
 	if !pFrom {
@@ -106,61 +104,54 @@ func (moduleStruct) purgeMsgsConcurrent(bot *gotgbot.Bot, chat *gotgbot.Chat, pF
 		loopFrom = msgId + 1
 	}
 
-	// Calculate total messages to delete
-	totalMessages := deleteTo - loopFrom + 1
-	if totalMessages <= 0 {
-		return true
+	ids := make([]int64, 0, max(deleteTo-loopFrom+1, 0))
+	for mId := deleteTo; mId >= loopFrom; mId-- {
+		ids = append(ids, mId)
 	}
+	deleteMessageIDs(bot, chat, ids)
+	return true
+}
 
-	// For small ranges, use sequential deletion
-	if totalMessages <= 10 {
-		for mId := deleteTo; mId >= loopFrom; mId-- {
-			_ = helpers.DeleteMessageWithErrorHandling(bot, chat.Id, mId)
+// purgeProgressInterval is how long a purge runs before it reports its
+// progress, and how often it updates the report.
+var purgeProgressInterval = 2 * time.Second
+
+// deleteMessageIDs deletes the given messages, in order, in batches. Purges
+// that take a while report their progress in one status message, edited as
+// batches go and deleted at the end.
+func deleteMessageIDs(bot *gotgbot.Bot, chat *gotgbot.Chat, ids []int64) {
+	var status *gotgbot.Message
+	lastReport := time.Now()
+	progress := func(done, total int) {
+		if done == total || time.Since(lastReport) < purgeProgressInterval {
+			return
 		}
-		return true
-	}
-
-	// For larger ranges, use fixed worker pool
-	const maxConcurrentMsgDeletions = 10
-	worker := &PurgeWorker{
-		errors: make([]error, 0),
-	}
-
-	jobs := make(chan int64, totalMessages)
-	for mId := deleteTo; mId >= loopFrom; mId-- {
-		jobs <- mId
-	}
-	close(jobs)
-
-	var wg sync.WaitGroup
-	for i := 0; i < maxConcurrentMsgDeletions; i++ {
-		wg.Add(1)
-		go func() {
-			defer wg.Done()
-			for messageID := range jobs {
-				if err := helpers.DeleteMessageWithErrorHandling(bot, chat.Id, messageID); err != nil {
-					worker.mu.Lock()
-					worker.errorCount++
-					if worker.errorCount <= 5 {
-						worker.errors = append(worker.errors, err)
-					}
-					worker.mu.Unlock()
-				}
+		lastReport = time.Now()
+		tr := i18n.MustNewTranslator(lang.GetLanguage(&ext.Context{EffectiveChat: chat}))
+		text, _ := tr.GetString("purges_progress", i18n.TranslationParams{"done": strconv.Itoa(done), "total": strconv.Itoa(total)})
+		if status == nil {
+			sent, err := bot.SendMessage(chat.Id, text, formatting.Shtml())
+			if err != nil {
+				log.Error(err)
+				return
 			}
-		}()
+			status = sent
+			return
+		}
+		if _, _, err := status.EditText(bot, text, &gotgbot.EditMessageTextOpts{ParseMode: formatting.HTML}); err != nil {
+			log.Error(err)
+		}
 	}
-	wg.Wait()
 
-	// Log errors if any (excluding "not found" errors)
-	if len(worker.errors) > 0 {
+	if err := helpers.DeleteMessagesBatched(bot, chat.Id, ids, progress); err != nil {
 		log.WithFields(log.Fields{
-			"chat_id":       chat.Id,
-			"error_count":   worker.errorCount,
-			"sample_errors": worker.errors,
-		}).Warn("Some messages could not be deleted during purge")
+			"chat_id":  chat.Id,
+			"messages": len(ids),
+		}).WithError(err).Warn("Some messages could not be deleted during purge")
+	}
+	if status != nil {
+		_ = helpers.DeleteMessageWithErrorHandling(bot, chat.Id, status.MessageId)
 	}
-
-	return true
 }
 
 // purgeMsgs performs the actual message deletion operation for purge commands,
@@ -187,7 +178,6 @@ func (m moduleStruct) purge(bot *gotgbot.Bot, ctx *ext.Context) error {
 		totalMsgs := deleteTo - msgId + 1 // adding 1 because we want to delete the message we are replying to
 
 		// Limit purge range to prevent abuse and API overload
-		const maxPurgeMessages = 1000
 		if totalMsgs > maxPurgeMessages {
 			tr := i18n.MustNewTranslator(lang.GetLanguage(ctx))
 			text, _ := tr.GetString("purges_limit_exceeded")
@@ -455,7 +445,6 @@ func (m moduleStruct) purgeTo(bot *gotgbot.Bot, ctx *ext.Context) error {
 		totalMsgs := endId - startId + 1
 
 		// Enforce same limit as /purge command to prevent abuse
-		const maxPurgeMessages = 1000
 		if totalMsgs > maxPurgeMessages {
 			tr := i18n.MustNewTranslator(lang.GetLanguage(ctx))
 			text, _ := tr.GetString("purges_limit_exceeded")
@@ -506,20 +495,164 @@ func (m moduleStruct) purgeTo(bot *gotgbot.Bot, ctx *ext.Context) error {
 	return ext.EndGroups
 }
 
+// purgeLimit parses the optional count or duration of the index purges into
+// how many messages to delete at most and since when, in unix seconds.
+func purgeLimit(arg string) (limit int, since int64, ok bool) {
+	since = time.Now().Add(-messageIndexWindow).Unix()
+	if arg == "" {
+		return maxPurgeMessages, since, true
+	}
+	if n, err := strconv.Atoi(arg); err == nil {
+		if n <= 0 || n > maxPurgeMessages {
+			return 0, 0, false
+		}
+		return n, since, true
+	}
+	seconds, ok := parseDuration(arg)
+	if !ok || seconds > int(messageIndexWindow.Seconds()) {
+		return 0, 0, false
+	}
+	return maxPurgeMessages, time.Now().Unix() - int64(seconds), true
+}
+
+// purgeIndexed deletes indexed messages and the command, then briefly tells
+// the chat how many went.
+func (moduleStruct) purgeIndexed(bot *gotgbot.Bot, ctx *ext.Context, messages []indexedMessage, doneKey string, params i18n.TranslationParams) error {
+	msg := ctx.EffectiveMessage
+	chat := ctx.EffectiveChat
+	tr := i18n.MustNewTranslator(lang.GetLanguage(ctx))
+	if len(messages) == 0 {
+		text, _ := tr.GetString("purges_index_empty")
+		_, err := msg.Reply(bot, text, formatting.Shtml())
+		if err != nil {
+			log.Error(err)
+		}
+		return ext.EndGroups
+	}
+
+	deleteMessageIDs(bot, chat, messageIDs(messages))
+	forgetMessages(chat.Id, messages)
+	_ = helpers.DeleteMessageWithErrorHandling(bot, chat.Id, msg.MessageId)
+
+	params["count"] = strconv.Itoa(len(messages))
+	text, _ := tr.GetString(doneKey, params)
+	pMsg, err := bot.SendMessage(chat.Id, text, formatting.Shtml())
+	if err != nil {
+		log.Error(err)
+		return ext.EndGroups
+	}
+	// Delete notification message after 3 seconds in background
+	go func(msgToDelete *gotgbot.Message) {
+		time.Sleep(3 * time.Second)
+		_, _ = msgToDelete.Delete(bot, nil)
+	}(pMsg)
+	return ext.EndGroups
+}
+
+// purgeUser handles the /purgeuser command to delete the recent messages of
+// the replied or named user, optionally only the last few or those of the
+// last hours.
+func (m moduleStruct) purgeUser(bot *gotgbot.Bot, ctx *ext.Context) error {
+	if _, ok := checkPurgePermissions(bot, ctx); !ok {
+		return ext.EndGroups
+	}
+
+	msg := ctx.EffectiveMessage
+	chat := ctx.EffectiveChat
+	tr := i18n.MustNewTranslator(lang.GetLanguage(ctx))
+
+	// With a reply, a number is a count rather than a user ID.
+	var userID int64
+	var name, rest string
+	if msg.ReplyToMessage != nil {
+		sender := msg.ReplyToMessage.GetSender()
+		userID, name = sender.Id(), sender.Name()
+		rest = strings.Join(ctx.Args()[1:], " ")
+	} else {
+		userID, rest = extraction.ExtractUserAndText(bot, ctx)
+		if userID == -1 {
+			return ext.EndGroups
+		}
+		name = extractDisplayName(userID)
+	}
+	if userID == 0 {
+		text, _ := tr.GetString("purges_purgeuser_usage")
+		_, err := msg.Reply(bot, text, formatting.Shtml())
+		if err != nil {
+			log.Error(err)
+		}
+		return ext.EndGroups
+	}
+	limit, since, ok := purgeLimit(strings.TrimSpace(rest))
+	if !ok {
+		text, _ := tr.GetString("purges_index_limit_invalid", i18n.TranslationParams{"max": strconv.Itoa(maxPurgeMessages)})
+		_, err := msg.Reply(bot, text, formatting.Shtml())
+		if err != nil {
+			log.Error(err)
+		}
+		return ext.EndGroups
+	}
+
+	messages := recentMessagesFrom(chat.Id, userID, since, limit)
+	return m.purgeIndexed(bot, ctx, messages, "purges_purgeuser_done", i18n.TranslationParams{"user": formatting.MentionHtml(userID, name)})
+}
+
+// purgeKind deletes recent messages with the given index flag, optionally
+// only the last few or those of the last hours.
+func (m moduleStruct) purgeKind(bot *gotgbot.Bot, ctx *ext.Context, flag int, doneKey string) error {
+	if _, ok := checkPurgePermissions(bot, ctx); !ok {
+		return ext.EndGroups
+	}
+
+	limit, since, ok := purgeLimit(strings.Join(ctx.Args()[1:], " "))
+	if !ok {
+		tr := i18n.MustNewTranslator(lang.GetLanguage(ctx))
+		text, _ := tr.GetString("purges_index_limit_invalid", i18n.TranslationParams{"max": strconv.Itoa(maxPurgeMessages)})
+		_, err := ctx.EffectiveMessage.Reply(bot, text, formatting.Shtml())
+		if err != nil {
+			log.Error(err)
+		}
+		return ext.EndGroups
+	}
+
+	messages := recentMessagesOfKind(ctx.EffectiveChat.Id, flag, since, limit)
+	return m.purgeIndexed(bot, ctx, messages, doneKey, i18n.TranslationParams{})
+}
+
+// purgeBots handles the /purgebots command to delete recent messages sent
+// via inline bots.
+func (m moduleStruct) purgeBots(bot *gotgbot.Bot, ctx *ext.Context) error {
+	return m.purgeKind(bot, ctx, indexedViaBot, "purges_purgebots_done")
+}
+
+// purgeMedia handles the /purgemedia command to delete recent photos,
+// videos, files, voice messages and stickers.
+func (m moduleStruct) purgeMedia(bot *gotgbot.Bot, ctx *ext.Context) error {
+	return m.purgeKind(bot, ctx, indexedMedia, "purges_purgemedia_done")
+}
+
 // LoadPurges registers all purges module handlers with the dispatcher,
 // including message deletion commands and callback handlers.
 func LoadPurges(dispatcher *ext.Dispatcher) {
 	DefaultHelpRegistry().AbleMap[purgesModule.moduleName] = true
 
-	dispatcher.AddHandler(handlers.NewCommand("del", purgesModule.delCmd))
-	dispatcher.AddHandler(handlers.NewCommand("purge", purgesModule.purge))
-	dispatcher.AddHandler(handlers.NewCommand("purgefrom", purgesModule.purgeFrom))
-	dispatcher.AddHandler(handlers.NewCommand("purgeto", purgesModule.purgeTo))
+	helpers.AddCommand(dispatcher, "del", purgesModule.delCmd)
+	helpers.AddCommand(dispatcher, "purge", purgesModule.purge)
+	helpers.AddCommand(dispatcher, "purgefrom", purgesModule.purgeFrom)
+	helpers.AddCommand(dispatcher, "purgeto", purgesModule.purgeTo)
+	helpers.AddCommand(dispatcher, "purgeuser", purgesModule.purgeUser)
+	helpers.AddCommand(dispatcher, "purgebots", purgesModule.purgeBots)
+	helpers.AddCommand(dispatcher, "purgemedia", purgesModule.purgeMedia)
 	dispatcher.AddHandler(handlers.NewCallback(callbackquery.Prefix("deleteMsg"), purgesModule.deleteButtonHandler))
+	// Bot messages are indexed too, so /purgeuser can remove them.
+	dispatcher.AddHandlerToGroup(handlers.NewMessage(message.All, purgesModule.recordMessage).SetAllowBot(true), messageIndexHandlerGroup)
 }
 
 func init() {
 	RegisterLegacyModule("Purges", 90, LoadPurges)
 	RegisterAnonymousAdminHandler("purge", purgesModule.purge)
 	RegisterAnonymousAdminHandler("del", purgesModule.delCmd)
+	RegisterAnonymousAdminHandler("purgeuser", purgesModule.purgeUser)
+	RegisterAnonymousAdminHandler("purgebots", purgesModule.purgeBots)
+	RegisterAnonymousAdminHandler("purgemedia", purgesModule.purgeMedia)
 }
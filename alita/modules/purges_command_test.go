@@ -74,7 +74,7 @@ func TestPurgeMsgsConcurrentHandlesDeleteBoundariesAndErrors(t *testing.T) {
 			wantDelete: 1,
 		},
 		{
-			name:       "large ranges use worker deletion path",
+			name:       "large ranges are deleted in batches",
 			pFrom:      true,
 			msgID:      1,
 			deleteTo:   12,
@@ -96,8 +96,8 @@ func TestPurgeMsgsConcurrentHandlesDeleteBoundariesAndErrors(t *testing.T) {
 			if got != tt.wantOK {
 				t.Fatalf("purgeMsgsConcurrent() = %v, want %v", got, tt.wantOK)
 			}
-			if calls := client.callsFor("deleteMessage"); len(calls) != tt.wantDelete {
-				t.Fatalf("deleteMessage calls = %d, want %d", len(calls), tt.wantDelete)
+			if ids := client.deletedMessageIDs(); len(ids) != tt.wantDelete {
+				t.Fatalf("deleted messages = %d, want %d", len(ids), tt.wantDelete)
 			}
 			if calls := client.callsFor("sendMessage"); len(calls) != tt.wantSends {
 				t.Fatalf("sendMessage calls = %d, want %d", len(calls), tt.wantSends)
@@ -124,8 +124,8 @@ func TestPurgeRequiresReplyAndDeletesRange(t *testing.T) {
 	if err := purgesModule.purge(bot, replyCtx); err != ext.EndGroups {
 		t.Fatalf("purge reply error = %v, want EndGroups", err)
 	}
-	if calls := client.callsFor("deleteMessage"); len(calls) < 5 {
-		t.Fatalf("deleteMessage calls = %d, want range and command deletions", len(calls))
+	if ids := client.deletedMessageIDs(); len(ids) < 5 {
+		t.Fatalf("deleted messages = %d, want range and command deletions", len(ids))
 	}
 	if calls := client.callsFor("sendMessage"); len(calls) < 2 {
 		t.Fatalf("sendMessage calls = %d, want purge notification", len(calls))
@@ -210,8 +210,8 @@ func TestPurgeFromToDeletesMarkedRange(t *testing.T) {
 	if _, ok := delMsgs.Load(chat.Id); ok {
 		t.Fatal("purge marker was not cleared after purgeTo")
 	}
-	if calls := client.callsFor("deleteMessage"); len(calls) < 6 {
-		t.Fatalf("deleteMessage calls = %d, want marker, range, and command deletions", len(calls))
+	if ids := client.deletedMessageIDs(); len(ids) < 6 {
+		t.Fatalf("deleted messages = %d, want marker, range, and command deletions", len(ids))
 	}
 }
 
@@ -310,3 +310,29 @@ func TestDeleteButtonHandlerReturnsDeleteFailure(t *testing.T) {
 		t.Fatalf("answerCallbackQuery calls = %d, want no success answer", len(calls))
 	}
 }
+
+func TestLongPurgeReportsProgressInOneMessage(t *testing.T) {
+	oldInterval := purgeProgressInterval
+	purgeProgressInterval = 0
+	t.Cleanup(func() { purgeProgressInterval = oldInterval })
+
+	client := newModuleBotClient()
+	bot := newModuleTestBot(client)
+	chat := gotgbot.Chat{Id: uniqueModuleChatID(), Type: "supergroup", Title: "Purge Chat"}
+
+	if ok := purgesModule.purgeMsgsConcurrent(bot, &chat, true, 1, 350); !ok {
+		t.Fatal("purgeMsgsConcurrent() = false, want true")
+	}
+	if calls := client.callsFor("deleteMessages"); len(calls) != 4 {
+		t.Fatalf("deleteMessages calls = %d, want 4 batches of up to 100", len(calls))
+	}
+	if calls := client.callsFor("sendMessage"); len(calls) != 1 {
+		t.Fatalf("sendMessage calls = %d, want one status message", len(calls))
+	}
+	if calls := client.callsFor("editMessageText"); len(calls) != 2 {
+		t.Fatalf("editMessageText calls = %d, want the status updated after later batches", len(calls))
+	}
+	if ids := client.deletedMessageIDs(); len(ids) != 351 {
+		t.Fatalf("deleted messages = %d, want the range and the status message", len(ids))
+	}
+}
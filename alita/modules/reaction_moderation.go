@@ -0,0 +1,413 @@
+package modules
+
+import (
+	"encoding/json"
+	"fmt"
+	"html"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/PaulSonOfLars/gotgbot/v2"
+	"github.com/PaulSonOfLars/gotgbot/v2/ext"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/divkix/Alita_Robot/alita/db/karma"
+	"github.com/divkix/Alita_Robot/alita/db/lang"
+	"github.com/divkix/Alita_Robot/alita/db/models"
+	"github.com/divkix/Alita_Robot/alita/db/reactions"
+	"github.com/divkix/Alita_Robot/alita/i18n"
+	"github.com/divkix/Alita_Robot/alita/utils/cache"
+	"github.com/divkix/Alita_Robot/alita/utils/chat_status"
+	"github.com/divkix/Alita_Robot/alita/utils/formatting"
+)
+
+const (
+	maxReactionActions   = 20
+	minReactionActionTTL = 30                      // seconds; Telegram makes shorter mutes and bans permanent
+	reactModAuthorTTL    = 48 * time.Hour          // bots cannot delete messages older than this
+	reactModAuthorPrefix = "alita:reactmod:author" // format: reactmod:author:<chat>:<message> -> reactModAuthor
+)
+
+// reactModActions are the actions a reaction can be mapped to. They match
+// the moderation commands of the same name: the d-variants delete the
+// message too.
+var reactModActions = []string{"delete", "mute", "dmute", "ban", "dban", "kick", "dkick", "warn", "dwarn"}
+
+// reactModAuthor is the sender of a message, kept so that a later reaction
+// to the message can be acted on: reaction updates do not say who wrote the
+// message.
+type reactModAuthor struct {
+	SenderID int64  `json:"s"`
+	ThreadID int64  `json:"t,omitempty"`
+	Name     string `json:"n,omitempty"`
+	Bot      bool   `json:"b,omitempty"`
+}
+
+func reactModAuthorKey(chatID, messageID int64) string {
+	return fmt.Sprintf("%s:%d:%d", reactModAuthorPrefix, chatID, messageID)
+}
+
+// tracksMessageAuthors reports whether reactions in a chat need to know who
+// wrote a message: to moderate it, or to give its author karma.
+func tracksMessageAuthors(chatID int64) bool {
+	if len(reactions.GetReactionActions(chatID)) > 0 {
+		return true
+	}
+	settings := karma.GetKarmaSettings(chatID)
+	return settings.Enabled && settings.Reactions
+}
+
+// rememberReactModAuthor records the sender of a message in chats that map
+// reactions to moderation actions or give karma for reactions. Authors are
+// kept in Redis only, for reactModAuthorTTL; without Redis, or once the key
+// expired, reactions can delete a message but not act on its author.
+func rememberReactModAuthor(chat *gotgbot.Chat, msg *gotgbot.Message) {
+	if chat.Type == "private" || !cache.IsRedisAvailable() || !tracksMessageAuthors(chat.Id) {
+		return
+	}
+	sender := msg.GetSender()
+	if sender == nil || sender.Id() == 0 {
+		return
+	}
+	author := reactModAuthor{SenderID: sender.Id(), Name: sender.Name(), Bot: sender.User != nil && sender.User.IsBot}
+	if msg.IsTopicMessage {
+		author.ThreadID = msg.MessageThreadId
+	}
+	raw, err := json.Marshal(author)
+	if err != nil {
+		return
+	}
+	if err := cache.GetRedisClient().Set(cache.Context, reactModAuthorKey(chat.Id, msg.MessageId), raw, reactModAuthorTTL).Err(); err != nil {
+		log.Debugf("[ReactMod] Failed to remember the author of message %d in chat %d: %v", msg.MessageId, chat.Id, err)
+	}
+}
+
+// reactModAuthorOf returns the recorded sender of a message.
+func reactModAuthorOf(chatID, messageID int64) (reactModAuthor, bool) {
+	var author reactModAuthor
+	if !cache.IsRedisAvailable() {
+		return author, false
+	}
+	raw, err := cache.GetRedisClient().Get(cache.Context, reactModAuthorKey(chatID, messageID)).Bytes()
+	if err != nil || json.Unmarshal(raw, &author) != nil || author.SenderID == 0 {
+		return author, false
+	}
+	return author, true
+}
+
+// forgetReactModAuthor drops the sender of a message once it was acted on,
+// so taking a reaction back and setting it again does not act twice.
+func forgetReactModAuthor(chatID, messageID int64) {
+	if cache.IsRedisAvailable() {
+		_ = cache.GetRedisClient().Del(cache.Context, reactModAuthorKey(chatID, messageID)).Err()
+	}
+}
+
+// reactionKey returns the reaction a Telegram reaction type stands for,
+// without the variation selectors stored rules leave out. Custom emoji are
+// keyed by ID alone. Paid reactions have no key.
+func reactionKey(reaction gotgbot.ReactionType) (models.ReactionEmoji, bool) {
+	switch r := reaction.(type) {
+	case gotgbot.ReactionTypeEmoji:
+		return models.ReactionEmoji{Emoji: strings.ReplaceAll(r.Emoji, "\ufe0f", "")}, true
+	case gotgbot.ReactionTypeCustomEmoji:
+		return models.ReactionEmoji{CustomEmojiID: r.CustomEmojiId}, true
+	}
+	return models.ReactionEmoji{}, false
+}
+
+// sameReaction reports whether two reactions are the same: custom emoji by
+// ID, others by emoji.
+func sameReaction(a, b models.ReactionEmoji) bool {
+	if a.CustomEmojiID != "" || b.CustomEmojiID != "" {
+		return a.CustomEmojiID == b.CustomEmojiID
+	}
+	return a.Emoji == b.Emoji
+}
+
+// addedReactions returns the reactions of an update that were not there
+// before.
+func addedReactions(update *gotgbot.MessageReactionUpdated) []models.ReactionEmoji {
+	return reactionsNotIn(update.NewReaction, update.OldReaction)
+}
+
+// removedReactions returns the reactions an update took away.
+func removedReactions(update *gotgbot.MessageReactionUpdated) []models.ReactionEmoji {
+	return reactionsNotIn(update.OldReaction, update.NewReaction)
+}
+
+// reactionsNotIn returns the reactions of list that are not in other.
+func reactionsNotIn(list, other []gotgbot.ReactionType) []models.ReactionEmoji {
+	seen := make(map[models.ReactionEmoji]bool, len(other))
+	for _, reaction := range other {
+		if emoji, ok := reactionKey(reaction); ok {
+			seen[emoji] = true
+		}
+	}
+	var missing []models.ReactionEmoji
+	for _, reaction := range list {
+		if emoji, ok := reactionKey(reaction); ok && !seen[emoji] {
+			missing = append(missing, emoji)
+		}
+	}
+	return missing
+}
+
+// reactionActionFor returns the action mapped to the first of the added
+// reactions that has one.
+func reactionActionFor(actions []*models.ReactionAction, added []models.ReactionEmoji) *models.ReactionAction {
+	for _, emoji := range added {
+		for _, action := range actions {
+			if sameReaction(emoji, models.ReactionEmoji{Emoji: action.Emoji, CustomEmojiID: action.CustomEmojiID}) {
+				return action
+			}
+		}
+	}
+	return nil
+}
+
+// moderateByReaction acts on the author of a message when an admin reacts
+// to it with a reaction the chat mapped to a moderation action. Reactions
+// from members, and from admins lacking the rights the action needs, are
+// ignored silently.
+func (m moduleStruct) moderateByReaction(b *gotgbot.Bot, ctx *ext.Context) error {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Errorf("[ReactMod][moderateByReaction] Recovered from panic: %v", r)
+		}
+	}()
+
+	update := ctx.MessageReaction
+	chat := &update.Chat
+	if chat.Type == "private" || chat.Type == "channel" {
+		return ext.ContinueGroups
+	}
+	actions := reactions.GetReactionActions(chat.Id)
+	if len(actions) == 0 {
+		return ext.ContinueGroups
+	}
+	action := reactionActionFor(actions, addedReactions(update))
+	if action == nil {
+		return ext.ContinueGroups
+	}
+
+	// An anonymous admin reacts as the chat itself. Telegram does not say
+	// which admin it was, so like anonymous admin commands elsewhere it is
+	// treated as an admin with every right; the rights checks below would
+	// only look up the chat ID.
+	sender := update.GetSender()
+	anonAdmin := sender.IsAnonymousAdmin()
+	if !anonAdmin && (sender.User == nil || !chat_status.IsUserAdmin(b, chat.Id, sender.Id())) {
+		return ext.ContinueGroups
+	}
+	deletes := strings.HasPrefix(action.Action, "d") // delete and the d-variants
+	restricts := action.Action != "delete"
+	if deletes && (!anonAdmin && !chat_status.CanUserDelete(b, ctx, chat, sender.Id()) || !chat_status.CanBotDelete(b, ctx, chat)) {
+		return ext.ContinueGroups
+	}
+	if restricts && (!anonAdmin && !chat_status.CanUserRestrict(b, ctx, chat, sender.Id()) || !chat_status.CanBotRestrict(b, ctx, chat)) {
+		return ext.ContinueGroups
+	}
+
+	author, known := reactModAuthorOf(chat.Id, update.MessageId)
+	if restricts {
+		if !known {
+			log.Debugf("[ReactMod] Unknown author of message %d in chat %d", update.MessageId, chat.Id)
+			return ext.ContinueGroups
+		}
+		if author.SenderID == b.Id || chat_status.IsUserBanProtected(b, ctx, chat, author.SenderID) {
+			return ext.ContinueGroups
+		}
+	}
+	if known {
+		forgetReactModAuthor(chat.Id, update.MessageId)
+	}
+	if deletes {
+		deleteMessageIDs(b, chat, []int64{update.MessageId})
+	}
+	if !restricts {
+		return ext.ContinueGroups
+	}
+
+	if err := applyReactionAction(b, ctx, action, author); err != nil {
+		log.Errorf("[ReactMod] Failed to %s user %d in chat %d: %v", action.Action, author.SenderID, chat.Id, err)
+	}
+	return ext.ContinueGroups
+}
+
+// applyReactionAction mutes, bans, kicks or warns the author of the reacted
+// message through the same implementations as /mute, /ban, /kick and /warn,
+// and announces it in the message's topic.
+func applyReactionAction(b *gotgbot.Bot, ctx *ext.Context, action *models.ReactionAction, author reactModAuthor) error {
+	update := ctx.MessageReaction
+	chat := &update.Chat
+	tr := i18n.MustNewTranslator(lang.GetLanguage(ctx))
+	c := &moderationCtx{Bot: b, Chat: chat, User: update.User, Ctx: ctx, Tr: tr, Module: &bansModule}
+	t := &target{userID: author.SenderID, isChannel: chat_status.IsChannelId(author.SenderID)}
+	if action.DurationSeconds > 0 {
+		t.until = time.Now().Add(time.Duration(action.DurationSeconds) * time.Second).Unix()
+	}
+
+	var (
+		noticeKey string
+		err       error
+	)
+	switch strings.TrimPrefix(action.Action, "d") {
+	case "mute":
+		if t.isChannel {
+			return nil
+		}
+		err = muteTarget(c, t)
+		noticeKey = "reactmod_muted"
+	case "ban":
+		err = banTarget(c, t)
+		noticeKey = "reactmod_banned"
+	case "kick":
+		if t.isChannel {
+			return nil
+		}
+		err = kickTarget(c, t)
+		noticeKey = "reactmod_kicked"
+	case "warn":
+		if t.isChannel {
+			return nil
+		}
+		// warnThisUser replies to the message of the update; point it at
+		// the reacted message, which is gone for dwarn.
+		warnCtx := *ctx
+		warnCtx.EffectiveMessage = &gotgbot.Message{MessageId: update.MessageId, Chat: update.Chat, MessageThreadId: author.ThreadID}
+		return warnsModule.warnThisUser(b, &warnCtx, author.SenderID, "", "warn")
+	}
+	if err != nil {
+		return err
+	}
+
+	admin := html.EscapeString(chat.Title)
+	if sender := update.GetSender(); sender.User != nil {
+		admin = formatting.MentionHtml(sender.User.Id, sender.User.FirstName)
+	}
+	params := i18n.TranslationParams{
+		"admin": admin,
+		"user":  formatting.MentionHtml(author.SenderID, author.Name),
+	}
+	if t.until != 0 {
+		noticeKey += "_for"
+		params["duration"] = formatDuration(action.DurationSeconds)
+	}
+	text, _ := tr.GetString(noticeKey, params)
+	opts := formatting.Shtml()
+	opts.MessageThreadId = author.ThreadID
+	if !strings.HasPrefix(action.Action, "d") {
+		opts.ReplyParameters = &gotgbot.ReplyParameters{MessageId: update.MessageId, AllowSendingWithoutReply: true}
+	}
+	_, err = b.SendMessage(chat.Id, text, opts)
+	return err
+}
+
+// reactMod handles /reactmod, which lists, maps and unmaps the reactions
+// admins can moderate messages with.
+func (m moduleStruct) reactMod(b *gotgbot.Bot, ctx *ext.Context) error {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Errorf("[ReactMod][reactMod] Recovered from panic: %v", r)
+		}
+	}()
+
+	msg := ctx.EffectiveMessage
+	chat := ctx.EffectiveChat
+	user := chat_status.RequireUser(b, ctx)
+	if user == nil {
+		return ext.EndGroups
+	}
+	if !chat_status.RequireGroup(b, ctx, chat) {
+		return ext.EndGroups
+	}
+	tr := i18n.MustNewTranslator(lang.GetLanguage(ctx))
+	reply := func(key string, params i18n.TranslationParams) error {
+		text, _ := tr.GetString(key, params)
+		_, err := msg.Reply(b, text, formatting.Shtml())
+		if err != nil {
+			log.Error(err)
+			return err
+		}
+		return ext.EndGroups
+	}
+
+	args := reactionArgs(msg)[1:]
+	if len(args) == 0 {
+		actions := reactions.GetReactionActions(chat.Id)
+		if len(actions) == 0 {
+			return reply("reactmod_none", nil)
+		}
+		var sb strings.Builder
+		for _, action := range actions {
+			fmt.Fprintf(&sb, "• %s → %s\n", formatReactionEmoji(models.ReactionEmoji{Emoji: action.Emoji, CustomEmojiID: action.CustomEmojiID}), formatReactionAction(action))
+		}
+		return reply("reactmod_list_header", i18n.TranslationParams{"list": sb.String()})
+	}
+
+	if !chat_status.CanUserChangeInfo(b, ctx, chat, user.Id) {
+		chat_status.NewPermissionResponder(b).Respond(ctx, "chat_status_change_info_cmd_error", "chat_status_change_info_button_error")
+		return ext.EndGroups
+	}
+
+	if strings.EqualFold(args[0], "reset") {
+		if err := reactions.ResetReactionActions(chat.Id); err != nil {
+			return reply("reactmod_save_error", nil)
+		}
+		return reply("reactmod_reset_success", nil)
+	}
+
+	emoji, ok := parseReactionEmoji(args[0])
+	if !ok {
+		return reply("reactions_invalid_emoji", nil)
+	}
+	if len(args) < 2 || len(args) > 3 {
+		return reply("reactmod_usage", nil)
+	}
+	params := i18n.TranslationParams{"emoji": formatReactionEmoji(emoji)}
+
+	name := strings.ToLower(args[1])
+	if name == "off" {
+		removed, err := reactions.RemoveReactionAction(chat.Id, emoji.Emoji, emoji.CustomEmojiID)
+		if err != nil {
+			return reply("reactmod_save_error", nil)
+		}
+		if !removed {
+			return reply("reactmod_not_found", params)
+		}
+		return reply("reactmod_removed", params)
+	}
+	if !slices.Contains(reactModActions, name) {
+		return reply("reactmod_invalid_action", i18n.TranslationParams{"actions": "<code>" + strings.Join(reactModActions, "</code>, <code>") + "</code>"})
+	}
+	action := &models.ReactionAction{ChatID: chat.Id, Emoji: emoji.Emoji, CustomEmojiID: emoji.CustomEmojiID, Action: name}
+	if len(args) == 3 {
+		seconds, ok := parseDuration(args[2])
+		if !ok || seconds < minReactionActionTTL || !strings.HasSuffix(name, "mute") && !strings.HasSuffix(name, "ban") {
+			return reply("reactmod_invalid_duration", nil)
+		}
+		action.DurationSeconds = seconds
+	}
+
+	existing := reactions.GetReactionActions(chat.Id)
+	if len(existing) >= maxReactionActions && reactionActionFor(existing, []models.ReactionEmoji{emoji}) == nil {
+		return reply("reactmod_too_many", i18n.TranslationParams{"max": maxReactionActions})
+	}
+	if err := reactions.SetReactionAction(action); err != nil {
+		return reply("reactmod_save_error", nil)
+	}
+	params["action"] = formatReactionAction(action)
+	return reply("reactmod_set_success", params)
+}
+
+// formatReactionAction renders an action with its duration, like the
+// command an admin would type.
+func formatReactionAction(action *models.ReactionAction) string {
+	text := action.Action
+	if action.DurationSeconds > 0 {
+		text += " " + formatDuration(action.DurationSeconds)
+	}
+	return "<code>" + html.EscapeString(text) + "</code>"
+}
@@ -0,0 +1,175 @@
+package modules
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/PaulSonOfLars/gotgbot/v2"
+	"github.com/PaulSonOfLars/gotgbot/v2/ext"
+
+	"github.com/divkix/Alita_Robot/alita/db/models"
+	"github.com/divkix/Alita_Robot/alita/db/reactions"
+)
+
+func TestReactModCommandManagesActions(t *testing.T) {
+	client := newModuleBotClient()
+	bot := newModuleTestBot(client)
+	chat := gotgbot.Chat{Id: uniqueModuleChatID(), Type: "supergroup", Title: "ReactMod Chat"}
+	admin := gotgbot.User{Id: 777000, FirstName: "Telegram"}
+	member := gotgbot.User{Id: 42, FirstName: "Member"}
+	t.Cleanup(func() { _ = reactions.ResetReactionActions(chat.Id) })
+
+	run := func(from gotgbot.User, text string) {
+		t.Helper()
+		if err := reactionsModule.reactMod(bot, newModuleMessageContext(bot, chat, from, text)); err != ext.EndGroups {
+			t.Fatalf("reactMod(%q) error = %v, want EndGroups", text, err)
+		}
+	}
+
+	run(admin, "/reactmod 🤬 DMUTE 1h")
+	run(admin, "/reactmod 💩 delete")
+	actions := reactions.GetReactionActions(chat.Id)
+	if len(actions) != 2 || actions[0].Action != "dmute" || actions[0].DurationSeconds != 3600 || actions[1].Action != "delete" {
+		t.Fatalf("stored actions = %+v, want a 1h dmute on 🤬 and delete on 💩", actions)
+	}
+
+	for _, text := range []string{
+		"/reactmod 🤬 ban 10s",      // shorter than Telegram allows
+		"/reactmod 💩 kick 1h",      // kicks take no duration
+		"/reactmod 💩 explode",      // unknown action
+		"/reactmod 🗑 delete",       // not a reaction emoji
+		"/reactmod 💩 ban 1h extra", // too many arguments
+	} {
+		run(admin, text)
+	}
+	run(member, "/reactmod 💩 ban")
+	actions = reactions.GetReactionActions(chat.Id)
+	if len(actions) != 2 || actions[0].Action != "dmute" || actions[1].Action != "delete" {
+		t.Fatalf("actions after invalid input = %+v, want them unchanged", actions)
+	}
+
+	run(member, "/reactmod")
+	run(admin, "/reactmod 🤬 off")
+	if actions := reactions.GetReactionActions(chat.Id); len(actions) != 1 || actions[0].Emoji != "💩" {
+		t.Fatalf("actions after off = %+v, want only 💩", actions)
+	}
+	run(admin, "/reactmod reset")
+	if actions := reactions.GetReactionActions(chat.Id); len(actions) != 0 {
+		t.Fatalf("actions after reset = %+v, want none", actions)
+	}
+}
+
+func TestModerateByReactionActsOnTheAuthor(t *testing.T) {
+	withMiniredis(t)
+
+	client := newModuleBotClient()
+	bot := newModuleTestBot(client)
+	dispatcher := ext.NewDispatcher(&ext.DispatcherOpts{MaxRoutines: -1})
+	LoadReactions(dispatcher)
+
+	chat := gotgbot.Chat{Id: uniqueModuleChatID(), Type: "supergroup", Title: "ReactMod Chat"}
+	admin := &gotgbot.User{Id: 777000, FirstName: "Telegram"}
+	member := &gotgbot.User{Id: 42, FirstName: "Member"}
+	t.Cleanup(func() { _ = reactions.ResetReactionActions(chat.Id) })
+	for _, action := range []*models.ReactionAction{
+		{ChatID: chat.Id, Emoji: "💩", Action: "delete"},
+		{ChatID: chat.Id, Emoji: "🤬", Action: "dban", DurationSeconds: 3600},
+	} {
+		if err := reactions.SetReactionAction(action); err != nil {
+			t.Fatalf("SetReactionAction() error = %v", err)
+		}
+	}
+
+	updateID := int64(0)
+	process := func(update *gotgbot.Update) {
+		t.Helper()
+		updateID++
+		update.UpdateId = updateID
+		if err := dispatcher.ProcessUpdate(bot, update, nil); err != nil {
+			t.Fatalf("ProcessUpdate() error = %v", err)
+		}
+	}
+	send := func(id int64, from *gotgbot.User) {
+		process(&gotgbot.Update{Message: &gotgbot.Message{MessageId: id, Date: 1, Chat: chat, From: from, Text: "hi"}})
+	}
+	react := func(id int64, from *gotgbot.User, emoji string) {
+		process(&gotgbot.Update{MessageReaction: &gotgbot.MessageReactionUpdated{
+			Chat: chat, MessageId: id, User: from, Date: 1,
+			NewReaction: []gotgbot.ReactionType{gotgbot.ReactionTypeEmoji{Emoji: emoji}},
+		}})
+	}
+
+	send(101, member)
+	send(102, admin)
+
+	// Members cannot moderate, and unmapped reactions do nothing.
+	react(101, member, "🤬")
+	react(101, admin, "👍")
+	if n := len(client.callsFor("deleteMessage")) + len(client.callsFor("banChatMember")); n != 0 {
+		t.Fatalf("moderation calls = %d, want none", n)
+	}
+
+	react(101, admin, "🤬")
+	deletes := client.callsFor("deleteMessage")
+	bans := client.callsFor("banChatMember")
+	if len(deletes) != 1 || fmt.Sprint(deletes[0].Params["message_id"]) != "101" {
+		t.Fatalf("deleteMessage calls = %+v, want message 101 deleted", deletes)
+	}
+	if len(bans) != 1 || fmt.Sprint(bans[0].Params["user_id"]) != "42" || bans[0].Params["until_date"] == nil {
+		t.Fatalf("banChatMember calls = %+v, want user 42 banned for a while", bans)
+	}
+	if len(client.callsFor("sendMessage")) != 1 {
+		t.Fatalf("sendMessage calls = %d, want the ban announced", len(client.callsFor("sendMessage")))
+	}
+
+	// Reacting again does not act twice, and admins are never banned.
+	react(101, admin, "🤬")
+	react(102, admin, "🤬")
+	if len(client.callsFor("banChatMember")) != 1 {
+		t.Fatalf("banChatMember calls = %d, want still 1", len(client.callsFor("banChatMember")))
+	}
+
+	// Deleting needs no author.
+	react(555, admin, "💩")
+	if deletes := client.callsFor("deleteMessage"); len(deletes) != 2 || fmt.Sprint(deletes[1].Params["message_id"]) != "555" {
+		t.Fatalf("deleteMessage calls = %+v, want message 555 deleted", deletes)
+	}
+}
+
+func TestModerateByReactionTreatsAnonymousAdminAsAdmin(t *testing.T) {
+	withMiniredis(t)
+
+	client := newModuleBotClient()
+	bot := newModuleTestBot(client)
+	dispatcher := ext.NewDispatcher(&ext.DispatcherOpts{MaxRoutines: -1})
+	LoadReactions(dispatcher)
+
+	chat := gotgbot.Chat{Id: uniqueModuleChatID(), Type: "supergroup", Title: "ReactMod Chat"}
+	member := &gotgbot.User{Id: 42, FirstName: "Member"}
+	t.Cleanup(func() { _ = reactions.ResetReactionActions(chat.Id) })
+	if err := reactions.SetReactionAction(&models.ReactionAction{ChatID: chat.Id, Emoji: "🤬", Action: "mute", DurationSeconds: 3600}); err != nil {
+		t.Fatalf("SetReactionAction() error = %v", err)
+	}
+
+	if err := dispatcher.ProcessUpdate(bot, &gotgbot.Update{UpdateId: 1, Message: &gotgbot.Message{
+		MessageId: 101, Date: 1, Chat: chat, From: member, Text: "hi",
+	}}, nil); err != nil {
+		t.Fatalf("ProcessUpdate() error = %v", err)
+	}
+	if err := dispatcher.ProcessUpdate(bot, &gotgbot.Update{UpdateId: 2, MessageReaction: &gotgbot.MessageReactionUpdated{
+		Chat: chat, MessageId: 101, ActorChat: &chat, Date: 1,
+		NewReaction: []gotgbot.ReactionType{gotgbot.ReactionTypeEmoji{Emoji: "🤬"}},
+	}}, nil); err != nil {
+		t.Fatalf("ProcessUpdate() error = %v", err)
+	}
+
+	for _, call := range client.callsFor("getChatMember") {
+		if fmt.Sprint(call.Params["user_id"]) == fmt.Sprint(chat.Id) {
+			t.Fatalf("getChatMember called with the chat ID: %+v", call.Params)
+		}
+	}
+	mutes := client.callsFor("restrictChatMember")
+	if len(mutes) != 1 || fmt.Sprint(mutes[0].Params["user_id"]) != "42" || mutes[0].Params["until_date"] == nil {
+		t.Fatalf("restrictChatMember calls = %+v, want user 42 muted for an hour", mutes)
+	}
+}
@@ -3,8 +3,13 @@ package modules
 import (
 	"fmt"
 	"html"
+	"math/rand"
 	"slices"
+	"sort"
+	"strconv"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/PaulSonOfLars/gotgbot/v2"
 	"github.com/PaulSonOfLars/gotgbot/v2/ext"
@@ -14,10 +19,13 @@ import (
 	log "github.com/sirupsen/logrus"
 
 	"github.com/divkix/Alita_Robot/alita/db/lang"
+	"github.com/divkix/Alita_Robot/alita/db/models"
 	"github.com/divkix/Alita_Robot/alita/db/reactions"
 	"github.com/divkix/Alita_Robot/alita/i18n"
 	"github.com/divkix/Alita_Robot/alita/utils/chat_status"
 	"github.com/divkix/Alita_Robot/alita/utils/formatting"
+	"github.com/divkix/Alita_Robot/alita/utils/helpers"
+	"github.com/divkix/Alita_Robot/alita/utils/keyword_matcher"
 )
 
 var reactionsModule = moduleStruct{
@@ -35,17 +43,34 @@ var supportedReactionEmoji = []string{
 	"🤷‍♀", "😡",
 }
 
+const (
+	// maxReactionsPerKeyword caps the reactions a keyword picks from.
+	maxReactionsPerKeyword = 10
+	// maxReactionCooldownSeconds is the longest cooldown /addreaction accepts.
+	maxReactionCooldownSeconds = 24 * 60 * 60
+	// customEmojiArg marks a custom emoji in the arguments of /addreaction,
+	// followed by its ID, a colon and the emoji it stands in for.
+	customEmojiArg = "\x00custom_emoji:"
+)
+
+// reactionCooldowns holds when the bot last reacted to a keyword, keyed by
+// chat ID and keyword.
+var reactionCooldowns = &sync.Map{}
+
 // LoadReactions loads the reactions module with all command handlers
 func LoadReactions(dispatcher *ext.Dispatcher) {
 	// Admin commands
-	dispatcher.AddHandler(handlers.NewCommand("addreaction", reactionsModule.addReaction))
-	dispatcher.AddHandler(handlers.NewCommand("removereaction", reactionsModule.removeReaction))
-	dispatcher.AddHandler(handlers.NewCommand("reactions", reactionsModule.listReactions))
-	dispatcher.AddHandler(handlers.NewCommand("resetreactions", reactionsModule.resetReactions))
+	helpers.AddCommand(dispatcher, "addreaction", reactionsModule.addReaction)
+	helpers.AddCommand(dispatcher, "removereaction", reactionsModule.removeReaction)
+	helpers.AddCommand(dispatcher, "reactions", reactionsModule.listReactions)
+	helpers.AddCommand(dispatcher, "resetreactions", reactionsModule.resetReactions)
+	helpers.AddCommand(dispatcher, "reactmod", reactionsModule.reactMod)
 	dispatcher.AddHandler(handlers.NewCallback(callbackquery.Prefix("reactions_help"), reactionsModule.reactionsHelpHandler))
 
 	// Message watcher for reactions (positive handler group for monitoring)
 	dispatcher.AddHandlerToGroup(handlers.NewMessage(message.All, reactionsModule.checkReactions), reactionsModule.handlerGroup)
+	// Admin reactions mapped to moderation actions
+	dispatcher.AddHandlerToGroup(handlers.NewReaction(nil, reactionsModule.moderateByReaction), reactionsModule.handlerGroup)
 
 	// Register module as disableable
 	DefaultHelpRegistry().AbleMap[reactionsModule.moduleName] = true
@@ -172,12 +197,13 @@ func (m moduleStruct) addReaction(b *gotgbot.Bot, ctx *ext.Context) error {
 	}
 
 	keyword := strings.ToLower(strings.TrimSpace(args[1]))
-	emoji := strings.ReplaceAll(strings.TrimSpace(args[2]), "\ufe0f", "")
-
-	// ReactionTypeEmoji accepts only Telegram's documented reaction set.
-	if !slices.Contains(supportedReactionEmoji, emoji) {
+	rule, errKey := parseReactionRule(reactionArgs(msg)[2:])
+	if errKey != "" {
 		tr := i18n.MustNewTranslator(lang.GetLanguage(ctx))
-		text, _ := tr.GetString("reactions_invalid_emoji")
+		text, _ := tr.GetString(errKey, i18n.TranslationParams{
+			"max":      maxReactionsPerKeyword,
+			"cooldown": formatDuration(maxReactionCooldownSeconds),
+		})
 		_, err := msg.Reply(b, text, formatting.Shtml())
 		if err != nil {
 			log.Error(err)
@@ -185,20 +211,23 @@ func (m moduleStruct) addReaction(b *gotgbot.Bot, ctx *ext.Context) error {
 		}
 		return ext.EndGroups
 	}
+	rule.ChatID = chat.Id
+	rule.Keyword = keyword
 
 	// Store in DB (cache is invalidated by the repository).
-	if err := reactions.AddReaction(chat.Id, keyword, emoji); err != nil {
+	if err := reactions.AddReaction(rule); err != nil {
 		log.Errorf("[Reactions] Failed to save reaction: %v", err)
 		tr := i18n.MustNewTranslator(lang.GetLanguage(ctx))
 		text, _ := tr.GetString("reactions_add_error")
 		_, _ = msg.Reply(b, text, formatting.Shtml())
 		return ext.EndGroups
 	}
+	reactionCooldowns.Delete(reactionCooldownKey{chat.Id, keyword})
 
 	tr := i18n.MustNewTranslator(lang.GetLanguage(ctx))
 	text, _ := tr.GetString("reactions_add_success", i18n.TranslationParams{
 		"keyword": html.EscapeString(keyword),
-		"emoji":   html.EscapeString(emoji),
+		"emoji":   formatReactionRule(tr, rule),
 	})
 	_, err := msg.Reply(b, text, formatting.Shtml())
 	if err != nil {
@@ -265,6 +294,8 @@ func (m moduleStruct) removeReaction(b *gotgbot.Bot, ctx *ext.Context) error {
 		return ext.EndGroups
 	}
 
+	reactionCooldowns.Delete(reactionCooldownKey{chat.Id, keyword})
+
 	tr := i18n.MustNewTranslator(lang.GetLanguage(ctx))
 	text, _ := tr.GetString("reactions_remove_success", i18n.TranslationParams{
 		"keyword": html.EscapeString(keyword),
@@ -298,12 +329,17 @@ func (m moduleStruct) listReactions(b *gotgbot.Bot, ctx *ext.Context) error {
 	}
 
 	// Build list
+	tr := i18n.MustNewTranslator(lang.GetLanguage(ctx))
+	keywords := make([]string, 0, len(reactionsMap))
+	for keyword := range reactionsMap {
+		keywords = append(keywords, keyword)
+	}
+	sort.Strings(keywords)
 	var sb strings.Builder
-	for keyword, emoji := range reactionsMap {
-		fmt.Fprintf(&sb, "• %s → %s\n", html.EscapeString(keyword), html.EscapeString(emoji))
+	for _, keyword := range keywords {
+		fmt.Fprintf(&sb, "• %s → %s\n", html.EscapeString(keyword), formatReactionRule(tr, reactionsMap[keyword]))
 	}
 
-	tr := i18n.MustNewTranslator(lang.GetLanguage(ctx))
 	text, _ := tr.GetString("reactions_list_header", i18n.TranslationParams{
 		"list": sb.String(),
 	})
@@ -366,12 +402,12 @@ func (m moduleStruct) checkReactions(b *gotgbot.Bot, ctx *ext.Context) error {
 	}()
 
 	msg := ctx.EffectiveMessage
-	if msg == nil || msg.Text == "" {
+	chat := ctx.EffectiveChat
+	if msg == nil || chat == nil {
 		return ext.ContinueGroups
 	}
-
-	chat := ctx.EffectiveChat
-	if chat == nil {
+	rememberReactModAuthor(chat, msg)
+	if msg.Text == "" {
 		return ext.ContinueGroups
 	}
 
@@ -381,30 +417,35 @@ func (m moduleStruct) checkReactions(b *gotgbot.Bot, ctx *ext.Context) error {
 		return ext.ContinueGroups
 	}
 
-	// Check if message text contains any keywords (case-insensitive).
-	// Collect all matching keywords first, then sort them for deterministic selection.
-	lowerText := strings.ToLower(msg.Text)
-	var matchedKeywords []string
+	// Find the first keyword in the message (case-insensitive) whose rule
+	// passes its probability roll and is not cooling down.
+	keywords := make([]string, 0, len(reactionsMap))
 	for keyword := range reactionsMap {
-		if strings.Contains(lowerText, keyword) {
-			matchedKeywords = append(matchedKeywords, keyword)
-		}
+		keywords = append(keywords, keyword)
 	}
-	if len(matchedKeywords) == 0 {
+	sort.Strings(keywords)
+	matcher := keyword_matcher.GetNamedCache("reactions").GetOrCreateMatcher(chat.Id, keywords)
+	keyword, found := matcher.FirstMatchWhere(msg.Text, func(keyword string) bool {
+		rule := reactionsMap[keyword]
+		return rule != nil && len(rule.Emojis) > 0 && reactionRuleFires(chat.Id, rule)
+	})
+	if !found {
 		return ext.ContinueGroups
 	}
-	slices.Sort(matchedKeywords)
-	emoji := reactionsMap[matchedKeywords[0]]
+	rule := reactionsMap[keyword]
+
+	// Bots can set one reaction per message, so pick one of the rule's.
+	emoji := rule.Emojis[rand.Intn(len(rule.Emojis))] // #nosec G404 - Non-cryptographic random is sufficient for picking a reaction
+	var reaction gotgbot.ReactionType = gotgbot.ReactionTypeEmoji{Emoji: emoji.Emoji}
+	if emoji.CustomEmojiID != "" {
+		reaction = gotgbot.ReactionTypeCustomEmoji{CustomEmojiId: emoji.CustomEmojiID}
+	}
 
 	_, err := b.SetMessageReaction(
 		chat.Id,
 		msg.MessageId,
 		&gotgbot.SetMessageReactionOpts{
-			Reaction: []gotgbot.ReactionType{
-				gotgbot.ReactionTypeEmoji{
-					Emoji: emoji,
-				},
-			},
+			Reaction: []gotgbot.ReactionType{reaction},
 		},
 	)
 	if err != nil {
@@ -414,6 +455,126 @@ func (m moduleStruct) checkReactions(b *gotgbot.Bot, ctx *ext.Context) error {
 	return ext.ContinueGroups
 }
 
+// reactionCooldownKey identifies a keyword of a chat in reactionCooldowns.
+type reactionCooldownKey struct {
+	chatID  int64
+	keyword string
+}
+
+// reactionRuleFires rolls the probability of a matching rule and, when it
+// hits, starts the cooldown of the rule. It reports whether the bot should
+// react.
+func reactionRuleFires(chatID int64, rule *models.Reactions) bool {
+	if rule.Probability > 0 && rule.Probability < 100 && rand.Intn(100) >= rule.Probability { // #nosec G404 - Non-cryptographic random is sufficient for a reaction chance
+		return false
+	}
+	if rule.CooldownSeconds <= 0 {
+		return true
+	}
+	return shouldUpdateKey(reactionCooldowns, reactionCooldownKey{chatID, rule.Keyword}, time.Duration(rule.CooldownSeconds)*time.Second)
+}
+
+// reactionArgs splits the text of an /addreaction message like ctx.Args,
+// with each custom emoji replaced by customEmojiArg, its ID, a colon and the
+// emoji it stands in for, so it can be told apart from that emoji.
+func reactionArgs(msg *gotgbot.Message) []string {
+	text := msg.GetText()
+	var sb strings.Builder
+	last := 0
+	for _, entity := range gotgbot.ParseEntityTypes(text, msg.Entities, map[string]struct{}{"custom_emoji": {}}) {
+		start := int(entity.Offset)
+		if start < last || entity.CustomEmojiId == "" {
+			continue
+		}
+		sb.WriteString(text[last:start])
+		sb.WriteString(" " + customEmojiArg + entity.CustomEmojiId + ":" + entity.Text + " ")
+		last = start + int(entity.Length)
+	}
+	sb.WriteString(text[last:])
+	return strings.Fields(sb.String())
+}
+
+// parseReactionRule reads the reactions, chance (like 30%) and cooldown
+// (like 10m) given to /addreaction. On invalid input it returns the
+// translation key of the reply instead.
+func parseReactionRule(args []string) (*models.Reactions, string) {
+	rule := &models.Reactions{Probability: 100}
+	seen := make(map[models.ReactionEmoji]bool)
+	for _, arg := range args {
+		switch {
+		case strings.HasSuffix(arg, "%"):
+			chance, err := strconv.Atoi(strings.TrimSuffix(arg, "%"))
+			if err != nil || chance < 1 || chance > 100 {
+				return nil, "reactions_invalid_chance"
+			}
+			rule.Probability = chance
+			continue
+		case arg[0] >= '0' && arg[0] <= '9':
+			seconds, ok := parseDuration(arg)
+			if !ok || seconds > maxReactionCooldownSeconds {
+				return nil, "reactions_invalid_cooldown"
+			}
+			rule.CooldownSeconds = seconds
+			continue
+		}
+		emoji, ok := parseReactionEmoji(arg)
+		if !ok {
+			return nil, "reactions_invalid_emoji"
+		}
+		if !seen[emoji] {
+			seen[emoji] = true
+			rule.Emojis = append(rule.Emojis, emoji)
+		}
+	}
+	if len(rule.Emojis) == 0 {
+		return nil, "reactions_add_usage"
+	}
+	if len(rule.Emojis) > maxReactionsPerKeyword {
+		return nil, "reactions_too_many"
+	}
+	return rule, ""
+}
+
+// parseReactionEmoji reads a reaction argument as returned by
+// reactionArgs: a custom emoji or one of the emoji Telegram accepts as a
+// reaction.
+func parseReactionEmoji(arg string) (models.ReactionEmoji, bool) {
+	if strings.HasPrefix(arg, customEmojiArg) {
+		id, fallback, _ := strings.Cut(strings.TrimPrefix(arg, customEmojiArg), ":")
+		return models.ReactionEmoji{Emoji: fallback, CustomEmojiID: id}, id != ""
+	}
+	// ReactionTypeEmoji accepts only Telegram's documented reaction set.
+	emoji := models.ReactionEmoji{Emoji: strings.ReplaceAll(arg, "\ufe0f", "")}
+	return emoji, slices.Contains(supportedReactionEmoji, emoji.Emoji)
+}
+
+// formatReactionEmoji renders a reaction, custom emoji included.
+func formatReactionEmoji(emoji models.ReactionEmoji) string {
+	if emoji.CustomEmojiID != "" {
+		return fmt.Sprintf(`<tg-emoji emoji-id="%s">%s</tg-emoji>`, html.EscapeString(emoji.CustomEmojiID), html.EscapeString(emoji.Emoji))
+	}
+	return html.EscapeString(emoji.Emoji)
+}
+
+// formatReactionRule renders the reactions of a rule, followed by its
+// chance and cooldown when set.
+func formatReactionRule(tr *i18n.Translator, rule *models.Reactions) string {
+	parts := make([]string, 0, len(rule.Emojis))
+	for _, emoji := range rule.Emojis {
+		parts = append(parts, formatReactionEmoji(emoji))
+	}
+	text := strings.Join(parts, " ")
+	if rule.Probability > 0 && rule.Probability < 100 {
+		chance, _ := tr.GetString("reactions_rule_chance", i18n.TranslationParams{"chance": rule.Probability})
+		text += " · " + chance
+	}
+	if rule.CooldownSeconds > 0 {
+		cooldown, _ := tr.GetString("reactions_rule_cooldown", i18n.TranslationParams{"cooldown": formatDuration(rule.CooldownSeconds)})
+		text += " · " + cooldown
+	}
+	return text
+}
+
 func init() {
 	RegisterLegacyModule("Reactions", 250, LoadReactions)
 }
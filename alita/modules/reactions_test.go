@@ -1,16 +1,43 @@
 package modules
 
 import (
+	"encoding/json"
+	"strings"
 	"testing"
 
 	"github.com/PaulSonOfLars/gotgbot/v2"
 	"github.com/PaulSonOfLars/gotgbot/v2/ext"
 
 	"github.com/divkix/Alita_Robot/alita/db"
+	"github.com/divkix/Alita_Robot/alita/db/models"
 	"github.com/divkix/Alita_Robot/alita/db/reactions"
 	"github.com/divkix/Alita_Robot/alita/utils/cache"
 )
 
+// addTestReaction stores a rule that always reacts with one emoji.
+func addTestReaction(chatID int64, keyword, emoji string) error {
+	return reactions.AddReaction(&models.Reactions{
+		ChatID:      chatID,
+		Keyword:     keyword,
+		Emojis:      models.ReactionEmojiArray{{Emoji: emoji}},
+		Probability: 100,
+	})
+}
+
+// lastReaction returns the reaction param of the last setMessageReaction call.
+func lastReaction(t *testing.T, client *moduleBotClient) string {
+	t.Helper()
+	calls := client.callsFor("setMessageReaction")
+	if len(calls) == 0 {
+		t.Fatal("no setMessageReaction call")
+	}
+	raw, err := json.Marshal(calls[len(calls)-1].Params["reaction"])
+	if err != nil {
+		t.Fatalf("marshal reaction: %v", err)
+	}
+	return string(raw)
+}
+
 func TestReactionCommandsManageDBRows(t *testing.T) {
 	if db.DB == nil {
 		t.Skip("requires database connection")
@@ -28,8 +55,8 @@ func TestReactionCommandsManageDBRows(t *testing.T) {
 	if err := reactionsModule.addReaction(bot, addCtx); err != ext.EndGroups {
 		t.Fatalf("addReaction() error = %v, want EndGroups", err)
 	}
-	if got := reactions.GetReactions(chat.Id)["hello"]; got != "👍" {
-		t.Fatalf("stored reaction = %q, want 👍", got)
+	if got := reactions.GetReactions(chat.Id)["hello"]; got == nil || len(got.Emojis) != 1 || got.Emojis[0].Emoji != "👍" || got.Probability != 100 {
+		t.Fatalf("stored reaction = %+v, want 👍 always", got)
 	}
 
 	listCtx := newModuleMessageContext(bot, chat, admin, "/reactions")
@@ -45,7 +72,7 @@ func TestReactionCommandsManageDBRows(t *testing.T) {
 		t.Fatalf("reactions remained after removing final reaction: %v", m)
 	}
 
-	_ = reactions.AddReaction(chat.Id, "bye", "👍")
+	_ = addTestReaction(chat.Id, "bye", "👍")
 	resetCtx := newModuleMessageContext(bot, chat, admin, "/resetreactions")
 	if err := reactionsModule.resetReactions(bot, resetCtx); err != ext.EndGroups {
 		t.Fatalf("resetReactions() error = %v, want EndGroups", err)
@@ -90,7 +117,7 @@ func TestReactionCommandsHandleUsageAndMissingEntries(t *testing.T) {
 		})
 	}
 
-	if err := reactions.AddReaction(chat.Id, "hello", "👍"); err != nil {
+	if err := addTestReaction(chat.Id, "hello", "👍"); err != nil {
 		t.Fatalf("seed reaction: %v", err)
 	}
 	missingKeywordCtx := newModuleMessageContext(bot, chat, admin, "/removereaction absent")
@@ -166,7 +193,7 @@ func TestCheckReactionsSetsMessageReactionForMatchingKeyword(t *testing.T) {
 		_ = reactions.ResetReactions(chat.Id)
 	})
 
-	if err := reactions.AddReaction(chat.Id, "hello", "👍"); err != nil {
+	if err := addTestReaction(chat.Id, "hello", "👍"); err != nil {
 		t.Fatalf("seed reaction: %v", err)
 	}
 
@@ -239,8 +266,8 @@ func TestReactionCommandsWorkWithNilCacheMarshal(t *testing.T) {
 	if err := reactionsModule.addReaction(bot, addCtx); err != ext.EndGroups {
 		t.Fatalf("addReaction(nil marshal) error = %v, want EndGroups", err)
 	}
-	if got := reactions.GetReactions(chat.Id)["hello"]; got != "👍" {
-		t.Fatalf("stored reaction with nil marshal = %q, want 👍", got)
+	if got := reactions.GetReactions(chat.Id)["hello"]; got == nil || len(got.Emojis) != 1 || got.Emojis[0].Emoji != "👍" {
+		t.Fatalf("stored reaction with nil marshal = %+v, want 👍", got)
 	}
 
 	listCtx := newModuleMessageContext(bot, chat, admin, "/reactions")
@@ -276,3 +303,102 @@ func TestLoadReactionsRegistersHelpAndHandlers(t *testing.T) {
 		t.Fatalf("reactions help keyboard = %#v, want one row with two buttons", got)
 	}
 }
+
+func TestAddReactionParsesReactionsChanceAndCooldown(t *testing.T) {
+	if db.DB == nil {
+		t.Skip("requires database connection")
+	}
+
+	client := newModuleBotClient()
+	bot := newModuleTestBot(client)
+	chat := gotgbot.Chat{Id: uniqueModuleChatID(), Type: "supergroup", Title: "Reaction Chat"}
+	admin := gotgbot.User{Id: 777000, FirstName: "Telegram"}
+	t.Cleanup(func() {
+		_ = reactions.ResetReactions(chat.Id)
+	})
+
+	// 😎 is a custom emoji here: UTF-16 offset 19, after "/addreaction hi 👍 ".
+	addCtx := newModuleMessageContext(bot, chat, admin, "/addreaction hi 👍 😎 👍 30% 10m")
+	addCtx.EffectiveMessage.Entities = []gotgbot.MessageEntity{
+		{Type: "bot_command", Offset: 0, Length: 12},
+		{Type: "custom_emoji", Offset: 19, Length: 2, CustomEmojiId: "5368324170671202286"},
+	}
+	if err := reactionsModule.addReaction(bot, addCtx); err != ext.EndGroups {
+		t.Fatalf("addReaction() error = %v, want EndGroups", err)
+	}
+	got := reactions.GetReactions(chat.Id)["hi"]
+	want := models.ReactionEmojiArray{{Emoji: "👍"}, {Emoji: "😎", CustomEmojiID: "5368324170671202286"}}
+	if got == nil || len(got.Emojis) != 2 || got.Emojis[0] != want[0] || got.Emojis[1] != want[1] {
+		t.Fatalf("stored reactions = %+v, want %+v", got, want)
+	}
+	if got.Probability != 30 || got.CooldownSeconds != 600 {
+		t.Fatalf("stored rule = %d%% / %ds, want 30%% / 600s", got.Probability, got.CooldownSeconds)
+	}
+
+	for _, text := range []string{
+		"/addreaction hi 👍 150%",
+		"/addreaction hi 👍 2d",
+		"/addreaction hi 👍 pizza",
+		"/addreaction hi 30%",
+		"/addreaction hi ❤ 👍 👎 🔥 🥰 👏 😁 🤔 🤯 😱 🤬",
+	} {
+		ctx := newModuleMessageContext(bot, chat, admin, text)
+		if err := reactionsModule.addReaction(bot, ctx); err != ext.EndGroups {
+			t.Fatalf("addReaction(%q) error = %v, want EndGroups", text, err)
+		}
+		if rule := reactions.GetReactions(chat.Id)["hi"]; rule.Probability != 30 || len(rule.Emojis) != 2 {
+			t.Fatalf("rule changed by invalid %q: %+v", text, rule)
+		}
+	}
+}
+
+func TestCheckReactionsUsesFirstKeywordAndCooldown(t *testing.T) {
+	if db.DB == nil {
+		t.Skip("requires database connection")
+	}
+
+	client := newModuleBotClient()
+	bot := newModuleTestBot(client)
+	chat := gotgbot.Chat{Id: uniqueModuleChatID(), Type: "supergroup", Title: "Reaction Chat"}
+	user := gotgbot.User{Id: 4307, FirstName: "Member"}
+	t.Cleanup(func() {
+		_ = reactions.ResetReactions(chat.Id)
+	})
+
+	if err := addTestReaction(chat.Id, "apple", "🍓"); err != nil {
+		t.Fatalf("seed reaction: %v", err)
+	}
+	if err := reactions.AddReaction(&models.Reactions{
+		ChatID:          chat.Id,
+		Keyword:         "zebra",
+		Emojis:          models.ReactionEmojiArray{{Emoji: "😎", CustomEmojiID: "5368324170671202286"}},
+		Probability:     100,
+		CooldownSeconds: 3600,
+	}); err != nil {
+		t.Fatalf("seed reaction: %v", err)
+	}
+
+	// The keyword that comes first in the message wins, not the first
+	// keyword in alphabetical order.
+	if err := reactionsModule.checkReactions(bot, newModuleMessageContext(bot, chat, user, "a zebra ate an apple")); err != ext.ContinueGroups {
+		t.Fatalf("checkReactions() error = %v, want ContinueGroups", err)
+	}
+	if got := lastReaction(t, client); !strings.Contains(got, "5368324170671202286") {
+		t.Fatalf("reaction = %s, want the zebra custom emoji", got)
+	}
+
+	// zebra is cooling down now, so apple gets its turn.
+	if err := reactionsModule.checkReactions(bot, newModuleMessageContext(bot, chat, user, "a zebra ate an apple")); err != ext.ContinueGroups {
+		t.Fatalf("checkReactions() error = %v, want ContinueGroups", err)
+	}
+	if got := lastReaction(t, client); !strings.Contains(got, "🍓") {
+		t.Fatalf("reaction = %s, want 🍓 while zebra cools down", got)
+	}
+
+	if err := reactionsModule.checkReactions(bot, newModuleMessageContext(bot, chat, user, "zebra again")); err != ext.ContinueGroups {
+		t.Fatalf("checkReactions() error = %v, want ContinueGroups", err)
+	}
+	if calls := client.callsFor("setMessageReaction"); len(calls) != 2 {
+		t.Fatalf("setMessageReaction calls = %d, want no reaction during the cooldown", len(calls))
+	}
+}
@@ -169,7 +169,9 @@ func TestDefaultRegistryIncludesEveryRuntimeModule(t *testing.T) {
 	slices.Sort(got)
 
 	want := []string{
+		"Activity",
 		"Admin",
+		"Aliases",
 		"AntiRaid",
 		"Antiflood",
 		"Antispam",
@@ -185,6 +187,8 @@ func TestDefaultRegistryIncludesEveryRuntimeModule(t *testing.T) {
 		"Filters",
 		"Formatting",
 		"Greetings",
+		"Inline",
+		"Karma",
 		"Languages",
 		"Locks",
 		"Misc",
@@ -195,6 +199,9 @@ func TestDefaultRegistryIncludesEveryRuntimeModule(t *testing.T) {
 		"Reactions",
 		"Reports",
 		"Rules",
+		"Starboard",
+		"Topics",
+		"Trust",
 		"Users",
 		"Warns",
 	}
@@ -217,9 +224,12 @@ func TestDefaultRegistryLoadsRuntimeModules(t *testing.T) {
 
 	loadedModules := listModulesFrom(defaultHelpRegistry)
 	want := []string{
+		"Activity",
 		"Admin",
+		"Aliases",
 		"AntiRaid",
 		"Antiflood",
+		"Antispam",
 		"Approvals",
 		"Backup",
 		"Bans",
@@ -230,6 +240,8 @@ func TestDefaultRegistryLoadsRuntimeModules(t *testing.T) {
 		"Filters",
 		"Formatting",
 		"Greetings",
+		"Inline",
+		"Karma",
 		"Languages",
 		"Locks",
 		"Misc",
@@ -240,6 +252,9 @@ func TestDefaultRegistryLoadsRuntimeModules(t *testing.T) {
 		"Reactions",
 		"Reports",
 		"Rules",
+		"Starboard",
+		"Topics",
+		"Trust",
 		"Warns",
 	}
 	if !reflect.DeepEqual(loadedModules, want) {
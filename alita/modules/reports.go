@@ -593,9 +593,9 @@ func LoadReports(dispatcher *ext.Dispatcher) {
 		reportsModule.handlerGroup,
 	)
 	dispatcher.AddHandler(handlers.NewCallback(callbackquery.Prefix("report"), reportsModule.markResolvedButtonHandler))
-	dispatcher.AddHandler(handlers.NewCommand("report", reportsModule.report))
+	helpers.AddCommand(dispatcher, "report", reportsModule.report)
 	helpers.AddCmdToDisableable("report")
-	dispatcher.AddHandler(handlers.NewCommand("reports", reportsModule.reports))
+	helpers.AddCommand(dispatcher, "reports", reportsModule.reports)
 }
 
 func init() {
@@ -0,0 +1,473 @@
+package modules
+
+import (
+	"fmt"
+	"html"
+	"strconv"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/PaulSonOfLars/gotgbot/v2"
+	"github.com/PaulSonOfLars/gotgbot/v2/ext"
+
+	"github.com/divkix/Alita_Robot/alita/db/connections"
+	db_filters "github.com/divkix/Alita_Robot/alita/db/filters"
+	"github.com/divkix/Alita_Robot/alita/db/lang"
+	"github.com/divkix/Alita_Robot/alita/db/models"
+	"github.com/divkix/Alita_Robot/alita/db/notes"
+	"github.com/divkix/Alita_Robot/alita/i18n"
+	"github.com/divkix/Alita_Robot/alita/utils/chat_status"
+	"github.com/divkix/Alita_Robot/alita/utils/extraction"
+	"github.com/divkix/Alita_Robot/alita/utils/formatting"
+	"github.com/divkix/Alita_Robot/alita/utils/textdiff"
+)
+
+// revisionHistoryLimit caps how many revisions /notehistory and
+// /filterhistory list.
+const revisionHistoryLimit = 10
+
+// revisionDiffMaxLength keeps a rendered diff well inside Telegram's message
+// length limit.
+const revisionDiffMaxLength = 3500
+
+// revisionView is a note or filter revision reduced to what the history and
+// diff views show.
+type revisionView struct {
+	ID       uint
+	Name     string
+	Revision int
+	Action   string
+	EditorID int64
+	At       time.Time
+	Content  string
+}
+
+// revisionKind binds the history views to notes or to filters.
+type revisionKind struct {
+	namespace  string // callback namespace of the history keyboard
+	historyCmd string
+	restoreCmd string
+	display    func(name string) string
+	history    func(chatID int64, name string) []revisionView
+	byID       func(chatID int64, id uint) *revisionView
+	current    func(chatID int64, name string) (string, bool)
+}
+
+var noteRevisionKind = revisionKind{
+	namespace:  "notes.rev",
+	historyCmd: "notehistory",
+	restoreCmd: "noterestore",
+	display:    func(name string) string { return "#" + name },
+	history: func(chatID int64, name string) []revisionView {
+		revisions := notes.GetNoteRevisions(chatID, name)
+		views := make([]revisionView, 0, len(revisions))
+		for _, rev := range revisions {
+			views = append(views, noteRevisionView(rev))
+		}
+		return views
+	},
+	byID: func(chatID int64, id uint) *revisionView {
+		rev := notes.GetNoteRevisionByID(chatID, id)
+		if rev == nil {
+			return nil
+		}
+		view := noteRevisionView(rev)
+		return &view
+	},
+	current: func(chatID int64, name string) (string, bool) {
+		note := notes.GetNote(chatID, name)
+		if note == nil {
+			return "", false
+		}
+		return note.NoteContent, true
+	},
+}
+
+var filterRevisionKind = revisionKind{
+	namespace:  "filters.rev",
+	historyCmd: "filterhistory",
+	restoreCmd: "filterrestore",
+	display:    func(name string) string { return name },
+	history: func(chatID int64, name string) []revisionView {
+		revisions := db_filters.GetFilterRevisions(chatID, name)
+		views := make([]revisionView, 0, len(revisions))
+		for _, rev := range revisions {
+			views = append(views, filterRevisionView(rev))
+		}
+		return views
+	},
+	byID: func(chatID int64, id uint) *revisionView {
+		rev := db_filters.GetFilterRevisionByID(chatID, id)
+		if rev == nil {
+			return nil
+		}
+		view := filterRevisionView(rev)
+		return &view
+	},
+	current: func(chatID int64, name string) (string, bool) {
+		filters, err := db_filters.GetChatFiltersCached(chatID)
+		if err != nil {
+			return "", false
+		}
+		for _, filter := range filters {
+			if filter.KeyWord == name {
+				return filter.FilterReply, true
+			}
+		}
+		return "", false
+	},
+}
+
+func noteRevisionView(rev *models.NoteRevision) revisionView {
+	return revisionView{
+		ID:       rev.ID,
+		Name:     rev.NoteName,
+		Revision: rev.Revision,
+		Action:   rev.Action,
+		EditorID: rev.EditorId,
+		At:       rev.CreatedAt,
+		Content:  rev.NoteContent,
+	}
+}
+
+func filterRevisionView(rev *models.FilterRevision) revisionView {
+	return revisionView{
+		ID:       rev.ID,
+		Name:     rev.KeyWord,
+		Revision: rev.Revision,
+		Action:   rev.Action,
+		EditorID: rev.EditorId,
+		At:       rev.CreatedAt,
+		Content:  rev.FilterReply,
+	}
+}
+
+// revisionSummary renders "overwritten by X on <date>" for a revision.
+func revisionSummary(tr *i18n.Translator, rev revisionView) string {
+	action, _ := tr.GetString("revisions_action_" + rev.Action)
+	editor, _ := tr.GetString("revisions_unknown_editor")
+	if _, name, found := extraction.GetUserInfo(rev.EditorID); found {
+		editor = formatting.MentionHtml(rev.EditorID, html.EscapeString(name))
+	} else if rev.EditorID != 0 {
+		editor = formatting.MentionHtml(rev.EditorID, strconv.FormatInt(rev.EditorID, 10))
+	}
+	summary, _ := tr.GetString("revisions_summary", i18n.TranslationParams{
+		"action": action,
+		"editor": editor,
+		"date":   rev.At.UTC().Format("2006-01-02 15:04 UTC"),
+	})
+	return summary
+}
+
+// renderRevisionHistory renders the newest revisions of a note or filter with
+// a diff button for each.
+func renderRevisionHistory(tr *i18n.Translator, kind revisionKind, name string, revisions []revisionView) (string, gotgbot.InlineKeyboardMarkup) {
+	if len(revisions) > revisionHistoryLimit {
+		revisions = revisions[:revisionHistoryLimit]
+	}
+	display := html.EscapeString(kind.display(name))
+	text, _ := tr.GetString("revisions_history_header", i18n.TranslationParams{"name": display})
+
+	var sb strings.Builder
+	var row []gotgbot.InlineKeyboardButton
+	keyboard := make([][]gotgbot.InlineKeyboardButton, 0, (len(revisions)+2)/3)
+	for _, rev := range revisions {
+		fmt.Fprintf(&sb, "\n • <b>#%d</b> %s", rev.Revision, revisionSummary(tr, rev))
+		label, _ := tr.GetString("revisions_diff_button", i18n.TranslationParams{"revision": rev.Revision})
+		row = append(row, gotgbot.InlineKeyboardButton{
+			Text: label,
+			CallbackData: encodeCallbackData(kind.namespace, map[string]string{
+				"a": "d",
+				"i": strconv.FormatUint(uint64(rev.ID), 10),
+			}),
+		})
+		if len(row) == 3 {
+			keyboard = append(keyboard, row)
+			row = nil
+		}
+	}
+	if len(row) > 0 {
+		keyboard = append(keyboard, row)
+	}
+
+	footer, _ := tr.GetString("revisions_restore_hint", i18n.TranslationParams{
+		"command": kind.restoreCmd,
+		"name":    html.EscapeString(name),
+	})
+	return text + sb.String() + "\n\n" + footer, gotgbot.InlineKeyboardMarkup{InlineKeyboard: keyboard}
+}
+
+// renderRevisionDiff renders what the change recorded by rev did: the diff
+// from the revision's content to the next revision, or to the current
+// content when rev is the latest one.
+func renderRevisionDiff(tr *i18n.Translator, kind revisionKind, chatID int64, rev revisionView) string {
+	newContent, _ := kind.current(chatID, rev.Name)
+	history := kind.history(chatID, rev.Name)
+	for i := len(history) - 1; i >= 0; i-- {
+		if history[i].Revision > rev.Revision {
+			newContent = history[i].Content
+			break
+		}
+	}
+
+	header, _ := tr.GetString("revisions_diff_header", i18n.TranslationParams{
+		"name":     html.EscapeString(kind.display(rev.Name)),
+		"revision": rev.Revision,
+	})
+	text := header + "\n" + revisionSummary(tr, rev) + "\n"
+
+	lines := textdiff.Lines(rev.Content, newContent)
+	if !textdiff.Changed(lines) {
+		noText, _ := tr.GetString("revisions_diff_no_text")
+		return text + "\n" + noText
+	}
+
+	var sb strings.Builder
+	for _, line := range lines {
+		marker := "  "
+		switch line.Op {
+		case textdiff.Delete:
+			marker = "- "
+		case textdiff.Insert:
+			marker = "+ "
+		}
+		entry := html.EscapeString(marker+line.Text) + "\n"
+		if sb.Len()+len(entry) > revisionDiffMaxLength {
+			sb.WriteString("…\n")
+			break
+		}
+		sb.WriteString(entry)
+	}
+	return text + "<pre>" + strings.TrimSuffix(sb.String(), "\n") + "</pre>"
+}
+
+// revisionHistory handles /notehistory and /filterhistory, listing the
+// restorable revisions of a note or filter.
+func (moduleStruct) revisionHistory(kind revisionKind) func(*gotgbot.Bot, *ext.Context) error {
+	return func(b *gotgbot.Bot, ctx *ext.Context) error {
+		msg := ctx.EffectiveMessage
+		// connection status
+		connectedChat := chat_status.IsUserConnected(b, ctx, true, true)
+		if connectedChat == nil {
+			return ext.EndGroups
+		}
+		ctx.EffectiveChat = connectedChat
+		chat := ctx.EffectiveChat
+		user := chat_status.RequireUser(b, ctx)
+		if user == nil {
+			return ext.EndGroups
+		}
+		if !chat_status.CanUserChangeInfo(b, ctx, chat, user.Id) {
+			chat_status.NewPermissionResponder(b).Respond(ctx, "chat_status_change_info_cmd_error", "chat_status_change_info_button_error")
+			return ext.EndGroups
+		}
+		tr := i18n.MustNewTranslator(lang.GetLanguage(ctx))
+
+		name := revisionTarget(kind, ctx.Args()[1:])
+		if name == "" {
+			text, _ := tr.GetString("revisions_history_usage", i18n.TranslationParams{"command": kind.historyCmd})
+			_, err := msg.Reply(b, text, formatting.Shtml())
+			if err != nil {
+				log.Error(err)
+				return err
+			}
+			return ext.EndGroups
+		}
+
+		revisions := kind.history(chat.Id, name)
+		if len(revisions) == 0 {
+			text, _ := tr.GetString("revisions_none", i18n.TranslationParams{"name": html.EscapeString(kind.display(name))})
+			_, err := msg.Reply(b, text, formatting.Shtml())
+			if err != nil {
+				log.Error(err)
+				return err
+			}
+			return ext.EndGroups
+		}
+
+		text, keyboard := renderRevisionHistory(tr, kind, name, revisions)
+		opts := formatting.Shtml()
+		opts.ReplyMarkup = keyboard
+		if _, err := msg.Reply(b, text, opts); err != nil {
+			log.Error(err)
+			return err
+		}
+		return ext.EndGroups
+	}
+}
+
+// revisionTarget extracts the note name or filter keyword from command
+// arguments, normalized the way /clear and /stop do.
+func revisionTarget(kind revisionKind, args []string) string {
+	if len(args) == 0 {
+		return ""
+	}
+	if kind.namespace == noteRevisionKind.namespace {
+		return strings.ToLower(strings.TrimLeft(args[0], "#"))
+	}
+	keyword, _ := extraction.ExtractQuotes(strings.Join(args, " "), true, true)
+	return strings.ToLower(keyword)
+}
+
+// revisionRestore handles /noterestore and /filterrestore: "<name> <rev>"
+// restores one revision, "all" undoes the latest /clearall or /stopall.
+func (moduleStruct) revisionRestore(kind revisionKind) func(*gotgbot.Bot, *ext.Context) error {
+	return func(b *gotgbot.Bot, ctx *ext.Context) error {
+		msg := ctx.EffectiveMessage
+		// connection status
+		connectedChat := chat_status.IsUserConnected(b, ctx, true, true)
+		if connectedChat == nil {
+			return ext.EndGroups
+		}
+		ctx.EffectiveChat = connectedChat
+		chat := ctx.EffectiveChat
+		user := chat_status.RequireUser(b, ctx)
+		if user == nil {
+			return ext.EndGroups
+		}
+		if !chat_status.CanUserChangeInfo(b, ctx, chat, user.Id) {
+			chat_status.NewPermissionResponder(b).Respond(ctx, "chat_status_change_info_cmd_error", "chat_status_change_info_button_error")
+			return ext.EndGroups
+		}
+		tr := i18n.MustNewTranslator(lang.GetLanguage(ctx))
+		args := ctx.Args()[1:]
+
+		var text string
+		switch {
+		case len(args) == 1 && strings.ToLower(args[0]) == "all":
+			var restored int
+			var err error
+			if kind.namespace == noteRevisionKind.namespace {
+				restored, err = notes.RestoreClearedNotes(chat.Id, user.Id)
+			} else {
+				restored, err = db_filters.RestoreClearedFilters(chat.Id, user.Id)
+			}
+			switch {
+			case err != nil:
+				text, _ = tr.GetString("error_generic")
+			case restored == 0:
+				text, _ = tr.GetString("revisions_nothing_cleared")
+			default:
+				text, _ = tr.GetString("revisions_restored_all", i18n.TranslationParams{"count": restored})
+			}
+		case len(args) >= 2:
+			rev, err := strconv.Atoi(strings.TrimPrefix(args[len(args)-1], "#"))
+			name := revisionTarget(kind, args[:len(args)-1])
+			if err != nil || rev <= 0 || name == "" {
+				text, _ = tr.GetString("revisions_restore_usage", i18n.TranslationParams{"command": kind.restoreCmd})
+				break
+			}
+			var restored bool
+			if kind.namespace == noteRevisionKind.namespace {
+				restored, err = notes.RestoreNoteRevision(chat.Id, user.Id, name, rev)
+			} else {
+				restored, err = db_filters.RestoreFilterRevision(chat.Id, user.Id, name, rev)
+			}
+			params := i18n.TranslationParams{"name": html.EscapeString(kind.display(name)), "revision": rev}
+			switch {
+			case err != nil:
+				text, _ = tr.GetString("error_generic")
+			case !restored:
+				text, _ = tr.GetString("revisions_not_found", params)
+			default:
+				text, _ = tr.GetString("revisions_restored", params)
+			}
+		default:
+			text, _ = tr.GetString("revisions_restore_usage", i18n.TranslationParams{"command": kind.restoreCmd})
+		}
+
+		_, err := msg.Reply(b, text, formatting.Shtml())
+		if err != nil {
+			log.Error(err)
+			return err
+		}
+		return ext.EndGroups
+	}
+}
+
+// revisionCallback processes the history keyboard: "d" shows the diff of a
+// revision, "h" goes back to the revision list.
+// Callback format:
+// - v1 codec: notes.rev|v1|a={d/h}&i={revisionID} (filters.rev for filters)
+func (moduleStruct) revisionCallback(kind revisionKind) func(*gotgbot.Bot, *ext.Context) error {
+	return func(b *gotgbot.Bot, ctx *ext.Context) error {
+		query, ok := callbackQueryFromContext(ctx)
+		if !ok {
+			return ext.EndGroups
+		}
+		user := query.From
+		tr := i18n.MustNewTranslator(lang.GetLanguage(ctx))
+		invalid := func() error {
+			text, _ := tr.GetString("common_callback_invalid_request")
+			_, _ = query.Answer(b, &gotgbot.AnswerCallbackQueryOpts{Text: text})
+			return ext.EndGroups
+		}
+
+		decoded, ok := decodeCallbackData(query.Data, kind.namespace)
+		if !ok || query.Message == nil {
+			return invalid()
+		}
+		action, _ := decoded.Field("a")
+		idText, _ := decoded.Field("i")
+		id, err := strconv.ParseUint(idText, 10, 64)
+		if err != nil {
+			return invalid()
+		}
+
+		// In private chats the history belongs to the connected chat.
+		msgChat := query.Message.GetChat()
+		chat := &msgChat
+		if msgChat.Type == "private" {
+			conn := connections.Connection(user.Id)
+			if conn == nil || !conn.Connected {
+				return invalid()
+			}
+			chat = &gotgbot.Chat{Id: conn.ChatId, Type: "supergroup"}
+		}
+		if !chat_status.CanUserChangeInfo(b, ctx, chat, user.Id) {
+			chat_status.NewPermissionResponder(b).Respond(ctx, "chat_status_change_info_cmd_error", "chat_status_change_info_button_error")
+			return ext.EndGroups
+		}
+
+		rev := kind.byID(chat.Id, uint(id))
+		if rev == nil {
+			text, _ := tr.GetString("revisions_expired")
+			_, _ = query.Answer(b, &gotgbot.AnswerCallbackQueryOpts{Text: text})
+			return ext.EndGroups
+		}
+
+		opts := &gotgbot.EditMessageTextOpts{
+			ParseMode:          formatting.HTML,
+			LinkPreviewOptions: &gotgbot.LinkPreviewOptions{IsDisabled: true},
+		}
+		var text string
+		switch action {
+		case "d":
+			text = renderRevisionDiff(tr, kind, chat.Id, *rev)
+			backText, _ := tr.GetString("revisions_back_button")
+			opts.ReplyMarkup = gotgbot.InlineKeyboardMarkup{InlineKeyboard: [][]gotgbot.InlineKeyboardButton{{{
+				Text: backText,
+				CallbackData: encodeCallbackData(kind.namespace, map[string]string{
+					"a": "h",
+					"i": idText,
+				}),
+			}}}}
+		case "h":
+			text, opts.ReplyMarkup = renderRevisionHistory(tr, kind, rev.Name, kind.history(chat.Id, rev.Name))
+		default:
+			return invalid()
+		}
+
+		if _, _, err := query.Message.EditText(b, text, opts); err != nil {
+			log.Error(err)
+			return err
+		}
+		if _, err := query.Answer(b, nil); err != nil {
+			log.Error(err)
+			return err
+		}
+		return ext.EndGroups
+	}
+}
@@ -11,7 +11,6 @@ import (
 	tgmd2html "github.com/PaulSonOfLars/gotg_md2html"
 	"github.com/PaulSonOfLars/gotgbot/v2"
 	"github.com/PaulSonOfLars/gotgbot/v2/ext"
-	"github.com/PaulSonOfLars/gotgbot/v2/ext/handlers"
 
 	"github.com/divkix/Alita_Robot/alita/db/lang"
 	"github.com/divkix/Alita_Robot/alita/db/rules"
@@ -327,17 +326,17 @@ func (moduleStruct) resetRulesBtn(bot *gotgbot.Bot, ctx *ext.Context) error {
 func LoadRules(dispatcher *ext.Dispatcher) {
 	DefaultHelpRegistry().AbleMap[rulesModule.moduleName] = true
 
-	dispatcher.AddHandler(handlers.NewCommand("rules", rulesModule.sendRules))
+	helpers.AddCommand(dispatcher, "rules", rulesModule.sendRules)
 	helpers.AddCmdToDisableable("rules")
-	dispatcher.AddHandler(handlers.NewCommand("setrules", rulesModule.setRules))
+	helpers.AddCommand(dispatcher, "setrules", rulesModule.setRules)
 	helpers.MultiCommand(dispatcher, []string{"resetrules", "clearrules"}, rulesModule.clearRules)
-	dispatcher.AddHandler(handlers.NewCommand("privaterules", rulesModule.privaterules))
-	dispatcher.AddHandler(handlers.NewCommand("rulesbutton", rulesModule.rulesBtn))
-	dispatcher.AddHandler(handlers.NewCommand("rulesbtn", rulesModule.rulesBtn))
-	dispatcher.AddHandler(handlers.NewCommand("clearrulesbutton", rulesModule.resetRulesBtn))
-	dispatcher.AddHandler(handlers.NewCommand("clearrulesbtn", rulesModule.resetRulesBtn))
-	dispatcher.AddHandler(handlers.NewCommand("resetrulesbutton", rulesModule.resetRulesBtn))
-	dispatcher.AddHandler(handlers.NewCommand("resetrulesbtn", rulesModule.resetRulesBtn))
+	helpers.AddCommand(dispatcher, "privaterules", rulesModule.privaterules)
+	helpers.AddCommand(dispatcher, "rulesbutton", rulesModule.rulesBtn)
+	helpers.AddCommand(dispatcher, "rulesbtn", rulesModule.rulesBtn)
+	helpers.AddCommand(dispatcher, "clearrulesbutton", rulesModule.resetRulesBtn)
+	helpers.AddCommand(dispatcher, "clearrulesbtn", rulesModule.resetRulesBtn)
+	helpers.AddCommand(dispatcher, "resetrulesbutton", rulesModule.resetRulesBtn)
+	helpers.AddCommand(dispatcher, "resetrulesbtn", rulesModule.resetRulesBtn)
 }
 
 func init() {
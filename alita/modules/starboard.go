@@ -0,0 +1,384 @@
+package modules
+
+import (
+	"fmt"
+	"html"
+	"strconv"
+	"strings"
+
+	"github.com/PaulSonOfLars/gotgbot/v2"
+	"github.com/PaulSonOfLars/gotgbot/v2/ext"
+	"github.com/PaulSonOfLars/gotgbot/v2/ext/handlers"
+	"github.com/PaulSonOfLars/gotgbot/v2/ext/handlers/filters/message"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/divkix/Alita_Robot/alita/db/blacklists"
+	"github.com/divkix/Alita_Robot/alita/db/lang"
+	"github.com/divkix/Alita_Robot/alita/db/models"
+	"github.com/divkix/Alita_Robot/alita/db/starboard"
+	"github.com/divkix/Alita_Robot/alita/i18n"
+	"github.com/divkix/Alita_Robot/alita/utils/chat_status"
+	"github.com/divkix/Alita_Robot/alita/utils/formatting"
+	"github.com/divkix/Alita_Robot/alita/utils/helpers"
+)
+
+var starboardModule = moduleStruct{moduleName: "Starboard", handlerGroup: 11}
+
+// reactionCountHandler handles message_reaction_count updates, which
+// gotgbot has no handler for. Telegram sends them instead of per-user
+// reaction updates for messages with anonymous reactions.
+type reactionCountHandler struct {
+	response handlers.Response
+}
+
+func (h reactionCountHandler) CheckUpdate(_ *gotgbot.Bot, ctx *ext.Context) bool {
+	return ctx.MessageReactionCount != nil
+}
+
+func (h reactionCountHandler) HandleUpdate(b *gotgbot.Bot, ctx *ext.Context) error {
+	return h.response(b, ctx)
+}
+
+func (h reactionCountHandler) Name() string {
+	return fmt.Sprintf("reaction_count_%p", h.response)
+}
+
+// starboardReaction returns the reaction that stars messages in a chat.
+func starboardReaction(settings *models.StarboardSettings) models.ReactionEmoji {
+	return models.ReactionEmoji{Emoji: settings.Emoji, CustomEmojiID: settings.CustomEmojiID}
+}
+
+// hasReaction reports whether a list of reactions contains the given one.
+func hasReaction(reactions []gotgbot.ReactionType, want models.ReactionEmoji) bool {
+	for _, reaction := range reactions {
+		if emoji, ok := reactionKey(reaction); ok && sameReaction(emoji, want) {
+			return true
+		}
+	}
+	return false
+}
+
+// starboardExcluded reports whether a message must never reach the
+// starboard: content its chat locked or blacklisted, no matter who sent it.
+func starboardExcluded(chatID int64, msg *gotgbot.Message) bool {
+	if lockedContent(chatID, msg) {
+		return true
+	}
+	triggers := blacklists.GetBlacklistSettings(chatID).Triggers()
+	if len(triggers) == 0 {
+		return false
+	}
+	_, found := blacklistedTrigger(chatID, triggers, msg)
+	return found
+}
+
+// starboardWatcher checks new messages in chats with a starboard and marks
+// locked or blacklisted ones as excluded. Reaction updates carry no message
+// content, so this has to happen when the message is sent.
+func (moduleStruct) starboardWatcher(b *gotgbot.Bot, ctx *ext.Context) error {
+	chat := ctx.EffectiveChat
+	msg := ctx.EffectiveMessage
+	if chat == nil || msg == nil || chat.Type == "private" {
+		return ext.ContinueGroups
+	}
+	if starboard.GetStarboardSettings(chat.Id).ChannelId == 0 {
+		return ext.ContinueGroups
+	}
+	if starboardExcluded(chat.Id, msg) {
+		_ = starboard.ExcludeMessage(chat.Id, msg.MessageId)
+	}
+	return ext.ContinueGroups
+}
+
+// countStarReaction counts a member adding or taking back the starboard
+// reaction on a message.
+func (moduleStruct) countStarReaction(b *gotgbot.Bot, ctx *ext.Context) error {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Errorf("[Starboard][countStarReaction] Recovered from panic: %v", r)
+		}
+	}()
+
+	update := ctx.MessageReaction
+	chat := &update.Chat
+	if chat.Type == "private" || update.User != nil && update.User.Id == b.Id {
+		return ext.ContinueGroups
+	}
+	settings := starboard.GetStarboardSettings(chat.Id)
+	if settings.ChannelId == 0 {
+		return ext.ContinueGroups
+	}
+	star := starboardReaction(settings)
+	delta := 0
+	if hasReaction(update.NewReaction, star) {
+		delta++
+	}
+	if hasReaction(update.OldReaction, star) {
+		delta--
+	}
+	if delta == 0 {
+		return ext.ContinueGroups
+	}
+
+	entry, err := starboard.AddStars(chat.Id, update.MessageId, delta)
+	if err == nil {
+		updateStarboard(b, ctx, chat, settings, entry)
+	}
+	return ext.ContinueGroups
+}
+
+// countStarReactions takes the starboard reaction count of a message with
+// anonymous reactions from the totals Telegram reports for it.
+func (moduleStruct) countStarReactions(b *gotgbot.Bot, ctx *ext.Context) error {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Errorf("[Starboard][countStarReactions] Recovered from panic: %v", r)
+		}
+	}()
+
+	update := ctx.MessageReactionCount
+	chat := &update.Chat
+	settings := starboard.GetStarboardSettings(chat.Id)
+	if settings.ChannelId == 0 {
+		return ext.ContinueGroups
+	}
+	star := starboardReaction(settings)
+	stars := 0
+	for _, count := range update.Reactions {
+		if emoji, ok := reactionKey(count.Type); ok && sameReaction(emoji, star) {
+			stars = int(count.TotalCount)
+			break
+		}
+	}
+
+	entry, err := starboard.SetStars(chat.Id, update.MessageId, stars)
+	if err == nil {
+		updateStarboard(b, ctx, chat, settings, entry)
+	}
+	return ext.ContinueGroups
+}
+
+// updateStarboard reposts a message that reached the threshold, or updates
+// the count shown under its starboard post. Posts stay when the count drops
+// below the threshold again.
+func updateStarboard(b *gotgbot.Bot, ctx *ext.Context, chat *gotgbot.Chat, settings *models.StarboardSettings, entry *models.StarboardEntry) {
+	if entry.Excluded {
+		return
+	}
+	tr := i18n.MustNewTranslator(lang.GetLanguage(ctx))
+	text, _ := tr.GetString("starboard_counter", i18n.TranslationParams{
+		"emoji": html.EscapeString(settings.Emoji),
+		"count": entry.Stars,
+		"link":  chat_status.GetMessageLinkFromMessageId(chat, entry.MessageId),
+		"chat":  html.EscapeString(chat.Title),
+	})
+	noPreview := &gotgbot.LinkPreviewOptions{IsDisabled: true}
+
+	if entry.CounterMessageId != 0 {
+		_, _, err := b.EditMessageText(text, &gotgbot.EditMessageTextOpts{
+			ChatId:             entry.StarboardChatId,
+			MessageId:          entry.CounterMessageId,
+			ParseMode:          formatting.HTML,
+			LinkPreviewOptions: noPreview,
+		})
+		if err != nil && !strings.Contains(err.Error(), "message is not modified") && !helpers.IsExpectedTelegramError(err) {
+			log.Warnf("[Starboard] Failed to update the count of message %d in chat %d: %v", entry.MessageId, chat.Id, err)
+		}
+		return
+	}
+	if entry.StarboardChatId != 0 || entry.Stars < settings.Threshold {
+		return
+	}
+	claimed, err := starboard.ClaimStarboardPost(chat.Id, entry.MessageId, settings.ChannelId)
+	if err != nil || !claimed {
+		return
+	}
+
+	forwarded, err := b.ForwardMessage(settings.ChannelId, chat.Id, entry.MessageId, nil)
+	if err != nil {
+		if errStr := err.Error(); strings.Contains(errStr, "message to forward not found") || strings.Contains(errStr, "can't be forwarded") {
+			// Deleted or protected: no later reaction will change that.
+			_ = starboard.ExcludeMessage(chat.Id, entry.MessageId)
+		} else {
+			log.Warnf("[Starboard] Failed to forward message %d of chat %d to %d: %v", entry.MessageId, chat.Id, settings.ChannelId, err)
+		}
+		_ = starboard.ReleaseStarboardPost(chat.Id, entry.MessageId)
+		return
+	}
+	counter, err := b.SendMessage(settings.ChannelId, text, &gotgbot.SendMessageOpts{
+		ParseMode:          formatting.HTML,
+		LinkPreviewOptions: noPreview,
+		ReplyParameters:    &gotgbot.ReplyParameters{MessageId: forwarded.MessageId, AllowSendingWithoutReply: true},
+	})
+	if err != nil {
+		log.Warnf("[Starboard] Failed to post the count of message %d of chat %d: %v", entry.MessageId, chat.Id, err)
+		_ = helpers.DeleteMessageWithErrorHandling(b, settings.ChannelId, forwarded.MessageId)
+		_ = starboard.ReleaseStarboardPost(chat.Id, entry.MessageId)
+		return
+	}
+	_ = starboard.SetStarboardPost(chat.Id, entry.MessageId, forwarded.MessageId, counter.MessageId)
+}
+
+// starboardTarget looks up the chat given to /starboard channel, by ID or
+// username.
+func starboardTarget(b *gotgbot.Bot, arg string) (*gotgbot.Chat, error) {
+	if id, err := strconv.ParseInt(arg, 10, 64); err == nil {
+		full, err := b.GetChat(id, nil)
+		if err != nil {
+			return nil, err
+		}
+		chat := full.ToChat()
+		return &chat, nil
+	}
+	if !strings.HasPrefix(arg, "@") {
+		arg = "@" + arg
+	}
+	return chat_status.GetChat(b, arg)
+}
+
+// canBotPostIn reports whether the bot can send messages to a chat: as an
+// admin with posting rights in channels, as a member elsewhere.
+func canBotPostIn(b *gotgbot.Bot, chat *gotgbot.Chat) bool {
+	member, err := b.GetChatMember(chat.Id, b.Id, nil)
+	if err != nil {
+		return false
+	}
+	merged := member.MergeChatMember()
+	switch merged.Status {
+	case "creator":
+		return true
+	case "administrator":
+		return chat.Type != "channel" || merged.CanPostMessages
+	case "member":
+		return chat.Type != "channel"
+	case "restricted":
+		return chat.Type != "channel" && merged.CanSendMessages
+	}
+	return false
+}
+
+// starboardCmd handles /starboard, which shows and changes where and when
+// messages are reposted.
+func (m moduleStruct) starboardCmd(b *gotgbot.Bot, ctx *ext.Context) error {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Errorf("[Starboard][starboardCmd] Recovered from panic: %v", r)
+		}
+	}()
+
+	msg := ctx.EffectiveMessage
+	chat := ctx.EffectiveChat
+	user := chat_status.RequireUser(b, ctx)
+	if user == nil {
+		return ext.EndGroups
+	}
+	if !chat_status.RequireGroup(b, ctx, chat) {
+		return ext.EndGroups
+	}
+	tr := i18n.MustNewTranslator(lang.GetLanguage(ctx))
+	reply := func(key string, params i18n.TranslationParams) error {
+		text, _ := tr.GetString(key, params)
+		_, err := msg.Reply(b, text, formatting.Shtml())
+		if err != nil {
+			log.Error(err)
+			return err
+		}
+		return ext.EndGroups
+	}
+
+	settings := starboard.GetStarboardSettings(chat.Id)
+	args := reactionArgs(msg)[1:]
+	if len(args) == 0 {
+		params := i18n.TranslationParams{
+			"emoji":     formatReactionEmoji(starboardReaction(settings)),
+			"threshold": settings.Threshold,
+		}
+		if settings.ChannelId == 0 {
+			return reply("starboard_status_off", params)
+		}
+		params["channel"] = settings.ChannelId
+		return reply("starboard_status", params)
+	}
+
+	if !chat_status.CanUserChangeInfo(b, ctx, chat, user.Id) {
+		chat_status.NewPermissionResponder(b).Respond(ctx, "chat_status_change_info_cmd_error", "chat_status_change_info_button_error")
+		return ext.EndGroups
+	}
+
+	switch strings.ToLower(args[0]) {
+	case "off":
+		if len(args) != 1 {
+			return reply("starboard_usage", nil)
+		}
+		if err := starboard.SetStarboardChannel(chat.Id, 0); err != nil {
+			return reply("common_settings_save_failed", nil)
+		}
+		return reply("starboard_disabled", nil)
+
+	case "channel":
+		if len(args) != 2 {
+			return reply("starboard_usage", nil)
+		}
+		target, err := starboardTarget(b, args[1])
+		if err != nil || target.Type == "private" || target.Id == chat.Id {
+			return reply("starboard_channel_invalid", nil)
+		}
+		if !chat_status.IsUserAdmin(b, target.Id, user.Id) {
+			return reply("starboard_channel_not_admin", nil)
+		}
+		if !canBotPostIn(b, target) {
+			return reply("starboard_channel_cant_post", nil)
+		}
+		if err := starboard.SetStarboardChannel(chat.Id, target.Id); err != nil {
+			return reply("common_settings_save_failed", nil)
+		}
+		return reply("starboard_channel_set", i18n.TranslationParams{
+			"channel":   html.EscapeString(target.Title),
+			"emoji":     formatReactionEmoji(starboardReaction(settings)),
+			"threshold": settings.Threshold,
+		})
+
+	case "emoji":
+		if len(args) != 2 {
+			return reply("starboard_usage", nil)
+		}
+		emoji, ok := parseReactionEmoji(args[1])
+		if !ok {
+			return reply("reactions_invalid_emoji", nil)
+		}
+		if err := starboard.SetStarboardEmoji(chat.Id, emoji.Emoji, emoji.CustomEmojiID); err != nil {
+			return reply("common_settings_save_failed", nil)
+		}
+		return reply("starboard_emoji_set", i18n.TranslationParams{"emoji": formatReactionEmoji(emoji)})
+
+	case "threshold":
+		if len(args) != 2 {
+			return reply("starboard_usage", nil)
+		}
+		threshold, err := strconv.Atoi(args[1])
+		if err != nil || threshold < 1 || threshold > starboard.MaxThreshold {
+			return reply("starboard_threshold_invalid", i18n.TranslationParams{"max": starboard.MaxThreshold})
+		}
+		if err := starboard.SetStarboardThreshold(chat.Id, threshold); err != nil {
+			return reply("common_settings_save_failed", nil)
+		}
+		return reply("starboard_threshold_set", i18n.TranslationParams{"threshold": threshold})
+	}
+	return reply("starboard_usage", nil)
+}
+
+// LoadStarboard registers the starboard command and the handlers that
+// count reactions and repost starred messages.
+func LoadStarboard(dispatcher *ext.Dispatcher) {
+	DefaultHelpRegistry().AbleMap[starboardModule.moduleName] = true
+
+	helpers.AddCommand(dispatcher, "starboard", starboardModule.starboardCmd)
+
+	dispatcher.AddHandlerToGroup(handlers.NewMessage(message.All, starboardModule.starboardWatcher), starboardModule.handlerGroup)
+	dispatcher.AddHandlerToGroup(handlers.NewReaction(nil, starboardModule.countStarReaction), starboardModule.handlerGroup)
+	dispatcher.AddHandlerToGroup(reactionCountHandler{response: starboardModule.countStarReactions}, starboardModule.handlerGroup)
+}
+
+func init() {
+	RegisterLegacyModule("Starboard", 310, LoadStarboard)
+}
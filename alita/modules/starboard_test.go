@@ -0,0 +1,160 @@
+package modules
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/PaulSonOfLars/gotgbot/v2"
+	"github.com/PaulSonOfLars/gotgbot/v2/ext"
+
+	"github.com/divkix/Alita_Robot/alita/db"
+	"github.com/divkix/Alita_Robot/alita/db/blacklists"
+	"github.com/divkix/Alita_Robot/alita/db/models"
+	"github.com/divkix/Alita_Robot/alita/db/starboard"
+)
+
+func cleanupStarboardChat(t *testing.T, chatID int64) {
+	t.Cleanup(func() {
+		_ = starboard.SetStarboardChannel(chatID, 0)
+		_ = db.DB.Where("chat_id = ?", chatID).Delete(&models.StarboardEntry{}).Error
+		_ = blacklists.RemoveAllBlacklist(chatID)
+	})
+}
+
+func TestStarboardCommandConfiguresTheStarboard(t *testing.T) {
+	client := newModuleBotClient()
+	bot := newModuleTestBot(client)
+	chat := gotgbot.Chat{Id: uniqueModuleChatID(), Type: "supergroup", Title: "Starboard Chat"}
+	admin := gotgbot.User{Id: 777000, FirstName: "Telegram"}
+	member := gotgbot.User{Id: 42, FirstName: "Member"}
+	cleanupStarboardChat(t, chat.Id)
+
+	run := func(from gotgbot.User, text string) {
+		t.Helper()
+		if err := starboardModule.starboardCmd(bot, newModuleMessageContext(bot, chat, from, text)); err != ext.EndGroups {
+			t.Fatalf("starboardCmd(%q) error = %v, want EndGroups", text, err)
+		}
+	}
+
+	run(admin, "/starboard threshold 3")
+	run(admin, "/starboard emoji 👍")
+	run(admin, "/starboard channel -1001")
+	settings := starboard.GetStarboardSettings(chat.Id)
+	if settings.ChannelId != -1001 || settings.Emoji != "👍" || settings.Threshold != 3 {
+		t.Fatalf("settings = %+v, want channel -1001, 👍 and threshold 3", settings)
+	}
+
+	for _, text := range []string{
+		"/starboard threshold 0",
+		"/starboard threshold many",
+		"/starboard emoji ⭐", // not a reaction emoji
+		"/starboard explode",
+	} {
+		run(admin, text)
+	}
+	run(member, "/starboard threshold 1")
+	run(member, "/starboard off")
+	settings = starboard.GetStarboardSettings(chat.Id)
+	if settings.ChannelId != -1001 || settings.Emoji != "👍" || settings.Threshold != 3 {
+		t.Fatalf("settings after invalid input = %+v, want them unchanged", settings)
+	}
+
+	// The starboard cannot be the chat itself.
+	client.responses["getChat"] = json.RawMessage(fmt.Sprintf(`{"id":%d,"type":"supergroup","title":"Starboard Chat"}`, chat.Id))
+	run(admin, fmt.Sprintf("/starboard channel %d", chat.Id))
+	if got := starboard.GetStarboardSettings(chat.Id).ChannelId; got != -1001 {
+		t.Fatalf("channel = %d after pointing it at the chat itself, want -1001", got)
+	}
+
+	run(admin, "/starboard off")
+	if got := starboard.GetStarboardSettings(chat.Id).ChannelId; got != 0 {
+		t.Fatalf("channel = %d after off, want 0", got)
+	}
+}
+
+func TestStarboardRepostsStarredMessages(t *testing.T) {
+	client := newModuleBotClient()
+	client.responses["forwardMessage"] = json.RawMessage(`{"message_id":5001,"date":1,"chat":{"id":-100777,"type":"channel","title":"Best of"}}`)
+	bot := newModuleTestBot(client)
+	dispatcher := ext.NewDispatcher(&ext.DispatcherOpts{MaxRoutines: -1})
+	LoadStarboard(dispatcher)
+
+	chat := gotgbot.Chat{Id: uniqueModuleChatID(), Type: "supergroup", Title: "Starboard Chat"}
+	cleanupStarboardChat(t, chat.Id)
+	if err := starboard.SetStarboardChannel(chat.Id, -100777); err != nil {
+		t.Fatalf("SetStarboardChannel() error = %v", err)
+	}
+	if err := starboard.SetStarboardThreshold(chat.Id, 2); err != nil {
+		t.Fatalf("SetStarboardThreshold() error = %v", err)
+	}
+	if err := blacklists.AddBlacklist(chat.Id, "spoiler"); err != nil {
+		t.Fatalf("AddBlacklist() error = %v", err)
+	}
+
+	updateID := int64(0)
+	process := func(update *gotgbot.Update) {
+		t.Helper()
+		updateID++
+		update.UpdateId = updateID
+		if err := dispatcher.ProcessUpdate(bot, update, nil); err != nil {
+			t.Fatalf("ProcessUpdate() error = %v", err)
+		}
+	}
+	send := func(id int64, text string) {
+		process(&gotgbot.Update{Message: &gotgbot.Message{MessageId: id, Date: 1, Chat: chat, From: &gotgbot.User{Id: 42, FirstName: "Member"}, Text: text}})
+	}
+	react := func(id, userID int64, old, new []gotgbot.ReactionType) {
+		process(&gotgbot.Update{MessageReaction: &gotgbot.MessageReactionUpdated{
+			Chat: chat, MessageId: id, User: &gotgbot.User{Id: userID, FirstName: "Reactor"}, Date: 1,
+			OldReaction: old, NewReaction: new,
+		}})
+	}
+	fire := []gotgbot.ReactionType{gotgbot.ReactionTypeEmoji{Emoji: "🔥"}}
+	thumbs := []gotgbot.ReactionType{gotgbot.ReactionTypeEmoji{Emoji: "👍"}}
+
+	send(101, "a great message")
+	send(102, "huge spoiler ahead")
+
+	// Other reactions do not count, and one star is below the threshold.
+	react(101, 43, nil, thumbs)
+	react(101, 44, nil, fire)
+	if n := len(client.callsFor("forwardMessage")); n != 0 {
+		t.Fatalf("forwardMessage calls = %d below the threshold, want none", n)
+	}
+
+	react(101, 43, thumbs, fire)
+	forwards := client.callsFor("forwardMessage")
+	if len(forwards) != 1 || fmt.Sprint(forwards[0].Params["chat_id"]) != "-100777" || fmt.Sprint(forwards[0].Params["message_id"]) != "101" {
+		t.Fatalf("forwardMessage calls = %+v, want message 101 forwarded to -100777", forwards)
+	}
+	if n := len(client.callsFor("sendMessage")); n != 1 {
+		t.Fatalf("sendMessage calls = %d, want the counter posted", n)
+	}
+
+	// Further changes edit the counter instead of posting again.
+	react(101, 45, nil, fire)
+	react(101, 44, fire, nil)
+	if n := len(client.callsFor("editMessageText")); n != 2 {
+		t.Fatalf("editMessageText calls = %d, want the counter updated twice", n)
+	}
+	if n := len(client.callsFor("forwardMessage")); n != 1 {
+		t.Fatalf("forwardMessage calls = %d, want still 1", n)
+	}
+
+	// Blacklisted content is never reposted.
+	react(102, 43, nil, fire)
+	react(102, 44, nil, fire)
+	if n := len(client.callsFor("forwardMessage")); n != 1 {
+		t.Fatalf("forwardMessage calls = %d after starring a blacklisted message, want still 1", n)
+	}
+
+	// Anonymous reactions arrive as totals.
+	process(&gotgbot.Update{MessageReactionCount: &gotgbot.MessageReactionCountUpdated{
+		Chat: chat, MessageId: 103, Date: 1,
+		Reactions: []gotgbot.ReactionCount{{Type: gotgbot.ReactionTypeEmoji{Emoji: "🔥"}, TotalCount: 4}},
+	}})
+	if forwards := client.callsFor("forwardMessage"); len(forwards) != 2 || fmt.Sprint(forwards[1].Params["message_id"]) != "103" {
+		t.Fatalf("forwardMessage calls = %+v, want message 103 forwarded", forwards)
+	}
+}
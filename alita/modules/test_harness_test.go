@@ -96,7 +96,7 @@ func newModuleBotClient() *moduleBotClient {
 				`{"status":"member","user":{"id":42,"is_bot":false,"first_name":"Member"}}`,
 			),
 			"getChatAdministrators": json.RawMessage(
-				`[{"status":"administrator","user":{"id":999,"is_bot":true,"first_name":"Alita"},"can_pin_messages":true,"can_delete_messages":true,"can_restrict_members":true,"can_promote_members":true,"can_change_info":true,"can_invite_users":true,"can_manage_chat":true}]`,
+				`[{"status":"administrator","user":{"id":999,"is_bot":true,"first_name":"Alita"},"can_pin_messages":true,"can_delete_messages":true,"can_restrict_members":true,"can_promote_members":true,"can_change_info":true,"can_invite_users":true,"can_manage_chat":true,"can_manage_topics":true}]`,
 			),
 		},
 		errors: make(map[string]error),
@@ -118,7 +118,7 @@ func (c *moduleBotClient) RequestWithContext(_ context.Context, _ string, method
 	}
 	if method == "getChatMember" && fmt.Sprint(params["user_id"]) == "999" {
 		return json.RawMessage(
-			`{"status":"administrator","user":{"id":999,"is_bot":true,"first_name":"Alita"},"can_pin_messages":true,"can_delete_messages":true,"can_restrict_members":true,"can_promote_members":true,"can_change_info":true,"can_invite_users":true,"can_manage_chat":true}`,
+			`{"status":"administrator","user":{"id":999,"is_bot":true,"first_name":"Alita"},"can_pin_messages":true,"can_delete_messages":true,"can_restrict_members":true,"can_promote_members":true,"can_change_info":true,"can_invite_users":true,"can_manage_chat":true,"can_manage_topics":true}`,
 		), nil
 	}
 	if method == "getChatMember" && fmt.Sprint(params["user_id"]) == "777000" {
@@ -163,6 +163,28 @@ func (c *moduleBotClient) callsFor(method string) []moduleBotCall {
 	return calls
 }
 
+// deletedMessageIDs returns the IDs of all messages deleted so far, one at a
+// time or in batches, in call order.
+func (c *moduleBotClient) deletedMessageIDs() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var ids []string
+	for _, call := range c.calls {
+		switch call.Method {
+		case "deleteMessage":
+			ids = append(ids, fmt.Sprint(call.Params["message_id"]))
+		case "deleteMessages":
+			if batch, ok := call.Params["message_ids"].([]int64); ok {
+				for _, id := range batch {
+					ids = append(ids, fmt.Sprint(id))
+				}
+			}
+		}
+	}
+	return ids
+}
+
 func newModuleMessageContext(bot *gotgbot.Bot, chat gotgbot.Chat, from gotgbot.User, text string) *ext.Context {
 	msg := &gotgbot.Message{
 		MessageId: 101,
@@ -327,6 +349,7 @@ func TestMain(m *testing.M) {
 		&db.AdminSettings{},
 		&db.DisableSettings{},
 		&db.DisableChatSettings{},
+		&db.CommandPermission{},
 		&db.RulesSettings{},
 		&db.PinSettings{},
 		&db.WarnSettings{},
@@ -346,9 +369,33 @@ func TestMain(m *testing.M) {
 		&db.ReportChatSettings{},
 		&db.ReportUserSettings{},
 		&db.AntifloodSettings{},
+		&db.AntifloodTopicLimit{},
 		&db.AntiRaidSettings{},
 		&db.DevSettings{},
 		&db.Reactions{},
+		&db.CommandAlias{},
+		&db.NoteRevision{},
+		&db.FilterRevision{},
+		&db.JoinRequest{},
+		&db.JoinQuestion{},
+		&db.JoinApplication{},
+		&db.CaptchaQuestion{},
+		&db.CaptchaEvent{},
+		&db.ChatActivityHour{},
+		&db.ChatActivityUser{},
+		&db.AntispamSettings{},
+		&db.SpamModel{},
+		&db.SpamToken{},
+		&db.ForumTopicSettings{},
+		&db.ForumTopic{},
+		&db.ReactionAction{},
+		&db.StarboardSettings{},
+		&db.StarboardEntry{},
+		&db.KarmaSettings{},
+		&db.KarmaUser{},
+		&db.KarmaVote{},
+		&db.TrustSettings{},
+		&db.TrustMember{},
 	); err != nil {
 		fmt.Printf("AutoMigrate failed: %v\n", err)
 		os.Exit(1)
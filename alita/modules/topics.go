@@ -0,0 +1,533 @@
+package modules
+
+import (
+	"context"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/PaulSonOfLars/gotgbot/v2"
+	"github.com/PaulSonOfLars/gotgbot/v2/ext"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/divkix/Alita_Robot/alita/db/forumtopics"
+	"github.com/divkix/Alita_Robot/alita/db/greetings"
+	"github.com/divkix/Alita_Robot/alita/db/lang"
+	"github.com/divkix/Alita_Robot/alita/i18n"
+	"github.com/divkix/Alita_Robot/alita/utils/chat_status"
+	"github.com/divkix/Alita_Robot/alita/utils/error_handling"
+	"github.com/divkix/Alita_Robot/alita/utils/formatting"
+	"github.com/divkix/Alita_Robot/alita/utils/helpers"
+)
+
+var topicsModule = moduleStruct{moduleName: "Topics"}
+
+const (
+	// maxTopicNameLength is the longest topic name Telegram accepts.
+	maxTopicNameLength = 128
+	// topicActivityInterval is how often a message in a topic is written to
+	// the database; idle times are counted in days, so hourly is plenty.
+	topicActivityInterval  = time.Hour
+	topicAutoCloseInterval = time.Hour
+	topicAutoCloseBatch    = 100
+)
+
+var (
+	topicActivityCache = &sync.Map{}
+
+	topicAutoCloserMu     sync.Mutex
+	topicAutoCloserCancel context.CancelFunc
+	topicAutoCloserWG     sync.WaitGroup
+)
+
+// messageTopic returns the forum topic a message was sent in, or 0 for
+// messages outside of topics, including the General topic and replies in
+// chats without topics, whose MessageThreadId is not a topic.
+func messageTopic(msg *gotgbot.Message) int64 {
+	if msg == nil || !msg.IsTopicMessage {
+		return 0
+	}
+	return msg.MessageThreadId
+}
+
+// contextThread returns the message thread of the message an update is
+// about, e.g. the captcha message a button was pressed on, or 0.
+func contextThread(ctx *ext.Context) int64 {
+	if ctx == nil || ctx.EffectiveMessage == nil {
+		return 0
+	}
+	return ctx.EffectiveMessage.MessageThreadId
+}
+
+// isGoneTopicError reports whether a send failed because its forum topic was
+// deleted or closed.
+func isGoneTopicError(err error) bool {
+	if err == nil {
+		return false
+	}
+	errStr := err.Error()
+	return strings.Contains(errStr, "TOPIC_ID_INVALID") ||
+		strings.Contains(errStr, "TOPIC_DELETED") ||
+		strings.Contains(errStr, "TOPIC_CLOSED") ||
+		strings.Contains(errStr, "thread not found")
+}
+
+// sendToGreetingTopic sends a welcome or captcha message of a chat with send:
+// to the topic set with /welcometopic, or to threadID, where the member
+// joined, when none is set. A topic deleted or closed outside the bot is
+// skipped for threadID and then the General topic, and a lost welcome topic
+// is cleared, so the message is never lost and the captcha never skipped.
+func sendToGreetingTopic(chatID, threadID int64, send func(threadID int64) (*gotgbot.Message, error)) (*gotgbot.Message, error) {
+	topic := greetings.GetGreetingSettings(chatID).WelcomeTopic
+	targets := []int64{threadID, 0}
+	if topic != 0 {
+		targets = append([]int64{topic}, targets...)
+	}
+	targets = slices.Compact(targets)
+	var sent *gotgbot.Message
+	var err error
+	for _, target := range targets {
+		sent, err = send(target)
+		if !isGoneTopicError(err) {
+			return sent, err
+		}
+		if target != 0 && target == topic {
+			log.Warnf("[Topics] Welcome topic %d of chat %d is gone, clearing it: %v", topic, chatID, err)
+			if clearErr := greetings.SetWelcomeTopic(chatID, 0); clearErr != nil {
+				log.Errorf("[Topics] Failed to clear welcome topic of chat %d: %v", chatID, clearErr)
+			}
+		}
+	}
+	return sent, err
+}
+
+// topicScopeNote returns the line appended to a settings confirmation when
+// the setting was changed in a topic and only applies there.
+func topicScopeNote(tr *i18n.Translator, threadID int64) string {
+	if threadID == 0 {
+		return ""
+	}
+	return "\n\n" + trS(tr, "topics_scope_note")
+}
+
+// trackTopicActivity records messages in forum topics for idle topic
+// auto-closing. Topic service messages are stored right away; other messages
+// at most once per topicActivityInterval per topic.
+func trackTopicActivity(chat *gotgbot.Chat, msg *gotgbot.Message) {
+	threadID := messageTopic(msg)
+	if chat == nil || threadID == 0 {
+		return
+	}
+	now := time.Now()
+	switch {
+	case msg.ForumTopicCreated != nil:
+		_ = forumtopics.SaveTopic(chat.Id, threadID, msg.ForumTopicCreated.Name, now)
+	case msg.ForumTopicEdited != nil && msg.ForumTopicEdited.Name != "":
+		_ = forumtopics.SaveTopic(chat.Id, threadID, msg.ForumTopicEdited.Name, now)
+	case msg.ForumTopicClosed != nil:
+		_ = forumtopics.SetTopicClosed(chat.Id, threadID, true, now)
+	case msg.ForumTopicReopened != nil:
+		_ = forumtopics.SetTopicClosed(chat.Id, threadID, false, now)
+	default:
+		if shouldUpdateKey(topicActivityCache, [2]int64{chat.Id, threadID}, topicActivityInterval) {
+			go func() {
+				if err := forumtopics.TouchTopic(chat.Id, threadID, now); err != nil {
+					topicActivityCache.Delete([2]int64{chat.Id, threadID})
+				}
+			}()
+		}
+	}
+}
+
+// topicAdminChat resolves the chat a topic management command acts on,
+// following connections, and checks that it is a forum where both the user
+// and the bot can manage topics.
+func topicAdminChat(b *gotgbot.Bot, ctx *ext.Context, tr *i18n.Translator) *gotgbot.Chat {
+	connectedChat := chat_status.IsUserConnected(b, ctx, true, true)
+	if connectedChat == nil {
+		return nil
+	}
+	ctx.EffectiveChat = connectedChat
+	chat := ctx.EffectiveChat
+	if !chat.IsForum {
+		_, _ = ctx.EffectiveMessage.Reply(b, trS(tr, "topics_not_forum"), formatting.Shtml())
+		return nil
+	}
+	user := chat_status.RequireUser(b, ctx)
+	if user == nil {
+		return nil
+	}
+	if !chat_status.CanUserManageTopics(b, ctx, chat, user.Id) {
+		chat_status.NewPermissionResponder(b).Respond(ctx, "chat_status_manage_topics_cmd_error", "chat_status_manage_topics_button_error")
+		return nil
+	}
+	if !chat_status.CanBotManageTopics(b, ctx, chat) {
+		chat_status.NewPermissionResponder(b).Respond(ctx, "chat_status_bot_manage_topics_error", "", chat_status.WithReply())
+		return nil
+	}
+	return chat
+}
+
+// commandTopic returns the topic a topic command acts on: the topic ID given
+// as its first argument, else the topic the command was sent in, or 0.
+func commandTopic(msg *gotgbot.Message, args []string) int64 {
+	if len(args) > 0 {
+		threadID, err := strconv.ParseInt(args[0], 10, 64)
+		if err != nil || threadID <= 0 {
+			return 0
+		}
+		return threadID
+	}
+	return messageTopic(msg)
+}
+
+// topicReply sends a reply to a topic command.
+func topicReply(b *gotgbot.Bot, msg *gotgbot.Message, text string) error {
+	if _, err := msg.Reply(b, text, formatting.Shtml()); err != nil {
+		log.Error(err)
+		return err
+	}
+	return ext.EndGroups
+}
+
+// topicErrorKey maps a failed forum topic request to the reply explaining it.
+func topicErrorKey(err error, notModifiedKey string) string {
+	errStr := err.Error()
+	switch {
+	case strings.Contains(errStr, "TOPIC_NOT_MODIFIED"):
+		return notModifiedKey
+	case strings.Contains(errStr, "TOPIC_ID_INVALID"), strings.Contains(errStr, "thread not found"):
+		return "topics_not_found"
+	default:
+		log.Errorf("[Topics] Forum topic request failed: %v", err)
+		return "topics_failed"
+	}
+}
+
+// newTopic creates a forum topic.
+// Usage: /newtopic <name>
+func (moduleStruct) newTopic(b *gotgbot.Bot, ctx *ext.Context) error {
+	msg := ctx.EffectiveMessage
+	tr := i18n.MustNewTranslator(lang.GetLanguage(ctx))
+	chat := topicAdminChat(b, ctx, tr)
+	if chat == nil {
+		return ext.EndGroups
+	}
+
+	name := strings.Join(ctx.Args()[1:], " ")
+	if name == "" {
+		return topicReply(b, msg, trS(tr, "topics_newtopic_usage"))
+	}
+	if utf8.RuneCountInString(name) > maxTopicNameLength {
+		text, _ := tr.GetString("topics_name_too_long", i18n.TranslationParams{"max": maxTopicNameLength})
+		return topicReply(b, msg, text)
+	}
+
+	topic, err := b.CreateForumTopic(chat.Id, name, nil)
+	if err != nil {
+		return topicReply(b, msg, trS(tr, topicErrorKey(err, "topics_failed")))
+	}
+	_ = forumtopics.SaveTopic(chat.Id, topic.MessageThreadId, topic.Name, time.Now())
+	text, _ := tr.GetString("topics_created", i18n.TranslationParams{
+		"name":  formatting.HtmlEscape(topic.Name),
+		"topic": topic.MessageThreadId,
+	})
+	return topicReply(b, msg, text)
+}
+
+// renameTopic renames the topic it is sent in, or the topic with the given ID.
+// Usage: /renametopic [<topic id>] <name>
+func (moduleStruct) renameTopic(b *gotgbot.Bot, ctx *ext.Context) error {
+	msg := ctx.EffectiveMessage
+	tr := i18n.MustNewTranslator(lang.GetLanguage(ctx))
+	chat := topicAdminChat(b, ctx, tr)
+	if chat == nil {
+		return ext.EndGroups
+	}
+
+	args := ctx.Args()[1:]
+	threadID := messageTopic(msg)
+	if threadID == 0 && len(args) > 0 {
+		if parsed, err := strconv.ParseInt(args[0], 10, 64); err == nil && parsed > 0 {
+			threadID, args = parsed, args[1:]
+		}
+	}
+	name := strings.Join(args, " ")
+	if threadID == 0 || name == "" {
+		return topicReply(b, msg, trS(tr, "topics_renametopic_usage"))
+	}
+	if utf8.RuneCountInString(name) > maxTopicNameLength {
+		text, _ := tr.GetString("topics_name_too_long", i18n.TranslationParams{"max": maxTopicNameLength})
+		return topicReply(b, msg, text)
+	}
+
+	if _, err := b.EditForumTopic(chat.Id, threadID, &gotgbot.EditForumTopicOpts{Name: name}); err != nil {
+		text, _ := tr.GetString(topicErrorKey(err, "topics_renamed"), i18n.TranslationParams{
+			"name":  formatting.HtmlEscape(name),
+			"topic": threadID,
+		})
+		return topicReply(b, msg, text)
+	}
+	_ = forumtopics.SaveTopic(chat.Id, threadID, name, time.Now())
+	text, _ := tr.GetString("topics_renamed", i18n.TranslationParams{"name": formatting.HtmlEscape(name)})
+	return topicReply(b, msg, text)
+}
+
+// closeTopic closes the topic it is sent in, or the topic with the given ID.
+// Usage: /closetopic [<topic id>]
+func (moduleStruct) closeTopic(b *gotgbot.Bot, ctx *ext.Context) error {
+	return setTopicClosed(b, ctx, true)
+}
+
+// reopenTopic reopens the topic it is sent in, or the topic with the given ID.
+// Usage: /reopentopic [<topic id>]
+func (moduleStruct) reopenTopic(b *gotgbot.Bot, ctx *ext.Context) error {
+	return setTopicClosed(b, ctx, false)
+}
+
+// setTopicClosed implements /closetopic and /reopentopic.
+func setTopicClosed(b *gotgbot.Bot, ctx *ext.Context, closed bool) error {
+	msg := ctx.EffectiveMessage
+	tr := i18n.MustNewTranslator(lang.GetLanguage(ctx))
+	chat := topicAdminChat(b, ctx, tr)
+	if chat == nil {
+		return ext.EndGroups
+	}
+
+	command, doneKey, unchangedKey := "reopentopic", "topics_reopened", "topics_already_open"
+	if closed {
+		command, doneKey, unchangedKey = "closetopic", "topics_closed", "topics_already_closed"
+	}
+	threadID := commandTopic(msg, ctx.Args()[1:])
+	if threadID == 0 {
+		text, _ := tr.GetString("topics_need_topic", i18n.TranslationParams{"command": command})
+		return topicReply(b, msg, text)
+	}
+
+	var err error
+	if closed {
+		_, err = b.CloseForumTopic(chat.Id, threadID, nil)
+	} else {
+		_, err = b.ReopenForumTopic(chat.Id, threadID, nil)
+	}
+	if err != nil {
+		key := topicErrorKey(err, unchangedKey)
+		if key == unchangedKey {
+			_ = forumtopics.SetTopicClosed(chat.Id, threadID, closed, time.Now())
+		}
+		text, _ := tr.GetString(key, i18n.TranslationParams{"topic": threadID})
+		return topicReply(b, msg, text)
+	}
+	_ = forumtopics.SetTopicClosed(chat.Id, threadID, closed, time.Now())
+	text, _ := tr.GetString(doneKey, i18n.TranslationParams{"topic": threadID})
+	return topicReply(b, msg, text)
+}
+
+// deleteTopic deletes the topic it is sent in, or the topic with the given
+// ID, along with all its messages.
+// Usage: /deletetopic [<topic id>]
+func (moduleStruct) deleteTopic(b *gotgbot.Bot, ctx *ext.Context) error {
+	msg := ctx.EffectiveMessage
+	tr := i18n.MustNewTranslator(lang.GetLanguage(ctx))
+	chat := topicAdminChat(b, ctx, tr)
+	if chat == nil {
+		return ext.EndGroups
+	}
+
+	threadID := commandTopic(msg, ctx.Args()[1:])
+	if threadID == 0 {
+		text, _ := tr.GetString("topics_need_topic", i18n.TranslationParams{"command": "deletetopic"})
+		return topicReply(b, msg, text)
+	}
+	if _, err := b.DeleteForumTopic(chat.Id, threadID, nil); err != nil {
+		text, _ := tr.GetString(topicErrorKey(err, "topics_failed"), i18n.TranslationParams{"topic": threadID})
+		return topicReply(b, msg, text)
+	}
+	_ = forumtopics.DeleteTopic(chat.Id, threadID)
+	if greetings.GetGreetingSettings(chat.Id).WelcomeTopic == threadID {
+		_ = greetings.SetWelcomeTopic(chat.Id, 0)
+	}
+
+	text, _ := tr.GetString("topics_deleted", i18n.TranslationParams{"topic": threadID})
+	if msg.Chat.Id == chat.Id && messageTopic(msg) == threadID {
+		// The command went with the topic, so there is nothing to reply to.
+		if _, err := b.SendMessage(chat.Id, text, &gotgbot.SendMessageOpts{ParseMode: formatting.HTML}); err != nil {
+			log.Error(err)
+			return err
+		}
+		return ext.EndGroups
+	}
+	return topicReply(b, msg, text)
+}
+
+// topicID shows the ID of the topic a message was sent in.
+// Usage: /topicid
+func (moduleStruct) topicID(b *gotgbot.Bot, ctx *ext.Context) error {
+	msg := ctx.EffectiveMessage
+	chat := ctx.EffectiveChat
+	if !chat_status.RequireGroup(b, ctx, chat) {
+		chat_status.NewPermissionResponder(b).Respond(ctx, "chat_status_group_only_error", "", chat_status.WithReply())
+		return ext.EndGroups
+	}
+	if chat_status.CheckDisabledCmd(b, msg, "topicid") {
+		return ext.EndGroups
+	}
+
+	tr := i18n.MustNewTranslator(lang.GetLanguage(ctx))
+	threadID := messageTopic(msg)
+	if threadID == 0 && msg.ReplyToMessage != nil {
+		threadID = messageTopic(msg.ReplyToMessage)
+	}
+	if threadID == 0 {
+		text, _ := tr.GetString("topics_id_not_topic", i18n.TranslationParams{"chat": chat.Id})
+		return topicReply(b, msg, text)
+	}
+	text, _ := tr.GetString("topics_id", i18n.TranslationParams{"topic": threadID, "chat": chat.Id})
+	return topicReply(b, msg, text)
+}
+
+// topicAutoClose shows or sets after how many idle days topics are closed.
+// Usage: /topicautoclose [<days>|off]
+func (moduleStruct) topicAutoClose(b *gotgbot.Bot, ctx *ext.Context) error {
+	msg := ctx.EffectiveMessage
+	tr := i18n.MustNewTranslator(lang.GetLanguage(ctx))
+	chat := topicAdminChat(b, ctx, tr)
+	if chat == nil {
+		return ext.EndGroups
+	}
+
+	args := ctx.Args()[1:]
+	if len(args) == 0 {
+		days := forumtopics.GetTopicSettings(chat.Id).AutoCloseDays
+		if days == 0 {
+			return topicReply(b, msg, trS(tr, "topics_autoclose_status_off"))
+		}
+		text, _ := tr.GetString("topics_autoclose_status", i18n.TranslationParams{"days": days})
+		return topicReply(b, msg, text)
+	}
+
+	var days int
+	switch arg := strings.ToLower(args[0]); arg {
+	case "off", "no", "0":
+		days = 0
+	default:
+		parsed, err := strconv.Atoi(arg)
+		if err != nil || parsed < 1 || parsed > forumtopics.MaxAutoCloseDays {
+			text, _ := tr.GetString("topics_autoclose_invalid", i18n.TranslationParams{"max": forumtopics.MaxAutoCloseDays})
+			return topicReply(b, msg, text)
+		}
+		days = parsed
+	}
+
+	if err := forumtopics.SetAutoCloseDays(chat.Id, days); err != nil {
+		return topicReply(b, msg, trS(tr, "common_settings_save_failed"))
+	}
+	if days == 0 {
+		return topicReply(b, msg, trS(tr, "topics_autoclose_disabled"))
+	}
+	// Topics nobody has written in since the bot started tracking them would
+	// never be closed, so start the clock for the ones the chat's settings
+	// mention.
+	_, _ = forumtopics.SeedTopics(chat.Id, time.Now())
+	text, _ := tr.GetString("topics_autoclose_enabled", i18n.TranslationParams{"days": days})
+	return topicReply(b, msg, text)
+}
+
+// closeIdleTopics closes the topics that have been idle for longer than
+// their chat allows. A topic that can't be closed is left alone for
+// forumtopics.CloseRetryDelay.
+func closeIdleTopics(bot *gotgbot.Bot) {
+	idle, err := forumtopics.IdleTopics(time.Now(), topicAutoCloseBatch)
+	if err != nil {
+		log.Errorf("[Topics] Failed to load idle topics: %v", err)
+	}
+	for _, topic := range idle {
+		_, err := bot.CloseForumTopic(topic.ChatId, topic.ThreadId, nil)
+		if err == nil {
+			_ = forumtopics.SetTopicClosed(topic.ChatId, topic.ThreadId, true, time.Now())
+			continue
+		}
+		switch errStr := err.Error(); {
+		case strings.Contains(errStr, "TOPIC_NOT_MODIFIED"):
+			_ = forumtopics.SetTopicClosed(topic.ChatId, topic.ThreadId, true, time.Now())
+		case strings.Contains(errStr, "TOPIC_ID_INVALID"), strings.Contains(errStr, "thread not found"):
+			// The topic is gone; forget it.
+			_ = forumtopics.DeleteTopic(topic.ChatId, topic.ThreadId)
+		default:
+			// Most failures, like missing topic rights, last; skip the topic
+			// for a while so it doesn't take the place of other chats' topics.
+			_ = forumtopics.MarkCloseFailed(topic.ChatId, topic.ThreadId, time.Now())
+			if helpers.IsExpectedTelegramError(err) {
+				log.Debugf("[Topics] Skipping idle topic %d in chat %d: %v", topic.ThreadId, topic.ChatId, err)
+			} else {
+				log.Warnf("[Topics] Failed to close idle topic %d in chat %d: %v", topic.ThreadId, topic.ChatId, err)
+			}
+		}
+	}
+}
+
+// StartTopicAutoCloser starts closing idle forum topics.
+func StartTopicAutoCloser(bot *gotgbot.Bot) {
+	topicAutoCloserMu.Lock()
+	defer topicAutoCloserMu.Unlock()
+	if topicAutoCloserCancel != nil || bot == nil {
+		return
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	topicAutoCloserCancel = cancel
+	topicAutoCloserWG.Add(1)
+	go func() {
+		defer topicAutoCloserWG.Done()
+		ticker := time.NewTicker(topicAutoCloseInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				func() {
+					defer error_handling.RecoverFromPanic("topicAutoCloser", "topics")
+					closeIdleTopics(bot)
+				}()
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+}
+
+// StopTopicAutoCloser stops and joins the idle topic closer.
+func StopTopicAutoCloser() {
+	topicAutoCloserMu.Lock()
+	defer topicAutoCloserMu.Unlock()
+	if topicAutoCloserCancel != nil {
+		topicAutoCloserCancel()
+		topicAutoCloserWG.Wait()
+		topicAutoCloserCancel = nil
+	}
+}
+
+// LoadTopics registers the forum topic management commands.
+func LoadTopics(dispatcher *ext.Dispatcher) {
+	DefaultHelpRegistry().AbleMap[topicsModule.moduleName] = true
+
+	helpers.AddCommand(dispatcher, "newtopic", topicsModule.newTopic)
+	helpers.AddCommand(dispatcher, "renametopic", topicsModule.renameTopic)
+	helpers.AddCommand(dispatcher, "closetopic", topicsModule.closeTopic)
+	helpers.AddCommand(dispatcher, "reopentopic", topicsModule.reopenTopic)
+	helpers.AddCommand(dispatcher, "deletetopic", topicsModule.deleteTopic)
+	helpers.AddCommand(dispatcher, "topicautoclose", topicsModule.topicAutoClose)
+	helpers.AddCommand(dispatcher, "topicid", topicsModule.topicID)
+	helpers.AddCmdToDisableable("topicid")
+}
+
+func init() {
+	RegisterLegacyModule("Topics", 300, LoadTopics)
+	RegisterAnonymousAdminHandler("newtopic", topicsModule.newTopic)
+	RegisterAnonymousAdminHandler("renametopic", topicsModule.renameTopic)
+	RegisterAnonymousAdminHandler("closetopic", topicsModule.closeTopic)
+	RegisterAnonymousAdminHandler("reopentopic", topicsModule.reopenTopic)
+	RegisterAnonymousAdminHandler("deletetopic", topicsModule.deleteTopic)
+	RegisterAnonymousAdminHandler("topicautoclose", topicsModule.topicAutoClose)
+}
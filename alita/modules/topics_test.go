@@ -0,0 +1,358 @@
+package modules
+
+import (
+	"encoding/json"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/PaulSonOfLars/gotgbot/v2"
+	"github.com/PaulSonOfLars/gotgbot/v2/ext"
+
+	"github.com/divkix/Alita_Robot/alita/db"
+	"github.com/divkix/Alita_Robot/alita/db/filters"
+	"github.com/divkix/Alita_Robot/alita/db/forumtopics"
+	"github.com/divkix/Alita_Robot/alita/db/greetings"
+	"github.com/divkix/Alita_Robot/alita/db/locks"
+)
+
+// inTopic marks the message of ctx as sent in forum topic threadID.
+func inTopic(ctx *ext.Context, threadID int64) *ext.Context {
+	ctx.EffectiveMessage.IsTopicMessage = true
+	ctx.EffectiveMessage.MessageThreadId = threadID
+	return ctx
+}
+
+func TestMessageTopicIgnoresReplyThreads(t *testing.T) {
+	msg := &gotgbot.Message{MessageThreadId: 12}
+	if got := messageTopic(msg); got != 0 {
+		t.Fatalf("messageTopic(reply thread) = %d, want 0", got)
+	}
+	msg.IsTopicMessage = true
+	if got := messageTopic(msg); got != 12 {
+		t.Fatalf("messageTopic(topic message) = %d, want 12", got)
+	}
+	if got := messageTopic(nil); got != 0 {
+		t.Fatalf("messageTopic(nil) = %d, want 0", got)
+	}
+}
+
+func TestLockInTopicOnlyAppliesThere(t *testing.T) {
+	client := newModuleBotClient()
+	bot := newModuleTestBot(client)
+	chat := gotgbot.Chat{Id: uniqueModuleChatID(), Type: "supergroup", Title: "Forum", IsForum: true}
+	admin := gotgbot.User{Id: 777000, FirstName: "Telegram"}
+	member := gotgbot.User{Id: 42, FirstName: "Member"}
+	const announcements = int64(5)
+
+	lockCtx := inTopic(newModuleMessageContext(bot, chat, admin, "/lock media"), announcements)
+	if err := locksModule.lockPerm(bot, lockCtx); err != ext.EndGroups {
+		t.Fatalf("lockPerm error = %v, want EndGroups", err)
+	}
+	if locks.IsPermLocked(chat.Id, "media") {
+		t.Fatal("topic lock leaked into the chat-wide locks")
+	}
+	if !locks.GetTopicLocks(chat.Id, announcements)["media"] {
+		t.Fatalf("topic locks = %v, want media locked", locks.GetTopicLocks(chat.Id, announcements))
+	}
+
+	elsewhere := newModuleMessageContext(bot, chat, member, "photo")
+	elsewhere.EffectiveMessage.Photo = []gotgbot.PhotoSize{{FileId: "photo-1"}}
+	if err := locksModule.restHandler(bot, elsewhere); err != ext.ContinueGroups {
+		t.Fatalf("restHandler error = %v, want ContinueGroups", err)
+	}
+	if calls := client.callsFor("deleteMessage"); len(calls) != 0 {
+		t.Fatalf("deleteMessage calls = %d outside the topic, want 0", len(calls))
+	}
+
+	inside := inTopic(newModuleMessageContext(bot, chat, member, "photo"), announcements)
+	inside.EffectiveMessage.Photo = []gotgbot.PhotoSize{{FileId: "photo-2"}}
+	if err := locksModule.restHandler(bot, inside); err != ext.ContinueGroups {
+		t.Fatalf("restHandler error = %v, want ContinueGroups", err)
+	}
+	if calls := client.callsFor("deleteMessage"); len(calls) != 1 {
+		t.Fatalf("deleteMessage calls = %d in the topic, want 1", len(calls))
+	}
+}
+
+func TestFilterAddedInTopicOnlyAnswersThere(t *testing.T) {
+	client := newModuleBotClient()
+	bot := newModuleTestBot(client)
+	chat := gotgbot.Chat{Id: uniqueModuleChatID(), Type: "supergroup", Title: "Forum", IsForum: true}
+	admin := gotgbot.User{Id: 777000, FirstName: "Telegram"}
+	member := gotgbot.User{Id: 42, FirstName: "Member"}
+	const support = int64(9)
+
+	addCtx := inTopic(newModuleMessageContext(bot, chat, admin, "/filter refund See the pinned FAQ"), support)
+	if err := filtersModule.addFilter(bot, addCtx); err != ext.EndGroups {
+		t.Fatalf("addFilter error = %v, want EndGroups", err)
+	}
+	if !filters.DoesFilterExists(chat.Id, "refund") {
+		t.Fatal("filter was not stored")
+	}
+	sent := len(client.callsFor("sendMessage"))
+
+	if err := filtersModule.filtersWatcher(bot, newModuleMessageContext(bot, chat, member, "refund please")); err != ext.ContinueGroups {
+		t.Fatalf("filtersWatcher error = %v, want ContinueGroups", err)
+	}
+	if got := len(client.callsFor("sendMessage")); got != sent {
+		t.Fatalf("sendMessage calls = %d outside the topic, want %d", got, sent)
+	}
+
+	watchCtx := inTopic(newModuleMessageContext(bot, chat, member, "refund please"), support)
+	if err := filtersModule.filtersWatcher(bot, watchCtx); err != ext.ContinueGroups {
+		t.Fatalf("filtersWatcher error = %v, want ContinueGroups", err)
+	}
+	calls := client.callsFor("sendMessage")
+	if len(calls) != sent+1 {
+		t.Fatalf("sendMessage calls = %d in the topic, want %d", len(calls), sent+1)
+	}
+	last := calls[len(calls)-1]
+	if !strings.Contains(last.Params["text"].(string), "pinned FAQ") {
+		t.Fatalf("filter reply = %q, want stored reply", last.Params["text"])
+	}
+	if last.Params["message_thread_id"] != int64(9) {
+		t.Fatalf("filter reply thread = %v, want 9", last.Params["message_thread_id"])
+	}
+
+	moveCtx := inTopic(newModuleMessageContext(bot, chat, admin, "/filter refund Ask in support"), 12)
+	if err := filtersModule.addFilter(bot, moveCtx); err != ext.EndGroups {
+		t.Fatalf("addFilter in another topic error = %v, want EndGroups", err)
+	}
+	if topic, _ := filters.GetFilterTopic(chat.Id, "refund"); topic != support {
+		t.Fatalf("filter topic = %d after /filter in another topic, want it kept in %d", topic, support)
+	}
+	if updated, err := filters.UpdateFilter(chat.Id, admin.Id, 12, "refund", "Ask in support", "", nil, nil, db.TEXT); err != nil || updated {
+		t.Fatalf("UpdateFilter() from another topic = %v, %v; want it refused", updated, err)
+	}
+}
+
+func TestWelcomeTopicCommand(t *testing.T) {
+	client := newModuleBotClient()
+	bot := newModuleTestBot(client)
+	chat := gotgbot.Chat{Id: uniqueModuleChatID(), Type: "supergroup", Title: "Forum", IsForum: true}
+	admin := gotgbot.User{Id: 777000, FirstName: "Telegram"}
+
+	if err := greetingsModule.welcomeTopic(bot, newModuleMessageContext(bot, chat, admin, "/welcometopic here")); err != ext.EndGroups {
+		t.Fatalf("welcomeTopic here error = %v, want EndGroups", err)
+	}
+	if got := greetings.GetGreetingSettings(chat.Id).WelcomeTopic; got != 0 {
+		t.Fatalf("WelcomeTopic = %d after /welcometopic here outside a topic, want 0", got)
+	}
+
+	hereCtx := inTopic(newModuleMessageContext(bot, chat, admin, "/welcometopic here"), 3)
+	if err := greetingsModule.welcomeTopic(bot, hereCtx); err != ext.EndGroups {
+		t.Fatalf("welcomeTopic here error = %v, want EndGroups", err)
+	}
+	if got := greetingTarget(chat.Id, 11); got != 3 {
+		t.Fatalf("greeting sent to topic %d, want the welcome topic 3", got)
+	}
+
+	if err := greetingsModule.welcomeTopic(bot, newModuleMessageContext(bot, chat, admin, "/welcometopic 8")); err != ext.EndGroups {
+		t.Fatalf("welcomeTopic id error = %v, want EndGroups", err)
+	}
+	if got := greetings.GetGreetingSettings(chat.Id).WelcomeTopic; got != 8 {
+		t.Fatalf("WelcomeTopic = %d, want 8", got)
+	}
+
+	if err := greetingsModule.welcomeTopic(bot, newModuleMessageContext(bot, chat, admin, "/welcometopic nonsense")); err != ext.EndGroups {
+		t.Fatalf("welcomeTopic invalid error = %v, want EndGroups", err)
+	}
+	if got := greetings.GetGreetingSettings(chat.Id).WelcomeTopic; got != 8 {
+		t.Fatalf("WelcomeTopic = %d after invalid input, want 8", got)
+	}
+
+	if err := greetingsModule.welcomeTopic(bot, newModuleMessageContext(bot, chat, admin, "/welcometopic off")); err != ext.EndGroups {
+		t.Fatalf("welcomeTopic off error = %v, want EndGroups", err)
+	}
+	if got := greetingTarget(chat.Id, 11); got != 11 {
+		t.Fatalf("greeting sent to topic %d after off, want the join topic 11", got)
+	}
+}
+
+// greetingTarget returns the topic sendToGreetingTopic sends to first.
+func greetingTarget(chatID, threadID int64) int64 {
+	var target int64
+	_, _ = sendToGreetingTopic(chatID, threadID, func(threadID int64) (*gotgbot.Message, error) {
+		target = threadID
+		return &gotgbot.Message{MessageId: 1}, nil
+	})
+	return target
+}
+
+func TestSendToGreetingTopicFallsBackFromGoneTopic(t *testing.T) {
+	chatID := uniqueModuleChatID()
+	if err := greetings.SetWelcomeTopic(chatID, 3); err != nil {
+		t.Fatalf("SetWelcomeTopic() error = %v", err)
+	}
+
+	var tried []int64
+	sent, err := sendToGreetingTopic(chatID, 11, func(threadID int64) (*gotgbot.Message, error) {
+		tried = append(tried, threadID)
+		switch threadID {
+		case 3:
+			return nil, errors.New("Bad Request: message thread not found")
+		case 11:
+			return nil, errors.New("Bad Request: TOPIC_CLOSED")
+		}
+		return &gotgbot.Message{MessageId: 5}, nil
+	})
+	if err != nil || sent == nil || sent.MessageId != 5 {
+		t.Fatalf("sendToGreetingTopic() = %v, %v; want the General topic message", sent, err)
+	}
+	if !slices.Equal(tried, []int64{3, 11, 0}) {
+		t.Fatalf("tried topics %v, want the welcome topic, the join topic, then General", tried)
+	}
+	if got := greetings.GetGreetingSettings(chatID).WelcomeTopic; got != 0 {
+		t.Fatalf("WelcomeTopic = %d after it was lost, want 0", got)
+	}
+
+	sendErr := errors.New("Forbidden: bot was kicked")
+	if _, err := sendToGreetingTopic(chatID, 11, func(int64) (*gotgbot.Message, error) { return nil, sendErr }); !errors.Is(err, sendErr) {
+		t.Fatalf("sendToGreetingTopic() error = %v, want other errors returned as is", err)
+	}
+}
+
+func TestTopicManagementCommands(t *testing.T) {
+	client := newModuleBotClient()
+	client.responses["createForumTopic"] = json.RawMessage(`{"message_thread_id":21,"name":"Support","icon_color":7322096}`)
+	bot := newModuleTestBot(client)
+	chat := gotgbot.Chat{Id: uniqueModuleChatID(), Type: "supergroup", Title: "Forum", IsForum: true}
+	admin := gotgbot.User{Id: 777000, FirstName: "Telegram"}
+
+	if err := topicsModule.newTopic(bot, newModuleMessageContext(bot, chat, admin, "/newtopic Support")); err != ext.EndGroups {
+		t.Fatalf("newTopic error = %v, want EndGroups", err)
+	}
+	calls := client.callsFor("createForumTopic")
+	if len(calls) != 1 || calls[0].Params["name"] != "Support" {
+		t.Fatalf("createForumTopic calls = %+v, want one for Support", calls)
+	}
+
+	closeCtx := inTopic(newModuleMessageContext(bot, chat, admin, "/closetopic"), 21)
+	if err := topicsModule.closeTopic(bot, closeCtx); err != ext.EndGroups {
+		t.Fatalf("closeTopic error = %v, want EndGroups", err)
+	}
+	calls = client.callsFor("closeForumTopic")
+	if len(calls) != 1 || calls[0].Params["message_thread_id"] != int64(21) {
+		t.Fatalf("closeForumTopic calls = %+v, want one for topic 21", calls)
+	}
+
+	client.errors["reopenForumTopic"] = errors.New("Bad Request: TOPIC_NOT_MODIFIED")
+	if err := topicsModule.reopenTopic(bot, newModuleMessageContext(bot, chat, admin, "/reopentopic 21")); err != ext.EndGroups {
+		t.Fatalf("reopenTopic error = %v, want EndGroups", err)
+	}
+	// A topic that is already open is recorded as open.
+	var topic db.ForumTopic
+	if err := db.DB.Where("chat_id = ? AND thread_id = ?", chat.Id, 21).First(&topic).Error; err != nil {
+		t.Fatalf("stored topic error = %v", err)
+	}
+	if topic.Closed || topic.Name != "Support" {
+		t.Fatalf("stored topic = %+v, want open Support", topic)
+	}
+
+	if err := topicsModule.deleteTopic(bot, newModuleMessageContext(bot, chat, admin, "/deletetopic")); err != ext.EndGroups {
+		t.Fatalf("deleteTopic error = %v, want EndGroups", err)
+	}
+	if calls := client.callsFor("deleteForumTopic"); len(calls) != 0 {
+		t.Fatalf("deleteForumTopic calls = %d without a topic, want 0", len(calls))
+	}
+
+	plain := gotgbot.Chat{Id: uniqueModuleChatID(), Type: "supergroup", Title: "Plain"}
+	if err := topicsModule.newTopic(bot, newModuleMessageContext(bot, plain, admin, "/newtopic Nope")); err != ext.EndGroups {
+		t.Fatalf("newTopic error = %v, want EndGroups", err)
+	}
+	if calls := client.callsFor("createForumTopic"); len(calls) != 1 {
+		t.Fatalf("createForumTopic calls = %d, want no call outside forums", len(calls))
+	}
+}
+
+func TestTopicIDCommand(t *testing.T) {
+	client := newModuleBotClient()
+	bot := newModuleTestBot(client)
+	chat := gotgbot.Chat{Id: uniqueModuleChatID(), Type: "supergroup", Title: "Forum", IsForum: true}
+	member := gotgbot.User{Id: 42, FirstName: "Member"}
+
+	ctx := inTopic(newModuleMessageContext(bot, chat, member, "/topicid"), 33)
+	if err := topicsModule.topicID(bot, ctx); err != ext.EndGroups {
+		t.Fatalf("topicID error = %v, want EndGroups", err)
+	}
+	if sent := client.callsFor("sendMessage"); len(sent) != 1 {
+		t.Fatalf("sendMessage calls = %d, want 1", len(sent))
+	}
+	if calls := client.callsFor("getChatMember"); len(calls) != 0 {
+		t.Fatalf("getChatMember calls = %d, want /topicid open to members", len(calls))
+	}
+}
+
+func TestTopicAutoCloseClosesIdleTopics(t *testing.T) {
+	client := newModuleBotClient()
+	bot := newModuleTestBot(client)
+	chat := gotgbot.Chat{Id: uniqueModuleChatID(), Type: "supergroup", Title: "Forum", IsForum: true}
+	admin := gotgbot.User{Id: 777000, FirstName: "Telegram"}
+
+	if err := topicsModule.topicAutoClose(bot, newModuleMessageContext(bot, chat, admin, "/topicautoclose 400")); err != ext.EndGroups {
+		t.Fatalf("topicAutoClose error = %v, want EndGroups", err)
+	}
+	if got := forumtopics.GetTopicSettings(chat.Id).AutoCloseDays; got != 0 {
+		t.Fatalf("AutoCloseDays = %d after invalid input, want 0", got)
+	}
+	if err := topicsModule.topicAutoClose(bot, newModuleMessageContext(bot, chat, admin, "/topicautoclose 7")); err != ext.EndGroups {
+		t.Fatalf("topicAutoClose error = %v, want EndGroups", err)
+	}
+	if got := forumtopics.GetTopicSettings(chat.Id).AutoCloseDays; got != 7 {
+		t.Fatalf("AutoCloseDays = %d, want 7", got)
+	}
+
+	if err := forumtopics.SaveTopic(chat.Id, 4, "Stale", time.Now().Add(-8*24*time.Hour)); err != nil {
+		t.Fatalf("SaveTopic error = %v", err)
+	}
+	trackTopicActivity(&chat, inTopic(newModuleMessageContext(bot, chat, admin, "hello"), 5).EffectiveMessage)
+	// trackTopicActivity stores regular messages in the background.
+	waitForModuleCondition(t, func() bool {
+		var count int64
+		db.DB.Model(&db.ForumTopic{}).Where("chat_id = ? AND thread_id = ?", chat.Id, 5).Count(&count)
+		return count == 1
+	})
+
+	closeIdleTopics(bot)
+	calls := client.callsFor("closeForumTopic")
+	if len(calls) != 1 || calls[0].Params["message_thread_id"] != int64(4) {
+		t.Fatalf("closeForumTopic calls = %+v, want only the stale topic 4", calls)
+	}
+	closeIdleTopics(bot)
+	if calls := client.callsFor("closeForumTopic"); len(calls) != 1 {
+		t.Fatalf("closeForumTopic calls = %d, want the closed topic left alone", len(calls))
+	}
+}
+
+func TestTopicAutoCloseBacksOffFromFailedTopics(t *testing.T) {
+	client := newModuleBotClient()
+	bot := newModuleTestBot(client)
+	chat := gotgbot.Chat{Id: uniqueModuleChatID(), Type: "supergroup", Title: "Forum", IsForum: true}
+	admin := gotgbot.User{Id: 777000, FirstName: "Telegram"}
+
+	if err := greetings.SetWelcomeTopic(chat.Id, 9); err != nil {
+		t.Fatalf("SetWelcomeTopic error = %v", err)
+	}
+	if err := topicsModule.topicAutoClose(bot, newModuleMessageContext(bot, chat, admin, "/topicautoclose 7")); err != ext.EndGroups {
+		t.Fatalf("topicAutoClose error = %v, want EndGroups", err)
+	}
+	// The welcome topic had no message since the bot started, but enabling
+	// auto-closing starts tracking it.
+	var seeded db.ForumTopic
+	if err := db.DB.Where("chat_id = ? AND thread_id = ?", chat.Id, 9).First(&seeded).Error; err != nil {
+		t.Fatalf("welcome topic not tracked after enabling auto-close: %v", err)
+	}
+
+	if err := forumtopics.SaveTopic(chat.Id, 4, "Stale", time.Now().Add(-8*24*time.Hour)); err != nil {
+		t.Fatalf("SaveTopic error = %v", err)
+	}
+	client.errors["closeForumTopic"] = errors.New("Bad Request: not enough rights to manage topics")
+	closeIdleTopics(bot)
+	closeIdleTopics(bot)
+	if calls := client.callsFor("closeForumTopic"); len(calls) != 1 {
+		t.Fatalf("closeForumTopic calls = %d, want the failed topic skipped on the next run", len(calls))
+	}
+}
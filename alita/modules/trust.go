@@ -0,0 +1,431 @@
+package modules
+
+import (
+	"html"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/PaulSonOfLars/gotgbot/v2"
+	"github.com/PaulSonOfLars/gotgbot/v2/ext"
+	"github.com/PaulSonOfLars/gotgbot/v2/ext/handlers"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/divkix/Alita_Robot/alita/db/lang"
+	"github.com/divkix/Alita_Robot/alita/db/models"
+	"github.com/divkix/Alita_Robot/alita/db/trust"
+	"github.com/divkix/Alita_Robot/alita/db/warns"
+	"github.com/divkix/Alita_Robot/alita/i18n"
+	"github.com/divkix/Alita_Robot/alita/utils/chat_status"
+	"github.com/divkix/Alita_Robot/alita/utils/extraction"
+	"github.com/divkix/Alita_Robot/alita/utils/formatting"
+	"github.com/divkix/Alita_Robot/alita/utils/helpers"
+)
+
+// trustModule tracks joins and messages before any other module can end the
+// update, in a group of its own: -7 keeps it apart from the album recorder
+// (-3) and the message index (-6), so none of them depends on how the others
+// return.
+var trustModule = moduleStruct{moduleName: "Trust", handlerGroup: -7}
+
+const (
+	// A tracked member's trust score is made of up to trustTenurePoints for
+	// being in the chat trustTenureDays, up to trustMessagePoints for sending
+	// trustActiveMessages messages, and trustCaptchaPoints for solving the
+	// captcha, less trustWarnPenalty per active warn.
+	trustTenurePoints   = 40
+	trustTenureDays     = 30
+	trustMessagePoints  = 40
+	trustActiveMessages = 100
+	trustCaptchaPoints  = 20
+	trustWarnPenalty    = 20
+	trustMaxScore       = trustTenurePoints + trustMessagePoints + trustCaptchaPoints
+
+	newcomerFloodMin = 3
+	newcomerFloodMax = 100
+)
+
+// newcomerLockExempt are the lock types about bots, channels and non-members,
+// which cannot apply to newcomers.
+var newcomerLockExempt = map[string]bool{"bots": true, "comments": true, "anonchannel": true}
+
+// isNewcomer reports whether a sender counts as a newcomer in a chat.
+// Channels and members the bot did not see join never do.
+func isNewcomer(chatID, senderID int64, settings *models.TrustSettings) bool {
+	if senderID <= 0 {
+		return false
+	}
+	return trust.IsNewcomer(settings, trust.GetMember(chatID, senderID), time.Now())
+}
+
+// withNewcomerLocks returns the locks that apply to a sender: chatLocks,
+// plus the chat's newcomer locks when the sender is a newcomer.
+func withNewcomerLocks(chatID, senderID int64, chatLocks map[string]bool) map[string]bool {
+	settings := trust.GetTrustSettings(chatID)
+	if len(settings.NewcomerLocks) == 0 || !isNewcomer(chatID, senderID, settings) {
+		return chatLocks
+	}
+	// chatLocks may be shared through the cache, so it is never written to.
+	merged := make(map[string]bool, len(chatLocks)+len(settings.NewcomerLocks))
+	maps.Copy(merged, chatLocks)
+	for _, name := range settings.NewcomerLocks {
+		merged[name] = true
+	}
+	return merged
+}
+
+// newcomerFloodLimit returns the flood limit for a sender: the chat's
+// newcomer limit when the sender is a newcomer and it is stricter than
+// limit, else limit. A limit of 0 means antiflood is off.
+func newcomerFloodLimit(chatID, senderID int64, limit int) int {
+	settings := trust.GetTrustSettings(chatID)
+	if settings.NewcomerFlood == 0 || limit != 0 && limit <= settings.NewcomerFlood {
+		return limit
+	}
+	if !isNewcomer(chatID, senderID, settings) {
+		return limit
+	}
+	return settings.NewcomerFlood
+}
+
+// hitsNewcomerLock reports whether a message matches a newcomer lock of its
+// chat. Such messages do not count towards leaving the newcomer profile.
+func hitsNewcomerLock(settings *models.TrustSettings, msg *gotgbot.Message) bool {
+	for _, name := range settings.NewcomerLocks {
+		filter, ok := lockMap[name]
+		if !ok {
+			filter, ok = restrMap[name]
+		}
+		if ok && filter(msg) {
+			return true
+		}
+	}
+	return false
+}
+
+// trustScore rates a member from 0 to trustMaxScore. Members the bot did not
+// see join (nil) have been around longer than it can tell, and start from
+// the maximum.
+func trustScore(member *models.TrustMember, activeWarns int, now time.Time) int {
+	score := trustMaxScore
+	if member != nil {
+		days := now.Sub(member.JoinedAt).Hours() / 24
+		score = int(trustTenurePoints*min(days/trustTenureDays, 1) +
+			trustMessagePoints*min(float64(member.Messages)/trustActiveMessages, 1))
+		if member.CaptchaPassed {
+			score += trustCaptchaPoints
+		}
+	}
+	return max(score-trustWarnPenalty*activeWarns, 0)
+}
+
+// truncateDuration rounds seconds down to whole days, hours or minutes,
+// whichever is the largest that fits, for showing how long ago something was.
+func truncateDuration(seconds int) int {
+	for _, unit := range []int{86400, 3600, 60} {
+		if seconds >= unit {
+			return seconds - seconds%unit
+		}
+	}
+	return seconds
+}
+
+// trackJoin records members joining a chat, which starts their time as
+// newcomers.
+func (moduleStruct) trackJoin(b *gotgbot.Bot, ctx *ext.Context) error {
+	update := ctx.ChatMember
+	member := update.NewChatMember.MergeChatMember().User
+	if update.Chat.Type == "channel" || member.IsBot {
+		return ext.ContinueGroups
+	}
+	_ = trust.RecordJoin(update.Chat.Id, member.Id, time.Unix(update.Date, 0))
+	return ext.ContinueGroups
+}
+
+// countMessage counts the messages of tracked members, leaving out those a
+// newcomer lock removes.
+func (moduleStruct) countMessage(b *gotgbot.Bot, ctx *ext.Context) error {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Errorf("[Trust][countMessage] Recovered from panic: %v", r)
+		}
+	}()
+
+	chat := ctx.EffectiveChat
+	msg := ctx.EffectiveMessage
+	if chat == nil || msg == nil || chat.Type == "private" || chat.Type == "channel" || msg.From == nil || msg.SenderChat != nil || isAliasRedispatch(ctx) {
+		return ext.ContinueGroups
+	}
+	member := trust.GetMember(chat.Id, msg.From.Id)
+	if member == nil || member.Messages >= trust.MaxCountedMessages {
+		return ext.ContinueGroups
+	}
+	if hitsNewcomerLock(trust.GetTrustSettings(chat.Id), msg) {
+		return ext.ContinueGroups
+	}
+	_ = trust.CountMessage(chat.Id, msg.From.Id)
+	return ext.ContinueGroups
+}
+
+// trustCommand handles /trust, which shows the trust profile of the
+// replied-to or given member, or of the sender.
+func (moduleStruct) trustCommand(b *gotgbot.Bot, ctx *ext.Context) error {
+	msg := ctx.EffectiveMessage
+	chat := ctx.EffectiveChat
+	user := chat_status.RequireUser(b, ctx)
+	if user == nil {
+		return ext.EndGroups
+	}
+	if !chat_status.RequireGroup(b, ctx, chat) {
+		return ext.EndGroups
+	}
+	tr := i18n.MustNewTranslator(lang.GetLanguage(ctx))
+
+	targetID, name := user.Id, user.FirstName
+	if len(ctx.Args()) > 1 || msg.ReplyToMessage != nil && msg.ReplyToMessage.ForumTopicCreated == nil {
+		targetID = extraction.ExtractUser(b, ctx)
+		if targetID == -1 {
+			return ext.EndGroups
+		}
+		if targetID == 0 {
+			text, _ := tr.GetString("common_no_user_specified")
+			_, err := msg.Reply(b, text, formatting.Shtml())
+			if err != nil {
+				log.Error(err)
+				return err
+			}
+			return ext.EndGroups
+		}
+		name = extractDisplayName(targetID)
+	}
+
+	now := time.Now()
+	settings := trust.GetTrustSettings(chat.Id)
+	member := trust.GetMember(chat.Id, targetID)
+	activeWarns, _ := warns.GetWarns(targetID, chat.Id)
+	joined, messages, captcha := trS(tr, "trust_unknown"), trS(tr, "trust_unknown"), trS(tr, "trust_unknown")
+	if member != nil {
+		joined, _ = tr.GetString("trust_joined_ago", i18n.TranslationParams{
+			"ago": formatDuration(truncateDuration(max(int(now.Sub(member.JoinedAt).Seconds()), 0))),
+		})
+		messages = strconv.Itoa(member.Messages)
+		if member.Messages >= trust.MaxCountedMessages {
+			messages += "+"
+		}
+		captcha = trS(tr, "common_no")
+		if member.CaptchaPassed {
+			captcha = trS(tr, "common_yes")
+		}
+	}
+	level := trS(tr, "trust_level_member")
+	if trust.IsNewcomer(settings, member, now) {
+		level = trS(tr, "trust_level_newcomer")
+	}
+	text, _ := tr.GetString("trust_profile", i18n.TranslationParams{
+		"user":     formatting.MentionHtml(targetID, name),
+		"score":    trustScore(member, activeWarns, now),
+		"max":      trustMaxScore,
+		"level":    level,
+		"joined":   joined,
+		"messages": messages,
+		"warns":    activeWarns,
+		"captcha":  captcha,
+	})
+	_, err := msg.Reply(b, text, formatting.Shtml())
+	if err != nil {
+		log.Error(err)
+		return err
+	}
+	return ext.EndGroups
+}
+
+// newcomersStatus describes the newcomer profile of a chat.
+func newcomersStatus(tr *i18n.Translator, settings *models.TrustSettings) string {
+	params := i18n.TranslationParams{
+		"time":     formatDuration(settings.NewcomerHours * 3600),
+		"messages": settings.NewcomerMessages,
+	}
+	var rule string
+	switch {
+	case settings.NewcomerHours > 0 && settings.NewcomerMessages > 0:
+		rule, _ = tr.GetString("trust_rule_both", params)
+	case settings.NewcomerHours > 0:
+		rule, _ = tr.GetString("trust_rule_time", params)
+	case settings.NewcomerMessages > 0:
+		rule, _ = tr.GetString("trust_rule_messages", params)
+	default:
+		rule = trS(tr, "trust_rule_off")
+	}
+	lockList := trS(tr, "trust_none")
+	if len(settings.NewcomerLocks) > 0 {
+		lockList = strings.Join(settings.NewcomerLocks, ", ")
+	}
+	flood := trS(tr, "trust_flood_chat")
+	if settings.NewcomerFlood > 0 {
+		flood = strconv.Itoa(settings.NewcomerFlood)
+	}
+	text, _ := tr.GetString("trust_newcomers_status", i18n.TranslationParams{
+		"rule":  rule,
+		"locks": lockList,
+		"flood": flood,
+	})
+	return text
+}
+
+// newcomers handles /newcomers, which shows and changes who counts as a
+// newcomer in a chat and what newcomers may not do.
+func (m moduleStruct) newcomers(b *gotgbot.Bot, ctx *ext.Context) error {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Errorf("[Trust][newcomers] Recovered from panic: %v", r)
+		}
+	}()
+
+	msg := ctx.EffectiveMessage
+	chat := ctx.EffectiveChat
+	user := chat_status.RequireUser(b, ctx)
+	if user == nil {
+		return ext.EndGroups
+	}
+	if !chat_status.RequireGroup(b, ctx, chat) {
+		return ext.EndGroups
+	}
+	tr := i18n.MustNewTranslator(lang.GetLanguage(ctx))
+	reply := func(key string, params i18n.TranslationParams) error {
+		text, _ := tr.GetString(key, params)
+		_, err := msg.Reply(b, text, formatting.Shtml())
+		if err != nil {
+			log.Error(err)
+			return err
+		}
+		return ext.EndGroups
+	}
+	save := func(err error, key string, params i18n.TranslationParams) error {
+		if err != nil {
+			return reply("common_settings_save_failed", nil)
+		}
+		return reply(key, params)
+	}
+
+	settings := trust.GetTrustSettings(chat.Id)
+	args := ctx.Args()[1:]
+	if len(args) == 0 {
+		_, err := msg.Reply(b, newcomersStatus(tr, settings), formatting.Shtml())
+		if err != nil {
+			log.Error(err)
+			return err
+		}
+		return ext.EndGroups
+	}
+
+	if !chat_status.CanUserChangeInfo(b, ctx, chat, user.Id) {
+		chat_status.NewPermissionResponder(b).Respond(ctx, "chat_status_change_info_cmd_error", "chat_status_change_info_button_error")
+		return ext.EndGroups
+	}
+
+	switch strings.ToLower(args[0]) {
+	case "time":
+		if len(args) != 2 {
+			return reply("trust_usage", nil)
+		}
+		if strings.EqualFold(args[1], "off") {
+			return save(trust.SetNewcomerHours(chat.Id, 0), "trust_time_off", nil)
+		}
+		seconds, ok := parseDuration(args[1])
+		if !ok || seconds < 3600 || seconds%3600 != 0 || seconds/3600 > trust.MaxNewcomerHours {
+			return reply("trust_time_invalid", i18n.TranslationParams{"max": formatDuration(trust.MaxNewcomerHours * 3600)})
+		}
+		return save(trust.SetNewcomerHours(chat.Id, seconds/3600), "trust_time_set", i18n.TranslationParams{"time": formatDuration(seconds)})
+
+	case "messages":
+		if len(args) != 2 {
+			return reply("trust_usage", nil)
+		}
+		if strings.EqualFold(args[1], "off") {
+			return save(trust.SetNewcomerMessages(chat.Id, 0), "trust_messages_off", nil)
+		}
+		count, err := strconv.Atoi(args[1])
+		if err != nil || count < 1 || count > trust.MaxNewcomerMessages {
+			return reply("trust_messages_invalid", i18n.TranslationParams{"max": trust.MaxNewcomerMessages})
+		}
+		return save(trust.SetNewcomerMessages(chat.Id, count), "trust_messages_set", i18n.TranslationParams{"messages": count})
+
+	case "lock", "unlock":
+		if len(args) < 2 {
+			return reply("trust_usage", nil)
+		}
+		var names, invalid []string
+		for _, name := range args[1:] {
+			name = strings.ToLower(name)
+			if !slices.Contains(m.getLockMapAsArray(), name) || newcomerLockExempt[name] {
+				invalid = append(invalid, html.EscapeString(name))
+			} else if !slices.Contains(names, name) {
+				names = append(names, name)
+			}
+		}
+		if len(invalid) > 0 {
+			return reply("trust_lock_invalid", i18n.TranslationParams{"types": strings.Join(invalid, ", ")})
+		}
+		current := slices.Clone(settings.NewcomerLocks)
+		if strings.EqualFold(args[0], "lock") {
+			for _, name := range names {
+				if !slices.Contains(current, name) {
+					current = append(current, name)
+				}
+			}
+			slices.Sort(current)
+			return save(trust.SetNewcomerLocks(chat.Id, current), "trust_locked", i18n.TranslationParams{"types": strings.Join(names, ", ")})
+		}
+		current = slices.DeleteFunc(current, func(name string) bool { return slices.Contains(names, name) })
+		return save(trust.SetNewcomerLocks(chat.Id, current), "trust_unlocked", i18n.TranslationParams{"types": strings.Join(names, ", ")})
+
+	case "flood":
+		if len(args) != 2 {
+			return reply("trust_usage", nil)
+		}
+		if strings.EqualFold(args[1], "off") {
+			return save(trust.SetNewcomerFlood(chat.Id, 0), "trust_flood_off", nil)
+		}
+		limit, err := strconv.Atoi(args[1])
+		if err != nil || limit < newcomerFloodMin || limit > newcomerFloodMax {
+			return reply("trust_flood_invalid", i18n.TranslationParams{"min": newcomerFloodMin, "max": newcomerFloodMax})
+		}
+		return save(trust.SetNewcomerFlood(chat.Id, limit), "trust_flood_set", i18n.TranslationParams{"limit": limit})
+	}
+	return reply("trust_usage", nil)
+}
+
+// LoadTrust registers the trust commands and the handlers that track when
+// members join and how much they take part.
+func LoadTrust(dispatcher *ext.Dispatcher) {
+	DefaultHelpRegistry().AbleMap[trustModule.moduleName] = true
+
+	helpers.AddCommand(dispatcher, "trust", trustModule.trustCommand)
+	helpers.AddCommand(dispatcher, "newcomers", trustModule.newcomers)
+
+	dispatcher.AddHandlerToGroup(
+		handlers.NewChatMember(
+			func(u *gotgbot.ChatMemberUpdated) bool {
+				wasMember, isMember := chat_status.ExtractJoinLeftStatusChange(u)
+				return !wasMember && isMember
+			},
+			trustModule.trackJoin,
+		),
+		trustModule.handlerGroup,
+	)
+	dispatcher.AddHandlerToGroup(
+		handlers.NewMessage(
+			func(msg *gotgbot.Message) bool {
+				return msg.NewChatMembers == nil && msg.LeftChatMember == nil
+			},
+			trustModule.countMessage,
+		),
+		trustModule.handlerGroup,
+	)
+}
+
+func init() {
+	RegisterLegacyModule("Trust", 330, LoadTrust)
+}
@@ -0,0 +1,171 @@
+package modules
+
+import (
+	"testing"
+	"time"
+
+	"github.com/PaulSonOfLars/gotgbot/v2"
+	"github.com/PaulSonOfLars/gotgbot/v2/ext"
+
+	"github.com/divkix/Alita_Robot/alita/db"
+	"github.com/divkix/Alita_Robot/alita/db/models"
+	"github.com/divkix/Alita_Robot/alita/db/trust"
+)
+
+func cleanupTrustChat(t *testing.T, chatID int64) {
+	t.Cleanup(func() {
+		_ = trust.SetNewcomerLocks(chatID, nil)
+		_ = trust.SetNewcomerFlood(chatID, 0)
+		_ = db.DB.Where("chat_id = ?", chatID).Delete(&models.TrustSettings{}).Error
+		_ = db.DB.Where("chat_id = ?", chatID).Delete(&models.TrustMember{}).Error
+	})
+}
+
+func TestTrustScore(t *testing.T) {
+	now := time.Now()
+	for _, tc := range []struct {
+		name   string
+		member *models.TrustMember
+		warns  int
+		want   int
+	}{
+		{"untracked", nil, 0, 100},
+		{"untracked with a warn", nil, 1, 80},
+		{"just joined", &models.TrustMember{JoinedAt: now}, 0, 0},
+		{"just solved the captcha", &models.TrustMember{JoinedAt: now, CaptchaPassed: true}, 0, 20},
+		{"halfway", &models.TrustMember{JoinedAt: now.Add(-15 * 24 * time.Hour), Messages: 50}, 0, 40},
+		{"established", &models.TrustMember{JoinedAt: now.Add(-60 * 24 * time.Hour), Messages: 500, CaptchaPassed: true}, 0, 100},
+		{"warned newcomer", &models.TrustMember{JoinedAt: now, CaptchaPassed: true}, 3, 0},
+	} {
+		if got := trustScore(tc.member, tc.warns, now); got != tc.want {
+			t.Errorf("trustScore(%s) = %d, want %d", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestNewcomersChangesSettings(t *testing.T) {
+	client := newModuleBotClient()
+	bot := newModuleTestBot(client)
+	chat := gotgbot.Chat{Id: uniqueModuleChatID(), Type: "supergroup", Title: "Trust Chat"}
+	admin := gotgbot.User{Id: 777000, FirstName: "Telegram"}
+	member := gotgbot.User{Id: 42, FirstName: "Member"}
+	cleanupTrustChat(t, chat.Id)
+
+	run := func(from gotgbot.User, text string) {
+		t.Helper()
+		if err := trustModule.newcomers(bot, newModuleMessageContext(bot, chat, from, text)); err != ext.EndGroups {
+			t.Fatalf("newcomers(%q) error = %v, want EndGroups", text, err)
+		}
+	}
+
+	run(admin, "/newcomers time 2d")
+	run(admin, "/newcomers messages 25")
+	run(admin, "/newcomers lock url photo url")
+	run(admin, "/newcomers lock media")
+	run(admin, "/newcomers flood 4")
+	settings := trust.GetTrustSettings(chat.Id)
+	if settings.NewcomerHours != 48 || settings.NewcomerMessages != 25 || settings.NewcomerFlood != 4 ||
+		len(settings.NewcomerLocks) != 3 || settings.NewcomerLocks[0] != "media" || settings.NewcomerLocks[2] != "url" {
+		t.Fatalf("settings = %+v, want them as set", settings)
+	}
+
+	for _, text := range []string{
+		"/newcomers time 90m",      // not whole hours
+		"/newcomers time 31d",      // above the maximum
+		"/newcomers messages 0",    // off is spelled out
+		"/newcomers messages 5000", // above the maximum
+		"/newcomers lock bots",     // not about newcomers
+		"/newcomers lock url spam", // unknown type
+		"/newcomers flood 1",       // below the minimum
+		"/newcomers explode",
+	} {
+		run(admin, text)
+	}
+	run(member, "/newcomers time off")
+	run(member, "/newcomers")
+	if got := trust.GetTrustSettings(chat.Id); got.NewcomerHours != 48 || got.NewcomerMessages != 25 || len(got.NewcomerLocks) != 3 || got.NewcomerFlood != 4 {
+		t.Fatalf("settings after invalid input = %+v, want them unchanged", got)
+	}
+
+	run(admin, "/newcomers unlock photo media")
+	run(admin, "/newcomers time off")
+	run(admin, "/newcomers flood off")
+	settings = trust.GetTrustSettings(chat.Id)
+	if settings.NewcomerHours != 0 || settings.NewcomerFlood != 0 || len(settings.NewcomerLocks) != 1 || settings.NewcomerLocks[0] != "url" {
+		t.Fatalf("settings = %+v, want no time rule, no flood limit and only url locked", settings)
+	}
+}
+
+func TestNewcomersGetTheirOwnLocksAndFloodLimit(t *testing.T) {
+	resetAntifloodState(t)
+
+	client := newModuleBotClient()
+	bot := newModuleTestBot(client)
+	dispatcher := ext.NewDispatcher(&ext.DispatcherOpts{MaxRoutines: -1})
+	LoadTrust(dispatcher)
+
+	chat := gotgbot.Chat{Id: uniqueModuleChatID(), Type: "supergroup", Title: "Trust Chat"}
+	cleanupTrustChat(t, chat.Id)
+	if err := trust.SetNewcomerLocks(chat.Id, []string{"url"}); err != nil {
+		t.Fatalf("SetNewcomerLocks() error = %v", err)
+	}
+	if err := trust.SetNewcomerFlood(chat.Id, 3); err != nil {
+		t.Fatalf("SetNewcomerFlood() error = %v", err)
+	}
+
+	newcomer := gotgbot.User{Id: 42, FirstName: "Newcomer"}
+	regular := gotgbot.User{Id: 43, FirstName: "Regular"}
+	updateID := int64(0)
+	process := func(update *gotgbot.Update) {
+		t.Helper()
+		updateID++
+		update.UpdateId = updateID
+		if err := dispatcher.ProcessUpdate(bot, update, nil); err != nil {
+			t.Fatalf("ProcessUpdate() error = %v", err)
+		}
+	}
+	linkFrom := func(user gotgbot.User) *ext.Context {
+		ctx := newModuleMessageContext(bot, chat, user, "https://example.com")
+		ctx.EffectiveMessage.Entities = []gotgbot.MessageEntity{{Type: "url", Offset: 0, Length: 19}}
+		return ctx
+	}
+
+	process(&gotgbot.Update{ChatMember: &gotgbot.ChatMemberUpdated{
+		Chat: chat, From: newcomer, Date: time.Now().Unix(),
+		OldChatMember: gotgbot.ChatMemberLeft{User: newcomer},
+		NewChatMember: gotgbot.ChatMemberMember{User: newcomer},
+	}})
+	if trust.GetMember(chat.Id, newcomer.Id) == nil {
+		t.Fatal("join was not tracked")
+	}
+
+	// Only tracked members' messages count, and not those a newcomer lock removes.
+	process(&gotgbot.Update{Message: &gotgbot.Message{MessageId: 10, Date: 1, Chat: chat, From: &newcomer, Text: "hello"}})
+	process(&gotgbot.Update{Message: linkFrom(newcomer).EffectiveMessage})
+	process(&gotgbot.Update{Message: &gotgbot.Message{MessageId: 11, Date: 1, Chat: chat, From: &regular, Text: "hi"}})
+	if member := trust.GetMember(chat.Id, newcomer.Id); member.Messages != 1 {
+		t.Fatalf("messages = %d, want 1", member.Messages)
+	}
+	if trust.GetMember(chat.Id, regular.Id) != nil {
+		t.Fatal("a member whose join was not seen got tracked")
+	}
+
+	for _, user := range []gotgbot.User{newcomer, regular} {
+		if err := locksModule.permHandler(bot, linkFrom(user)); err != ext.ContinueGroups {
+			t.Fatalf("permHandler error = %v, want ContinueGroups", err)
+		}
+	}
+	if calls := client.callsFor("deleteMessage"); len(calls) != 1 {
+		t.Fatalf("deleteMessage calls = %d, want only the newcomer's link removed", len(calls))
+	}
+
+	for i := range 4 {
+		flooded, _, settings := antifloodModule.updateFlood(chat.Id, 0, newcomer.Id, int64(100+i))
+		if flooded != (i == 3) || settings.Limit != 3 {
+			t.Fatalf("newcomer message %d flooded = %v with limit %d, want the 4th flooding with limit 3", i+1, flooded, settings.Limit)
+		}
+		if flooded, _, settings = antifloodModule.updateFlood(chat.Id, 0, regular.Id, int64(200+i)); flooded || settings.Limit != 0 {
+			t.Fatalf("regular message %d flooded = %v with limit %d, want antiflood off", i+1, flooded, settings.Limit)
+		}
+	}
+}
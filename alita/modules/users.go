@@ -121,6 +121,10 @@ func updateCurrentChat(chatID int64, chatName string, userID int64) {
 // logUsers handles automatic user and chat tracking by updating
 // database records with rate limiting for all message events.
 func (moduleStruct) logUsers(bot *gotgbot.Bot, ctx *ext.Context) error {
+	// A rewritten alias was already logged and counted as the original
+	if isAliasRedispatch(ctx) {
+		return ext.ContinueGroups
+	}
 	msg := ctx.EffectiveMessage
 	chat := ctx.EffectiveChat
 	user := ctx.EffectiveSender
@@ -203,6 +207,11 @@ func (moduleStruct) logUsers(bot *gotgbot.Bot, ctx *ext.Context) error {
 		}
 	}
 
+	// count the message towards /chatstats
+	trackChatActivity(chat, msg, user)
+	// keep forum topics fresh for /topicautoclose
+	trackTopicActivity(chat, msg)
+
 	return ext.ContinueGroups
 }
 
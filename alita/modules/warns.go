@@ -141,7 +141,7 @@ func (moduleStruct) warnThisUser(b *gotgbot.Bot, ctx *ext.Context, userId int64,
 	switch warnType {
 	case "dwarn":
 		if msg.ReplyToMessage != nil {
-			if _, deleteErr := msg.ReplyToMessage.Delete(b, nil); deleteErr != nil {
+			if deleteErr := helpers.DeleteMessagesBatched(b, chat.Id, []int64{msg.ReplyToMessage.MessageId}, nil); deleteErr != nil {
 				log.Errorf("[Warns] Failed to delete message: %v", deleteErr)
 			}
 		}
@@ -858,22 +858,22 @@ func (moduleStruct) removeWarn(b *gotgbot.Bot, ctx *ext.Context) error {
 func LoadWarns(dispatcher *ext.Dispatcher) {
 	DefaultHelpRegistry().AbleMap[warnsModule.moduleName] = true
 
-	dispatcher.AddHandler(handlers.NewCommand("warn", warnsModule.warnUser))
-	dispatcher.AddHandler(handlers.NewCommand("swarn", warnsModule.sWarnUser))
-	dispatcher.AddHandler(handlers.NewCommand("dwarn", warnsModule.dWarnUser))
+	helpers.AddCommand(dispatcher, "warn", warnsModule.warnUser)
+	helpers.AddCommand(dispatcher, "swarn", warnsModule.sWarnUser)
+	helpers.AddCommand(dispatcher, "dwarn", warnsModule.dWarnUser)
 	// Aliases for reset warnings (docs mention /resetwarn as well)
-	dispatcher.AddHandler(handlers.NewCommand("resetwarns", warnsModule.resetWarns))
-	dispatcher.AddHandler(handlers.NewCommand("resetwarn", warnsModule.resetWarns))
+	helpers.AddCommand(dispatcher, "resetwarns", warnsModule.resetWarns)
+	helpers.AddCommand(dispatcher, "resetwarn", warnsModule.resetWarns)
 	// Add commands to remove latest warn for a user
-	dispatcher.AddHandler(handlers.NewCommand("rmwarn", warnsModule.removeWarn))
-	dispatcher.AddHandler(handlers.NewCommand("unwarn", warnsModule.removeWarn))
-	dispatcher.AddHandler(handlers.NewCommand("warns", warnsModule.warns))
+	helpers.AddCommand(dispatcher, "rmwarn", warnsModule.removeWarn)
+	helpers.AddCommand(dispatcher, "unwarn", warnsModule.removeWarn)
+	helpers.AddCommand(dispatcher, "warns", warnsModule.warns)
 	helpers.AddCmdToDisableable("warns")
-	dispatcher.AddHandler(handlers.NewCommand("setwarnlimit", warnsModule.setWarnLimit))
-	dispatcher.AddHandler(handlers.NewCommand("setwarnmode", warnsModule.setWarnMode))
-	dispatcher.AddHandler(handlers.NewCommand("resetallwarns", warnsModule.resetAllWarns))
+	helpers.AddCommand(dispatcher, "setwarnlimit", warnsModule.setWarnLimit)
+	helpers.AddCommand(dispatcher, "setwarnmode", warnsModule.setWarnMode)
+	helpers.AddCommand(dispatcher, "resetallwarns", warnsModule.resetAllWarns)
 	dispatcher.AddHandler(handlers.NewCallback(callbackquery.Prefix("rmAllChatWarns"), warnsModule.warnsButtonHandler))
-	dispatcher.AddHandler(handlers.NewCommand("warnings", warnsModule.warnings))
+	helpers.AddCommand(dispatcher, "warnings", warnsModule.warnings)
 	dispatcher.AddHandler(handlers.NewCallback(callbackquery.Prefix("rmWarn"), warnsModule.rmWarnButton))
 }
 
@@ -79,6 +79,14 @@ func CanUserInvite(b *gotgbot.Bot, ctx *ext.Context, chat *gotgbot.Chat, userId
 	})
 }
 
+// CanUserManageTopics reports whether the user can create, edit, close and
+// delete forum topics.
+func CanUserManageTopics(b *gotgbot.Bot, ctx *ext.Context, chat *gotgbot.Chat, userId int64) bool {
+	return hasUserPermission(b, ctx, chat, userId, func(m *gotgbot.MergedChatMember) bool {
+		return m.CanManageTopics
+	})
+}
+
 // CanBotRestrict reports whether the bot can restrict members.
 func CanBotRestrict(b *gotgbot.Bot, ctx *ext.Context, chat *gotgbot.Chat) bool {
 	if b == nil {
@@ -164,6 +172,23 @@ func CanBotInvite(b *gotgbot.Bot, ctx *ext.Context, chat *gotgbot.Chat) bool {
 	return botMember.CanInviteUsers
 }
 
+// CanBotManageTopics reports whether the bot can manage forum topics.
+func CanBotManageTopics(b *gotgbot.Bot, ctx *ext.Context, chat *gotgbot.Chat) bool {
+	if b == nil {
+		return false
+	}
+	chat = extractChatFromContext(ctx, chat)
+	if chat == nil {
+		return false
+	}
+
+	botMember, ok := getUserMemberWithCache(b, chat, b.Id, "canBotManageTopics")
+	if !ok {
+		return false
+	}
+	return botMember.CanManageTopics
+}
+
 // RequireBotAdmin reports whether the bot is an admin.
 func RequireBotAdmin(b *gotgbot.Bot, ctx *ext.Context, chat *gotgbot.Chat) bool {
 	return IsBotAdmin(b, ctx, chat)
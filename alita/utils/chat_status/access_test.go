@@ -20,15 +20,15 @@ func (chatStatusBotClient) RequestWithContext(_ context.Context, _ string, metho
 	case "getChatMember":
 		switch fmt.Sprint(params["user_id"]) {
 		case "10":
-			return json.RawMessage(`{"status":"administrator","user":{"id":10,"is_bot":false,"first_name":"Full Admin"},"can_change_info":true,"can_restrict_members":true,"can_promote_members":true,"can_pin_messages":true,"can_delete_messages":true,"can_invite_users":true}`), nil
+			return json.RawMessage(`{"status":"administrator","user":{"id":10,"is_bot":false,"first_name":"Full Admin"},"can_change_info":true,"can_restrict_members":true,"can_promote_members":true,"can_pin_messages":true,"can_delete_messages":true,"can_invite_users":true,"can_manage_topics":true}`), nil
 		case "11":
-			return json.RawMessage(`{"status":"administrator","user":{"id":11,"is_bot":false,"first_name":"Limited Admin"},"can_change_info":false,"can_restrict_members":false,"can_promote_members":false,"can_pin_messages":false,"can_delete_messages":false,"can_invite_users":false}`), nil
+			return json.RawMessage(`{"status":"administrator","user":{"id":11,"is_bot":false,"first_name":"Limited Admin"},"can_change_info":false,"can_restrict_members":false,"can_promote_members":false,"can_pin_messages":false,"can_delete_messages":false,"can_invite_users":false,"can_manage_topics":false}`), nil
 		case "12":
 			return json.RawMessage(`{"status":"creator","user":{"id":12,"is_bot":false,"first_name":"Owner"}}`), nil
 		case "999":
-			return json.RawMessage(`{"status":"administrator","user":{"id":999,"is_bot":true,"first_name":"Bot"},"can_restrict_members":true,"can_promote_members":true,"can_pin_messages":true,"can_delete_messages":true,"can_invite_users":true}`), nil
+			return json.RawMessage(`{"status":"administrator","user":{"id":999,"is_bot":true,"first_name":"Bot"},"can_restrict_members":true,"can_promote_members":true,"can_pin_messages":true,"can_delete_messages":true,"can_invite_users":true,"can_manage_topics":true}`), nil
 		case "998":
-			return json.RawMessage(`{"status":"administrator","user":{"id":998,"is_bot":true,"first_name":"Limited Bot"},"can_restrict_members":false,"can_promote_members":false,"can_pin_messages":false,"can_delete_messages":false,"can_invite_users":false}`), nil
+			return json.RawMessage(`{"status":"administrator","user":{"id":998,"is_bot":true,"first_name":"Limited Bot"},"can_restrict_members":false,"can_promote_members":false,"can_pin_messages":false,"can_delete_messages":false,"can_invite_users":false,"can_manage_topics":false}`), nil
 		case "13":
 			return json.RawMessage(`{"status":"left","user":{"id":13,"is_bot":false,"first_name":"Left User"}}`), nil
 		case "14":
@@ -304,6 +304,7 @@ func TestPermissionHelpersUseGotgbotMemberPermissions(t *testing.T) {
 		{name: "pin", fn: func() bool { return CanUserPin(bot, ctx, chat, 10) }},
 		{name: "delete", fn: func() bool { return CanUserDelete(bot, ctx, chat, 10) }},
 		{name: "invite", fn: func() bool { return CanUserInvite(bot, ctx, chat, 10) }},
+		{name: "manage topics", fn: func() bool { return CanUserManageTopics(bot, ctx, chat, 10) }},
 	}
 
 	for _, tt := range tests {
@@ -349,6 +350,7 @@ func TestPermissionHelpersRejectMissingMemberPermissions(t *testing.T) {
 		{name: "pin", fn: func() bool { return CanUserPin(bot, ctx, chat, 11) }},
 		{name: "delete", fn: func() bool { return CanUserDelete(bot, ctx, chat, 11) }},
 		{name: "invite", fn: func() bool { return CanUserInvite(bot, ctx, chat, 11) }},
+		{name: "manage topics", fn: func() bool { return CanUserManageTopics(bot, ctx, chat, 11) }},
 	}
 
 	for _, tt := range tests {
@@ -374,6 +376,7 @@ func TestBotPermissionHelpersUseGotgbotMemberPermissions(t *testing.T) {
 		{name: "pin", fn: func() bool { return CanBotPin(fullBot, ctx, chat) }},
 		{name: "delete", fn: func() bool { return CanBotDelete(fullBot, ctx, chat) }},
 		{name: "invite", fn: func() bool { return CanBotInvite(fullBot, ctx, chat) }},
+		{name: "manage topics", fn: func() bool { return CanBotManageTopics(fullBot, ctx, chat) }},
 	}
 	for _, tt := range fullTests {
 		t.Run("full/"+tt.name, func(t *testing.T) {
@@ -393,6 +396,7 @@ func TestBotPermissionHelpersUseGotgbotMemberPermissions(t *testing.T) {
 		{name: "pin", fn: func() bool { return CanBotPin(limitedBot, ctx, chat) }},
 		{name: "delete", fn: func() bool { return CanBotDelete(limitedBot, ctx, chat) }},
 		{name: "invite", fn: func() bool { return CanBotInvite(limitedBot, ctx, chat) }},
+		{name: "manage topics", fn: func() bool { return CanBotManageTopics(limitedBot, ctx, chat) }},
 	}
 	for _, tt := range limitedTests {
 		t.Run("limited/"+tt.name, func(t *testing.T) {
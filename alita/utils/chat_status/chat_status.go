@@ -18,6 +18,7 @@ import (
 	"github.com/divkix/Alita_Robot/alita/db/connections"
 	"github.com/divkix/Alita_Robot/alita/db/disabling"
 	"github.com/divkix/Alita_Robot/alita/db/lang"
+	"github.com/divkix/Alita_Robot/alita/db/models"
 	"github.com/divkix/Alita_Robot/alita/i18n"
 	"github.com/divkix/Alita_Robot/alita/utils/cache"
 	"github.com/divkix/Alita_Robot/alita/utils/callbackcodec"
@@ -199,6 +200,51 @@ func CheckDisabledCmd(bot *gotgbot.Bot, msg *gotgbot.Message, cmd string) bool {
 	return true
 }
 
+// commandRightChecks maps the rights accepted in an admins:<right> command
+// policy to the permission check that enforces them.
+var commandRightChecks = map[string]func(*gotgbot.Bot, *ext.Context, *gotgbot.Chat, int64) bool{
+	models.CommandRightRestrict:   CanUserRestrict,
+	models.CommandRightDelete:     CanUserDelete,
+	models.CommandRightPin:        CanUserPin,
+	models.CommandRightPromote:    CanUserPromote,
+	models.CommandRightChangeInfo: CanUserChangeInfo,
+	models.CommandRightInvite:     CanUserInvite,
+}
+
+// IsCommandPermitted reports whether the sender of the current message satisfies
+// the chat's permission override for cmd (set with /cmdperm).
+// Commands without an override, private chats and anonymous admins always pass;
+// the command's own checks still run afterwards.
+func IsCommandPermitted(b *gotgbot.Bot, ctx *ext.Context, cmd string) bool {
+	if ctx == nil || ctx.EffectiveChat == nil || ctx.EffectiveChat.Type == "private" {
+		return true
+	}
+	chat := ctx.EffectiveChat
+
+	perm := disabling.GetCommandPermission(chat.Id, cmd)
+	if perm == nil || perm.Level == disabling.LevelEveryone {
+		return true
+	}
+	if sender := ctx.EffectiveSender; sender != nil && sender.IsAnonymousAdmin() {
+		return true
+	}
+	user := ctx.EffectiveUser
+	if user == nil {
+		return false
+	}
+
+	switch perm.Level {
+	case disabling.LevelApproved:
+		return IsUserAdmin(b, chat.Id, user.Id) || approvals.IsUserApproved(chat.Id, user.Id)
+	case disabling.LevelAdmins:
+		if check, ok := commandRightChecks[perm.RequiredRight]; ok {
+			return check(b, ctx, chat, user.Id)
+		}
+		return IsUserAdmin(b, chat.Id, user.Id)
+	}
+	return IsUserAdmin(b, chat.Id, user.Id)
+}
+
 // IsApproved checks if a user is in the approved whitelist for a chat.
 // Approved users are immune to anti-spam measures (antiflood, blacklists, locks, captcha, antispam).
 // This is a simple delegation to the DB layer for consistent usage in watcher handlers.
@@ -206,6 +252,13 @@ func IsApproved(b *gotgbot.Bot, chatID, userID int64) bool {
 	return approvals.IsUserApproved(chatID, userID)
 }
 
+// IsApprovedFor checks if a user's approval exempts them from a specific protection.
+// Each watcher passes its own scope (models.ApprovalScopeLocks, ApprovalScopeFlood, ...)
+// so scoped approvals only bypass the protections they were granted for.
+func IsApprovedFor(b *gotgbot.Bot, chatID, userID int64, scope string) bool {
+	return approvals.IsUserApprovedFor(chatID, userID, scope)
+}
+
 // IsUserAdmin checks if a user has administrator privileges in a chat.
 // Uses caching system to avoid repeated API calls and handles special Telegram admin accounts.
 // Returns true if the user is an admin, creator, or special Telegram account.
@@ -147,6 +147,28 @@ func extractMediaFromReply(replyMsg *gotgbot.Message) (fileid string, dataType i
 	return "", -1
 }
 
+// MediaItemFromMessage converts a message of a media album into the item
+// stored for album notes and filters, with its caption as HTML. Only the
+// types Telegram can group into an album qualify: photos, videos, documents
+// and audio files.
+func MediaItemFromMessage(msg *gotgbot.Message) (db.MediaItem, bool) {
+	if msg == nil || msg.Animation != nil {
+		return db.MediaItem{}, false
+	}
+	fileID, dataType := extractMediaFromReply(msg)
+	switch dataType {
+	case db.PHOTO, db.VIDEO, db.DOCUMENT, db.AUDIO:
+	default:
+		return db.MediaItem{}, false
+	}
+	caption, _ := tgmd2html.MD2HTMLButtonsV2(msg.OriginalCaptionMDV2())
+	return db.MediaItem{
+		FileID:  fileID,
+		MsgType: dataType,
+		Caption: strings.Trim(caption, "\n\t\r "),
+	}, true
+}
+
 // ExtractWelcome extracts and processes welcome/greeting content from a Telegram message.
 // Similar to ExtractNoteAndFilter but specifically for greeting messages.
 // Returns processed content with data type, file ID, and buttons for the greeting.
@@ -267,6 +267,43 @@ func TestExtractMediaFromReply(t *testing.T) {
 	}
 }
 
+// ---------------------------------------------------------------------------
+// MediaItemFromMessage
+// ---------------------------------------------------------------------------
+
+func TestMediaItemFromMessage(t *testing.T) {
+	t.Parallel()
+
+	item, ok := MediaItemFromMessage(&gotgbot.Message{
+		Photo:   []gotgbot.PhotoSize{{FileId: "small", Width: 90}, {FileId: "large", Width: 800}},
+		Caption: "bold caption",
+		CaptionEntities: []gotgbot.MessageEntity{
+			{Type: "bold", Offset: 0, Length: 4},
+		},
+	})
+	if !ok {
+		t.Fatal("expected photo to be an album item")
+	}
+	if item.FileID != "large" || item.MsgType != db.PHOTO {
+		t.Fatalf("item = %#v, want the largest photo", item)
+	}
+	if item.Caption != "<b>bold</b> caption" {
+		t.Fatalf("caption = %q, want HTML caption", item.Caption)
+	}
+
+	for name, msg := range map[string]*gotgbot.Message{
+		"nil":       nil,
+		"text":      {Text: "hello"},
+		"sticker":   {Sticker: &gotgbot.Sticker{FileId: "sticker"}},
+		"voice":     {Voice: &gotgbot.Voice{FileId: "voice"}},
+		"animation": {Animation: &gotgbot.Animation{FileId: "anim"}, Document: &gotgbot.Document{FileId: "anim"}},
+	} {
+		if _, ok := MediaItemFromMessage(msg); ok {
+			t.Fatalf("%s: expected no album item", name)
+		}
+	}
+}
+
 // ---------------------------------------------------------------------------
 // setRawText
 // ---------------------------------------------------------------------------
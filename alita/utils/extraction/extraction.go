@@ -383,6 +383,17 @@ func ExtractTime(b *gotgbot.Bot, ctx *ext.Context, inputVal string) (banTime int
 	}
 }
 
+// ParseTemporaryDuration parses a duration token such as 30m, 12h, 7d, or 2w
+// without replying to the user. It returns the resulting Unix timestamp, a
+// human-readable duration, and whether the token was a valid duration.
+func ParseTemporaryDuration(token string, now int64) (untilDate int64, timeStr string, ok bool) {
+	if strings.ContainsAny(token, " \t\n") {
+		return 0, "", false
+	}
+	untilDate, timeStr, _, err := parseTemporaryDuration(token, now)
+	return untilDate, timeStr, err == nil
+}
+
 func parseTemporaryDuration(inputVal string, now int64) (banTime int64, timeStr, reason string, err error) {
 	args := strings.Fields(inputVal)
 	if len(args) == 0 {
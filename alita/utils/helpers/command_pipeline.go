@@ -1,10 +1,14 @@
 package helpers
 
 import (
+	"sync"
+
 	"github.com/PaulSonOfLars/gotgbot/v2"
 	"github.com/PaulSonOfLars/gotgbot/v2/ext"
 	"github.com/PaulSonOfLars/gotgbot/v2/ext/handlers"
+	log "github.com/sirupsen/logrus"
 
+	"github.com/divkix/Alita_Robot/alita/db/disabling"
 	"github.com/divkix/Alita_Robot/alita/db/lang"
 	"github.com/divkix/Alita_Robot/alita/i18n"
 	"github.com/divkix/Alita_Robot/alita/utils/chat_status"
@@ -64,7 +68,8 @@ type CommandDescriptor struct {
 
 // WrapCommand registers a command with the dispatcher, running all declared
 // checks before invoking the business logic handler. If a check returns false,
-// the pipeline short-circuits with ext.EndGroups.
+// the pipeline short-circuits with ext.EndGroups. The chat's /cmdperm override
+// for desc.Name is always checked first.
 //
 // The handler receives a pre-built *CommandContext containing Bot, Ctx, Chat,
 // Msg, User, and Tr. Use this for new handlers; for gradual migration of
@@ -80,6 +85,9 @@ func WrapCommand(
 		if err != nil {
 			return ext.EndGroups
 		}
+		if !CheckCommandPermission(desc.Name)(c) {
+			return ext.EndGroups
+		}
 		for _, check := range desc.RequiredChecks {
 			if !check(c) {
 				return ext.EndGroups
@@ -108,6 +116,9 @@ func WrapCommandRaw(
 		if err != nil {
 			return ext.EndGroups
 		}
+		if !CheckCommandPermission(desc.Name)(c) {
+			return ext.EndGroups
+		}
 		for _, check := range desc.RequiredChecks {
 			if !check(c) {
 				return ext.EndGroups
@@ -118,15 +129,38 @@ func WrapCommandRaw(
 	register(dispatcher, desc, h)
 }
 
+// pipelineCmds maps every command registered through the pipeline (including
+// aliases) to its descriptor name, which is the key for /cmdperm overrides.
+var (
+	pipelineCmds   = map[string]string{}
+	pipelineCmdsMu sync.RWMutex
+)
+
+// PipelineCommandName returns the descriptor name for a command registered
+// through WrapCommand or WrapCommandRaw, and whether it was registered there.
+// Such commands evaluate their /cmdperm override inside the pipeline.
+func PipelineCommandName(cmd string) (string, bool) {
+	pipelineCmdsMu.RLock()
+	defer pipelineCmdsMu.RUnlock()
+	name, ok := pipelineCmds[cmd]
+	return name, ok
+}
+
 // register wires a handler (already wrapped) into the dispatcher.
 func register(dispatcher *ext.Dispatcher, desc CommandDescriptor, h handlers.Response) {
 	cmds := append([]string{desc.Name}, desc.Aliases...)
+	pipelineCmdsMu.Lock()
+	for _, c := range cmds {
+		pipelineCmds[c] = desc.Name
+	}
+	pipelineCmdsMu.Unlock()
 	for _, c := range cmds {
 		if desc.Group != 0 {
 			dispatcher.AddHandlerToGroup(handlers.NewCommand(c, h), desc.Group)
 		} else {
 			dispatcher.AddHandler(handlers.NewCommand(c, h))
 		}
+		recordCommand(c)
 		if desc.Disableable {
 			AddCmdToDisableable(c)
 		}
@@ -149,6 +183,25 @@ func CheckDisabled(cmdName string) CheckFunc {
 	}
 }
 
+// CheckCommandPermission returns a CheckFunc that enforces the chat's
+// /cmdperm override for cmdName. Blocked messages are deleted when the chat
+// deletes disabled commands, otherwise the user is told they lack permission.
+func CheckCommandPermission(cmdName string) CheckFunc {
+	return func(c *CommandContext) bool {
+		if chat_status.IsCommandPermitted(c.Bot, c.Ctx, cmdName) {
+			return true
+		}
+		if c.Msg != nil && c.Chat != nil && disabling.ShouldDel(c.Chat.Id) {
+			if _, err := c.Msg.Delete(c.Bot, nil); err != nil {
+				log.Errorf("[CheckCommandPermission] Failed to delete message for command '%s' in chat %d: %v", cmdName, c.Chat.Id, err)
+			}
+			return false
+		}
+		chat_status.NewPermissionResponder(c.Bot).Respond(c.Ctx, "chat_status_command_not_permitted", "", chat_status.WithReply())
+		return false
+	}
+}
+
 // RequireGroup returns a CheckFunc that ensures the chat is a group
 // (not private). If the check fails, an error message is sent automatically.
 func RequireGroup() CheckFunc {
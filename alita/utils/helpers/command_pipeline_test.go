@@ -626,4 +626,10 @@ func TestRegisterWithGroup(t *testing.T) {
 			t.Fatalf("expected handler to be called for %s", text)
 		}
 	}
+
+	for _, cmd := range []string{"groupcmd", "groupcmda"} {
+		if name, ok := PipelineCommandName(cmd); !ok || name != "groupcmd" {
+			t.Fatalf("PipelineCommandName(%q) = %q, %v; want groupcmd, true", cmd, name, ok)
+		}
+	}
 }
@@ -1,6 +1,7 @@
 package helpers
 
 import (
+	"slices"
 	"sync"
 
 	"github.com/PaulSonOfLars/gotgbot/v2/ext"
@@ -11,12 +12,22 @@ import (
 var (
 	DisableCmds = make([]string, 0)
 	cmdsMu      = &sync.Mutex{}
+
+	// registeredCmds holds every command name registered with the dispatcher.
+	registeredCmds = map[string]struct{}{}
 )
 
+// AddCommand registers a command handler with the dispatcher and records its
+// name, so aliases and /cmdperm can tell real commands from unknown ones.
+func AddCommand(dispatcher *ext.Dispatcher, cmd string, r handlers.Response) {
+	dispatcher.AddHandler(handlers.NewCommand(cmd, r))
+	recordCommand(cmd)
+}
+
 // MultiCommand registers multiple command aliases with the same handler function.
 func MultiCommand(dispatcher *ext.Dispatcher, alias []string, r handlers.Response) {
 	for _, cmd := range alias {
-		dispatcher.AddHandler(handlers.NewCommand(cmd, r))
+		AddCommand(dispatcher, cmd, r)
 	}
 }
 
@@ -26,3 +37,30 @@ func AddCmdToDisableable(cmd string) {
 	DisableCmds = append(DisableCmds, cmd)
 	cmdsMu.Unlock()
 }
+
+// recordCommand marks cmd as a registered command.
+func recordCommand(cmd string) {
+	cmdsMu.Lock()
+	registeredCmds[cmd] = struct{}{}
+	cmdsMu.Unlock()
+}
+
+// IsRegisteredCommand reports whether cmd is a command handled by the bot.
+func IsRegisteredCommand(cmd string) bool {
+	cmdsMu.Lock()
+	defer cmdsMu.Unlock()
+	_, ok := registeredCmds[cmd]
+	return ok
+}
+
+// RegisteredCommands returns every registered command name, sorted.
+func RegisteredCommands() []string {
+	cmdsMu.Lock()
+	defer cmdsMu.Unlock()
+	cmds := make([]string, 0, len(registeredCmds))
+	for cmd := range registeredCmds {
+		cmds = append(cmds, cmd)
+	}
+	slices.Sort(cmds)
+	return cmds
+}
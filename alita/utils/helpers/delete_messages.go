@@ -0,0 +1,158 @@
+package helpers
+
+import (
+	stderrors "errors"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/PaulSonOfLars/gotgbot/v2"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/divkix/Alita_Robot/alita/utils/errors"
+)
+
+const (
+	// MaxDeleteBatch is the most message IDs one deleteMessages call takes.
+	MaxDeleteBatch = 100
+	// maxDeleteRetries is how often a rate limited batch is tried again
+	// before its messages are given up on.
+	maxDeleteRetries = 3
+	// deleteQueueDelay is how long queued deletions wait for more messages
+	// of the same chat before they are sent.
+	deleteQueueDelay = 500 * time.Millisecond
+)
+
+// retrySleep waits out retry_after; tests replace it.
+var retrySleep = time.Sleep
+
+// DeleteProgressFunc is told after each batch how many of the messages have
+// been handled so far.
+type DeleteProgressFunc func(done, total int)
+
+// isGoneDeleteError reports whether a deletion failed only because the
+// messages are already gone or too old to delete.
+func isGoneDeleteError(err error) bool {
+	errStr := err.Error()
+	return strings.Contains(errStr, "message to delete not found") ||
+		strings.Contains(errStr, "message can't be deleted")
+}
+
+// deleteRetryAfter returns how long Telegram asked to wait when it rate
+// limited a request.
+func deleteRetryAfter(err error) (time.Duration, bool) {
+	var tgErr *gotgbot.TelegramError
+	if !stderrors.As(err, &tgErr) || tgErr.Code != http.StatusTooManyRequests ||
+		tgErr.ResponseParams == nil || tgErr.ResponseParams.RetryAfter <= 0 {
+		return 0, false
+	}
+	return time.Duration(tgErr.ResponseParams.RetryAfter) * time.Second, true
+}
+
+// deleteBatch deletes up to MaxDeleteBatch messages in one call, waiting out
+// rate limits. A single message goes through deleteMessage.
+func deleteBatch(bot *gotgbot.Bot, chatID int64, ids []int64) error {
+	var err error
+	for attempt := 0; ; attempt++ {
+		if len(ids) == 1 {
+			_, err = bot.DeleteMessage(chatID, ids[0], nil)
+		} else {
+			_, err = bot.DeleteMessages(chatID, ids, nil)
+		}
+		if err == nil || isGoneDeleteError(err) {
+			return nil
+		}
+		wait, limited := deleteRetryAfter(err)
+		if !limited || attempt == maxDeleteRetries {
+			return errors.Wrapf(err, "failed to delete %d messages in chat %d", len(ids), chatID)
+		}
+		log.WithFields(log.Fields{
+			"chat_id":     chatID,
+			"messages":    len(ids),
+			"retry_after": wait,
+		}).Debug("[Helpers] Deletion rate limited, waiting")
+		retrySleep(wait)
+	}
+}
+
+// DeleteMessagesBatched deletes messages of a chat in order, with as few
+// API calls as possible: batches of up to MaxDeleteBatch go through
+// deleteMessages and rate limited batches are retried after retry_after.
+// Messages already gone or too old are skipped, as in
+// DeleteMessageWithErrorHandling. A failed batch does not stop the rest;
+// the first error is returned. progress may be nil.
+func DeleteMessagesBatched(bot *gotgbot.Bot, chatID int64, ids []int64, progress DeleteProgressFunc) error {
+	var firstErr error
+	for start := 0; start < len(ids); start += MaxDeleteBatch {
+		end := min(start+MaxDeleteBatch, len(ids))
+		if err := deleteBatch(bot, chatID, ids[start:end]); err != nil && firstErr == nil {
+			firstErr = err
+		}
+		if progress != nil {
+			progress(end, len(ids))
+		}
+	}
+	return firstErr
+}
+
+// queuedDeletions are the messages of a chat waiting to be deleted together.
+type queuedDeletions struct {
+	bot   *gotgbot.Bot
+	ids   []int64
+	timer *time.Timer
+}
+
+var (
+	deleteQueueMu sync.Mutex
+	deleteQueue   = make(map[int64]*queuedDeletions)
+)
+
+// QueueMessageDeletion deletes a message soon, together with the other
+// messages of its chat queued in the meantime. Use it for messages removed
+// one at a time as they arrive, so a burst of them costs one API call.
+func QueueMessageDeletion(bot *gotgbot.Bot, chatID, messageID int64) {
+	deleteQueueMu.Lock()
+	defer deleteQueueMu.Unlock()
+
+	queued, ok := deleteQueue[chatID]
+	if !ok {
+		queued = &queuedDeletions{bot: bot}
+		queued.timer = time.AfterFunc(deleteQueueDelay, func() { flushQueuedDeletions(chatID) })
+		deleteQueue[chatID] = queued
+	}
+	queued.ids = append(queued.ids, messageID)
+	if len(queued.ids) >= MaxDeleteBatch {
+		queued.timer.Stop()
+		go flushQueuedDeletions(chatID)
+	}
+}
+
+// flushQueuedDeletions deletes the queued messages of a chat.
+func flushQueuedDeletions(chatID int64) {
+	deleteQueueMu.Lock()
+	queued, ok := deleteQueue[chatID]
+	delete(deleteQueue, chatID)
+	deleteQueueMu.Unlock()
+	if !ok {
+		return
+	}
+	if err := DeleteMessagesBatched(queued.bot, chatID, queued.ids, nil); err != nil {
+		log.WithError(err).Warn("[Helpers] Some queued messages could not be deleted")
+	}
+}
+
+// FlushQueuedDeletions deletes all queued messages now, for shutdown.
+func FlushQueuedDeletions() {
+	deleteQueueMu.Lock()
+	chatIDs := make([]int64, 0, len(deleteQueue))
+	for chatID, queued := range deleteQueue {
+		queued.timer.Stop()
+		chatIDs = append(chatIDs, chatID)
+	}
+	deleteQueueMu.Unlock()
+
+	for _, chatID := range chatIDs {
+		flushQueuedDeletions(chatID)
+	}
+}
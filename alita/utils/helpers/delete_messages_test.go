@@ -0,0 +1,183 @@
+package helpers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/PaulSonOfLars/gotgbot/v2"
+)
+
+// deleteClient records deletions and fails the next calls with the queued
+// errors.
+type deleteClient struct {
+	mu      sync.Mutex
+	batches [][]int64
+	errs    []error
+}
+
+func (c *deleteClient) RequestWithContext(_ context.Context, _ string, method string, params map[string]any, _ *gotgbot.RequestOpts) (json.RawMessage, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	switch method {
+	case "deleteMessage":
+		c.batches = append(c.batches, []int64{params["message_id"].(int64)})
+	case "deleteMessages":
+		c.batches = append(c.batches, params["message_ids"].([]int64))
+	}
+	if len(c.errs) > 0 {
+		err := c.errs[0]
+		c.errs = c.errs[1:]
+		if err != nil {
+			return nil, err
+		}
+	}
+	return json.RawMessage(`true`), nil
+}
+
+func (c *deleteClient) GetAPIURL(*gotgbot.RequestOpts) string {
+	return gotgbot.DefaultAPIURL
+}
+
+func (c *deleteClient) FileURL(token string, path string, _ *gotgbot.RequestOpts) string {
+	return gotgbot.DefaultAPIURL + "/file/bot" + token + "/" + path
+}
+
+func (c *deleteClient) sizes() []int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	sizes := make([]int, len(c.batches))
+	for i, batch := range c.batches {
+		sizes[i] = len(batch)
+	}
+	return sizes
+}
+
+func newDeleteBot(client *deleteClient) *gotgbot.Bot {
+	return &gotgbot.Bot{Token: "999:test", BotClient: client, User: gotgbot.User{Id: 999, IsBot: true}}
+}
+
+func messageRange(n int) []int64 {
+	ids := make([]int64, n)
+	for i := range ids {
+		ids[i] = int64(n - i)
+	}
+	return ids
+}
+
+func rateLimited(seconds int64) error {
+	return &gotgbot.TelegramError{
+		Method:         "deleteMessages",
+		Code:           429,
+		Description:    fmt.Sprintf("Too Many Requests: retry after %d", seconds),
+		ResponseParams: &gotgbot.ResponseParameters{RetryAfter: seconds},
+	}
+}
+
+func stubRetrySleep(t *testing.T) *[]time.Duration {
+	t.Helper()
+	var waits []time.Duration
+	old := retrySleep
+	retrySleep = func(d time.Duration) { waits = append(waits, d) }
+	t.Cleanup(func() { retrySleep = old })
+	return &waits
+}
+
+func TestDeleteMessagesBatchedSplitsIntoBatchesAndReportsProgress(t *testing.T) {
+	client := &deleteClient{}
+	var reports []string
+	err := DeleteMessagesBatched(newDeleteBot(client), -1001, messageRange(250), func(done, total int) {
+		reports = append(reports, fmt.Sprintf("%d/%d", done, total))
+	})
+	if err != nil {
+		t.Fatalf("DeleteMessagesBatched() error = %v", err)
+	}
+	if got := fmt.Sprint(client.sizes()); got != "[100 100 50]" {
+		t.Fatalf("batch sizes = %s, want [100 100 50]", got)
+	}
+	if got := fmt.Sprint(reports); got != "[100/250 200/250 250/250]" {
+		t.Fatalf("progress = %s", got)
+	}
+	if first := client.batches[0][0]; first != 250 {
+		t.Fatalf("first deleted message = %d, want the order kept", first)
+	}
+
+	single := &deleteClient{}
+	if err := DeleteMessagesBatched(newDeleteBot(single), -1001, []int64{7}, nil); err != nil {
+		t.Fatalf("DeleteMessagesBatched(single) error = %v", err)
+	}
+	if got := fmt.Sprint(single.sizes()); got != "[1]" {
+		t.Fatalf("batch sizes = %s, want one deleteMessage", got)
+	}
+}
+
+func TestDeleteMessagesBatchedWaitsOutRateLimits(t *testing.T) {
+	waits := stubRetrySleep(t)
+	client := &deleteClient{errs: []error{rateLimited(3), rateLimited(5)}}
+	if err := DeleteMessagesBatched(newDeleteBot(client), -1001, messageRange(150), nil); err != nil {
+		t.Fatalf("DeleteMessagesBatched() error = %v", err)
+	}
+	if got := fmt.Sprint(client.sizes()); got != "[100 100 100 50]" {
+		t.Fatalf("batch sizes = %s, want the first batch retried twice", got)
+	}
+	if got := fmt.Sprint(*waits); got != "[3s 5s]" {
+		t.Fatalf("waits = %s, want retry_after honored", got)
+	}
+}
+
+func TestDeleteMessagesBatchedGivesUpAndContinues(t *testing.T) {
+	waits := stubRetrySleep(t)
+	client := &deleteClient{errs: []error{
+		rateLimited(1), rateLimited(1), rateLimited(1), rateLimited(1),
+		fmt.Errorf("Bad Request: message to delete not found"),
+	}}
+	err := DeleteMessagesBatched(newDeleteBot(client), -1001, messageRange(300), nil)
+	if err == nil {
+		t.Fatal("DeleteMessagesBatched() error = nil, want the rate limited batch reported")
+	}
+	if got := fmt.Sprint(client.sizes()); got != "[100 100 100 100 100 100]" {
+		t.Fatalf("batch sizes = %s, want the later batches still deleted", got)
+	}
+	if len(*waits) != maxDeleteRetries {
+		t.Fatalf("waits = %d, want %d retries", len(*waits), maxDeleteRetries)
+	}
+
+	client = &deleteClient{errs: []error{fmt.Errorf("Bad Request: message can't be deleted")}}
+	if err := DeleteMessagesBatched(newDeleteBot(client), -1001, messageRange(2), nil); err != nil {
+		t.Fatalf("DeleteMessagesBatched() error = %v, want gone messages ignored", err)
+	}
+}
+
+func TestQueueMessageDeletionCoalescesPerChat(t *testing.T) {
+	client := &deleteClient{}
+	bot := newDeleteBot(client)
+	for id := int64(1); id <= 3; id++ {
+		QueueMessageDeletion(bot, -1001, id)
+	}
+	QueueMessageDeletion(bot, -1002, 9)
+	if got := client.sizes(); len(got) != 0 {
+		t.Fatalf("batch sizes = %v before the queue delay, want none", got)
+	}
+
+	FlushQueuedDeletions()
+	sizes := client.sizes()
+	if len(sizes) != 2 || sizes[0]+sizes[1] != 4 {
+		t.Fatalf("batch sizes = %v, want one batch per chat", sizes)
+	}
+
+	for id := int64(1); id <= MaxDeleteBatch; id++ {
+		QueueMessageDeletion(bot, -1003, id)
+	}
+	deadline := time.Now().Add(deleteQueueDelay / 2)
+	for len(client.sizes()) == 2 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if got := client.sizes(); len(got) != 3 || got[2] != MaxDeleteBatch {
+		t.Fatalf("batch sizes = %v, want a full queue sent at once", got)
+	}
+}
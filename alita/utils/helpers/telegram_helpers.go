@@ -12,13 +12,11 @@ import (
 func DeleteMessageWithErrorHandling(bot *gotgbot.Bot, chatId, messageId int64) error {
 	_, err := bot.DeleteMessage(chatId, messageId, nil)
 	if err != nil {
-		errStr := err.Error()
-		if strings.Contains(errStr, "message to delete not found") ||
-			strings.Contains(errStr, "message can't be deleted") {
+		if isGoneDeleteError(err) {
 			log.WithFields(log.Fields{
 				"chat_id":    chatId,
 				"message_id": messageId,
-				"error":      errStr,
+				"error":      err.Error(),
 			}).Debug("Message already deleted or can't be deleted")
 			return nil
 		}
@@ -119,3 +117,29 @@ func IsExpectedTelegramError(err error) bool {
 
 	return false
 }
+
+// ExtractCommand returns the lower-cased command name a message invokes,
+// mirroring how gotgbot's command handler matches "/cmd" and "/cmd@bot".
+// Returns an empty string for non-commands and commands addressed to another bot.
+func ExtractCommand(bot *gotgbot.Bot, msg *gotgbot.Message) string {
+	if msg == nil {
+		return ""
+	}
+	ents := msg.GetEntities()
+	if len(ents) != 0 && ents[0].Offset == 0 && ents[0].Type != "bot_command" {
+		return ""
+	}
+	text := msg.GetText()
+	if !strings.HasPrefix(text, "/") {
+		return ""
+	}
+	firstField := text
+	if end := strings.IndexAny(text, " \t\n\v\f\r"); end != -1 {
+		firstField = text[:end]
+	}
+	cmd, target, addressed := strings.Cut(strings.ToLower(firstField[1:]), "@")
+	if addressed && (bot == nil || target != strings.ToLower(bot.Username)) {
+		return ""
+	}
+	return cmd
+}
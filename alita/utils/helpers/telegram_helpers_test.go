@@ -303,3 +303,22 @@ func TestIsExpectedTelegramError_ErrNoPermission(t *testing.T) {
 		t.Fatalf("IsExpectedTelegramError(media.ErrNoPermission) expected true (ErrNoPermission should be suppressed); got false for %q", media.ErrNoPermission.Error())
 	}
 }
+
+func TestExtractCommand(t *testing.T) {
+	bot := &gotgbot.Bot{User: gotgbot.User{Username: "AlitaRobot"}}
+	tests := []struct {
+		text string
+		want string
+	}{
+		{"/Notes", "notes"},
+		{"/warn@alitarobot reason", "warn"},
+		{"/warn@OtherBot reason", ""},
+		{"hello /notes", ""},
+		{"", ""},
+	}
+	for _, tc := range tests {
+		if got := helpers.ExtractCommand(bot, &gotgbot.Message{Text: tc.text}); got != tc.want {
+			t.Fatalf("ExtractCommand(%q) = %q, want %q", tc.text, got, tc.want)
+		}
+	}
+}
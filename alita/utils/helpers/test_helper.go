@@ -19,3 +19,23 @@ func SetDisableCmdsForTest(t *testing.T, cmds []string) {
 		cmdsMu.Unlock()
 	})
 }
+
+// SetRegisteredCmdsForTest replaces the registered command names for the
+// duration of a test.
+func SetRegisteredCmdsForTest(t *testing.T, cmds []string) {
+	t.Helper()
+
+	cmdsMu.Lock()
+	original := registeredCmds
+	registeredCmds = make(map[string]struct{}, len(cmds))
+	for _, cmd := range cmds {
+		registeredCmds[cmd] = struct{}{}
+	}
+	cmdsMu.Unlock()
+
+	t.Cleanup(func() {
+		cmdsMu.Lock()
+		registeredCmds = original
+		cmdsMu.Unlock()
+	})
+}
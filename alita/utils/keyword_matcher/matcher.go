@@ -63,10 +63,16 @@ func (km *KeywordMatcher) build() {
 }
 
 // FirstMatch returns the first pattern that matches the given text.
+func (km *KeywordMatcher) FirstMatch(text string) (string, bool) {
+	return km.FirstMatchWhere(text, nil)
+}
+
+// FirstMatchWhere returns the first pattern that matches the given text and
+// is accepted by keep; a nil keep accepts every pattern.
 // ahocorasick.Matcher.Match is not safe for concurrent use, so we hold an
 // exclusive lock across the Match call. ToLower is done outside the lock to
 // reduce contention.
-func (km *KeywordMatcher) FirstMatch(text string) (string, bool) {
+func (km *KeywordMatcher) FirstMatchWhere(text string, keep func(pattern string) bool) (string, bool) {
 	lowerText := strings.ToLower(text)
 
 	km.mu.Lock()
@@ -76,14 +82,13 @@ func (km *KeywordMatcher) FirstMatch(text string) (string, bool) {
 		return "", false
 	}
 
-	hits := km.matcher.Match([]byte(lowerText))
-	if len(hits) == 0 {
-		return "", false
-	}
-
-	firstIdx := hits[0]
-	if firstIdx >= 0 && firstIdx < len(km.patterns) {
-		return km.patterns[firstIdx], true
+	for _, idx := range km.matcher.Match([]byte(lowerText)) {
+		if idx < 0 || idx >= len(km.patterns) {
+			continue
+		}
+		if keep == nil || keep(km.patterns[idx]) {
+			return km.patterns[idx], true
+		}
 	}
 	return "", false
 }
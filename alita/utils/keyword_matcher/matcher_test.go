@@ -99,6 +99,28 @@ func TestFirstMatch(t *testing.T) {
 	}
 }
 
+func TestFirstMatchWhere(t *testing.T) {
+	t.Parallel()
+
+	km := newKeywordMatcher([]string{"alpha", "beta", "gamma"})
+
+	got, ok := km.FirstMatchWhere("alpha beta gamma", func(pattern string) bool {
+		return pattern != "alpha"
+	})
+	if !ok || got != "beta" {
+		t.Fatalf("FirstMatchWhere() = %q, %v, want %q, true", got, ok, "beta")
+	}
+
+	if _, ok := km.FirstMatchWhere("alpha", func(string) bool { return false }); ok {
+		t.Fatalf("FirstMatchWhere() matched a rejected pattern")
+	}
+
+	got, ok = km.FirstMatchWhere("say gamma", nil)
+	if !ok || got != "gamma" {
+		t.Fatalf("FirstMatchWhere(nil) = %q, %v, want %q, true", got, ok, "gamma")
+	}
+}
+
 func TestConcurrentAccess(t *testing.T) {
 	t.Parallel()
 
@@ -0,0 +1,101 @@
+package media
+
+import (
+	"github.com/PaulSonOfLars/gotgbot/v2"
+
+	"github.com/divkix/Alita_Robot/alita/db"
+)
+
+// InlineResult builds the inline query result for media content, using the
+// same message type mapping as Send. Text (and media without a file ID) is
+// returned as an article; media types use their cached-file result so the
+// stored file ID is reused. Video notes and albums have no inline result
+// type, so ok is false for them.
+func InlineResult(id, title string, content Content, opts Options) (result gotgbot.InlineQueryResult, ok bool) {
+	if len(content.Media) > 1 {
+		return nil, false
+	}
+
+	parseMode := HTML
+	if opts.NoFormat {
+		parseMode = None
+	}
+
+	if content.FileID == "" && content.MsgType != db.TEXT {
+		content.MsgType = db.TEXT
+	}
+
+	switch content.MsgType {
+	case db.TEXT, 0:
+		if content.Text == "" {
+			return nil, false
+		}
+		return gotgbot.InlineQueryResultArticle{
+			Id:    id,
+			Title: title,
+			InputMessageContent: gotgbot.InputTextMessageContent{
+				MessageText: content.Text,
+				ParseMode:   parseMode,
+				LinkPreviewOptions: &gotgbot.LinkPreviewOptions{
+					IsDisabled: !opts.WebPreview,
+				},
+			},
+			ReplyMarkup: opts.Keyboard,
+		}, true
+	case db.STICKER:
+		return gotgbot.InlineQueryResultCachedSticker{
+			Id:            id,
+			StickerFileId: content.FileID,
+			ReplyMarkup:   opts.Keyboard,
+		}, true
+	case db.DOCUMENT:
+		return gotgbot.InlineQueryResultCachedDocument{
+			Id:             id,
+			Title:          title,
+			DocumentFileId: content.FileID,
+			Caption:        content.Text,
+			ParseMode:      parseMode,
+			ReplyMarkup:    opts.Keyboard,
+		}, true
+	case db.PHOTO:
+		return gotgbot.InlineQueryResultCachedPhoto{
+			Id:          id,
+			Title:       title,
+			PhotoFileId: content.FileID,
+			Caption:     content.Text,
+			ParseMode:   parseMode,
+			ReplyMarkup: opts.Keyboard,
+		}, true
+	case db.AUDIO:
+		return gotgbot.InlineQueryResultCachedAudio{
+			Id:          id,
+			AudioFileId: content.FileID,
+			Caption:     content.Text,
+			ParseMode:   parseMode,
+			ReplyMarkup: opts.Keyboard,
+		}, true
+	case db.VOICE:
+		return gotgbot.InlineQueryResultCachedVoice{
+			Id:          id,
+			Title:       title,
+			VoiceFileId: content.FileID,
+			Caption:     content.Text,
+			ParseMode:   parseMode,
+			ReplyMarkup: opts.Keyboard,
+		}, true
+	case db.VIDEO:
+		return gotgbot.InlineQueryResultCachedVideo{
+			Id:          id,
+			Title:       title,
+			VideoFileId: content.FileID,
+			Caption:     content.Text,
+			ParseMode:   parseMode,
+			ReplyMarkup: opts.Keyboard,
+		}, true
+	case db.VIDEO_NOTE:
+		return nil, false
+	default:
+		content.MsgType = db.TEXT
+		return InlineResult(id, title, content, opts)
+	}
+}
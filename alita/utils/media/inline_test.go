@@ -0,0 +1,60 @@
+package media
+
+import (
+	"testing"
+
+	"github.com/PaulSonOfLars/gotgbot/v2"
+
+	"github.com/divkix/Alita_Robot/alita/db"
+)
+
+func TestInlineResultMapsMessageTypes(t *testing.T) {
+	t.Parallel()
+
+	kb := &gotgbot.InlineKeyboardMarkup{}
+	tests := []struct {
+		name    string
+		content Content
+		want    string
+		wantOK  bool
+	}{
+		{"text", Content{Text: "hi", MsgType: db.TEXT}, "article", true},
+		{"legacy zero type", Content{Text: "hi"}, "article", true},
+		{"empty text", Content{MsgType: db.TEXT}, "", false},
+		{"sticker", Content{FileID: "f", MsgType: db.STICKER}, "sticker", true},
+		{"document", Content{FileID: "f", MsgType: db.DOCUMENT}, "document", true},
+		{"photo", Content{FileID: "f", MsgType: db.PHOTO}, "photo", true},
+		{"audio", Content{FileID: "f", MsgType: db.AUDIO}, "audio", true},
+		{"voice", Content{FileID: "f", MsgType: db.VOICE}, "voice", true},
+		{"video", Content{FileID: "f", MsgType: db.VIDEO}, "video", true},
+		{"video note", Content{FileID: "f", MsgType: db.VIDEO_NOTE}, "", false},
+		{"photo without file", Content{Text: "caption", MsgType: db.PHOTO}, "article", true},
+		{"album", Content{FileID: "f", MsgType: db.PHOTO, Media: []db.MediaItem{{FileID: "f", MsgType: db.PHOTO}, {FileID: "g", MsgType: db.PHOTO}}}, "", false},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			result, ok := InlineResult("id", "title", tc.content, Options{Keyboard: kb})
+			if ok != tc.wantOK {
+				t.Fatalf("InlineResult() ok = %v, want %v", ok, tc.wantOK)
+			}
+			if ok && result.GetType() != tc.want {
+				t.Fatalf("InlineResult() type = %q, want %q", result.GetType(), tc.want)
+			}
+		})
+	}
+}
+
+func TestInlineResultTextUsesFormattingAndPreview(t *testing.T) {
+	t.Parallel()
+
+	result, ok := InlineResult("id", "title", Content{Text: "<b>hi</b>"}, Options{WebPreview: true})
+	if !ok {
+		t.Fatal("InlineResult() ok = false, want true")
+	}
+	article := result.(gotgbot.InlineQueryResultArticle)
+	content := article.InputMessageContent.(gotgbot.InputTextMessageContent)
+	if content.ParseMode != HTML || content.LinkPreviewOptions.IsDisabled {
+		t.Fatalf("article content = %+v, want HTML with preview enabled", content)
+	}
+}
@@ -54,10 +54,11 @@ const (
 
 // Content represents the media content to be sent.
 type Content struct {
-	Text    string // Text content or caption
-	FileID  string // File ID for media types
-	MsgType int    // One of db.TEXT, db.STICKER, etc.
-	Name    string // Optional name for logging (note name, filter keyword, etc.)
+	Text    string         // Text content or caption
+	FileID  string         // File ID for media types
+	MsgType int            // One of db.TEXT, db.STICKER, etc.
+	Name    string         // Optional name for logging (note name, filter keyword, etc.)
+	Media   []db.MediaItem // Album items; when there are two or more, they are sent as a media group
 }
 
 // Options configures how the media is sent.
@@ -97,6 +98,10 @@ func Send(b *gotgbot.Bot, content Content, opts Options) (*gotgbot.Message, erro
 		}
 	}
 
+	if len(content.Media) > 1 {
+		return sendMediaGroup(b, content, opts, parseMode, replyParams)
+	}
+
 	switch content.MsgType {
 	case db.TEXT, 0: // 0 is fallback for uninitialized/legacy records
 		return sendText(b, content, opts, parseMode, replyParams)
@@ -136,6 +141,68 @@ func sendText(b *gotgbot.Bot, content Content, opts Options, parseMode string, r
 	return resolveSendResult(msg, err, opts.ChatID, "text")
 }
 
+// sendMediaGroup sends the album items of content as one media group. The
+// first item is captioned with content.Text, the others keep their own
+// captions. Telegram does not allow keyboards on albums, so when there is one
+// the text is sent with it as a reply to the album instead.
+// Returns the first message of the album.
+func sendMediaGroup(b *gotgbot.Bot, content Content, opts Options, parseMode string, replyParams *gotgbot.ReplyParameters) (*gotgbot.Message, error) {
+	withKeyboard := opts.Keyboard != nil && len(opts.Keyboard.InlineKeyboard) > 0 && strings.TrimSpace(content.Text) != ""
+
+	group := make([]gotgbot.InputMedia, 0, len(content.Media))
+	for i, item := range content.Media {
+		caption := item.Caption
+		if i == 0 {
+			caption = content.Text
+			if withKeyboard {
+				caption = ""
+			}
+		}
+		switch item.MsgType {
+		case db.PHOTO:
+			group = append(group, gotgbot.InputMediaPhoto{Media: gotgbot.InputFileByID(item.FileID), Caption: caption, ParseMode: parseMode})
+		case db.VIDEO:
+			group = append(group, gotgbot.InputMediaVideo{Media: gotgbot.InputFileByID(item.FileID), Caption: caption, ParseMode: parseMode})
+		case db.DOCUMENT:
+			group = append(group, gotgbot.InputMediaDocument{Media: gotgbot.InputFileByID(item.FileID), Caption: caption, ParseMode: parseMode})
+		case db.AUDIO:
+			group = append(group, gotgbot.InputMediaAudio{Media: gotgbot.InputFileByID(item.FileID), Caption: caption, ParseMode: parseMode})
+		default:
+			log.Warnf("[Media] Skipping album item of type %d in '%s' for chat %d", item.MsgType, content.Name, opts.ChatID)
+		}
+	}
+	if len(group) < 2 {
+		single := content
+		single.Media = nil
+		return Send(b, single, opts)
+	}
+
+	msgs, err := b.SendMediaGroup(opts.ChatID, group, &gotgbot.SendMediaGroupOpts{
+		ReplyParameters:     replyParams,
+		ProtectContent:      opts.IsProtected,
+		DisableNotification: opts.NoNotif,
+		MessageThreadId:     opts.ThreadID,
+	})
+	msgs, err = resolveSendResult(msgs, err, opts.ChatID, "media group")
+	if err != nil {
+		return nil, err
+	}
+	if len(msgs) == 0 {
+		return nil, fmt.Errorf("empty response sending media group to chat %d", opts.ChatID)
+	}
+
+	if withKeyboard {
+		_, err = sendText(b, content, opts, parseMode, &gotgbot.ReplyParameters{
+			MessageId:                msgs[0].MessageId,
+			AllowSendingWithoutReply: true,
+		})
+		if err != nil {
+			log.Warnf("[Media] Failed to send keyboard of album '%s' in chat %d: %v", content.Name, opts.ChatID, err)
+		}
+	}
+	return &msgs[0], nil
+}
+
 // sendSticker sends a sticker or falls back to text if FileID is empty.
 func sendSticker(b *gotgbot.Bot, content Content, opts Options, replyParams *gotgbot.ReplyParameters) (*gotgbot.Message, error) {
 	if content.FileID == "" {
@@ -258,10 +325,11 @@ func sendVideoNote(b *gotgbot.Bot, content Content, opts Options, replyParams *g
 	return resolveSendResult(msg, err, opts.ChatID, "video note")
 }
 
-// SendNote sends a note with full preprocessing (randomization, formatting, keyboard, options).
-// The chat parameter provides the group context for formatting placeholders like {chatname};
-// ctx.EffectiveChat is used as the actual send target.
-func SendNote(b *gotgbot.Bot, ctx *ext.Context, chat *gotgbot.Chat, note *db.Notes, replyMsgID, threadID int64) (*gotgbot.Message, error) {
+// PrepareNote applies the note preprocessing shared by every way of
+// delivering a note: random variant selection, formatting placeholders,
+// note tags and the button keyboard. The chat provides the group context for
+// placeholders like {chatname}; user is the member the note is rendered for.
+func PrepareNote(b *gotgbot.Bot, chat *gotgbot.Chat, user *gotgbot.User, note *db.Notes) (Content, gotgbot.InlineKeyboardMarkup) {
 	var (
 		buttons []db.Button
 		sent    string
@@ -281,26 +349,34 @@ func SendNote(b *gotgbot.Bot, ctx *ext.Context, chat *gotgbot.Chat, note *db.Not
 
 	// Avoid mutating the original note
 	noteCopy := *note
-	noteCopy.NoteContent, buttons = formatting.FormattingReplacer(b, chat, ctx.EffectiveUser, sent, buttons)
+	noteCopy.NoteContent, buttons = formatting.FormattingReplacer(b, chat, user, sent, buttons)
 	_, _, _, _, _, _, noteCopy.NoteContent = content.NotesParser(noteCopy.NoteContent)
 
 	keyb := keyboard.BuildKeyboard(buttons)
-	keyboardMarkup := gotgbot.InlineKeyboardMarkup{InlineKeyboard: keyb}
-
-	return Send(b, Content{
+	return Content{
 		Text:    noteCopy.NoteContent,
 		FileID:  noteCopy.FileID,
 		MsgType: noteCopy.MsgType,
 		Name:    noteCopy.NoteName,
-	}, Options{
+		Media:   noteCopy.Media,
+	}, gotgbot.InlineKeyboardMarkup{InlineKeyboard: keyb}
+}
+
+// SendNote sends a note with full preprocessing (randomization, formatting, keyboard, options).
+// The chat parameter provides the group context for formatting placeholders like {chatname};
+// ctx.EffectiveChat is used as the actual send target.
+func SendNote(b *gotgbot.Bot, ctx *ext.Context, chat *gotgbot.Chat, note *db.Notes, replyMsgID, threadID int64) (*gotgbot.Message, error) {
+	noteContent, keyboardMarkup := PrepareNote(b, chat, ctx.EffectiveUser, note)
+
+	return Send(b, noteContent, Options{
 		ChatID:            ctx.EffectiveChat.Id,
 		ReplyMsgID:        replyMsgID,
 		ThreadID:          threadID,
 		Keyboard:          &keyboardMarkup,
 		NoFormat:          false, // Notes use formatting by default
-		NoNotif:           noteCopy.NoNotif,
-		WebPreview:        noteCopy.WebPreview,
-		IsProtected:       noteCopy.IsProtected,
+		NoNotif:           note.NoNotif,
+		WebPreview:        note.WebPreview,
+		IsProtected:       note.IsProtected,
 		AllowWithoutReply: true,
 	})
 }
@@ -339,6 +415,7 @@ func SendFilter(b *gotgbot.Bot, ctx *ext.Context, filter *db.ChatFilters, replyM
 		FileID:  tmpfilterData.FileID,
 		MsgType: tmpfilterData.MsgType,
 		Name:    tmpfilterData.KeyWord,
+		Media:   tmpfilterData.Media,
 	}, Options{
 		ChatID:            chat.Id,
 		ReplyMsgID:        replyMsgID,
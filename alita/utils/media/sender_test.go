@@ -17,15 +17,25 @@ import (
 type recordingBotClient struct {
 	method string
 	params map[string]any
+	calls  []recordedCall
 	err    error
 }
 
+type recordedCall struct {
+	method string
+	params map[string]any
+}
+
 func (c *recordingBotClient) RequestWithContext(_ context.Context, _ string, method string, params map[string]any, _ *gotgbot.RequestOpts) (json.RawMessage, error) {
 	c.method = method
 	c.params = params
+	c.calls = append(c.calls, recordedCall{method: method, params: params})
 	if c.err != nil {
 		return nil, c.err
 	}
+	if method == "sendMediaGroup" {
+		return json.RawMessage(`[{"message_id":42,"date":1,"chat":{"id":-100,"type":"supergroup"}},{"message_id":43,"date":1,"chat":{"id":-100,"type":"supergroup"}}]`), nil
+	}
 	return json.RawMessage(`{"message_id":42,"date":1,"chat":{"id":-100,"type":"supergroup"}}`), nil
 }
 
@@ -308,6 +318,102 @@ func TestSendMediaUsesTelegramMethodForFileID(t *testing.T) {
 	}
 }
 
+func TestSendMediaGroupForAlbumItems(t *testing.T) {
+	t.Parallel()
+
+	album := []db.MediaItem{
+		{FileID: "photo-1", MsgType: db.PHOTO, Caption: "first"},
+		{FileID: "video-2", MsgType: db.VIDEO, Caption: "second"},
+	}
+
+	t.Run("captions", func(t *testing.T) {
+		t.Parallel()
+
+		client := &recordingBotClient{}
+		msg, err := Send(newRecordingBot(client), Content{
+			Text:    "note text",
+			FileID:  "photo-1",
+			MsgType: db.PHOTO,
+			Media:   album,
+		}, Options{ChatID: -100, ReplyMsgID: 7, ThreadID: 10, IsProtected: true})
+		if err != nil {
+			t.Fatalf("Send album error = %v", err)
+		}
+		if msg == nil || msg.MessageId != 42 {
+			t.Fatalf("Send album message = %#v, want first album message", msg)
+		}
+		if len(client.calls) != 1 || client.method != "sendMediaGroup" {
+			t.Fatalf("calls = %#v, want a single sendMediaGroup", client.calls)
+		}
+		group, ok := client.params["media"].(gotgbot.InputMedias)
+		if !ok || len(group) != 2 {
+			t.Fatalf("media param = %#v, want two input media", client.params["media"])
+		}
+		first, ok := group[0].(gotgbot.InputMediaPhoto)
+		if !ok || first.Caption != "note text" || first.ParseMode != HTML {
+			t.Fatalf("first item = %#v, want photo captioned with the note text", group[0])
+		}
+		second, ok := group[1].(gotgbot.InputMediaVideo)
+		if !ok || second.Caption != "second" {
+			t.Fatalf("second item = %#v, want video with its own caption", group[1])
+		}
+		if got := client.params["message_thread_id"]; got != int64(10) {
+			t.Fatalf("message_thread_id = %#v, want 10", got)
+		}
+		if got := client.params["protect_content"]; got != true {
+			t.Fatalf("protect_content = %#v, want true", got)
+		}
+	})
+
+	t.Run("keyboard follows album", func(t *testing.T) {
+		t.Parallel()
+
+		client := &recordingBotClient{}
+		keyboard := &gotgbot.InlineKeyboardMarkup{InlineKeyboard: [][]gotgbot.InlineKeyboardButton{{{Text: "Open", Url: "https://example.com"}}}}
+		_, err := Send(newRecordingBot(client), Content{
+			Text:    "note text",
+			FileID:  "photo-1",
+			MsgType: db.PHOTO,
+			Media:   album,
+		}, Options{ChatID: -100, Keyboard: keyboard})
+		if err != nil {
+			t.Fatalf("Send album error = %v", err)
+		}
+		if len(client.calls) != 2 || client.calls[0].method != "sendMediaGroup" || client.calls[1].method != "sendMessage" {
+			t.Fatalf("calls = %#v, want sendMediaGroup then sendMessage", client.calls)
+		}
+		group := client.calls[0].params["media"].(gotgbot.InputMedias)
+		if first := group[0].(gotgbot.InputMediaPhoto); first.Caption != "" {
+			t.Fatalf("first caption = %q, want text moved to the keyboard message", first.Caption)
+		}
+		followUp := client.calls[1].params
+		if followUp["text"] != "note text" || followUp["reply_markup"] == nil {
+			t.Fatalf("follow-up params = %#v, want text with keyboard", followUp)
+		}
+		if reply, ok := followUp["reply_parameters"].(*gotgbot.ReplyParameters); !ok || reply.MessageId != 42 {
+			t.Fatalf("follow-up reply_parameters = %#v, want reply to the album", followUp["reply_parameters"])
+		}
+	})
+
+	t.Run("single usable item", func(t *testing.T) {
+		t.Parallel()
+
+		client := &recordingBotClient{}
+		_, err := Send(newRecordingBot(client), Content{
+			Text:    "note text",
+			FileID:  "photo-1",
+			MsgType: db.PHOTO,
+			Media:   []db.MediaItem{album[0], {FileID: "sticker", MsgType: db.STICKER}},
+		}, Options{ChatID: -100})
+		if err != nil {
+			t.Fatalf("Send album error = %v", err)
+		}
+		if client.method != "sendPhoto" {
+			t.Fatalf("method = %q, want sendPhoto fallback", client.method)
+		}
+	})
+}
+
 func TestSendConvenienceWrappersBuildContentAndOptions(t *testing.T) {
 	t.Parallel()
 
@@ -0,0 +1,63 @@
+package monitoring
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/divkix/Alita_Robot/alita/db/models"
+)
+
+// Captcha metrics are exported on /metrics. They are labelled by captcha
+// mode but not by chat, to keep the series count bounded; per-chat numbers
+// are available through /captchastats.
+var (
+	captchaOutcomes = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "alita",
+		Subsystem: "captcha",
+		Name:      "outcomes_total",
+		Help:      "Finished captcha attempts by mode and outcome (solved, failed, timeout, left).",
+	}, []string{"mode", "outcome"})
+	captchaWrongAnswers = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "alita",
+		Subsystem: "captcha",
+		Name:      "wrong_answers_total",
+		Help:      "Wrong captcha answers by mode.",
+	}, []string{"mode"})
+	captchaRefreshes = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "alita",
+		Subsystem: "captcha",
+		Name:      "refreshes_total",
+		Help:      "Captcha refreshes by mode.",
+	}, []string{"mode"})
+	captchaSolveSeconds = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: "alita",
+		Subsystem: "captcha",
+		Name:      "solve_seconds",
+		Help:      "Time from the captcha being sent to the right answer, by mode.",
+		Buckets:   []float64{5, 10, 20, 30, 60, 120, 300, 600},
+	}, []string{"mode"})
+)
+
+func init() {
+	prometheus.MustRegister(captchaOutcomes, captchaWrongAnswers, captchaRefreshes, captchaSolveSeconds)
+}
+
+// RecordCaptchaOutcome counts a finished captcha attempt. solveTime is only
+// observed for solved attempts.
+func RecordCaptchaOutcome(mode, outcome string, solveTime time.Duration) {
+	captchaOutcomes.WithLabelValues(mode, outcome).Inc()
+	if outcome == models.CaptchaOutcomeSolved {
+		captchaSolveSeconds.WithLabelValues(mode).Observe(solveTime.Seconds())
+	}
+}
+
+// RecordCaptchaWrongAnswer counts a wrong captcha answer.
+func RecordCaptchaWrongAnswer(mode string) {
+	captchaWrongAnswers.WithLabelValues(mode).Inc()
+}
+
+// RecordCaptchaRefresh counts a captcha refresh.
+func RecordCaptchaRefresh(mode string) {
+	captchaRefreshes.WithLabelValues(mode).Inc()
+}
@@ -0,0 +1,75 @@
+package spamfilter
+
+import (
+	"hash/fnv"
+	"math/bits"
+	"strings"
+	"unicode"
+)
+
+const (
+	// MinSimHashWords is how many words a text needs before it gets a
+	// SimHash; shorter texts are too common to tell copies apart.
+	MinSimHashWords = 5
+	// SimHashMaxDistance is the largest number of differing bits between
+	// two SimHashes of texts considered the same.
+	SimHashMaxDistance = 3
+	// SimHashBands is how many parts a SimHash is split into by Bands. Two
+	// hashes at most SimHashMaxDistance bits apart share at least one band.
+	SimHashBands = SimHashMaxDistance + 1
+)
+
+// SimHash returns the 64-bit SimHash of a text, built from its lower cased
+// words and word pairs. Links all count as the same word, so copies that only
+// differ in their links hash alike. ok is false for texts shorter than
+// MinSimHashWords words.
+func SimHash(text string) (hash uint64, ok bool) {
+	text = linkPattern.ReplaceAllString(text, " "+TokenLink+" ")
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_'
+	})
+	if len(words) < MinSimHashWords {
+		return 0, false
+	}
+
+	var weights [64]int
+	add := func(feature string) {
+		h := fnv.New64a()
+		_, _ = h.Write([]byte(feature))
+		sum := h.Sum64()
+		for bit := range weights {
+			if sum&(1<<bit) != 0 {
+				weights[bit]++
+			} else {
+				weights[bit]--
+			}
+		}
+	}
+	for i, word := range words {
+		add(word)
+		if i > 0 {
+			add(words[i-1] + " " + word)
+		}
+	}
+	for bit, weight := range weights {
+		if weight > 0 {
+			hash |= 1 << bit
+		}
+	}
+	return hash, true
+}
+
+// SimHashDistance returns how many bits two SimHashes differ in.
+func SimHashDistance(a, b uint64) int {
+	return bits.OnesCount64(a ^ b)
+}
+
+// Bands splits a SimHash into SimHashBands parts, to look up texts that may
+// be the same by any of them.
+func Bands(hash uint64) [SimHashBands]uint16 {
+	var bands [SimHashBands]uint16
+	for i := range bands {
+		bands[i] = uint16(hash >> (16 * i))
+	}
+	return bands
+}
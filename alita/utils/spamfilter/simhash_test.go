@@ -0,0 +1,46 @@
+package spamfilter
+
+import "testing"
+
+func TestSimHash(t *testing.T) {
+	base := "Earn 500 dollars a day from home, message me now to join our trading group https://t.me/+abc"
+
+	original, ok := SimHash(base)
+	if !ok {
+		t.Fatal("SimHash() ok = false for a long text")
+	}
+	// Only the case, the spacing, the punctuation and the link differ.
+	copied, _ := SimHash("EARN 500 dollars a day   from home!! message me now to join our trading group t.me/+xyz")
+	if d := SimHashDistance(original, copied); d != 0 {
+		t.Fatalf("distance to a copy = %d, want 0", d)
+	}
+
+	other, _ := SimHash("Is anyone going to the meetup in Berlin next Saturday afternoon?")
+	if d := SimHashDistance(original, other); d <= SimHashMaxDistance {
+		t.Fatalf("distance to an unrelated text = %d, want > %d", d, SimHashMaxDistance)
+	}
+
+	if _, ok := SimHash("hi there friend"); ok {
+		t.Fatal("SimHash() ok = true for a text shorter than MinSimHashWords")
+	}
+}
+
+func TestBandsCoverCloseHashes(t *testing.T) {
+	hash := uint64(0x0123456789abcdef)
+	// Flip one bit in each of three bands: the fourth band still matches.
+	near := hash ^ (1 | 1<<20 | 1<<40)
+	if d := SimHashDistance(hash, near); d != SimHashMaxDistance {
+		t.Fatalf("SimHashDistance() = %d, want %d", d, SimHashMaxDistance)
+	}
+
+	a, b := Bands(hash), Bands(near)
+	shared := 0
+	for i := range a {
+		if a[i] == b[i] {
+			shared++
+		}
+	}
+	if shared != 1 {
+		t.Fatalf("shared bands = %d, want 1", shared)
+	}
+}
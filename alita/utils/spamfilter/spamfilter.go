@@ -0,0 +1,204 @@
+// Package spamfilter is a small naive Bayes spam classifier. It turns a
+// message into tokens, its words plus a few synthetic feature tokens, and
+// scores them against per-token spam and non-spam counts. It needs no
+// external service: the counts are kept by the caller.
+package spamfilter
+
+import (
+	"math"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Synthetic feature tokens. They start with "__" so no word can collide with
+// them.
+const (
+	TokenLink         = "__link__"
+	TokenManyLinks    = "__many_links__"
+	TokenMention      = "__mention__"
+	TokenManyMentions = "__many_mentions__"
+	TokenEmojiDense   = "__emoji_dense__"
+	TokenCaps         = "__caps__"
+	TokenForward      = "__forward__"
+	TokenNewAccount   = "__new_account__"
+	TokenRepeated     = "__repeated__"
+	TokenBurst        = "__burst__"
+)
+
+const (
+	// MaxTokens caps how many distinct tokens a message contributes, so a
+	// long message costs the same to score as a short one.
+	MaxTokens = 64
+	// MaxTokenLength is the longest word kept, in runes.
+	MaxTokenLength = 32
+	// MinTrainingDocs is how many spam and how many non-spam messages a model
+	// needs before Score gives an answer.
+	MinTrainingDocs = 5
+
+	minWordLength   = 2
+	manyThreshold   = 3
+	minEmojiCount   = 5
+	minEmojiPercent = 20
+	minCapsLetters  = 10
+	minCapsPercent  = 70
+)
+
+var (
+	linkPattern    = regexp.MustCompile(`(?i)\b(?:https?://|www\.|t\.me/|telegram\.me/)\S+`)
+	mentionPattern = regexp.MustCompile(`(?:^|[^\w@])@\w{4,32}\b`)
+)
+
+// Message is what the classifier knows about a message: its text and the
+// signals that are not visible in the text itself.
+type Message struct {
+	Text string
+	// HiddenLinks and HiddenMentions count text links and mentions by name,
+	// which point somewhere the text does not show.
+	HiddenLinks    int
+	HiddenMentions int
+	Forwarded      bool
+	NewAccount     bool // the sender's account looks recently created
+	Repeated       bool // the sender just sent the same text
+	Burst          bool // the sender is sending messages very quickly
+}
+
+// Tokens returns the distinct tokens of a message, at most MaxTokens of them.
+// Feature tokens come first so they are never cut off.
+func Tokens(msg Message) []string {
+	tokens := make([]string, 0, 16)
+	seen := make(map[string]struct{})
+	add := func(token string) {
+		if len(tokens) >= MaxTokens {
+			return
+		}
+		if _, ok := seen[token]; ok {
+			return
+		}
+		seen[token] = struct{}{}
+		tokens = append(tokens, token)
+	}
+
+	links := len(linkPattern.FindAllStringIndex(msg.Text, -1)) + msg.HiddenLinks
+	if links > 0 {
+		add(TokenLink)
+	}
+	if links >= manyThreshold {
+		add(TokenManyLinks)
+	}
+	mentions := len(mentionPattern.FindAllStringIndex(msg.Text, -1)) + msg.HiddenMentions
+	if mentions > 0 {
+		add(TokenMention)
+	}
+	if mentions >= manyThreshold {
+		add(TokenManyMentions)
+	}
+	if emojiDense(msg.Text) {
+		add(TokenEmojiDense)
+	}
+	if mostlyCaps(msg.Text) {
+		add(TokenCaps)
+	}
+	if msg.Forwarded {
+		add(TokenForward)
+	}
+	if msg.NewAccount {
+		add(TokenNewAccount)
+	}
+	if msg.Repeated {
+		add(TokenRepeated)
+	}
+	if msg.Burst {
+		add(TokenBurst)
+	}
+
+	// Links are reduced to the feature tokens above; their paths would only
+	// add one-off words.
+	text := strings.ToLower(linkPattern.ReplaceAllString(msg.Text, " "))
+	for _, word := range strings.FieldsFunc(text, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}) {
+		if n := utf8.RuneCountInString(word); n < minWordLength || n > MaxTokenLength {
+			continue
+		}
+		add(word)
+	}
+	return tokens
+}
+
+// Counts are the training counts of a model, summed over the models in use.
+type Counts struct {
+	SpamDocs int64
+	HamDocs  int64
+	Tokens   map[string]TokenCounts
+}
+
+// TokenCounts is how many spam and non-spam training messages contained a
+// token.
+type TokenCounts struct {
+	Spam int64
+	Ham  int64
+}
+
+// Trained reports whether the counts hold enough training to score with.
+func (c *Counts) Trained() bool {
+	return c.SpamDocs >= MinTrainingDocs && c.HamDocs >= MinTrainingDocs
+}
+
+// Score returns the probability, between 0 and 1, that a message with the
+// given tokens is spam. Tokens never seen in training are ignored, and both
+// classes get the same prior so a model trained mostly on spam does not flag
+// every message. ok is false while the model is not trained enough.
+func Score(counts *Counts, tokens []string) (probability float64, ok bool) {
+	if counts == nil || !counts.Trained() {
+		return 0, false
+	}
+	var logSpam, logHam float64
+	for _, token := range tokens {
+		tc, found := counts.Tokens[token]
+		if !found || tc.Spam+tc.Ham == 0 {
+			continue
+		}
+		// Laplace smoothing keeps a token seen in one class only from
+		// deciding the message on its own.
+		logSpam += math.Log(float64(tc.Spam+1) / float64(counts.SpamDocs+2))
+		logHam += math.Log(float64(tc.Ham+1) / float64(counts.HamDocs+2))
+	}
+	return 1 / (1 + math.Exp(logHam-logSpam)), true
+}
+
+// emojiDense reports whether emoji make up a large part of the text.
+func emojiDense(text string) bool {
+	var emoji, visible int
+	for _, r := range text {
+		if unicode.IsSpace(r) {
+			continue
+		}
+		visible++
+		if isEmoji(r) {
+			emoji++
+		}
+	}
+	return emoji >= minEmojiCount && emoji*100 >= visible*minEmojiPercent
+}
+
+func isEmoji(r rune) bool {
+	return (r >= 0x1F000 && r <= 0x1FAFF) || (r >= 0x2600 && r <= 0x27BF) || unicode.Is(unicode.So, r)
+}
+
+// mostlyCaps reports whether the text is shouted: most of its letters, and
+// enough of them, are upper case.
+func mostlyCaps(text string) bool {
+	var letters, upper int
+	for _, r := range text {
+		if !unicode.IsLetter(r) {
+			continue
+		}
+		letters++
+		if unicode.IsUpper(r) {
+			upper++
+		}
+	}
+	return upper >= minCapsLetters && upper*100 >= letters*minCapsPercent
+}
@@ -0,0 +1,136 @@
+package spamfilter
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestTokens(t *testing.T) {
+	tests := []struct {
+		name    string
+		msg     Message
+		want    []string
+		without []string
+	}{
+		{
+			name:    "plain words are lower cased and deduplicated",
+			msg:     Message{Text: "Hello hello, World! a"},
+			want:    []string{"hello", "world"},
+			without: []string{"a", TokenLink, TokenCaps},
+		},
+		{
+			name:    "links become feature tokens",
+			msg:     Message{Text: "join https://t.me/+abcdef and www.example.com or t.me/cryptodrop"},
+			want:    []string{TokenLink, TokenManyLinks, "join"},
+			without: []string{"abcdef", "cryptodrop", "example"},
+		},
+		{
+			name:    "hidden links count",
+			msg:     Message{Text: "click here", HiddenLinks: 1},
+			want:    []string{TokenLink},
+			without: []string{TokenManyLinks},
+		},
+		{
+			name:    "mentions",
+			msg:     Message{Text: "ask @alice_one @bob_two @carol_3 now"},
+			want:    []string{TokenMention, TokenManyMentions},
+			without: []string{TokenLink},
+		},
+		{
+			name:    "emails are not mentions",
+			msg:     Message{Text: "mail me at someone@example.com"},
+			without: []string{TokenMention},
+		},
+		{
+			name: "emoji dense",
+			msg:  Message{Text: "🚀🚀🚀 moon 💰💰"},
+			want: []string{TokenEmojiDense},
+		},
+		{
+			name: "shouting",
+			msg:  Message{Text: "FREE MONEY FOR EVERYONE"},
+			want: []string{TokenCaps, "free", "money"},
+		},
+		{
+			name: "context signals",
+			msg:  Message{Text: "hi", Forwarded: true, NewAccount: true, Repeated: true, Burst: true},
+			want: []string{TokenForward, TokenNewAccount, TokenRepeated, TokenBurst, "hi"},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got := Tokens(tc.msg)
+			for _, token := range tc.want {
+				if !slices.Contains(got, token) {
+					t.Errorf("Tokens() = %v, missing %q", got, token)
+				}
+			}
+			for _, token := range tc.without {
+				if slices.Contains(got, token) {
+					t.Errorf("Tokens() = %v, should not contain %q", got, token)
+				}
+			}
+		})
+	}
+}
+
+func TestTokensKeepsFeaturesWhenCapped(t *testing.T) {
+	words := make([]string, 0, 2*MaxTokens)
+	for i := range 2 * MaxTokens {
+		words = append(words, "word"+strings.Repeat("x", i%30)+string(rune('a'+i%26)))
+	}
+	got := Tokens(Message{Text: strings.Join(words, " "), NewAccount: true})
+	if len(got) != MaxTokens {
+		t.Fatalf("len(Tokens()) = %d, want %d", len(got), MaxTokens)
+	}
+	if got[0] != TokenNewAccount {
+		t.Fatalf("Tokens()[0] = %q, want the feature token first", got[0])
+	}
+}
+
+func TestScore(t *testing.T) {
+	counts := &Counts{
+		SpamDocs: 10,
+		HamDocs:  10,
+		Tokens: map[string]TokenCounts{
+			TokenLink:  {Spam: 9, Ham: 1},
+			"airdrop":  {Spam: 8},
+			"meeting":  {Ham: 6},
+			"tomorrow": {Spam: 1, Ham: 5},
+		},
+	}
+
+	spam, ok := Score(counts, Tokens(Message{Text: "free airdrop https://t.me/x"}))
+	if !ok {
+		t.Fatal("Score() ok = false for a trained model")
+	}
+	if spam < 0.9 {
+		t.Fatalf("Score(spam) = %.3f, want >= 0.9", spam)
+	}
+
+	ham, _ := Score(counts, Tokens(Message{Text: "meeting tomorrow"}))
+	if ham > 0.1 {
+		t.Fatalf("Score(ham) = %.3f, want <= 0.1", ham)
+	}
+
+	unknown, _ := Score(counts, Tokens(Message{Text: "completely unseen words"}))
+	if unknown != 0.5 {
+		t.Fatalf("Score(unknown) = %.3f, want 0.5", unknown)
+	}
+}
+
+func TestScoreNeedsTraining(t *testing.T) {
+	counts := &Counts{
+		SpamDocs: 50,
+		HamDocs:  MinTrainingDocs - 1,
+		Tokens:   map[string]TokenCounts{"airdrop": {Spam: 50}},
+	}
+	if _, ok := Score(counts, []string{"airdrop"}); ok {
+		t.Fatal("Score() ok = true for a model with too little non-spam training")
+	}
+	if _, ok := Score(nil, []string{"airdrop"}); ok {
+		t.Fatal("Score(nil) ok = true")
+	}
+}
@@ -0,0 +1,104 @@
+// Package textdiff computes line-based diffs, used to show admins how a note
+// or filter changed between two revisions.
+package textdiff
+
+import "strings"
+
+// Op is the kind of change a diff line represents.
+type Op int
+
+const (
+	// Equal marks a line present in both texts.
+	Equal Op = iota
+	// Delete marks a line only present in the old text.
+	Delete
+	// Insert marks a line only present in the new text.
+	Insert
+)
+
+// Line is one line of a diff.
+type Line struct {
+	Op   Op
+	Text string
+}
+
+// MaxLines bounds the quadratic diff. Texts with more lines are shown as a
+// full replacement instead.
+const MaxLines = 400
+
+// Lines returns the line diff turning oldText into newText, computed from
+// the longest common subsequence of their lines. Identical texts yield only
+// Equal lines.
+func Lines(oldText, newText string) []Line {
+	a, b := splitLines(oldText), splitLines(newText)
+	if len(a) > MaxLines || len(b) > MaxLines {
+		return replace(a, b)
+	}
+
+	// lcs[i][j] is the LCS length of a[i:] and b[j:].
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	out := make([]Line, 0, len(a)+len(b))
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] == b[j]:
+			out = append(out, Line{Op: Equal, Text: a[i]})
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			out = append(out, Line{Op: Delete, Text: a[i]})
+			i++
+		default:
+			out = append(out, Line{Op: Insert, Text: b[j]})
+			j++
+		}
+	}
+	for ; i < len(a); i++ {
+		out = append(out, Line{Op: Delete, Text: a[i]})
+	}
+	for ; j < len(b); j++ {
+		out = append(out, Line{Op: Insert, Text: b[j]})
+	}
+	return out
+}
+
+// Changed reports whether a diff contains any inserted or deleted line.
+func Changed(lines []Line) bool {
+	for _, line := range lines {
+		if line.Op != Equal {
+			return true
+		}
+	}
+	return false
+}
+
+func replace(a, b []string) []Line {
+	out := make([]Line, 0, len(a)+len(b))
+	for _, line := range a {
+		out = append(out, Line{Op: Delete, Text: line})
+	}
+	for _, line := range b {
+		out = append(out, Line{Op: Insert, Text: line})
+	}
+	return out
+}
+
+func splitLines(text string) []string {
+	if text == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+}
@@ -0,0 +1,69 @@
+package textdiff
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestLines(t *testing.T) {
+	tests := []struct {
+		name    string
+		oldText string
+		newText string
+		want    []Line
+	}{
+		{
+			name:    "identical",
+			oldText: "a\nb",
+			newText: "a\nb",
+			want:    []Line{{Equal, "a"}, {Equal, "b"}},
+		},
+		{
+			name:    "changed middle line",
+			oldText: "intro\nstep one\noutro",
+			newText: "intro\nstep 1\noutro",
+			want:    []Line{{Equal, "intro"}, {Delete, "step one"}, {Insert, "step 1"}, {Equal, "outro"}},
+		},
+		{
+			name:    "appended lines",
+			oldText: "a",
+			newText: "a\nb\nc",
+			want:    []Line{{Equal, "a"}, {Insert, "b"}, {Insert, "c"}},
+		},
+		{
+			name:    "cleared",
+			oldText: "a\nb\n",
+			newText: "",
+			want:    []Line{{Delete, "a"}, {Delete, "b"}},
+		},
+		{
+			name: "both empty",
+			want: []Line{},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := Lines(tc.oldText, tc.newText); !reflect.DeepEqual(got, tc.want) {
+				t.Fatalf("Lines() = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestLinesFallsBackToReplacementForLongTexts(t *testing.T) {
+	long := strings.Repeat("line\n", MaxLines+1)
+	got := Lines(long, "line")
+	if len(got) != MaxLines+2 || got[0].Op != Delete || got[len(got)-1].Op != Insert {
+		t.Fatalf("Lines() on a long text returned %d lines, want a full replacement", len(got))
+	}
+}
+
+func TestChanged(t *testing.T) {
+	if Changed(Lines("same", "same")) {
+		t.Fatal("Changed() = true for identical texts")
+	}
+	if !Changed(Lines("old", "new")) {
+		t.Fatal("Changed() = false for different texts")
+	}
+}
@@ -227,6 +227,26 @@ func main() {
 		modules.StopCaptchaLifecycle()
 		return nil
 	})
+	shutdownManager.RegisterHandler(func() error {
+		log.Info("[Shutdown] Stopping join questions poller...")
+		modules.StopJoinQuestionsPoller()
+		return nil
+	})
+	shutdownManager.RegisterHandler(func() error {
+		log.Info("[Shutdown] Flushing chat activity...")
+		modules.StopChatActivityFlusher()
+		return nil
+	})
+	shutdownManager.RegisterHandler(func() error {
+		log.Info("[Shutdown] Stopping topic auto-closer...")
+		modules.StopTopicAutoCloser()
+		return nil
+	})
+	shutdownManager.RegisterHandler(func() error {
+		log.Info("[Shutdown] Flushing queued message deletions...")
+		helpers.FlushQueuedDeletions()
+		return nil
+	})
 
 	// Create unified HTTP server for health, metrics, and webhook endpoints
 	httpServer := httpserver.New(config.AppConfig.HTTPPort, appStartTime)
@@ -447,6 +467,9 @@ func postInit(b *gotgbot.Bot, d *ext.Dispatcher, username string, mode string) {
 	if err := modules.StartCaptchaLifecycle(b); err != nil {
 		log.Fatalf("[Captcha] Failed to start lifecycle: %v", err)
 	}
+	modules.StartJoinQuestionsPoller(b)
+	modules.StartChatActivityFlusher()
+	modules.StartTopicAutoCloser(b)
 	log.Infof("[Modules] Loaded modules: %s", alita.ListModules())
 
 	config.AppConfig.WorkingMode = mode
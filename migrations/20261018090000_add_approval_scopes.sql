@@ -0,0 +1,9 @@
+-- Add scoped and expiring approvals.
+-- An empty scopes array keeps the legacy behaviour of exempting the user from
+-- every protection; a NULL expires_at means the approval never expires.
+ALTER TABLE approved_users ADD COLUMN IF NOT EXISTS scopes JSONB NOT NULL DEFAULT '[]'::jsonb;
+ALTER TABLE approved_users ADD COLUMN IF NOT EXISTS expires_at TIMESTAMP WITH TIME ZONE;
+
+CREATE INDEX IF NOT EXISTS idx_approved_users_expires_at
+ON approved_users(expires_at)
+WHERE expires_at IS NOT NULL;
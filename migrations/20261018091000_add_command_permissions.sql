@@ -0,0 +1,26 @@
+-- Add command_permissions table for per-chat overrides of who may run a command
+-- (set with /cmdperm). Commands without a row keep their default permissions.
+CREATE TABLE IF NOT EXISTS command_permissions (
+    id BIGSERIAL PRIMARY KEY,
+    chat_id BIGINT NOT NULL,
+    command TEXT NOT NULL,
+    level TEXT NOT NULL DEFAULT 'everyone',
+    required_right TEXT,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    CONSTRAINT idx_command_permissions_chat_command UNIQUE(chat_id, command)
+);
+
+-- Add foreign key to chats table for referential integrity when available.
+DO $$
+BEGIN
+    IF EXISTS (SELECT 1 FROM information_schema.table_constraints WHERE constraint_name = 'fk_command_permissions_chat') THEN
+        ALTER TABLE command_permissions DROP CONSTRAINT fk_command_permissions_chat;
+    END IF;
+
+    IF EXISTS (SELECT 1 FROM information_schema.tables WHERE table_name = 'chats') THEN
+        ALTER TABLE command_permissions
+        ADD CONSTRAINT fk_command_permissions_chat
+        FOREIGN KEY (chat_id) REFERENCES chats(chat_id) ON DELETE CASCADE ON UPDATE CASCADE;
+    END IF;
+END $$;
@@ -0,0 +1,25 @@
+-- Add command_aliases table for per-chat command aliases (set with /alias).
+-- A target is either a command name or a "#note" reference.
+CREATE TABLE IF NOT EXISTS command_aliases (
+    id BIGSERIAL PRIMARY KEY,
+    chat_id BIGINT NOT NULL,
+    alias TEXT NOT NULL,
+    target TEXT NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    CONSTRAINT idx_command_aliases_chat_alias UNIQUE(chat_id, alias)
+);
+
+-- Add foreign key to chats table for referential integrity when available.
+DO $$
+BEGIN
+    IF EXISTS (SELECT 1 FROM information_schema.table_constraints WHERE constraint_name = 'fk_command_aliases_chat') THEN
+        ALTER TABLE command_aliases DROP CONSTRAINT fk_command_aliases_chat;
+    END IF;
+
+    IF EXISTS (SELECT 1 FROM information_schema.tables WHERE table_name = 'chats') THEN
+        ALTER TABLE command_aliases
+        ADD CONSTRAINT fk_command_aliases_chat
+        FOREIGN KEY (chat_id) REFERENCES chats(chat_id) ON DELETE CASCADE ON UPDATE CASCADE;
+    END IF;
+END $$;
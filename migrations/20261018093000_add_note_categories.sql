@@ -0,0 +1,12 @@
+-- Add note categories (set with /save category/name) and a full-text index
+-- over note content for /notes search.
+-- An empty category keeps a note in the uncategorized group.
+ALTER TABLE notes ADD COLUMN IF NOT EXISTS category TEXT NOT NULL DEFAULT '';
+
+CREATE INDEX IF NOT EXISTS idx_notes_chat_category
+ON notes(chat_id, category);
+
+-- The expression must match the one used by notes.SearchNotes for the
+-- planner to use this index.
+CREATE INDEX IF NOT EXISTS idx_notes_content_fts
+ON notes USING GIN (to_tsvector('simple', coalesce(note_content, '')));
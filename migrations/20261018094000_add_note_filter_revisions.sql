@@ -0,0 +1,66 @@
+-- Add note_revisions and filter_revisions tables. Every overwrite, delete,
+-- /clearall and /stopall keeps the previous content here so it can be
+-- restored with /noterestore or /filterrestore within the retention window.
+CREATE TABLE IF NOT EXISTS note_revisions (
+    id BIGSERIAL PRIMARY KEY,
+    chat_id BIGINT NOT NULL,
+    note_name TEXT NOT NULL,
+    revision INTEGER NOT NULL,
+    action TEXT NOT NULL,
+    editor_id BIGINT,
+    batch BIGINT NOT NULL DEFAULT 0,
+    category TEXT NOT NULL DEFAULT '',
+    note_content TEXT,
+    file_id TEXT,
+    msg_type BIGINT,
+    buttons JSONB,
+    admin_only BOOLEAN DEFAULT FALSE,
+    private_only BOOLEAN DEFAULT FALSE,
+    group_only BOOLEAN DEFAULT FALSE,
+    web_preview BOOLEAN,
+    is_protected BOOLEAN DEFAULT FALSE,
+    no_notif BOOLEAN DEFAULT FALSE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    CONSTRAINT uk_note_revisions_chat_name_rev UNIQUE(chat_id, note_name, revision)
+);
+
+CREATE INDEX IF NOT EXISTS idx_note_revisions_created_at ON note_revisions(created_at);
+
+CREATE TABLE IF NOT EXISTS filter_revisions (
+    id BIGSERIAL PRIMARY KEY,
+    chat_id BIGINT NOT NULL,
+    keyword TEXT NOT NULL,
+    revision INTEGER NOT NULL,
+    action TEXT NOT NULL,
+    editor_id BIGINT,
+    batch BIGINT NOT NULL DEFAULT 0,
+    filter_reply TEXT,
+    msgtype BIGINT,
+    fileid TEXT,
+    nonotif BOOLEAN DEFAULT FALSE,
+    filter_buttons JSONB,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    CONSTRAINT uk_filter_revisions_chat_keyword_rev UNIQUE(chat_id, keyword, revision)
+);
+
+CREATE INDEX IF NOT EXISTS idx_filter_revisions_created_at ON filter_revisions(created_at);
+
+-- Add foreign keys to chats table for referential integrity when available.
+DO $$
+BEGIN
+    IF EXISTS (SELECT 1 FROM information_schema.table_constraints WHERE constraint_name = 'fk_note_revisions_chat') THEN
+        ALTER TABLE note_revisions DROP CONSTRAINT fk_note_revisions_chat;
+    END IF;
+    IF EXISTS (SELECT 1 FROM information_schema.table_constraints WHERE constraint_name = 'fk_filter_revisions_chat') THEN
+        ALTER TABLE filter_revisions DROP CONSTRAINT fk_filter_revisions_chat;
+    END IF;
+
+    IF EXISTS (SELECT 1 FROM information_schema.tables WHERE table_name = 'chats') THEN
+        ALTER TABLE note_revisions
+        ADD CONSTRAINT fk_note_revisions_chat
+        FOREIGN KEY (chat_id) REFERENCES chats(chat_id) ON DELETE CASCADE ON UPDATE CASCADE;
+        ALTER TABLE filter_revisions
+        ADD CONSTRAINT fk_filter_revisions_chat
+        FOREIGN KEY (chat_id) REFERENCES chats(chat_id) ON DELETE CASCADE ON UPDATE CASCADE;
+    END IF;
+END $$;
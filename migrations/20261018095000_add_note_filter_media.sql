@@ -0,0 +1,7 @@
+-- Store media albums in notes and filters: an ordered list of the album's
+-- items with their captions. file_id and msg_type keep mirroring the first
+-- item, so single-media rows and older readers are unaffected.
+ALTER TABLE notes ADD COLUMN IF NOT EXISTS media JSONB;
+ALTER TABLE filters ADD COLUMN IF NOT EXISTS media JSONB;
+ALTER TABLE note_revisions ADD COLUMN IF NOT EXISTS media JSONB;
+ALTER TABLE filter_revisions ADD COLUMN IF NOT EXISTS media JSONB;
@@ -0,0 +1,39 @@
+-- Add join request review. greetings.join_review_chat is the chat join
+-- request cards are posted to (0 = off), and join_requests keeps every
+-- reviewed request with the admin decision for /pendingrequests and audit.
+ALTER TABLE greetings ADD COLUMN IF NOT EXISTS join_review_chat BIGINT NOT NULL DEFAULT 0;
+
+CREATE TABLE IF NOT EXISTS join_requests (
+    id BIGSERIAL PRIMARY KEY,
+    chat_id BIGINT NOT NULL,
+    user_id BIGINT NOT NULL,
+    first_name TEXT,
+    username TEXT,
+    bio TEXT,
+    invite_link TEXT,
+    status TEXT NOT NULL,
+    review_chat_id BIGINT,
+    review_message_id BIGINT,
+    decided_by BIGINT,
+    decided_at TIMESTAMP WITH TIME ZONE,
+    requested_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_join_requests_chat_status ON join_requests(chat_id, status);
+CREATE INDEX IF NOT EXISTS idx_join_requests_user_id ON join_requests(user_id);
+
+-- Add foreign key to chats table for referential integrity when available.
+DO $$
+BEGIN
+    IF EXISTS (SELECT 1 FROM information_schema.table_constraints WHERE constraint_name = 'fk_join_requests_chat') THEN
+        ALTER TABLE join_requests DROP CONSTRAINT fk_join_requests_chat;
+    END IF;
+
+    IF EXISTS (SELECT 1 FROM information_schema.tables WHERE table_name = 'chats') THEN
+        ALTER TABLE join_requests
+        ADD CONSTRAINT fk_join_requests_chat
+        FOREIGN KEY (chat_id) REFERENCES chats(chat_id) ON DELETE CASCADE ON UPDATE CASCADE;
+    END IF;
+END $$;
@@ -0,0 +1,55 @@
+-- Add join questions. join_questions holds the questions applicants answer
+-- in a private chat with the bot before their join request is decided, and
+-- join_applications the answers of applicants who have not finished yet.
+-- greetings.join_questions_timeout is how many minutes they have to answer.
+ALTER TABLE greetings ADD COLUMN IF NOT EXISTS join_questions_timeout INTEGER NOT NULL DEFAULT 60;
+
+CREATE TABLE IF NOT EXISTS join_questions (
+    id BIGSERIAL PRIMARY KEY,
+    chat_id BIGINT NOT NULL,
+    position INTEGER NOT NULL,
+    question TEXT NOT NULL,
+    answer TEXT,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    CONSTRAINT uk_join_questions_chat_position UNIQUE (chat_id, position)
+);
+
+CREATE TABLE IF NOT EXISTS join_applications (
+    id BIGSERIAL PRIMARY KEY,
+    chat_id BIGINT NOT NULL,
+    user_id BIGINT NOT NULL,
+    user_chat_id BIGINT NOT NULL,
+    chat_title TEXT,
+    questions JSONB,
+    expected JSONB,
+    answers JSONB,
+    started BOOLEAN NOT NULL DEFAULT FALSE,
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    CONSTRAINT uk_join_applications_chat_user UNIQUE (chat_id, user_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_join_applications_user_id ON join_applications(user_id);
+CREATE INDEX IF NOT EXISTS idx_join_applications_expires_at ON join_applications(expires_at);
+
+-- Add foreign keys to chats table for referential integrity when available.
+DO $$
+BEGIN
+    IF EXISTS (SELECT 1 FROM information_schema.table_constraints WHERE constraint_name = 'fk_join_questions_chat') THEN
+        ALTER TABLE join_questions DROP CONSTRAINT fk_join_questions_chat;
+    END IF;
+    IF EXISTS (SELECT 1 FROM information_schema.table_constraints WHERE constraint_name = 'fk_join_applications_chat') THEN
+        ALTER TABLE join_applications DROP CONSTRAINT fk_join_applications_chat;
+    END IF;
+
+    IF EXISTS (SELECT 1 FROM information_schema.tables WHERE table_name = 'chats') THEN
+        ALTER TABLE join_questions
+        ADD CONSTRAINT fk_join_questions_chat
+        FOREIGN KEY (chat_id) REFERENCES chats(chat_id) ON DELETE CASCADE ON UPDATE CASCADE;
+        ALTER TABLE join_applications
+        ADD CONSTRAINT fk_join_applications_chat
+        FOREIGN KEY (chat_id) REFERENCES chats(chat_id) ON DELETE CASCADE ON UPDATE CASCADE;
+    END IF;
+END $$;
@@ -0,0 +1,31 @@
+-- Add the emoji and custom captcha modes. captcha_questions holds the
+-- admin-defined questions the custom mode asks, with the wrong answers shown
+-- next to the right one.
+ALTER TABLE captcha_settings DROP CONSTRAINT IF EXISTS chk_captcha_mode;
+ALTER TABLE captcha_settings ADD CONSTRAINT chk_captcha_mode CHECK (captcha_mode IN ('math', 'text', 'emoji', 'custom')) NOT VALID;
+
+CREATE TABLE IF NOT EXISTS captcha_questions (
+    id BIGSERIAL PRIMARY KEY,
+    chat_id BIGINT NOT NULL,
+    question TEXT NOT NULL,
+    answer TEXT NOT NULL,
+    wrong_answers JSONB,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_captcha_questions_chat_id ON captcha_questions(chat_id);
+
+-- Add foreign key to chats table for referential integrity when available.
+DO $$
+BEGIN
+    IF EXISTS (SELECT 1 FROM information_schema.table_constraints WHERE constraint_name = 'fk_captcha_questions_chat') THEN
+        ALTER TABLE captcha_questions DROP CONSTRAINT fk_captcha_questions_chat;
+    END IF;
+
+    IF EXISTS (SELECT 1 FROM information_schema.tables WHERE table_name = 'chats') THEN
+        ALTER TABLE captcha_questions
+        ADD CONSTRAINT fk_captcha_questions_chat
+        FOREIGN KEY (chat_id) REFERENCES chats(chat_id) ON DELETE CASCADE ON UPDATE CASCADE;
+    END IF;
+END $$;
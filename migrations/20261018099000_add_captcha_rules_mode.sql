@@ -0,0 +1,4 @@
+-- Add the rules captcha mode, where new members accept the chat rules
+-- instead of solving a challenge.
+ALTER TABLE captcha_settings DROP CONSTRAINT IF EXISTS chk_captcha_mode;
+ALTER TABLE captcha_settings ADD CONSTRAINT chk_captcha_mode CHECK (captcha_mode IN ('math', 'text', 'emoji', 'custom', 'rules')) NOT VALID;
@@ -0,0 +1,29 @@
+-- Record how captcha attempts end, for /captchastats. One row is kept per
+-- finished attempt; rows older than 30 days are pruned by the bot.
+CREATE TABLE IF NOT EXISTS captcha_events (
+    id BIGSERIAL PRIMARY KEY,
+    chat_id BIGINT NOT NULL,
+    user_id BIGINT NOT NULL,
+    mode VARCHAR(10) NOT NULL DEFAULT 'math',
+    outcome VARCHAR(10) NOT NULL,
+    wrong_answers INTEGER NOT NULL DEFAULT 0,
+    refreshes INTEGER NOT NULL DEFAULT 0,
+    solve_seconds INTEGER NOT NULL DEFAULT 0,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_captcha_events_chat_created ON captcha_events(chat_id, created_at);
+
+-- Add foreign key to chats table for referential integrity when available.
+DO $$
+BEGIN
+    IF EXISTS (SELECT 1 FROM information_schema.table_constraints WHERE constraint_name = 'fk_captcha_events_chat') THEN
+        ALTER TABLE captcha_events DROP CONSTRAINT fk_captcha_events_chat;
+    END IF;
+
+    IF EXISTS (SELECT 1 FROM information_schema.tables WHERE table_name = 'chats') THEN
+        ALTER TABLE captcha_events
+        ADD CONSTRAINT fk_captcha_events_chat
+        FOREIGN KEY (chat_id) REFERENCES chats(chat_id) ON DELETE CASCADE ON UPDATE CASCADE;
+    END IF;
+END $$;
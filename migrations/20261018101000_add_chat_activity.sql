@@ -0,0 +1,45 @@
+-- Chat activity rollups for /chatstats. Messages are counted in Redis and
+-- flushed here every few minutes: chat_activity_hours holds the hourly totals
+-- of a chat and chat_activity_users the daily message count of each member.
+-- Rows older than 30 days are pruned by the bot.
+CREATE TABLE IF NOT EXISTS chat_activity_hours (
+    id BIGSERIAL PRIMARY KEY,
+    chat_id BIGINT NOT NULL,
+    hour TIMESTAMP WITH TIME ZONE NOT NULL,
+    messages BIGINT NOT NULL DEFAULT 0,
+    media_messages BIGINT NOT NULL DEFAULT 0,
+    joins BIGINT NOT NULL DEFAULT 0,
+    leaves BIGINT NOT NULL DEFAULT 0
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS uk_chat_activity_hours_chat_hour ON chat_activity_hours(chat_id, hour);
+
+CREATE TABLE IF NOT EXISTS chat_activity_users (
+    id BIGSERIAL PRIMARY KEY,
+    chat_id BIGINT NOT NULL,
+    day TIMESTAMP WITH TIME ZONE NOT NULL,
+    user_id BIGINT NOT NULL,
+    messages BIGINT NOT NULL DEFAULT 0
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS uk_chat_activity_users_chat_day_user ON chat_activity_users(chat_id, day, user_id);
+
+-- Add foreign keys to chats table for referential integrity when available.
+DO $$
+BEGIN
+    IF EXISTS (SELECT 1 FROM information_schema.table_constraints WHERE constraint_name = 'fk_chat_activity_hours_chat') THEN
+        ALTER TABLE chat_activity_hours DROP CONSTRAINT fk_chat_activity_hours_chat;
+    END IF;
+    IF EXISTS (SELECT 1 FROM information_schema.table_constraints WHERE constraint_name = 'fk_chat_activity_users_chat') THEN
+        ALTER TABLE chat_activity_users DROP CONSTRAINT fk_chat_activity_users_chat;
+    END IF;
+
+    IF EXISTS (SELECT 1 FROM information_schema.tables WHERE table_name = 'chats') THEN
+        ALTER TABLE chat_activity_hours
+        ADD CONSTRAINT fk_chat_activity_hours_chat
+        FOREIGN KEY (chat_id) REFERENCES chats(chat_id) ON DELETE CASCADE ON UPDATE CASCADE;
+        ALTER TABLE chat_activity_users
+        ADD CONSTRAINT fk_chat_activity_users_chat
+        FOREIGN KEY (chat_id) REFERENCES chats(chat_id) ON DELETE CASCADE ON UPDATE CASCADE;
+    END IF;
+END $$;
@@ -0,0 +1,53 @@
+-- Spam classifier. antispam_settings holds the per-chat configuration,
+-- spam_models the number of spam and non-spam messages each model was trained
+-- on and spam_tokens the per-token counts. Models are keyed by chat, with
+-- chat_id 0 holding the global model every chat trains, so the model tables
+-- have no foreign key to chats.
+CREATE TABLE IF NOT EXISTS antispam_settings (
+    id BIGSERIAL PRIMARY KEY,
+    chat_id BIGINT NOT NULL,
+    enabled BOOLEAN DEFAULT FALSE,
+    action TEXT DEFAULT 'delete',
+    threshold INTEGER DEFAULT 90,
+    use_global BOOLEAN DEFAULT TRUE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    CONSTRAINT chk_antispam_action CHECK (action IN ('delete', 'warn', 'mute', 'kick', 'ban')),
+    CONSTRAINT chk_antispam_threshold CHECK (threshold BETWEEN 50 AND 99)
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_antispam_settings_chat_id ON antispam_settings(chat_id);
+
+CREATE TABLE IF NOT EXISTS spam_models (
+    id BIGSERIAL PRIMARY KEY,
+    chat_id BIGINT NOT NULL,
+    spam_docs BIGINT NOT NULL DEFAULT 0,
+    ham_docs BIGINT NOT NULL DEFAULT 0,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_spam_models_chat_id ON spam_models(chat_id);
+
+CREATE TABLE IF NOT EXISTS spam_tokens (
+    id BIGSERIAL PRIMARY KEY,
+    chat_id BIGINT NOT NULL,
+    token VARCHAR(64) NOT NULL,
+    spam_count BIGINT NOT NULL DEFAULT 0,
+    ham_count BIGINT NOT NULL DEFAULT 0
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS uk_spam_tokens_chat_token ON spam_tokens(chat_id, token);
+
+-- Add foreign key to chats table for referential integrity when available.
+DO $$
+BEGIN
+    IF EXISTS (SELECT 1 FROM information_schema.table_constraints WHERE constraint_name = 'fk_antispam_settings_chat') THEN
+        ALTER TABLE antispam_settings DROP CONSTRAINT fk_antispam_settings_chat;
+    END IF;
+
+    IF EXISTS (SELECT 1 FROM information_schema.tables WHERE table_name = 'chats') THEN
+        ALTER TABLE antispam_settings
+        ADD CONSTRAINT fk_antispam_settings_chat
+        FOREIGN KEY (chat_id) REFERENCES chats(chat_id) ON DELETE CASCADE ON UPDATE CASCADE;
+    END IF;
+END $$;
@@ -0,0 +1,19 @@
+-- Cross-chat duplicate message detection. Chats opt in with dupe_enabled;
+-- dupe_chats is in how many chats the same message has to show up before
+-- dupe_action is taken.
+ALTER TABLE antispam_settings ADD COLUMN IF NOT EXISTS dupe_enabled BOOLEAN DEFAULT FALSE;
+ALTER TABLE antispam_settings ADD COLUMN IF NOT EXISTS dupe_action TEXT DEFAULT 'mute';
+ALTER TABLE antispam_settings ADD COLUMN IF NOT EXISTS dupe_chats INTEGER DEFAULT 3;
+
+DO $$
+BEGIN
+    IF NOT EXISTS (SELECT 1 FROM information_schema.table_constraints WHERE constraint_name = 'chk_antispam_dupe_action') THEN
+        ALTER TABLE antispam_settings
+        ADD CONSTRAINT chk_antispam_dupe_action CHECK (dupe_action IN ('flag', 'delete', 'warn', 'mute', 'kick', 'ban'));
+    END IF;
+
+    IF NOT EXISTS (SELECT 1 FROM information_schema.table_constraints WHERE constraint_name = 'chk_antispam_dupe_chats') THEN
+        ALTER TABLE antispam_settings
+        ADD CONSTRAINT chk_antispam_dupe_chats CHECK (dupe_chats BETWEEN 2 AND 10);
+    END IF;
+END $$;
@@ -0,0 +1,50 @@
+-- Forum topic scopes. Locks and filters get the topic they apply in, with 0
+-- meaning the whole chat; a lock set in a topic overrides the chat-wide one
+-- there. antiflood_topic_limits overrides the flood limit of a chat in one
+-- topic, and greetings.welcome_topic is the topic welcome and captcha
+-- messages are sent to.
+ALTER TABLE locks ADD COLUMN IF NOT EXISTS thread_id BIGINT NOT NULL DEFAULT 0;
+ALTER TABLE filters ADD COLUMN IF NOT EXISTS thread_id BIGINT NOT NULL DEFAULT 0;
+ALTER TABLE filter_revisions ADD COLUMN IF NOT EXISTS thread_id BIGINT NOT NULL DEFAULT 0;
+ALTER TABLE greetings ADD COLUMN IF NOT EXISTS welcome_topic BIGINT DEFAULT 0;
+
+-- A chat can now hold the same lock type once per topic.
+ALTER TABLE locks DROP CONSTRAINT IF EXISTS uk_locks_chat_type;
+DROP INDEX IF EXISTS idx_lock_chat_type;
+
+DO $$
+BEGIN
+    IF NOT EXISTS (SELECT 1 FROM information_schema.table_constraints WHERE constraint_name = 'uk_locks_chat_thread_type') THEN
+        ALTER TABLE locks
+        ADD CONSTRAINT uk_locks_chat_thread_type UNIQUE (chat_id, thread_id, lock_type);
+    END IF;
+END $$;
+
+CREATE TABLE IF NOT EXISTS antiflood_topic_limits (
+    id BIGSERIAL PRIMARY KEY,
+    chat_id BIGINT NOT NULL,
+    thread_id BIGINT NOT NULL,
+    flood_limit INTEGER NOT NULL DEFAULT 0,
+    created_at TIMESTAMP WITH TIME ZONE,
+    updated_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS uk_antiflood_topic_limits_chat_thread ON antiflood_topic_limits(chat_id, thread_id);
+
+DO $$
+BEGIN
+    IF NOT EXISTS (SELECT 1 FROM information_schema.table_constraints WHERE constraint_name = 'chk_antiflood_topic_limit') THEN
+        ALTER TABLE antiflood_topic_limits
+        ADD CONSTRAINT chk_antiflood_topic_limit CHECK (flood_limit >= 0);
+    END IF;
+
+    IF EXISTS (SELECT 1 FROM information_schema.table_constraints WHERE constraint_name = 'fk_antiflood_topic_limits_chat') THEN
+        ALTER TABLE antiflood_topic_limits DROP CONSTRAINT fk_antiflood_topic_limits_chat;
+    END IF;
+
+    IF EXISTS (SELECT 1 FROM information_schema.tables WHERE table_name = 'chats') THEN
+        ALTER TABLE antiflood_topic_limits
+        ADD CONSTRAINT fk_antiflood_topic_limits_chat
+        FOREIGN KEY (chat_id) REFERENCES chats(chat_id) ON DELETE CASCADE ON UPDATE CASCADE;
+    END IF;
+END $$;
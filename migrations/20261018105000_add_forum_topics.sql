@@ -0,0 +1,52 @@
+-- Forum topic management. forum_topic_settings holds the idle time after
+-- which /topicautoclose closes a topic (0 = off), and forum_topics the
+-- topics the bot has seen with their last activity.
+CREATE TABLE IF NOT EXISTS forum_topic_settings (
+    id BIGSERIAL PRIMARY KEY,
+    chat_id BIGINT NOT NULL,
+    auto_close_days INTEGER NOT NULL DEFAULT 0,
+    created_at TIMESTAMP WITH TIME ZONE,
+    updated_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_forum_topic_settings_chat_id ON forum_topic_settings(chat_id);
+
+CREATE TABLE IF NOT EXISTS forum_topics (
+    id BIGSERIAL PRIMARY KEY,
+    chat_id BIGINT NOT NULL,
+    thread_id BIGINT NOT NULL,
+    name TEXT,
+    closed BOOLEAN NOT NULL DEFAULT FALSE,
+    last_activity_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE,
+    updated_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS uk_forum_topics_chat_thread ON forum_topics(chat_id, thread_id);
+CREATE INDEX IF NOT EXISTS idx_forum_topics_activity ON forum_topics(last_activity_at);
+
+DO $$
+BEGIN
+    IF NOT EXISTS (SELECT 1 FROM information_schema.table_constraints WHERE constraint_name = 'chk_forum_topic_settings_auto_close') THEN
+        ALTER TABLE forum_topic_settings
+        ADD CONSTRAINT chk_forum_topic_settings_auto_close CHECK (auto_close_days >= 0);
+    END IF;
+
+    IF EXISTS (SELECT 1 FROM information_schema.table_constraints WHERE constraint_name = 'fk_forum_topic_settings_chat') THEN
+        ALTER TABLE forum_topic_settings DROP CONSTRAINT fk_forum_topic_settings_chat;
+    END IF;
+
+    IF EXISTS (SELECT 1 FROM information_schema.table_constraints WHERE constraint_name = 'fk_forum_topics_chat') THEN
+        ALTER TABLE forum_topics DROP CONSTRAINT fk_forum_topics_chat;
+    END IF;
+
+    IF EXISTS (SELECT 1 FROM information_schema.tables WHERE table_name = 'chats') THEN
+        ALTER TABLE forum_topic_settings
+        ADD CONSTRAINT fk_forum_topic_settings_chat
+        FOREIGN KEY (chat_id) REFERENCES chats(chat_id) ON DELETE CASCADE ON UPDATE CASCADE;
+
+        ALTER TABLE forum_topics
+        ADD CONSTRAINT fk_forum_topics_chat
+        FOREIGN KEY (chat_id) REFERENCES chats(chat_id) ON DELETE CASCADE ON UPDATE CASCADE;
+    END IF;
+END $$;
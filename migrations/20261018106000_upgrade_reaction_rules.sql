@@ -0,0 +1,27 @@
+-- Reaction rules: a keyword can react with one of several emoji (including
+-- custom emoji), fire with a probability and respect a cooldown. The old
+-- single emoji column is folded into the emojis list.
+ALTER TABLE reactions ADD COLUMN IF NOT EXISTS emojis JSONB NOT NULL DEFAULT '[]';
+ALTER TABLE reactions ADD COLUMN IF NOT EXISTS probability INTEGER NOT NULL DEFAULT 100;
+ALTER TABLE reactions ADD COLUMN IF NOT EXISTS cooldown_seconds INTEGER NOT NULL DEFAULT 0;
+
+DO $$
+BEGIN
+    IF EXISTS (SELECT 1 FROM information_schema.columns WHERE table_name = 'reactions' AND column_name = 'emoji') THEN
+        UPDATE reactions
+        SET emojis = jsonb_build_array(jsonb_build_object('emoji', emoji))
+        WHERE emojis = '[]'::jsonb;
+
+        ALTER TABLE reactions DROP COLUMN emoji;
+    END IF;
+
+    IF NOT EXISTS (SELECT 1 FROM information_schema.table_constraints WHERE constraint_name = 'chk_reactions_probability') THEN
+        ALTER TABLE reactions
+        ADD CONSTRAINT chk_reactions_probability CHECK (probability BETWEEN 1 AND 100);
+    END IF;
+
+    IF NOT EXISTS (SELECT 1 FROM information_schema.table_constraints WHERE constraint_name = 'chk_reactions_cooldown') THEN
+        ALTER TABLE reactions
+        ADD CONSTRAINT chk_reactions_cooldown CHECK (cooldown_seconds >= 0);
+    END IF;
+END $$;
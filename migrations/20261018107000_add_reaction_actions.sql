@@ -0,0 +1,33 @@
+-- Reaction moderation. reaction_actions maps a reaction (an emoji, or a
+-- custom emoji by ID) to the moderation action taken on the author of a
+-- message when an admin reacts to it with it.
+CREATE TABLE IF NOT EXISTS reaction_actions (
+    id BIGSERIAL PRIMARY KEY,
+    chat_id BIGINT NOT NULL,
+    emoji TEXT NOT NULL,
+    custom_emoji_id TEXT NOT NULL DEFAULT '',
+    action TEXT NOT NULL,
+    duration_seconds INTEGER NOT NULL DEFAULT 0,
+    created_at TIMESTAMP WITH TIME ZONE,
+    updated_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS uk_reaction_actions_chat_emoji ON reaction_actions(chat_id, emoji, custom_emoji_id);
+
+DO $$
+BEGIN
+    IF NOT EXISTS (SELECT 1 FROM information_schema.table_constraints WHERE constraint_name = 'chk_reaction_actions_duration') THEN
+        ALTER TABLE reaction_actions
+        ADD CONSTRAINT chk_reaction_actions_duration CHECK (duration_seconds >= 0);
+    END IF;
+
+    IF EXISTS (SELECT 1 FROM information_schema.table_constraints WHERE constraint_name = 'fk_reaction_actions_chat') THEN
+        ALTER TABLE reaction_actions DROP CONSTRAINT fk_reaction_actions_chat;
+    END IF;
+
+    IF EXISTS (SELECT 1 FROM information_schema.tables WHERE table_name = 'chats') THEN
+        ALTER TABLE reaction_actions
+        ADD CONSTRAINT fk_reaction_actions_chat
+        FOREIGN KEY (chat_id) REFERENCES chats(chat_id) ON DELETE CASCADE ON UPDATE CASCADE;
+    END IF;
+END $$;
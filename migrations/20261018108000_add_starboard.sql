@@ -0,0 +1,62 @@
+-- Starboard. starboard_settings holds where a chat reposts its starred
+-- messages (channel_id, 0 = off), the reaction that stars a message and how
+-- many it takes. starboard_entries maps a source message to its star count
+-- and, once reposted, to its forward and counter messages in the starboard.
+CREATE TABLE IF NOT EXISTS starboard_settings (
+    id BIGSERIAL PRIMARY KEY,
+    chat_id BIGINT NOT NULL,
+    channel_id BIGINT NOT NULL DEFAULT 0,
+    emoji TEXT NOT NULL,
+    custom_emoji_id TEXT NOT NULL DEFAULT '',
+    threshold INTEGER NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE,
+    updated_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_starboard_settings_chat_id ON starboard_settings(chat_id);
+
+CREATE TABLE IF NOT EXISTS starboard_entries (
+    id BIGSERIAL PRIMARY KEY,
+    chat_id BIGINT NOT NULL,
+    message_id BIGINT NOT NULL,
+    stars INTEGER NOT NULL DEFAULT 0,
+    excluded BOOLEAN NOT NULL DEFAULT FALSE,
+    starboard_chat_id BIGINT NOT NULL DEFAULT 0,
+    forward_message_id BIGINT NOT NULL DEFAULT 0,
+    counter_message_id BIGINT NOT NULL DEFAULT 0,
+    created_at TIMESTAMP WITH TIME ZONE,
+    updated_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS uk_starboard_entries_chat_message ON starboard_entries(chat_id, message_id);
+
+DO $$
+BEGIN
+    IF NOT EXISTS (SELECT 1 FROM information_schema.table_constraints WHERE constraint_name = 'chk_starboard_settings_threshold') THEN
+        ALTER TABLE starboard_settings
+        ADD CONSTRAINT chk_starboard_settings_threshold CHECK (threshold >= 1);
+    END IF;
+
+    IF NOT EXISTS (SELECT 1 FROM information_schema.table_constraints WHERE constraint_name = 'chk_starboard_entries_stars') THEN
+        ALTER TABLE starboard_entries
+        ADD CONSTRAINT chk_starboard_entries_stars CHECK (stars >= 0);
+    END IF;
+
+    IF EXISTS (SELECT 1 FROM information_schema.table_constraints WHERE constraint_name = 'fk_starboard_settings_chat') THEN
+        ALTER TABLE starboard_settings DROP CONSTRAINT fk_starboard_settings_chat;
+    END IF;
+
+    IF EXISTS (SELECT 1 FROM information_schema.table_constraints WHERE constraint_name = 'fk_starboard_entries_chat') THEN
+        ALTER TABLE starboard_entries DROP CONSTRAINT fk_starboard_entries_chat;
+    END IF;
+
+    IF EXISTS (SELECT 1 FROM information_schema.tables WHERE table_name = 'chats') THEN
+        ALTER TABLE starboard_settings
+        ADD CONSTRAINT fk_starboard_settings_chat
+        FOREIGN KEY (chat_id) REFERENCES chats(chat_id) ON DELETE CASCADE ON UPDATE CASCADE;
+
+        ALTER TABLE starboard_entries
+        ADD CONSTRAINT fk_starboard_entries_chat
+        FOREIGN KEY (chat_id) REFERENCES chats(chat_id) ON DELETE CASCADE ON UPDATE CASCADE;
+    END IF;
+END $$;
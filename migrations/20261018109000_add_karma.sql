@@ -0,0 +1,62 @@
+-- Karma. karma_settings holds whether a chat gives karma, what gives it
+-- (the chat's own reply triggers on top of the built-in ones, and whether
+-- 👍 reactions count), how long a member waits before giving karma to the
+-- same person again, and the karma at which members are approved.
+-- karma_users holds the karma of each member.
+CREATE TABLE IF NOT EXISTS karma_settings (
+    id BIGSERIAL PRIMARY KEY,
+    chat_id BIGINT NOT NULL,
+    enabled BOOLEAN NOT NULL DEFAULT FALSE,
+    reactions BOOLEAN NOT NULL DEFAULT TRUE,
+    triggers JSONB NOT NULL DEFAULT '[]',
+    cooldown_seconds INTEGER NOT NULL,
+    approve_at INTEGER NOT NULL DEFAULT 0,
+    approve_scopes JSONB NOT NULL DEFAULT '[]',
+    created_at TIMESTAMP WITH TIME ZONE,
+    updated_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_karma_settings_chat_id ON karma_settings(chat_id);
+
+CREATE TABLE IF NOT EXISTS karma_users (
+    id BIGSERIAL PRIMARY KEY,
+    chat_id BIGINT NOT NULL,
+    user_id BIGINT NOT NULL,
+    karma INTEGER NOT NULL DEFAULT 0,
+    created_at TIMESTAMP WITH TIME ZONE,
+    updated_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS uk_karma_users_chat_user ON karma_users(chat_id, user_id);
+CREATE INDEX IF NOT EXISTS idx_karma_users_chat_karma ON karma_users(chat_id, karma);
+
+DO $$
+BEGIN
+    IF NOT EXISTS (SELECT 1 FROM information_schema.table_constraints WHERE constraint_name = 'chk_karma_settings_cooldown') THEN
+        ALTER TABLE karma_settings
+        ADD CONSTRAINT chk_karma_settings_cooldown CHECK (cooldown_seconds >= 0);
+    END IF;
+
+    IF NOT EXISTS (SELECT 1 FROM information_schema.table_constraints WHERE constraint_name = 'chk_karma_settings_approve_at') THEN
+        ALTER TABLE karma_settings
+        ADD CONSTRAINT chk_karma_settings_approve_at CHECK (approve_at >= 0);
+    END IF;
+
+    IF EXISTS (SELECT 1 FROM information_schema.table_constraints WHERE constraint_name = 'fk_karma_settings_chat') THEN
+        ALTER TABLE karma_settings DROP CONSTRAINT fk_karma_settings_chat;
+    END IF;
+
+    IF EXISTS (SELECT 1 FROM information_schema.table_constraints WHERE constraint_name = 'fk_karma_users_chat') THEN
+        ALTER TABLE karma_users DROP CONSTRAINT fk_karma_users_chat;
+    END IF;
+
+    IF EXISTS (SELECT 1 FROM information_schema.tables WHERE table_name = 'chats') THEN
+        ALTER TABLE karma_settings
+        ADD CONSTRAINT fk_karma_settings_chat
+        FOREIGN KEY (chat_id) REFERENCES chats(chat_id) ON DELETE CASCADE ON UPDATE CASCADE;
+
+        ALTER TABLE karma_users
+        ADD CONSTRAINT fk_karma_users_chat
+        FOREIGN KEY (chat_id) REFERENCES chats(chat_id) ON DELETE CASCADE ON UPDATE CASCADE;
+    END IF;
+END $$;
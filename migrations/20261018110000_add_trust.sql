@@ -0,0 +1,67 @@
+-- Member trust. trust_settings holds who counts as a newcomer in a chat (a
+-- member who joined less than newcomer_hours ago or sent fewer than
+-- newcomer_messages messages, 0 turning a rule off) and the locks and flood
+-- limit that apply only to newcomers. trust_members holds, for each member
+-- the bot saw join, when they joined, how many messages they have sent and
+-- whether they solved the captcha.
+CREATE TABLE IF NOT EXISTS trust_settings (
+    id BIGSERIAL PRIMARY KEY,
+    chat_id BIGINT NOT NULL,
+    newcomer_hours INTEGER NOT NULL,
+    newcomer_messages INTEGER NOT NULL,
+    newcomer_locks JSONB NOT NULL DEFAULT '[]',
+    newcomer_flood INTEGER NOT NULL DEFAULT 0,
+    created_at TIMESTAMP WITH TIME ZONE,
+    updated_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_trust_settings_chat_id ON trust_settings(chat_id);
+
+CREATE TABLE IF NOT EXISTS trust_members (
+    id BIGSERIAL PRIMARY KEY,
+    chat_id BIGINT NOT NULL,
+    user_id BIGINT NOT NULL,
+    joined_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    messages INTEGER NOT NULL DEFAULT 0,
+    captcha_passed BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at TIMESTAMP WITH TIME ZONE,
+    updated_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS uk_trust_members_chat_user ON trust_members(chat_id, user_id);
+
+DO $$
+BEGIN
+    IF NOT EXISTS (SELECT 1 FROM information_schema.table_constraints WHERE constraint_name = 'chk_trust_settings_hours') THEN
+        ALTER TABLE trust_settings
+        ADD CONSTRAINT chk_trust_settings_hours CHECK (newcomer_hours >= 0);
+    END IF;
+
+    IF NOT EXISTS (SELECT 1 FROM information_schema.table_constraints WHERE constraint_name = 'chk_trust_settings_messages') THEN
+        ALTER TABLE trust_settings
+        ADD CONSTRAINT chk_trust_settings_messages CHECK (newcomer_messages >= 0);
+    END IF;
+
+    IF NOT EXISTS (SELECT 1 FROM information_schema.table_constraints WHERE constraint_name = 'chk_trust_settings_flood') THEN
+        ALTER TABLE trust_settings
+        ADD CONSTRAINT chk_trust_settings_flood CHECK (newcomer_flood >= 0);
+    END IF;
+
+    IF EXISTS (SELECT 1 FROM information_schema.table_constraints WHERE constraint_name = 'fk_trust_settings_chat') THEN
+        ALTER TABLE trust_settings DROP CONSTRAINT fk_trust_settings_chat;
+    END IF;
+
+    IF EXISTS (SELECT 1 FROM information_schema.table_constraints WHERE constraint_name = 'fk_trust_members_chat') THEN
+        ALTER TABLE trust_members DROP CONSTRAINT fk_trust_members_chat;
+    END IF;
+
+    IF EXISTS (SELECT 1 FROM information_schema.tables WHERE table_name = 'chats') THEN
+        ALTER TABLE trust_settings
+        ADD CONSTRAINT fk_trust_settings_chat
+        FOREIGN KEY (chat_id) REFERENCES chats(chat_id) ON DELETE CASCADE ON UPDATE CASCADE;
+
+        ALTER TABLE trust_members
+        ADD CONSTRAINT fk_trust_members_chat
+        FOREIGN KEY (chat_id) REFERENCES chats(chat_id) ON DELETE CASCADE ON UPDATE CASCADE;
+    END IF;
+END $$;
@@ -0,0 +1,31 @@
+-- Raid detectors: besides the join count, auto antiraid can weigh similar
+-- names, accounts with no username or photo, identical first messages and
+-- joins through the same invite link. Each weight is the points one hit adds
+-- to the score compared with auto_antiraid_threshold; 0 turns a detector off.
+ALTER TABLE antiraid_settings ADD COLUMN IF NOT EXISTS name_weight INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE antiraid_settings ADD COLUMN IF NOT EXISTS profile_weight INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE antiraid_settings ADD COLUMN IF NOT EXISTS message_weight INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE antiraid_settings ADD COLUMN IF NOT EXISTS invite_weight INTEGER NOT NULL DEFAULT 0;
+
+DO $$
+BEGIN
+    IF NOT EXISTS (SELECT 1 FROM information_schema.table_constraints WHERE constraint_name = 'chk_antiraid_name_weight') THEN
+        ALTER TABLE antiraid_settings
+        ADD CONSTRAINT chk_antiraid_name_weight CHECK (name_weight >= 0);
+    END IF;
+
+    IF NOT EXISTS (SELECT 1 FROM information_schema.table_constraints WHERE constraint_name = 'chk_antiraid_profile_weight') THEN
+        ALTER TABLE antiraid_settings
+        ADD CONSTRAINT chk_antiraid_profile_weight CHECK (profile_weight >= 0);
+    END IF;
+
+    IF NOT EXISTS (SELECT 1 FROM information_schema.table_constraints WHERE constraint_name = 'chk_antiraid_message_weight') THEN
+        ALTER TABLE antiraid_settings
+        ADD CONSTRAINT chk_antiraid_message_weight CHECK (message_weight >= 0);
+    END IF;
+
+    IF NOT EXISTS (SELECT 1 FROM information_schema.table_constraints WHERE constraint_name = 'chk_antiraid_invite_weight') THEN
+        ALTER TABLE antiraid_settings
+        ADD CONSTRAINT chk_antiraid_invite_weight CHECK (invite_weight >= 0);
+    END IF;
+END $$;
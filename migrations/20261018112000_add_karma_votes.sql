@@ -0,0 +1,35 @@
+-- Karma votes: the point of karma a member gave for one message. A member
+-- gives each message karma at most once, and taking a 👍 reaction back
+-- removes the vote and its point, so toggling a reaction cannot pile up
+-- karma.
+CREATE TABLE IF NOT EXISTS karma_votes (
+    id BIGSERIAL PRIMARY KEY,
+    chat_id BIGINT NOT NULL,
+    message_id BIGINT NOT NULL,
+    giver_id BIGINT NOT NULL,
+    receiver_id BIGINT NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS uk_karma_votes_chat_message_giver ON karma_votes(chat_id, message_id, giver_id);
+
+-- Karma now only approves members for scopes chosen explicitly, and never
+-- for captcha or antiraid. Thresholds that would approve for those are
+-- turned off.
+UPDATE karma_settings
+SET approve_at = 0
+WHERE approve_at > 0
+  AND (approve_scopes = '[]'::jsonb OR approve_scopes ?| ARRAY['captcha', 'antiraid']);
+
+DO $$
+BEGIN
+    IF EXISTS (SELECT 1 FROM information_schema.table_constraints WHERE constraint_name = 'fk_karma_votes_chat') THEN
+        ALTER TABLE karma_votes DROP CONSTRAINT fk_karma_votes_chat;
+    END IF;
+
+    IF EXISTS (SELECT 1 FROM information_schema.tables WHERE table_name = 'chats') THEN
+        ALTER TABLE karma_votes
+        ADD CONSTRAINT fk_karma_votes_chat
+        FOREIGN KEY (chat_id) REFERENCES chats(chat_id) ON DELETE CASCADE ON UPDATE CASCADE;
+    END IF;
+END $$;
@@ -0,0 +1,8 @@
+-- The global spam model is now only trained by chats the bot owner trusts
+-- (train_global), and chats no longer use it unless they opt in. What it
+-- learned so far came from any chat's admins, so it is reset.
+ALTER TABLE antispam_settings ADD COLUMN IF NOT EXISTS train_global BOOLEAN DEFAULT FALSE;
+ALTER TABLE antispam_settings ALTER COLUMN use_global SET DEFAULT FALSE;
+
+DELETE FROM spam_tokens WHERE chat_id = 0;
+DELETE FROM spam_models WHERE chat_id = 0;
@@ -0,0 +1,4 @@
+-- close_failed_at records the last failed attempt to auto-close an idle
+-- topic, so topics the bot can't close are skipped for a while instead of
+-- filling every run.
+ALTER TABLE forum_topics ADD COLUMN IF NOT EXISTS close_failed_at TIMESTAMP WITH TIME ZONE;